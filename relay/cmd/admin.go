@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/api"
+	"github.com/keep-network/tbtc/relay/pkg/api/pb"
+	"github.com/urfave/cli"
+)
+
+const adminDescription = `
+Controls the running relay maintainer through the admin API.
+
+The admin API address and the API key are taken from the config file. The
+admin API is available only if the operator API requires authentication.
+The config and logs commands use the gRPC admin service served at
+` + "`API.AdminAddress`" + ` instead.
+`
+
+// Timeout of the calls to the gRPC admin service, other than logs streaming.
+const adminCallTimeout = 10 * time.Second
+
+var adminFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "api-key",
+		Usage: "API key overriding the first key from the config file",
+	},
+	cli.StringFlag{
+		Name:  "ca-cert",
+		Usage: "certificate used to verify the API server certificate",
+	},
+	cli.StringFlag{
+		Name:  "client-cert",
+		Usage: "client certificate used for mutual TLS",
+	},
+	cli.StringFlag{
+		Name:  "client-key",
+		Usage: "client private key used for mutual TLS",
+	},
+}
+
+// AdminCommand contains the definition of the admin command-line sub-command.
+var AdminCommand = cli.Command{
+	Name:        "admin",
+	Usage:       `Controls the running relay maintainer`,
+	Description: adminDescription,
+	Subcommands: []cli.Command{
+		{
+			Name:   "state",
+			Usage:  "Shows whether headers pushing is paused",
+			Flags:  adminFlags,
+			Action: adminAction(""),
+		},
+		{
+			Name:   "pause",
+			Usage:  "Pauses pushing headers to the host chain",
+			Flags:  adminFlags,
+			Action: adminAction(api.AdminPausePath),
+		},
+		{
+			Name:   "resume",
+			Usage:  "Resumes pushing headers to the host chain",
+			Flags:  adminFlags,
+			Action: adminAction(api.AdminResumePath),
+		},
+		{
+			Name:   "resync",
+			Usage:  "Restarts the relay from the best header known by the host chain",
+			Flags:  adminFlags,
+			Action: adminAction(api.AdminResyncPath),
+		},
+		{
+			Name:   "push",
+			Usage:  "Pushes the next headers batch immediately",
+			Flags:  adminFlags,
+			Action: adminAction(api.AdminPushPath),
+		},
+		{
+			Name:   "pause-tasks",
+			Usage:  "Pauses all scheduled relay tasks, including headers pulling",
+			Flags:  adminFlags,
+			Action: adminAction(api.AdminPauseTasksPath),
+		},
+		{
+			Name:   "resume-tasks",
+			Usage:  "Resumes all scheduled relay tasks",
+			Flags:  adminFlags,
+			Action: adminAction(api.AdminResumeTasksPath),
+		},
+		{
+			Name:      "schedule",
+			Usage:     "Sets the schedule of a relay task",
+			ArgsUsage: "<task> [<schedule>]",
+			Description: "Sets the schedule of the given relay task. If the " +
+				"schedule is omitted, the default interval of the task is " +
+				"restored.",
+			Flags:  adminFlags,
+			Action: adminSchedule,
+		},
+		{
+			Name:      "config",
+			Usage:     "Shows or sets the relay settings tunable at runtime",
+			ArgsUsage: "[<name> <value>]",
+			Description: "Shows the relay settings which can be tuned at " +
+				"runtime, i.e. `" + api.TunableLogLevels + "` and the `" +
+				api.TunableSchedulePrefix + "<task>` schedules. If the " +
+				"name and the value are given, the setting is changed first.",
+			Flags:  adminFlags,
+			Action: adminConfig,
+		},
+		{
+			Name:  "logs",
+			Usage: "Streams the relay logs",
+			Description: "Streams the logs of the running relay maintainer. " +
+				"Log streaming must be enabled with `API.LogStreaming`. " +
+				"Lines are formatted as time, level, logger, message and " +
+				"fields, separated by tabs.",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "filter",
+					Usage: "show only entries containing the given text",
+				},
+			}, adminFlags...),
+			Action: adminLogs,
+		},
+	},
+}
+
+func adminAction(path string) func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		client, err := newAPIClient(c)
+		if err != nil {
+			return err
+		}
+
+		var state *api.AdminStateResponse
+		if path == "" {
+			state, err = client.AdminState()
+		} else {
+			state, err = client.AdminAction(path)
+		}
+		if err != nil {
+			return fmt.Errorf("admin request failed: [%v]", err)
+		}
+
+		printAdminState(state)
+
+		return nil
+	}
+}
+
+func adminSchedule(c *cli.Context) error {
+	if c.NArg() < 1 || c.NArg() > 2 {
+		return fmt.Errorf("expected task and optional schedule arguments")
+	}
+
+	client, err := newAPIClient(c)
+	if err != nil {
+		return err
+	}
+
+	state, err := client.AdminSchedule(c.Args().Get(0), c.Args().Get(1))
+	if err != nil {
+		return fmt.Errorf("admin request failed: [%v]", err)
+	}
+
+	printAdminState(state)
+
+	return nil
+}
+
+func adminConfig(c *cli.Context) error {
+	if c.NArg() != 0 && c.NArg() != 2 {
+		return fmt.Errorf("expected no arguments or name and value arguments")
+	}
+
+	client, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminCallTimeout)
+	defer cancel()
+
+	var config *pb.Config
+	if c.NArg() == 2 {
+		config, err = client.SetConfig(ctx, &pb.SetConfigRequest{
+			Name:  c.Args().Get(0),
+			Value: c.Args().Get(1),
+		})
+	} else {
+		config, err = client.GetConfig(ctx, &pb.GetConfigRequest{})
+	}
+	if err != nil {
+		return fmt.Errorf("admin call failed: [%v]", err)
+	}
+
+	names := make([]string, 0, len(config.Tunables))
+	for name := range config.Tunables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%v: %v\n", name, config.Tunables[name])
+	}
+
+	return nil
+}
+
+func adminLogs(c *cli.Context) error {
+	client, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.TailLogs(
+		context.Background(),
+		&pb.TailLogsRequest{Filter: c.String("filter")},
+	)
+	if err != nil {
+		return fmt.Errorf("admin call failed: [%v]", err)
+	}
+
+	for {
+		entry, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("logs streaming failed: [%v]", err)
+		}
+
+		printLogEntry(entry)
+	}
+}
+
+func printLogEntry(entry *pb.LogEntry) {
+	line := fmt.Sprintf(
+		"%v\t%v\t%v\t%v",
+		entry.Time,
+		strings.ToUpper(entry.Level),
+		entry.Logger,
+		entry.Message,
+	)
+
+	names := make([]string, 0, len(entry.Fields))
+	for name := range entry.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		line += fmt.Sprintf("\t%v=%v", name, entry.Fields[name])
+	}
+
+	fmt.Println(line)
+}
+
+func printAdminState(state *api.AdminStateResponse) {
+	fmt.Printf("headers pushing paused: %v\n", state.Paused)
+	fmt.Printf("scheduled tasks paused: %v\n", state.TasksPaused)
+
+	tasks := make([]string, 0, len(state.Schedules))
+	for task := range state.Schedules {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+
+	for _, task := range tasks {
+		fmt.Printf("schedule of task %v: %v\n", task, state.Schedules[task])
+	}
+}
+
+func newAPIClient(c *cli.Context) (*api.Client, error) {
+	config, err := readConfig(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	if !config.API.IsEnabled() {
+		return nil, fmt.Errorf("API is not configured")
+	}
+
+	return api.NewClient(newClientConfig(c, config.API, config.API.Address))
+}
+
+func newAdminClient(c *cli.Context) (*api.AdminClient, error) {
+	config, err := readConfig(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	if config.API.AdminAddress == "" {
+		return nil, fmt.Errorf("admin service is not configured")
+	}
+
+	return api.NewAdminClient(
+		newClientConfig(c, config.API, config.API.AdminAddress),
+	)
+}
+
+func newClientConfig(
+	c *cli.Context,
+	config api.Config,
+	address string,
+) *api.ClientConfig {
+	apiKey := c.String("api-key")
+	if apiKey == "" && len(config.APIKeys) > 0 {
+		apiKey = config.APIKeys[0]
+	}
+
+	caCertFile := c.String("ca-cert")
+	if caCertFile == "" {
+		// The server certificate is usually self-signed.
+		caCertFile = config.TLSCertFile
+	}
+
+	return &api.ClientConfig{
+		Address:        address,
+		APIKey:         apiKey,
+		TLS:            config.TLSCertFile != "",
+		CACertFile:     caCertFile,
+		ClientCertFile: c.String("client-cert"),
+		ClientKeyFile:  c.String("client-key"),
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/proof"
+	"github.com/urfave/cli"
+)
+
+const benchDescription = `
+Measures the throughput of the relay under varying batch sizes, headers queue
+sizes and push latencies, to guide the tuning of the relay and catch
+performance regressions.
+
+The relay is run against an in-memory Bitcoin chain of synthetic headers and
+a host chain mock accepting each submission after the push latency, so
+neither the Bitcoin node nor the host chain node is needed. Each combination
+of '--batch-size', '--batches-ahead' and '--push-latency' is run separately
+and the number of headers pulled and pushed per second is printed. Then the
+construction of transaction proofs in a block of '--proof-transactions'
+transactions is measured.
+
+The relay logs each pushed batch; set the LOG_LEVEL environment variable to
+'warn' to keep the output readable.
+`
+
+// Default values of the bench command flags.
+var (
+	defaultBenchBatchSizes    = []int{1, 5}
+	defaultBenchBatchesAhead  = []int{1, 10}
+	defaultBenchPushLatencies = []string{"0s", "10ms"}
+)
+
+// BenchCommand contains the definition of the bench command-line
+// sub-command.
+var BenchCommand = cli.Command{
+	Name:        "bench",
+	Usage:       `Measures the relay throughput`,
+	Description: benchDescription,
+	Action:      Bench,
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "headers",
+			Value: 1000,
+			Usage: "number of headers relayed by each run",
+		},
+		cli.IntSliceFlag{
+			Name:  "batch-size",
+			Usage: "maximum size of pushed batches, up to 5; 1 and 5 by default",
+		},
+		cli.IntSliceFlag{
+			Name:  "batches-ahead",
+			Usage: "maximum batches pulled ahead, up to 10; 1 and 10 by default",
+		},
+		cli.StringSliceFlag{
+			Name:  "push-latency",
+			Usage: "time each submission takes, e.g. 100ms; 0s and 10ms by default",
+		},
+		cli.IntFlag{
+			Name:  "proof-transactions",
+			Value: 3000,
+			Usage: "number of transactions in the block of measured proofs",
+		},
+	},
+}
+
+// Bench measures the throughput of the relay and the proof construction.
+func Bench(c *cli.Context) error {
+	batchSizes := c.IntSlice("batch-size")
+	if len(batchSizes) == 0 {
+		batchSizes = defaultBenchBatchSizes
+	}
+
+	batchesAheads := c.IntSlice("batches-ahead")
+	if len(batchesAheads) == 0 {
+		batchesAheads = defaultBenchBatchesAhead
+	}
+
+	rawPushLatencies := c.StringSlice("push-latency")
+	if len(rawPushLatencies) == 0 {
+		rawPushLatencies = defaultBenchPushLatencies
+	}
+
+	pushLatencies := make([]time.Duration, len(rawPushLatencies))
+	for i, rawPushLatency := range rawPushLatencies {
+		pushLatency, err := time.ParseDuration(rawPushLatency)
+		if err != nil {
+			return fmt.Errorf(
+				"invalid push latency [%v]: [%v]",
+				rawPushLatency,
+				err,
+			)
+		}
+		pushLatencies[i] = pushLatency
+	}
+
+	ctx := context.Background()
+
+	for _, batchSize := range batchSizes {
+		for _, batchesAhead := range batchesAheads {
+			for _, pushLatency := range pushLatencies {
+				result, err := header.RunBenchmark(ctx, &header.BenchConfig{
+					Headers:      c.Int("headers"),
+					BatchSize:    batchSize,
+					BatchesAhead: batchesAhead,
+					PushLatency:  pushLatency,
+				})
+				if err != nil {
+					return fmt.Errorf("could not run benchmark: [%v]", err)
+				}
+
+				if _, err := fmt.Fprintf(
+					os.Stdout,
+					"relay: batch size %v, batches ahead %v, "+
+						"push latency %v: %.0f headers/s "+
+						"(%v headers, %v submissions in %v)\n",
+					result.BatchSize,
+					result.BatchesAhead,
+					pushLatency,
+					result.HeadersPerSecond(),
+					result.Headers,
+					result.Submissions,
+					result.Duration.Round(time.Millisecond),
+				); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return benchProofs(c.Int("proof-transactions"))
+}
+
+// benchProofs measures building the merkle tree of a block with the given
+// number of synthetic transactions and building a single proof from it.
+func benchProofs(transactions int) error {
+	if transactions <= 0 {
+		return fmt.Errorf("number of proof transactions must be positive")
+	}
+
+	txIDs := make([]btc.Digest, transactions)
+	for i := range txIDs {
+		var seed [8]byte
+		binary.LittleEndian.PutUint64(seed[:], uint64(i))
+		txIDs[i] = btc.Digest(sha256.Sum256(seed[:]))
+	}
+
+	var benchErr error
+
+	treeResult := testing.Benchmark(func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := proof.NewMerkleTree(txIDs); err != nil {
+				benchErr = err
+				return
+			}
+		}
+	})
+
+	tree, err := proof.NewMerkleTree(txIDs)
+	if err != nil {
+		return fmt.Errorf("could not build merkle tree: [%v]", err)
+	}
+
+	proofResult := testing.Benchmark(func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := tree.Proof(uint64(i % transactions)); err != nil {
+				benchErr = err
+				return
+			}
+		}
+	})
+
+	if benchErr != nil {
+		return fmt.Errorf("could not build proof: [%v]", benchErr)
+	}
+
+	_, err = fmt.Fprintf(
+		os.Stdout,
+		"proofs: block of %v transactions: merkle tree in %v, proof in %v\n",
+		transactions,
+		time.Duration(treeResult.NsPerOp()),
+		time.Duration(proofResult.NsPerOp()),
+	)
+
+	return err
+}
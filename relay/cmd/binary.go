@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/urfave/cli"
+)
+
+const verifyBinaryDescription = `
+Verifies the signed manifest appended to the relay binary at release time. The
+binary is verified only if the manifest is signed by one of the trusted keys,
+matches the SHA-256 digest of the binary and, when the running binary is
+verified, matches its embedded version, revision and build date.
+
+Unless trusted keys are passed with --key, the release key embedded in the
+binary is trusted. A tampered binary can embed any key, so operators should
+pass the release key published with the release notes.
+`
+
+const signBinaryDescription = `
+Appends a manifest binding the build information of the running relay binary
+to the SHA-256 digest of the given binary, signed with the given Ed25519 key.
+It is meant to be run by the release pipeline, with the released binary
+signing itself.
+`
+
+// VerifyBinaryCommand contains the definition of the verify-binary
+// command-line sub-command.
+var VerifyBinaryCommand = cli.Command{
+	Name:        "verify-binary",
+	Usage:       `Verifies the signature of the relay binary`,
+	Description: verifyBinaryDescription,
+	Action:      VerifyBinary,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "file",
+			Usage: "binary to verify instead of the running one",
+		},
+		cli.StringSliceFlag{
+			Name:  "key",
+			Usage: "hex-encoded Ed25519 public key trusted to sign releases",
+		},
+	},
+}
+
+// SignBinaryCommand contains the definition of the sign-binary command-line
+// sub-command.
+var SignBinaryCommand = cli.Command{
+	Name:        "sign-binary",
+	Usage:       `Signs a released relay binary`,
+	Description: signBinaryDescription,
+	Action:      SignBinary,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "file",
+			Usage: "binary to sign instead of the running one",
+		},
+		cli.StringFlag{
+			Name:  "key-file",
+			Usage: "file with the hex-encoded Ed25519 signing key",
+		},
+		cli.StringFlag{
+			Name:  "out",
+			Usage: "file the signed binary is written to",
+		},
+	},
+}
+
+// VerifyBinary verifies the manifest appended to the relay binary.
+func VerifyBinary(c *cli.Context) error {
+	path, running, err := binaryPath(c)
+	if err != nil {
+		return err
+	}
+
+	trustedKeys := c.StringSlice("key")
+	if len(trustedKeys) == 0 {
+		if build.ReleaseKey == "" {
+			return fmt.Errorf(
+				"no release key embedded in the binary; pass trusted keys " +
+					"with --key",
+			)
+		}
+
+		trustedKeys = []string{build.ReleaseKey}
+	}
+
+	executable, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read binary [%v]: [%v]", path, err)
+	}
+
+	manifest, err := build.VerifyBinary(executable, trustedKeys)
+	if err != nil {
+		return fmt.Errorf("could not verify binary [%v]: [%v]", path, err)
+	}
+
+	if running {
+		if err := manifest.Matches(build.GetInfo()); err != nil {
+			return fmt.Errorf("could not verify binary [%v]: [%v]", path, err)
+		}
+	}
+
+	fmt.Printf("binary:     %v\n", path)
+	fmt.Printf("version:    %v\n", manifest.Version)
+	fmt.Printf("revision:   %v\n", manifest.Revision)
+	fmt.Printf("build date: %v\n", manifest.Date)
+	fmt.Printf("sha256:     %v\n", manifest.Digest)
+	fmt.Printf("signed by:  %v\n", manifest.PublicKey)
+
+	return nil
+}
+
+// SignBinary appends the signed manifest to the relay binary.
+func SignBinary(c *cli.Context) error {
+	path, _, err := binaryPath(c)
+	if err != nil {
+		return err
+	}
+
+	if c.String("out") == "" {
+		return fmt.Errorf("output file is not set")
+	}
+
+	privateKey, err := readSigningKey(c.String("key-file"))
+	if err != nil {
+		return err
+	}
+
+	executable, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read binary [%v]: [%v]", path, err)
+	}
+
+	signed, err := build.SignBinary(executable, build.GetInfo(), privateKey)
+	if err != nil {
+		return fmt.Errorf("could not sign binary [%v]: [%v]", path, err)
+	}
+
+	if err := ioutil.WriteFile(c.String("out"), signed, 0755); err != nil {
+		return fmt.Errorf("could not write signed binary: [%v]", err)
+	}
+
+	fmt.Printf("signed binary written to %v\n", c.String("out"))
+
+	return nil
+}
+
+// binaryPath returns the path of the binary passed with --file or, if not
+// set, of the running binary. The second value tells whether it is the
+// running binary.
+func binaryPath(c *cli.Context) (string, bool, error) {
+	if path := c.String("file"); path != "" {
+		return path, false, nil
+	}
+
+	path, err := os.Executable()
+	if err != nil {
+		return "", false, fmt.Errorf("could not locate relay binary: [%v]", err)
+	}
+
+	return path, true, nil
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/keep-network/tbtc/relay/config"
+	"github.com/urfave/cli"
+)
+
+// readConfig reads the configuration file passed with the global flags,
+// overlaid with the files of the selected profile and secrets.
+func readConfig(c *cli.Context) (*config.Config, error) {
+	return config.ReadLayeredConfig(
+		c.GlobalString("config"),
+		c.GlobalString("profile"),
+		c.GlobalString("secrets"),
+	)
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	commonethereum "github.com/keep-network/keep-common/pkg/chain/ethereum"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+	"github.com/keep-network/tbtc/relay/config"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum"
+	"github.com/keep-network/tbtc/relay/pkg/doctor"
+	"github.com/urfave/cli"
+)
+
+const doctorDescription = `
+Runs a battery of checks verifying the relay maintainer configuration without
+starting it: Bitcoin node reachability, network, RPC methods and sync status,
+Ethereum node health, chain ID, relay contract presence, operator key,
+operator balance and local clock skew.
+
+Prints a pass/fail report and exits with an error if any check fails.
+
+The password of the operator host chain key file should be provided as
+` + config.PasswordEnvVariable + ` environment variable.
+`
+
+// maxTipAge is the maximum age of the best block known by the Bitcoin node
+// for the node to be considered synced.
+const maxTipAge = 2 * time.Hour
+
+// maxClockSkew is the maximum difference between the local clock and the
+// timestamp of the latest host chain block.
+const maxClockSkew = 5 * time.Minute
+
+// DoctorCommand contains the definition of the doctor command-line
+// sub-command.
+var DoctorCommand = cli.Command{
+	Name:        "doctor",
+	Usage:       `Checks the relay maintainer configuration`,
+	Description: doctorDescription,
+	Action:      Doctor,
+}
+
+// Doctor runs the self-test and prints the report.
+func Doctor(c *cli.Context) error {
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	var checks []*doctor.Check
+
+	btcDiagnostics, err := btc.NewDiagnostics(&config.Bitcoin)
+	if err != nil {
+		checks = append(checks, failingCheck("Bitcoin RPC", err))
+	} else {
+		defer btcDiagnostics.Close()
+		checks = append(checks, bitcoinChecks(btcDiagnostics)...)
+	}
+
+	ethDiagnostics, err := ethereum.NewDiagnostics(&config.Ethereum)
+	if err != nil {
+		checks = append(checks, failingCheck("Ethereum RPC", err))
+	} else {
+		defer ethDiagnostics.Close()
+		checks = append(
+			checks,
+			ethereumChecks(
+				ethDiagnostics,
+				&config.Ethereum,
+				config.Relay.WatchOnly,
+			)...,
+		)
+	}
+
+	report := doctor.Run(context.Background(), checks)
+
+	if err := report.Print(os.Stdout); err != nil {
+		return fmt.Errorf("could not print report: [%v]", err)
+	}
+
+	if !report.Passed() {
+		return fmt.Errorf("self-test failed")
+	}
+
+	return nil
+}
+
+func failingCheck(name string, err error) *doctor.Check {
+	return &doctor.Check{
+		Name: name,
+		Run: func(ctx context.Context) (string, error) {
+			return "", err
+		},
+	}
+}
+
+func bitcoinChecks(diagnostics *btc.Diagnostics) []*doctor.Check {
+	return []*doctor.Check{
+		{
+			Name: "Bitcoin RPC",
+			Run: func(ctx context.Context) (string, error) {
+				return "", diagnostics.CheckConnection()
+			},
+		},
+		{
+			Name: "Bitcoin network",
+			Run: func(ctx context.Context) (string, error) {
+				return "", diagnostics.CheckNetwork()
+			},
+		},
+		{
+			Name: "Bitcoin RPC methods",
+			Run: func(ctx context.Context) (string, error) {
+				return "", diagnostics.CheckRPCMethods()
+			},
+		},
+		{
+			Name: "Bitcoin sync status",
+			Run: func(ctx context.Context) (string, error) {
+				status, err := diagnostics.SyncStatus()
+				if err != nil {
+					return "", err
+				}
+
+				details := fmt.Sprintf(
+					"blocks [%v], headers [%v], tip time [%v]",
+					status.Blocks,
+					status.Headers,
+					status.TipTime.UTC().Format(time.RFC3339),
+				)
+
+				if !status.IsSynced() {
+					return "", fmt.Errorf("node is syncing; %v", details)
+				}
+
+				if tipAge := time.Since(status.TipTime); tipAge > maxTipAge {
+					return "", fmt.Errorf(
+						"best block is [%v] old; %v",
+						tipAge.Round(time.Minute),
+						details,
+					)
+				}
+
+				return details, nil
+			},
+		},
+	}
+}
+
+func ethereumChecks(
+	diagnostics *ethereum.Diagnostics,
+	config *ethereum.Config,
+	watchOnly bool,
+) []*doctor.Check {
+	var key *keystore.Key
+
+	return []*doctor.Check{
+		{
+			Name: "Ethereum RPC",
+			Run: func(ctx context.Context) (string, error) {
+				return "", diagnostics.CheckSyncStatus(ctx)
+			},
+		},
+		{
+			Name: "Ethereum chain ID",
+			Run: func(ctx context.Context) (string, error) {
+				chainID, err := diagnostics.CheckChainID(ctx)
+				if err != nil {
+					return "", err
+				}
+
+				if config.ChainID == 0 {
+					return fmt.Sprintf(
+						"chain ID [%v]; no expected chain ID configured",
+						chainID,
+					), nil
+				}
+
+				return fmt.Sprintf("chain ID [%v]", chainID), nil
+			},
+		},
+		{
+			Name: "Relay contract",
+			Run: func(ctx context.Context) (string, error) {
+				version, err := diagnostics.CheckRelayContract()
+				if err != nil {
+					return "", err
+				}
+
+				return fmt.Sprintf("version [%v]", version), nil
+			},
+		},
+		{
+			Name: "Operator key",
+			Run: func(ctx context.Context) (string, error) {
+				if len(config.Account.KeyFile) == 0 || watchOnly {
+					return "watch-only mode; no key needed", nil
+				}
+
+				decryptedKey, err := ethutil.DecryptKeyFile(
+					config.Account.KeyFile,
+					config.Account.KeyFilePassword,
+				)
+				if err != nil {
+					return "", fmt.Errorf(
+						"could not unlock key file [%v]: [%v]",
+						config.Account.KeyFile,
+						err,
+					)
+				}
+
+				key = decryptedKey
+
+				return fmt.Sprintf("account [%v]", key.Address.Hex()), nil
+			},
+		},
+		{
+			Name: "Operator balance",
+			Run: func(ctx context.Context) (string, error) {
+				if key == nil {
+					if len(config.Account.KeyFile) == 0 || watchOnly {
+						return "watch-only mode; no balance needed", nil
+					}
+
+					return "", fmt.Errorf("operator key is not unlocked")
+				}
+
+				return checkBalance(
+					ctx,
+					diagnostics,
+					key.Address,
+					config.BalanceAlertThreshold,
+				)
+			},
+		},
+		{
+			Name: "Clock skew",
+			Run: func(ctx context.Context) (string, error) {
+				blockTime, err := diagnostics.LatestBlockTime(ctx)
+				if err != nil {
+					return "", err
+				}
+
+				skew := time.Since(blockTime)
+				if skew < -maxClockSkew || skew > maxClockSkew {
+					return "", fmt.Errorf(
+						"local clock differs by [%v] from the latest host "+
+							"chain block timestamp; check the local clock "+
+							"and the node sync status",
+						skew.Round(time.Second),
+					)
+				}
+
+				return fmt.Sprintf(
+					"[%v] behind the latest host chain block",
+					skew.Round(time.Second),
+				), nil
+			},
+		},
+	}
+}
+
+func checkBalance(
+	ctx context.Context,
+	diagnostics *ethereum.Diagnostics,
+	address common.Address,
+	threshold *commonethereum.Wei,
+) (string, error) {
+	balance, err := diagnostics.Balance(ctx, address)
+	if err != nil {
+		return "", fmt.Errorf("could not get balance: [%v]", err)
+	}
+
+	if balance.Sign() == 0 {
+		return "", fmt.Errorf(
+			"account [%v] has no funds to pay for transactions",
+			address.Hex(),
+		)
+	}
+
+	if threshold != nil && threshold.Int != nil &&
+		balance.Cmp(threshold.Int) < 0 {
+		return "", fmt.Errorf(
+			"balance [%v] wei is below the alert threshold [%v] wei",
+			balance,
+			threshold.Int,
+		)
+	}
+
+	return fmt.Sprintf("balance [%v] wei", balance), nil
+}
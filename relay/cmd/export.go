@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/export"
+	"github.com/urfave/cli"
+)
+
+const exportDescription = `
+Walks the events emitted by the relay contract within the given range of host
+chain blocks and writes a log of the transactions which advanced the relay
+contract: tip extensions, retargets and new best headers, along with their
+submitter addresses, gas used and fees.
+
+The range is given using the '--from-block' and '--to-block' flags, both
+inclusive; the range ends at the current block by default. The log is written
+in the format given by the '--format' flag, either 'json' or 'csv', to the file
+given by the '--output' flag or to the standard output.
+
+The command never submits any transactions.
+`
+
+// ExportCommand contains the definition of the export command-line
+// sub-command.
+var ExportCommand = cli.Command{
+	Name:        "export",
+	Usage:       `Exports the relay contract history`,
+	Description: exportDescription,
+	Action:      Export,
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name:  "from-block",
+			Usage: "first host chain block of the exported range",
+		},
+		cli.Uint64Flag{
+			Name:  "to-block",
+			Usage: "last host chain block of the exported range",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: export.FormatJSON,
+			Usage: "format of the log, either json or csv",
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "path of the output file",
+		},
+	},
+}
+
+// Export writes the log of the relay contract history.
+func Export(c *cli.Context) error {
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	format := c.String("format")
+	if format != export.FormatJSON && format != export.FormatCSV {
+		return fmt.Errorf("unknown export format [%v]", format)
+	}
+
+	ctx := context.Background()
+
+	hostChain, err := ethereum.Connect(nil, &config.Ethereum, nil)
+	if err != nil {
+		return fmt.Errorf("could not connect host chain: [%v]", err)
+	}
+
+	fromBlock := c.Uint64("from-block")
+	toBlock := c.Uint64("to-block")
+	if !c.IsSet("to-block") {
+		toBlock, err = hostChain.CurrentBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("could not get current block: [%v]", err)
+		}
+	}
+
+	if fromBlock > toBlock {
+		return fmt.Errorf(
+			"invalid block range [%v-%v]",
+			fromBlock,
+			toBlock,
+		)
+	}
+
+	advances, err := pastRelayAdvances(ctx, hostChain, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+
+	var writer io.Writer = os.Stdout
+	if output := c.String("output"); output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("could not create output file: [%v]", err)
+		}
+		defer file.Close()
+
+		writer = file
+	}
+
+	return export.Write(writer, format, export.NewRecords(advances))
+}
+
+// pastRelayAdvances returns advances of the relay contract made within the
+// given range of host chain blocks, both inclusive. The range is queried in
+// chunks as many nodes refuse too broad queries.
+func pastRelayAdvances(
+	ctx context.Context,
+	hostChain chain.RelayEvents,
+	fromBlock uint64,
+	toBlock uint64,
+) ([]*chain.RelayAdvance, error) {
+	var advances []*chain.RelayAdvance
+
+	for fromBlock <= toBlock {
+		chunkEnd := fromBlock + competition.MaxBlocksPerQuery - 1
+		if chunkEnd > toBlock {
+			chunkEnd = toBlock
+		}
+
+		relayAdvances, err := hostChain.PastRelayAdvances(
+			ctx,
+			fromBlock,
+			chunkEnd,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not get past relay advances: [%v]",
+				err,
+			)
+		}
+
+		advances = append(advances, relayAdvances...)
+		fromBlock = chunkEnd + 1
+	}
+
+	return advances, nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/keep-network/tbtc/relay/pkg/metrics"
+	"github.com/keep-network/tbtc/relay/pkg/monitoring"
+	"github.com/urfave/cli"
+)
+
+const genMonitoringDescription = `
+Generates Prometheus alerting rules and a Grafana dashboard matching the
+metrics exposed by the relay maintainer.
+
+The rules are written to the ` + alertRulesFile + ` file and the dashboard to
+the ` + dashboardFile + ` file in the directory given by the '--output-dir'
+flag. The rules file can be referenced in the rule_files section of the
+Prometheus config and the dashboard can be imported to Grafana.
+`
+
+const (
+	alertRulesFile = "tbtc-relay-alerts.yml"
+	dashboardFile  = "tbtc-relay-dashboard.json"
+)
+
+// GenMonitoringCommand contains the definition of the gen-monitoring
+// command-line sub-command.
+var GenMonitoringCommand = cli.Command{
+	Name:        "gen-monitoring",
+	Usage:       `Generates alerting rules and a dashboard for the metrics`,
+	Description: genMonitoringDescription,
+	Action:      GenMonitoring,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output-dir",
+			Value: ".",
+			Usage: "directory the generated files are written to",
+		},
+	},
+}
+
+// GenMonitoring writes the alerting rules and the dashboard.
+func GenMonitoring(c *cli.Context) error {
+	outputDir := c.String("output-dir")
+
+	alertRules, err := os.Create(filepath.Join(outputDir, alertRulesFile))
+	if err != nil {
+		return fmt.Errorf("could not create alerting rules file: [%v]", err)
+	}
+	defer alertRules.Close()
+
+	if err := monitoring.WriteAlertRules(
+		alertRules,
+		metrics.Definitions(),
+	); err != nil {
+		return fmt.Errorf("could not write alerting rules: [%v]", err)
+	}
+
+	dashboard, err := os.Create(filepath.Join(outputDir, dashboardFile))
+	if err != nil {
+		return fmt.Errorf("could not create dashboard file: [%v]", err)
+	}
+	defer dashboard.Close()
+
+	if err := monitoring.WriteDashboard(
+		dashboard,
+		metrics.Definitions(),
+	); err != nil {
+		return fmt.Errorf("could not write dashboard: [%v]", err)
+	}
+
+	fmt.Printf(
+		"alerting rules written to [%v]\ndashboard written to [%v]\n",
+		alertRules.Name(),
+		dashboard.Name(),
+	)
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/urfave/cli"
+)
+
+const planDescription = `
+Precomputes the sequence of relay contract calls the relay maintainer makes to
+push all headers between the best header known by the relay contract and the
+Bitcoin tip, so the calls can be reviewed before a big catch-up.
+
+For each call, the submitted headers and the estimated gas are printed, along
+with the total estimated gas and duration. The estimates are based on the
+relay contract calls made within the last '--sample-blocks' host chain blocks.
+
+The command never submits any transactions.
+`
+
+// PlanCommand contains the definition of the plan command-line sub-command.
+var PlanCommand = cli.Command{
+	Name:        "plan",
+	Usage:       `Previews the relay contract calls needed to catch up`,
+	Description: planDescription,
+	Action:      Plan,
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name:  "sample-blocks",
+			Value: competition.DefaultLookbackBlocks,
+			Usage: "number of recent host chain blocks the estimates are based on",
+		},
+	},
+}
+
+// Plan prints the plan of relay contract calls needed to catch up with the
+// Bitcoin tip.
+func Plan(c *cli.Context) error {
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	ctx := context.Background()
+
+	btcChain, err := btc.Connect(ctx, &config.Bitcoin, nil)
+	if err != nil {
+		return fmt.Errorf("could not connect BTC chain: [%v]", err)
+	}
+
+	hostChain, err := ethereum.Connect(nil, &config.Ethereum, nil)
+	if err != nil {
+		return fmt.Errorf("could not connect host chain: [%v]", err)
+	}
+
+	bestDigest, err := hostChain.GetBestKnownDigest(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get best known digest: [%v]", err)
+	}
+
+	bestHeight, err := btcChain.GetHeightByDigest(ctx, bestDigest)
+	if err != nil {
+		return fmt.Errorf(
+			"could not get best known header height: [%v]",
+			err,
+		)
+	}
+
+	tipHeight, err := btcChain.GetBlockCount(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get BTC block count: [%v]", err)
+	}
+
+	estimates, err := estimatePlan(ctx, hostChain, c.Uint64("sample-blocks"))
+	if err != nil {
+		return fmt.Errorf("could not estimate plan: [%v]", err)
+	}
+
+	plan, err := header.NewPlan(
+		&config.Relay,
+		bestHeight,
+		tipHeight,
+		estimates,
+	)
+	if err != nil {
+		return err
+	}
+
+	return plan.Print(os.Stdout)
+}
+
+// estimatePlan derives the plan estimates from the relay contract calls made
+// within the given number of recent host chain blocks. Estimates which cannot
+// be derived are left zero.
+func estimatePlan(
+	ctx context.Context,
+	hostChain chain.Handle,
+	sampleBlocks uint64,
+) (*header.PlanEstimates, error) {
+	currentBlock, err := hostChain.CurrentBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get current block: [%v]", err)
+	}
+
+	fromBlock := uint64(0)
+	if currentBlock > sampleBlocks {
+		fromBlock = currentBlock - sampleBlocks
+	}
+
+	advances, err := pastRelayAdvances(ctx, hostChain, fromBlock, currentBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := &header.PlanEstimates{}
+
+	var headersGas, headers, updatesGas, updates uint64
+	for _, advance := range advances {
+		if advance.HeadersCount > 0 {
+			headersGas += advance.GasUsed
+			headers += uint64(advance.HeadersCount)
+		} else {
+			updatesGas += advance.GasUsed
+			updates++
+		}
+	}
+
+	if headers > 0 {
+		estimates.GasPerHeader = float64(headersGas) / float64(headers)
+	}
+
+	if updates > 0 {
+		estimates.GasPerBestHeaderUpdate = float64(updatesGas) / float64(updates)
+	}
+
+	if len(advances) > 1 {
+		first, last := advances[0], advances[len(advances)-1]
+		if last.BlockNumber > first.BlockNumber {
+			estimates.HostBlockTime = last.Timestamp.Sub(first.Timestamp) /
+				time.Duration(last.BlockNumber-first.BlockNumber)
+		}
+	}
+
+	return estimates, nil
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum"
+	"github.com/keep-network/tbtc/relay/pkg/headerstore"
+	"github.com/keep-network/tbtc/relay/pkg/proof"
+	"github.com/keep-network/tbtc/relay/pkg/prover"
+	"github.com/urfave/cli"
+)
+
+const proofDescription = `
+Builds the SPV proof of the confirmed Bitcoin transaction with the given ID,
+in the format accepted by the tBTC Deposit contract. The proof is printed as
+JSON with hex-encoded fields for inspection, together with the ABI-encoded
+calldata of the provideBTCFundingProof call, ready to be submitted to the
+Deposit contract manually.
+
+The headers of the block including the transaction and of the blocks
+confirming it are served from the header store configured in the HeaderStore
+section of the config file. Headers missing in the store are fetched from the
+Bitcoin node.
+`
+
+// ProofCommand contains the definition of the proof command-line
+// sub-command.
+var ProofCommand = cli.Command{
+	Name:        "proof",
+	Usage:       `Builds the SPV proof of a Bitcoin transaction`,
+	Description: proofDescription,
+	Action:      Proof,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "tx",
+			Usage: "ID of the proven transaction",
+		},
+		cli.IntFlag{
+			Name:  "confirmations",
+			Value: prover.DefaultConfirmations,
+			Usage: "number of headers included in the proof",
+		},
+		cli.UintFlag{
+			Name:  "output-index",
+			Usage: "index of the output funding the deposit",
+		},
+	},
+}
+
+// proofOutput is the printed SPV proof.
+type proofOutput struct {
+	TxID           string `json:"txid"`
+	BlockHeight    int64  `json:"blockHeight"`
+	TxVersion      string `json:"txVersion"`
+	TxInputVector  string `json:"txInputVector"`
+	TxOutputVector string `json:"txOutputVector"`
+	TxLocktime     string `json:"txLocktime"`
+	OutputIndex    uint8  `json:"fundingOutputIndex"`
+	MerkleProof    string `json:"merkleProof"`
+	TxIndexInBlock uint64 `json:"txIndexInBlock"`
+	BitcoinHeaders string `json:"bitcoinHeaders"`
+	// Calldata is the ABI-encoded calldata of the provideBTCFundingProof
+	// call of the Deposit contract.
+	Calldata string `json:"calldata"`
+}
+
+// Proof prints the SPV proof of the given transaction.
+func Proof(c *cli.Context) error {
+	ctx := context.Background()
+
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	txID, err := chainhash.NewHashFromStr(c.String("tx"))
+	if err != nil {
+		return fmt.Errorf("invalid transaction ID: [%v]", err)
+	}
+
+	outputIndex := c.Uint("output-index")
+	if outputIndex > math.MaxUint8 {
+		return fmt.Errorf("funding output index [%v] is too big", outputIndex)
+	}
+
+	btcChain, err := btc.Connect(ctx, &config.Bitcoin, nil)
+	if err != nil {
+		return fmt.Errorf("could not connect BTC chain: [%v]", err)
+	}
+
+	if config.HeaderStore.IsEnabled() {
+		headerStore, err := headerstore.Open(&config.HeaderStore)
+		if err != nil {
+			return err
+		}
+		defer headerStore.Close()
+
+		btcChain, err = headerstore.WrapChain(ctx, btcChain, headerStore)
+		if err != nil {
+			return err
+		}
+	}
+
+	bundle, err := proof.FetchTransactionProof(ctx, btcChain, btc.Digest(*txID))
+	if err != nil {
+		return fmt.Errorf("could not get merkle proof: [%v]", err)
+	}
+
+	spvProof, err := proof.BuildSPVProof(
+		ctx,
+		btcChain,
+		bundle,
+		c.Int("confirmations"),
+	)
+	if err != nil {
+		return fmt.Errorf("could not build SPV proof: [%v]", err)
+	}
+
+	calldata, err := ethereum.PackFundingProof(
+		&chain.TransactionProof{
+			TxVersion:      spvProof.TxVersion,
+			TxInputVector:  spvProof.TxInputVector,
+			TxOutputVector: spvProof.TxOutputVector,
+			TxLocktime:     spvProof.TxLocktime,
+			MerkleProof:    spvProof.MerkleProof,
+			TxIndexInBlock: spvProof.TxIndexInBlock,
+			BitcoinHeaders: spvProof.BitcoinHeaders,
+		},
+		uint8(outputIndex),
+	)
+	if err != nil {
+		return fmt.Errorf("could not encode calldata: [%v]", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(&proofOutput{
+		TxID:           txID.String(),
+		BlockHeight:    spvProof.BlockHeight,
+		TxVersion:      hexutil.Encode(spvProof.TxVersion),
+		TxInputVector:  hexutil.Encode(spvProof.TxInputVector),
+		TxOutputVector: hexutil.Encode(spvProof.TxOutputVector),
+		TxLocktime:     hexutil.Encode(spvProof.TxLocktime),
+		OutputIndex:    uint8(outputIndex),
+		MerkleProof:    hexutil.Encode(spvProof.MerkleProof),
+		TxIndexInBlock: spvProof.TxIndexInBlock,
+		BitcoinHeaders: hexutil.Encode(spvProof.BitcoinHeaders),
+		Calldata:       hexutil.Encode(calldata),
+	})
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/config"
+	"github.com/keep-network/tbtc/relay/pkg/headerstore"
+	"github.com/keep-network/tbtc/relay/pkg/history"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+	"github.com/urfave/cli"
+)
+
+const pruneDescription = `
+Prunes the local data of each relay target according to the retention
+policies set in the config file and compacts the databases, returning the
+freed space to the file system:
+
+- the journal kept in Storage.DataDir is pruned to Storage.JournalMaxEntries
+  entries and Storage.JournalRetentionDays days,
+- the header store is pruned to HeaderStore.RetainHeaders headers,
+- the metrics history is pruned to History.MaxSamples samples and
+  History.RetentionDays days.
+
+The relay prunes its data while running as well, but databases are compacted
+only by this command. The relay should be stopped while the command runs.
+`
+
+// PruneCommand contains the definition of the prune command-line
+// sub-command.
+var PruneCommand = cli.Command{
+	Name:        "prune",
+	Usage:       `Prunes the local relay data`,
+	Description: pruneDescription,
+	Action:      Prune,
+}
+
+// Prune prunes the local data of all relay targets.
+func Prune(c *cli.Context) error {
+	relayConfig, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	for _, target := range relayConfig.RelayTargets() {
+		if err := pruneTarget(target); err != nil {
+			return fmt.Errorf(
+				"could not prune data of target [%v]: [%v]",
+				target.Name,
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+func pruneTarget(target *config.Target) error {
+	now := time.Now()
+
+	if len(target.Storage.DataDir) > 0 {
+		relayStore, err := store.Open(&target.Storage)
+		if err != nil {
+			return fmt.Errorf("could not open relay store: [%v]", err)
+		}
+
+		path := filepath.Join(target.Storage.DataDir, "journal.json")
+		sizeBefore := fileSize(path)
+
+		if err := relayStore.PruneJournal(now); err != nil {
+			return fmt.Errorf("could not prune journal: [%v]", err)
+		}
+
+		printPruned("journal", path, sizeBefore)
+	}
+
+	if target.HeaderStore.IsEnabled() && target.HeaderStore.RetainHeaders > 0 {
+		headerStore, err := headerstore.Open(&target.HeaderStore)
+		if err != nil {
+			return err
+		}
+		defer headerStore.Close()
+
+		path := target.HeaderStore.File
+		sizeBefore := fileSize(path)
+
+		pruned, err := headerStore.Prune(target.HeaderStore.RetainHeaders)
+		if err != nil {
+			return err
+		}
+		logger.Infof("pruned [%v] headers from the header store", pruned)
+
+		if err := headerStore.Compact(); err != nil {
+			return err
+		}
+
+		printPruned("header store", path, sizeBefore)
+	}
+
+	if target.History.IsEnabled() {
+		relayHistory, err := history.Open(&target.History)
+		if err != nil {
+			return err
+		}
+		defer relayHistory.Close()
+
+		path := target.History.File
+		sizeBefore := fileSize(path)
+
+		if err := relayHistory.Prune(now); err != nil {
+			return err
+		}
+
+		if err := relayHistory.Compact(); err != nil {
+			return err
+		}
+
+		printPruned("metrics history", path, sizeBefore)
+	}
+
+	return nil
+}
+
+// printPruned prints the size of the given file before and after pruning.
+func printPruned(name string, path string, sizeBefore int64) {
+	fmt.Printf(
+		"pruned %v [%v]: [%v] -> [%v] bytes\n",
+		name,
+		path,
+		sizeBefore,
+		fileSize(path),
+	)
+}
+
+// fileSize returns the size of the given file or zero if the file does not
+// exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/prover"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+	"github.com/urfave/cli"
+)
+
+const reorgsDescription = `
+Lists the Bitcoin reorgs observed by the relay, as recorded in the data
+directory configured in the Storage section of the config file. For each
+reorg, the last common ancestor of the replaced and the new branch, the
+replaced headers and the new best header are printed.
+
+The summary recommends the number of confirmations of the transactions proven
+to the host chain, exceeding the depth of the deepest observed reorg.
+`
+
+// ReorgsCommand contains the definition of the reorgs command-line
+// sub-command.
+var ReorgsCommand = cli.Command{
+	Name:        "reorgs",
+	Usage:       `Lists the observed Bitcoin reorgs`,
+	Description: reorgsDescription,
+	Action:      Reorgs,
+}
+
+// Reorgs prints the reorg history of the relay.
+func Reorgs(c *cli.Context) error {
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	if len(config.Storage.DataDir) == 0 {
+		return fmt.Errorf("data directory is not configured")
+	}
+
+	relayStore, err := store.Open(&config.Storage)
+	if err != nil {
+		return fmt.Errorf("could not open relay store: [%v]", err)
+	}
+
+	reorgs, err := relayStore.LoadReorgs()
+	if err != nil {
+		return err
+	}
+
+	for _, reorg := range reorgs {
+		replacedHashes := make([]string, len(reorg.ReplacedDigests))
+		for i, digest := range reorg.ReplacedDigests {
+			replacedHashes[i] = digest.String()
+		}
+
+		if _, err := fmt.Fprintf(
+			os.Stdout,
+			"%v: depth %v above header %v (%v)\n"+
+				"  replaced: %v\n"+
+				"  new best: header %v (%v)\n",
+			reorg.ObservedAt.Format(time.RFC3339),
+			reorg.Depth,
+			reorg.AncestorHeight,
+			reorg.AncestorDigest,
+			strings.Join(replacedHashes, ", "),
+			reorg.NewBestHeight,
+			reorg.NewBestDigest,
+		); err != nil {
+			return err
+		}
+	}
+
+	stats := store.SummarizeReorgs(reorgs, prover.DefaultConfirmations)
+
+	_, err = fmt.Fprintf(
+		os.Stdout,
+		"reorgs:                    %v\n"+
+			"depth:                     average %.2f, max %v blocks\n"+
+			"recommended confirmations: %v\n",
+		stats.Count,
+		stats.AverageDepth,
+		stats.MaxDepth,
+		stats.RecommendedConfirmations,
+	)
+	return err
+}
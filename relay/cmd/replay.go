@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+	"github.com/urfave/cli"
+)
+
+const replayDescription = `
+Re-executes the headers batches recorded in a relay journal, e.g. the
+'journal.json' file copied from the Storage.DataDir directory of a production
+deployment, to reproduce contract-side rejections deterministically.
+
+Each batch is rebuilt from the Bitcoin node configured in the config file and
+submitted with the same relay contract call the relay made. Batches are
+submitted to the local chain mock if '--local' is set or to the host chain
+node at '--fork-url', which should be a fork of the host chain, like the one
+started by 'anvil --fork-url' or 'npx hardhat node --fork', at the block just
+before the incident. The operator key configured in the config file signs the
+submissions. The journal and the relay contract of the configured host chain
+are never modified.
+`
+
+// ReplayCommand contains the definition of the replay command-line
+// sub-command.
+var ReplayCommand = cli.Command{
+	Name:        "replay",
+	Usage:       `Re-executes journaled headers batches`,
+	Description: replayDescription,
+	Action:      Replay,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "journal",
+			Usage: "journal file with the batches to replay",
+		},
+		cli.StringFlag{
+			Name:  "batch",
+			Usage: "ID of the only batch to replay; all batches by default",
+		},
+		cli.BoolFlag{
+			Name:  "local",
+			Usage: "replay against the local chain mock",
+		},
+		cli.StringFlag{
+			Name:  "fork-url",
+			Usage: "URL of the host chain fork node to replay against",
+		},
+	},
+}
+
+// Replay re-executes the journaled headers batches against a local chain
+// mock or a fork of the host chain.
+func Replay(c *cli.Context) error {
+	if c.String("journal") == "" {
+		return fmt.Errorf("journal file must be set")
+	}
+
+	if c.Bool("local") == (c.String("fork-url") != "") {
+		return fmt.Errorf("exactly one of --local and --fork-url must be set")
+	}
+
+	entries, err := store.ReadJournal(c.String("journal"))
+	if err != nil {
+		return err
+	}
+
+	if batchID := c.String("batch"); batchID != "" {
+		entries = selectBatch(entries, store.BatchID(batchID))
+		if len(entries) == 0 {
+			return fmt.Errorf("batch [%v] is not journaled", batchID)
+		}
+	}
+
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	ctx := context.Background()
+
+	btcChain, err := btc.Connect(ctx, &config.Bitcoin, nil)
+	if err != nil {
+		return fmt.Errorf("could not connect BTC chain: [%v]", err)
+	}
+
+	var hostChain chain.Handle
+	if c.Bool("local") {
+		hostChain, err = chainlocal.Connect()
+	} else {
+		forkConfig := config.Ethereum
+		forkConfig.URL = c.String("fork-url")
+		hostChain, err = connectEthereum(forkConfig, false)
+	}
+	if err != nil {
+		return fmt.Errorf("could not connect host chain: [%v]", err)
+	}
+
+	failed := 0
+	for _, entry := range entries {
+		err := header.ReplayBatch(ctx, btcChain, hostChain, entry)
+		if err != nil {
+			failed++
+			fmt.Printf(
+				"batch [%v] with headers [%v-%v]: failed: [%v]\n",
+				entry.ID,
+				entry.FirstHeight,
+				entry.LastHeight,
+				err,
+			)
+			continue
+		}
+
+		fmt.Printf(
+			"batch [%v] with headers [%v-%v]: submitted\n",
+			entry.ID,
+			entry.FirstHeight,
+			entry.LastHeight,
+		)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("[%v] of [%v] batches failed", failed, len(entries))
+	}
+
+	return nil
+}
+
+func selectBatch(
+	entries []*store.JournalEntry,
+	id store.BatchID,
+) []*store.JournalEntry {
+	for _, entry := range entries {
+		if entry.ID == id {
+			return []*store.JournalEntry{entry}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/history"
+	"github.com/keep-network/tbtc/relay/pkg/rewards"
+	"github.com/urfave/cli"
+)
+
+const reportDescription = `
+Summarizes the relay performance based on the metrics history recorded in the
+local SQLite database configured in the History section of the config file.
+
+The report also breaks down the pushes advancing the relay contract per day,
+showing the share of pushes made by this relay maintainer versus other
+relayers along with the number of pushes per submitter address, and compares
+the claimed relay rewards with the fees paid for the pushes.
+
+The period is given using the '--since' flag either as a number of days,
+like '7d', or as a duration, like '12h'.
+`
+
+// ReportCommand contains the definition of the report command-line
+// sub-command.
+var ReportCommand = cli.Command{
+	Name:        "report",
+	Usage:       `Summarizes the relay performance`,
+	Description: reportDescription,
+	Action:      Report,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "since",
+			Value: "7d",
+			Usage: "period of the report, like 7d or 12h",
+		},
+	},
+}
+
+// Report prints the summary of the relay performance.
+func Report(c *cli.Context) error {
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	if !config.History.IsEnabled() {
+		return fmt.Errorf("metrics history is not configured")
+	}
+
+	since, err := history.ParseSince(c.String("since"), time.Now())
+	if err != nil {
+		return err
+	}
+
+	relayHistory, err := history.Open(&config.History)
+	if err != nil {
+		return err
+	}
+	defer relayHistory.Close()
+
+	samples, err := relayHistory.Samples(since)
+	if err != nil {
+		return err
+	}
+
+	if err := history.Summarize(since, samples).Print(os.Stdout); err != nil {
+		return err
+	}
+
+	advances, err := relayHistory.Advances(since)
+	if err != nil {
+		return err
+	}
+
+	if err := competition.PrintDaily(
+		os.Stdout,
+		competition.SummarizeDaily(advances),
+	); err != nil {
+		return err
+	}
+
+	claims, err := relayHistory.Claims(since)
+	if err != nil {
+		return err
+	}
+
+	gasExpenditure, err := relayHistory.GasExpenditure(since)
+	if err != nil {
+		return err
+	}
+
+	return rewards.Summarize(claims, gasExpenditure).Print(os.Stdout)
+}
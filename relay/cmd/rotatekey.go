@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+	"github.com/keep-network/tbtc/relay/config"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+	"github.com/urfave/cli"
+)
+
+// NewPasswordEnvVariable is the environment variable holding the password of
+// the key file the operator account is rotated to.
+const NewPasswordEnvVariable = "NEW_OPERATOR_KEY_FILE_PASSWORD"
+
+// Interval in which the rotation status is polled.
+const rotationPollInterval = 5 * time.Second
+
+const rotateKeyDescription = `
+Rotates the operator account of the running relay maintainer to the account
+of the given key file without a restart.
+
+The relay registers the new key, waits until no headers batch or proof is
+being submitted, holds the new submissions and waits until all transactions
+of the old account are mined. Only then the submissions are switched to the
+new account and the journal of the old account is archived in the data
+directory. If the transactions of the old account are not mined within the
+timeout, the rotation is aborted and the old account is kept.
+
+The key file must be readable by the relay. Its password should be provided
+as ` + NewPasswordEnvVariable + ` environment variable. Once the rotation
+completes, update Ethereum.Account.KeyFile in the config file so the relay
+uses the new account after a restart.
+`
+
+// RotateKeyCommand contains the definition of the rotate-key command-line
+// sub-command.
+var RotateKeyCommand = cli.Command{
+	Name:        "rotate-key",
+	Usage:       `Rotates the operator account of the running relay`,
+	ArgsUsage:   "<key-file>",
+	Description: rotateKeyDescription,
+	Flags: append([]cli.Flag{
+		cli.IntFlag{
+			Name:  "timeout",
+			Usage: "time in seconds after which the rotation is aborted",
+			Value: 600,
+		},
+		cli.BoolFlag{
+			Name:  "no-wait",
+			Usage: "do not wait until the rotation completes",
+		},
+	}, adminFlags...),
+	Action: RotateKey,
+}
+
+// RotateKey starts the rotation of the operator account of the running
+// relay and waits until it completes.
+func RotateKey(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected key file argument")
+	}
+
+	client, err := newAPIClient(c)
+	if err != nil {
+		return err
+	}
+
+	status, err := client.RotateKey(
+		c.Args().Get(0),
+		os.Getenv(NewPasswordEnvVariable),
+		time.Duration(c.Int("timeout"))*time.Second,
+	)
+	if err != nil {
+		return fmt.Errorf("could not start key rotation: [%v]", err)
+	}
+
+	fmt.Printf(
+		"rotating operator account [%v] to [%v]\n",
+		status.OldAddress,
+		status.NewAddress,
+	)
+
+	if c.Bool("no-wait") {
+		return nil
+	}
+
+	pending := -1
+	for status.State == chain.RotationDraining {
+		if status.PendingTransactions != pending {
+			pending = status.PendingTransactions
+			fmt.Printf("waiting for [%v] pending transactions\n", pending)
+		}
+
+		time.Sleep(rotationPollInterval)
+
+		status, err = client.KeyRotationStatus()
+		if err != nil {
+			return fmt.Errorf("could not get key rotation status: [%v]", err)
+		}
+	}
+
+	if status.State != chain.RotationCompleted {
+		return fmt.Errorf("key rotation failed: [%v]", status.Error)
+	}
+
+	fmt.Printf("operator account rotated to [%v]\n", status.NewAddress)
+
+	return nil
+}
+
+// keyRotator rotates the operator account of a running relay target.
+type keyRotator struct {
+	ctx        context.Context
+	config     *config.Target
+	hostChain  *chain.RotatingHandle
+	relayStore *store.Store
+}
+
+func (kr *keyRotator) RotateKey(
+	keyFile string,
+	password string,
+	timeout time.Duration,
+) error {
+	if kr.config.Relay.WatchOnly {
+		return fmt.Errorf("relay runs in the watch-only mode")
+	}
+
+	if _, err := ethutil.DecryptKeyFile(keyFile, password); err != nil {
+		return fmt.Errorf("could not read key file [%v]: [%v]", keyFile, err)
+	}
+
+	rotatedConfig := *kr.config
+	rotatedConfig.Ethereum.Account.KeyFile = keyFile
+	rotatedConfig.Ethereum.Account.KeyFilePassword = password
+
+	newHostChain, err := connectHostChain(kr.ctx, &rotatedConfig)
+	if err != nil {
+		return fmt.Errorf("could not connect host chain: [%v]", err)
+	}
+
+	if strings.EqualFold(
+		newHostChain.OperatorAddress(),
+		kr.hostChain.OperatorAddress(),
+	) {
+		return fmt.Errorf(
+			"account [%v] is already the operator account",
+			newHostChain.OperatorAddress(),
+		)
+	}
+
+	if err := kr.hostChain.StartRotation(newHostChain); err != nil {
+		return err
+	}
+
+	logger.Warnf(
+		"rotating operator account [%v] to [%v]; waiting for the pending "+
+			"transactions",
+		kr.hostChain.OperatorAddress(),
+		newHostChain.OperatorAddress(),
+	)
+
+	go func() {
+		ctx, cancelCtx := context.WithTimeout(kr.ctx, timeout)
+		defer cancelCtx()
+
+		if err := kr.hostChain.Rotate(
+			ctx,
+			newHostChain,
+			kr.archiveJournal,
+		); err != nil {
+			logger.Errorf(
+				"could not rotate operator account; keeping the old "+
+					"account: [%v]",
+				err,
+			)
+			return
+		}
+
+		logger.Warnf(
+			"operator account rotated to [%v]; update the key file in the "+
+				"config file",
+			newHostChain.OperatorAddress(),
+		)
+	}()
+
+	return nil
+}
+
+func (kr *keyRotator) RotationStatus() *chain.RotationStatus {
+	return kr.hostChain.Status()
+}
+
+func (kr *keyRotator) archiveJournal(oldHandle, newHandle chain.Handle) error {
+	archive, err := kr.relayStore.ArchiveJournal(
+		oldHandle.OperatorAddress(),
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not archive journal: [%v]", err)
+	}
+
+	logger.Infof(
+		"archived journal of operator account [%v] as [%v]",
+		oldHandle.OperatorAddress(),
+		archive,
+	)
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/headerstore"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+	"github.com/urfave/cli"
+)
+
+const snapshotDescription = `
+Manages signed snapshots of historical Bitcoin headers used to bootstrap the
+local header store configured in the HeaderStore section of the config file.
+
+A snapshot is imported only if it is signed by one of the keys listed in
+HeaderStore.SnapshotKeys, its headers form a valid chain with enough proof of
+work and it passes through at least one known checkpoint: one built into the
+network parameters, one of HeaderStore.Checkpoints or the relay checkpoint
+kept in Storage.DataDir.
+`
+
+// SnapshotCommand contains the definition of the snapshot command-line
+// sub-command.
+var SnapshotCommand = cli.Command{
+	Name:        "snapshot",
+	Usage:       `Manages header snapshots`,
+	Description: snapshotDescription,
+	Subcommands: []cli.Command{
+		{
+			Name:   "import",
+			Usage:  "Verifies the snapshot and imports it to the header store",
+			Action: SnapshotImport,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "file",
+					Usage: "snapshot file to import",
+				},
+			},
+		},
+		{
+			Name:   "create",
+			Usage:  "Creates a signed snapshot using the Bitcoin node",
+			Action: SnapshotCreate,
+			Flags: []cli.Flag{
+				cli.Int64Flag{
+					Name:  "from",
+					Usage: "height of the first snapshot header",
+				},
+				cli.Int64Flag{
+					Name:  "to",
+					Usage: "height of the last snapshot header",
+				},
+				cli.StringFlag{
+					Name:  "key-file",
+					Usage: "file with the hex-encoded Ed25519 signing key",
+				},
+				cli.StringFlag{
+					Name:  "file",
+					Usage: "snapshot file to create",
+				},
+			},
+		},
+		{
+			Name:   "keygen",
+			Usage:  "Generates a new snapshot signing key",
+			Action: SnapshotKeygen,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "key-file",
+					Usage: "file the hex-encoded Ed25519 signing key is written to",
+				},
+			},
+		},
+	},
+}
+
+// SnapshotImport verifies the given snapshot and imports its headers to the
+// header store.
+func SnapshotImport(c *cli.Context) error {
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	if !config.HeaderStore.IsEnabled() {
+		return fmt.Errorf("header store is not configured")
+	}
+
+	params, err := btc.ConfigNetworkParams(&config.Bitcoin)
+	if err != nil {
+		return err
+	}
+
+	checkpoints, err := btc.ParseCheckpoints(config.HeaderStore.Checkpoints)
+	if err != nil {
+		return fmt.Errorf("invalid header store checkpoints: [%v]", err)
+	}
+	checkpoints = append(checkpoints, btc.BuiltinCheckpoints(params)...)
+
+	relayStore, err := store.Open(&config.Storage)
+	if err != nil {
+		return fmt.Errorf("could not open relay store: [%v]", err)
+	}
+
+	relayCheckpoint, err := relayStore.LoadCheckpoint()
+	if err != nil {
+		return fmt.Errorf("could not load relay checkpoint: [%v]", err)
+	}
+
+	if relayCheckpoint != nil {
+		checkpoints = append(checkpoints, &btc.Checkpoint{
+			Height: relayCheckpoint.Height,
+			Digest: relayCheckpoint.Digest,
+		})
+	}
+
+	snapshot, err := headerstore.ReadSnapshot(c.String("file"))
+	if err != nil {
+		return err
+	}
+
+	headers, err := snapshot.Verify(
+		params,
+		config.HeaderStore.SnapshotKeys,
+		checkpoints,
+	)
+	if err != nil {
+		return fmt.Errorf("could not verify snapshot: [%v]", err)
+	}
+
+	headerStore, err := headerstore.Open(&config.HeaderStore)
+	if err != nil {
+		return err
+	}
+	defer headerStore.Close()
+
+	if err := headerStore.Put(headers); err != nil {
+		return fmt.Errorf("could not import snapshot: [%v]", err)
+	}
+
+	fmt.Printf(
+		"imported [%v] headers from [%v] to [%v]\n",
+		len(headers),
+		headers[0].Height,
+		headers[len(headers)-1].Height,
+	)
+
+	return nil
+}
+
+// SnapshotCreate creates a snapshot of the given range of headers fetched
+// from the Bitcoin node and signs it with the given key.
+func SnapshotCreate(c *cli.Context) error {
+	ctx := context.Background()
+
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	from, to := c.Int64("from"), c.Int64("to")
+	if from < 0 || to < from {
+		return fmt.Errorf("invalid range of heights from [%v] to [%v]", from, to)
+	}
+
+	privateKey, err := readSigningKey(c.String("key-file"))
+	if err != nil {
+		return err
+	}
+
+	btcChain, err := btc.Connect(ctx, &config.Bitcoin, nil)
+	if err != nil {
+		return fmt.Errorf("could not connect BTC chain: [%v]", err)
+	}
+
+	headers := make([]*btc.Header, 0, to-from+1)
+	for height := from; height <= to; height++ {
+		header, err := btcChain.GetHeaderByHeight(ctx, height)
+		if err != nil {
+			return fmt.Errorf("could not get header [%v]: [%v]", height, err)
+		}
+
+		headers = append(headers, header)
+	}
+
+	snapshot := headerstore.NewSnapshot(btcChain.NetworkParams(), headers)
+	if err := snapshot.Sign(privateKey); err != nil {
+		return fmt.Errorf("could not sign snapshot: [%v]", err)
+	}
+
+	if err := snapshot.Write(c.String("file")); err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"created snapshot of [%v] headers signed by key [%v]\n",
+		len(headers),
+		snapshot.PublicKey,
+	)
+
+	return nil
+}
+
+// SnapshotKeygen generates a new Ed25519 snapshot signing key.
+func SnapshotKeygen(c *cli.Context) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("could not generate key: [%v]", err)
+	}
+
+	if err := ioutil.WriteFile(
+		c.String("key-file"),
+		[]byte(hex.EncodeToString(privateKey.Seed())),
+		0600,
+	); err != nil {
+		return fmt.Errorf("could not write key file: [%v]", err)
+	}
+
+	fmt.Printf("public key: %v\n", hex.EncodeToString(publicKey))
+
+	return nil
+}
+
+func readSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file [%v]: [%v]", path, err)
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("key file [%v] has invalid format", path)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -3,13 +3,14 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"strings"
 	"time"
 
 	"github.com/keep-network/tbtc/relay/pkg/metrics"
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 
-	commoneth "github.com/keep-network/keep-common/pkg/chain/ethereum"
 	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
 	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum"
 
@@ -17,96 +18,1455 @@ import (
 
 	"github.com/ipfs/go-log"
 	"github.com/keep-network/tbtc/relay/config"
+	"github.com/keep-network/tbtc/relay/pkg/api"
+	"github.com/keep-network/tbtc/relay/pkg/build"
 	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+	"github.com/keep-network/tbtc/relay/pkg/events"
+	"github.com/keep-network/tbtc/relay/pkg/fraud"
+	"github.com/keep-network/tbtc/relay/pkg/gasusage"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/headerstore"
+	"github.com/keep-network/tbtc/relay/pkg/history"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/proof"
+	"github.com/keep-network/tbtc/relay/pkg/prover"
+	"github.com/keep-network/tbtc/relay/pkg/quorum"
+	"github.com/keep-network/tbtc/relay/pkg/rewards"
+	"github.com/keep-network/tbtc/relay/pkg/service"
+	"github.com/keep-network/tbtc/relay/pkg/slo"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+	"github.com/keep-network/tbtc/relay/pkg/summary"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+	"github.com/keep-network/tbtc/relay/pkg/trigger"
+	"github.com/keep-network/tbtc/relay/pkg/webhook"
 	"github.com/urfave/cli"
 )
 
 var logger = log.Logger("tbtc-relay-cmd")
 
+// Maximum time for which the relay nodes can persist their state once the
+// relay maintainer is requested to stop.
+const shutdownTimeout = 30 * time.Second
+
+// Maximum time for which the run summaries can be posted to the webhooks once
+// the relay is stopped.
+const summaryWebhookTimeout = 10 * time.Second
+
 const startDescription = `
 Starts the relay maintainer in the foreground.
 
-It requires the password of the operator host chain key file to be provided
-as ` + config.PasswordEnvVariable + ` environment variable.
-`
+It requires the password of the operator host chain key file to be provided
+as ` + config.PasswordEnvVariable + ` environment variable or set as
+Ethereum.Account.KeyFilePassword in the secrets file.
+
+If no operator key file is configured or the watch-only mode is enabled
+explicitly in the config file, the relay maintainer only observes both chains
+and never submits any transactions.
+
+If independent relay targets are configured, all of them are run by the same
+process. The operator key file password of a named target can be provided as
+` + config.PasswordEnvVariable + `_<NAME> environment variable.
+`
+
+// StartCommand contains the definition of the start command-line sub-command.
+var StartCommand = cli.Command{
+	Name:        "start",
+	Usage:       `Starts the relay maintainer in the foreground`,
+	Description: startDescription,
+	Action:      Start,
+}
+
+// Start starts the relay maintainer.
+func Start(c *cli.Context) error {
+	return service.Run("tbtc-relay", func(ctx context.Context) error {
+		return start(ctx, c)
+	})
+}
+
+func start(ctx context.Context, c *cli.Context) error {
+	config, err := readConfig(c)
+	if err != nil {
+		return fmt.Errorf("could not read config file: [%v]", err)
+	}
+
+	updateChecker := initializeUpdateChecker(ctx, config)
+
+	targets := config.RelayTargets()
+
+	logTail := initializeLogTail(targets)
+
+	stats := make([]service.RelayStats, len(targets))
+	running := make([]*runningTarget, len(targets))
+	for i, target := range targets {
+		runningTarget, err := startTarget(
+			ctx,
+			target,
+			&config.Service,
+			updateChecker,
+			logTail,
+		)
+		if err != nil {
+			if target.Name == "" {
+				return err
+			}
+
+			return fmt.Errorf(
+				"could not start relay target [%v]: [%v]",
+				target.Name,
+				err,
+			)
+		}
+
+		stats[i] = runningTarget.node.Stats()
+		running[i] = runningTarget
+	}
+
+	go service.Supervise(ctx, &config.Service, service.CombineStats(stats...))
+
+	logger.Infof("relay [%v] started", build.Version)
+
+	<-ctx.Done()
+
+	logger.Infof("stopping relay")
+
+	err = waitForStop(running)
+
+	for _, target := range running {
+		reportSummary(target)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("relay stopped")
+
+	return nil
+}
+
+// runningTarget is a relay target run by the relay process.
+type runningTarget struct {
+	config   *config.Target
+	node     *node.Node
+	summary  *summary.Tracker
+	webhooks *webhook.Dispatcher
+}
+
+// waitForStop waits until the relay nodes of all the given relay targets
+// persist their state, but no longer than shutdownTimeout.
+func waitForStop(running []*runningTarget) error {
+	shutdownDeadline := time.After(shutdownTimeout)
+	for _, target := range running {
+		select {
+		case <-target.node.Stopped():
+		case <-shutdownDeadline:
+			return fmt.Errorf(
+				"relay did not stop within [%v]",
+				shutdownTimeout,
+			)
+		}
+	}
+
+	return nil
+}
+
+// reportSummary logs the summary of the run of the given relay target,
+// appends it to the summary file and posts it to the webhooks if configured.
+func reportSummary(target *runningTarget) {
+	runSummary := target.summary.Summarize()
+	runSummary.Log(logger)
+
+	if file := target.config.Summary.File; file != "" {
+		if err := runSummary.AppendToFile(file); err != nil {
+			logger.Errorf("could not write run summary: [%v]", err)
+		}
+	}
+
+	if !target.config.Summary.Webhook {
+		return
+	}
+
+	if target.webhooks == nil {
+		logger.Warnf("webhooks are not enabled; run summary is not posted")
+		return
+	}
+
+	if err := target.webhooks.Send(
+		webhook.EventRunSummary,
+		runSummary,
+	); err != nil {
+		logger.Errorf("could not post run summary: [%v]", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		summaryWebhookTimeout,
+	)
+	defer cancel()
+
+	target.webhooks.Flush(ctx)
+}
+
+// startTarget starts the relay node of a single relay target along with all
+// the services attached to it.
+func startTarget(
+	ctx context.Context,
+	config *config.Target,
+	serviceConfig *service.Config,
+	updateChecker *build.UpdateChecker,
+	logTail *logs.Tail,
+) (*runningTarget, error) {
+	if config.Name != "" {
+		logger.Infof("starting relay target [%v]", config.Name)
+	}
+
+	btcChain, err := btc.Connect(ctx, &config.Bitcoin, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect BTC chain: [%v]", err)
+	}
+
+	checkBitcoinCapabilities(ctx, config, btcChain)
+
+	btcChain, queueStore, err := initializeHeaderStore(ctx, config, btcChain)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize header store: [%v]", err)
+	}
+
+	if !hasOperatorKey(config) && !config.Relay.WatchOnly &&
+		!config.Ethereum.Sponsorship.IsKeyless() {
+		logger.Warnf(
+			"operator key file is not configured; " +
+				"enabling the watch-only mode",
+		)
+		config.Relay.WatchOnly = true
+	}
+
+	hostChain, err := connectHostChain(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect host chain: [%v]", err)
+	}
+
+	relayStore, err := store.Open(&config.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("could not open relay store: [%v]", err)
+	}
+
+	webhooks := initializeWebhooks(ctx, config, relayStore)
+
+	pauseGuard := initializePauseGuard(ctx, config, hostChain, webhooks)
+
+	// Submissions go through the rotating handle so the operator account
+	// can be rotated without a restart.
+	rotatingHostChain := chain.NewRotatingHandle(hostChain)
+
+	capGasPriceCeiling(config)
+
+	submissionStats := &chain.SubmissionStats{}
+	hostChain = chain.WrapWriter(
+		rotatingHostChain,
+		writerMiddlewares(config, hostChain, submissionStats, pauseGuard)...,
+	)
+
+	relayHistory, err := initializeHistory(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not initialize metrics history: [%v]",
+			err,
+		)
+	}
+
+	gasUsageDetector := gasusage.NewDetector(&config.GasUsage, relayStore)
+
+	summarySources := &summary.Sources{
+		Submissions: submissionStats,
+		GasUsage:    gasUsageDetector,
+	}
+	summaryTracker := summary.NewTracker(config.Name, summarySources)
+
+	rewardsTracker, err := initializeRewardsTracker(
+		ctx,
+		config,
+		hostChain,
+		relayHistory,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not initialize rewards tracker: [%v]",
+			err,
+		)
+	}
+
+	quorumStage, err := initializeQuorum(ctx, config, btcChain)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize quorum mode: [%v]", err)
+	}
+
+	var pipeline *header.Pipeline
+	if quorumStage != nil {
+		pipeline = &header.Pipeline{Stages: []header.Stage{quorumStage}}
+	}
+
+	node := node.Initialize(
+		ctx,
+		btcChain,
+		hostChain,
+		relayStore,
+		&config.Relay,
+		initializeProfitabilityGate(config, rewardsTracker, gasUsageDetector),
+		queueStore,
+		pipeline,
+		targetLogger(config),
+	)
+	summarySources.Node = node.Stats()
+
+	if webhooks != nil {
+		webhooks.ForwardRelayEvents(ctx, node.Bus())
+	}
+
+	latencySLO := slo.NewTracker(&config.SLO)
+	latencySLO.Observe(ctx, node.Bus())
+
+	readiness := initializeReadiness(ctx, serviceConfig, node, webhooks)
+
+	if relayHistory != nil {
+		relayHistory.StartRecording(
+			ctx,
+			node.Stats(),
+			hostChain,
+			time.Duration(config.History.Tick)*time.Second,
+		)
+	}
+
+	competitionTracker := initializeCompetitionTracker(
+		ctx,
+		config,
+		hostChain,
+		relayHistory,
+		gasUsageDetector,
+		rewardsTracker,
+		summaryTracker,
+	)
+
+	fraudWatcher, err := initializeFraudWatcher(
+		ctx,
+		config,
+		btcChain,
+		hostChain,
+		webhooks,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not initialize relay contract watcher: [%v]",
+			err,
+		)
+	}
+	summarySources.Fraud = fraudWatcher
+
+	initializeMetrics(
+		ctx,
+		config,
+		btcChain,
+		hostChain,
+		submissionStats,
+		node.Stats(),
+		node.Bus(),
+		readiness,
+		competitionTracker,
+		gasUsageDetector,
+		latencySLO,
+		rewardsTracker,
+		fraudWatcher,
+		quorumStage,
+		updateChecker,
+		pauseGuard,
+	)
+
+	depositMonitor, err := initializeDepositMonitor(ctx, config, btcChain)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not initialize deposit monitor: [%v]",
+			err,
+		)
+	}
+
+	if webhooks != nil && depositMonitor != nil {
+		webhooks.ForwardDepositEvents(ctx, depositMonitor.Feed())
+	}
+
+	proofCache, err := initializeProofCache(ctx, config, btcChain, hostChain)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize proof cache: [%v]", err)
+	}
+
+	if err := initializeProver(
+		ctx,
+		config,
+		btcChain,
+		hostChain,
+		depositMonitor,
+		proofCache,
+		node.Control(),
+	); err != nil {
+		return nil, fmt.Errorf(
+			"could not initialize proofs submission: [%v]",
+			err,
+		)
+	}
+
+	if err := initializeTrigger(ctx, config, hostChain, node); err != nil {
+		return nil, fmt.Errorf(
+			"could not initialize low-latency trigger: [%v]",
+			err,
+		)
+	}
+
+	checkConfirmations(config, relayStore)
+
+	if err := initializeAPI(
+		ctx,
+		config,
+		node,
+		readiness,
+		relayStore,
+		depositMonitor,
+		proofCache,
+		&keyRotator{
+			ctx:        ctx,
+			config:     config,
+			hostChain:  rotatingHostChain,
+			relayStore: relayStore,
+		},
+	); err != nil {
+		return nil, fmt.Errorf("could not initialize API: [%v]", err)
+	}
+
+	if err := initializeAdminService(ctx, config, node, logTail); err != nil {
+		return nil, fmt.Errorf(
+			"could not initialize admin service: [%v]",
+			err,
+		)
+	}
+
+	if err := initializePublicAPI(
+		ctx,
+		config,
+		node,
+		readiness,
+		proofCache,
+	); err != nil {
+		return nil, fmt.Errorf("could not initialize public API: [%v]", err)
+	}
+
+	return &runningTarget{
+		config:   config,
+		node:     node,
+		summary:  summaryTracker,
+		webhooks: webhooks,
+	}, nil
+}
+
+// targetLogger returns the logger of the relay node of the given relay
+// target, tagging the messages with the target name so the relays of
+// different targets can be told apart. Nil is returned for an unnamed target,
+// so the default loggers are used.
+func targetLogger(config *config.Target) logs.Logger {
+	if config.Name == "" {
+		return nil
+	}
+
+	return log.Logger("tbtc-relay-header").With("target", config.Name)
+}
+
+func connectHostChain(
+	ctx context.Context,
+	config *config.Target,
+) (chain.Handle, error) {
+	// TODO: add support for multiple host chains (like Celo).
+	hostChain, err := connectEthereum(config.Ethereum, config.Relay.WatchOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	readCacheTTL := time.Duration(config.Ethereum.ReadCacheTTL) * time.Second
+
+	if config.Ethereum.ReadCacheEvents {
+		return chain.WrapEventReadCache(
+			ctx,
+			hostChain,
+			readCacheTTL,
+			log.Logger("tbtc-relay-read-cache"),
+		), nil
+	}
+
+	return chain.WrapReadCache(hostChain, readCacheTTL), nil
+}
+
+// initializePauseGuard starts tracking the emergency pause of the contracts
+// the relay submits transactions to. Pause changes are posted to the
+// webhooks, which may be nil.
+func initializePauseGuard(
+	ctx context.Context,
+	config *config.Target,
+	hostChain chain.Handle,
+	webhooks *webhook.Dispatcher,
+) *chain.PauseGuard {
+	var hook chain.PauseHook
+	if webhooks != nil {
+		hook = webhooks.PauseHook()
+	}
+
+	pauseGuard := chain.NewPauseGuard(
+		hostChain,
+		hook,
+		log.Logger("tbtc-relay-pause"),
+	)
+	pauseGuard.Start(ctx, time.Duration(config.Writer.PauseTick)*time.Second)
+
+	return pauseGuard
+}
+
+// capGasPriceCeiling lowers the gas price ceiling of the push schedule to the
+// maximum gas price of the writer. The gas gating passes header pushes
+// through, so they are deferred by the push schedule rather than failed.
+func capGasPriceCeiling(config *config.Target) {
+	maxGasPrice := config.Writer.MaxGasPrice
+	if maxGasPrice <= 0 {
+		return
+	}
+
+	if config.Relay.GasPriceCeiling == 0 ||
+		config.Relay.GasPriceCeiling > maxGasPrice {
+		logger.Infof(
+			"deferring pushes while gas price exceeds the writer "+
+				"maximum of [%v] Gwei",
+			maxGasPrice,
+		)
+		config.Relay.GasPriceCeiling = maxGasPrice
+	}
+}
+
+// writerMiddlewares returns the middlewares the host chain writer is wrapped
+// with. Submissions are logged and counted first so the ones rejected by the
+// pause guard or the gas gating or dropped by the dry run are accounted as
+// well.
+func writerMiddlewares(
+	config *config.Target,
+	gasOracle chain.GasOracle,
+	submissionStats *chain.SubmissionStats,
+	pauseGuard *chain.PauseGuard,
+) []chain.WriterMiddleware {
+	writerLogger := log.Logger("tbtc-relay-writer")
+
+	middlewares := []chain.WriterMiddleware{
+		chain.LoggingMiddleware(writerLogger),
+		chain.MetricsMiddleware(submissionStats),
+	}
+
+	if pauseGuard != nil {
+		middlewares = append(middlewares, chain.PauseMiddleware(pauseGuard))
+	}
+
+	if config.Writer.MaxGasPrice > 0 {
+		logger.Infof(
+			"rejecting submissions other than header pushes while "+
+				"gas price exceeds [%v] Gwei",
+			config.Writer.MaxGasPrice,
+		)
+
+		middlewares = append(middlewares, chain.GasGatingMiddleware(
+			gasOracle,
+			new(big.Int).Mul(
+				big.NewInt(config.Writer.MaxGasPrice),
+				big.NewInt(1e9),
+			),
+		))
+	}
+
+	if config.Writer.MinSubmissionInterval > 0 {
+		middlewares = append(middlewares, chain.RateLimitMiddleware(
+			time.Duration(config.Writer.MinSubmissionInterval)*time.Second,
+			clock.System,
+		))
+	}
+
+	if config.Writer.DryRun {
+		logger.Warnf("dry run enabled; transactions are never submitted")
+
+		middlewares = append(middlewares, chain.DryRunMiddleware(writerLogger))
+	}
+
+	return middlewares
+}
+
+// hasOperatorKey checks whether the operator key is configured. Without it,
+// only header pushes can be submitted and only if they are sent through
+// a meta-transaction relayer.
+func hasOperatorKey(config *config.Target) bool {
+	return len(config.Ethereum.Account.KeyFile) != 0
+}
+
+func connectEthereum(
+	config ethereum.Config,
+	watchOnly bool,
+) (chain.Handle, error) {
+	// Header pushes submitted through a meta-transaction relayer do not need
+	// the operator key.
+	if watchOnly || len(config.Account.KeyFile) == 0 {
+		return ethereum.Connect(nil, &config, nil)
+	}
+
+	key, err := ethutil.DecryptKeyFile(
+		config.Account.KeyFile,
+		config.Account.KeyFilePassword,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to read key file [%s]: [%v]",
+			config.Account.KeyFile,
+			err,
+		)
+	}
+
+	return ethereum.Connect(key, &config, nil)
+}
+
+func initializeAPI(
+	ctx context.Context,
+	config *config.Target,
+	node *node.Node,
+	readiness *service.Readiness,
+	relayStore *store.Store,
+	depositMonitor *deposit.Monitor,
+	proofCache *proof.Cache,
+	keyRotator api.KeyRotator,
+) error {
+	if !config.API.IsEnabled() {
+		logger.Infof("API is not configured")
+		return nil
+	}
+
+	server, err := api.NewServer(&config.API)
+	if err != nil {
+		return err
+	}
+
+	api.RegisterStatusHandler(server, node.Stats(), readiness)
+	api.RegisterAdminHandlers(server, node.Control())
+	api.RegisterKeyRotationHandler(server, keyRotator)
+	api.RegisterHeadersSubscriptionHandler(server, node.Feed())
+	api.RegisterReorgsHandler(
+		server,
+		relayStore,
+		prover.DefaultConfirmations,
+	)
+
+	if depositMonitor != nil {
+		api.RegisterDepositHandlers(server, depositMonitor)
+	}
+
+	if proofCache != nil {
+		api.RegisterProofHandler(server, proofCache)
+	}
+
+	return server.Start(ctx)
+}
+
+// initializeAdminService starts the gRPC admin service, if configured. Logs
+// are streamed only if log streaming is enabled.
+func initializeAdminService(
+	ctx context.Context,
+	config *config.Target,
+	node *node.Node,
+	logTail *logs.Tail,
+) error {
+	if config.API.AdminAddress == "" {
+		return nil
+	}
+
+	if !config.API.LogStreaming {
+		logTail = nil
+	}
+
+	server, err := api.NewAdminServer(&config.API, node.Control(), logTail)
+	if err != nil {
+		return err
+	}
+
+	return server.Start(ctx)
+}
+
+// initializePublicAPI starts the public API serving only the status and the
+// transaction proof endpoints, if configured.
+func initializePublicAPI(
+	ctx context.Context,
+	config *config.Target,
+	node *node.Node,
+	readiness *service.Readiness,
+	proofCache *proof.Cache,
+) error {
+	if !config.PublicAPI.IsEnabled() {
+		return nil
+	}
+
+	server, err := api.NewPublicServer(&config.PublicAPI)
+	if err != nil {
+		return err
+	}
+
+	api.RegisterStatusHandler(server, node.Stats(), readiness)
+
+	if proofCache != nil {
+		api.RegisterProofHandler(server, proofCache)
+	} else {
+		logger.Warnf(
+			"transaction proofs are not enabled; " +
+				"public API serves only the relay status",
+		)
+	}
+
+	return server.Start(ctx)
+}
+
+// initializeLogTail starts capturing the relay logs if log streaming is
+// enabled for the admin service of any relay target. The logs are captured
+// once per process, so all targets stream the same logs. Returns nil if log
+// streaming is not enabled.
+func initializeLogTail(targets []*config.Target) *logs.Tail {
+	enabled := false
+	for _, target := range targets {
+		if target.API.AdminAddress != "" && target.API.LogStreaming {
+			enabled = true
+		}
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	return logs.StartTail()
+}
+
+// initializeHeaderStore wraps the Bitcoin chain handle with the header store
+// if configured. The returned queue store is nil unless the header store is
+// configured and the headers queue persistence is enabled.
+func initializeHeaderStore(
+	ctx context.Context,
+	config *config.Target,
+	btcChain btc.Handle,
+) (btc.Handle, header.QueueStore, error) {
+	if !config.HeaderStore.IsEnabled() {
+		if config.HeaderStore.PersistQueue {
+			logger.Warnf(
+				"headers queue persistence requires the header store " +
+					"to be configured; queue will not be persisted",
+			)
+		} else {
+			logger.Infof("header store is not configured")
+		}
+		return btcChain, nil, nil
+	}
+
+	headerStore, err := headerstore.Open(&config.HeaderStore)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if retain := config.HeaderStore.RetainHeaders; retain > 0 {
+		pruned, err := headerStore.Prune(retain)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		logger.Infof("pruned [%v] headers from the header store", pruned)
+	}
+
+	wrappedChain, err := headerstore.WrapChain(ctx, btcChain, headerStore)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Leave the queue store nil instead of wrapping the header store if the
+	// persistence is disabled.
+	var queueStore header.QueueStore
+	if config.HeaderStore.PersistQueue {
+		logger.Infof("persisting headers queue in the header store")
+		queueStore = headerStore
+	}
+
+	return wrappedChain, queueStore, nil
+}
+
+// checkBitcoinCapabilities probes the optional capabilities of the Bitcoin
+// node and disables the features the node cannot support, so they do not
+// fail at runtime.
+func checkBitcoinCapabilities(
+	ctx context.Context,
+	config *config.Target,
+	btcChain btc.Handle,
+) {
+	prober, ok := btcChain.(btc.CapabilityProber)
+	if !ok {
+		return
+	}
+
+	capabilities, err := prober.ProbeCapabilities(ctx)
+	if err != nil {
+		logger.Warnf("could not probe Bitcoin node capabilities: [%v]", err)
+		return
+	}
+
+	logger.Infof("Bitcoin node capabilities: %v", capabilities)
+
+	if len(capabilities.UnavailableMethods) > 0 {
+		logger.Infof(
+			"Bitcoin node does not permit optional RPC methods [%v]",
+			strings.Join(capabilities.UnavailableMethods, ", "),
+		)
+	}
+
+	if len(capabilities.FailedProbes) > 0 {
+		logger.Warnf(
+			"could not probe optional RPC methods [%v] of Bitcoin node; "+
+				"assuming they are available",
+			strings.Join(capabilities.FailedProbes, ", "),
+		)
+	}
+
+	if config.Deposits.Enabled {
+		if supported, reason := capabilities.SupportsDepositMonitor(); !supported {
+			logger.Warnf("disabling deposit monitor: %v", reason)
+			config.Deposits.Enabled = false
+		} else if !capabilities.TxIndex {
+			logger.Warnf(
+				"Bitcoin node runs without the transaction index; " +
+					"deposit transactions confirmed before being seen " +
+					"in the mempool cannot be looked up",
+			)
+		}
+	}
+
+	if config.Proofs.Enabled {
+		if supported, reason := capabilities.SupportsProofs(); !supported {
+			if config.Proofs.Archive.IsEnabled() {
+				logger.Warnf(
+					"proofs are built using the archival source only: %v",
+					reason,
+				)
+			} else {
+				logger.Warnf("disabling transaction proofs: %v", reason)
+				config.Proofs.Enabled = false
+			}
+		}
+	}
+
+	if capabilities.Pruned &&
+		(config.Proofs.Enabled || config.Prover.IsFundingEnabled() ||
+			config.Prover.IsRedemptionEnabled()) {
+		logger.Warnf(
+			"Bitcoin node prunes blocks below [%v]; proofs of older "+
+				"transactions cannot be built",
+			capabilities.PruneHeight,
+		)
+	}
+
+	if !capabilities.TxIndex && (config.Prover.IsFundingEnabled() ||
+		config.Prover.IsRedemptionEnabled()) {
+		logger.Warnf(
+			"Bitcoin node runs without the transaction index; " +
+				"proofs of confirmed transactions may not be submitted",
+		)
+	}
+}
+
+func initializeDepositMonitor(
+	ctx context.Context,
+	config *config.Target,
+	btcChain btc.Handle,
+) (*deposit.Monitor, error) {
+	if !config.Deposits.Enabled {
+		logger.Infof("deposit monitor is not enabled")
+		return nil, nil
+	}
+
+	monitor, err := deposit.NewMonitor(btcChain, &config.Deposits)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor.Start(
+		ctx,
+		time.Duration(config.Deposits.MempoolTick)*time.Second,
+	)
+
+	return monitor, nil
+}
 
-// StartCommand contains the definition of the start command-line sub-command.
-var StartCommand = cli.Command{
-	Name:        "start",
-	Usage:       `Starts the relay maintainer in the foreground`,
-	Description: startDescription,
-	Action:      Start,
+// initializeProofCache starts caching merkle trees of the most recent blocks
+// if enabled. Returns nil if the proof cache is not enabled.
+func initializeProofCache(
+	ctx context.Context,
+	config *config.Target,
+	btcChain btc.Handle,
+	hostChain chain.Handle,
+) (*proof.Cache, error) {
+	if !config.Proofs.Enabled {
+		logger.Infof("proof cache is not enabled")
+		return nil, nil
+	}
+
+	cache := proof.NewCache(btcChain, config.Proofs.CachedBlocks)
+
+	if archive := &config.Proofs.Archive; archive.IsEnabled() {
+		var source proof.ArchiveSource
+		if archive.Esplora != "" {
+			source = proof.NewEsploraSource(archive.Esplora)
+		} else {
+			archiveChain, err := btc.Connect(ctx, &archive.Bitcoin, nil)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"could not connect archival Bitcoin node: [%v]",
+					err,
+				)
+			}
+
+			source = proof.NewChainSource(archiveChain)
+		}
+
+		// Proofs are served only for blocks known by the relay, so they can
+		// be validated against the relay contract.
+		cache.SetArchive(
+			source,
+			func(ctx context.Context, header *btc.Header) error {
+				if _, err := hostChain.FindHeight(ctx, header.Hash); err != nil {
+					return fmt.Errorf(
+						"block [%v] is not known by the relay: [%v]",
+						header.Hash,
+						err,
+					)
+				}
+
+				return nil
+			},
+		)
+
+		logger.Infof("falling back to [%v] for proofs of old blocks", source)
+	}
+
+	cache.Start(
+		ctx,
+		time.Duration(config.Proofs.PregenerationTick)*time.Second,
+	)
+
+	return cache, nil
 }
 
-// Start starts the relay maintainer.
-func Start(c *cli.Context) error {
-	ctx := context.Background()
+// initializeProver starts submitting funding and redemption proofs of the
+// configured deposits. Transactions are found by the deposit monitor, which
+// must be enabled. Headers awaited by the proofs are prioritized by the given
+// prioritizer.
+func initializeProver(
+	ctx context.Context,
+	config *config.Target,
+	btcChain btc.Handle,
+	hostChain chain.Handle,
+	depositMonitor *deposit.Monitor,
+	proofCache *proof.Cache,
+	prioritizer prover.Prioritizer,
+) error {
+	if !config.Prover.IsFundingEnabled() &&
+		!config.Prover.IsRedemptionEnabled() {
+		logger.Infof("proofs submission is not configured")
+		return nil
+	}
 
-	config, err := config.ReadConfig(c.GlobalString("config"))
+	if config.Relay.WatchOnly || !hasOperatorKey(config) {
+		return fmt.Errorf("proofs cannot be submitted in watch-only mode")
+	}
+
+	if depositMonitor == nil {
+		return fmt.Errorf("proofs submission requires the deposit monitor")
+	}
+
+	// Leave the proof source nil if the proof cache is disabled so proofs
+	// are built from blocks fetched from the Bitcoin chain.
+	var proofs prover.ProofSource
+	if proofCache != nil {
+		proofs = proofCache
+	}
+
+	if config.Prover.MaxGasPrice > 0 {
+		logger.Infof(
+			"not submitting proofs while gas price exceeds [%v] Gwei",
+			config.Prover.MaxGasPrice,
+		)
+
+		hostChain = chain.WrapWriter(hostChain, chain.GasGatingMiddleware(
+			hostChain,
+			new(big.Int).Mul(
+				big.NewInt(config.Prover.MaxGasPrice),
+				big.NewInt(1e9),
+			),
+		))
+	}
+
+	tick := time.Duration(config.Prover.Tick) * time.Second
+
+	if config.Prover.IsFundingEnabled() {
+		service, err := prover.NewFundingService(
+			btcChain,
+			hostChain,
+			depositMonitor,
+			proofs,
+			prioritizer,
+			&config.Prover,
+		)
+		if err != nil {
+			return err
+		}
+
+		service.Start(ctx, tick)
+
+		logger.Infof(
+			"submitting funding proofs of [%v] deposits",
+			len(config.Prover.Deposits),
+		)
+	}
+
+	if config.Prover.IsRedemptionEnabled() {
+		prover.NewRedemptionService(
+			btcChain,
+			hostChain,
+			depositMonitor,
+			proofs,
+			prioritizer,
+			&config.Prover,
+		).Start(ctx, tick)
+
+		logger.Infof(
+			"submitting redemption proofs of [%v] deposits",
+			len(config.Prover.Redemptions),
+		)
+	}
+
+	return nil
+}
+
+// checkConfirmations warns if proofs are submitted with fewer confirmations
+// than recommended given the reorgs observed by the relay so far.
+func checkConfirmations(config *config.Target, relayStore *store.Store) {
+	if !config.Prover.IsFundingEnabled() &&
+		!config.Prover.IsRedemptionEnabled() {
+		return
+	}
+
+	reorgs, err := relayStore.LoadReorgs()
 	if err != nil {
-		return fmt.Errorf("could not read config file: [%v]", err)
+		logger.Warnf("could not load reorg history: [%v]", err)
+		return
+	}
+
+	confirmations := config.Prover.Confirmations
+	if confirmations <= 0 {
+		confirmations = prover.DefaultConfirmations
+	}
+
+	stats := store.SummarizeReorgs(reorgs, prover.DefaultConfirmations)
+	if confirmations < stats.RecommendedConfirmations {
+		logger.Warnf(
+			"proofs are submitted with [%v] confirmations while reorgs of "+
+				"depth up to [%v] have been observed; consider at least "+
+				"[%v] confirmations",
+			confirmations,
+			stats.MaxDepth,
+			stats.RecommendedConfirmations,
+		)
+	}
+}
+
+// initializeTrigger starts switching the relay into the low-latency mode
+// while proofs of tBTC deposits are pending, if enabled.
+func initializeTrigger(
+	ctx context.Context,
+	config *config.Target,
+	hostChain chain.Handle,
+	node *node.Node,
+) error {
+	if !config.Trigger.Enabled {
+		logger.Infof("low-latency trigger is not enabled")
+		return nil
+	}
+
+	if config.Relay.WatchOnly || !hasOperatorKey(config) {
+		return fmt.Errorf(
+			"low-latency trigger cannot be used in watch-only mode",
+		)
+	}
+
+	if err := config.Ethereum.ValidateContractAddress(
+		ethereum.TBTCSystemContractName,
+	); err != nil {
+		return err
+	}
+
+	trigger.New(hostChain, node.Control(), &config.Trigger).Start(
+		ctx,
+		time.Duration(config.Trigger.Tick)*time.Second,
+	)
+
+	logger.Infof("switching to low-latency mode while proofs are pending")
+
+	return nil
+}
+
+// initializeHistory opens the metrics history if enabled. Recording of
+// samples must be started once the node is initialized. Returns nil if the
+// metrics history is not configured.
+func initializeHistory(
+	ctx context.Context,
+	config *config.Target,
+) (*history.History, error) {
+	if !config.History.IsEnabled() {
+		logger.Infof("metrics history is not configured")
+		return nil, nil
 	}
 
-	btcChain, err := btc.Connect(ctx, &config.Bitcoin)
+	relayHistory, err := history.Open(&config.History)
 	if err != nil {
-		return fmt.Errorf("could not connect BTC chain: [%v]", err)
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := relayHistory.Close(); err != nil {
+			logger.Warnf("could not close metrics history: [%v]", err)
+		}
+	}()
+
+	logger.Infof("recording metrics history to [%v]", config.History.File)
+
+	return relayHistory, nil
+}
+
+// initializeRewardsTracker starts tracking and claiming relay rewards if
+// enabled. Returns nil if rewards claiming is not enabled or the relay runs
+// in the watch-only mode.
+func initializeRewardsTracker(
+	ctx context.Context,
+	config *config.Target,
+	hostChain chain.Handle,
+	relayHistory *history.History,
+) (*rewards.Tracker, error) {
+	if !config.Rewards.Enabled {
+		logger.Infof("rewards claiming is not enabled")
+		return nil, nil
+	}
+
+	if config.Relay.WatchOnly || !hasOperatorKey(config) {
+		logger.Warnf("rewards claiming is not available in watch-only mode")
+		return nil, nil
+	}
+
+	// Leave the recorder nil instead of wrapping a nil history pointer.
+	var recorder rewards.ClaimRecorder
+	if relayHistory != nil {
+		recorder = relayHistory
 	}
 
-	hostChain, err := connectHostChain(config)
+	tracker, err := rewards.NewTracker(hostChain, &config.Rewards, recorder)
 	if err != nil {
-		return fmt.Errorf("could not connect host chain: [%v]", err)
+		return nil, err
 	}
 
-	node := node.Initialize(ctx, btcChain, hostChain)
+	tracker.Start(ctx, time.Duration(config.Rewards.Tick)*time.Second)
 
-	initializeMetrics(ctx, config, btcChain, hostChain, node.Stats())
+	return tracker, nil
+}
 
-	logger.Info("relay started")
+// initializeProfitabilityGate returns the estimator used by the relay to skip
+// unprofitable pushes if the profitability gate is enabled. Returns nil if
+// the gate is not enabled or rewards are not tracked.
+func initializeProfitabilityGate(
+	config *config.Target,
+	rewardsTracker *rewards.Tracker,
+	gasUsageDetector *gasusage.Detector,
+) header.ProfitabilityEstimator {
+	if !config.Relay.ProfitabilityGate {
+		return nil
+	}
 
-	<-ctx.Done()
-	return fmt.Errorf("unexpected context cancellation")
+	if rewardsTracker == nil {
+		logger.Warnf(
+			"profitability gate requires rewards claiming to be enabled; " +
+				"pushes will not be gated",
+		)
+		return nil
+	}
+
+	return rewards.NewProfitability(rewardsTracker, gasUsageDetector)
 }
 
-func connectHostChain(config *config.Config) (chain.Handle, error) {
-	// TODO: add support for multiple host chains (like Celo).
-	return connectEthereum(config.Ethereum)
+// initializeCompetitionTracker starts tracking transactions advancing the
+// relay contract if either metrics, metrics history, rewards claiming or the
+// run summary file or webhook are enabled. Tracked advances also sum up the
+// gas spent in the run summary. Returns nil if the tracking is not needed.
+func initializeCompetitionTracker(
+	ctx context.Context,
+	config *config.Target,
+	hostChain chain.Handle,
+	relayHistory *history.History,
+	gasUsageDetector *gasusage.Detector,
+	rewardsTracker *rewards.Tracker,
+	summaryTracker *summary.Tracker,
+) *competition.Tracker {
+	if !config.Metrics.IsEnabled() &&
+		!config.Summary.IsEnabled() &&
+		relayHistory == nil &&
+		rewardsTracker == nil {
+		return nil
+	}
+
+	recorders := competition.Recorders{gasUsageDetector, summaryTracker}
+	if relayHistory != nil {
+		recorders = append(recorders, relayHistory)
+	}
+	if rewardsTracker != nil {
+		recorders = append(recorders, rewardsTracker)
+	}
+
+	tracker := competition.NewTracker(hostChain, recorders)
+	tracker.Start(ctx, competition.DefaultTick)
+
+	return tracker
 }
 
-func connectEthereum(config commoneth.Config) (chain.Handle, error) {
-	key, err := ethutil.DecryptKeyFile(
-		config.Account.KeyFile,
-		config.Account.KeyFilePassword,
+// initializeQuorum creates the pipeline stage holding back pulled headers
+// until the quorum of the relay Bitcoin node and the configured secondary
+// Bitcoin nodes agree on them. Returns nil if the quorum mode is not enabled.
+func initializeQuorum(
+	ctx context.Context,
+	config *config.Target,
+	btcChain btc.Handle,
+) (*quorum.Stage, error) {
+	if !config.Quorum.Enabled {
+		logger.Infof("quorum mode is not enabled")
+		return nil, nil
+	}
+
+	secondaryChains, err := connectSecondaryBtcChains(
+		ctx,
+		config.Quorum.Sources,
 	)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to read key file [%s]: [%v]",
-			config.Account.KeyFile,
-			err,
+		return nil, err
+	}
+
+	sources := make([]*quorum.Source, len(secondaryChains))
+	for i, secondaryChain := range secondaryChains {
+		sources[i] = &quorum.Source{
+			Name:  config.Quorum.Sources[i].URL,
+			Chain: secondaryChain,
+		}
+	}
+
+	stage, err := quorum.NewStage(btcChain, sources, &config.Quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof(
+		"relaying headers agreed by the quorum of [%v] Bitcoin sources",
+		len(sources)+1,
+	)
+
+	return stage, nil
+}
+
+// connectSecondaryBtcChains connects the secondary Bitcoin nodes with the
+// given configurations.
+func connectSecondaryBtcChains(
+	ctx context.Context,
+	configs []btc.Config,
+) ([]btc.Handle, error) {
+	chains := make([]btc.Handle, len(configs))
+
+	for i := range configs {
+		secondaryChain, err := btc.Connect(ctx, &configs[i], nil)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not connect secondary Bitcoin node [%v]: [%v]",
+				transport.RedactURL(configs[i].URL),
+				err,
+			)
+		}
+
+		chains[i] = secondaryChain
+	}
+
+	return chains, nil
+}
+
+// initializeFraudWatcher starts checking the digests recorded by the relay
+// contract against the relay Bitcoin node and the configured secondary
+// Bitcoin nodes if enabled. Divergences are posted to the webhooks, which may
+// be nil. Returns nil if the watcher is not enabled.
+func initializeFraudWatcher(
+	ctx context.Context,
+	config *config.Target,
+	btcChain btc.Handle,
+	hostChain chain.Handle,
+	webhooks *webhook.Dispatcher,
+) (*fraud.Watcher, error) {
+	if !config.Fraud.Enabled {
+		logger.Infof("relay contract watcher is not enabled")
+		return nil, nil
+	}
+
+	secondaryChains, err := connectSecondaryBtcChains(
+		ctx,
+		config.Fraud.Sources,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []*fraud.Source{{Name: "relay", Chain: btcChain}}
+	for i, secondaryChain := range secondaryChains {
+		sources = append(sources, &fraud.Source{
+			Name:  config.Fraud.Sources[i].URL,
+			Chain: secondaryChain,
+		})
+	}
+
+	var hook fraud.ChallengeHook
+	if config.Fraud.ChallengeCommand != "" {
+		hook = fraud.CommandHook(config.Fraud.ChallengeCommand)
+	}
+	if webhooks != nil {
+		hook = webhooks.ChallengeHook(hook)
+	}
+
+	watcher := fraud.NewWatcher(hostChain, sources, hook)
+	watcher.Start(ctx, time.Duration(config.Fraud.Tick)*time.Second)
+
+	logger.Infof(
+		"watching relay contract against [%v] Bitcoin sources",
+		len(sources),
+	)
+
+	return watcher, nil
+}
+
+// initializeReadiness starts tracking the readiness state of the relay
+// node. State transitions are logged and posted to the webhooks, if enabled.
+func initializeReadiness(
+	ctx context.Context,
+	serviceConfig *service.Config,
+	node *node.Node,
+	webhooks *webhook.Dispatcher,
+) *service.Readiness {
+	readiness := service.NewReadiness(serviceConfig, node.Stats())
+
+	readiness.OnTransition(func(transition *service.Transition) {
+		if transition.To == service.StateDegraded {
+			logger.Warnf(
+				"relay is degraded: %v",
+				transition.Reason,
+			)
+			return
+		}
+
+		logger.Infof(
+			"relay is %v: %v",
+			transition.To,
+			transition.Reason,
+		)
+	})
+
+	if webhooks != nil {
+		readiness.OnTransition(webhooks.TransitionHook())
+	}
+
+	go readiness.Watch(ctx)
+
+	return readiness
+}
+
+// initializeWebhooks starts the dispatcher of the outbound webhooks keeping
+// the undelivered events in the given relay store. Returns nil if webhooks
+// are not enabled.
+func initializeWebhooks(
+	ctx context.Context,
+	config *config.Target,
+	relayStore *store.Store,
+) *webhook.Dispatcher {
+	if !config.Webhooks.IsEnabled() {
+		logger.Infof("webhooks are not enabled")
+		return nil
+	}
+
+	if !relayStore.IsPersistent() {
+		logger.Warnf(
+			"data directory is not configured; " +
+				"undelivered webhook events will be lost on restart",
 		)
 	}
 
-	return ethereum.Connect(key, &config)
+	if config.Webhooks.Secret == "" {
+		logger.Warnf("webhook secret is not configured; requests are not signed")
+	}
+
+	dispatcher := webhook.NewDispatcher(&config.Webhooks, relayStore)
+	dispatcher.Start(ctx)
+
+	logger.Infof(
+		"posting events to [%v] webhook endpoints",
+		len(config.Webhooks.URLs),
+	)
+
+	return dispatcher
 }
 
 func initializeMetrics(
 	ctx context.Context,
-	config *config.Config,
+	config *config.Target,
 	btcChain btc.Handle,
 	hostChain chain.Handle,
+	submissionStats *chain.SubmissionStats,
 	nodeStats node.Stats,
+	nodeBus *events.Bus,
+	readiness *service.Readiness,
+	competitionTracker *competition.Tracker,
+	gasUsageDetector *gasusage.Detector,
+	latencySLO *slo.Tracker,
+	rewardsTracker *rewards.Tracker,
+	fraudWatcher *fraud.Watcher,
+	quorumStage *quorum.Stage,
+	updateChecker *build.UpdateChecker,
+	pauseGuard *chain.PauseGuard,
 ) {
 	registry, isConfigured := metrics.Initialize(
+		ctx,
 		config.Metrics.Port,
+		&config.Metrics.Push,
+		map[string]string{"target": config.Name},
 	)
 	if !isConfigured {
 		logger.Infof("metrics are not configured")
 		return
 	}
 
-	logger.Infof(
-		"enabled metrics on port [%v]",
-		config.Metrics.Port,
-	)
+	if config.Metrics.Port != 0 {
+		logger.Infof(
+			"enabled metrics on port [%v]",
+			config.Metrics.Port,
+		)
+	}
+
+	metrics.ExposeBuildInfo(registry)
 
 	metrics.ObserveBtcChainConnectivity(
 		ctx,
@@ -122,6 +1482,20 @@ func initializeMetrics(
 		time.Duration(config.Metrics.ChainMetricsTick)*time.Second,
 	)
 
+	metrics.ObserveHostChainSubmissions(
+		ctx,
+		registry,
+		submissionStats,
+		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+	)
+
+	metrics.ObserveContractsPaused(
+		ctx,
+		registry,
+		pauseGuard,
+		time.Duration(config.Metrics.ChainMetricsTick)*time.Second,
+	)
+
 	metrics.ObserveHeadersRelayActive(
 		ctx,
 		registry,
@@ -136,6 +1510,20 @@ func initializeMetrics(
 		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
 	)
 
+	metrics.ObserveRelayState(
+		ctx,
+		registry,
+		readiness,
+		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+	)
+
+	metrics.ObserveEventBus(
+		ctx,
+		registry,
+		nodeBus,
+		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+	)
+
 	metrics.ObserveHeadersPulled(
 		ctx,
 		registry,
@@ -149,4 +1537,95 @@ func initializeMetrics(
 		nodeStats,
 		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
 	)
+
+	metrics.ObserveHeadersRelayLag(
+		ctx,
+		registry,
+		nodeStats,
+		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+	)
+
+	metrics.ObserveBtcForks(
+		ctx,
+		registry,
+		nodeStats,
+		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+	)
+
+	metrics.ObserveLatencySLO(
+		ctx,
+		registry,
+		latencySLO,
+		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+	)
+
+	if competitionTracker != nil {
+		metrics.ObserveRelayCompetition(
+			ctx,
+			registry,
+			competitionTracker,
+			time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+		)
+	}
+
+	metrics.ObserveGasUsage(
+		ctx,
+		registry,
+		gasUsageDetector,
+		time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+	)
+
+	if rewardsTracker != nil {
+		metrics.ObserveRewards(
+			ctx,
+			registry,
+			rewardsTracker,
+			time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+		)
+	}
+
+	if quorumStage != nil {
+		metrics.ObserveQuorum(
+			ctx,
+			registry,
+			quorumStage,
+			time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+		)
+	}
+
+	if fraudWatcher != nil {
+		metrics.ObserveRelayDivergence(
+			ctx,
+			registry,
+			fraudWatcher,
+			time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+		)
+	}
+
+	if updateChecker != nil {
+		metrics.ObserveUpdateAvailable(
+			ctx,
+			registry,
+			updateChecker,
+			time.Duration(config.Metrics.NodeMetricsTick)*time.Second,
+		)
+	}
+}
+
+func initializeUpdateChecker(
+	ctx context.Context,
+	config *config.Config,
+) *build.UpdateChecker {
+	if !config.UpdateCheck.Enabled {
+		logger.Infof("update check is not enabled")
+		return nil
+	}
+
+	checker := build.NewUpdateChecker(&config.UpdateCheck)
+	checker.Start(
+		ctx,
+		time.Duration(config.UpdateCheck.Interval)*time.Second,
+	)
+
+	return checker
 }
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/urfave/cli"
+)
+
+// VersionCommand contains the definition of the version command-line
+// sub-command.
+var VersionCommand = cli.Command{
+	Name:   "version",
+	Usage:  `Prints the version and build information`,
+	Action: Version,
+}
+
+// Version prints the version and build information of the relay binary.
+func Version(c *cli.Context) error {
+	info := build.GetInfo()
+
+	fmt.Printf("version:    %v\n", info.Version)
+	fmt.Printf("revision:   %v\n", info.Revision)
+	fmt.Printf("build date: %v\n", info.Date)
+	fmt.Printf("go version: %v\n", info.GoVersion)
+
+	return nil
+}
@@ -3,10 +3,33 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
 
-	"github.com/BurntSushi/toml"
-	"github.com/keep-network/keep-common/pkg/chain/ethereum"
+	"github.com/keep-network/tbtc/relay/pkg/api"
 	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+	"github.com/keep-network/tbtc/relay/pkg/fraud"
+	"github.com/keep-network/tbtc/relay/pkg/gasusage"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/headerstore"
+	"github.com/keep-network/tbtc/relay/pkg/history"
+	"github.com/keep-network/tbtc/relay/pkg/metrics"
+	"github.com/keep-network/tbtc/relay/pkg/proof"
+	"github.com/keep-network/tbtc/relay/pkg/prover"
+	"github.com/keep-network/tbtc/relay/pkg/quorum"
+	"github.com/keep-network/tbtc/relay/pkg/rewards"
+	"github.com/keep-network/tbtc/relay/pkg/service"
+	"github.com/keep-network/tbtc/relay/pkg/slo"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+	"github.com/keep-network/tbtc/relay/pkg/summary"
+	"github.com/keep-network/tbtc/relay/pkg/trigger"
+	"github.com/keep-network/tbtc/relay/pkg/webhook"
 )
 
 // PasswordEnvVariable environment variable name for operator key file password.
@@ -16,11 +39,49 @@ import (
 // It's just the name of the environment variable.
 const PasswordEnvVariable = "OPERATOR_KEY_FILE_PASSWORD"
 
-// Config is the top level config structure.
+// Config is the top level config structure. The relay target configured at
+// the top level is run unless independent relay targets are configured.
 type Config struct {
+	Target
+
+	Service     service.Config
+	UpdateCheck build.UpdateCheckConfig
+
+	// Targets are independent relay targets run by the relay process, each
+	// with its own chains, relay contract, keys, storage and metrics. If set,
+	// the relay target configured at the top level is not run.
+	Targets []Target
+}
+
+// Target is the configuration of a single relay target, that is a Bitcoin
+// network relayed to a relay contract on the host chain.
+type Target struct {
+	// Name identifies the relay target in logs and metrics labels. It is
+	// required if more than one target is configured.
+	Name string
+
 	Ethereum ethereum.Config
 	Bitcoin  btc.Config
+	Relay    header.Config
+	Writer   chain.WriterConfig
+	Storage  store.Config
+	API      api.Config
 	Metrics  Metrics
+	History  history.Config
+	Deposits deposit.Config
+	Proofs   proof.Config
+	Prover   prover.Config
+	Fraud    fraud.Config
+	Trigger  trigger.Config
+	Quorum   quorum.Config
+	Webhooks webhook.Config
+	Summary  summary.Config
+
+	HeaderStore headerstore.Config
+	GasUsage    gasusage.Config
+	SLO         slo.Config
+	Rewards     rewards.Config
+	PublicAPI   api.PublicConfig
 }
 
 // Metrics stores meta-info about metrics.
@@ -28,23 +89,184 @@ type Metrics struct {
 	Port             int
 	ChainMetricsTick int
 	NodeMetricsTick  int
+	Push             metrics.PushConfig
+}
+
+// IsEnabled checks whether the metrics are enabled, either exposed on the
+// port or pushed.
+func (m *Metrics) IsEnabled() bool {
+	return m.Port != 0 || m.Push.IsEnabled()
 }
 
 // ReadConfig reads in the configuration file in .toml format. Chain key file
-// password is expected to be provided as environment variable.
+// password is expected to be provided as environment variable or set in the
+// config, e.g. in the secrets file of ReadLayeredConfig; the environment
+// variable takes precedence. Passwords of key files used by named relay
+// targets can be provided as separate environment variables suffixed with the
+// target name; see PasswordEnvVariableFor.
 func ReadConfig(filePath string) (*Config, error) {
+	return ReadLayeredConfig(filePath, "", "")
+}
+
+// ReadLayeredConfig reads in the configuration file in .toml format just
+// like ReadConfig and overlays it with the file of the given profile, see
+// ProfileFilePath, and the given secrets file. The profile and the secrets
+// file are optional and can be empty.
+func ReadLayeredConfig(
+	filePath string,
+	profile string,
+	secretsFilePath string,
+) (*Config, error) {
 	config := &Config{}
-	if _, err := toml.DecodeFile(filePath, config); err != nil {
-		return nil, fmt.Errorf(
-			"failed to decode file [%s]: [%v]",
-			filePath,
-			err,
-		)
+	for _, layer := range configLayers(filePath, profile, secretsFilePath) {
+		if err := decodeLayer(layer, config); err != nil {
+			return nil, err
+		}
 	}
 
-	password := os.Getenv(PasswordEnvVariable)
+	names := make(map[string]bool)
+	resources := make(targetResources)
+	for _, target := range config.RelayTargets() {
+		if len(config.Targets) > 1 && target.Name == "" {
+			return nil, fmt.Errorf("relay target name is not set")
+		}
 
-	config.Ethereum.Account.KeyFilePassword = password
+		if names[target.Name] {
+			return nil, fmt.Errorf(
+				"duplicated relay target name [%v]",
+				target.Name,
+			)
+		}
+		names[target.Name] = true
+
+		if err := resources.claimAll(target); err != nil {
+			return nil, err
+		}
+
+		if err := target.Relay.Validate(); err != nil {
+			return nil, fmt.Errorf(
+				"invalid relay config%v: [%v]",
+				target.describe(),
+				err,
+			)
+		}
+
+		// The password set in the environment overrides the one set in the
+		// config files, e.g. in the secrets file.
+		if password, ok := targetPassword(target.Name); ok {
+			target.Ethereum.Account.KeyFilePassword = password
+		}
+	}
 
 	return config, nil
 }
+
+// RelayTargets returns the relay targets run by the relay process.
+func (c *Config) RelayTargets() []*Target {
+	if len(c.Targets) == 0 {
+		return []*Target{&c.Target}
+	}
+
+	targets := make([]*Target, len(c.Targets))
+	for i := range c.Targets {
+		targets[i] = &c.Targets[i]
+	}
+
+	return targets
+}
+
+// targetResources tracks the ports and files claimed by relay targets.
+// Relay targets must not share them: two targets listening on the same
+// address fail to start and two targets writing the same files corrupt
+// each other's state.
+type targetResources map[string]bool
+
+// claimAll claims the ports and files configured for the given target.
+func (tr targetResources) claimAll(target *Target) error {
+	claims := []struct {
+		kind  string
+		value string
+	}{
+		{"metrics port", portString(target.Metrics.Port)},
+		{"API address", target.API.Address},
+		{"API address", target.PublicAPI.Address},
+		{"API address", target.API.AdminAddress},
+		{"data directory", cleanPath(target.Storage.DataDir)},
+		{"header store file", cleanPath(target.HeaderStore.File)},
+		{"history file", cleanPath(target.History.File)},
+	}
+
+	for _, claim := range claims {
+		if claim.value == "" {
+			continue
+		}
+
+		key := claim.kind + "/" + claim.value
+		if tr[key] {
+			return fmt.Errorf(
+				"%v [%v] is used by more than one relay target",
+				claim.kind,
+				claim.value,
+			)
+		}
+		tr[key] = true
+	}
+
+	return nil
+}
+
+func portString(port int) string {
+	if port == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(port)
+}
+
+func cleanPath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Clean(path)
+}
+
+// describe returns the suffix identifying the target in messages.
+func (t *Target) describe() string {
+	if t.Name == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" of target [%v]", t.Name)
+}
+
+// PasswordEnvVariableFor returns the name of the environment variable holding
+// the operator key file password of the relay target with the given name,
+// e.g. OPERATOR_KEY_FILE_PASSWORD_TESTNET for the `testnet` target. If that
+// variable is not set, PasswordEnvVariable is used.
+func PasswordEnvVariableFor(targetName string) string {
+	if targetName == "" {
+		return PasswordEnvVariable
+	}
+
+	suffix := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, targetName)
+
+	return PasswordEnvVariable + "_" + suffix
+}
+
+// targetPassword returns the key file password of the given relay target set
+// in the environment, if any.
+func targetPassword(targetName string) (string, bool) {
+	if password, ok := os.LookupEnv(
+		PasswordEnvVariableFor(targetName),
+	); ok {
+		return password, true
+	}
+
+	return os.LookupEnv(PasswordEnvVariable)
+}
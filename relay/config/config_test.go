@@ -0,0 +1,120 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/metrics"
+)
+
+func TestReadConfig_SharedTargetResources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = map[string]struct {
+		settingA      string
+		settingB      string
+		expectedError bool
+	}{
+		"distinct data directories": {
+			settingA: "[targets.storage]\n  DataDir = \"/data/a\"\n",
+			settingB: "[targets.storage]\n  DataDir = \"/data/b\"\n",
+		},
+		"shared data directory": {
+			settingA:      "[targets.storage]\n  DataDir = \"/data/a\"\n",
+			settingB:      "[targets.storage]\n  DataDir = \"/data/a/\"\n",
+			expectedError: true,
+		},
+		"shared header store file": {
+			settingA:      "[targets.headerstore]\n  File = \"/data/headers.db\"\n",
+			settingB:      "[targets.headerstore]\n  File = \"/data/headers.db\"\n",
+			expectedError: true,
+		},
+		"shared history file": {
+			settingA:      "[targets.history]\n  File = \"/data/history.db\"\n",
+			settingB:      "[targets.history]\n  File = \"/data/history.db\"\n",
+			expectedError: true,
+		},
+		"shared API address": {
+			settingA:      "[targets.api]\n  Address = \"127.0.0.1:8081\"\n",
+			settingB:      "[targets.api]\n  Address = \"127.0.0.1:8081\"\n",
+			expectedError: true,
+		},
+		"admin service address shared with API": {
+			settingA:      "[targets.api]\n  Address = \"127.0.0.1:8081\"\n",
+			settingB:      "[targets.api]\n  AdminAddress = \"127.0.0.1:8081\"\n",
+			expectedError: true,
+		},
+		"shared metrics port": {
+			settingA:      "[targets.metrics]\n  Port = 8080\n",
+			settingB:      "[targets.metrics]\n  Port = 8080\n",
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			content := "[[targets]]\n  Name = \"a\"\n" + test.settingA +
+				"[[targets]]\n  Name = \"b\"\n" + test.settingB
+
+			filePath := filepath.Join(dir, "config.toml")
+			if err := ioutil.WriteFile(
+				filePath,
+				[]byte(content),
+				0600,
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := ReadConfig(filePath)
+			if test.expectedError && err == nil {
+				t.Errorf("expected error for shared resource")
+			}
+			if !test.expectedError && err != nil {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+		})
+	}
+}
+
+func TestMetrics_IsEnabled(t *testing.T) {
+	var tests = map[string]struct {
+		metrics         Metrics
+		expectedEnabled bool
+	}{
+		"not configured": {
+			metrics:         Metrics{},
+			expectedEnabled: false,
+		},
+		"port only": {
+			metrics:         Metrics{Port: 8080},
+			expectedEnabled: true,
+		},
+		"push only": {
+			metrics: Metrics{
+				Push: metrics.PushConfig{URL: "http://127.0.0.1:9091"},
+			},
+			expectedEnabled: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			enabled := test.metrics.IsEnabled()
+			if enabled != test.expectedEnabled {
+				t.Errorf(
+					"unexpected enabled state:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedEnabled,
+					enabled,
+				)
+			}
+		})
+	}
+}
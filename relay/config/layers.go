@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// layers.go file contains the layering of configuration files. The base
+// configuration file is shared by all environments, a profile file overlays
+// the settings of a single environment, like mainnet or testnet, and
+// a secrets file, kept outside the version control, overlays the secrets.
+// Secrets can also be injected from the environment only, by referencing
+// environment variables in any of the files, so the files can be committed
+// to operations repositories without keys.
+
+// envReferencePattern matches references to environment variables in
+// configuration files, e.g. `${ETHEREUM_URL}`.
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ProfileFilePath returns the path of the file overlaying the given
+// configuration file with the settings of the given profile, e.g.
+// `config/config.mainnet.toml` for `config/config.toml` and the `mainnet`
+// profile.
+func ProfileFilePath(filePath string, profile string) string {
+	extension := filepath.Ext(filePath)
+
+	return strings.TrimSuffix(filePath, extension) + "." + profile + extension
+}
+
+// configLayers returns the paths of the configuration files in the order
+// they are applied.
+func configLayers(
+	filePath string,
+	profile string,
+	secretsFilePath string,
+) []string {
+	layers := []string{filePath}
+
+	if profile != "" {
+		layers = append(layers, ProfileFilePath(filePath, profile))
+	}
+
+	if secretsFilePath != "" {
+		layers = append(layers, secretsFilePath)
+	}
+
+	return layers
+}
+
+// decodeLayer decodes the given configuration file into the given config.
+// Only the settings present in the file are overwritten; lists, including
+// the lists of tables, are replaced as a whole. References to environment
+// variables are replaced with their values before decoding.
+func decodeLayer(filePath string, config *Config) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file [%s]: [%v]", filePath, err)
+	}
+
+	expanded, err := expandEnvReferences(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to expand file [%s]: [%v]", filePath, err)
+	}
+
+	if _, err := toml.Decode(expanded, config); err != nil {
+		return fmt.Errorf(
+			"failed to decode file [%s]: [%v]",
+			filePath,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// expandEnvReferences replaces references to environment variables in the
+// given content with their values, placed verbatim. Comment lines are left
+// intact. Returns an error if any of the referenced variables is not set.
+func expandEnvReferences(content string) (string, error) {
+	var missing []string
+
+	expand := func(reference string) string {
+		name := envReferencePattern.FindStringSubmatch(reference)[1]
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+
+		return value
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		lines[i] = envReferencePattern.ReplaceAllStringFunc(line, expand)
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf(
+			"environment variables [%v] are not set",
+			strings.Join(missing, ", "),
+		)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
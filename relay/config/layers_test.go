@@ -0,0 +1,210 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	baseConfig = `
+[ethereum]
+  URL = "ws://127.0.0.1:8546"
+  RequestTimeout = 30
+
+[bitcoin]
+  URL = "127.0.0.1:8332"
+`
+
+	mainnetConfig = `
+[ethereum]
+  URL = "wss://mainnet.example.com"
+`
+
+	secretsConfig = `
+# Placeholders in comments, like ${RELAY_TEST_UNSET}, are not expanded.
+[ethereum.account]
+  KeyFile = "${RELAY_TEST_KEY_FILE}"
+`
+)
+
+func TestReadLayeredConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "config.toml")
+	secretsFilePath := filepath.Join(dir, "secrets.toml")
+
+	for path, content := range map[string]string{
+		filePath:                             baseConfig,
+		ProfileFilePath(filePath, "mainnet"): mainnetConfig,
+		secretsFilePath:                      secretsConfig,
+	} {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Setenv("RELAY_TEST_KEY_FILE", "/keys/operator"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("RELAY_TEST_KEY_FILE")
+
+	var tests = map[string]struct {
+		profile         string
+		secretsFilePath string
+		expectedURL     string
+		expectedKeyFile string
+	}{
+		"base config": {
+			expectedURL: "ws://127.0.0.1:8546",
+		},
+		"profile overlay": {
+			profile:     "mainnet",
+			expectedURL: "wss://mainnet.example.com",
+		},
+		"profile and secrets overlays": {
+			profile:         "mainnet",
+			secretsFilePath: secretsFilePath,
+			expectedURL:     "wss://mainnet.example.com",
+			expectedKeyFile: "/keys/operator",
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			config, err := ReadLayeredConfig(
+				filePath,
+				test.profile,
+				test.secretsFilePath,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if config.Ethereum.URL != test.expectedURL {
+				t.Errorf(
+					"unexpected URL:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedURL,
+					config.Ethereum.URL,
+				)
+			}
+
+			if config.Ethereum.Account.KeyFile != test.expectedKeyFile {
+				t.Errorf(
+					"unexpected key file:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedKeyFile,
+					config.Ethereum.Account.KeyFile,
+				)
+			}
+
+			// Settings not overlaid are kept.
+			if config.Ethereum.RequestTimeout != 30 {
+				t.Errorf(
+					"unexpected request timeout [%v]",
+					config.Ethereum.RequestTimeout,
+				)
+			}
+		})
+	}
+}
+
+func TestReadLayeredConfig_MissingEnvVariable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "config.toml")
+	content := []byte("[ethereum]\n  URL = \"${RELAY_TEST_UNSET}\"\n")
+	if err := ioutil.WriteFile(filePath, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadLayeredConfig(filePath, "", ""); err == nil {
+		t.Errorf("expected error for unset environment variable")
+	}
+}
+
+func TestReadLayeredConfig_PasswordInSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "config.toml")
+	secretsFilePath := filepath.Join(dir, "secrets.toml")
+
+	for path, content := range map[string]string{
+		filePath: baseConfig,
+		secretsFilePath: "[ethereum.account]\n" +
+			"  KeyFilePassword = \"from-secrets\"\n",
+	} {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tests = map[string]struct {
+		envPassword      *string
+		expectedPassword string
+	}{
+		"no environment variable": {
+			expectedPassword: "from-secrets",
+		},
+		"environment variable": {
+			envPassword:      stringPointer("from-env"),
+			expectedPassword: "from-env",
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if password, ok := os.LookupEnv(PasswordEnvVariable); ok {
+				defer os.Setenv(PasswordEnvVariable, password)
+			} else {
+				defer os.Unsetenv(PasswordEnvVariable)
+			}
+
+			var err error
+			if test.envPassword != nil {
+				err = os.Setenv(PasswordEnvVariable, *test.envPassword)
+			} else {
+				err = os.Unsetenv(PasswordEnvVariable)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := ReadLayeredConfig(filePath, "", secretsFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			password := config.Ethereum.Account.KeyFilePassword
+			if password != test.expectedPassword {
+				t.Errorf(
+					"unexpected key file password:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedPassword,
+					password,
+				)
+			}
+		})
+	}
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
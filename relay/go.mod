@@ -5,8 +5,17 @@ go 1.15
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/btcsuite/btcd v0.20.1-beta
+	github.com/btcsuite/btcutil v1.0.2
 	github.com/ethereum/go-ethereum v1.9.10
+	github.com/golang/protobuf v1.4.2
+	github.com/gorilla/websocket v1.4.1
 	github.com/ipfs/go-log v1.0.4
+	github.com/ipfs/go-log/v2 v2.1.1
 	github.com/keep-network/keep-common v1.4.1-0.20210315092601-3203332583f0
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/urfave/cli v1.22.5
+	go.uber.org/zap v1.14.1
+	golang.org/x/sys v0.0.0-20210119212857-b64e53b001e4
+	google.golang.org/grpc v1.35.0
+	google.golang.org/protobuf v1.25.0
 )
@@ -7,8 +7,9 @@ import (
 	"time"
 
 	"github.com/ipfs/go-log"
-	"github.com/keep-network/keep-common/pkg/logging"
 	"github.com/keep-network/tbtc/relay/cmd"
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
 	"github.com/urfave/cli"
 )
 
@@ -16,6 +17,8 @@ var logger = log.Logger("tbtc-relay-main")
 
 const (
 	logLevelEnvVariable = "LOG_LEVEL"
+	profileEnvVariable  = "RELAY_PROFILE"
+	secretsEnvVariable  = "RELAY_SECRETS"
 	defaultConfigPath   = "./config/config.toml"
 )
 
@@ -26,24 +29,40 @@ It requires the config file path to be passed via the '--config' flag or looks
 for the config under the default '` + defaultConfigPath + `' path if the flag 
 is missing.
 
+The config file can be overlaid with the file of an environment profile passed
+via the '--profile' flag, e.g. './config/config.mainnet.toml' for the 'mainnet'
+profile, and with the secrets file passed via the '--secrets' flag. They can
+also be set via ` + profileEnvVariable + ` and ` + secretsEnvVariable + ` env
+variables.
+
 Log level can be customized via ` + logLevelEnvVariable + ` env variable.
 `
 
-var (
-	version  string
-	revision string
-
-	configPath string
-)
+var configPath string
 
 func main() {
-	resolveVersionTags()
 	configureLogging()
 
 	app := configureCLIApp()
 
 	app.Commands = []cli.Command{
 		cmd.StartCommand,
+		cmd.AdminCommand,
+		cmd.RotateKeyCommand,
+		cmd.ReportCommand,
+		cmd.PlanCommand,
+		cmd.ExportCommand,
+		cmd.GenMonitoringCommand,
+		cmd.DoctorCommand,
+		cmd.SnapshotCommand,
+		cmd.ReplayCommand,
+		cmd.ReorgsCommand,
+		cmd.ProofCommand,
+		cmd.PruneCommand,
+		cmd.BenchCommand,
+		cmd.VersionCommand,
+		cmd.VerifyBinaryCommand,
+		cmd.SignBinaryCommand,
 	}
 
 	err := app.Run(os.Args)
@@ -52,18 +71,8 @@ func main() {
 	}
 }
 
-func resolveVersionTags() {
-	if version == "" {
-		version = "unknown"
-	}
-
-	if revision == "" {
-		revision = "unknown"
-	}
-}
-
 func configureLogging() {
-	err := logging.Configure(os.Getenv(logLevelEnvVariable))
+	err := logs.Configure(os.Getenv(logLevelEnvVariable))
 
 	if err != nil {
 		_, _ = fmt.Fprintf(
@@ -82,7 +91,7 @@ func configureCLIApp() *cli.App {
 	app.Usage = "CLI for the relay maintainer"
 	app.Description = appDescription
 	app.Compiled = time.Now()
-	app.Version = fmt.Sprintf("%s (revision %s)", version, revision)
+	app.Version = fmt.Sprintf("%s (revision %s)", build.Version, build.Revision)
 	app.Authors = []cli.Author{
 		{
 			Name:  "Keep Network",
@@ -96,6 +105,16 @@ func configureCLIApp() *cli.App {
 			Destination: &configPath,
 			Usage:       "full path to the configuration file",
 		},
+		cli.StringFlag{
+			Name:   "profile,p",
+			EnvVar: profileEnvVariable,
+			Usage:  "environment profile overlaying the configuration file",
+		},
+		cli.StringFlag{
+			Name:   "secrets",
+			EnvVar: secretsEnvVariable,
+			Usage:  "full path to the secrets file overlaying the configuration",
+		},
 	}
 
 	return app
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+// Prefix of the paths of all admin endpoints.
+const adminPathPrefix = "/admin/"
+
+// Paths of the admin endpoints.
+const (
+	AdminPausePath  = "/admin/pause"
+	AdminResumePath = "/admin/resume"
+	AdminResyncPath = "/admin/resync"
+	AdminPushPath   = "/admin/push"
+	AdminStatePath  = "/admin/state"
+
+	AdminPauseTasksPath  = "/admin/tasks/pause"
+	AdminResumeTasksPath = "/admin/tasks/resume"
+	AdminSchedulePath    = "/admin/tasks/schedule"
+)
+
+// AdminStateResponse is the response of the admin endpoints.
+type AdminStateResponse struct {
+	Paused      bool              `json:"paused"`
+	TasksPaused bool              `json:"tasksPaused"`
+	Schedules   map[string]string `json:"schedules"`
+}
+
+// RegisterAdminHandlers registers the admin endpoints which let the operator
+// control the headers relay at runtime. The endpoints are registered only if
+// the server requires authentication.
+func RegisterAdminHandlers(server *Server, control *header.Control) {
+	if !server.IsAuthenticated() {
+		logger.Warnf(
+			"admin API is disabled as no API keys or client " +
+				"certificates are configured",
+		)
+		return
+	}
+
+	server.HandleFunc(
+		AdminStatePath,
+		adminHandler(http.MethodGet, control, nil),
+	)
+
+	actions := map[string]func(){
+		AdminPausePath:  control.Pause,
+		AdminResumePath: control.Resume,
+		AdminResyncPath: control.TriggerResync,
+		AdminPushPath:   control.TriggerPush,
+
+		AdminPauseTasksPath:  control.Scheduler().Pause,
+		AdminResumeTasksPath: control.Scheduler().Resume,
+	}
+
+	for path, action := range actions {
+		server.HandleFunc(
+			path,
+			adminHandler(http.MethodPost, control, action),
+		)
+	}
+
+	server.HandleFunc(AdminSchedulePath, scheduleHandler(control))
+}
+
+// scheduleHandler sets the schedule of the task given by the `task` request
+// parameter to the schedule given by the `schedule` request parameter. An
+// empty schedule restores the default interval of the task.
+func scheduleHandler(
+	control *header.Control,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		task := r.FormValue("task")
+		schedule := r.FormValue("schedule")
+
+		logger.Infof(
+			"admin request [%v] for task [%v] received from [%v]",
+			r.URL.Path,
+			task,
+			r.RemoteAddr,
+		)
+
+		if err := control.Scheduler().SetSchedule(task, schedule); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, adminState(control))
+	}
+}
+
+func adminHandler(
+	method string,
+	control *header.Control,
+	action func(),
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		if action != nil {
+			logger.Infof(
+				"admin request [%v] received from [%v]",
+				r.URL.Path,
+				r.RemoteAddr,
+			)
+			action()
+		}
+
+		writeJSON(w, http.StatusOK, adminState(control))
+	}
+}
+
+func adminState(control *header.Control) *AdminStateResponse {
+	return &AdminStateResponse{
+		Paused:      control.IsPaused(),
+		TasksPaused: control.Scheduler().IsPaused(),
+		Schedules:   control.Scheduler().Schedules(),
+	}
+}
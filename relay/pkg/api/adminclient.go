@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/api/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// AdminClient is a client of the gRPC admin service.
+type AdminClient struct {
+	pb.AdminClient
+
+	connection *grpc.ClientConn
+}
+
+// NewAdminClient creates a new client of the gRPC admin service served at
+// the configured address. The connection is established lazily, on the first
+// call.
+func NewAdminClient(config *ClientConfig) (*AdminClient, error) {
+	var options []grpc.DialOption
+
+	if config.TLS {
+		tlsConfig, err := newClientTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(
+			options,
+			grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		)
+	} else {
+		options = append(options, grpc.WithInsecure())
+	}
+
+	if config.APIKey != "" {
+		options = append(
+			options,
+			grpc.WithPerRPCCredentials(apiKeyCredentials(config.APIKey)),
+		)
+	}
+
+	connection, err := grpc.Dial(config.Address, options...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not connect to admin service at [%v]: [%v]",
+			config.Address,
+			err,
+		)
+	}
+
+	return &AdminClient{
+		AdminClient: pb.NewAdminClient(connection),
+		connection:  connection,
+	}, nil
+}
+
+// Close closes the connection to the admin service.
+func (ac *AdminClient) Close() error {
+	return ac.connection.Close()
+}
+
+// apiKeyCredentials passes the API key in the authorization metadata of the
+// gRPC calls.
+type apiKeyCredentials string
+
+func (akc apiKeyCredentials) GetRequestMetadata(
+	ctx context.Context,
+	uri ...string,
+) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(akc)}, nil
+}
+
+// RequireTransportSecurity returns false as the API client sends the API key
+// without TLS as well, e.g. to a server on the loopback interface.
+func (akc apiKeyCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/api/pb"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// adminservice.go file contains the gRPC admin service which lets the
+// operator control the relay at runtime, tune its settings and tail its logs
+// remotely, e.g. when the relay runs in a locked-down environment without
+// shell access. Calls are authenticated just like the API requests.
+
+// AdminServer serves the gRPC admin service.
+type AdminServer struct {
+	config        *Config
+	authenticator *authenticator
+	tlsConfig     *tls.Config
+	service       *adminService
+}
+
+// NewAdminServer creates a new gRPC admin server controlling the relay with
+// the given control. Logs are streamed from the given tail, unless it is
+// nil. An error is returned if the configuration is not valid or neither
+// API keys nor client certificates are configured.
+func NewAdminServer(
+	config *Config,
+	control *header.Control,
+	tail *logs.Tail,
+) (*AdminServer, error) {
+	authenticator, err := newAuthenticator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		certificate, err := tls.LoadX509KeyPair(
+			config.TLSCertFile,
+			config.TLSKeyFile,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not load TLS certificate: [%v]",
+				err,
+			)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	authenticated := authenticator.requiresKey() ||
+		(tlsConfig != nil &&
+			tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert)
+
+	if !authenticated {
+		return nil, fmt.Errorf(
+			"refusing to serve the admin service without authentication; " +
+				"configure API keys or client certificates",
+		)
+	}
+
+	return &AdminServer{
+		config:        config,
+		authenticator: authenticator,
+		tlsConfig:     tlsConfig,
+		service: &adminService{
+			control: control,
+			tail:    tail,
+		},
+	}, nil
+}
+
+// Start starts serving the admin service in the background. The server is
+// shut down once the passed context is done.
+func (as *AdminServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", as.config.AdminAddress)
+	if err != nil {
+		return fmt.Errorf(
+			"could not listen on [%v]: [%v]",
+			as.config.AdminAddress,
+			err,
+		)
+	}
+
+	server := as.newGRPCServer()
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Errorf("admin server failed: [%v]", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		// Log streams last until the clients leave, so they are cut once
+		// the shutdown timeout is reached.
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			server.Stop()
+		}
+	}()
+
+	logger.Infof(
+		"admin server listening on [%v] (TLS: [%v], log streaming: [%v])",
+		listener.Addr(),
+		as.tlsConfig != nil,
+		as.service.tail != nil,
+	)
+
+	return nil
+}
+
+func (as *AdminServer) newGRPCServer() *grpc.Server {
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(as.authenticator.unaryInterceptor),
+		grpc.StreamInterceptor(as.authenticator.streamInterceptor),
+	}
+
+	if as.tlsConfig != nil {
+		options = append(
+			options,
+			grpc.Creds(credentials.NewTLS(as.tlsConfig)),
+		)
+	}
+
+	server := grpc.NewServer(options...)
+	pb.RegisterAdminServer(server, as.service)
+
+	return server
+}
+
+// adminService implements the calls of the gRPC admin service.
+type adminService struct {
+	pb.UnimplementedAdminServer
+
+	control *header.Control
+	tail    *logs.Tail
+}
+
+func (as *adminService) GetState(
+	ctx context.Context,
+	request *pb.GetStateRequest,
+) (*pb.State, error) {
+	return as.state(), nil
+}
+
+func (as *adminService) Pause(
+	ctx context.Context,
+	request *pb.PauseRequest,
+) (*pb.State, error) {
+	logger.Infof("admin call [pause] received from [%v]", peerAddress(ctx))
+	as.control.Pause()
+
+	return as.state(), nil
+}
+
+func (as *adminService) Resume(
+	ctx context.Context,
+	request *pb.ResumeRequest,
+) (*pb.State, error) {
+	logger.Infof("admin call [resume] received from [%v]", peerAddress(ctx))
+	as.control.Resume()
+
+	return as.state(), nil
+}
+
+func (as *adminService) Resync(
+	ctx context.Context,
+	request *pb.ResyncRequest,
+) (*pb.State, error) {
+	logger.Infof("admin call [resync] received from [%v]", peerAddress(ctx))
+	as.control.TriggerResync()
+
+	return as.state(), nil
+}
+
+func (as *adminService) GetConfig(
+	ctx context.Context,
+	request *pb.GetConfigRequest,
+) (*pb.Config, error) {
+	return &pb.Config{Tunables: tunables(as.control)}, nil
+}
+
+func (as *adminService) SetConfig(
+	ctx context.Context,
+	request *pb.SetConfigRequest,
+) (*pb.Config, error) {
+	logger.Infof(
+		"admin call [set-config] for tunable [%v] received from [%v]",
+		request.Name,
+		peerAddress(ctx),
+	)
+
+	if err := setTunable(as.control, request.Name, request.Value); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.Config{Tunables: tunables(as.control)}, nil
+}
+
+func (as *adminService) TailLogs(
+	request *pb.TailLogsRequest,
+	stream pb.Admin_TailLogsServer,
+) error {
+	if as.tail == nil {
+		return status.Error(
+			codes.FailedPrecondition,
+			"log streaming is not enabled",
+		)
+	}
+
+	remoteAddress := peerAddress(stream.Context())
+
+	logger.Infof("new logs subscriber [%v]", remoteAddress)
+	defer logger.Infof("logs subscriber [%v] left", remoteAddress)
+
+	subscription := as.tail.Subscribe()
+	defer subscription.Unsubscribe()
+
+	for {
+		select {
+		case line, ok := <-subscription.Lines():
+			if !ok {
+				// The subscription has been cancelled by the tail.
+				return status.Error(
+					codes.ResourceExhausted,
+					"subscriber does not keep up with logs",
+				)
+			}
+
+			if !strings.Contains(line, request.Filter) {
+				continue
+			}
+
+			// Logging the failures would emit another entry to the
+			// stream, so they are not logged.
+			entry, err := parseLogEntry(line)
+			if err != nil {
+				continue
+			}
+
+			if err := stream.Send(entry); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (as *adminService) state() *pb.State {
+	return &pb.State{
+		Paused:      as.control.IsPaused(),
+		TasksPaused: as.control.Scheduler().IsPaused(),
+		Schedules:   as.control.Scheduler().Schedules(),
+	}
+}
+
+// Keys of the log entry properties in the JSON encoding of the logging core.
+const (
+	logEntryTimeKey    = "ts"
+	logEntryLevelKey   = "level"
+	logEntryLoggerKey  = "logger"
+	logEntryMessageKey = "msg"
+)
+
+// parseLogEntry decodes the given JSON-encoded log entry. The properties
+// other than the time, level, logger and message are returned as fields,
+// with the values left encoded.
+func parseLogEntry(line string) (*pb.LogEntry, error) {
+	properties := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(line), &properties); err != nil {
+		return nil, err
+	}
+
+	entry := &pb.LogEntry{Fields: make(map[string]string)}
+
+	for key, value := range properties {
+		var target *string
+		switch key {
+		case logEntryTimeKey:
+			target = &entry.Time
+		case logEntryLevelKey:
+			target = &entry.Level
+		case logEntryLoggerKey:
+			target = &entry.Logger
+		case logEntryMessageKey:
+			target = &entry.Message
+		default:
+			entry.Fields[key] = string(value)
+			continue
+		}
+
+		if err := json.Unmarshal(value, target); err != nil {
+			return nil, fmt.Errorf(
+				"could not decode log entry property [%v]: [%v]",
+				key,
+				err,
+			)
+		}
+	}
+
+	return entry, nil
+}
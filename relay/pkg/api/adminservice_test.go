@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/api/pb"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newAdminTestClient(
+	t *testing.T,
+	server *AdminServer,
+	apiKey string,
+) (pb.AdminClient, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+
+	grpcServer := server.newGRPCServer()
+	go grpcServer.Serve(listener)
+
+	options := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(
+			func(ctx context.Context, address string) (net.Conn, error) {
+				return listener.Dial()
+			},
+		),
+	}
+	if apiKey != "" {
+		options = append(
+			options,
+			grpc.WithPerRPCCredentials(apiKeyCredentials(apiKey)),
+		)
+	}
+
+	connection, err := grpc.Dial("bufconn", options...)
+	if err != nil {
+		grpcServer.Stop()
+		t.Fatal(err)
+	}
+
+	return pb.NewAdminClient(connection), func() {
+		connection.Close()
+		grpcServer.Stop()
+	}
+}
+
+func TestNewAdminServer_RequiresAuthentication(t *testing.T) {
+	_, err := NewAdminServer(
+		&Config{AdminAddress: "127.0.0.1:0"},
+		header.NewControl(nil),
+		nil,
+	)
+	if err == nil {
+		t.Errorf("expected error for admin service without authentication")
+	}
+}
+
+func TestAdminService_Authentication(t *testing.T) {
+	server, err := NewAdminServer(
+		&Config{AdminAddress: "127.0.0.1:0", APIKeys: []string{"secret"}},
+		header.NewControl(nil),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = map[string]struct {
+		apiKey       string
+		expectedCode codes.Code
+	}{
+		"valid API key": {
+			apiKey:       "secret",
+			expectedCode: codes.OK,
+		},
+		"invalid API key": {
+			apiKey:       "guess",
+			expectedCode: codes.Unauthenticated,
+		},
+		"missing API key": {
+			expectedCode: codes.Unauthenticated,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			client, closeServer := newAdminTestClient(t, server, test.apiKey)
+			defer closeServer()
+
+			_, err := client.GetState(
+				context.Background(),
+				&pb.GetStateRequest{},
+			)
+			if status.Code(err) != test.expectedCode {
+				t.Errorf(
+					"unexpected status code:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedCode,
+					status.Code(err),
+				)
+			}
+		})
+	}
+}
+
+func TestAdminService_Config(t *testing.T) {
+	control := header.NewControl(nil)
+
+	server, err := NewAdminServer(
+		&Config{AdminAddress: "127.0.0.1:0", APIKeys: []string{"secret"}},
+		control,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, closeServer := newAdminTestClient(t, server, "secret")
+	defer closeServer()
+
+	ctx := context.Background()
+
+	config, err := client.SetConfig(ctx, &pb.SetConfigRequest{
+		Name:  TunableSchedulePrefix + header.TaskPull,
+		Value: "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedule := config.Tunables[TunableSchedulePrefix+header.TaskPull]
+	if schedule != "30s" {
+		t.Errorf(
+			"unexpected schedule tunable:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			"30s",
+			schedule,
+		)
+	}
+
+	if control.Scheduler().Schedules()[header.TaskPull] != "30s" {
+		t.Errorf("schedule should be set on the scheduler")
+	}
+
+	config, err = client.GetConfig(ctx, &pb.GetConfigRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := config.Tunables[TunableLogLevels]; !ok {
+		t.Errorf("log levels tunable should be listed")
+	}
+
+	_, err = client.SetConfig(ctx, &pb.SetConfigRequest{
+		Name:  "unknown",
+		Value: "1",
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected invalid argument error for unknown tunable")
+	}
+}
+
+func TestAdminService_TailLogs(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	server, err := NewAdminServer(
+		&Config{AdminAddress: "127.0.0.1:0", APIKeys: []string{"secret"}},
+		header.NewControl(nil),
+		logs.NewTail(reader),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, closeServer := newAdminTestClient(t, server, "secret")
+	defer closeServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.TailLogs(ctx, &pb.TailLogsRequest{Filter: "relay"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The subscription is established asynchronously, so the lines are
+	// written until the expected entry is received.
+	lines := `{"level":"info","ts":"2020-10-01T12:00:00.000Z",` +
+		`"logger":"tbtc-relay-header","msg":"skipped"}` + "\n" +
+		`{"level":"info","ts":"2020-10-01T12:00:00.000Z",` +
+		`"logger":"other","msg":"filtered out"}` + "\n" +
+		`{"level":"warn","ts":"2020-10-01T12:00:01.000Z",` +
+		`"logger":"tbtc-relay-header","msg":"pushed headers",` +
+		`"correlation":"abc"}` + "\n"
+
+	writing := make(chan struct{})
+	defer close(writing)
+
+	go func() {
+		for {
+			select {
+			case <-writing:
+				return
+			case <-time.After(10 * time.Millisecond):
+				if _, err := writer.Write([]byte(lines)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		entry, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.Logger != "tbtc-relay-header" {
+			t.Fatalf("unexpected entry of logger [%v]", entry.Logger)
+		}
+
+		if entry.Message == "skipped" {
+			continue
+		}
+
+		if entry.Level != "warn" ||
+			entry.Time != "2020-10-01T12:00:01.000Z" ||
+			entry.Message != "pushed headers" ||
+			entry.Fields["correlation"] != `"abc"` {
+			t.Errorf("unexpected entry: [%v]", entry)
+		}
+
+		return
+	}
+}
+
+func TestAdminService_TailLogsNotEnabled(t *testing.T) {
+	server, err := NewAdminServer(
+		&Config{AdminAddress: "127.0.0.1:0", APIKeys: []string{"secret"}},
+		header.NewControl(nil),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, closeServer := newAdminTestClient(t, server, "secret")
+	defer closeServer()
+
+	stream, err := client.TailLogs(
+		context.Background(),
+		&pb.TailLogsRequest{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stream.Recv(); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected failed precondition error, got [%v]", err)
+	}
+}
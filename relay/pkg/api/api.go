@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-log"
+)
+
+var logger = log.Logger("tbtc-relay-api")
+
+const (
+	// Maximum time the server waits for the request headers to be read.
+	readHeaderTimeout = 10 * time.Second
+
+	// Maximum time the server waits for the active connections to be closed
+	// on shutdown.
+	shutdownTimeout = 5 * time.Second
+)
+
+// Config holds the configuration of the operator API server.
+type Config struct {
+	// Address is the host:port address the server listens on. If empty,
+	// the server is disabled.
+	Address string
+
+	// APIKeys is a list of keys accepted by the server. Clients must pass
+	// one of them in the `Authorization: Bearer <key>` header.
+	APIKeys []string
+
+	// TLSCertFile and TLSKeyFile are paths to the PEM-encoded certificate
+	// and private key used to serve the API over TLS.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ClientCAFile is a path to the PEM-encoded certificates of authorities
+	// used to verify client certificates. If set, clients must authenticate
+	// using a certificate signed by one of them (mutual TLS).
+	ClientCAFile string
+
+	// AllowedIPs is a list of IP addresses or CIDR ranges allowed to access
+	// the server. If empty, all addresses are allowed.
+	AllowedIPs []string
+
+	// AdminAddress is the host:port address the gRPC admin service listens
+	// on. The service is authenticated just like the API, but it is never
+	// served without API keys or client certificates. If empty, the admin
+	// service is disabled.
+	AdminAddress string
+
+	// LogStreaming enables the streaming of the relay logs by the gRPC admin
+	// service.
+	LogStreaming bool
+}
+
+// IsEnabled checks whether the operator API server is configured.
+func (c *Config) IsEnabled() bool {
+	return c.Address != ""
+}
+
+// Server is the operator API server. Handlers registered on the server are
+// served only to authenticated clients, unless the server runs in the public
+// mode, see NewPublicServer.
+type Server struct {
+	config        *Config
+	mux           *http.ServeMux
+	authenticator *authenticator
+	tlsConfig     *tls.Config
+	authenticated bool
+
+	// publicGuard limits the requests of the public server. It is nil if
+	// the server does not run in the public mode.
+	publicGuard *publicGuard
+}
+
+// NewServer creates a new operator API server. An error is returned if
+// the configuration is not valid or the server would be exposed beyond
+// localhost without any authentication.
+func NewServer(config *Config) (*Server, error) {
+	authenticator, err := newAuthenticator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticated := authenticator.requiresKey() ||
+		(tlsConfig != nil &&
+			tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert)
+
+	if !authenticated {
+		loopback, err := isLoopbackAddress(config.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		if !loopback {
+			return nil, fmt.Errorf(
+				"refusing to expose the API on [%v] without authentication; "+
+					"configure API keys or client certificates",
+				config.Address,
+			)
+		}
+	}
+
+	return &Server{
+		config:        config,
+		mux:           http.NewServeMux(),
+		authenticator: authenticator,
+		tlsConfig:     tlsConfig,
+		authenticated: authenticated,
+	}, nil
+}
+
+// IsAuthenticated returns whether clients must authenticate with an API key
+// or a client certificate.
+func (s *Server) IsAuthenticated() bool {
+	return s.authenticated
+}
+
+// Handle registers the handler for the given pattern. Admin endpoints are
+// never registered on the public server.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	if s.IsPublic() && strings.HasPrefix(pattern, adminPathPrefix) {
+		logger.Warnf(
+			"refusing to serve admin endpoint [%v] on the public API",
+			pattern,
+		)
+		return
+	}
+
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers the handler function for the given pattern.
+func (s *Server) HandleFunc(
+	pattern string,
+	handler func(http.ResponseWriter, *http.Request),
+) {
+	s.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Start starts serving the API in the background. The server is shut down
+// once the passed context is done.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return fmt.Errorf(
+			"could not listen on [%v]: [%v]",
+			s.config.Address,
+			err,
+		)
+	}
+
+	server := &http.Server{
+		Handler:           s.authenticator.wrap(s.mux),
+		TLSConfig:         s.tlsConfig,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	if s.IsPublic() {
+		server.Handler = s.publicGuard.wrap(server.Handler)
+		server.ReadTimeout = publicReadTimeout
+		server.WriteTimeout = publicWriteTimeout
+		server.IdleTimeout = publicIdleTimeout
+		server.MaxHeaderBytes = publicMaxHeaderBytes
+	}
+
+	go func() {
+		var err error
+		if s.tlsConfig != nil {
+			err = server.ServeTLS(
+				listener,
+				s.config.TLSCertFile,
+				s.config.TLSKeyFile,
+			)
+		} else {
+			err = server.Serve(listener)
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			logger.Errorf("API server failed: [%v]", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(
+			context.Background(),
+			shutdownTimeout,
+		)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Warnf("could not shut down API server: [%v]", err)
+		}
+	}()
+
+	logger.Infof(
+		"API server listening on [%v] (TLS: [%v], public: [%v])",
+		listener.Addr(),
+		s.tlsConfig != nil,
+		s.IsPublic(),
+	)
+
+	return nil
+}
+
+func newTLSConfig(config *Config) (*tls.Config, error) {
+	if config.TLSCertFile == "" && config.TLSKeyFile == "" {
+		if config.ClientCAFile != "" {
+			return nil, fmt.Errorf(
+				"client certificates verification requires TLS to be " +
+					"configured",
+			)
+		}
+
+		return nil, nil
+	}
+
+	if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+		return nil, fmt.Errorf(
+			"both TLS certificate and key files must be configured",
+		)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not read client CA file [%v]: [%v]",
+				config.ClientCAFile,
+				err,
+			)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf(
+				"no certificates found in client CA file [%v]",
+				config.ClientCAFile,
+			)
+		}
+
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+func isLoopbackAddress(address string) (bool, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false, fmt.Errorf(
+			"invalid API address [%v]: [%v]",
+			address,
+			err,
+		)
+	}
+
+	if host == "localhost" {
+		return true, nil
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback(), nil
+}
+
+// writeJSON writes the given value as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Warnf("could not write API response: [%v]", err)
+	}
+}
+
+// writeError writes the given error message as a JSON response.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
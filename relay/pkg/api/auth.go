@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authenticator guards the API handlers. A request is let through only if
+// it comes from an allowed address and carries a valid API key, if any keys
+// are configured. Client certificates are verified during the TLS handshake.
+type authenticator struct {
+	apiKeys    [][]byte
+	allowedIPs []*net.IPNet
+}
+
+func newAuthenticator(config *Config) (*authenticator, error) {
+	authenticator := &authenticator{}
+
+	for _, key := range config.APIKeys {
+		if key == "" {
+			return nil, fmt.Errorf("API key must not be empty")
+		}
+
+		authenticator.apiKeys = append(authenticator.apiKeys, []byte(key))
+	}
+
+	for _, value := range config.AllowedIPs {
+		network, err := parseAllowedIP(value)
+		if err != nil {
+			return nil, err
+		}
+
+		authenticator.allowedIPs = append(authenticator.allowedIPs, network)
+	}
+
+	return authenticator, nil
+}
+
+// parseAllowedIP parses an IP address or a CIDR range.
+func parseAllowedIP(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range [%v]: [%v]", value, err)
+		}
+
+		return network, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address [%v]", value)
+	}
+
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func (a *authenticator) requiresKey() bool {
+	return len(a.apiKeys) > 0
+}
+
+func (a *authenticator) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.isAllowedAddress(r.RemoteAddr) {
+			logger.Warnf(
+				"rejected API request from not allowed address [%v]",
+				r.RemoteAddr,
+			)
+			writeError(w, http.StatusForbidden, "address not allowed")
+			return
+		}
+
+		if !a.isAuthorized(r) {
+			logger.Warnf(
+				"rejected unauthorized API request from [%v]",
+				r.RemoteAddr,
+			)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (a *authenticator) unaryInterceptor(
+	ctx context.Context,
+	request interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if err := a.authenticateCall(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, request)
+}
+
+func (a *authenticator) streamInterceptor(
+	server interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := a.authenticateCall(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(server, stream)
+}
+
+// authenticateCall guards the gRPC calls the same way the API requests are
+// guarded, with the API key passed in the authorization metadata.
+func (a *authenticator) authenticateCall(
+	ctx context.Context,
+	method string,
+) error {
+	remoteAddress := peerAddress(ctx)
+
+	if !a.isAllowedAddress(remoteAddress) {
+		logger.Warnf(
+			"rejected admin call [%v] from not allowed address [%v]",
+			method,
+			remoteAddress,
+		)
+		return status.Error(codes.PermissionDenied, "address not allowed")
+	}
+
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	if !a.isAuthorizedBy(authorization) {
+		logger.Warnf(
+			"rejected unauthorized admin call [%v] from [%v]",
+			method,
+			remoteAddress,
+		)
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	return nil
+}
+
+// peerAddress returns the address of the client making the gRPC call.
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+func (a *authenticator) isAllowedAddress(remoteAddress string) bool {
+	if len(a.allowedIPs) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddress)
+	if err != nil {
+		host = remoteAddress
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range a.allowedIPs {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *authenticator) isAuthorized(r *http.Request) bool {
+	return a.isAuthorizedBy(r.Header.Get("Authorization"))
+}
+
+// isAuthorizedBy checks whether the given value of the authorization header,
+// or the authorization metadata of gRPC calls, carries a valid API key.
+func (a *authenticator) isAuthorizedBy(header string) bool {
+	if !a.requiresKey() {
+		return true
+	}
+
+	const prefix = "Bearer "
+
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+
+	key := []byte(strings.TrimPrefix(header, prefix))
+
+	authorized := false
+	for _, apiKey := range a.apiKeys {
+		// Compare all keys in constant time to not leak which one matched.
+		if subtle.ConstantTimeCompare(apiKey, key) == 1 {
+			authorized = true
+		}
+	}
+
+	return authorized
+}
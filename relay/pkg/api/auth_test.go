@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticator(t *testing.T) {
+	var tests = map[string]struct {
+		config         *Config
+		remoteAddress  string
+		authorization  string
+		expectedStatus int
+	}{
+		"no authentication configured": {
+			config:         &Config{},
+			remoteAddress:  "127.0.0.1:5000",
+			expectedStatus: http.StatusOK,
+		},
+		"valid API key": {
+			config:         &Config{APIKeys: []string{"key-1", "key-2"}},
+			remoteAddress:  "127.0.0.1:5000",
+			authorization:  "Bearer key-2",
+			expectedStatus: http.StatusOK,
+		},
+		"invalid API key": {
+			config:         &Config{APIKeys: []string{"key-1"}},
+			remoteAddress:  "127.0.0.1:5000",
+			authorization:  "Bearer key-2",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"missing API key": {
+			config:         &Config{APIKeys: []string{"key-1"}},
+			remoteAddress:  "127.0.0.1:5000",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"address in allowed range": {
+			config:         &Config{AllowedIPs: []string{"10.0.0.0/8"}},
+			remoteAddress:  "10.1.2.3:5000",
+			expectedStatus: http.StatusOK,
+		},
+		"allowed single address": {
+			config:         &Config{AllowedIPs: []string{"192.168.1.10"}},
+			remoteAddress:  "192.168.1.10:5000",
+			expectedStatus: http.StatusOK,
+		},
+		"address not allowed": {
+			config:         &Config{AllowedIPs: []string{"10.0.0.0/8"}},
+			remoteAddress:  "192.168.1.10:5000",
+			expectedStatus: http.StatusForbidden,
+		},
+		"allowed address with invalid API key": {
+			config: &Config{
+				APIKeys:    []string{"key-1"},
+				AllowedIPs: []string{"10.0.0.0/8"},
+			},
+			remoteAddress:  "10.1.2.3:5000",
+			authorization:  "Bearer key-2",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			authenticator, err := newAuthenticator(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := authenticator.wrap(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				},
+			))
+
+			request := httptest.NewRequest(http.MethodGet, "/status", nil)
+			request.RemoteAddr = test.remoteAddress
+			if test.authorization != "" {
+				request.Header.Set("Authorization", test.authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if test.expectedStatus != recorder.Code {
+				t.Errorf(
+					"unexpected status code:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedStatus,
+					recorder.Code,
+				)
+			}
+		})
+	}
+}
+
+func TestNewServer_RequiresAuthenticationBeyondLocalhost(t *testing.T) {
+	var tests = map[string]struct {
+		config      *Config
+		expectError bool
+	}{
+		"localhost without authentication": {
+			config:      &Config{Address: "127.0.0.1:8081"},
+			expectError: false,
+		},
+		"public address without authentication": {
+			config:      &Config{Address: "0.0.0.0:8081"},
+			expectError: true,
+		},
+		"public address with API key": {
+			config: &Config{
+				Address: "0.0.0.0:8081",
+				APIKeys: []string{"key-1"},
+			},
+			expectError: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := NewServer(test.config)
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Errorf(
+					"unexpected result:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+		})
+	}
+}
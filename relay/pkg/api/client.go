@@ -0,0 +1,243 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeout of requests sent by the API client.
+const clientTimeout = 10 * time.Second
+
+// ClientConfig holds the configuration of the operator API client.
+type ClientConfig struct {
+	// Address is the host:port address of the API server.
+	Address string
+
+	// APIKey is the key passed to the server. May be empty if the server
+	// does not require API keys.
+	APIKey string
+
+	// TLS determines whether the server is accessed over TLS.
+	TLS bool
+
+	// CACertFile is a path to the PEM-encoded certificates used to verify
+	// the server certificate. If empty, the system certificates are used.
+	CACertFile string
+
+	// ClientCertFile and ClientKeyFile are paths to the PEM-encoded client
+	// certificate and private key used for mutual TLS.
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// Client is a client of the operator API.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a new operator API client.
+func NewClient(config *ClientConfig) (*Client, error) {
+	transport := &http.Transport{}
+	scheme := "http"
+
+	if config.TLS {
+		scheme = "https"
+
+		tlsConfig, err := newClientTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &Client{
+		baseURL: fmt.Sprintf("%v://%v", scheme, config.Address),
+		apiKey:  config.APIKey,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   clientTimeout,
+		},
+	}, nil
+}
+
+// newClientTLSConfig returns the TLS configuration of clients verifying the
+// server certificate and, if configured, presenting a client certificate.
+func newClientTLSConfig(config *ClientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.CACertFile != "" {
+		pem, err := ioutil.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not read CA file [%v]: [%v]",
+				config.CACertFile,
+				err,
+			)
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf(
+				"no certificates found in CA file [%v]",
+				config.CACertFile,
+			)
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(
+			config.ClientCertFile,
+			config.ClientKeyFile,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not load client certificate: [%v]",
+				err,
+			)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// AdminState returns the current admin state of the relay.
+func (c *Client) AdminState() (*AdminStateResponse, error) {
+	return c.admin(http.MethodGet, AdminStatePath)
+}
+
+// AdminAction performs the admin action available under the given path.
+func (c *Client) AdminAction(path string) (*AdminStateResponse, error) {
+	return c.admin(http.MethodPost, path)
+}
+
+// AdminSchedule sets the schedule of the given relay task. An empty schedule
+// restores the default interval of the task.
+func (c *Client) AdminSchedule(
+	task string,
+	schedule string,
+) (*AdminStateResponse, error) {
+	query := url.Values{}
+	query.Set("task", task)
+	query.Set("schedule", schedule)
+
+	return c.admin(http.MethodPost, AdminSchedulePath+"?"+query.Encode())
+}
+
+// RotateKey starts the rotation of the operator account to the one of the
+// given key file, which must be readable by the relay. The rotation is
+// aborted if it does not complete within the given timeout.
+func (c *Client) RotateKey(
+	keyFile string,
+	password string,
+	timeout time.Duration,
+) (*KeyRotationResponse, error) {
+	form := url.Values{}
+	form.Set("keyFile", keyFile)
+	form.Set("password", password)
+	form.Set("timeout", strconv.Itoa(int(timeout.Seconds())))
+
+	response := &KeyRotationResponse{}
+	if err := c.send(
+		http.MethodPost,
+		AdminRotateKeyPath,
+		form,
+		response,
+	); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// KeyRotationStatus returns the status of the latest operator account
+// rotation.
+func (c *Client) KeyRotationStatus() (*KeyRotationResponse, error) {
+	response := &KeyRotationResponse{}
+	if err := c.do(http.MethodGet, AdminRotateKeyPath, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *Client) admin(method string, path string) (*AdminStateResponse, error) {
+	response := &AdminStateResponse{}
+	if err := c.do(method, path, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *Client) do(method string, path string, result interface{}) error {
+	return c.send(method, path, nil, result)
+}
+
+// send sends the request with the given form, if any, in its body. Secrets
+// are sent in the body so they do not end up in the logged URLs.
+func (c *Client) send(
+	method string,
+	path string,
+	form url.Values,
+	result interface{},
+) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	request, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("could not create request: [%v]", err)
+	}
+
+	if form != nil {
+		request.Header.Set(
+			"Content-Type",
+			"application/x-www-form-urlencoded",
+		)
+	}
+
+	if c.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not send request: [%v]", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		errorResponse := make(map[string]string)
+		_ = json.NewDecoder(response.Body).Decode(&errorResponse)
+
+		return fmt.Errorf(
+			"request failed with status [%v]: [%v]",
+			response.StatusCode,
+			errorResponse["error"],
+		)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("could not decode response: [%v]", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/gorilla/websocket"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+)
+
+// Paths of the deposit monitor endpoints.
+const (
+	DepositsSubscriptionPath = "/deposits/subscribe"
+	AdminDepositsPath        = "/admin/deposits"
+	AdminTransactionsPath    = "/admin/transactions"
+	AdminDepositsScanPath    = "/admin/deposits/scan"
+)
+
+// DepositEvent is a single message sent to the deposit events subscribers.
+type DepositEvent struct {
+	Type            deposit.EventType `json:"type"`
+	Address         string            `json:"address,omitempty"`
+	TxID            string            `json:"txid"`
+	OutputIndex     uint32            `json:"outputIndex"`
+	BlockHeight     int64             `json:"blockHeight,omitempty"`
+	Value           int64             `json:"value"`
+	ConflictingTxID string            `json:"conflictingTxid,omitempty"`
+	Fee             int64             `json:"fee"`
+	VirtualSize     int64             `json:"vsize"`
+	FeeRate         float64           `json:"feeRate"`
+}
+
+// AdminDepositsResponse is the response of the deposit addresses admin
+// endpoint.
+type AdminDepositsResponse struct {
+	Addresses []string `json:"addresses"`
+}
+
+// AdminTransactionsResponse is the response of the tracked transactions
+// admin endpoint.
+type AdminTransactionsResponse struct {
+	TxIDs []string `json:"txids"`
+}
+
+// AdminDepositsScanResponse is the response of the block range scan admin
+// endpoint.
+type AdminDepositsScanResponse struct {
+	Blocks        int64 `json:"blocks"`
+	FetchedBlocks int64 `json:"fetchedBlocks"`
+	Payments      int   `json:"payments"`
+	UsedFilters   bool  `json:"usedFilters"`
+}
+
+// RegisterDepositHandlers registers the websocket endpoint which streams
+// deposit events and, if the server requires authentication, the admin
+// endpoints managing watched deposit addresses and tracked transactions.
+// Addresses are listed with GET, watched with POST and unwatched with DELETE
+// requests, passing the address in the `address` query parameter.
+// Transactions are listed with GET and tracked with POST requests, passing
+// the transaction ID in the `txid` query parameter. Block ranges are scanned
+// for payments to watched addresses with POST requests, passing the range in
+// the `from` and `to` query parameters.
+func RegisterDepositHandlers(server *Server, monitor *deposit.Monitor) {
+	server.HandleFunc(
+		DepositsSubscriptionPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			connection, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				// The upgrader already replied with an error.
+				logger.Warnf("could not upgrade connection: [%v]", err)
+				return
+			}
+
+			logger.Infof("new deposits subscriber [%v]", r.RemoteAddr)
+
+			streamDeposits(connection, monitor.Feed().Subscribe())
+
+			logger.Infof("deposits subscriber [%v] left", r.RemoteAddr)
+		},
+	)
+
+	if !server.IsAuthenticated() {
+		return
+	}
+
+	server.HandleFunc(
+		AdminDepositsPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			address := r.URL.Query().Get("address")
+
+			switch r.Method {
+			case http.MethodGet:
+			case http.MethodPost:
+				if err := monitor.Watch(address); err != nil {
+					writeError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			case http.MethodDelete:
+				monitor.Unwatch(address)
+			default:
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+
+			writeJSON(w, http.StatusOK, &AdminDepositsResponse{
+				Addresses: monitor.Addresses(),
+			})
+		},
+	)
+
+	server.HandleFunc(
+		AdminTransactionsPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+			case http.MethodPost:
+				txID, err := chainhash.NewHashFromStr(
+					r.URL.Query().Get("txid"),
+				)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+
+				err = monitor.Track(r.Context(), btc.Digest(*txID))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			default:
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+
+			tracked := monitor.Tracked()
+
+			response := &AdminTransactionsResponse{
+				TxIDs: make([]string, len(tracked)),
+			}
+			for i, txID := range tracked {
+				response.TxIDs[i] = chainhash.Hash(txID).String()
+			}
+
+			writeJSON(w, http.StatusOK, response)
+		},
+	)
+
+	server.HandleFunc(
+		AdminDepositsScanPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+
+			query := r.URL.Query()
+
+			fromHeight, err := strconv.ParseInt(query.Get("from"), 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid from height")
+				return
+			}
+
+			toHeight, err := strconv.ParseInt(query.Get("to"), 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid to height")
+				return
+			}
+
+			result, err := monitor.ScanBlocks(r.Context(), fromHeight, toHeight)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			writeJSON(w, http.StatusOK, &AdminDepositsScanResponse{
+				Blocks:        result.Blocks,
+				FetchedBlocks: result.FetchedBlocks,
+				Payments:      result.Payments,
+				UsedFilters:   result.UsedFilters,
+			})
+		},
+	)
+}
+
+func streamDeposits(
+	connection *websocket.Conn,
+	subscription *deposit.Subscription,
+) {
+	defer connection.Close()
+	defer subscription.Unsubscribe()
+
+	// Subscribers are not expected to send anything but the reads are
+	// needed to process control messages and detect closed connections.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := connection.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	pingTicker := time.NewTicker(subscriptionPingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// The subscription has been cancelled by the feed.
+				_ = connection.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(
+						websocket.CloseTryAgainLater,
+						"subscriber does not keep up with events",
+					),
+					time.Now().Add(subscriptionWriteTimeout),
+				)
+				return
+			}
+
+			_ = connection.SetWriteDeadline(
+				time.Now().Add(subscriptionWriteTimeout),
+			)
+			message := &DepositEvent{
+				Type:        event.Type,
+				Address:     event.Address,
+				TxID:        chainhash.Hash(event.TxID).String(),
+				OutputIndex: event.OutputIndex,
+				BlockHeight: event.BlockHeight,
+				Value:       event.Value,
+				Fee:         event.Fee,
+				VirtualSize: event.VirtualSize,
+				FeeRate:     event.FeeRate,
+			}
+			if event.ConflictingTxID != nil {
+				message.ConflictingTxID = chainhash.Hash(
+					*event.ConflictingTxID,
+				).String()
+			}
+
+			if err := connection.WriteJSON(message); err != nil {
+				logger.Warnf("could not send deposit event: [%v]", err)
+				return
+			}
+		case <-pingTicker.C:
+			if err := connection.WriteControl(
+				websocket.PingMessage,
+				nil,
+				time.Now().Add(subscriptionWriteTimeout),
+			); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/proof"
+)
+
+// ProofPath is the path of the transaction proof endpoint.
+const ProofPath = "/proof"
+
+// ProofResponse is the response of the transaction proof endpoint.
+type ProofResponse struct {
+	TxID        string `json:"txid"`
+	BlockHash   string `json:"blockHash"`
+	BlockHeight int64  `json:"blockHeight"`
+	Index       uint64 `json:"index"`
+	MerkleProof string `json:"merkleProof"`
+	// Source is the source the proof has been built from: `cache`, `node`
+	// or `archive`.
+	Source string `json:"source"`
+}
+
+// RegisterProofHandler registers the endpoint returning the merkle proof of
+// inclusion of the confirmed transaction with the ID passed in the `txid`
+// query parameter.
+func RegisterProofHandler(server *Server, cache *proof.Cache) {
+	server.HandleFunc(ProofPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		txID, err := chainhash.NewHashFromStr(r.URL.Query().Get("txid"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		bundle, err := cache.TransactionProof(r.Context(), btc.Digest(*txID))
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, &ProofResponse{
+			TxID:        chainhash.Hash(bundle.TxID).String(),
+			BlockHash:   bundle.BlockDigest.String(),
+			BlockHeight: bundle.BlockHeight,
+			Index:       bundle.Index,
+			MerkleProof: hex.EncodeToString(bundle.MerkleProof),
+			Source:      bundle.Source,
+		})
+	})
+}
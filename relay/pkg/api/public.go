@@ -0,0 +1,344 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+// public.go file contains the public mode of the API, in which the status
+// and transaction proof endpoints are served to anyone, e.g. by community
+// members hosting a public tBTC proof service backed by their relay. The
+// public server never serves the admin endpoints, limits the rate of requests
+// of each client IP address and caps the size of the responses.
+
+const (
+	// Default number of requests per second a single client IP address can
+	// make in the public mode.
+	defaultPublicRateLimit = 2
+
+	// Default number of requests a single client IP address can make at
+	// once, exceeding the rate limit, in the public mode.
+	defaultPublicRateBurst = 10
+
+	// Default maximum size of a single response in the public mode.
+	defaultPublicMaxResponseSize = 64 * 1024
+
+	// Timeouts of the public server, guarding it against slow clients.
+	publicReadTimeout  = 10 * time.Second
+	publicWriteTimeout = 30 * time.Second
+	publicIdleTimeout  = 60 * time.Second
+
+	// Maximum size of the request headers in the public mode.
+	publicMaxHeaderBytes = 8 * 1024
+
+	// Time after which the rate limit state of an idle client is forgotten.
+	clientIdleTime = 10 * time.Minute
+)
+
+// PublicConfig holds the configuration of the public API server.
+type PublicConfig struct {
+	// Address is the host:port address the public server listens on. If
+	// empty, the public server is disabled.
+	Address string
+
+	// TLSCertFile and TLSKeyFile are paths to the PEM-encoded certificate
+	// and private key used to serve the public API over TLS.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// RateLimit is the number of requests per second a single client IP
+	// address can make. If zero, a default value is used.
+	RateLimit float64
+
+	// RateBurst is the number of requests a single client IP address can
+	// make at once, exceeding the rate limit. If zero, a default value is
+	// used.
+	RateBurst int
+
+	// MaxResponseSize is the maximum size of a single response, in bytes.
+	// Larger responses are replaced with an error. If zero, a default value
+	// is used.
+	MaxResponseSize int
+
+	// TrustedProxies is a list of IP addresses or CIDR ranges of reverse
+	// proxies in front of the public server. The client IP address of
+	// requests coming from them is taken from the `X-Forwarded-For` header.
+	TrustedProxies []string
+}
+
+// IsEnabled checks whether the public API server is configured.
+func (pc *PublicConfig) IsEnabled() bool {
+	return pc.Address != ""
+}
+
+// NewPublicServer creates a new public API server. Clients do not have to
+// authenticate, but the rate of their requests and the size of responses are
+// limited. Admin endpoints are never registered on the public server.
+func NewPublicServer(config *PublicConfig) (*Server, error) {
+	serverConfig := &Config{
+		Address:     config.Address,
+		TLSCertFile: config.TLSCertFile,
+		TLSKeyFile:  config.TLSKeyFile,
+	}
+
+	if _, _, err := net.SplitHostPort(config.Address); err != nil {
+		return nil, fmt.Errorf(
+			"invalid public API address [%v]: [%v]",
+			config.Address,
+			err,
+		)
+	}
+
+	tlsConfig, err := newTLSConfig(serverConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	guard, err := newPublicGuard(config, clock.System)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		config:        serverConfig,
+		mux:           http.NewServeMux(),
+		authenticator: &authenticator{},
+		tlsConfig:     tlsConfig,
+		publicGuard:   guard,
+	}, nil
+}
+
+// IsPublic returns whether the server runs in the public mode.
+func (s *Server) IsPublic() bool {
+	return s.publicGuard != nil
+}
+
+// publicGuard limits the rate of requests of each client and the size of
+// responses of the public server.
+type publicGuard struct {
+	clock           clock.Clock
+	rate            float64
+	burst           float64
+	maxResponseSize int
+	trustedProxies  []*net.IPNet
+
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket holds the rate limit state of a single client.
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func newPublicGuard(
+	config *PublicConfig,
+	clock clock.Clock,
+) (*publicGuard, error) {
+	if config.RateLimit < 0 || config.RateBurst < 0 ||
+		config.MaxResponseSize < 0 {
+		return nil, fmt.Errorf("public API limits must not be negative")
+	}
+
+	guard := &publicGuard{
+		clock:           clock,
+		rate:            config.RateLimit,
+		burst:           float64(config.RateBurst),
+		maxResponseSize: config.MaxResponseSize,
+		buckets:         make(map[string]*tokenBucket),
+		lastSweep:       clock.Now(),
+	}
+
+	if guard.rate == 0 {
+		guard.rate = defaultPublicRateLimit
+	}
+	if guard.burst == 0 {
+		guard.burst = defaultPublicRateBurst
+	}
+	if guard.maxResponseSize == 0 {
+		guard.maxResponseSize = defaultPublicMaxResponseSize
+	}
+
+	for _, value := range config.TrustedProxies {
+		network, err := parseAllowedIP(value)
+		if err != nil {
+			return nil, err
+		}
+
+		guard.trustedProxies = append(guard.trustedProxies, network)
+	}
+
+	return guard, nil
+}
+
+func (pg *publicGuard) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := pg.clientAddress(r)
+
+		if wait, ok := pg.allow(client); !ok {
+			logger.Debugf("rate limited public API request from [%v]", client)
+			w.Header().Set(
+				"Retry-After",
+				strconv.Itoa(int(math.Ceil(wait.Seconds()))),
+			)
+			writeError(w, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+
+		recorder := &cappedResponseWriter{
+			header:  make(http.Header),
+			status:  http.StatusOK,
+			maxSize: pg.maxResponseSize,
+		}
+
+		handler.ServeHTTP(recorder, r)
+
+		if recorder.exceeded {
+			logger.Warnf(
+				"public API response to [%v] exceeded [%v] bytes",
+				r.URL.Path,
+				pg.maxResponseSize,
+			)
+			writeError(w, http.StatusInternalServerError, "response too large")
+			return
+		}
+
+		for key, values := range recorder.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.status)
+		if _, err := w.Write(recorder.body.Bytes()); err != nil {
+			logger.Debugf("could not write public API response: [%v]", err)
+		}
+	})
+}
+
+// allow takes a token from the bucket of the given client. If there are no
+// tokens left, the time after which the next one is available is returned.
+func (pg *publicGuard) allow(client string) (time.Duration, bool) {
+	pg.mutex.Lock()
+	defer pg.mutex.Unlock()
+
+	now := pg.clock.Now()
+	pg.sweep(now)
+
+	bucket, ok := pg.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: pg.burst, updatedAt: now}
+		pg.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(
+		pg.burst,
+		bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*pg.rate,
+	)
+	bucket.updatedAt = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / pg.rate * float64(time.Second))
+		return wait, false
+	}
+
+	bucket.tokens--
+
+	return 0, true
+}
+
+// sweep forgets the clients idle for long enough to have their buckets
+// refilled, so the state does not grow with the number of clients seen.
+func (pg *publicGuard) sweep(now time.Time) {
+	if now.Sub(pg.lastSweep) < clientIdleTime {
+		return
+	}
+
+	for client, bucket := range pg.buckets {
+		if now.Sub(bucket.updatedAt) >= clientIdleTime {
+			delete(pg.buckets, client)
+		}
+	}
+
+	pg.lastSweep = now
+}
+
+// clientAddress returns the IP address of the client making the request.
+// The address is taken from the `X-Forwarded-For` header only if the request
+// comes from a trusted proxy; the rightmost address not belonging to
+// a trusted proxy is used, as the ones to the left can be forged.
+func (pg *publicGuard) clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !pg.isTrustedProxy(host) {
+		return host
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(forwarded[i])
+		if address == "" {
+			continue
+		}
+
+		if !pg.isTrustedProxy(address) {
+			return address
+		}
+	}
+
+	return host
+}
+
+func (pg *publicGuard) isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range pg.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cappedResponseWriter buffers the response until the handler completes,
+// so a response exceeding the maximum size can be replaced with an error.
+type cappedResponseWriter struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	maxSize  int
+	exceeded bool
+}
+
+func (crw *cappedResponseWriter) Header() http.Header {
+	return crw.header
+}
+
+func (crw *cappedResponseWriter) WriteHeader(status int) {
+	crw.status = status
+}
+
+func (crw *cappedResponseWriter) Write(data []byte) (int, error) {
+	if crw.exceeded || crw.body.Len()+len(data) > crw.maxSize {
+		crw.exceeded = true
+		crw.body.Reset()
+		return 0, fmt.Errorf("response exceeds [%v] bytes", crw.maxSize)
+	}
+
+	return crw.body.Write(data)
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+func TestPublicGuard_RateLimit(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+	guard, err := newPublicGuard(
+		&PublicConfig{RateLimit: 1, RateBurst: 2},
+		fakeClock,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := guard.wrap(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, "ok")
+		},
+	))
+
+	request := func(remoteAddress string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/status", nil)
+		request.RemoteAddr = remoteAddress
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	expectedStatuses := []int{
+		http.StatusOK,
+		http.StatusOK,
+		http.StatusTooManyRequests,
+	}
+
+	for i, expectedStatus := range expectedStatuses {
+		if status := request("10.0.0.1:5000").Code; status != expectedStatus {
+			t.Errorf(
+				"unexpected status of request [%v]\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]",
+				i,
+				expectedStatus,
+				status,
+			)
+		}
+	}
+
+	// Other clients are limited separately.
+	if status := request("10.0.0.2:5000").Code; status != http.StatusOK {
+		t.Errorf("request of another client has been limited: [%v]", status)
+	}
+
+	fakeClock.Advance(time.Second)
+
+	if status := request("10.0.0.1:5000").Code; status != http.StatusOK {
+		t.Errorf("request after the refill has been limited: [%v]", status)
+	}
+}
+
+func TestPublicGuard_ClientAddress(t *testing.T) {
+	guard, err := newPublicGuard(
+		&PublicConfig{TrustedProxies: []string{"10.0.0.0/8"}},
+		clock.NewFake(time.Unix(1000, 0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = map[string]struct {
+		remoteAddress   string
+		forwardedFor    string
+		expectedAddress string
+	}{
+		"direct client": {
+			remoteAddress:   "203.0.113.5:5000",
+			expectedAddress: "203.0.113.5",
+		},
+		"forged header of direct client": {
+			remoteAddress:   "203.0.113.5:5000",
+			forwardedFor:    "198.51.100.1",
+			expectedAddress: "203.0.113.5",
+		},
+		"client behind trusted proxy": {
+			remoteAddress:   "10.0.0.1:5000",
+			forwardedFor:    "198.51.100.1, 203.0.113.5",
+			expectedAddress: "203.0.113.5",
+		},
+		"client behind chained trusted proxies": {
+			remoteAddress:   "10.0.0.1:5000",
+			forwardedFor:    "203.0.113.5, 10.0.0.2",
+			expectedAddress: "203.0.113.5",
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/status", nil)
+			request.RemoteAddr = test.remoteAddress
+			if test.forwardedFor != "" {
+				request.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+
+			if address := guard.clientAddress(request); address !=
+				test.expectedAddress {
+				t.Errorf(
+					"unexpected client address\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]",
+					test.expectedAddress,
+					address,
+				)
+			}
+		})
+	}
+}
+
+func TestPublicGuard_MaxResponseSize(t *testing.T) {
+	guard, err := newPublicGuard(
+		&PublicConfig{MaxResponseSize: 16},
+		clock.NewFake(time.Unix(1000, 0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := guard.wrap(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, strings.Repeat("a", 32))
+		},
+	))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodGet, "/status", nil),
+	)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf(
+			"unexpected status\nexpected: [%v]\nactual:   [%v]",
+			http.StatusInternalServerError,
+			recorder.Code,
+		)
+	}
+
+	if strings.Contains(recorder.Body.String(), "aaaa") {
+		t.Errorf("oversized response has been served")
+	}
+}
+
+func TestPublicServer_NoAdminRoutes(t *testing.T) {
+	server, err := NewPublicServer(&PublicConfig{Address: "0.0.0.0:8082"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterAdminHandlers(server, header.NewControl(nil))
+	server.HandleFunc(AdminPausePath, func(http.ResponseWriter, *http.Request) {})
+
+	recorder := httptest.NewRecorder()
+	server.mux.ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodPost, AdminPausePath, nil),
+	)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf(
+			"unexpected status\nexpected: [%v]\nactual:   [%v]",
+			http.StatusNotFound,
+			recorder.Code,
+		)
+	}
+}
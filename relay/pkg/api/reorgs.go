@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// ReorgsPath is the path of the reorg history endpoint.
+const ReorgsPath = "/reorgs"
+
+// Reorg is a single Bitcoin reorg returned by the reorg history endpoint.
+type Reorg struct {
+	Depth          int64     `json:"depth"`
+	AncestorHeight int64     `json:"ancestorHeight"`
+	AncestorHash   string    `json:"ancestorHash"`
+	ReplacedHashes []string  `json:"replacedHashes"`
+	NewBestHeight  int64     `json:"newBestHeight"`
+	NewBestHash    string    `json:"newBestHash"`
+	ObservedAt     time.Time `json:"observedAt"`
+}
+
+// ReorgsResponse is the response of the reorg history endpoint.
+type ReorgsResponse struct {
+	Reorgs                   []*Reorg `json:"reorgs"`
+	MaxDepth                 int64    `json:"maxDepth"`
+	AverageDepth             float64  `json:"averageDepth"`
+	RecommendedConfirmations int      `json:"recommendedConfirmations"`
+}
+
+// RegisterReorgsHandler registers the endpoint returning the Bitcoin reorgs
+// observed by the relay along with the number of confirmations recommended
+// for proven transactions, which is never lower than the given minimum.
+func RegisterReorgsHandler(
+	server *Server,
+	relayStore *store.Store,
+	minConfirmations int,
+) {
+	server.HandleFunc(ReorgsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		reorgs, err := relayStore.LoadReorgs()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		stats := store.SummarizeReorgs(reorgs, minConfirmations)
+
+		response := &ReorgsResponse{
+			Reorgs:                   make([]*Reorg, len(reorgs)),
+			MaxDepth:                 stats.MaxDepth,
+			AverageDepth:             stats.AverageDepth,
+			RecommendedConfirmations: stats.RecommendedConfirmations,
+		}
+
+		for i, reorg := range reorgs {
+			replacedHashes := make([]string, len(reorg.ReplacedDigests))
+			for j, digest := range reorg.ReplacedDigests {
+				replacedHashes[j] = digest.String()
+			}
+
+			response.Reorgs[i] = &Reorg{
+				Depth:          reorg.Depth,
+				AncestorHeight: reorg.AncestorHeight,
+				AncestorHash:   reorg.AncestorDigest.String(),
+				ReplacedHashes: replacedHashes,
+				NewBestHeight:  reorg.NewBestHeight,
+				NewBestHash:    reorg.NewBestDigest.String(),
+				ObservedAt:     reorg.ObservedAt,
+			}
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// rotation.go file contains the admin endpoint rotating the operator account
+// of the running relay, see chain.RotatingHandle.
+
+// AdminRotateKeyPath is the path of the operator key rotation endpoint.
+const AdminRotateKeyPath = "/admin/rotate-key"
+
+// Default time, in seconds, the rotation waits for the submissions in flight
+// and the pending transactions of the rotated account.
+const defaultRotationTimeout = 600
+
+// KeyRotator rotates the operator account of the running relay.
+type KeyRotator interface {
+	// RotateKey registers the key from the given key file and starts the
+	// rotation to its account. The rotation continues in the background and
+	// is aborted if it does not reach the safe point within the timeout.
+	RotateKey(keyFile string, password string, timeout time.Duration) error
+
+	// RotationStatus returns the status of the latest rotation.
+	RotationStatus() *chain.RotationStatus
+}
+
+// KeyRotationResponse is the response of the key rotation endpoint.
+type KeyRotationResponse struct {
+	State               string     `json:"state"`
+	OldAddress          string     `json:"oldAddress,omitempty"`
+	NewAddress          string     `json:"newAddress,omitempty"`
+	PendingTransactions int        `json:"pendingTransactions"`
+	Error               string     `json:"error,omitempty"`
+	StartedAt           *time.Time `json:"startedAt,omitempty"`
+	FinishedAt          *time.Time `json:"finishedAt,omitempty"`
+}
+
+// RegisterKeyRotationHandler registers the endpoint returning the status of
+// the latest rotation on GET requests and starting the rotation to the key
+// file given by the `keyFile` form parameter, encrypted with the password
+// given by the `password` form parameter, on POST requests. The optional
+// `timeout` parameter limits the rotation time in seconds. The endpoint is
+// registered only if the server requires authentication.
+func RegisterKeyRotationHandler(server *Server, rotator KeyRotator) {
+	if !server.IsAuthenticated() {
+		return
+	}
+
+	server.HandleFunc(
+		AdminRotateKeyPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+			case http.MethodPost:
+				keyFile := r.PostFormValue("keyFile")
+				if keyFile == "" {
+					writeError(w, http.StatusBadRequest, "missing key file")
+					return
+				}
+
+				timeout := defaultRotationTimeout
+				if value := r.PostFormValue("timeout"); value != "" {
+					parsed, err := strconv.Atoi(value)
+					if err != nil || parsed <= 0 {
+						writeError(w, http.StatusBadRequest, "invalid timeout")
+						return
+					}
+					timeout = parsed
+				}
+
+				logger.Infof(
+					"admin request [%v] for key file [%v] received from [%v]",
+					r.URL.Path,
+					keyFile,
+					r.RemoteAddr,
+				)
+
+				if err := rotator.RotateKey(
+					keyFile,
+					r.PostFormValue("password"),
+					time.Duration(timeout)*time.Second,
+				); err != nil {
+					writeError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			default:
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+
+			writeJSON(
+				w,
+				http.StatusOK,
+				newKeyRotationResponse(rotator.RotationStatus()),
+			)
+		},
+	)
+}
+
+func newKeyRotationResponse(status *chain.RotationStatus) *KeyRotationResponse {
+	response := &KeyRotationResponse{
+		State:               status.State,
+		OldAddress:          status.OldAddress,
+		NewAddress:          status.NewAddress,
+		PendingTransactions: status.PendingTransactions,
+		Error:               status.Error,
+	}
+
+	if !status.StartedAt.IsZero() {
+		response.StartedAt = &status.StartedAt
+	}
+	if !status.FinishedAt.IsZero() {
+		response.FinishedAt = &status.FinishedAt
+	}
+
+	return response
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/keep-network/tbtc/relay/pkg/node"
+	"github.com/keep-network/tbtc/relay/pkg/service"
+)
+
+// statusResponse is the response of the status endpoint.
+type statusResponse struct {
+	State               string      `json:"state"`
+	StateReason         string      `json:"stateReason"`
+	StateSince          time.Time   `json:"stateSince"`
+	HeadersRelayActive  bool        `json:"headersRelayActive"`
+	HeadersRelayErrors  int         `json:"headersRelayErrors"`
+	UniqueHeadersPulled int         `json:"uniqueHeadersPulled"`
+	UniqueHeadersPushed int         `json:"uniqueHeadersPushed"`
+	HeadersRelayLag     int64       `json:"headersRelayLag"`
+	Build               *build.Info `json:"build"`
+}
+
+// RegisterStatusHandler registers the `/status` endpoint exposing
+// the readiness state and the statistics of the relay node.
+func RegisterStatusHandler(
+	server *Server,
+	stats node.Stats,
+	readiness *service.Readiness,
+) {
+	server.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		status := readiness.Status()
+
+		writeJSON(w, http.StatusOK, &statusResponse{
+			State:               status.State.String(),
+			StateReason:         status.Reason,
+			StateSince:          status.Since,
+			HeadersRelayActive:  stats.HeadersRelayActive(),
+			HeadersRelayErrors:  stats.HeadersRelayErrors(),
+			UniqueHeadersPulled: stats.UniqueHeadersPulled(),
+			UniqueHeadersPushed: stats.UniqueHeadersPushed(),
+			HeadersRelayLag:     stats.HeadersRelayLag(),
+			Build:               build.GetInfo(),
+		})
+	})
+}
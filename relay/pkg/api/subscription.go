@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+const (
+	// HeadersSubscriptionPath is the path of the headers subscription
+	// endpoint.
+	HeadersSubscriptionPath = "/headers/subscribe"
+
+	// Maximum time allowed to write a single message to the subscriber.
+	subscriptionWriteTimeout = 10 * time.Second
+
+	// Interval in which ping messages are sent to the subscriber.
+	subscriptionPingInterval = 30 * time.Second
+)
+
+// HeaderEvent is a single message sent to the headers subscribers.
+type HeaderEvent struct {
+	Type       header.EventType `json:"type"`
+	Height     int64            `json:"height"`
+	Hash       string           `json:"hash"`
+	PrevHash   string           `json:"prevHash"`
+	MerkleRoot string           `json:"merkleRoot"`
+	Raw        string           `json:"raw"`
+	// Epoch is set only for the retarget and epoch end events.
+	Epoch uint64 `json:"epoch,omitempty"`
+	// ReorgDepth is set only for the reorg events.
+	ReorgDepth int64 `json:"reorgDepth,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	// Clients are authenticated by the server so requests from any origin
+	// can be accepted.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// RegisterHeadersSubscriptionHandler registers the websocket endpoint which
+// streams headers pulled and pushed by the relay. The `events` query
+// parameter can limit the stream to the given comma-separated event types,
+// i.e. `pulled`, `pushed`, `retarget`, `epoch-end` or `reorg`.
+func RegisterHeadersSubscriptionHandler(server *Server, feed *header.Feed) {
+	server.HandleFunc(
+		HeadersSubscriptionPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			eventTypes, err := parseEventTypes(r.URL.Query().Get("events"))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			connection, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				// The upgrader already replied with an error.
+				logger.Warnf("could not upgrade connection: [%v]", err)
+				return
+			}
+
+			logger.Infof("new headers subscriber [%v]", r.RemoteAddr)
+
+			streamHeaders(connection, feed.Subscribe(), eventTypes)
+
+			logger.Infof("headers subscriber [%v] left", r.RemoteAddr)
+		},
+	)
+}
+
+func parseEventTypes(value string) (map[header.EventType]bool, error) {
+	eventTypes := map[header.EventType]bool{
+		header.EventHeaderPulled:        true,
+		header.EventHeaderPushed:        true,
+		header.EventRetargetSubmitted:   true,
+		header.EventEpochEndApproaching: true,
+		header.EventReorg:               true,
+	}
+
+	if value == "" {
+		return eventTypes, nil
+	}
+
+	selected := make(map[header.EventType]bool)
+	for _, name := range strings.Split(value, ",") {
+		eventType := header.EventType(strings.TrimSpace(name))
+		if !eventTypes[eventType] {
+			return nil, fmt.Errorf("unknown event type [%v]", name)
+		}
+
+		selected[eventType] = true
+	}
+
+	return selected, nil
+}
+
+func streamHeaders(
+	connection *websocket.Conn,
+	subscription *header.Subscription,
+	eventTypes map[header.EventType]bool,
+) {
+	defer connection.Close()
+	defer subscription.Unsubscribe()
+
+	// Subscribers are not expected to send anything but the reads are
+	// needed to process control messages and detect closed connections.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := connection.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	pingTicker := time.NewTicker(subscriptionPingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// The subscription has been cancelled by the feed.
+				_ = connection.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(
+						websocket.CloseTryAgainLater,
+						"subscriber does not keep up with events",
+					),
+					time.Now().Add(subscriptionWriteTimeout),
+				)
+				return
+			}
+
+			if !eventTypes[event.Type] {
+				continue
+			}
+
+			_ = connection.SetWriteDeadline(
+				time.Now().Add(subscriptionWriteTimeout),
+			)
+			message := &HeaderEvent{
+				Type:       event.Type,
+				Height:     event.Header.Height,
+				Hash:       event.Header.Hash.String(),
+				PrevHash:   event.Header.PrevHash.String(),
+				MerkleRoot: event.Header.MerkleRoot.String(),
+				Raw:        hex.EncodeToString(event.Header.Raw),
+				Epoch:      event.Epoch,
+			}
+			if event.Reorg != nil {
+				message.ReorgDepth = event.Reorg.Depth
+			}
+
+			if err := connection.WriteJSON(message); err != nil {
+				logger.Warnf("could not send header event: [%v]", err)
+				return
+			}
+		case <-pingTicker.C:
+			if err := connection.WriteControl(
+				websocket.PingMessage,
+				nil,
+				time.Now().Add(subscriptionWriteTimeout),
+			); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+func TestHeadersSubscription(t *testing.T) {
+	server, err := NewServer(&Config{Address: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed := header.NewFeed(nil)
+	RegisterHeadersSubscriptionHandler(server, feed)
+
+	httpServer := httptest.NewServer(server.authenticator.wrap(server.mux))
+	defer httpServer.Close()
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") +
+		HeadersSubscriptionPath + "?events=pushed"
+
+	connection, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+
+	// Wait until the subscription is registered by the handler.
+	time.Sleep(100 * time.Millisecond)
+
+	feed.NotifyHeaderPulled(&btc.Header{Height: 1})
+	feed.NotifyHeadersConfirmed(
+		[]*btc.Header{{Height: 2, Raw: []byte{0xab}}},
+		time.Now(),
+	)
+
+	_ = connection.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	event := &HeaderEvent{}
+	if err := connection.ReadJSON(event); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedEvent := &HeaderEvent{
+		Type:       header.EventHeaderPushed,
+		Height:     2,
+		Hash:       btc.Digest{}.String(),
+		PrevHash:   btc.Digest{}.String(),
+		MerkleRoot: btc.Digest{}.String(),
+		Raw:        "ab",
+	}
+	if *expectedEvent != *event {
+		t.Errorf(
+			"unexpected event:\n"+
+				"expected: [%+v]\n"+
+				"actual:   [%+v]\n",
+			expectedEvent,
+			event,
+		)
+	}
+}
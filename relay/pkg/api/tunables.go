@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// tunables.go file contains the reading and changing of the relay settings
+// which can be tuned at runtime without a restart, through the admin service.
+
+const (
+	// TunableLogLevels is the tunable holding the space-delimited log level
+	// directives, like `info tbtc-relay-header=debug`.
+	TunableLogLevels = "log-levels"
+
+	// TunableSchedulePrefix prefixes the tunables holding the schedules of
+	// the relay tasks, like `schedule.pull`.
+	TunableSchedulePrefix = "schedule."
+)
+
+// tunables returns the current values of the settings tunable at runtime.
+// Only the schedules overriding the default intervals are listed.
+func tunables(control *header.Control) map[string]string {
+	tunables := map[string]string{
+		TunableLogLevels: logs.Levels(),
+	}
+
+	for task, schedule := range control.Scheduler().Schedules() {
+		tunables[TunableSchedulePrefix+task] = schedule
+	}
+
+	return tunables
+}
+
+// setTunable changes the setting with the given name.
+func setTunable(control *header.Control, name string, value string) error {
+	switch {
+	case name == TunableLogLevels:
+		return logs.Configure(value)
+	case strings.HasPrefix(name, TunableSchedulePrefix):
+		return control.Scheduler().SetSchedule(
+			strings.TrimPrefix(name, TunableSchedulePrefix),
+			value,
+		)
+	default:
+		return fmt.Errorf("unknown tunable [%v]", name)
+	}
+}
@@ -0,0 +1,371 @@
+package btc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// batch.go file contains the batching layer of the remote Bitcoin chain.
+// Calls issued concurrently within a short window, like the transaction and
+// block lookups made while building a proof or the headers prefetched by the
+// relay, are coalesced into a single JSON-RPC batch request. Each batch is
+// sent as soon as it is formed, so several batches can be in flight at once.
+// The number of batches in flight is bounded; calls issued while all of them
+// are in flight wait for the next batch. Responses are requested compressed
+// and decoded as a stream, so the calls of a batch carrying large results,
+// like the transaction IDs of full blocks, get their results as soon as they
+// are decoded instead of after the whole response is buffered.
+
+const (
+	// Default maximum number of calls coalesced into a single batch request.
+	defaultMaxBatchSize = 50
+
+	// Default maximum number of batch requests in flight at once.
+	defaultMaxInFlightBatches = 4
+)
+
+// rpcBatcher coalesces concurrent JSON-RPC calls into batch requests.
+type rpcBatcher struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+	window   time.Duration
+	maxSize  int
+
+	// inFlight holds a token for each batch request in flight.
+	inFlight chan struct{}
+
+	mutex   sync.Mutex
+	nextID  uint64
+	pending []*batchedCall
+	timer   *time.Timer
+}
+
+type batchRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type batchResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *batchError     `json:"error"`
+}
+
+type batchError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (be *batchError) Error() string {
+	return fmt.Sprintf("%v: %v", be.Code, be.Message)
+}
+
+// batchRejectedError is returned if the node rejects the batch request as
+// a whole, without RPC errors, because the credentials are not valid or
+// a method is not permitted by `-rpcwhitelist`.
+type batchRejectedError struct {
+	status string
+}
+
+func (bre *batchRejectedError) Error() string {
+	return fmt.Sprintf("batch request rejected with status [%v]", bre.status)
+}
+
+type batchedCall struct {
+	request       *batchRequest
+	correlationID correlation.ID
+	result        chan *batchResponse
+	err           chan error
+}
+
+// newRPCBatcher creates a batcher sending the batch requests to the Bitcoin
+// node described by the given connection config using the given HTTP client.
+// Calls are collected for the given window before the batch is sent, unless
+// the batch is full earlier. At most the given number of batches are in
+// flight at once.
+func newRPCBatcher(
+	connCfg *rpcclient.ConnConfig,
+	client *http.Client,
+	window time.Duration,
+	maxSize int,
+	maxInFlight int,
+) *rpcBatcher {
+	if maxSize <= 0 {
+		maxSize = defaultMaxBatchSize
+	}
+
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlightBatches
+	}
+
+	scheme := "https://"
+	if connCfg.DisableTLS {
+		scheme = "http://"
+	}
+
+	return &rpcBatcher{
+		url:      scheme + connCfg.Host,
+		username: connCfg.User,
+		password: connCfg.Pass,
+		client:   client,
+		window:   window,
+		maxSize:  maxSize,
+		inFlight: make(chan struct{}, maxInFlight),
+	}
+}
+
+// call adds the call to the batch being formed, waits until the batch is sent
+// and unmarshals the result of the call into the given target.
+func (rb *rpcBatcher) call(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	target interface{},
+) error {
+	call := &batchedCall{
+		correlationID: correlation.FromContext(ctx),
+		result:        make(chan *batchResponse, 1),
+		err:           make(chan error, 1),
+	}
+
+	rb.mutex.Lock()
+	rb.nextID++
+	call.request = &batchRequest{
+		JSONRPC: "1.0",
+		ID:      rb.nextID,
+		Method:  method,
+		Params:  params,
+	}
+	rb.pending = append(rb.pending, call)
+	if len(rb.pending) >= rb.maxSize {
+		rb.flushLocked()
+	} else if len(rb.pending) == 1 {
+		rb.timer = time.AfterFunc(rb.window, rb.flush)
+	}
+	rb.mutex.Unlock()
+
+	select {
+	case response := <-call.result:
+		if response.Error != nil {
+			return response.Error
+		}
+
+		if target == nil {
+			return nil
+		}
+
+		return json.Unmarshal(response.Result, target)
+	case err := <-call.err:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf(
+			"RPC call [%v] abandoned: [%v]",
+			method,
+			ctx.Err(),
+		)
+	}
+}
+
+// flush sends the batch being formed once its window elapses.
+func (rb *rpcBatcher) flush() {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	rb.flushLocked()
+}
+
+// flushLocked sends the batch being formed. If the maximum number of batches
+// is already in flight, the calls wait until one of them completes. Must be
+// called with the mutex held.
+func (rb *rpcBatcher) flushLocked() {
+	if len(rb.pending) == 0 {
+		return
+	}
+
+	select {
+	case rb.inFlight <- struct{}{}:
+	default:
+		// The calls are sent once a batch in flight completes.
+		return
+	}
+
+	if rb.timer != nil {
+		rb.timer.Stop()
+		rb.timer = nil
+	}
+
+	size := len(rb.pending)
+	if size > rb.maxSize {
+		size = rb.maxSize
+	}
+
+	batch := rb.pending[:size:size]
+	rb.pending = rb.pending[size:]
+	if len(rb.pending) == 0 {
+		rb.pending = nil
+	}
+
+	go rb.send(batch)
+}
+
+// send sends the batch request and dispatches the responses to the calls.
+// Once done, the calls which waited for the batch to complete are sent.
+func (rb *rpcBatcher) send(batch []*batchedCall) {
+	defer func() {
+		<-rb.inFlight
+		rb.flush()
+	}()
+
+	calls := make(map[uint64]*batchedCall, len(batch))
+	for _, call := range batch {
+		calls[call.request.ID] = call
+	}
+
+	err := rb.roundTrip(batch, func(response *batchResponse) {
+		call, ok := calls[response.ID]
+		if !ok {
+			return
+		}
+
+		delete(calls, response.ID)
+		call.result <- response
+	})
+
+	for _, call := range calls {
+		if err != nil {
+			call.err <- err
+			continue
+		}
+
+		call.err <- fmt.Errorf(
+			"no response to RPC call [%v] in batch",
+			call.request.Method,
+		)
+	}
+}
+
+// roundTrip sends the batch request and passes each response to the given
+// dispatch function as soon as it is decoded, so large responses are not
+// buffered until the whole batch is decoded. Compressed responses are
+// requested from the node or the proxy in front of it.
+func (rb *rpcBatcher) roundTrip(
+	batch []*batchedCall,
+	dispatch func(response *batchResponse),
+) error {
+	requests := make([]*batchRequest, len(batch))
+	for i, call := range batch {
+		requests[i] = call.request
+	}
+
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return fmt.Errorf("could not encode batch request: [%v]", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		batchContext(batch),
+		http.MethodPost,
+		rb.url,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept-Encoding", "gzip")
+	request.SetBasicAuth(rb.username, rb.password)
+
+	response, err := rb.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not send batch request: [%v]", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusUnauthorized ||
+		response.StatusCode == http.StatusForbidden {
+		return &batchRejectedError{status: response.Status}
+	}
+
+	var reader io.Reader = response.Body
+	if response.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return fmt.Errorf(
+				"could not decompress batch response with status [%v]: [%v]",
+				response.Status,
+				err,
+			)
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	if err := decodeBatchResponses(reader, dispatch); err != nil {
+		return fmt.Errorf(
+			"could not decode batch response with status [%v]: [%v]",
+			response.Status,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// batchContext returns the context of the batch request, carrying the
+// correlation ID of the first call which has one, so the request ID of the
+// batch request can be tied to the relay log messages. The batch request
+// outlives the calls it serves, so their contexts are not used as such.
+func batchContext(batch []*batchedCall) context.Context {
+	for _, call := range batch {
+		if call.correlationID != "" {
+			return correlation.WithID(context.Background(), call.correlationID)
+		}
+	}
+
+	return context.Background()
+}
+
+// decodeBatchResponses decodes the array of batch responses element by
+// element and passes each one to the given dispatch function.
+func decodeBatchResponses(
+	reader io.Reader,
+	dispatch func(response *batchResponse),
+) error {
+	decoder := json.NewDecoder(reader)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if delimiter, ok := token.(json.Delim); !ok || delimiter != '[' {
+		return fmt.Errorf("expected array of responses, got [%v]", token)
+	}
+
+	for decoder.More() {
+		response := &batchResponse{}
+		if err := decoder.Decode(response); err != nil {
+			return err
+		}
+
+		dispatch(response)
+	}
+
+	_, err = decoder.Token()
+	return err
+}
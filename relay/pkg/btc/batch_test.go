@@ -0,0 +1,630 @@
+package btc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+// batchNode is a fake Bitcoin node serving batch requests.
+type batchNode struct {
+	mutex      sync.Mutex
+	batchSizes []int
+
+	handle func(request *batchRequest) (interface{}, *batchError)
+}
+
+func (bn *batchNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var requests []*batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bn.mutex.Lock()
+	bn.batchSizes = append(bn.batchSizes, len(requests))
+	bn.mutex.Unlock()
+
+	responses := make([]map[string]interface{}, 0, len(requests))
+	for _, request := range requests {
+		result, err := bn.handle(request)
+		responses = append(responses, map[string]interface{}{
+			"id":     request.ID,
+			"result": result,
+			"error":  err,
+		})
+	}
+
+	_ = json.NewEncoder(w).Encode(responses)
+}
+
+func (bn *batchNode) sizes() []int {
+	bn.mutex.Lock()
+	defer bn.mutex.Unlock()
+
+	return append([]int{}, bn.batchSizes...)
+}
+
+func newTestBatcher(
+	server *httptest.Server,
+	maxSize int,
+	maxInFlight int,
+) *rpcBatcher {
+	return newRPCBatcher(
+		&rpcclient.ConnConfig{
+			Host:       strings.TrimPrefix(server.URL, "http://"),
+			User:       "user",
+			Pass:       "password",
+			DisableTLS: true,
+		},
+		&http.Client{Timeout: time.Second},
+		50*time.Millisecond,
+		maxSize,
+		maxInFlight,
+	)
+}
+
+func TestRPCBatcher_Coalesce(t *testing.T) {
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			return request.Params[0], nil
+		},
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	batcher := newTestBatcher(server, 3, 0)
+
+	results := make([]float64, 5)
+	errs := make([]error, 5)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = batcher.call(
+				context.Background(),
+				"getblockhash",
+				[]interface{}{i},
+				&results[i],
+			)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 5; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+
+		if results[i] != float64(i) {
+			t.Errorf(
+				"unexpected result of call [%v]:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				i,
+				i,
+				results[i],
+			)
+		}
+	}
+
+	// The first batch is sent once full, the rest once the window elapses.
+	sizes := node.sizes()
+	if len(sizes) != 2 || sizes[0]+sizes[1] != 5 {
+		t.Errorf("unexpected batch sizes: [%v]", sizes)
+	}
+}
+
+func TestRPCBatcher_CallError(t *testing.T) {
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			if request.Params[0] == "missing" {
+				return nil, &batchError{Code: -5, Message: "not found"}
+			}
+			return "found", nil
+		},
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	batcher := newTestBatcher(server, 2, 0)
+
+	var found, missing string
+	var foundErr, missingErr error
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		foundErr = batcher.call(
+			context.Background(),
+			"getrawtransaction",
+			[]interface{}{"existing"},
+			&found,
+		)
+	}()
+	go func() {
+		defer wg.Done()
+		missingErr = batcher.call(
+			context.Background(),
+			"getrawtransaction",
+			[]interface{}{"missing"},
+			&missing,
+		)
+	}()
+	wg.Wait()
+
+	if foundErr != nil || found != "found" {
+		t.Errorf("unexpected result: [%v] [%v]", found, foundErr)
+	}
+
+	if missingErr == nil {
+		t.Errorf("error of the failed call should be returned")
+	}
+}
+
+func TestRPCBatcher_InFlightLimit(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			mutex.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mutex.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+
+			return request.Params[0], nil
+		},
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	batcher := newTestBatcher(server, 2, 1)
+
+	errs := make([]error, 7)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 7; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result float64
+			errs[i] = batcher.call(
+				context.Background(),
+				"getblockhash",
+				[]interface{}{i},
+				&result,
+			)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 7; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+	}
+
+	if maxInFlight != 1 {
+		t.Errorf(
+			"unexpected maximum number of batches in flight:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			1,
+			maxInFlight,
+		)
+	}
+
+	for _, size := range node.sizes() {
+		if size > 2 {
+			t.Errorf("batch of [%v] calls exceeds the maximum size", size)
+		}
+	}
+}
+
+func TestRPCBatcher_GzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Accept-Encoding") != "gzip" {
+				t.Errorf("compressed response not requested")
+			}
+
+			var requests []*batchRequest
+			if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			responses := make([]map[string]interface{}, len(requests))
+			for i, request := range requests {
+				responses[i] = map[string]interface{}{
+					"id":     request.ID,
+					"result": request.Params[0],
+				}
+			}
+
+			w.Header().Set("Content-Encoding", "gzip")
+			writer := gzip.NewWriter(w)
+			_ = json.NewEncoder(writer).Encode(responses)
+			_ = writer.Close()
+		},
+	))
+	defer server.Close()
+
+	batcher := newTestBatcher(server, 10, 0)
+
+	var result string
+	if err := batcher.call(
+		context.Background(),
+		"getblockhash",
+		[]interface{}{"compressed"},
+		&result,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if result != "compressed" {
+		t.Errorf(
+			"unexpected result:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			"compressed",
+			result,
+		)
+	}
+}
+
+func TestRPCBatcher_RequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		},
+	))
+	defer server.Close()
+
+	batcher := newTestBatcher(server, 10, 0)
+
+	err := batcher.call(context.Background(), "getblockcount", nil, nil)
+	if err == nil {
+		t.Fatal("error of the batch request should be returned")
+	}
+}
+
+func TestRemoteChain_BatchedHeaders(t *testing.T) {
+	headers := make(map[string]*wire.BlockHeader)
+	hashes := make(map[float64]string)
+	for height := 1; height <= 4; height++ {
+		header := &wire.BlockHeader{
+			Version:   1,
+			Timestamp: time.Unix(int64(1000+height), 0),
+			Nonce:     uint32(height),
+		}
+		hash := header.BlockHash().String()
+		headers[hash] = header
+		hashes[float64(height)] = hash
+	}
+
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			switch request.Method {
+			case "getblockhash":
+				return hashes[request.Params[0].(float64)], nil
+			case "getblockheader":
+				var buffer bytes.Buffer
+				header := headers[request.Params[0].(string)]
+				if err := header.Serialize(&buffer); err != nil {
+					return nil, &batchError{Code: -1, Message: err.Error()}
+				}
+				return hex.EncodeToString(buffer.Bytes()), nil
+			}
+			return nil, &batchError{Code: -32601, Message: "unknown method"}
+		},
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	chain := &remoteChain{
+		requestTimeout: time.Second,
+		batcher:        newTestBatcher(server, 10, 0),
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for height := int64(1); height <= 4; height++ {
+		wg.Add(1)
+		go func(height int64) {
+			defer wg.Done()
+			header, err := chain.GetHeaderByHeight(context.Background(), height)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			expectedHash := hashes[float64(height)]
+			if chainhash.Hash(header.Hash).String() != expectedHash {
+				errs <- fmt.Errorf(
+					"unexpected hash of header [%v]: [%v]",
+					height,
+					chainhash.Hash(header.Hash),
+				)
+			}
+		}(height)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Hashes and then headers of all heights are requested together.
+	if sizes := node.sizes(); len(sizes) != 2 {
+		t.Errorf("unexpected batch sizes: [%v]", sizes)
+	}
+}
+
+func TestRemoteChain_BatchedBlockTransactions(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	msgTx.AddTxOut(wire.NewTxOut(100000, []byte{0x6a}))
+
+	block := wire.NewMsgBlock(&wire.BlockHeader{
+		Version:   1,
+		Timestamp: time.Unix(1000, 0),
+	})
+	if err := block.AddTransaction(msgTx); err != nil {
+		t.Fatal(err)
+	}
+
+	var serialized bytes.Buffer
+	if err := block.Serialize(&serialized); err != nil {
+		t.Fatal(err)
+	}
+
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			if request.Method == "getblock" && request.Params[1] == 0.0 {
+				return hex.EncodeToString(serialized.Bytes()), nil
+			}
+			return nil, &batchError{Code: -32601, Message: "unknown method"}
+		},
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	chain := &remoteChain{
+		requestTimeout: time.Second,
+		batcher:        newTestBatcher(server, 10, 0),
+	}
+
+	digest := Digest(block.BlockHash())
+
+	transactions, err := chain.GetBlockTransactions(
+		context.Background(),
+		digest,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transactions) != 1 {
+		t.Fatalf("unexpected number of transactions: [%v]", len(transactions))
+	}
+
+	transaction := transactions[0]
+	if transaction.TxID != Digest(msgTx.TxHash()) {
+		t.Errorf(
+			"unexpected transaction ID: [%v]",
+			chainhash.Hash(transaction.TxID),
+		)
+	}
+	if transaction.BlockHash == nil || *transaction.BlockHash != digest {
+		t.Errorf("unexpected block hash: [%v]", transaction.BlockHash)
+	}
+	if len(transaction.Outputs) != 1 ||
+		transaction.Outputs[0].Value != 100000 {
+		t.Errorf("unexpected outputs: [%v]", transaction.Outputs)
+	}
+}
+
+func TestRemoteChain_BatchedMempoolEntry(t *testing.T) {
+	txID := Digest{0x01, 0x02, 0x03}
+	txIDString := chainhash.Hash(txID).String()
+
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			if request.Method == "getmempoolentry" &&
+				len(request.Params) == 1 &&
+				request.Params[0] == txIDString {
+				return map[string]interface{}{
+					"vsize": 200,
+					"time":  1000,
+					"fees":  map[string]interface{}{"base": 0.00002},
+				}, nil
+			}
+			return nil, &batchError{Code: -5, Message: "transaction not in mempool"}
+		},
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	chain := &remoteChain{
+		requestTimeout: time.Second,
+		batcher:        newTestBatcher(server, 10, 0),
+	}
+
+	entry, err := chain.GetMempoolEntry(context.Background(), txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Fee != 2000 {
+		t.Errorf(
+			"unexpected fee:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			2000,
+			entry.Fee,
+		)
+	}
+	if entry.VirtualSize != 200 {
+		t.Errorf(
+			"unexpected virtual size:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			200,
+			entry.VirtualSize,
+		)
+	}
+}
+
+func TestConfiguredBatcher_HTTPTransport(t *testing.T) {
+	var apiKey string
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			return 100, nil
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			apiKey = r.Header.Get("X-Api-Key")
+			node.ServeHTTP(w, r)
+		},
+	))
+	defer server.Close()
+
+	config := &Config{
+		URL: server.URL,
+		HTTP: transport.Config{
+			Headers: map[string]string{"X-Api-Key": "secret"},
+		},
+	}
+
+	connCfg, _, err := rpcConnConfig(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batcher, err := newConfiguredBatcher(config, connCfg, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &batcherNodeClient{batcher: batcher, requestTimeout: time.Second}
+
+	count, err := client.GetBlockCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 100 {
+		t.Errorf("unexpected block count: [%v]", count)
+	}
+
+	if apiKey != "secret" {
+		t.Errorf("configured header was not sent")
+	}
+}
+
+func TestRPCBatcher_TLS(t *testing.T) {
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			return 100, nil
+		},
+	}
+	server := httptest.NewTLSServer(node)
+	defer server.Close()
+
+	connCfg, _, err := rpcConnConfig(&Config{URL: server.URL}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batcher := newRPCBatcher(connCfg, server.Client(), 0, 1, 0)
+
+	if !strings.HasPrefix(batcher.url, "https://") {
+		t.Errorf("unexpected batcher URL: [%v]", batcher.url)
+	}
+
+	var count int64
+	if err := batcher.call(
+		context.Background(),
+		"getblockcount",
+		nil,
+		&count,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 100 {
+		t.Errorf("unexpected block count: [%v]", count)
+	}
+}
+
+func TestConfiguredBatcher_CorrelationID(t *testing.T) {
+	var requestID string
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			return 100, nil
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requestID = r.Header.Get("X-Request-Id")
+			node.ServeHTTP(w, r)
+		},
+	))
+	defer server.Close()
+
+	config := &Config{
+		URL:  server.URL,
+		HTTP: transport.Config{RequestIDHeader: "X-Request-Id"},
+	}
+
+	connCfg, _, err := rpcConnConfig(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batcher, err := newConfiguredBatcher(config, connCfg, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := correlation.WithID(context.Background(), "0a1b2c3d")
+
+	var count int64
+	if err := batcher.call(ctx, "getblockcount", nil, &count); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(requestID, "0a1b2c3d-") {
+		t.Errorf("request ID does not carry correlation ID: [%v]", requestID)
+	}
+}
@@ -1,25 +1,132 @@
 package btc
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
+	"time"
 
-	"github.com/ipfs/go-log"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
 )
 
-var logger = log.Logger("tbtc-relay-btc")
+// Name of the default logger of the Bitcoin chain handles.
+const loggerName = "tbtc-relay-btc"
 
-// Handle represents a handle to the Bitcoin chain.
+// ErrBlockFiltersUnsupported is returned by GetBlockFilter if the Bitcoin
+// node does not serve compact block filters.
+var ErrBlockFiltersUnsupported = errors.New(
+	"compact block filters are not supported by the Bitcoin node",
+)
+
+// Handle represents a handle to the Bitcoin chain. Calls made through the
+// handle are abandoned once the passed context is done.
 type Handle interface {
 	// GetHeaderByHeight returns the block header from the longest block chain at
 	// the given block height.
-	GetHeaderByHeight(height int64) (*Header, error)
+	GetHeaderByHeight(ctx context.Context, height int64) (*Header, error)
 
 	// GetHeaderByDigest returns the block header for given digest (hash).
-	GetHeaderByDigest(digest Digest) (*Header, error)
+	GetHeaderByDigest(ctx context.Context, digest Digest) (*Header, error)
+
+	// GetHeightByDigest returns the height of the block with the given
+	// digest (hash). It is cheaper than GetHeaderByDigest if only the height
+	// is needed.
+	GetHeightByDigest(ctx context.Context, digest Digest) (int64, error)
 
 	// GetBlockCount returns the number of blocks in the longest blockchain
-	GetBlockCount() (int64, error)
+	GetBlockCount(ctx context.Context) (int64, error)
+
+	// GetTransaction returns the parsed transaction with the given ID along
+	// with its confirmation data.
+	GetTransaction(ctx context.Context, txID Digest) (*Transaction, error)
+
+	// GetRawTransaction returns the serialized transaction with the given ID.
+	GetRawTransaction(ctx context.Context, txID Digest) ([]byte, error)
+
+	// GetBlockTxIDs returns IDs of all transactions included in the block
+	// with the given digest, in the order of the block.
+	GetBlockTxIDs(ctx context.Context, digest Digest) ([]Digest, error)
+
+	// GetBlockTransactions returns the parsed transactions included in the
+	// block with the given digest, in the order of the block. The
+	// transactions are decoded from the block itself, so they carry the
+	// block hash and time but not the number of confirmations.
+	GetBlockTransactions(
+		ctx context.Context,
+		digest Digest,
+	) ([]*Transaction, error)
+
+	// GetBlockFilter returns the serialized BIP-158 basic compact filter of
+	// the block with the given digest. ErrBlockFiltersUnsupported is returned
+	// if the node does not serve block filters.
+	GetBlockFilter(ctx context.Context, digest Digest) ([]byte, error)
+
+	// GetMempoolTxIDs returns IDs of all transactions in the node mempool.
+	GetMempoolTxIDs(ctx context.Context) ([]Digest, error)
+
+	// GetMempoolEntry returns the fee data of the mempool transaction with
+	// the given ID.
+	GetMempoolEntry(ctx context.Context, txID Digest) (*MempoolEntry, error)
+
+	// IsOutputUnspent checks whether the output with the given index of the
+	// transaction with the given ID is in the confirmed unspent outputs set.
+	IsOutputUnspent(
+		ctx context.Context,
+		txID Digest,
+		outputIndex uint32,
+	) (bool, error)
+
+	// GetChainTips returns the tips of all chains known by the node,
+	// including the active chain and competing forks.
+	GetChainTips(ctx context.Context) ([]*ChainTip, error)
+
+	// NetworkParams returns the consensus parameters of the Bitcoin network
+	// the handle is connected to.
+	NetworkParams() *chaincfg.Params
+}
+
+// Statuses of the chain tips, as reported by Bitcoin Core.
+const (
+	// ChainTipActive is the tip of the active chain.
+	ChainTipActive = "active"
+	// ChainTipValidFork is a fully validated fork which is not active.
+	ChainTipValidFork = "valid-fork"
+	// ChainTipValidHeaders is a fork with all blocks available but not
+	// fully validated.
+	ChainTipValidHeaders = "valid-headers"
+	// ChainTipHeadersOnly is a fork with valid headers whose blocks are not
+	// all available.
+	ChainTipHeadersOnly = "headers-only"
+	// ChainTipInvalid is a fork containing at least one invalid block.
+	ChainTipInvalid = "invalid"
+)
+
+// ChainTip is the tip of a chain known by the Bitcoin node.
+type ChainTip struct {
+	// Height is the height of the tip.
+	Height int64
+	// Hash is the hash of the tip.
+	Hash Digest
+	// BranchLength is the length of the branch connecting the tip to the
+	// active chain. It is zero for the active chain tip.
+	BranchLength int64
+	// Status is the status of the tip.
+	Status string
+}
+
+// IsCompeting returns whether the tip is a valid fork competing with the
+// active chain.
+func (ct *ChainTip) IsCompeting() bool {
+	switch ct.Status {
+	case ChainTipValidFork, ChainTipValidHeaders, ChainTipHeadersOnly:
+		return true
+	default:
+		return false
+	}
 }
 
 // Digests represents a 32-byte little-endian Bitcoin digest.
@@ -29,6 +136,31 @@ func (d Digest) String() string {
 	return hex.EncodeToString(d[:])
 }
 
+// MarshalText encodes the digest as a hex string.
+func (d Digest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes the digest from a hex string.
+func (d *Digest) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("could not decode digest: [%v]", err)
+	}
+
+	if len(decoded) != len(d) {
+		return fmt.Errorf(
+			"digest must have [%v] bytes; has [%v]",
+			len(d),
+			len(decoded),
+		)
+	}
+
+	copy(d[:], decoded)
+
+	return nil
+}
+
 // Header represents a Bitcoin block header.
 type Header struct {
 	// Hash is the hash of the block.
@@ -44,6 +176,44 @@ type Header struct {
 	Raw []byte
 }
 
+// ParseHeader creates a header at the given height from the serialized
+// 80-byte header data.
+func ParseHeader(height int64, raw []byte) (*Header, error) {
+	blockHeader, err := deserializeHeader(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Header{
+		Hash:       Digest(blockHeader.BlockHash()),
+		Height:     height,
+		PrevHash:   Digest(blockHeader.PrevBlock),
+		MerkleRoot: Digest(blockHeader.MerkleRoot),
+		Raw:        raw,
+	}, nil
+}
+
+// Work returns the proof of work of the header, i.e. the expected number of
+// hashes needed to produce it, computed from its difficulty target.
+func (h *Header) Work() (*big.Int, error) {
+	blockHeader, err := deserializeHeader(h.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockchain.CalcWork(blockHeader.Bits), nil
+}
+
+// Timestamp returns the time the header was created at, as set by the miner.
+func (h *Header) Timestamp() (time.Time, error) {
+	blockHeader, err := deserializeHeader(h.Raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return blockHeader.Timestamp, nil
+}
+
 func (h *Header) Equals(other *Header) bool {
 	if other == nil {
 		return false
@@ -71,4 +241,40 @@ type Config struct {
 	URL      string
 	Password string
 	Username string
+	// CookieFile is the path to the `.cookie` file written by Bitcoin Core
+	// when no RPC credentials are configured. If set, Username and Password
+	// are ignored.
+	CookieFile string
+	// Network is the name of the Bitcoin network the node runs. Supported
+	// values are `mainnet` (default), `testnet`, `regtest` and `signet`.
+	Network string
+	// SignetChallenge is the hex-encoded block script challenge of a custom
+	// signet. If empty, the public signet challenge is used. Only allowed
+	// for the `signet` network.
+	SignetChallenge string
+	// SignetGenesisHash is the hash of the genesis block of a custom signet
+	// started from a non-default genesis block. Only allowed for the
+	// `signet` network.
+	SignetGenesisHash string
+	// RequestTimeout is the maximum time, in seconds, a single RPC call can
+	// take. If zero, a default value is used.
+	RequestTimeout int
+	// BatchWindow is the time, in milliseconds, during which concurrent RPC
+	// calls are collected into a single batch request. If zero, calls are
+	// not batched.
+	BatchWindow int
+	// MaxBatchSize is the maximum number of calls in a single batch request.
+	// If zero, a default value is used.
+	MaxBatchSize int
+	// MaxInFlightBatches is the maximum number of batch requests sent to the
+	// node at once. Calls issued while all of them are in flight wait for
+	// the next batch. If zero, a default value is used.
+	MaxInFlightBatches int
+	// HTTP configures the extra headers, proxy and TLS settings of the
+	// connection to the node. The URL must have the `https://` scheme for
+	// the connection to use TLS.
+	HTTP transport.Config
+	// Tor configures the connection to the node through Tor, e.g. to a node
+	// running as an onion service.
+	Tor TorConfig
 }
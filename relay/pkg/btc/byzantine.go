@@ -0,0 +1,138 @@
+package btc
+
+import (
+	"context"
+	"sync"
+)
+
+// byzantine.go file contains a Bitcoin chain handle returning deliberately
+// inconsistent data, as a faulty or malicious Bitcoin node could. It is meant
+// for testing purposes only, to prove the relay does not trust the data
+// returned by the node blindly.
+
+// ByzantineFault is a kind of inconsistency of a header returned by
+// the Byzantine chain.
+type ByzantineFault int
+
+const (
+	// FaultNone returns the header as it is.
+	FaultNone ByzantineFault = iota
+	// FaultWrongPrevHash returns the header with a previous hash not
+	// matching its serialized form.
+	FaultWrongPrevHash
+	// FaultMismatchedRaw returns the header with serialized bytes not
+	// matching its hash.
+	FaultMismatchedRaw
+	// FaultWrongHeight returns the header with a height off by one.
+	FaultWrongHeight
+)
+
+// ByzantineChain is a local Bitcoin chain returning inconsistent headers at
+// the configured heights and flip-flopping between the configured sets of
+// chain tips.
+type ByzantineChain struct {
+	*LocalChain
+
+	mutex    sync.Mutex
+	faults   map[int64]ByzantineFault
+	tipSets  [][]*ChainTip
+	tipCalls int
+}
+
+// NewByzantineChain creates a Byzantine chain serving the headers of the
+// given local chain.
+func NewByzantineChain(localChain *LocalChain) *ByzantineChain {
+	return &ByzantineChain{
+		LocalChain: localChain,
+		faults:     make(map[int64]ByzantineFault),
+	}
+}
+
+// SetFault sets the inconsistency of the header at the given height.
+func (bc *ByzantineChain) SetFault(height int64, fault ByzantineFault) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	bc.faults[height] = fault
+}
+
+// FlipFlopTips makes each call to GetChainTips return the next of the given
+// chain tip sets, starting over after the last one.
+func (bc *ByzantineChain) FlipFlopTips(tipSets ...[]*ChainTip) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	bc.tipSets = tipSets
+	bc.tipCalls = 0
+}
+
+// GetHeaderByHeight returns the block header from the longest block chain at
+// the given block height, tampered with if a fault is set for the height.
+func (bc *ByzantineChain) GetHeaderByHeight(
+	ctx context.Context,
+	height int64,
+) (*Header, error) {
+	header, err := bc.LocalChain.GetHeaderByHeight(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return bc.tamper(header), nil
+}
+
+// GetHeaderByDigest returns the block header for given digest (hash),
+// tampered with if a fault is set for its height.
+func (bc *ByzantineChain) GetHeaderByDigest(
+	ctx context.Context,
+	digest Digest,
+) (*Header, error) {
+	header, err := bc.LocalChain.GetHeaderByDigest(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return bc.tamper(header), nil
+}
+
+// GetChainTips returns the next of the flip-flopping chain tip sets or, if
+// none are set, the tips of the local chain.
+func (bc *ByzantineChain) GetChainTips(
+	ctx context.Context,
+) ([]*ChainTip, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if len(bc.tipSets) == 0 {
+		return bc.LocalChain.GetChainTips(ctx)
+	}
+
+	tips := bc.tipSets[bc.tipCalls%len(bc.tipSets)]
+	bc.tipCalls++
+
+	return tips, nil
+}
+
+// tamper returns a copy of the header with the inconsistency set for its
+// height. The header kept by the local chain is never modified.
+func (bc *ByzantineChain) tamper(header *Header) *Header {
+	bc.mutex.Lock()
+	fault := bc.faults[header.Height]
+	bc.mutex.Unlock()
+
+	tampered := *header
+	tampered.Raw = append([]byte(nil), header.Raw...)
+
+	switch fault {
+	case FaultWrongPrevHash:
+		tampered.PrevHash[0] ^= 0xff
+	case FaultMismatchedRaw:
+		if len(tampered.Raw) > 0 {
+			// Change the nonce, which is the last field of the header.
+			tampered.Raw[len(tampered.Raw)-1] ^= 0xff
+		}
+	case FaultWrongHeight:
+		tampered.Height++
+	}
+
+	return &tampered
+}
@@ -0,0 +1,330 @@
+package btc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// capabilities.go file contains the detection of the optional capabilities
+// of the Bitcoin node, probed on startup. The relay itself needs only the
+// RequiredRPCMethods, but the deposit monitor and transaction proofs need
+// more methods and indexes the node may not provide, e.g. because it prunes
+// blocks, runs without `-txindex` or restricts the RPC methods. Features the
+// node cannot support are disabled on startup instead of failing at runtime.
+
+// Capabilities describes the optional capabilities of the Bitcoin node.
+type Capabilities struct {
+	// Version is the version of the node, e.g. 250000 for Bitcoin Core
+	// 25.0.0. It is zero if the node does not report its version.
+	Version int64
+	// Subversion is the user agent of the node, e.g. `/Satoshi:25.0.0/`.
+	Subversion string
+
+	// Pruned determines whether the node prunes old blocks. Blocks below
+	// PruneHeight are not available.
+	Pruned      bool
+	PruneHeight int64
+
+	// TxIndex determines whether the node keeps the index of all
+	// transactions, so confirmed transactions can be retrieved by their ID.
+	TxIndex bool
+	// BlockFilterIndex determines whether the node serves compact block
+	// filters.
+	BlockFilterIndex bool
+
+	// UnavailableMethods lists the optional RPC methods the node does not
+	// know or does not permit.
+	UnavailableMethods []string
+	// FailedProbes lists the optional RPC methods which could not be
+	// probed, e.g. because the node did not respond in time. They are
+	// assumed to be available, so no feature is disabled because of
+	// a transient failure.
+	FailedProbes []string
+}
+
+// HasMethods checks whether the node permits all the given RPC methods.
+func (c *Capabilities) HasMethods(methods ...string) bool {
+	for _, method := range methods {
+		for _, unavailable := range c.UnavailableMethods {
+			if method == unavailable {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// SupportsDepositMonitor checks whether the node supports the deposit
+// monitor. If not, the returned reason describes the missing capability.
+func (c *Capabilities) SupportsDepositMonitor() (bool, string) {
+	if !c.HasMethods(TransactionRPCMethods...) {
+		return false, fmt.Sprintf(
+			"node does not permit all of [%v]",
+			strings.Join(TransactionRPCMethods, ", "),
+		)
+	}
+
+	return true, ""
+}
+
+// SupportsProofs checks whether the node supports building transaction
+// proofs. If not, the returned reason describes the missing capability.
+func (c *Capabilities) SupportsProofs() (bool, string) {
+	if !c.HasMethods("getblock", "getrawtransaction") {
+		return false, "node does not permit [getblock, getrawtransaction]"
+	}
+
+	if !c.TxIndex {
+		return false, "node runs without the transaction index (-txindex)"
+	}
+
+	return true, ""
+}
+
+func (c *Capabilities) String() string {
+	version := "unknown version"
+	if c.Version > 0 {
+		version = fmt.Sprintf("version [%v] [%v]", c.Version, c.Subversion)
+	}
+
+	pruning := "not pruned"
+	if c.Pruned {
+		pruning = fmt.Sprintf("pruned below [%v]", c.PruneHeight)
+	}
+
+	return fmt.Sprintf(
+		"%v, %v, txindex [%v], block filter index [%v]",
+		version,
+		pruning,
+		c.TxIndex,
+		c.BlockFilterIndex,
+	)
+}
+
+// CapabilityProber is implemented by Bitcoin handles able to probe the
+// capabilities of the node.
+type CapabilityProber interface {
+	// ProbeCapabilities probes the optional capabilities of the node.
+	ProbeCapabilities(ctx context.Context) (*Capabilities, error)
+}
+
+// ProbeCapabilities probes the optional capabilities of the node. Optional
+// methods are called with arguments for which they either succeed or fail
+// with an error returned only by nodes permitting them. Calls failing for
+// other reasons are reported as failed probes.
+func (rc *remoteChain) ProbeCapabilities(
+	ctx context.Context,
+) (*Capabilities, error) {
+	capabilities := &Capabilities{}
+
+	unavailable := make(map[string]bool)
+	failed := make(map[string]bool)
+	probe := func(method string, err error) bool {
+		if err == nil {
+			return true
+		}
+
+		if isMethodUnavailable(err) {
+			unavailable[method] = true
+		} else if !isRPCError(err) {
+			failed[method] = true
+		}
+
+		return false
+	}
+
+	var networkInfo struct {
+		Version    int64  `json:"version"`
+		Subversion string `json:"subversion"`
+	}
+	if probe(
+		"getnetworkinfo",
+		rc.rawCall(ctx, "getnetworkinfo", []interface{}{}, &networkInfo),
+	) {
+		capabilities.Version = networkInfo.Version
+		capabilities.Subversion = networkInfo.Subversion
+	}
+
+	var chainInfo btcjson.GetBlockChainInfoResult
+	if err := rc.rawCall(
+		ctx,
+		"getblockchaininfo",
+		[]interface{}{},
+		&chainInfo,
+	); err != nil {
+		return nil, fmt.Errorf("could not get blockchain info: [%v]", err)
+	}
+
+	capabilities.Pruned = chainInfo.Pruned
+	capabilities.PruneHeight = int64(chainInfo.PruneHeight)
+
+	tipHash, err := rc.getBlockHash(ctx, int64(chainInfo.Blocks))
+	if err != nil {
+		return nil, fmt.Errorf("could not get best block hash: [%v]", err)
+	}
+
+	// The coinbase transaction of the best block is never in the mempool,
+	// so it can be retrieved by its ID only with the transaction index.
+	var tipBlock struct {
+		Tx []string `json:"tx"`
+	}
+	if probe(
+		"getblock",
+		rc.rawCall(
+			ctx,
+			"getblock",
+			[]interface{}{tipHash.String(), 1},
+			&tipBlock,
+		),
+	) && len(tipBlock.Tx) > 0 {
+		var transaction json.RawMessage
+		capabilities.TxIndex = probe(
+			"getrawtransaction",
+			rc.rawCall(
+				ctx,
+				"getrawtransaction",
+				[]interface{}{tipBlock.Tx[0], 0},
+				&transaction,
+			),
+		)
+	}
+
+	// The transaction index is assumed if it could not be probed, so the
+	// features needing it stay enabled.
+	if failed["getblock"] || failed["getrawtransaction"] {
+		capabilities.TxIndex = true
+	}
+
+	var filter json.RawMessage
+	err = rc.rawCall(
+		ctx,
+		"getblockfilter",
+		[]interface{}{tipHash.String()},
+		&filter,
+	)
+	capabilities.BlockFilterIndex = err == nil
+	if err != nil && !isBlockFilterUnsupported(err) {
+		probe("getblockfilter", err)
+	}
+
+	// No transaction has an all-zero ID, so these calls fail with
+	// a transaction-not-found error on nodes permitting them.
+	zeroTxID := chainhash.Hash{}.String()
+
+	var mempool json.RawMessage
+	probe(
+		"getrawmempool",
+		rc.rawCall(ctx, "getrawmempool", []interface{}{}, &mempool),
+	)
+
+	var entry json.RawMessage
+	probe(
+		"getmempoolentry",
+		rc.rawCall(ctx, "getmempoolentry", []interface{}{zeroTxID}, &entry),
+	)
+
+	var output json.RawMessage
+	probe(
+		"gettxout",
+		rc.rawCall(ctx, "gettxout", []interface{}{zeroTxID, 0}, &output),
+	)
+
+	var tips json.RawMessage
+	probe(
+		"getchaintips",
+		rc.rawCall(ctx, "getchaintips", []interface{}{}, &tips),
+	)
+
+	for method := range unavailable {
+		capabilities.UnavailableMethods = append(
+			capabilities.UnavailableMethods,
+			method,
+		)
+	}
+	sort.Strings(capabilities.UnavailableMethods)
+
+	for method := range failed {
+		capabilities.FailedProbes = append(capabilities.FailedProbes, method)
+	}
+	sort.Strings(capabilities.FailedProbes)
+
+	return capabilities, nil
+}
+
+// rawCall runs the RPC call with the given parameters and decodes its result
+// into the target.
+func (rc *remoteChain) rawCall(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	target interface{},
+) error {
+	if rc.batcher != nil {
+		return rc.batchCall(ctx, method, params, target)
+	}
+
+	result, err := rc.call(ctx, method, func() (interface{}, error) {
+		encodedParams := make([]json.RawMessage, len(params))
+		for i, param := range params {
+			encodedParam, err := json.Marshal(param)
+			if err != nil {
+				return nil, err
+			}
+
+			encodedParams[i] = encodedParam
+		}
+
+		return rc.client.RawRequest(method, encodedParams)
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(result.(json.RawMessage), target)
+}
+
+// isMethodUnavailable returns whether the error means the node does not know
+// or does not permit the method. Errors returned by the method itself, e.g.
+// about a transaction not being found, mean the method is available. Nodes
+// restricting the RPC methods with `-rpcwhitelist` reject the request before
+// it reaches the method, with the 403 status and no RPC error. Other errors,
+// like timeouts or refused connections, do not tell whether the method is
+// available.
+func isMethodUnavailable(err error) bool {
+	switch rpcErr := err.(type) {
+	case *btcjson.RPCError:
+		return rpcErr.Code == btcjson.ErrRPCMethodNotFound.Code
+	case *batchError:
+		return rpcErr.Code == int(btcjson.ErrRPCMethodNotFound.Code)
+	case *batchRejectedError:
+		return true
+	}
+
+	if err == rpcclient.ErrInvalidAuth {
+		return true
+	}
+
+	// The RPC client reports responses without an RPC error only with
+	// their status code.
+	message := err.Error()
+	return strings.HasPrefix(message, "status code: 401,") ||
+		strings.HasPrefix(message, "status code: 403,")
+}
+
+// isRPCError returns whether the error is an RPC error returned by the node.
+func isRPCError(err error) bool {
+	switch err.(type) {
+	case *btcjson.RPCError, *batchError:
+		return true
+	default:
+		return false
+	}
+}
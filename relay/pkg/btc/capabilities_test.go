@@ -0,0 +1,252 @@
+package btc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRemoteChain_ProbeCapabilities(t *testing.T) {
+	tipHash := "000000000000000000024bead8df69990852c202db0e0097c1a12ea637d7e96d"
+	coinbaseID := "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			switch request.Method {
+			case "getnetworkinfo":
+				return map[string]interface{}{
+					"version":    250000,
+					"subversion": "/Satoshi:25.0.0/",
+				}, nil
+			case "getblockchaininfo":
+				return map[string]interface{}{
+					"chain":       "main",
+					"blocks":      800000,
+					"pruned":      true,
+					"pruneheight": 750000,
+				}, nil
+			case "getblockhash":
+				return tipHash, nil
+			case "getblock":
+				return map[string]interface{}{"tx": []string{coinbaseID}}, nil
+			case "getrawtransaction":
+				return nil, &batchError{
+					Code:    -5,
+					Message: "No such mempool transaction. Use -txindex",
+				}
+			case "getblockfilter":
+				return nil, &batchError{
+					Code:    -1,
+					Message: "Index is not enabled for filtertype basic",
+				}
+			case "getrawmempool":
+				return []string{}, nil
+			case "getmempoolentry":
+				return nil, &batchError{
+					Code:    -5,
+					Message: "Transaction not in mempool",
+				}
+			case "gettxout":
+				return nil, nil
+			}
+			return nil, &batchError{Code: -32601, Message: "Method not found"}
+		},
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	chain := &remoteChain{
+		requestTimeout: time.Second,
+		batcher:        newTestBatcher(server, 10, 0),
+	}
+
+	capabilities, err := chain.ProbeCapabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCapabilities := &Capabilities{
+		Version:            250000,
+		Subversion:         "/Satoshi:25.0.0/",
+		Pruned:             true,
+		PruneHeight:        750000,
+		TxIndex:            false,
+		BlockFilterIndex:   false,
+		UnavailableMethods: []string{"getchaintips"},
+	}
+	if !reflect.DeepEqual(expectedCapabilities, capabilities) {
+		t.Errorf(
+			"unexpected capabilities\nexpected: [%+v]\nactual:   [%+v]",
+			expectedCapabilities,
+			capabilities,
+		)
+	}
+
+	if supported, _ := capabilities.SupportsDepositMonitor(); !supported {
+		t.Errorf("deposit monitor should be supported")
+	}
+
+	if supported, reason := capabilities.SupportsProofs(); supported {
+		t.Errorf("proofs should not be supported without txindex")
+	} else if reason == "" {
+		t.Errorf("missing reason of unsupported proofs")
+	}
+}
+
+func TestRemoteChain_ProbeCapabilities_FailedProbes(t *testing.T) {
+	tipHash := "000000000000000000024bead8df69990852c202db0e0097c1a12ea637d7e96d"
+	coinbaseID := "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+	node := &batchNode{
+		handle: func(request *batchRequest) (interface{}, *batchError) {
+			switch request.Method {
+			case "getnetworkinfo":
+				return map[string]interface{}{}, nil
+			case "getblockchaininfo":
+				return map[string]interface{}{"blocks": 800000}, nil
+			case "getblockhash":
+				return tipHash, nil
+			case "getblock":
+				return map[string]interface{}{"tx": []string{coinbaseID}}, nil
+			case "getblockfilter":
+				return map[string]interface{}{}, nil
+			case "getrawmempool":
+				return []string{}, nil
+			case "getmempoolentry":
+				return nil, &batchError{
+					Code:    -5,
+					Message: "Transaction not in mempool",
+				}
+			case "gettxout":
+				return nil, nil
+			}
+			return nil, &batchError{Code: -32601, Message: "Method not found"}
+		},
+	}
+
+	// Probes are sent one by one, so each batch carries a single method.
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var requests []*batchRequest
+			if err := json.Unmarshal(body, &requests); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			switch requests[0].Method {
+			case "getrawtransaction":
+				// Transient failure of the node.
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			case "getchaintips":
+				// Method not permitted by -rpcwhitelist.
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			node.ServeHTTP(w, r)
+		},
+	))
+	defer server.Close()
+
+	chain := &remoteChain{
+		requestTimeout: time.Second,
+		batcher:        newTestBatcher(server, 10, 0),
+	}
+
+	capabilities, err := chain.ProbeCapabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCapabilities := &Capabilities{
+		TxIndex:            true,
+		BlockFilterIndex:   true,
+		UnavailableMethods: []string{"getchaintips"},
+		FailedProbes:       []string{"getrawtransaction"},
+	}
+	if !reflect.DeepEqual(expectedCapabilities, capabilities) {
+		t.Errorf(
+			"unexpected capabilities\nexpected: [%+v]\nactual:   [%+v]",
+			expectedCapabilities,
+			capabilities,
+		)
+	}
+
+	if supported, reason := capabilities.SupportsDepositMonitor(); !supported {
+		t.Errorf("deposit monitor should be supported: %v", reason)
+	}
+
+	if supported, reason := capabilities.SupportsProofs(); !supported {
+		t.Errorf("proofs should be supported: %v", reason)
+	}
+}
+
+func TestIsMethodUnavailable(t *testing.T) {
+	var tests = map[string]struct {
+		err                 error
+		expectedUnavailable bool
+	}{
+		"method not found": {
+			err:                 &batchError{Code: -32601, Message: "Method not found"},
+			expectedUnavailable: true,
+		},
+		"method error": {
+			err:                 &batchError{Code: -5, Message: "not found"},
+			expectedUnavailable: false,
+		},
+		"batch rejected": {
+			err:                 &batchRejectedError{status: "403 Forbidden"},
+			expectedUnavailable: true,
+		},
+		"request forbidden": {
+			err:                 fmt.Errorf("status code: 403, response: \"\""),
+			expectedUnavailable: true,
+		},
+		"request unauthorized": {
+			err:                 fmt.Errorf("status code: 401, response: \"\""),
+			expectedUnavailable: true,
+		},
+		"service unavailable": {
+			err:                 fmt.Errorf("status code: 503, response: \"\""),
+			expectedUnavailable: false,
+		},
+		"timeout": {
+			err:                 fmt.Errorf("RPC call [getblock] abandoned: [context deadline exceeded]"),
+			expectedUnavailable: false,
+		},
+		"connection refused": {
+			err:                 fmt.Errorf("dial tcp 127.0.0.1:8332: connect: connection refused"),
+			expectedUnavailable: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			unavailable := isMethodUnavailable(test.err)
+			if unavailable != test.expectedUnavailable {
+				t.Errorf(
+					"unexpected result:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedUnavailable,
+					unavailable,
+				)
+			}
+		})
+	}
+}
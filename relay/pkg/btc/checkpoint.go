@@ -0,0 +1,168 @@
+package btc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// checkpoint.go file contains the representation of Bitcoin chain
+// checkpoints, i.e. headers known to be part of the chain at the given
+// heights. Checkpoints are used to verify header data coming from
+// sources which are not fully trusted. Built-in checkpoints are the ones
+// embedded in the network parameters, like in Bitcoin Core; they are
+// available for the mainnet and testnet.
+
+// Checkpoint represents a header known to be at the given height of the
+// Bitcoin chain.
+type Checkpoint struct {
+	Height int64
+	Digest Digest
+}
+
+// ParseCheckpoint parses a checkpoint in the `height:digest` format where the
+// digest is hex-encoded.
+func ParseCheckpoint(checkpoint string) (*Checkpoint, error) {
+	parts := strings.Split(checkpoint, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf(
+			"checkpoint [%v] is not in the height:digest format",
+			checkpoint,
+		)
+	}
+
+	height, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || height < 0 {
+		return nil, fmt.Errorf(
+			"invalid height of checkpoint [%v]",
+			checkpoint,
+		)
+	}
+
+	var digest Digest
+	if err := digest.UnmarshalText([]byte(parts[1])); err != nil {
+		return nil, fmt.Errorf(
+			"invalid digest of checkpoint [%v]: [%v]",
+			checkpoint,
+			err,
+		)
+	}
+
+	return &Checkpoint{Height: height, Digest: digest}, nil
+}
+
+// ParseCheckpoints parses all given checkpoints in the `height:digest`
+// format.
+func ParseCheckpoints(checkpoints []string) ([]*Checkpoint, error) {
+	parsed := make([]*Checkpoint, 0, len(checkpoints))
+
+	for _, checkpoint := range checkpoints {
+		checkpoint, err := ParseCheckpoint(checkpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, checkpoint)
+	}
+
+	return parsed, nil
+}
+
+func (c *Checkpoint) String() string {
+	return fmt.Sprintf("%v:%v", c.Height, c.Digest)
+}
+
+// BuiltinCheckpoints returns the checkpoints embedded in the parameters of
+// the given Bitcoin network, ordered from the oldest one.
+func BuiltinCheckpoints(params *chaincfg.Params) []*Checkpoint {
+	checkpoints := make([]*Checkpoint, len(params.Checkpoints))
+	for i, checkpoint := range params.Checkpoints {
+		checkpoints[i] = &Checkpoint{
+			Height: int64(checkpoint.Height),
+			Digest: Digest(*checkpoint.Hash),
+		}
+	}
+
+	return checkpoints
+}
+
+// CheckpointVerifier checks whether headers pass through the known
+// checkpoints.
+type CheckpointVerifier struct {
+	checkpoints map[int64]*Checkpoint
+}
+
+// NewCheckpointVerifier creates a verifier of the given checkpoints. An
+// error is returned if two of the checkpoints conflict with each other.
+func NewCheckpointVerifier(
+	checkpoints []*Checkpoint,
+) (*CheckpointVerifier, error) {
+	byHeight := make(map[int64]*Checkpoint)
+
+	for _, checkpoint := range checkpoints {
+		if existing, ok := byHeight[checkpoint.Height]; ok &&
+			existing.Digest != checkpoint.Digest {
+			return nil, fmt.Errorf(
+				"checkpoint [%v] conflicts with checkpoint [%v]",
+				checkpoint,
+				existing,
+			)
+		}
+
+		byHeight[checkpoint.Height] = checkpoint
+	}
+
+	return &CheckpointVerifier{checkpoints: byHeight}, nil
+}
+
+// Verify returns an error if there is a checkpoint at the height of the
+// given header and its digest is different.
+func (cv *CheckpointVerifier) Verify(header *Header) error {
+	checkpoint, ok := cv.checkpoints[header.Height]
+	if !ok || checkpoint.Digest == header.Hash {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"header [%v] with digest [%v] conflicts with checkpoint [%v]",
+		header.Height,
+		header.Hash,
+		checkpoint,
+	)
+}
+
+// VerifyChain checks whether the best chain of the given Bitcoin chain
+// handle passes through all checkpoints at or below its tip.
+func (cv *CheckpointVerifier) VerifyChain(
+	ctx context.Context,
+	btcChain Handle,
+) error {
+	tipHeight, err := btcChain.GetBlockCount(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get block count: [%v]", err)
+	}
+
+	for height := range cv.checkpoints {
+		if height > tipHeight {
+			continue
+		}
+
+		header, err := btcChain.GetHeaderByHeight(ctx, height)
+		if err != nil {
+			return fmt.Errorf(
+				"could not get header [%v]: [%v]",
+				height,
+				err,
+			)
+		}
+
+		if err := cv.Verify(header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
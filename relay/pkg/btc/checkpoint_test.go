@@ -0,0 +1,148 @@
+package btc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestParseCheckpoint(t *testing.T) {
+	digest := "6fe28c0ab6f1b372c1a6a246ae63f74f931e8365e15a089c68d6190000000000"
+
+	var tests = map[string]struct {
+		checkpoint  string
+		expectError bool
+	}{
+		"valid checkpoint": {
+			checkpoint: "11111:" + digest,
+		},
+		"missing digest": {
+			checkpoint:  "11111",
+			expectError: true,
+		},
+		"negative height": {
+			checkpoint:  "-1:" + digest,
+			expectError: true,
+		},
+		"invalid digest": {
+			checkpoint:  "11111:1234",
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			checkpoint, err := ParseCheckpoint(test.checkpoint)
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Fatalf(
+					"unexpected error:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+
+			if !test.expectError && checkpoint.String() != test.checkpoint {
+				t.Errorf(
+					"unexpected checkpoint:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.checkpoint,
+					checkpoint,
+				)
+			}
+		})
+	}
+}
+
+func TestBuiltinCheckpoints(t *testing.T) {
+	checkpoints := BuiltinCheckpoints(&chaincfg.MainNetParams)
+
+	if len(checkpoints) == 0 {
+		t.Fatal("expected built-in mainnet checkpoints")
+	}
+
+	expected := "11111:" +
+		"1d7c6eb2fd42f55925e92efad68b61edd22fba29fde8783df744e26900000000"
+	if checkpoints[0].String() != expected {
+		t.Errorf(
+			"unexpected first checkpoint:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expected,
+			checkpoints[0],
+		)
+	}
+
+	if len(BuiltinCheckpoints(&chaincfg.RegressionNetParams)) != 0 {
+		t.Errorf("expected no built-in regtest checkpoints")
+	}
+}
+
+func TestCheckpointVerifier(t *testing.T) {
+	ctx := context.Background()
+
+	headers := []*Header{
+		{Hash: Digest{1}, Height: 1},
+		{Hash: Digest{2}, Height: 2},
+		{Hash: Digest{3}, Height: 3},
+	}
+
+	var tests = map[string]struct {
+		checkpoints []*Checkpoint
+		expectError bool
+	}{
+		"matching checkpoint": {
+			checkpoints: []*Checkpoint{{Height: 2, Digest: Digest{2}}},
+		},
+		"checkpoint above the tip": {
+			checkpoints: []*Checkpoint{{Height: 10, Digest: Digest{10}}},
+		},
+		"conflicting checkpoint": {
+			checkpoints: []*Checkpoint{{Height: 2, Digest: Digest{3}}},
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			btcChain, err := ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			btcChain.(*LocalChain).SetHeaders(headers)
+
+			verifier, err := NewCheckpointVerifier(test.checkpoints)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = verifier.VerifyChain(ctx, btcChain)
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Errorf(
+					"unexpected error:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+		})
+	}
+}
+
+func TestNewCheckpointVerifier_Conflict(t *testing.T) {
+	_, err := NewCheckpointVerifier([]*Checkpoint{
+		{Height: 2, Digest: Digest{2}},
+		{Height: 2, Digest: Digest{3}},
+	})
+	if err == nil {
+		t.Errorf("expected error for conflicting checkpoints")
+	}
+}
@@ -0,0 +1,144 @@
+package btc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ipfs/go-log"
+)
+
+// diagnostics.go file contains checks of the Bitcoin node configuration
+// performed by the doctor command. Unlike Connect, each check is run
+// separately so that all problems can be reported at once.
+
+// SyncStatus describes the synchronization status of the Bitcoin node.
+type SyncStatus struct {
+	Blocks               int64
+	Headers              int64
+	VerificationProgress float64
+	TipTime              time.Time
+}
+
+// IsSynced checks whether the node has validated all known headers.
+func (ss *SyncStatus) IsSynced() bool {
+	return ss.Blocks >= ss.Headers && ss.VerificationProgress >= 0.9999
+}
+
+// Diagnostics runs checks against the configured Bitcoin node.
+type Diagnostics struct {
+	client            nodeClient
+	params            *chaincfg.Params
+	connectionTimeout time.Duration
+}
+
+// NewDiagnostics creates diagnostics for the configured Bitcoin node. The
+// returned diagnostics should be closed once no longer needed.
+func NewDiagnostics(config *Config) (*Diagnostics, error) {
+	connCfg, params, err := rpcConnConfig(config, log.Logger(loggerName))
+	if err != nil {
+		return nil, err
+	}
+
+	if usesHTTPTransport(config) {
+		requestTimeout := configRequestTimeout(config)
+
+		batcher, err := newConfiguredBatcher(config, connCfg, requestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not configure HTTP transport: [%v]",
+				err,
+			)
+		}
+
+		return &Diagnostics{
+			client: &batcherNodeClient{
+				batcher:        batcher,
+				requestTimeout: requestTimeout,
+			},
+			params:            params,
+			connectionTimeout: connectionTimeoutOf(config),
+		}, nil
+	}
+
+	client, err := newRPCClient(connCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Diagnostics{
+		client:            client,
+		params:            params,
+		connectionTimeout: connectionTimeoutOf(config),
+	}, nil
+}
+
+// CheckConnection checks whether the node is reachable with the configured
+// credentials.
+func (d *Diagnostics) CheckConnection() error {
+	return testConnection(d.client, d.connectionTimeout)
+}
+
+// CheckNetwork checks whether the node runs the configured network.
+func (d *Diagnostics) CheckNetwork() error {
+	info, err := d.client.GetBlockChainInfo()
+	if err != nil {
+		return rpcPermissionError("getblockchaininfo", err)
+	}
+
+	if expectedChain := nodeChainName(d.params); info.Chain != expectedChain {
+		return fmt.Errorf(
+			"node runs the [%v] Bitcoin chain while the [%v] network "+
+				"is configured",
+			info.Chain,
+			d.params.Name,
+		)
+	}
+
+	return nil
+}
+
+// CheckRPCMethods checks whether the node permits all RPC methods required
+// by the relay.
+func (d *Diagnostics) CheckRPCMethods() error {
+	if _, err := d.client.GetBlockCount(); err != nil {
+		return rpcPermissionError("getblockcount", err)
+	}
+
+	return verifyRPCPermissions(d.client, d.params)
+}
+
+// SyncStatus returns the synchronization status of the node.
+func (d *Diagnostics) SyncStatus() (*SyncStatus, error) {
+	info, err := d.client.GetBlockChainInfo()
+	if err != nil {
+		return nil, fmt.Errorf("could not get blockchain info: [%v]", err)
+	}
+
+	status := &SyncStatus{
+		Blocks:               int64(info.Blocks),
+		Headers:              int64(info.Headers),
+		VerificationProgress: info.VerificationProgress,
+	}
+
+	// Resolve the tip using the methods required by the relay anyway so
+	// the check also works against nodes restricting the RPC methods.
+	tipHash, err := d.client.GetBlockHash(status.Blocks)
+	if err != nil {
+		return nil, fmt.Errorf("could not get best block hash: [%v]", err)
+	}
+
+	tipHeader, err := d.client.GetBlockHeader(tipHash)
+	if err != nil {
+		return nil, fmt.Errorf("could not get best block header: [%v]", err)
+	}
+
+	status.TipTime = tipHeader.Timestamp
+
+	return status, nil
+}
+
+// Close disconnects the diagnostics from the node.
+func (d *Diagnostics) Close() {
+	d.client.Shutdown()
+}
@@ -2,26 +2,38 @@ package btc
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 // LocalChain represents a local Bitcoin chain.
 type LocalChain struct {
 	headers         []*Header
 	orphanedHeaders []*Header
+	transactions    []*Transaction
+	blockTxIDs      map[Digest][]Digest
+	mempool         map[Digest]*MempoolEntry
+	spentOutputs    map[Outpoint]bool
+	chainTips       []*ChainTip
+	blockFilters    map[Digest][]byte
+	params          *chaincfg.Params
 }
 
 // ConnectLocal connects to the local Bitcoin chain and returns a chain handle.
 func ConnectLocal() (Handle, error) {
-	logger.Infof("connecting local Bitcoin chain")
-
-	return &LocalChain{}, nil
+	return &LocalChain{params: &chaincfg.RegressionNetParams}, nil
 }
 
 // GetHeaderByHeight returns the block header from the longest block chain at
 // the given block height.
-func (lc *LocalChain) GetHeaderByHeight(height int64) (*Header, error) {
+func (lc *LocalChain) GetHeaderByHeight(
+	ctx context.Context,
+	height int64,
+) (*Header, error) {
 	for _, header := range lc.headers {
 		if header.Height == height {
 			return header, nil
@@ -33,6 +45,7 @@ func (lc *LocalChain) GetHeaderByHeight(height int64) (*Header, error) {
 
 // GetHeaderByDigest returns the block header for given digest (hash).
 func (lc *LocalChain) GetHeaderByDigest(
+	ctx context.Context,
 	digest Digest,
 ) (*Header, error) {
 	for _, header := range lc.headers {
@@ -53,8 +66,22 @@ func (lc *LocalChain) GetHeaderByDigest(
 	)
 }
 
+// GetHeightByDigest returns the height of the block with the given digest
+// (hash).
+func (lc *LocalChain) GetHeightByDigest(
+	ctx context.Context,
+	digest Digest,
+) (int64, error) {
+	header, err := lc.GetHeaderByDigest(ctx, digest)
+	if err != nil {
+		return 0, err
+	}
+
+	return header.Height, nil
+}
+
 // GetBlockCount returns the number of blocks in the longest blockchain
-func (lc *LocalChain) GetBlockCount() (int64, error) {
+func (lc *LocalChain) GetBlockCount(ctx context.Context) (int64, error) {
 	var count int64
 	for _, header := range lc.headers {
 		if header.Height > count {
@@ -64,6 +91,158 @@ func (lc *LocalChain) GetBlockCount() (int64, error) {
 	return count, nil
 }
 
+// GetTransaction returns the parsed transaction with the given ID along
+// with its confirmation data.
+func (lc *LocalChain) GetTransaction(
+	ctx context.Context,
+	txID Digest,
+) (*Transaction, error) {
+	for _, transaction := range lc.transactions {
+		if transaction.TxID == txID {
+			return transaction, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"no transaction with ID [%v]",
+		chainhash.Hash(txID),
+	)
+}
+
+// GetRawTransaction returns the serialized transaction with the given ID.
+func (lc *LocalChain) GetRawTransaction(
+	ctx context.Context,
+	txID Digest,
+) ([]byte, error) {
+	transaction, err := lc.GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction.Raw, nil
+}
+
+// GetBlockTxIDs returns IDs of all transactions included in the block
+// with the given digest, in the order of the block.
+func (lc *LocalChain) GetBlockTxIDs(
+	ctx context.Context,
+	digest Digest,
+) ([]Digest, error) {
+	txIDs, ok := lc.blockTxIDs[digest]
+	if !ok {
+		return nil, fmt.Errorf("no block with digest [%v]", digest)
+	}
+
+	return txIDs, nil
+}
+
+// GetBlockTransactions returns the transactions set for testing purposes
+// which are included in the block with the given digest.
+func (lc *LocalChain) GetBlockTransactions(
+	ctx context.Context,
+	digest Digest,
+) ([]*Transaction, error) {
+	txIDs, err := lc.GetBlockTxIDs(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]*Transaction, len(txIDs))
+	for i, txID := range txIDs {
+		transaction, err := lc.GetTransaction(ctx, txID)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions[i] = transaction
+	}
+
+	return transactions, nil
+}
+
+// GetBlockFilter returns the block filter set for the block with the given
+// digest. ErrBlockFiltersUnsupported is returned if no block filters are set.
+func (lc *LocalChain) GetBlockFilter(
+	ctx context.Context,
+	digest Digest,
+) ([]byte, error) {
+	if lc.blockFilters == nil {
+		return nil, ErrBlockFiltersUnsupported
+	}
+
+	filter, ok := lc.blockFilters[digest]
+	if !ok {
+		return nil, fmt.Errorf("no block filter for digest [%v]", digest)
+	}
+
+	return filter, nil
+}
+
+// GetMempoolTxIDs returns IDs of all transactions in the node mempool.
+func (lc *LocalChain) GetMempoolTxIDs(ctx context.Context) ([]Digest, error) {
+	txIDs := make([]Digest, 0, len(lc.mempool))
+	for txID := range lc.mempool {
+		txIDs = append(txIDs, txID)
+	}
+
+	return txIDs, nil
+}
+
+// GetMempoolEntry returns the fee data of the mempool transaction with
+// the given ID.
+func (lc *LocalChain) GetMempoolEntry(
+	ctx context.Context,
+	txID Digest,
+) (*MempoolEntry, error) {
+	entry, ok := lc.mempool[txID]
+	if !ok {
+		return nil, fmt.Errorf(
+			"no mempool transaction with ID [%v]",
+			chainhash.Hash(txID),
+		)
+	}
+
+	return entry, nil
+}
+
+// IsOutputUnspent checks whether the output with the given index of the
+// transaction with the given ID has not been marked as spent.
+func (lc *LocalChain) IsOutputUnspent(
+	ctx context.Context,
+	txID Digest,
+	outputIndex uint32,
+) (bool, error) {
+	return !lc.spentOutputs[Outpoint{TxID: txID, OutputIndex: outputIndex}], nil
+}
+
+// GetChainTips returns the chain tips set for testing purposes or, if none
+// are set, the tip of the local chain as the only active tip.
+func (lc *LocalChain) GetChainTips(ctx context.Context) ([]*ChainTip, error) {
+	if lc.chainTips != nil {
+		return lc.chainTips, nil
+	}
+
+	if len(lc.headers) == 0 {
+		return []*ChainTip{}, nil
+	}
+
+	tip := lc.headers[len(lc.headers)-1]
+	return []*ChainTip{
+		{Height: tip.Height, Hash: tip.Hash, Status: ChainTipActive},
+	}, nil
+}
+
+// NetworkParams returns the consensus parameters of the Bitcoin network
+// the handle is connected to.
+func (lc *LocalChain) NetworkParams() *chaincfg.Params {
+	return lc.params
+}
+
+// SetNetworkParams sets the network parameters for testing purposes.
+func (lc *LocalChain) SetNetworkParams(params *chaincfg.Params) {
+	lc.params = params
+}
+
 // SetHeaders sets internal headers for testing purposes.
 func (lc *LocalChain) SetHeaders(headers []*Header) {
 	lc.headers = headers
@@ -78,3 +257,47 @@ func (lc *LocalChain) AppendHeader(header *Header) {
 func (lc *LocalChain) SetOrphanedHeaders(headers []*Header) {
 	lc.orphanedHeaders = headers
 }
+
+// SetTransactions sets internal transactions for testing purposes.
+func (lc *LocalChain) SetTransactions(transactions []*Transaction) {
+	lc.transactions = transactions
+}
+
+// SetBlockTxIDs sets IDs of transactions included in the block with the
+// given digest for testing purposes.
+func (lc *LocalChain) SetBlockTxIDs(digest Digest, txIDs []Digest) {
+	if lc.blockTxIDs == nil {
+		lc.blockTxIDs = make(map[Digest][]Digest)
+	}
+
+	lc.blockTxIDs[digest] = txIDs
+}
+
+// SetBlockFilter sets the serialized filter of the block with the given
+// digest for testing purposes.
+func (lc *LocalChain) SetBlockFilter(digest Digest, filter []byte) {
+	if lc.blockFilters == nil {
+		lc.blockFilters = make(map[Digest][]byte)
+	}
+
+	lc.blockFilters[digest] = filter
+}
+
+// SetMempool sets the mempool entries for testing purposes.
+func (lc *LocalChain) SetMempool(mempool map[Digest]*MempoolEntry) {
+	lc.mempool = mempool
+}
+
+// SetChainTips sets the chain tips for testing purposes.
+func (lc *LocalChain) SetChainTips(tips []*ChainTip) {
+	lc.chainTips = tips
+}
+
+// SetOutputSpent marks the given output as spent for testing purposes.
+func (lc *LocalChain) SetOutputSpent(outpoint Outpoint) {
+	if lc.spentOutputs == nil {
+		lc.spentOutputs = make(map[Outpoint]bool)
+	}
+
+	lc.spentOutputs[outpoint] = true
+}
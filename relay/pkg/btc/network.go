@@ -0,0 +1,174 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Names of the Bitcoin networks supported by the relay.
+const (
+	NetworkMainnet = "mainnet"
+	NetworkTestnet = "testnet"
+	NetworkRegtest = "regtest"
+	NetworkSignet  = "signet"
+)
+
+// DefaultSignetChallenge is the block script challenge of the public signet.
+const DefaultSignetChallenge = "512103ad5e0edad18cb1f0fc0d28a3d4f1f3e44564" +
+	"0337489abb10404f2d1e086be430210359ef5021964fe22d6f8e05b2463c9540ce96" +
+	"883fe3b278760f048f5189f2e6c452ae"
+
+// signetPowLimit is the highest proof of work value a signet block can have.
+var signetPowLimit, _ = new(big.Int).SetString(
+	"00000377ae000000000000000000000000000000000000000000000000000000",
+	16,
+)
+
+// signetGenesisBlock is the genesis block shared by all signets unless
+// a custom genesis is used. Its coinbase is the same as on mainnet.
+var signetGenesisBlock = wire.MsgBlock{
+	Header: wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  chainhash.Hash{},
+		MerkleRoot: chaincfg.MainNetParams.GenesisBlock.Header.MerkleRoot,
+		Timestamp:  time.Unix(1598918400, 0),
+		Bits:       0x1e0377ae,
+		Nonce:      52613770,
+	},
+	Transactions: chaincfg.MainNetParams.GenesisBlock.Transactions,
+}
+
+// NetworkParams returns the consensus parameters of the Bitcoin network with
+// the given name. An empty name resolves to the mainnet. The `signet` name
+// resolves to the public signet.
+func NetworkParams(network string) (*chaincfg.Params, error) {
+	switch network {
+	case "", NetworkMainnet:
+		return &chaincfg.MainNetParams, nil
+	case NetworkTestnet:
+		return &chaincfg.TestNet3Params, nil
+	case NetworkRegtest:
+		return &chaincfg.RegressionNetParams, nil
+	case NetworkSignet:
+		return SignetParams(DefaultSignetChallenge, "")
+	default:
+		return nil, fmt.Errorf("unknown Bitcoin network [%v]", network)
+	}
+}
+
+// SignetParams returns the consensus parameters of the signet with the given
+// hex-encoded block script challenge. The genesis hash should be given only
+// for signets started from a custom genesis block and is the default signet
+// genesis hash otherwise.
+func SignetParams(
+	challenge string,
+	genesisHash string,
+) (*chaincfg.Params, error) {
+	challengeScript, err := hex.DecodeString(challenge)
+	if err != nil || len(challengeScript) == 0 {
+		return nil, fmt.Errorf(
+			"invalid signet challenge [%v]: [%v]",
+			challenge,
+			err,
+		)
+	}
+
+	net, err := signetNet(challengeScript)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bitcoin Core applies the same rules on all signets, only the block
+	// script challenge differs.
+	params := chaincfg.TestNet3Params
+	params.Name = NetworkSignet
+	params.Net = net
+	params.DefaultPort = "38333"
+	params.DNSSeeds = nil
+	params.GenesisBlock = &signetGenesisBlock
+	params.PowLimit = signetPowLimit
+	params.PowLimitBits = signetGenesisBlock.Header.Bits
+	params.BIP0034Height = 1
+	params.BIP0065Height = 1
+	params.BIP0066Height = 1
+	params.ReduceMinDifficulty = false
+	params.MinDiffReductionTime = 0
+	params.Checkpoints = nil
+
+	defaultGenesisHash := signetGenesisBlock.BlockHash()
+	params.GenesisHash = &defaultGenesisHash
+
+	if genesisHash != "" {
+		customGenesisHash, err := chainhash.NewHashFromStr(genesisHash)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid signet genesis hash [%v]: [%v]",
+				genesisHash,
+				err,
+			)
+		}
+
+		// The custom genesis block itself is not known, only its hash.
+		params.GenesisBlock = nil
+		params.GenesisHash = customGenesisHash
+	}
+
+	return &params, nil
+}
+
+// signetNet returns the network magic of the signet with the given block
+// script challenge. It consists of the first four bytes of the double
+// SHA-256 of the serialized challenge.
+func signetNet(challengeScript []byte) (wire.BitcoinNet, error) {
+	var buffer bytes.Buffer
+	if err := wire.WriteVarBytes(&buffer, 0, challengeScript); err != nil {
+		return 0, fmt.Errorf("could not serialize signet challenge: [%v]", err)
+	}
+
+	hash := chainhash.DoubleHashB(buffer.Bytes())
+
+	return wire.BitcoinNet(binary.LittleEndian.Uint32(hash[:4])), nil
+}
+
+// ConfigNetworkParams returns the consensus parameters of the network set in
+// the given config, taking the custom signet settings into account.
+func ConfigNetworkParams(config *Config) (*chaincfg.Params, error) {
+	if config.Network != NetworkSignet {
+		if config.SignetChallenge != "" || config.SignetGenesisHash != "" {
+			return nil, fmt.Errorf(
+				"signet settings are configured for the [%v] network",
+				config.Network,
+			)
+		}
+
+		return NetworkParams(config.Network)
+	}
+
+	challenge := config.SignetChallenge
+	if challenge == "" {
+		challenge = DefaultSignetChallenge
+	}
+
+	return SignetParams(challenge, config.SignetGenesisHash)
+}
+
+// nodeChainName returns the chain name reported by the `getblockchaininfo`
+// call of a Bitcoin Core node running the network with the given parameters.
+func nodeChainName(params *chaincfg.Params) string {
+	switch params.Name {
+	case chaincfg.MainNetParams.Name:
+		return "main"
+	case chaincfg.TestNet3Params.Name:
+		return "test"
+	default:
+		return params.Name
+	}
+}
@@ -0,0 +1,129 @@
+package btc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestSignetParams_Default(t *testing.T) {
+	params, err := NetworkParams(NetworkSignet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedGenesisHash :=
+		"00000008819873e925422c1ff0f99f7cc9bbb232af63a077a480a3633bee1ef6"
+	if params.GenesisHash.String() != expectedGenesisHash {
+		t.Errorf(
+			"unexpected genesis hash:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedGenesisHash,
+			params.GenesisHash,
+		)
+	}
+
+	// Message start bytes of the public signet are 0a03cf40.
+	expectedNet := wire.BitcoinNet(0x40cf030a)
+	if params.Net != expectedNet {
+		t.Errorf(
+			"unexpected network magic:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedNet,
+			params.Net,
+		)
+	}
+
+	if nodeChainName(params) != "signet" {
+		t.Errorf("unexpected node chain name: [%v]", nodeChainName(params))
+	}
+}
+
+func TestConfigNetworkParams(t *testing.T) {
+	customGenesisHash :=
+		"000000a5e1d7b0b8931e224bb2de9d6ec0488bdf0aaa4241a94b78a4b12c1d4b"
+
+	var tests = map[string]struct {
+		config              *Config
+		expectedGenesisHash string
+		expectedError       bool
+	}{
+		"custom challenge": {
+			config: &Config{
+				Network:         NetworkSignet,
+				SignetChallenge: "51",
+			},
+			expectedGenesisHash: "00000008819873e925422c1ff0f99f7cc9bbb2" +
+				"32af63a077a480a3633bee1ef6",
+		},
+		"custom genesis": {
+			config: &Config{
+				Network:           NetworkSignet,
+				SignetChallenge:   "51",
+				SignetGenesisHash: customGenesisHash,
+			},
+			expectedGenesisHash: customGenesisHash,
+		},
+		"invalid challenge": {
+			config: &Config{
+				Network:         NetworkSignet,
+				SignetChallenge: "zz",
+			},
+			expectedError: true,
+		},
+		"invalid genesis hash": {
+			config: &Config{
+				Network:           NetworkSignet,
+				SignetGenesisHash: "zz",
+			},
+			expectedError: true,
+		},
+		"challenge for other network": {
+			config: &Config{
+				Network:         NetworkTestnet,
+				SignetChallenge: "51",
+			},
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			params, err := ConfigNetworkParams(test.config)
+			if test.expectedError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if params.GenesisHash.String() != test.expectedGenesisHash {
+				t.Errorf(
+					"unexpected genesis hash:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedGenesisHash,
+					params.GenesisHash,
+				)
+			}
+
+			if params.BIP0065Height != 1 {
+				t.Errorf("unexpected BIP-65 height: [%v]", params.BIP0065Height)
+			}
+		})
+	}
+
+	defaultParams, _ := NetworkParams(NetworkSignet)
+	customParams, _ := ConfigNetworkParams(&Config{
+		Network:         NetworkSignet,
+		SignetChallenge: "51",
+	})
+	if defaultParams.Net == customParams.Net {
+		t.Errorf("custom signet should have a different network magic")
+	}
+}
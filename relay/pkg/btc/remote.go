@@ -3,70 +3,196 @@ package btc
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
 )
 
 const connectionTimeout = 3 * time.Second
 
+// defaultRequestTimeout is the default maximum time a single RPC call can
+// take.
+const defaultRequestTimeout = 30 * time.Second
+
 // remoteChain represents a remote Bitcoin chain.
 type remoteChain struct {
-	client *rpcclient.Client
+	client         *rpcclient.Client
+	params         *chaincfg.Params
+	requestTimeout time.Duration
+
+	// batcher coalesces concurrent calls into batch requests. If nil, each
+	// call is sent separately.
+	batcher *rpcBatcher
 }
 
-// Connect connects to the Bitcoin chain and returns a chain handle.
+// Connect connects to the Bitcoin chain and returns a chain handle. Messages
+// are logged to the given logger or, if it is nil, to the default logger.
 func Connect(
 	ctx context.Context,
 	config *Config,
+	logger logs.Logger,
 ) (Handle, error) {
+	logger = logs.OrDefault(logger, loggerName)
+
 	logger.Infof("connecting remote Bitcoin chain")
 
-	connCfg := &rpcclient.ConnConfig{
-		User:         config.Username,
-		Pass:         config.Password,
-		Host:         config.URL,
-		HTTPPostMode: true, // Bitcoin core only supports HTTP POST mode
-		DisableTLS:   true, // Bitcoin core does not provide TLS by default
+	connCfg, params, err := rpcConnConfig(config, logger)
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := rpcclient.New(connCfg, nil)
+	client, err := newRPCClient(connCfg)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to create rpc client at [%s]: [%v]",
-			config.URL,
-			err,
-		)
+		return nil, err
 	}
 
-	err = testConnection(client, connectionTimeout)
+	requestTimeout := configRequestTimeout(config)
+
+	batcher, err := newConfiguredBatcher(config, connCfg, requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure HTTP transport: [%v]", err)
+	}
+
+	var node nodeClient = client
+	if usesHTTPTransport(config) {
+		if config.Tor.Enabled {
+			logger.Infof("connecting Bitcoin node through Tor")
+		}
+
+		logger.Infof("sending RPC calls with the configured HTTP transport")
+
+		node = &batcherNodeClient{
+			batcher:        batcher,
+			requestTimeout: requestTimeout,
+		}
+	}
+
+	err = testConnection(node, connectionTimeoutOf(config))
 	if err != nil {
 		return nil, fmt.Errorf(
 			"error while connecting to [%s]: [%v]; check if the Bitcoin node "+
 				"is running and you provided correct credentials and url",
-			config.URL,
+			transport.RedactURL(config.URL),
 			err,
 		)
 	}
 
+	err = verifyNetwork(node, params, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyRPCPermissions(node, params)
+	if err != nil {
+		return nil, err
+	}
+
 	// When the context is done, cancel all requests from the RPC client
 	// and disconnect it.
 	go func() {
 		<-ctx.Done()
-		logger.Info("disconnecting from remote Bitcoin chain")
+		logger.Infof("disconnecting from remote Bitcoin chain")
 		client.Shutdown()
 	}()
 
-	return &remoteChain{client: client}, nil
+	if config.BatchWindow > 0 {
+		logger.Infof(
+			"batching RPC calls issued within [%v] ms",
+			config.BatchWindow,
+		)
+	}
+
+	chain := &remoteChain{
+		client:         client,
+		params:         params,
+		requestTimeout: requestTimeout,
+		batcher:        batcher,
+	}
+
+	return chain, nil
+}
+
+// configRequestTimeout returns the configured maximum time a single RPC call
+// can take or the default one if not configured.
+func configRequestTimeout(config *Config) time.Duration {
+	if config.RequestTimeout > 0 {
+		return time.Duration(config.RequestTimeout) * time.Second
+	}
+
+	return defaultRequestTimeout
+}
+
+// rpcConnConfig returns the connection config of the configured Bitcoin node
+// and resolves the parameters of the configured network.
+func rpcConnConfig(
+	config *Config,
+	logger logs.Logger,
+) (*rpcclient.ConnConfig, *chaincfg.Params, error) {
+	params, err := ConfigNetworkParams(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := verifyOnionRouting(config); err != nil {
+		return nil, nil, err
+	}
+
+	username, password, err := rpcCredentials(config, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Bitcoin core does not provide TLS by default so TLS is only used if
+	// the URL has the `https://` scheme, e.g. for TLS-terminating proxies.
+	host, useTLS := splitURLScheme(config.URL)
+	if err := transport.CheckHostPort(host); err != nil {
+		return nil, nil, err
+	}
+
+	connCfg := &rpcclient.ConnConfig{
+		User:         username,
+		Pass:         password,
+		Host:         host,
+		HTTPPostMode: true, // Bitcoin core only supports HTTP POST mode
+		DisableTLS:   !useTLS,
+	}
+
+	return connCfg, params, nil
+}
+
+// newRPCClient creates an RPC client for the Bitcoin node. The connection is
+// not tested.
+func newRPCClient(connCfg *rpcclient.ConnConfig) (*rpcclient.Client, error) {
+	client, err := rpcclient.New(connCfg, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to create rpc client at [%s]: [%v]",
+			connCfg.Host,
+			err,
+		)
+	}
+
+	return client, nil
 }
 
 // GetHeaderByHeight returns the block header from the longest block chain at
 // the given block height.
-func (rc *remoteChain) GetHeaderByHeight(height int64) (*Header, error) {
-	blockHash, err := rc.client.GetBlockHash(height)
+func (rc *remoteChain) GetHeaderByHeight(
+	ctx context.Context,
+	height int64,
+) (*Header, error) {
+	blockHash, err := rc.getBlockHash(ctx, height)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get block hash for height [%d]: [%v]",
@@ -75,7 +201,7 @@ func (rc *remoteChain) GetHeaderByHeight(height int64) (*Header, error) {
 		)
 	}
 
-	blockHeader, err := rc.client.GetBlockHeader(blockHash)
+	blockHeader, err := rc.getBlockHeader(ctx, blockHash)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get block header for hash [%s]: [%v]",
@@ -105,11 +231,479 @@ func (rc *remoteChain) GetHeaderByHeight(height int64) (*Header, error) {
 }
 
 // GetBlockCount returns the number of blocks in the longest block chain
-func (rc *remoteChain) GetBlockCount() (int64, error) {
-	return rc.client.GetBlockCount()
+func (rc *remoteChain) GetBlockCount(ctx context.Context) (int64, error) {
+	if rc.batcher != nil {
+		var count int64
+		err := rc.batchCall(ctx, "getblockcount", []interface{}{}, &count)
+		return count, err
+	}
+
+	result, err := rc.call(ctx, "getblockcount", func() (interface{}, error) {
+		return rc.client.GetBlockCount()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.(int64), nil
 }
 
-func testConnection(client *rpcclient.Client, timeout time.Duration) error {
+// GetTransaction returns the parsed transaction with the given ID along
+// with its confirmation data.
+func (rc *remoteChain) GetTransaction(
+	ctx context.Context,
+	txID Digest,
+) (*Transaction, error) {
+	result, err := rc.getRawTransactionVerbose(ctx, (*chainhash.Hash)(&txID))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get transaction [%v]: [%v]",
+			chainhash.Hash(txID),
+			err,
+		)
+	}
+
+	raw, err := hex.DecodeString(result.Hex)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not decode transaction [%v]: [%v]",
+			result.Txid,
+			err,
+		)
+	}
+
+	transaction, err := ParseTransaction(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.BlockHash != "" {
+		blockHash, err := chainhash.NewHashFromStr(result.BlockHash)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode block hash of transaction [%v]: [%v]",
+				result.Txid,
+				err,
+			)
+		}
+
+		blockDigest := Digest(*blockHash)
+		transaction.BlockHash = &blockDigest
+		transaction.Confirmations = result.Confirmations
+		transaction.BlockTime = time.Unix(result.Blocktime, 0)
+	}
+
+	return transaction, nil
+}
+
+// GetRawTransaction returns the serialized transaction with the given ID.
+func (rc *remoteChain) GetRawTransaction(
+	ctx context.Context,
+	txID Digest,
+) ([]byte, error) {
+	result, err := rc.getRawTransactionVerbose(ctx, (*chainhash.Hash)(&txID))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get transaction [%v]: [%v]",
+			chainhash.Hash(txID),
+			err,
+		)
+	}
+
+	return hex.DecodeString(result.Hex)
+}
+
+// GetBlockTxIDs returns IDs of all transactions included in the block
+// with the given digest, in the order of the block.
+func (rc *remoteChain) GetBlockTxIDs(
+	ctx context.Context,
+	digest Digest,
+) ([]Digest, error) {
+	block, err := rc.getBlockVerbose(ctx, (*chainhash.Hash)(&digest))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get block [%v]: [%v]",
+			digest.String(),
+			err,
+		)
+	}
+
+	txIDs := make([]Digest, len(block.Tx))
+	for i, tx := range block.Tx {
+		txID, err := chainhash.NewHashFromStr(tx)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode ID of transaction [%v] of block [%v]: [%v]",
+				i,
+				digest.String(),
+				err,
+			)
+		}
+
+		txIDs[i] = Digest(*txID)
+	}
+
+	return txIDs, nil
+}
+
+// GetBlockTransactions returns the parsed transactions included in the block
+// with the given digest, in the order of the block.
+func (rc *remoteChain) GetBlockTransactions(
+	ctx context.Context,
+	digest Digest,
+) ([]*Transaction, error) {
+	block, err := rc.getBlock(ctx, (*chainhash.Hash)(&digest))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get block [%v]: [%v]",
+			digest.String(),
+			err,
+		)
+	}
+
+	transactions := make([]*Transaction, len(block.Transactions))
+	for i, msgTx := range block.Transactions {
+		var raw bytes.Buffer
+		if err := msgTx.Serialize(&raw); err != nil {
+			return nil, fmt.Errorf(
+				"could not serialize transaction [%v] of block [%v]: [%v]",
+				i,
+				digest.String(),
+				err,
+			)
+		}
+
+		transaction, err := ParseTransaction(raw.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		blockDigest := digest
+		transaction.BlockHash = &blockDigest
+		transaction.BlockTime = block.Header.Timestamp
+
+		transactions[i] = transaction
+	}
+
+	return transactions, nil
+}
+
+// GetBlockFilter returns the serialized BIP-158 basic compact filter of the
+// block with the given digest. ErrBlockFiltersUnsupported is returned if the
+// node does not know the `getblockfilter` method or runs without the block
+// filter index.
+func (rc *remoteChain) GetBlockFilter(
+	ctx context.Context,
+	digest Digest,
+) ([]byte, error) {
+	hash := chainhash.Hash(digest).String()
+
+	var rawFilter json.RawMessage
+	var err error
+	if rc.batcher != nil {
+		err = rc.batchCall(
+			ctx,
+			"getblockfilter",
+			[]interface{}{hash},
+			&rawFilter,
+		)
+	} else {
+		var result interface{}
+		result, err = rc.call(ctx, "getblockfilter", func() (interface{}, error) {
+			encodedHash, err := json.Marshal(hash)
+			if err != nil {
+				return nil, err
+			}
+
+			return rc.client.RawRequest(
+				"getblockfilter",
+				[]json.RawMessage{encodedHash},
+			)
+		})
+		if err == nil {
+			rawFilter = result.(json.RawMessage)
+		}
+	}
+	if err != nil {
+		if isBlockFilterUnsupported(err) {
+			return nil, ErrBlockFiltersUnsupported
+		}
+
+		return nil, fmt.Errorf(
+			"could not get filter of block [%v]: [%v]",
+			digest.String(),
+			err,
+		)
+	}
+
+	var result blockFilterResult
+	if err := json.Unmarshal(rawFilter, &result); err != nil {
+		return nil, fmt.Errorf(
+			"could not decode filter of block [%v]: [%v]",
+			digest.String(),
+			err,
+		)
+	}
+
+	return hex.DecodeString(result.Filter)
+}
+
+// blockFilterResult is the result of the `getblockfilter` call.
+type blockFilterResult struct {
+	Filter string `json:"filter"`
+	Header string `json:"header"`
+}
+
+// isBlockFilterUnsupported returns whether the error means the node does not
+// serve block filters, either because it does not know the method or because
+// it runs without the block filter index.
+func isBlockFilterUnsupported(err error) bool {
+	var code int
+	var message string
+	switch rpcErr := err.(type) {
+	case *btcjson.RPCError:
+		code, message = int(rpcErr.Code), rpcErr.Message
+	case *batchError:
+		code, message = rpcErr.Code, rpcErr.Message
+	default:
+		return false
+	}
+
+	return code == int(btcjson.ErrRPCMethodNotFound.Code) ||
+		strings.Contains(message, "Index is not enabled")
+}
+
+// GetMempoolTxIDs returns IDs of all transactions in the node mempool.
+func (rc *remoteChain) GetMempoolTxIDs(ctx context.Context) ([]Digest, error) {
+	if rc.batcher != nil {
+		var hashes []string
+		err := rc.batchCall(ctx, "getrawmempool", []interface{}{}, &hashes)
+		if err != nil {
+			return nil, fmt.Errorf("could not get mempool: [%v]", err)
+		}
+
+		txIDs := make([]Digest, len(hashes))
+		for i, hash := range hashes {
+			decoded, err := chainhash.NewHashFromStr(hash)
+			if err != nil {
+				return nil, fmt.Errorf("could not get mempool: [%v]", err)
+			}
+			txIDs[i] = Digest(*decoded)
+		}
+
+		return txIDs, nil
+	}
+
+	result, err := rc.call(ctx, "getrawmempool", func() (interface{}, error) {
+		return rc.client.GetRawMempool()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get mempool: [%v]", err)
+	}
+
+	hashes := result.([]*chainhash.Hash)
+
+	txIDs := make([]Digest, len(hashes))
+	for i, hash := range hashes {
+		txIDs[i] = Digest(*hash)
+	}
+
+	return txIDs, nil
+}
+
+// GetMempoolEntry returns the fee data of the mempool transaction with
+// the given ID.
+func (rc *remoteChain) GetMempoolEntry(
+	ctx context.Context,
+	txID Digest,
+) (*MempoolEntry, error) {
+	txIDString := chainhash.Hash(txID).String()
+
+	// The entry is requested directly as the RPC client does not support
+	// fields returned by recent Bitcoin Core versions.
+	var rawEntry json.RawMessage
+	var err error
+	if rc.batcher != nil {
+		err = rc.batchCall(
+			ctx,
+			"getmempoolentry",
+			[]interface{}{txIDString},
+			&rawEntry,
+		)
+	} else {
+		var param json.RawMessage
+		param, err = json.Marshal(txIDString)
+		if err != nil {
+			return nil, err
+		}
+
+		var result interface{}
+		result, err = rc.call(
+			ctx,
+			"getmempoolentry",
+			func() (interface{}, error) {
+				return rc.client.RawRequest(
+					"getmempoolentry",
+					[]json.RawMessage{param},
+				)
+			},
+		)
+		if err == nil {
+			rawEntry = result.(json.RawMessage)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get mempool entry of transaction [%v]: [%v]",
+			chainhash.Hash(txID),
+			err,
+		)
+	}
+
+	var entry mempoolEntryResult
+	if err := json.Unmarshal(rawEntry, &entry); err != nil {
+		return nil, fmt.Errorf(
+			"could not decode mempool entry of transaction [%v]: [%v]",
+			chainhash.Hash(txID),
+			err,
+		)
+	}
+
+	return entry.toMempoolEntry(), nil
+}
+
+// IsOutputUnspent checks whether the output with the given index of the
+// transaction with the given ID is in the confirmed unspent outputs set.
+func (rc *remoteChain) IsOutputUnspent(
+	ctx context.Context,
+	txID Digest,
+	outputIndex uint32,
+) (bool, error) {
+	var output *btcjson.GetTxOutResult
+	var err error
+	if rc.batcher != nil {
+		err = rc.batchCall(
+			ctx,
+			"gettxout",
+			[]interface{}{chainhash.Hash(txID).String(), outputIndex, false},
+			&output,
+		)
+	} else {
+		var result interface{}
+		result, err = rc.call(ctx, "gettxout", func() (interface{}, error) {
+			return rc.client.GetTxOut(
+				(*chainhash.Hash)(&txID),
+				outputIndex,
+				false,
+			)
+		})
+		if err == nil {
+			output = result.(*btcjson.GetTxOutResult)
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf(
+			"could not get output [%v] of transaction [%v]: [%v]",
+			outputIndex,
+			chainhash.Hash(txID),
+			err,
+		)
+	}
+
+	// Spent or unknown outputs are returned as null.
+	return output != nil, nil
+}
+
+// GetChainTips returns the tips of all chains known by the node,
+// including the active chain and competing forks.
+func (rc *remoteChain) GetChainTips(ctx context.Context) ([]*ChainTip, error) {
+	var rawTips json.RawMessage
+	var err error
+	if rc.batcher != nil {
+		err = rc.batchCall(ctx, "getchaintips", []interface{}{}, &rawTips)
+	} else {
+		var result interface{}
+		result, err = rc.call(ctx, "getchaintips", func() (interface{}, error) {
+			return rc.client.RawRequest("getchaintips", nil)
+		})
+		if err == nil {
+			rawTips = result.(json.RawMessage)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get chain tips: [%v]", err)
+	}
+
+	var results []*chainTipResult
+	if err := json.Unmarshal(rawTips, &results); err != nil {
+		return nil, fmt.Errorf("could not decode chain tips: [%v]", err)
+	}
+
+	tips := make([]*ChainTip, len(results))
+	for i, result := range results {
+		hash, err := chainhash.NewHashFromStr(result.Hash)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode hash of chain tip [%v]: [%v]",
+				result.Height,
+				err,
+			)
+		}
+
+		tips[i] = &ChainTip{
+			Height:       result.Height,
+			Hash:         Digest(*hash),
+			BranchLength: result.BranchLength,
+			Status:       result.Status,
+		}
+	}
+
+	return tips, nil
+}
+
+// chainTipResult is a single chain tip returned by the `getchaintips` call.
+type chainTipResult struct {
+	Height       int64  `json:"height"`
+	Hash         string `json:"hash"`
+	BranchLength int64  `json:"branchlen"`
+	Status       string `json:"status"`
+}
+
+// NetworkParams returns the consensus parameters of the Bitcoin network
+// the handle is connected to.
+func (rc *remoteChain) NetworkParams() *chaincfg.Params {
+	return rc.params
+}
+
+// verifyNetwork checks whether the node runs the configured network. Nodes
+// which do not report their chain are accepted with a warning.
+func verifyNetwork(
+	client nodeClient,
+	params *chaincfg.Params,
+	logger logs.Logger,
+) error {
+	info, err := client.GetBlockChainInfo()
+	if err != nil {
+		logger.Warnf(
+			"could not verify the network of the Bitcoin node: [%v]",
+			err,
+		)
+		return nil
+	}
+
+	if expectedChain := nodeChainName(params); info.Chain != expectedChain {
+		return fmt.Errorf(
+			"node runs the [%v] Bitcoin chain while the [%v] network "+
+				"is configured",
+			info.Chain,
+			params.Name,
+		)
+	}
+
+	return nil
+}
+
+func testConnection(client nodeClient, timeout time.Duration) error {
 	errChan := make(chan error, 1)
 
 	go func() {
@@ -141,10 +735,10 @@ func serializeHeader(header *wire.BlockHeader) ([]byte, error) {
 
 // GetHeaderByDigest returns the block header for given digest (hash).
 func (rc *remoteChain) GetHeaderByDigest(
+	ctx context.Context,
 	digest Digest,
 ) (*Header, error) {
-
-	blockHeader, err := rc.client.GetBlockHeader((*chainhash.Hash)(&digest))
+	blockHeader, err := rc.getBlockHeader(ctx, (*chainhash.Hash)(&digest))
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get block header for hash [%s]: [%v]",
@@ -153,7 +747,10 @@ func (rc *remoteChain) GetHeaderByDigest(
 		)
 	}
 
-	headerVerbose, err := rc.client.GetBlockHeaderVerbose((*chainhash.Hash)(&digest))
+	headerVerbose, err := rc.getBlockHeaderVerbose(
+		ctx,
+		(*chainhash.Hash)(&digest),
+	)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get block header verbose for hash [%s]: [%v]",
@@ -181,3 +778,298 @@ func (rc *remoteChain) GetHeaderByDigest(
 
 	return relayHeader, nil
 }
+
+// GetHeightByDigest returns the height of the block with the given digest
+// (hash). Unlike GetHeaderByDigest, it needs only the verbose block header.
+func (rc *remoteChain) GetHeightByDigest(
+	ctx context.Context,
+	digest Digest,
+) (int64, error) {
+	headerVerbose, err := rc.getBlockHeaderVerbose(
+		ctx,
+		(*chainhash.Hash)(&digest),
+	)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"could not get block header verbose for hash [%s]: [%v]",
+			digest.String(),
+			err,
+		)
+	}
+
+	return int64(headerVerbose.Height), nil
+}
+
+// call runs the given RPC call and waits for its result until the context is
+// done or the request timeout is hit. The RPC client does not support
+// cancellation so an abandoned call keeps running in the background but its
+// result is discarded.
+func (rc *remoteChain) call(
+	ctx context.Context,
+	method string,
+	rpcCall func() (interface{}, error),
+) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, rc.requestTimeout)
+	defer cancel()
+
+	type callResult struct {
+		value interface{}
+		err   error
+	}
+
+	resultChan := make(chan callResult, 1)
+
+	go func() {
+		value, err := rpcCall()
+		resultChan <- callResult{value, err}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result.value, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf(
+			"RPC call [%v] abandoned: [%v]",
+			method,
+			ctx.Err(),
+		)
+	}
+}
+
+// batchCall sends the call through the batcher. The call is abandoned once
+// it takes longer than the request timeout.
+func (rc *remoteChain) batchCall(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	target interface{},
+) error {
+	ctx, cancel := context.WithTimeout(ctx, rc.requestTimeout)
+	defer cancel()
+
+	return rc.batcher.call(ctx, method, params, target)
+}
+
+func (rc *remoteChain) getBlockHash(
+	ctx context.Context,
+	height int64,
+) (*chainhash.Hash, error) {
+	if rc.batcher != nil {
+		var hash string
+		err := rc.batchCall(ctx, "getblockhash", []interface{}{height}, &hash)
+		if err != nil {
+			return nil, err
+		}
+
+		return chainhash.NewHashFromStr(hash)
+	}
+
+	result, err := rc.call(ctx, "getblockhash", func() (interface{}, error) {
+		return rc.client.GetBlockHash(height)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*chainhash.Hash), nil
+}
+
+func (rc *remoteChain) getBlockHeader(
+	ctx context.Context,
+	hash *chainhash.Hash,
+) (*wire.BlockHeader, error) {
+	if rc.batcher != nil {
+		var rawHeader string
+		err := rc.batchCall(
+			ctx,
+			"getblockheader",
+			[]interface{}{hash.String(), false},
+			&rawHeader,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		serialized, err := hex.DecodeString(rawHeader)
+		if err != nil {
+			return nil, err
+		}
+
+		header := &wire.BlockHeader{}
+		if err := header.Deserialize(bytes.NewReader(serialized)); err != nil {
+			return nil, err
+		}
+
+		return header, nil
+	}
+
+	result, err := rc.call(ctx, "getblockheader", func() (interface{}, error) {
+		return rc.client.GetBlockHeader(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*wire.BlockHeader), nil
+}
+
+func (rc *remoteChain) getBlockHeaderVerbose(
+	ctx context.Context,
+	hash *chainhash.Hash,
+) (*btcjson.GetBlockHeaderVerboseResult, error) {
+	if rc.batcher != nil {
+		header := &btcjson.GetBlockHeaderVerboseResult{}
+		err := rc.batchCall(
+			ctx,
+			"getblockheader",
+			[]interface{}{hash.String(), true},
+			header,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return header, nil
+	}
+
+	result, err := rc.call(ctx, "getblockheader", func() (interface{}, error) {
+		return rc.client.GetBlockHeaderVerbose(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*btcjson.GetBlockHeaderVerboseResult), nil
+}
+
+func (rc *remoteChain) getBlockVerbose(
+	ctx context.Context,
+	hash *chainhash.Hash,
+) (*btcjson.GetBlockVerboseResult, error) {
+	if rc.batcher != nil {
+		block := &btcjson.GetBlockVerboseResult{}
+		err := rc.batchCall(
+			ctx,
+			"getblock",
+			[]interface{}{hash.String(), 1},
+			block,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return block, nil
+	}
+
+	result, err := rc.call(ctx, "getblock", func() (interface{}, error) {
+		return rc.client.GetBlockVerbose(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*btcjson.GetBlockVerboseResult), nil
+}
+
+func (rc *remoteChain) getBlock(
+	ctx context.Context,
+	hash *chainhash.Hash,
+) (*wire.MsgBlock, error) {
+	if rc.batcher != nil {
+		var rawBlock string
+		err := rc.batchCall(
+			ctx,
+			"getblock",
+			[]interface{}{hash.String(), 0},
+			&rawBlock,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		serialized, err := hex.DecodeString(rawBlock)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode block: [%v]", err)
+		}
+
+		block := &wire.MsgBlock{}
+		if err := block.Deserialize(bytes.NewReader(serialized)); err != nil {
+			return nil, fmt.Errorf("could not deserialize block: [%v]", err)
+		}
+
+		return block, nil
+	}
+
+	result, err := rc.call(ctx, "getblock", func() (interface{}, error) {
+		return rc.client.GetBlock(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*wire.MsgBlock), nil
+}
+
+func (rc *remoteChain) getRawTransactionVerbose(
+	ctx context.Context,
+	hash *chainhash.Hash,
+) (*btcjson.TxRawResult, error) {
+	if rc.batcher != nil {
+		transaction := &btcjson.TxRawResult{}
+		err := rc.batchCall(
+			ctx,
+			"getrawtransaction",
+			[]interface{}{hash.String(), 1},
+			transaction,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return transaction, nil
+	}
+
+	result, err := rc.call(
+		ctx,
+		"getrawtransaction",
+		func() (interface{}, error) {
+			return rc.client.GetRawTransactionVerbose(hash)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*btcjson.TxRawResult), nil
+}
+
+// mempoolEntryResult is the result of the `getmempoolentry` call. Fees are
+// returned in BTC. Bitcoin Core versions before 0.19 return only the `fee`
+// and `size` fields.
+type mempoolEntryResult struct {
+	Size  int64   `json:"size"`
+	VSize int64   `json:"vsize"`
+	Time  int64   `json:"time"`
+	Fee   float64 `json:"fee"`
+	Fees  struct {
+		Base float64 `json:"base"`
+	} `json:"fees"`
+}
+
+func (mer *mempoolEntryResult) toMempoolEntry() *MempoolEntry {
+	fee := mer.Fees.Base
+	if fee == 0 {
+		fee = mer.Fee
+	}
+
+	virtualSize := mer.VSize
+	if virtualSize == 0 {
+		virtualSize = mer.Size
+	}
+
+	return &MempoolEntry{
+		Fee:         int64(math.Round(fee * 1e8)),
+		VirtualSize: virtualSize,
+		Time:        time.Unix(mer.Time, 0),
+	}
+}
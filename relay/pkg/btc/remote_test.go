@@ -0,0 +1,63 @@
+package btc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRemoteChainCall_Timeout(t *testing.T) {
+	chain := &remoteChain{requestTimeout: 10 * time.Millisecond}
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	_, err := chain.call(
+		context.Background(),
+		"getblockcount",
+		func() (interface{}, error) {
+			<-unblock
+			return int64(1), nil
+		},
+	)
+	if err == nil {
+		t.Fatal("expected error for hung call")
+	}
+}
+
+func TestRemoteChainCall_Cancelled(t *testing.T) {
+	chain := &remoteChain{requestTimeout: time.Minute}
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := chain.call(ctx, "getblockcount", func() (interface{}, error) {
+		<-unblock
+		return int64(1), nil
+	})
+	if err == nil {
+		t.Fatal("expected error for cancelled context")
+	}
+}
+
+func TestRemoteChainCall_Result(t *testing.T) {
+	chain := &remoteChain{requestTimeout: time.Minute}
+
+	result, err := chain.call(
+		context.Background(),
+		"getblockcount",
+		func() (interface{}, error) {
+			return int64(1), nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.(int64) != 1 {
+		t.Errorf("unexpected result: [%v]", result)
+	}
+}
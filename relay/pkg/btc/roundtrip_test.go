@@ -0,0 +1,287 @@
+//go:build integration
+// +build integration
+
+package btc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+// roundtrip_test.go file contains the integration tests fetching the same
+// headers from a bitcoind node through every supported RPC backend and
+// checking that the raw headers and their digests are byte-exact copies of
+// the headers served by the node. A serialization mismatch would make the
+// relay contract reject or, worse, record wrong headers. The tests run only
+// with the `integration` build tag and are configured with the following
+// environment variables:
+//
+//	RELAY_BTC_URL       RPC URL of the bitcoind node
+//	RELAY_BTC_USERNAME  bitcoind RPC username
+//	RELAY_BTC_PASSWORD  bitcoind RPC password
+//	RELAY_BTC_NETWORK   Bitcoin network of the node, `mainnet` by default
+
+// Heights of the checked headers, besides the chain tip and its parent.
+// They cover the genesis header and the headers around the first retarget.
+// Heights above the chain tip are skipped.
+var roundTripHeights = []int64{0, 1, 2015, 2016, 2017}
+
+// roundTripBackends are the RPC backends headers are fetched through. Each
+// backend modifies the node configuration so the handle uses it.
+var roundTripBackends = map[string]func(config *Config){
+	"rpc client": func(config *Config) {},
+	"batched calls": func(config *Config) {
+		config.BatchWindow = 50
+	},
+	"HTTP transport": func(config *Config) {
+		config.HTTP = transport.Config{
+			Headers: map[string]string{"X-Relay-Test": "round-trip"},
+		}
+	},
+}
+
+func TestRoundTrip_RawHeaders(t *testing.T) {
+	config := roundTripConfig(t)
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	heights := roundTripHeightsOf(t, config)
+
+	// Headers served by the node, fetched with plain JSON-RPC calls not
+	// involving any code of the relay.
+	expected := make(map[int64][]byte, len(heights))
+	for _, height := range heights {
+		expected[height] = nodeRawHeader(t, config, height)
+	}
+
+	for name, configure := range roundTripBackends {
+		t.Run(name, func(t *testing.T) {
+			backendConfig := *config
+			configure(&backendConfig)
+
+			handle, err := Connect(ctx, &backendConfig, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Headers are fetched concurrently, so the batched calls are
+			// actually sent in batches.
+			headers := make([]*Header, len(heights))
+			errs := make([]error, len(heights))
+
+			var wg sync.WaitGroup
+			for i, height := range heights {
+				wg.Add(1)
+				go func(i int, height int64) {
+					defer wg.Done()
+					headers[i], errs[i] = handle.GetHeaderByHeight(ctx, height)
+				}(i, height)
+			}
+			wg.Wait()
+
+			for i, height := range heights {
+				if errs[i] != nil {
+					t.Fatalf("could not get header [%v]: [%v]", height, errs[i])
+				}
+
+				assertRoundTrip(t, height, expected[height], headers[i])
+
+				byDigest, err := handle.GetHeaderByDigest(ctx, headers[i].Hash)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				assertRoundTrip(t, height, expected[height], byDigest)
+			}
+		})
+	}
+}
+
+func TestRoundTrip_ParseHeader(t *testing.T) {
+	config := roundTripConfig(t)
+
+	// Headers read from the header store, snapshots and the persisted
+	// headers queue are parsed from their raw form.
+	for _, height := range roundTripHeightsOf(t, config) {
+		raw := nodeRawHeader(t, config, height)
+
+		header, err := ParseHeader(height, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertRoundTrip(t, height, raw, header)
+	}
+}
+
+// assertRoundTrip checks that the given header is a byte-exact copy of the
+// given raw header served by the node and its digests are derived from it.
+func assertRoundTrip(t *testing.T, height int64, raw []byte, header *Header) {
+	t.Helper()
+
+	if header.Height != height {
+		t.Errorf(
+			"unexpected height\nexpected: [%v]\nactual:   [%v]",
+			height,
+			header.Height,
+		)
+	}
+
+	if !bytes.Equal(raw, header.Raw) {
+		t.Errorf(
+			"unexpected raw header [%v]\nexpected: [%x]\nactual:   [%x]",
+			height,
+			raw,
+			header.Raw,
+		)
+	}
+
+	first := sha256.Sum256(raw)
+	digest := Digest(sha256.Sum256(first[:]))
+	if header.Hash != digest {
+		t.Errorf(
+			"unexpected digest of header [%v]\nexpected: [%v]\nactual:   [%v]",
+			height,
+			digest,
+			header.Hash,
+		)
+	}
+
+	var prevHash, merkleRoot Digest
+	copy(prevHash[:], raw[4:36])
+	copy(merkleRoot[:], raw[36:68])
+
+	if header.PrevHash != prevHash || header.MerkleRoot != merkleRoot {
+		t.Errorf(
+			"unexpected derived digests of header [%v]\n"+
+				"expected: [%v %v]\n"+
+				"actual:   [%v %v]",
+			height,
+			prevHash,
+			merkleRoot,
+			header.PrevHash,
+			header.MerkleRoot,
+		)
+	}
+}
+
+func roundTripConfig(t *testing.T) *Config {
+	url := os.Getenv("RELAY_BTC_URL")
+	if url == "" {
+		t.Skip("RELAY_BTC_URL is not set")
+	}
+
+	return &Config{
+		URL:      url,
+		Username: os.Getenv("RELAY_BTC_USERNAME"),
+		Password: os.Getenv("RELAY_BTC_PASSWORD"),
+		Network:  os.Getenv("RELAY_BTC_NETWORK"),
+	}
+}
+
+// roundTripHeightsOf returns the checked heights available on the node.
+func roundTripHeightsOf(t *testing.T, config *Config) []int64 {
+	var tip int64
+	nodeCall(t, config, "getblockcount", []interface{}{}, &tip)
+
+	heights := make([]int64, 0)
+	for _, height := range roundTripHeights {
+		if height < tip-1 {
+			heights = append(heights, height)
+		}
+	}
+
+	if tip > 0 {
+		heights = append(heights, tip-1)
+	}
+
+	return append(heights, tip)
+}
+
+// nodeRawHeader returns the raw header at the given height served by the
+// node.
+func nodeRawHeader(t *testing.T, config *Config, height int64) []byte {
+	var hash string
+	nodeCall(t, config, "getblockhash", []interface{}{height}, &hash)
+
+	var rawHex string
+	nodeCall(t, config, "getblockheader", []interface{}{hash, false}, &rawHex)
+
+	raw, err := hex.DecodeString(rawHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw
+}
+
+// nodeCall calls the given method of the node with a plain JSON-RPC request
+// and unmarshals the result into the given target.
+func nodeCall(
+	t *testing.T,
+	config *Config,
+	method string,
+	params []interface{},
+	result interface{},
+) {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      "round-trip",
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := config.URL
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "http://" + url
+	}
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.SetBasicAuth(config.Username, config.Password)
+	request.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var decoded struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		t.Fatalf("could not decode [%v] response: [%v]", method, err)
+	}
+
+	if decoded.Error != nil {
+		t.Fatalf("[%v] failed: [%v]", method, decoded.Error.Message)
+	}
+
+	if err := json.Unmarshal(decoded.Result, result); err != nil {
+		t.Fatalf("could not unmarshal [%v] result: [%v]", method, err)
+	}
+}
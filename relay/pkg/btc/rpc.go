@@ -0,0 +1,130 @@
+package btc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// RequiredRPCMethods lists all Bitcoin Core RPC methods used by the relay.
+// The relay does not need any wallet or node management methods, so it can
+// run against a node restricting the relay RPC user to these methods with
+// the `-rpcwhitelist` option.
+var RequiredRPCMethods = []string{
+	"getblockchaininfo",
+	"getblockcount",
+	"getblockhash",
+	"getblockheader",
+}
+
+// ForkMonitoringRPCMethods lists Bitcoin Core RPC methods used only by the
+// fork monitoring. They are not checked on startup as the fork monitoring is
+// optional.
+var ForkMonitoringRPCMethods = []string{
+	"getchaintips",
+}
+
+// BlockFilterRPCMethods lists Bitcoin Core RPC methods used only by the
+// deposit monitor to scan block ranges with compact block filters. They are
+// not checked on startup as block ranges can be scanned without filters.
+// Serving block filters requires the node to run with the `-blockfilterindex`
+// option.
+var BlockFilterRPCMethods = []string{
+	"getblockfilter",
+}
+
+// TransactionRPCMethods lists Bitcoin Core RPC methods used only by the
+// transaction, block and mempool retrieval. They are not checked on startup as
+// relaying headers does not need them. Retrieving transactions not kept in the node mempool
+// requires the node to run with the `-txindex` option.
+var TransactionRPCMethods = []string{
+	"getblock",
+	"getmempoolentry",
+	"getrawmempool",
+	"getrawtransaction",
+	"gettxout",
+}
+
+// readCookie reads the RPC credentials from the Bitcoin Core `.cookie` file.
+// The file contains a single `user:password` line.
+func readCookie(path string) (string, string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf(
+			"could not read cookie file [%v]: [%v]",
+			path,
+			err,
+		)
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(content)), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf(
+			"cookie file [%v] is not in the user:password format",
+			path,
+		)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// rpcCredentials returns the RPC credentials from the cookie file, if
+// configured, or the configured username and password.
+func rpcCredentials(
+	config *Config,
+	logger logs.Logger,
+) (string, string, error) {
+	if config.CookieFile == "" {
+		return config.Username, config.Password, nil
+	}
+
+	if config.Username != "" || config.Password != "" {
+		logger.Warnf(
+			"cookie file is configured; ignoring configured username " +
+				"and password",
+		)
+	}
+
+	return readCookie(config.CookieFile)
+}
+
+// verifyRPCPermissions checks whether the node permits all RPC methods
+// required by the relay. Calls are made with arguments valid on any node.
+// The `getblockcount` method is already checked by the connection test and
+// `getblockchaininfo` by the network verification.
+func verifyRPCPermissions(
+	client nodeClient,
+	params *chaincfg.Params,
+) error {
+	genesisHash, err := client.GetBlockHash(0)
+	if err != nil {
+		return rpcPermissionError("getblockhash", err)
+	}
+
+	if _, err := client.GetBlockHeader(genesisHash); err != nil {
+		return rpcPermissionError("getblockheader", err)
+	}
+
+	if !genesisHash.IsEqual(params.GenesisHash) {
+		return fmt.Errorf(
+			"node genesis block [%v] does not match the [%v] network",
+			genesisHash,
+			params.Name,
+		)
+	}
+
+	return nil
+}
+
+func rpcPermissionError(method string, err error) error {
+	return fmt.Errorf(
+		"RPC method [%v] required by the relay failed: [%v]; make sure "+
+			"the node permits all of [%v]",
+		method,
+		err,
+		strings.Join(RequiredRPCMethods, ", "),
+	)
+}
@@ -0,0 +1,123 @@
+package btc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type recordingLogger struct {
+	warnings []string
+}
+
+func (rl *recordingLogger) Debugf(format string, args ...interface{}) {}
+
+func (rl *recordingLogger) Infof(format string, args ...interface{}) {}
+
+func (rl *recordingLogger) Warnf(format string, args ...interface{}) {
+	rl.warnings = append(rl.warnings, fmt.Sprintf(format, args...))
+}
+
+func (rl *recordingLogger) Errorf(format string, args ...interface{}) {}
+
+func TestRPCCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cookieFile := filepath.Join(dir, ".cookie")
+	if err := ioutil.WriteFile(
+		cookieFile,
+		[]byte("__cookie__:a1b2:c3\n"),
+		0600,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = map[string]struct {
+		config           *Config
+		expectedUsername string
+		expectedPassword string
+		expectedWarnings int
+	}{
+		"username and password": {
+			config:           &Config{Username: "user", Password: "password"},
+			expectedUsername: "user",
+			expectedPassword: "password",
+		},
+		"cookie file": {
+			config:           &Config{CookieFile: cookieFile},
+			expectedUsername: "__cookie__",
+			expectedPassword: "a1b2:c3",
+		},
+		"cookie file overrides username and password": {
+			config: &Config{
+				Username:   "user",
+				Password:   "password",
+				CookieFile: cookieFile,
+			},
+			expectedUsername: "__cookie__",
+			expectedPassword: "a1b2:c3",
+			expectedWarnings: 1,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			logger := &recordingLogger{}
+
+			username, password, err := rpcCredentials(test.config, logger)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.expectedUsername != username ||
+				test.expectedPassword != password {
+				t.Errorf(
+					"unexpected credentials:\n"+
+						"expected: [%v:%v]\n"+
+						"actual:   [%v:%v]\n",
+					test.expectedUsername,
+					test.expectedPassword,
+					username,
+					password,
+				)
+			}
+
+			if test.expectedWarnings != len(logger.warnings) {
+				t.Errorf(
+					"unexpected warnings count:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedWarnings,
+					len(logger.warnings),
+				)
+			}
+		})
+	}
+}
+
+func TestReadCookie_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cookieFile := filepath.Join(dir, ".cookie")
+	if err := ioutil.WriteFile(cookieFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := readCookie(cookieFile); err == nil {
+		t.Error("expected error for invalid cookie file")
+	}
+
+	if _, _, err := readCookie(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing cookie file")
+	}
+}
@@ -0,0 +1,134 @@
+package btc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+// tor.go file contains the connection of the Bitcoin node through Tor. All
+// calls are sent through the SOCKS5 proxy of a local Tor client which also
+// resolves the node host name, so the node can run as an onion service and
+// neither the node address nor the queried blocks and transactions are
+// revealed to the network the relay runs in.
+
+// DefaultTorProxy is the default address of the SOCKS5 proxy of the local Tor
+// client.
+const DefaultTorProxy = "127.0.0.1:9050"
+
+// Connecting through Tor takes longer as circuits need to be built first.
+const torConnectionTimeout = 30 * time.Second
+
+// TorConfig is the configuration of the Bitcoin node connection through Tor.
+type TorConfig struct {
+	// Enabled routes all connections to the node through Tor.
+	Enabled bool
+	// Proxy is the address of the SOCKS5 proxy of the Tor client. If empty,
+	// DefaultTorProxy is used.
+	Proxy string
+	// IsolateStreams makes the Tor client use circuits not shared with other
+	// applications using the same Tor client. Requires the default
+	// `IsolateSOCKSAuth` flag of the Tor SOCKS port.
+	IsolateStreams bool
+}
+
+func (tc *TorConfig) proxy() string {
+	if tc.Proxy != "" {
+		return tc.Proxy
+	}
+
+	return DefaultTorProxy
+}
+
+// usesHTTPTransport returns true if the calls to the node must be sent with
+// the relay HTTP client.
+func usesHTTPTransport(config *Config) bool {
+	return config.HTTP.IsSet() || config.Tor.Enabled
+}
+
+// connectionTimeoutOf returns the time the initial connection to the node
+// can take.
+func connectionTimeoutOf(config *Config) time.Duration {
+	if config.Tor.Enabled {
+		return torConnectionTimeout
+	}
+
+	return connectionTimeout
+}
+
+// httpTransportConfig returns the HTTP transport of the node connection with
+// the Tor proxy applied.
+func httpTransportConfig(config *Config) (*transport.Config, error) {
+	httpConfig := config.HTTP
+
+	if !config.Tor.Enabled {
+		return &httpConfig, nil
+	}
+
+	if httpConfig.Proxy != "" {
+		return nil, fmt.Errorf("HTTP proxy cannot be configured with Tor")
+	}
+
+	proxyURL := &url.URL{Scheme: "socks5", Host: config.Tor.proxy()}
+
+	if config.Tor.IsolateStreams {
+		// Tor isolates streams using different SOCKS credentials, which
+		// are otherwise not checked.
+		username, err := randomHex(8)
+		if err != nil {
+			return nil, err
+		}
+
+		password, err := randomHex(8)
+		if err != nil {
+			return nil, err
+		}
+
+		proxyURL.User = url.UserPassword(username, password)
+	}
+
+	httpConfig.Proxy = proxyURL.String()
+
+	return &httpConfig, nil
+}
+
+// verifyOnionRouting checks whether an onion service node is connected
+// through Tor or another SOCKS5 proxy. Onion addresses can be resolved only
+// by the Tor client.
+func verifyOnionRouting(config *Config) error {
+	host, _ := splitURLScheme(config.URL)
+
+	hostname := host
+	if splitHost, _, err := net.SplitHostPort(host); err == nil {
+		hostname = splitHost
+	}
+
+	if !strings.HasSuffix(hostname, ".onion") {
+		return nil
+	}
+
+	if config.Tor.Enabled || strings.HasPrefix(config.HTTP.Proxy, "socks5://") {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"onion service node [%v] can be connected only through Tor; "+
+			"enable Tor or configure a SOCKS5 proxy",
+		hostname,
+	)
+}
+
+func randomHex(length int) (string, error) {
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("could not generate random bytes: [%v]", err)
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
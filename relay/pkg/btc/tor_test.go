@@ -0,0 +1,126 @@
+package btc
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+func TestHTTPTransportConfig_Tor(t *testing.T) {
+	var tests = map[string]struct {
+		config        *Config
+		expectedProxy string
+		isolated      bool
+		expectedError bool
+	}{
+		"tor disabled": {
+			config: &Config{
+				HTTP: transport.Config{Proxy: "http://proxy:3128"},
+			},
+			expectedProxy: "proxy:3128",
+		},
+		"default tor proxy": {
+			config: &Config{
+				Tor: TorConfig{Enabled: true},
+			},
+			expectedProxy: DefaultTorProxy,
+		},
+		"custom tor proxy with isolated streams": {
+			config: &Config{
+				Tor: TorConfig{
+					Enabled:        true,
+					Proxy:          "127.0.0.1:9150",
+					IsolateStreams: true,
+				},
+			},
+			expectedProxy: "127.0.0.1:9150",
+			isolated:      true,
+		},
+		"tor with http proxy": {
+			config: &Config{
+				HTTP: transport.Config{Proxy: "http://proxy:3128"},
+				Tor:  TorConfig{Enabled: true},
+			},
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			httpConfig, err := httpTransportConfig(test.config)
+			if test.expectedError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			proxyURL, err := url.Parse(httpConfig.Proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proxyURL.Host != test.expectedProxy {
+				t.Errorf(
+					"unexpected proxy:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedProxy,
+					proxyURL.Host,
+				)
+			}
+
+			if isolated := proxyURL.User != nil; isolated != test.isolated {
+				t.Errorf(
+					"unexpected stream isolation:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.isolated,
+					isolated,
+				)
+			}
+		})
+	}
+}
+
+func TestVerifyOnionRouting(t *testing.T) {
+	const onionURL = "2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion:8332"
+
+	var tests = map[string]struct {
+		config        *Config
+		expectedError bool
+	}{
+		"clearnet node": {
+			config: &Config{URL: "127.0.0.1:8332"},
+		},
+		"onion node without tor": {
+			config:        &Config{URL: onionURL},
+			expectedError: true,
+		},
+		"onion node with tor": {
+			config: &Config{
+				URL: onionURL,
+				Tor: TorConfig{Enabled: true},
+			},
+		},
+		"onion node with socks5 proxy": {
+			config: &Config{
+				URL:  "http://" + onionURL,
+				HTTP: transport.Config{Proxy: "socks5://127.0.0.1:9050"},
+			},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := verifyOnionRouting(test.config)
+			if test.expectedError != (err != nil) {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package btc
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// transaction.go file contains the representation of Bitcoin transactions
+// retrieved from the Bitcoin node. Transaction IDs are in the internal
+// (little-endian) byte order, the same as block digests.
+
+// Transaction represents a Bitcoin transaction along with its confirmation
+// data.
+type Transaction struct {
+	// TxID is the hash of the transaction serialized without witness data.
+	TxID Digest
+	// Version is the transaction version.
+	Version int32
+	// Inputs are the transaction inputs.
+	Inputs []*TransactionInput
+	// Outputs are the transaction outputs.
+	Outputs []*TransactionOutput
+	// Locktime is the transaction lock time.
+	Locktime uint32
+	// Raw is the serialized transaction, including witness data if any.
+	Raw []byte
+
+	// BlockHash is the hash of the block including the transaction. It is
+	// nil for unconfirmed transactions.
+	BlockHash *Digest
+	// Confirmations is the number of blocks confirming the transaction,
+	// including the block the transaction is included in.
+	Confirmations uint64
+	// BlockTime is the timestamp of the block including the transaction.
+	// It is zero for unconfirmed transactions.
+	BlockTime time.Time
+}
+
+// TransactionInput represents an input of a Bitcoin transaction.
+type TransactionInput struct {
+	// PrevTxID is the ID of the transaction whose output is spent.
+	PrevTxID Digest
+	// PrevOutputIndex is the index of the spent output.
+	PrevOutputIndex uint32
+	// SignatureScript is the script satisfying the spent output conditions.
+	SignatureScript []byte
+	// Witness is the witness stack of the input, empty for non-segwit
+	// inputs.
+	Witness [][]byte
+	// Sequence is the input sequence number.
+	Sequence uint32
+}
+
+// Outpoint identifies an output of a Bitcoin transaction.
+type Outpoint struct {
+	TxID        Digest
+	OutputIndex uint32
+}
+
+// Outpoint returns the output spent by the input.
+func (ti *TransactionInput) Outpoint() Outpoint {
+	return Outpoint{TxID: ti.PrevTxID, OutputIndex: ti.PrevOutputIndex}
+}
+
+// TransactionOutput represents an output of a Bitcoin transaction.
+type TransactionOutput struct {
+	// Value is the output value in satoshis.
+	Value int64
+	// PublicKeyScript is the script defining the conditions of spending
+	// the output.
+	PublicKeyScript []byte
+}
+
+// IsConfirmed checks whether the transaction is included in a block.
+func (t *Transaction) IsConfirmed() bool {
+	return t.BlockHash != nil && t.Confirmations > 0
+}
+
+// ParseTransaction decodes the given serialized transaction. Confirmation
+// data of the returned transaction is not set.
+func ParseTransaction(raw []byte) (*Transaction, error) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	if err := msgTx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("could not deserialize transaction: [%v]", err)
+	}
+
+	transaction := &Transaction{
+		TxID:     Digest(msgTx.TxHash()),
+		Version:  msgTx.Version,
+		Inputs:   make([]*TransactionInput, len(msgTx.TxIn)),
+		Outputs:  make([]*TransactionOutput, len(msgTx.TxOut)),
+		Locktime: msgTx.LockTime,
+		Raw:      raw,
+	}
+
+	for i, txIn := range msgTx.TxIn {
+		transaction.Inputs[i] = &TransactionInput{
+			PrevTxID:        Digest(txIn.PreviousOutPoint.Hash),
+			PrevOutputIndex: txIn.PreviousOutPoint.Index,
+			SignatureScript: txIn.SignatureScript,
+			Witness:         txIn.Witness,
+			Sequence:        txIn.Sequence,
+		}
+	}
+
+	for i, txOut := range msgTx.TxOut {
+		transaction.Outputs[i] = &TransactionOutput{
+			Value:           txOut.Value,
+			PublicKeyScript: txOut.PkScript,
+		}
+	}
+
+	return transaction, nil
+}
+
+// MempoolEntry holds the fee data of an unconfirmed transaction kept in the
+// node mempool.
+type MempoolEntry struct {
+	// Fee is the transaction fee in satoshis.
+	Fee int64
+	// VirtualSize is the transaction virtual size in vbytes.
+	VirtualSize int64
+	// Time is the time the transaction entered the mempool.
+	Time time.Time
+}
+
+// FeeRate returns the transaction fee rate in satoshis per vbyte.
+func (me *MempoolEntry) FeeRate() float64 {
+	if me.VirtualSize == 0 {
+		return 0
+	}
+
+	return float64(me.Fee) / float64(me.VirtualSize)
+}
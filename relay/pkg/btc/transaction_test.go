@@ -0,0 +1,102 @@
+package btc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestParseTransaction_Genesis(t *testing.T) {
+	genesisBlock := chaincfg.MainNetParams.GenesisBlock
+
+	var buffer bytes.Buffer
+	if err := genesisBlock.Transactions[0].Serialize(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := ParseTransaction(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The genesis block has a single transaction so its ID is equal to the
+	// merkle root.
+	expectedTxID := Digest(genesisBlock.Header.MerkleRoot)
+	if transaction.TxID != expectedTxID {
+		t.Errorf(
+			"unexpected transaction ID:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedTxID,
+			transaction.TxID,
+		)
+	}
+
+	if len(transaction.Inputs) != 1 || len(transaction.Outputs) != 1 {
+		t.Fatalf(
+			"unexpected number of inputs [%v] and outputs [%v]",
+			len(transaction.Inputs),
+			len(transaction.Outputs),
+		)
+	}
+
+	if transaction.Outputs[0].Value != 5000000000 {
+		t.Errorf("unexpected output value: [%v]", transaction.Outputs[0].Value)
+	}
+
+	if transaction.IsConfirmed() {
+		t.Errorf("parsed transaction should have no confirmation data")
+	}
+}
+
+func TestParseTransaction_Witness(t *testing.T) {
+	prevTxID := chainhash.Hash{0x01}
+
+	msgTx := wire.NewMsgTx(2)
+	msgTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: prevTxID, Index: 3},
+		Witness:          wire.TxWitness{{0xaa, 0xbb}, {0xcc}},
+		Sequence:         0xfffffffd,
+	})
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x00, 0x14}))
+	msgTx.LockTime = 100
+
+	var buffer bytes.Buffer
+	if err := msgTx.Serialize(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := ParseTransaction(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Transaction ID does not commit to the witness data.
+	if transaction.TxID != Digest(msgTx.TxHash()) {
+		t.Errorf("unexpected transaction ID: [%v]", transaction.TxID)
+	}
+
+	input := transaction.Inputs[0]
+	if input.PrevTxID != Digest(prevTxID) || input.PrevOutputIndex != 3 {
+		t.Errorf("unexpected spent output: [%+v]", input)
+	}
+
+	if len(input.Witness) != 2 {
+		t.Errorf("unexpected witness stack size: [%v]", len(input.Witness))
+	}
+
+	if transaction.Version != 2 || transaction.Locktime != 100 {
+		t.Errorf(
+			"unexpected version [%v] or locktime [%v]",
+			transaction.Version,
+			transaction.Locktime,
+		)
+	}
+
+	if !bytes.Equal(transaction.Raw, buffer.Bytes()) {
+		t.Errorf("unexpected raw transaction")
+	}
+}
@@ -0,0 +1,170 @@
+package btc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+// transport.go file contains the HTTP transport of the remote Bitcoin chain.
+// The RPC client used by the relay cannot send extra headers or present
+// client certificates, so if the HTTP transport of the node or Tor is
+// configured, all calls are sent through the batcher which uses the relay
+// HTTP client. Without a batch window, each call is sent as soon as it is
+// issued.
+
+// nodeClient is the subset of the RPC client methods used to verify the node
+// on connection and by the diagnostics.
+type nodeClient interface {
+	GetBlockCount() (int64, error)
+	GetBlockChainInfo() (*btcjson.GetBlockChainInfoResult, error)
+	GetBlockHash(height int64) (*chainhash.Hash, error)
+	GetBlockHeader(hash *chainhash.Hash) (*wire.BlockHeader, error)
+	Shutdown()
+}
+
+// splitURLScheme strips the optional `http://` or `https://` scheme from
+// the configured node URL and tells whether the node is connected over TLS.
+func splitURLScheme(url string) (string, bool) {
+	if strings.HasPrefix(url, "https://") {
+		return strings.TrimPrefix(url, "https://"), true
+	}
+
+	return strings.TrimPrefix(url, "http://"), false
+}
+
+// newConfiguredBatcher creates the batcher of the configured node. Nil is
+// returned if calls are neither batched nor sent with the configured HTTP
+// transport.
+func newConfiguredBatcher(
+	config *Config,
+	connCfg *rpcclient.ConnConfig,
+	requestTimeout time.Duration,
+) (*rpcBatcher, error) {
+	if config.BatchWindow <= 0 && !usesHTTPTransport(config) {
+		return nil, nil
+	}
+
+	httpConfig, err := httpTransportConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := transport.NewClient(httpConfig, requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	window := time.Duration(config.BatchWindow) * time.Millisecond
+	maxSize := config.MaxBatchSize
+	if config.BatchWindow <= 0 {
+		// Batches of a single call are sent right away.
+		maxSize = 1
+	}
+
+	return newRPCBatcher(
+		connCfg,
+		client,
+		window,
+		maxSize,
+		config.MaxInFlightBatches,
+	), nil
+}
+
+// batcherNodeClient sends the node verification calls through the batcher.
+type batcherNodeClient struct {
+	batcher        *rpcBatcher
+	requestTimeout time.Duration
+}
+
+func (bnc *batcherNodeClient) call(
+	method string,
+	params []interface{},
+	target interface{},
+) error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		bnc.requestTimeout,
+	)
+	defer cancel()
+
+	return bnc.batcher.call(ctx, method, params, target)
+}
+
+func (bnc *batcherNodeClient) GetBlockCount() (int64, error) {
+	var count int64
+	err := bnc.call("getblockcount", []interface{}{}, &count)
+	return count, err
+}
+
+func (bnc *batcherNodeClient) GetBlockChainInfo() (
+	*btcjson.GetBlockChainInfoResult,
+	error,
+) {
+	// Only the fields used by the relay are decoded as the format of the
+	// soft forks differs between node versions.
+	var info struct {
+		Chain                string  `json:"chain"`
+		Blocks               int32   `json:"blocks"`
+		Headers              int32   `json:"headers"`
+		VerificationProgress float64 `json:"verificationprogress"`
+	}
+	if err := bnc.call("getblockchaininfo", []interface{}{}, &info); err != nil {
+		return nil, err
+	}
+
+	return &btcjson.GetBlockChainInfoResult{
+		Chain:                info.Chain,
+		Blocks:               info.Blocks,
+		Headers:              info.Headers,
+		VerificationProgress: info.VerificationProgress,
+	}, nil
+}
+
+func (bnc *batcherNodeClient) GetBlockHash(
+	height int64,
+) (*chainhash.Hash, error) {
+	var hash string
+	if err := bnc.call("getblockhash", []interface{}{height}, &hash); err != nil {
+		return nil, err
+	}
+
+	return chainhash.NewHashFromStr(hash)
+}
+
+func (bnc *batcherNodeClient) GetBlockHeader(
+	hash *chainhash.Hash,
+) (*wire.BlockHeader, error) {
+	var rawHeader string
+	err := bnc.call(
+		"getblockheader",
+		[]interface{}{hash.String(), false},
+		&rawHeader,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	serialized, err := hex.DecodeString(rawHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &wire.BlockHeader{}
+	if err := header.Deserialize(bytes.NewReader(serialized)); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// Shutdown does nothing as the batcher keeps no connections open.
+func (bnc *batcherNodeClient) Shutdown() {}
@@ -0,0 +1,254 @@
+package btc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// MedianTimeBlocks is the number of previous headers used to compute
+	// the median time past.
+	MedianTimeBlocks = 11
+
+	// Maximum time by which a header timestamp can be ahead of the
+	// current time.
+	maxTimeOffset = 2 * time.Hour
+
+	// Size of a serialized Bitcoin block header.
+	headerSize = 80
+)
+
+// ValidationError is returned when a header breaks contextual rules
+// enforced by Bitcoin full nodes.
+type ValidationError struct {
+	Header     *Header
+	Violations []string
+}
+
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf(
+		"header [%v] violates contextual rules: [%v]",
+		ve.Header.Height,
+		strings.Join(ve.Violations, "; "),
+	)
+}
+
+// HeaderValidator checks headers against the contextual rules Bitcoin full
+// nodes apply when accepting a block: the timestamp must be above the median
+// time past of the previous blocks and not too far in the future, the hash,
+// the previous hash and the merkle root must match the serialized header and
+// the version must signal all soft forks activated by the BIP-34, BIP-66 and
+// BIP-65 deployments.
+//
+// The validator is not safe for concurrent use.
+type HeaderValidator struct {
+	params *chaincfg.Params
+
+	lastHeader     *Header
+	ancestorsTimes []time.Time
+}
+
+// NewHeaderValidator creates a new header validator for the Bitcoin network
+// with the given parameters.
+func NewHeaderValidator(params *chaincfg.Params) *HeaderValidator {
+	return &HeaderValidator{params: params}
+}
+
+// Reset sets the context of the validator to the given ancestors ordered
+// from the oldest one. Only the most recent ancestors needed to compute
+// the median time past are retained.
+func (hv *HeaderValidator) Reset(ancestors []*Header) error {
+	hv.lastHeader = nil
+	hv.ancestorsTimes = nil
+
+	for _, ancestor := range ancestors {
+		blockHeader, err := deserializeHeader(ancestor.Raw)
+		if err != nil {
+			return fmt.Errorf(
+				"could not deserialize ancestor [%v]: [%v]",
+				ancestor.Height,
+				err,
+			)
+		}
+
+		hv.accept(ancestor, blockHeader)
+	}
+
+	return nil
+}
+
+// Accept adds the given header to the validator context without validating
+// it.
+func (hv *HeaderValidator) Accept(header *Header) error {
+	blockHeader, err := deserializeHeader(header.Raw)
+	if err != nil {
+		return err
+	}
+
+	hv.accept(header, blockHeader)
+
+	return nil
+}
+
+// Extends checks whether the given header is a direct successor of the last
+// header known by the validator. If not, the validator context should be
+// reset before validating the header.
+func (hv *HeaderValidator) Extends(header *Header) bool {
+	return hv.lastHeader != nil &&
+		hv.lastHeader.Hash == header.PrevHash &&
+		hv.lastHeader.Height+1 == header.Height
+}
+
+// Validate checks the header against the contextual rules and adds it to
+// the validator context. A *ValidationError listing all broken rules is
+// returned if the header is not valid. The header is not added to the
+// context in that case.
+func (hv *HeaderValidator) Validate(header *Header, now time.Time) error {
+	blockHeader, err := deserializeHeader(header.Raw)
+	if err != nil {
+		return &ValidationError{
+			Header:     header,
+			Violations: []string{err.Error()},
+		}
+	}
+
+	var violations []string
+
+	violations = append(violations, inconsistencies(header, blockHeader)...)
+
+	if len(hv.ancestorsTimes) > 0 {
+		medianTimePast := hv.medianTimePast()
+		if !blockHeader.Timestamp.After(medianTimePast) {
+			violations = append(violations, fmt.Sprintf(
+				"timestamp [%v] is not after the median time past [%v]",
+				blockHeader.Timestamp.UTC(),
+				medianTimePast.UTC(),
+			))
+		}
+	}
+
+	if blockHeader.Timestamp.After(now.Add(maxTimeOffset)) {
+		violations = append(violations, fmt.Sprintf(
+			"timestamp [%v] is more than [%v] in the future",
+			blockHeader.Timestamp.UTC(),
+			maxTimeOffset,
+		))
+	}
+
+	if minVersion := hv.minimumVersion(header.Height); blockHeader.Version < minVersion {
+		violations = append(violations, fmt.Sprintf(
+			"version [%v] is below the minimum version [%v] required "+
+				"at this height",
+			blockHeader.Version,
+			minVersion,
+		))
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Header: header, Violations: violations}
+	}
+
+	hv.accept(header, blockHeader)
+
+	return nil
+}
+
+// Inconsistencies returns the fields of the header, i.e. the hash, the
+// previous hash and the merkle root, which do not match its serialized form.
+// A Bitcoin node never returns inconsistent headers unless it is faulty or
+// malicious. An error is returned if the header cannot be deserialized.
+func Inconsistencies(header *Header) ([]string, error) {
+	blockHeader, err := deserializeHeader(header.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return inconsistencies(header, blockHeader), nil
+}
+
+func inconsistencies(header *Header, blockHeader *wire.BlockHeader) []string {
+	var violations []string
+
+	if Digest(blockHeader.BlockHash()) != header.Hash {
+		violations = append(violations, fmt.Sprintf(
+			"hash [%v] does not match the serialized header",
+			header.Hash,
+		))
+	}
+
+	if Digest(blockHeader.PrevBlock) != header.PrevHash {
+		violations = append(violations, fmt.Sprintf(
+			"previous hash [%v] does not match the serialized header",
+			header.PrevHash,
+		))
+	}
+
+	if header.MerkleRoot != (Digest{}) &&
+		Digest(blockHeader.MerkleRoot) != header.MerkleRoot {
+		violations = append(violations, fmt.Sprintf(
+			"merkle root [%v] does not match the serialized header",
+			header.MerkleRoot,
+		))
+	}
+
+	return violations
+}
+
+func (hv *HeaderValidator) accept(header *Header, blockHeader *wire.BlockHeader) {
+	hv.lastHeader = header
+	hv.ancestorsTimes = append(hv.ancestorsTimes, blockHeader.Timestamp)
+
+	if len(hv.ancestorsTimes) > MedianTimeBlocks {
+		hv.ancestorsTimes = hv.ancestorsTimes[1:]
+	}
+}
+
+func (hv *HeaderValidator) medianTimePast() time.Time {
+	timestamps := make([]time.Time, len(hv.ancestorsTimes))
+	copy(timestamps, hv.ancestorsTimes)
+
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i].Before(timestamps[j])
+	})
+
+	return timestamps[len(timestamps)/2]
+}
+
+// minimumVersion returns the minimum block version required at the given
+// height. Blocks with versions lower than the ones introduced by activated
+// soft forks are rejected by full nodes.
+func (hv *HeaderValidator) minimumVersion(height int64) int32 {
+	switch {
+	case height >= int64(hv.params.BIP0065Height):
+		return 4
+	case height >= int64(hv.params.BIP0066Height):
+		return 3
+	case height >= int64(hv.params.BIP0034Height):
+		return 2
+	default:
+		return 1
+	}
+}
+
+func deserializeHeader(raw []byte) (*wire.BlockHeader, error) {
+	if len(raw) != headerSize {
+		return nil, fmt.Errorf(
+			"serialized header must have [%v] bytes; has [%v]",
+			headerSize,
+			len(raw),
+		)
+	}
+
+	blockHeader := &wire.BlockHeader{}
+	if err := blockHeader.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("could not deserialize header: [%v]", err)
+	}
+
+	return blockHeader, nil
+}
@@ -0,0 +1,243 @@
+package btc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var validationGenesisTime = time.Unix(1600000000, 0)
+
+func TestHeaderValidator_Validate(t *testing.T) {
+	now := validationGenesisTime.Add(24 * time.Hour)
+
+	var tests = map[string]struct {
+		height           int64
+		version          int32
+		timestamp        time.Time
+		tamperHash       bool
+		expectViolations int
+	}{
+		"valid header": {
+			height:           400000,
+			version:          4,
+			timestamp:        validationGenesisTime.Add(20 * time.Minute),
+			expectViolations: 0,
+		},
+		"timestamp equal to median time past": {
+			height:           400000,
+			version:          4,
+			timestamp:        validationGenesisTime.Add(5 * time.Minute),
+			expectViolations: 1,
+		},
+		"timestamp too far in the future": {
+			height:           400000,
+			version:          4,
+			timestamp:        now.Add(3 * time.Hour),
+			expectViolations: 1,
+		},
+		"version below BIP-65 requirement": {
+			height:           400000,
+			version:          3,
+			timestamp:        validationGenesisTime.Add(20 * time.Minute),
+			expectViolations: 1,
+		},
+		"version 3 before BIP-65 activation": {
+			height:           370000,
+			version:          3,
+			timestamp:        validationGenesisTime.Add(20 * time.Minute),
+			expectViolations: 0,
+		},
+		"hash not matching serialized header": {
+			height:           400000,
+			version:          4,
+			timestamp:        validationGenesisTime.Add(20 * time.Minute),
+			tamperHash:       true,
+			expectViolations: 1,
+		},
+		"multiple violations": {
+			height:           400000,
+			version:          1,
+			timestamp:        validationGenesisTime,
+			expectViolations: 2,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			validator := NewHeaderValidator(&chaincfg.MainNetParams)
+
+			ancestors := validationAncestors(t, test.height-MedianTimeBlocks)
+			if err := validator.Reset(ancestors); err != nil {
+				t.Fatal(err)
+			}
+
+			header := validationHeader(
+				t,
+				ancestors[len(ancestors)-1],
+				test.version,
+				test.timestamp,
+			)
+			if test.tamperHash {
+				header.Hash[0] ^= 0xff
+			}
+
+			if !validator.Extends(header) {
+				t.Fatal("header should extend the validator context")
+			}
+
+			err := validator.Validate(header, now)
+
+			actualViolations := 0
+			if err != nil {
+				validationErr, ok := err.(*ValidationError)
+				if !ok {
+					t.Fatalf("unexpected error type: [%T]", err)
+				}
+				actualViolations = len(validationErr.Violations)
+			}
+
+			if test.expectViolations != actualViolations {
+				t.Errorf(
+					"unexpected number of violations:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v] (error: %v)\n",
+					test.expectViolations,
+					actualViolations,
+					err,
+				)
+			}
+		})
+	}
+}
+
+func TestHeaderValidator_InvalidSerialization(t *testing.T) {
+	validator := NewHeaderValidator(&chaincfg.MainNetParams)
+
+	err := validator.Validate(
+		&Header{Height: 1, Raw: []byte{0x01, 0x02}},
+		time.Now(),
+	)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected validation error; got: [%v]", err)
+	}
+}
+
+// validationAncestors builds a chain of valid headers starting at the given
+// height. Headers are ten minutes apart apart from the first one.
+func validationAncestors(t *testing.T, startHeight int64) []*Header {
+	ancestors := make([]*Header, 0, MedianTimeBlocks)
+
+	var previous *Header
+	for i := 0; i < MedianTimeBlocks; i++ {
+		timestamp := validationGenesisTime.Add(time.Duration(i) * time.Minute)
+
+		var header *Header
+		if previous == nil {
+			header = serializeValidationHeader(
+				t,
+				&wire.BlockHeader{Version: 4, Timestamp: timestamp},
+				startHeight,
+			)
+		} else {
+			header = validationHeader(t, previous, 4, timestamp)
+		}
+
+		ancestors = append(ancestors, header)
+		previous = header
+	}
+
+	return ancestors
+}
+
+func validationHeader(
+	t *testing.T,
+	previous *Header,
+	version int32,
+	timestamp time.Time,
+) *Header {
+	blockHeader := &wire.BlockHeader{
+		Version:   version,
+		Timestamp: timestamp,
+	}
+	copy(blockHeader.PrevBlock[:], previous.Hash[:])
+
+	return serializeValidationHeader(t, blockHeader, previous.Height+1)
+}
+
+func serializeValidationHeader(
+	t *testing.T,
+	blockHeader *wire.BlockHeader,
+	height int64,
+) *Header {
+	var buffer bytes.Buffer
+	if err := blockHeader.Serialize(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Header{
+		Hash:     Digest(blockHeader.BlockHash()),
+		Height:   height,
+		PrevHash: Digest(blockHeader.PrevBlock),
+		Raw:      buffer.Bytes(),
+	}
+}
+
+func TestInconsistencies(t *testing.T) {
+	ancestors := validationAncestors(t, 400000)
+	header := ancestors[len(ancestors)-1]
+
+	var tests = map[string]struct {
+		tamper           func(header *Header)
+		expectViolations int
+	}{
+		"consistent header": {
+			tamper:           func(header *Header) {},
+			expectViolations: 0,
+		},
+		"previous hash not matching serialized header": {
+			tamper: func(header *Header) {
+				header.PrevHash[0] ^= 0xff
+			},
+			expectViolations: 1,
+		},
+		"merkle root not matching serialized header": {
+			tamper: func(header *Header) {
+				header.MerkleRoot[0] ^= 0xff
+			},
+			expectViolations: 1,
+		},
+		"serialized header not matching hash": {
+			tamper: func(header *Header) {
+				header.Raw[len(header.Raw)-1] ^= 0xff
+			},
+			expectViolations: 1,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			tampered := *header
+			tampered.Raw = append([]byte(nil), header.Raw...)
+			test.tamper(&tampered)
+
+			violations, err := Inconsistencies(&tampered)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.expectViolations != len(violations) {
+				t.Errorf(
+					"unexpected number of violations:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectViolations,
+					violations,
+				)
+			}
+		})
+	}
+}
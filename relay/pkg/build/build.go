@@ -0,0 +1,47 @@
+package build
+
+import (
+	"runtime"
+)
+
+// build.go file contains the build information embedded in the binary at
+// build time using linker flags, e.g.:
+//
+//   go build -ldflags "-X github.com/keep-network/tbtc/relay/pkg/build.Version=v1.0.0"
+//
+// Builds are reproducible if the date is set to the date of the revision and
+// the binary is built with the `-trimpath` flag and an empty build ID.
+
+// Placeholder of build information not set at build time.
+const unknown = "unknown"
+
+var (
+	// Version is the released version of the relay, e.g. `v1.0.0`.
+	Version = unknown
+	// Revision is the git commit the relay has been built from.
+	Revision = unknown
+	// Date is the UTC date and time the relay has been built at.
+	Date = unknown
+	// ReleaseKey is the hex-encoded Ed25519 public key the release is
+	// signed with. It is the key trusted by default when verifying the
+	// binary.
+	ReleaseKey = ""
+)
+
+// Info holds the build information of the relay binary.
+type Info struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+// GetInfo returns the build information of the relay binary.
+func GetInfo() *Info {
+	return &Info{
+		Version:   Version,
+		Revision:  Revision,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+}
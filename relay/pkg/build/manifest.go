@@ -0,0 +1,220 @@
+package build
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// manifest.go file contains the signing of released relay binaries. The
+// release pipeline appends a signed manifest to the binary, binding its build
+// information to the SHA-256 digest of the binary. Executables ignore data
+// appended after their content, so the signed binary runs as before, and the
+// operators can check they run an untampered release before handing it a key
+// controlling funds.
+//
+// The manifest is appended as its JSON encoding followed by its length, as
+// a 4-byte big-endian integer, and the manifest magic.
+
+// manifestMagic marks the end of a binary with an appended manifest.
+const manifestMagic = "TBTCRMF1"
+
+// Maximum length of an appended manifest.
+const maxManifestLength = 64 * 1024
+
+// Manifest describes a signed relay binary.
+type Manifest struct {
+	Version  string `json:"version"`
+	Revision string `json:"revision"`
+	Date     string `json:"date"`
+	// Digest is the hex-encoded SHA-256 digest of the binary without the
+	// manifest.
+	Digest    string `json:"sha256"`
+	PublicKey string `json:"publicKey"`
+	Signature string `json:"signature"`
+}
+
+// SignBinary appends the manifest with the given build information and the
+// digest of the given binary, signed with the given Ed25519 private key. An
+// already appended manifest is replaced.
+func SignBinary(
+	executable []byte,
+	info *Info,
+	privateKey ed25519.PrivateKey,
+) ([]byte, error) {
+	content, _, err := splitManifest(executable)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(content)
+
+	manifest := &Manifest{
+		Version:   info.Version,
+		Revision:  info.Revision,
+		Date:      info.Date,
+		Digest:    hex.EncodeToString(digest[:]),
+		PublicKey: hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+	}
+	manifest.Signature = hex.EncodeToString(
+		ed25519.Sign(privateKey, manifest.signedMessage()),
+	)
+
+	encoded, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode manifest: [%v]", err)
+	}
+
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(encoded)))
+
+	signed := make(
+		[]byte,
+		0,
+		len(content)+len(encoded)+len(length)+len(manifestMagic),
+	)
+	signed = append(signed, content...)
+	signed = append(signed, encoded...)
+	signed = append(signed, length...)
+	signed = append(signed, manifestMagic...)
+
+	return signed, nil
+}
+
+// VerifyBinary checks whether the manifest appended to the given binary has
+// been signed by one of the trusted keys and matches the digest of the
+// binary. The verified manifest is returned.
+func VerifyBinary(executable []byte, trustedKeys []string) (*Manifest, error) {
+	content, manifest, err := splitManifest(executable)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("binary has no signed manifest")
+	}
+
+	if err := manifest.verifySignature(trustedKeys); err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(content)
+	if manifest.Digest != hex.EncodeToString(digest[:]) {
+		return nil, fmt.Errorf(
+			"binary digest [%x] does not match manifest digest [%v]",
+			digest,
+			manifest.Digest,
+		)
+	}
+
+	return manifest, nil
+}
+
+// Matches checks whether the manifest describes a binary with the given
+// build information.
+func (m *Manifest) Matches(info *Info) error {
+	if m.Version != info.Version ||
+		m.Revision != info.Revision ||
+		m.Date != info.Date {
+		return fmt.Errorf(
+			"manifest of version [%v] revision [%v] built at [%v] does not "+
+				"match binary of version [%v] revision [%v] built at [%v]",
+			m.Version,
+			m.Revision,
+			m.Date,
+			info.Version,
+			info.Revision,
+			info.Date,
+		)
+	}
+
+	return nil
+}
+
+func (m *Manifest) verifySignature(trustedKeys []string) error {
+	trusted := false
+	for _, key := range trustedKeys {
+		if key == m.PublicKey {
+			trusted = true
+			break
+		}
+	}
+
+	if !trusted {
+		return fmt.Errorf(
+			"manifest is signed by untrusted key [%v]",
+			m.PublicKey,
+		)
+	}
+
+	publicKey, err := hex.DecodeString(m.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid manifest public key [%v]", m.PublicKey)
+	}
+
+	signature, err := hex.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("could not decode manifest signature: [%v]", err)
+	}
+
+	if !ed25519.Verify(publicKey, m.signedMessage(), signature) {
+		return fmt.Errorf("invalid manifest signature")
+	}
+
+	return nil
+}
+
+// signedMessage returns the hash of the manifest fields other than the
+// signature, which is the message being signed.
+func (m *Manifest) signedMessage() []byte {
+	hash := sha256.New()
+
+	for _, field := range []string{
+		m.Version,
+		m.Revision,
+		m.Date,
+		m.Digest,
+		m.PublicKey,
+	} {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(field)))
+
+		hash.Write(length)
+		hash.Write([]byte(field))
+	}
+
+	return hash.Sum(nil)
+}
+
+// splitManifest splits the given binary into its content and the appended
+// manifest. The manifest is nil if none is appended.
+func splitManifest(executable []byte) ([]byte, *Manifest, error) {
+	trailerLength := 4 + len(manifestMagic)
+
+	if len(executable) < trailerLength ||
+		!bytes.HasSuffix(executable, []byte(manifestMagic)) {
+		return executable, nil, nil
+	}
+
+	lengthOffset := len(executable) - trailerLength
+	length := int(binary.BigEndian.Uint32(executable[lengthOffset:]))
+	if length > maxManifestLength || length > lengthOffset {
+		return nil, nil, fmt.Errorf("invalid manifest length [%v]", length)
+	}
+
+	manifestOffset := lengthOffset - length
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(
+		executable[manifestOffset:lengthOffset],
+		manifest,
+	); err != nil {
+		return nil, nil, fmt.Errorf("could not decode manifest: [%v]", err)
+	}
+
+	return executable[:manifestOffset], manifest, nil
+}
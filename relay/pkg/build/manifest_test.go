@@ -0,0 +1,111 @@
+package build
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSignBinary(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedKeys := []string{hex.EncodeToString(publicKey)}
+
+	info := &Info{
+		Version:  "v1.0.0",
+		Revision: "abcdef",
+		Date:     "2021-01-01T00:00:00Z",
+	}
+
+	executable := []byte("relay binary content")
+
+	signed, err := SignBinary(executable, info, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(signed, executable) {
+		t.Fatal("signed binary should start with the original content")
+	}
+
+	manifest, err := VerifyBinary(signed, trustedKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manifest.Matches(info); err != nil {
+		t.Error(err)
+	}
+
+	if err := manifest.Matches(&Info{Version: "v1.0.1"}); err == nil {
+		t.Error("manifest should not match another version")
+	}
+
+	// Signing again replaces the manifest instead of signing it as part of
+	// the binary.
+	resigned, err := SignBinary(signed, info, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(resigned, signed) {
+		t.Error("signing the signed binary again should replace the manifest")
+	}
+}
+
+func TestVerifyBinary_Tampered(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedKeys := []string{hex.EncodeToString(publicKey)}
+
+	_, untrustedKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &Info{Version: "v1.0.0", Revision: "abcdef", Date: unknown}
+	executable := []byte("relay binary content")
+
+	signed, err := SignBinary(executable, info, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modifiedContent := append([]byte(nil), signed...)
+	modifiedContent[0] ^= 0xff
+
+	modifiedManifest := bytes.Replace(
+		signed,
+		[]byte(`"v1.0.0"`),
+		[]byte(`"v9.0.0"`),
+		1,
+	)
+
+	signedByUntrusted, err := SignBinary(executable, info, untrustedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = map[string][]byte{
+		"unsigned binary":       executable,
+		"modified content":      modifiedContent,
+		"modified manifest":     modifiedManifest,
+		"signed by untrusted":   signedByUntrusted,
+		"truncated manifest":    signed[len(executable)+10:],
+		"manifest without body": []byte("\x00\x00\x00\x10" + manifestMagic),
+	}
+
+	for testName, binary := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if _, err := VerifyBinary(binary, trustedKeys); err == nil {
+				t.Errorf("expected verification error")
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log"
+)
+
+var logger = log.Logger("tbtc-relay-build")
+
+// update.go file contains the optional checker of new relay releases. The
+// checker periodically fetches the latest tagged release and warns once it
+// is newer than the running version.
+
+const (
+	// DefaultReleasesURL is the default URL of the latest relay release in
+	// the format of the GitHub releases API.
+	DefaultReleasesURL = "https://api.github.com/repos/keep-network/tbtc/releases/latest"
+
+	// DefaultUpdateCheckInterval is the default interval between update
+	// checks.
+	DefaultUpdateCheckInterval = 24 * time.Hour
+
+	// Maximum time a single update check can take.
+	updateCheckTimeout = 30 * time.Second
+)
+
+// UpdateCheckConfig holds the configuration of the update checker.
+type UpdateCheckConfig struct {
+	// Enabled determines whether new releases are checked.
+	Enabled bool
+
+	// URL is the URL of the latest release in the format of the GitHub
+	// releases API. If empty, a default value is used.
+	URL string
+
+	// Interval is the interval, in seconds, between update checks. If
+	// zero, a default value is used.
+	Interval int
+}
+
+// UpdateChecker periodically checks whether a newer relay release exists.
+type UpdateChecker struct {
+	url        string
+	httpClient *http.Client
+
+	mutex         sync.RWMutex
+	latestVersion string
+}
+
+// NewUpdateChecker creates a new update checker using the given
+// configuration.
+func NewUpdateChecker(config *UpdateCheckConfig) *UpdateChecker {
+	url := config.URL
+	if url == "" {
+		url = DefaultReleasesURL
+	}
+
+	return &UpdateChecker{
+		url:        url,
+		httpClient: &http.Client{Timeout: updateCheckTimeout},
+	}
+}
+
+// Start runs the checks in the given interval until the context is done.
+// If the interval is not positive, a default value is used.
+func (uc *UpdateChecker) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultUpdateCheckInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := uc.check(ctx); err != nil {
+				logger.Warnf("could not check for relay updates: [%v]", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// UpdateAvailable returns whether the latest known release is newer than
+// the running version.
+func (uc *UpdateChecker) UpdateAvailable() bool {
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+
+	return isNewer(uc.latestVersion, Version)
+}
+
+// LatestVersion returns the version of the latest known release or an
+// empty string if no check succeeded yet.
+func (uc *UpdateChecker) LatestVersion() string {
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+
+	return uc.latestVersion
+}
+
+func (uc *UpdateChecker) check(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, uc.url, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: [%v]", err)
+	}
+
+	response, err := uc.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not fetch latest release: [%v]", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf(
+			"unexpected response status [%v]",
+			response.StatusCode,
+		)
+	}
+
+	release := &struct {
+		TagName string `json:"tag_name"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(release); err != nil {
+		return fmt.Errorf("could not decode latest release: [%v]", err)
+	}
+
+	uc.mutex.Lock()
+	uc.latestVersion = release.TagName
+	uc.mutex.Unlock()
+
+	if isNewer(release.TagName, Version) {
+		logger.Warnf(
+			"newer relay release [%v] is available; running [%v]",
+			release.TagName,
+			Version,
+		)
+	} else {
+		logger.Infof("relay version [%v] is up to date", Version)
+	}
+
+	return nil
+}
+
+// isNewer checks whether the candidate version is newer than the current
+// one. Both versions are expected in the `vMAJOR.MINOR.PATCH` format with
+// an optional pre-release suffix, which is ignored. If any of them cannot
+// be parsed, false is returned.
+func isNewer(candidate string, current string) bool {
+	candidateParts, ok := parseVersion(candidate)
+	if !ok {
+		return false
+	}
+
+	currentParts, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+
+	for i := range candidateParts {
+		if candidateParts[i] != currentParts[i] {
+			return candidateParts[i] > currentParts[i]
+		}
+	}
+
+	return false
+}
+
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+
+	// Tags can be prefixed with the component name, e.g. `relay/v1.0.0`.
+	if index := strings.LastIndex(version, "/"); index >= 0 {
+		version = version[index+1:]
+	}
+
+	version = strings.TrimPrefix(version, "v")
+	if index := strings.IndexAny(version, "-+"); index >= 0 {
+		version = version[:index]
+	}
+
+	fields := strings.Split(version, ".")
+	if len(fields) != len(parts) {
+		return parts, false
+	}
+
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 {
+			return parts, false
+		}
+		parts[i] = number
+	}
+
+	return parts, true
+}
@@ -0,0 +1,95 @@
+package build
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsNewer(t *testing.T) {
+	var tests = map[string]struct {
+		candidate string
+		current   string
+		expected  bool
+	}{
+		"newer patch": {
+			candidate: "v1.0.1",
+			current:   "v1.0.0",
+			expected:  true,
+		},
+		"newer minor than higher patch": {
+			candidate: "v1.2.0",
+			current:   "v1.1.9",
+			expected:  true,
+		},
+		"same version": {
+			candidate: "v1.0.0",
+			current:   "v1.0.0",
+			expected:  false,
+		},
+		"older version": {
+			candidate: "v1.0.0",
+			current:   "v2.0.0",
+			expected:  false,
+		},
+		"prefixed tag": {
+			candidate: "relay/v1.1.0",
+			current:   "v1.0.0-rc.1",
+			expected:  true,
+		},
+		"unknown current version": {
+			candidate: "v1.0.0",
+			current:   unknown,
+			expected:  false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := isNewer(test.candidate, test.current)
+
+			if test.expected != actual {
+				t.Errorf(
+					"unexpected result:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expected,
+					actual,
+				)
+			}
+		})
+	}
+}
+
+func TestUpdateChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"tag_name": "v2.0.0"}`))
+		},
+	))
+	defer server.Close()
+
+	previousVersion := Version
+	Version = "v1.0.0"
+	defer func() { Version = previousVersion }()
+
+	checker := NewUpdateChecker(&UpdateCheckConfig{URL: server.URL})
+	if err := checker.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if checker.LatestVersion() != "v2.0.0" {
+		t.Errorf(
+			"unexpected latest version:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			"v2.0.0",
+			checker.LatestVersion(),
+		)
+	}
+
+	if !checker.UpdateAvailable() {
+		t.Errorf("expected update to be available")
+	}
+}
@@ -0,0 +1,472 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// cache.go file contains a host chain handle wrapper caching the results
+// of frequent reads. Several subsystems, like the relay, the status API and
+// metrics, query the same host chain state, so each result is kept for
+// a short time and concurrent identical reads are coalesced into a single
+// request. Successful transactions submitted through the handle invalidate
+// all cached results as they change the relay contract state.
+//
+// The relay contract state also changes with transactions of other relay
+// operators. By default, the results of relay contract reads expire just
+// like the others. Alternatively, they are cached until the relay contract
+// emits an advance event, which is checked once per cache time, so the
+// number of reads does not depend on how many digests are looked up. If the
+// relay contract does not support event queries, the results fall back to
+// expiring like the others.
+
+// DefaultReadCacheTTL is the default time for which read results are cached.
+const DefaultReadCacheTTL = 5 * time.Second
+
+// cachedRead is a cached result of a single read.
+type cachedRead struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// inflightRead is a read being currently performed. Identical reads wait
+// for its result instead of issuing their own requests.
+type inflightRead struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// cachingHandle is a host chain handle caching the results of reads.
+type cachingHandle struct {
+	Handle
+
+	ttl time.Duration
+
+	mutex sync.Mutex
+	// eventDriven determines whether the results of relay contract reads
+	// are cached until the relay contract emits an advance event.
+	eventDriven bool
+	cache       map[string]*cachedRead
+	inflight    map[string]*inflightRead
+	// generation is increased on each invalidation so the results of reads
+	// started before it are not cached.
+	generation uint64
+}
+
+// WrapReadCache wraps the given host chain handle so the results of frequent
+// reads are cached for the given time and concurrent identical reads are
+// coalesced. If the time is not positive, a default value is used.
+func WrapReadCache(handle Handle, ttl time.Duration) Handle {
+	if ttl <= 0 {
+		ttl = DefaultReadCacheTTL
+	}
+
+	return &cachingHandle{
+		Handle:   handle,
+		ttl:      ttl,
+		cache:    make(map[string]*cachedRead),
+		inflight: make(map[string]*inflightRead),
+	}
+}
+
+// WrapEventReadCache wraps the given host chain handle just like
+// WrapReadCache, except that the results of relay contract reads, like the
+// best known digest or the current epoch, are cached until the relay contract
+// emits an advance event. Events are checked in the given cache time until
+// the context is done. If the events cannot be checked, the cached results
+// are dropped, so they are not kept longer than the cache time. If the relay
+// contract does not support event queries, the handle falls back to the
+// behavior of WrapReadCache.
+func WrapEventReadCache(
+	ctx context.Context,
+	handle Handle,
+	ttl time.Duration,
+	logger logs.Logger,
+) Handle {
+	cachingHandle := WrapReadCache(handle, ttl).(*cachingHandle)
+	cachingHandle.eventDriven = true
+
+	go cachingHandle.watchRelayEvents(
+		ctx,
+		logs.OrDefault(logger, "tbtc-relay-read-cache"),
+	)
+
+	return cachingHandle
+}
+
+// watchRelayEvents invalidates the cached results each time the relay
+// contract emits an advance event.
+func (ch *cachingHandle) watchRelayEvents(
+	ctx context.Context,
+	logger logs.Logger,
+) {
+	ticker := time.NewTicker(ch.ttl)
+	defer ticker.Stop()
+
+	// The cache is empty at start, so only the later events matter.
+	checkedBlock, err := ch.Handle.CurrentBlock(ctx)
+	if err != nil {
+		logger.Warnf("could not get current block: [%v]", err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			checkedBlock = ch.checkRelayEvents(ctx, checkedBlock, logger)
+			if !ch.isEventDriven() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkRelayEvents invalidates the cached results if the relay contract has
+// been advanced after the given checked block. Returns the block up to which
+// the events have been checked.
+func (ch *cachingHandle) checkRelayEvents(
+	ctx context.Context,
+	checkedBlock uint64,
+	logger logs.Logger,
+) uint64 {
+	currentBlock, err := ch.Handle.CurrentBlock(ctx)
+	if err != nil {
+		logger.Warnf("could not get current block: [%v]", err)
+		ch.invalidate()
+		return checkedBlock
+	}
+
+	if currentBlock <= checkedBlock {
+		return checkedBlock
+	}
+
+	// Without a block checked yet, the cache cannot be trusted.
+	if checkedBlock == 0 {
+		ch.invalidate()
+		return currentBlock
+	}
+
+	advanced, err := ch.Handle.RelayAdvanced(ctx, checkedBlock+1, currentBlock)
+	if err == ErrRelayEventsNotSupported {
+		logger.Infof(
+			"relay contract does not support event queries; "+
+				"caching relay contract reads for [%v]",
+			ch.ttl,
+		)
+		ch.stopEventDriven()
+		return checkedBlock
+	} else if err != nil {
+		logger.Warnf("could not check relay events: [%v]", err)
+		ch.invalidate()
+		return checkedBlock
+	}
+
+	if advanced {
+		logger.Debugf(
+			"relay advanced up to block [%v]; invalidating cached reads",
+			currentBlock,
+		)
+		ch.invalidate()
+	}
+
+	return currentBlock
+}
+
+// relayStateTTL returns the time for which the results of relay contract
+// reads are cached. Zero means the results are cached until invalidated.
+func (ch *cachingHandle) relayStateTTL() time.Duration {
+	if ch.isEventDriven() {
+		return 0
+	}
+
+	return ch.ttl
+}
+
+func (ch *cachingHandle) isEventDriven() bool {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	return ch.eventDriven
+}
+
+// stopEventDriven makes the results of relay contract reads expire like the
+// others. The results cached so far would never expire, so they are dropped.
+func (ch *cachingHandle) stopEventDriven() {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	ch.eventDriven = false
+	ch.cache = make(map[string]*cachedRead)
+	ch.generation++
+}
+
+// read returns the cached result of the read with the given key or performs
+// the read using the given function. If an identical read is in progress,
+// its result is awaited instead. The result is cached for the given time or,
+// if it is zero, until invalidated. Errors are never cached.
+func (ch *cachingHandle) read(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	readFn func() (interface{}, error),
+) (interface{}, error) {
+	ch.mutex.Lock()
+
+	if cached, ok := ch.cache[key]; ok && (cached.expiresAt.IsZero() ||
+		time.Now().Before(cached.expiresAt)) {
+		ch.mutex.Unlock()
+		return cached.value, nil
+	}
+
+	if inflight, ok := ch.inflight[key]; ok {
+		ch.mutex.Unlock()
+
+		select {
+		case <-inflight.done:
+			return inflight.value, inflight.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	inflight := &inflightRead{done: make(chan struct{})}
+	ch.inflight[key] = inflight
+	generation := ch.generation
+	ch.mutex.Unlock()
+
+	inflight.value, inflight.err = readFn()
+
+	ch.mutex.Lock()
+	delete(ch.inflight, key)
+	if inflight.err == nil && generation == ch.generation {
+		cached := &cachedRead{value: inflight.value}
+		if ttl > 0 {
+			cached.expiresAt = time.Now().Add(ttl)
+		}
+		ch.cache[key] = cached
+	}
+	ch.mutex.Unlock()
+
+	close(inflight.done)
+
+	return inflight.value, inflight.err
+}
+
+// invalidate drops all cached results.
+func (ch *cachingHandle) invalidate() {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	ch.cache = make(map[string]*cachedRead)
+	ch.generation++
+}
+
+// GetBestKnownDigest returns the best known digest.
+func (ch *cachingHandle) GetBestKnownDigest(
+	ctx context.Context,
+) (btc.Digest, error) {
+	value, err := ch.read(
+		ctx,
+		"bestKnownDigest",
+		ch.relayStateTTL(),
+		func() (interface{}, error) {
+			return ch.Handle.GetBestKnownDigest(ctx)
+		},
+	)
+	if err != nil {
+		return btc.Digest{}, err
+	}
+
+	return value.(btc.Digest), nil
+}
+
+// IsAncestor checks if ancestorDigest is an ancestor of the descendantDigest.
+// The limit parameter determines the number of blocks to check.
+func (ch *cachingHandle) IsAncestor(
+	ctx context.Context,
+	ancestorDigest btc.Digest,
+	descendantDigest btc.Digest,
+	limit *big.Int,
+) (bool, error) {
+	key := fmt.Sprintf(
+		"isAncestor-%v-%v-%v",
+		ancestorDigest,
+		descendantDigest,
+		limit,
+	)
+
+	ttl := ch.relayStateTTL()
+
+	value, err := ch.read(ctx, key, ttl, func() (interface{}, error) {
+		return ch.Handle.IsAncestor(
+			ctx,
+			ancestorDigest,
+			descendantDigest,
+			limit,
+		)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return value.(bool), nil
+}
+
+// FindHeight finds the height of a header by its digest.
+func (ch *cachingHandle) FindHeight(
+	ctx context.Context,
+	digest btc.Digest,
+) (*big.Int, error) {
+	value, err := ch.read(
+		ctx,
+		"findHeight-"+digest.String(),
+		ch.relayStateTTL(),
+		func() (interface{}, error) {
+			return ch.Handle.FindHeight(ctx, digest)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return a copy so callers cannot modify the cached value.
+	return new(big.Int).Set(value.(*big.Int)), nil
+}
+
+// FindAncestor finds the digest of the ancestor of the header with the given
+// digest, the given number of blocks below it.
+func (ch *cachingHandle) FindAncestor(
+	ctx context.Context,
+	digest btc.Digest,
+	offset *big.Int,
+) (btc.Digest, error) {
+	key := fmt.Sprintf("findAncestor-%v-%v", digest, offset)
+
+	value, err := ch.read(
+		ctx,
+		key,
+		ch.relayStateTTL(),
+		func() (interface{}, error) {
+			return ch.Handle.FindAncestor(ctx, digest, offset)
+		},
+	)
+	if err != nil {
+		return btc.Digest{}, err
+	}
+
+	return value.(btc.Digest), nil
+}
+
+// GetCurrentEpoch returns the number of the latest difficulty epoch known
+// by the light relay.
+func (ch *cachingHandle) GetCurrentEpoch(ctx context.Context) (uint64, error) {
+	value, err := ch.read(
+		ctx,
+		"currentEpoch",
+		ch.relayStateTTL(),
+		func() (interface{}, error) {
+			return ch.Handle.GetCurrentEpoch(ctx)
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(uint64), nil
+}
+
+// GetGasPrice returns the gas price currently suggested by the host chain.
+func (ch *cachingHandle) GetGasPrice(ctx context.Context) (*big.Int, error) {
+	value, err := ch.read(ctx, "gasPrice", ch.ttl, func() (interface{}, error) {
+		return ch.Handle.GetGasPrice(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Return a copy so callers cannot modify the cached value.
+	return new(big.Int).Set(value.(*big.Int)), nil
+}
+
+// CurrentBlock returns the number of the current host chain block.
+func (ch *cachingHandle) CurrentBlock(ctx context.Context) (uint64, error) {
+	value, err := ch.read(
+		ctx,
+		"currentBlock",
+		ch.ttl,
+		func() (interface{}, error) {
+			return ch.Handle.CurrentBlock(ctx)
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(uint64), nil
+}
+
+// AddHeaders adds headers to storage after validating and invalidates the
+// cached reads.
+func (ch *cachingHandle) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	defer ch.invalidate()
+
+	return ch.Handle.AddHeaders(ctx, anchorHeader, headers)
+}
+
+// AddHeadersWithRetarget adds headers to storage, performs additional
+// validation of retarget and invalidates the cached reads.
+func (ch *cachingHandle) AddHeadersWithRetarget(
+	ctx context.Context,
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) error {
+	defer ch.invalidate()
+
+	return ch.Handle.AddHeadersWithRetarget(
+		ctx,
+		oldPeriodStartHeader,
+		oldPeriodEndHeader,
+		headers,
+	)
+}
+
+// MarkNewHeaviest gives a new starting point for the relay and invalidates
+// the cached reads.
+func (ch *cachingHandle) MarkNewHeaviest(
+	ctx context.Context,
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) error {
+	defer ch.invalidate()
+
+	return ch.Handle.MarkNewHeaviest(
+		ctx,
+		ancestorDigest,
+		currentBestHeader,
+		newBestHeader,
+		limit,
+	)
+}
+
+// Retarget adds a new difficulty epoch to the light relay and invalidates
+// the cached reads.
+func (ch *cachingHandle) Retarget(ctx context.Context, headers []byte) error {
+	defer ch.invalidate()
+
+	return ch.Handle.Retarget(ctx, headers)
+}
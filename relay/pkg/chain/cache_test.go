@@ -0,0 +1,261 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// countingHandle is a host chain handle counting the reads of the best
+// known digest. Reads block until the release channel is closed.
+type countingHandle struct {
+	Handle
+
+	reads   int32
+	release chan struct{}
+}
+
+func (ch *countingHandle) GetBestKnownDigest(
+	ctx context.Context,
+) (btc.Digest, error) {
+	atomic.AddInt32(&ch.reads, 1)
+	<-ch.release
+	return btc.Digest{1}, nil
+}
+
+func (ch *countingHandle) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	return nil
+}
+
+func TestReadCache(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = map[string]struct {
+		ttl           time.Duration
+		invalidate    bool
+		expectedReads int32
+	}{
+		"cached result": {
+			ttl:           time.Minute,
+			expectedReads: 1,
+		},
+		"expired result": {
+			ttl:           time.Nanosecond,
+			expectedReads: 2,
+		},
+		"result invalidated by transaction": {
+			ttl:           time.Minute,
+			invalidate:    true,
+			expectedReads: 2,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			handle := &countingHandle{release: make(chan struct{})}
+			close(handle.release)
+
+			cachingHandle := WrapReadCache(handle, test.ttl)
+
+			if _, err := cachingHandle.GetBestKnownDigest(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.invalidate {
+				if err := cachingHandle.AddHeaders(ctx, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				time.Sleep(time.Millisecond)
+			}
+
+			if _, err := cachingHandle.GetBestKnownDigest(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if reads := atomic.LoadInt32(&handle.reads); reads != test.expectedReads {
+				t.Errorf(
+					"unexpected number of reads:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedReads,
+					reads,
+				)
+			}
+		})
+	}
+}
+
+func TestReadCache_CoalescesConcurrentReads(t *testing.T) {
+	ctx := context.Background()
+
+	handle := &countingHandle{release: make(chan struct{})}
+	cachingHandle := WrapReadCache(handle, time.Minute)
+
+	readers := 10
+
+	wg := sync.WaitGroup{}
+	wg.Add(readers)
+
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+
+			digest, err := cachingHandle.GetBestKnownDigest(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			if digest != (btc.Digest{1}) {
+				t.Errorf("unexpected digest [%v]", digest)
+			}
+		}()
+	}
+
+	// Give the readers some time to issue their requests.
+	time.Sleep(100 * time.Millisecond)
+	close(handle.release)
+
+	wg.Wait()
+
+	if reads := atomic.LoadInt32(&handle.reads); reads != 1 {
+		t.Errorf(
+			"unexpected number of reads:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			1,
+			reads,
+		)
+	}
+}
+
+// eventHandle is a counting host chain handle reporting the configured
+// relay events.
+type eventHandle struct {
+	countingHandle
+
+	currentBlock uint64
+	advanced     bool
+	eventsErr    error
+}
+
+func (eh *eventHandle) CurrentBlock(ctx context.Context) (uint64, error) {
+	return eh.currentBlock, nil
+}
+
+func (eh *eventHandle) RelayAdvanced(
+	ctx context.Context,
+	fromBlock uint64,
+	toBlock uint64,
+) (bool, error) {
+	return eh.advanced, eh.eventsErr
+}
+
+func TestEventReadCache(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = map[string]struct {
+		advanced            bool
+		eventsErr           error
+		expectedReads       int32
+		expectedEventDriven bool
+	}{
+		"relay not advanced": {
+			expectedReads:       1,
+			expectedEventDriven: true,
+		},
+		"relay advanced": {
+			advanced:            true,
+			expectedReads:       2,
+			expectedEventDriven: true,
+		},
+		"events not available": {
+			eventsErr:           fmt.Errorf("connection refused"),
+			expectedReads:       2,
+			expectedEventDriven: true,
+		},
+		"events not supported": {
+			eventsErr:           ErrRelayEventsNotSupported,
+			expectedReads:       2,
+			expectedEventDriven: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			handle := &eventHandle{
+				countingHandle: countingHandle{release: make(chan struct{})},
+				currentBlock:   11,
+				advanced:       test.advanced,
+				eventsErr:      test.eventsErr,
+			}
+			close(handle.release)
+
+			// The cache time is short, but relay contract reads do not
+			// expire in the event-driven mode.
+			readCache := WrapReadCache(handle, time.Nanosecond)
+			cachingHandle := readCache.(*cachingHandle)
+			cachingHandle.eventDriven = true
+
+			if _, err := cachingHandle.GetBestKnownDigest(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			time.Sleep(time.Millisecond)
+
+			checkedBlock := cachingHandle.checkRelayEvents(
+				ctx,
+				10,
+				logs.OrDefault(nil, "tbtc-relay-read-cache-test"),
+			)
+
+			if _, err := cachingHandle.GetBestKnownDigest(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if reads := atomic.LoadInt32(&handle.reads); reads != test.expectedReads {
+				t.Errorf(
+					"unexpected number of reads:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedReads,
+					reads,
+				)
+			}
+
+			if cachingHandle.isEventDriven() != test.expectedEventDriven {
+				t.Errorf(
+					"unexpected event-driven mode:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedEventDriven,
+					cachingHandle.isEventDriven(),
+				)
+			}
+
+			expectedCheckedBlock := uint64(11)
+			if test.eventsErr != nil {
+				expectedCheckedBlock = 10
+			}
+
+			if checkedBlock != expectedCheckedBlock {
+				t.Errorf(
+					"unexpected checked block:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedCheckedBlock,
+					checkedBlock,
+				)
+			}
+		})
+	}
+}
@@ -1,37 +1,375 @@
 package chain
 
 import (
+	"context"
+	"errors"
 	"math/big"
+	"time"
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 )
 
-// Handle represents a handle to a host chain.
+// Handle represents a handle to a host chain. Calls made through the handle
+// are abandoned once the passed context is done.
 type Handle interface {
-	Relay
+	Reader
+	Writer
+}
+
+// Reader is an interface that provides ability to read the host chain state.
+// None of its methods submits transactions.
+type Reader interface {
+	RelayReader
+	LightRelayReader
+	GasOracle
+	BlockCounter
+	RelayEvents
+	RelayRewardsReader
+	DepositReader
+	PauseReader
+}
+
+// Writer is an interface that provides ability to submit transactions to the
+// host chain. Cross-cutting behaviors of the submissions are added by
+// wrapping the writer with middlewares, see WrapWriter.
+type Writer interface {
+	RelayWriter
+	LightRelayWriter
+	RelayRewardsWriter
+	DepositWriter
+	TransactionManager
+}
+
+// BlockCounter is an interface that provides information about blocks of the
+// host chain.
+type BlockCounter interface {
+	// CurrentBlock returns the number of the current host chain block.
+	CurrentBlock(ctx context.Context) (uint64, error)
+}
+
+// RelayEvents is an interface that provides information about transactions
+// which advanced the relay contract.
+type RelayEvents interface {
+	// PastRelayAdvances returns advances of the relay contract made within
+	// the given range of host chain blocks, both inclusive.
+	PastRelayAdvances(
+		ctx context.Context,
+		fromBlock uint64,
+		toBlock uint64,
+	) ([]*RelayAdvance, error)
+
+	// RelayAdvanced checks whether the relay contract has been advanced
+	// within the given range of host chain blocks, both inclusive. Unlike
+	// PastRelayAdvances, it does not resolve the advancing transactions.
+	// ErrRelayEventsNotSupported is returned if the relay contract does not
+	// support event queries.
+	RelayAdvanced(
+		ctx context.Context,
+		fromBlock uint64,
+		toBlock uint64,
+	) (bool, error)
+
+	// OperatorAddress returns the address of the account submitting relay
+	// transactions. An empty string is returned in the watch-only mode.
+	OperatorAddress() string
+}
+
+// RelayAdvance represents a transaction which advanced the relay contract,
+// i.e. added new headers or marked a new heaviest header.
+type RelayAdvance struct {
+	BlockNumber     uint64
+	Timestamp       time.Time
+	TransactionHash string
+	Submitter       string
+	// Method is the name of the called relay contract method. It is empty
+	// if the method is not known, e.g. if the relay contract was called
+	// through another contract.
+	Method string
+	// GasUsed is the amount of gas used by the transaction.
+	GasUsed uint64
+	// HeadersCount is the number of headers submitted by the transaction.
+	// It is zero if the transaction did not add any headers, e.g. if it
+	// only marked a new heaviest header.
+	HeadersCount int
+	// Fee is the fee paid for the transaction, expressed in the smallest
+	// unit of the host chain currency. It is nil if the fee is not known.
+	Fee *big.Int
+}
+
+// ErrRelayEventsNotSupported is returned by the relay event queries if the
+// relay contract does not support them.
+var ErrRelayEventsNotSupported = errors.New(
+	"relay contract does not support event queries",
+)
+
+// ErrRewardsNotSupported is returned by the relay rewards methods if the
+// relay contract does not pay rewards for header submissions.
+var ErrRewardsNotSupported = errors.New(
+	"relay contract does not pay rewards for header submissions",
+)
+
+// RelayRewards is an interface that provides ability to claim rewards paid
+// by the relay contract for header submissions.
+type RelayRewards interface {
+	RelayRewardsReader
+	RelayRewardsWriter
+}
+
+// RelayRewardsReader is an interface that provides information about the
+// rewards paid by the relay contract.
+type RelayRewardsReader interface {
+	// PendingRewards returns the rewards accrued by the operator and not
+	// claimed yet, expressed in the smallest unit of the host chain
+	// currency.
+	PendingRewards(ctx context.Context) (*big.Int, error)
+}
+
+// RelayRewardsWriter is an interface that provides ability to claim the
+// rewards paid by the relay contract.
+type RelayRewardsWriter interface {
+	// ClaimRewards submits a transaction claiming all rewards accrued by
+	// the operator and returns once the transaction is mined.
+	ClaimRewards(ctx context.Context) error
+}
+
+// Deposit is an interface that provides ability to interact with the tBTC
+// Deposit contracts.
+type Deposit interface {
+	DepositReader
+	DepositWriter
+}
+
+// DepositReader is an interface that provides information about the tBTC
+// Deposit contracts.
+type DepositReader interface {
+	// GetDepositState returns the state of the Deposit contract at the
+	// given address.
+	GetDepositState(
+		ctx context.Context,
+		depositAddress string,
+	) (DepositState, error)
+
+	// GetRedemptionRequest returns the latest redemption request of the
+	// Deposit contract at the given address made since the given host chain
+	// block. Nil is returned if there was no such request.
+	GetRedemptionRequest(
+		ctx context.Context,
+		depositAddress string,
+		fromBlock uint64,
+	) (*RedemptionRequest, error)
+
+	// PastDepositEvents returns the events emitted by the tBTC system on
+	// behalf of all deposits within the given range of host chain blocks,
+	// both inclusive.
+	PastDepositEvents(
+		ctx context.Context,
+		fromBlock uint64,
+		toBlock uint64,
+	) ([]*DepositEvent, error)
+}
+
+// DepositWriter is an interface that provides ability to submit proofs of
+// Bitcoin transactions to the tBTC Deposit contracts.
+type DepositWriter interface {
+	// ProvideFundingProof submits the proof of the funding transaction of
+	// the Deposit contract at the given address. The funding output index
+	// is the index of the transaction output paying the deposit.
+	ProvideFundingProof(
+		ctx context.Context,
+		depositAddress string,
+		proof *TransactionProof,
+		fundingOutputIndex uint8,
+	) error
+
+	// ProvideRedemptionProof submits the proof of the redemption
+	// transaction of the Deposit contract at the given address.
+	ProvideRedemptionProof(
+		ctx context.Context,
+		depositAddress string,
+		proof *TransactionProof,
+	) error
+}
+
+// DepositState is the state of a tBTC Deposit contract.
+type DepositState uint8
+
+// States of the tBTC Deposit contracts relevant for the proof submissions.
+const (
+	DepositAwaitingFundingProof    DepositState = 2
+	DepositActive                  DepositState = 4
+	DepositAwaitingWithdrawalProof DepositState = 6
+	DepositRedeemed                DepositState = 7
+)
+
+// RedemptionRequest is a request to redeem a tBTC deposit.
+type RedemptionRequest struct {
+	// Outpoint is the deposit output spent by the redemption transaction.
+	Outpoint btc.Outpoint
+	// RedeemerOutputScript is the output script the redeemed bitcoins are
+	// paid to.
+	RedeemerOutputScript []byte
+	// BlockNumber is the number of the host chain block including the
+	// request.
+	BlockNumber uint64
+}
+
+// DepositEventType is the type of an event emitted by the tBTC system on
+// behalf of a deposit.
+type DepositEventType int
+
+// Types of the deposit events relevant for the proof submissions.
+const (
+	// DepositEventFundingAwaited is emitted once the signers of a deposit
+	// registered their public key and the deposit awaits the funding proof.
+	DepositEventFundingAwaited DepositEventType = iota
+	// DepositEventFunded is emitted once the funding proof of a deposit has
+	// been accepted.
+	DepositEventFunded
+	// DepositEventRedemptionRequested is emitted once the redemption of
+	// a deposit has been requested and the deposit awaits the redemption
+	// proof.
+	DepositEventRedemptionRequested
+	// DepositEventRedeemed is emitted once the redemption proof of a deposit
+	// has been accepted.
+	DepositEventRedeemed
+)
+
+// DepositEvent is an event emitted by the tBTC system on behalf of a deposit.
+type DepositEvent struct {
+	Type           DepositEventType
+	DepositAddress string
+	BlockNumber    uint64
+}
+
+// TransactionProof is the SPV proof of a Bitcoin transaction accepted by the
+// tBTC Deposit contracts.
+type TransactionProof struct {
+	// TxVersion is the 4-byte little-endian transaction version.
+	TxVersion []byte
+	// TxInputVector is the vector of transaction inputs.
+	TxInputVector []byte
+	// TxOutputVector is the vector of transaction outputs.
+	TxOutputVector []byte
+	// TxLocktime is the 4-byte little-endian transaction lock time.
+	TxLocktime []byte
+	// MerkleProof is the merkle proof of transaction inclusion in a block.
+	MerkleProof []byte
+	// TxIndexInBlock is the position of the transaction in the block.
+	TxIndexInBlock uint64
+	// BitcoinHeaders are the tightly-packed headers of the block including
+	// the transaction and of the blocks confirming it.
+	BitcoinHeaders []byte
+}
+
+// TransactionManager is an interface that provides ability to manage
+// transactions submitted to the host chain.
+type TransactionManager interface {
+	// CancelPendingTransactions cancels all transactions submitted by the
+	// operator which are not mined yet. Each of them is replaced with
+	// a zero-value transaction sent by the operator to itself at the same
+	// nonce and with a higher fee. It returns the number of cancelled
+	// transactions.
+	CancelPendingTransactions(ctx context.Context) (int, error)
+
+	// PendingTransactions returns the number of transactions submitted by
+	// the operator which are not mined yet.
+	PendingTransactions(ctx context.Context) (int, error)
+}
+
+// Names of the contracts whose emergency pause is respected by the relay.
+const (
+	// ContractRelay is the relay contract headers are submitted to.
+	ContractRelay = "Relay"
+	// ContractTBTCSystem is the tBTC system contract governing the Deposit
+	// contracts proofs are submitted to.
+	ContractTBTCSystem = "TBTCSystem"
+)
+
+// PauseReader is an interface that provides information about the emergency
+// pause of the contracts the relay submits transactions to.
+type PauseReader interface {
+	// PausedContracts returns the names of the currently paused contracts,
+	// see the Contract* constants. Contracts which cannot be paused are
+	// never returned.
+	PausedContracts(ctx context.Context) ([]string, error)
+}
+
+// GasOracle is an interface that provides information about the current
+// transaction fees on the host chain.
+type GasOracle interface {
+	// GetGasPrice returns the gas price currently suggested by the host
+	// chain, expressed in the smallest unit of the host chain currency.
+	GetGasPrice(ctx context.Context) (*big.Int, error)
 }
 
 // Relay is an interface that provides ability to interact with Relay contract.
 type Relay interface {
+	RelayReader
+	RelayWriter
+}
+
+// RelayReader is an interface that provides ability to read the Relay
+// contract state.
+type RelayReader interface {
 	// GetBestKnownDigest returns the best known digest.
-	GetBestKnownDigest() (btc.Digest, error)
+	GetBestKnownDigest(ctx context.Context) (btc.Digest, error)
 
 	// IsAncestor checks if ancestorDigest is an ancestor of the
 	// descendantDigest. The limit parameter determines the number of blocks
 	// to check.
 	IsAncestor(
+		ctx context.Context,
 		ancestorDigest btc.Digest,
 		descendantDigest btc.Digest,
 		limit *big.Int,
 	) (bool, error)
 
 	// FindHeight finds the height of a header by its digest.
-	FindHeight(digest btc.Digest) (*big.Int, error)
+	FindHeight(ctx context.Context, digest btc.Digest) (*big.Int, error)
+
+	// FindAncestor finds the digest of the ancestor of the header with the
+	// given digest, the given number of blocks below it. Together with
+	// FindHeight and IsAncestor, it validates header relationships against
+	// the relay contract state rather than the local view of the relay.
+	FindAncestor(
+		ctx context.Context,
+		digest btc.Digest,
+		offset *big.Int,
+	) (btc.Digest, error)
+
+	// FindHeightAtBlock finds the height of a header by its digest using
+	// the relay contract state as of the given host chain block.
+	FindHeightAtBlock(
+		ctx context.Context,
+		digest btc.Digest,
+		blockNumber uint64,
+	) (*big.Int, error)
+
+	// MarkNewHeaviestPreflight performs a preflight call of the
+	// MarkNewHeaviest method to check whether its execution will
+	// succeed. If the preflight call was successful, `true` is returned.
+	// In case the preflight returns an error, `false` is returned.
+	MarkNewHeaviestPreflight(
+		ctx context.Context,
+		ancestorDigest btc.Digest,
+		currentBestHeader []byte,
+		newBestHeader []byte,
+		limit *big.Int,
+	) bool
+
+	// MaxHeaderGap returns the maximum number of blocks between consecutive
+	// headers accepted by the relay contract. It is one for relay contracts
+	// accepting only contiguous headers.
+	MaxHeaderGap(ctx context.Context) (uint64, error)
+}
 
+// RelayWriter is an interface that provides ability to submit transactions to
+// the Relay contract.
+type RelayWriter interface {
 	// AddHeaders adds headers to storage after validating. The anchorHeader
 	// parameter is the header immediately preceding the new chain. Headers
 	// parameter should be a tightly-packed list of 80-byte Bitcoin headers.
-	AddHeaders(anchorHeader []byte, headers []byte) error
+	AddHeaders(ctx context.Context, anchorHeader []byte, headers []byte) error
 
 	// AddHeadersWithRetarget adds headers to storage, performs additional
 	// validation of retarget. The oldPeriodStartHeader is the first header in
@@ -39,6 +377,7 @@ type Relay interface {
 	// Headers parameter should be a tightly-packed list of 80-byte
 	// Bitcoin headers.
 	AddHeadersWithRetarget(
+		ctx context.Context,
 		oldPeriodStartHeader []byte,
 		oldPeriodEndHeader []byte,
 		headers []byte,
@@ -50,20 +389,40 @@ type Relay interface {
 	// while the newBestHeader param should be the header to mark as new best.
 	// Limit parameter limits the amount of traversal of the chain.
 	MarkNewHeaviest(
+		ctx context.Context,
 		ancestorDigest btc.Digest,
 		currentBestHeader []byte,
 		newBestHeader []byte,
 		limit *big.Int,
 	) error
+}
 
-	// MarkNewHeaviestPreflight performs a preflight call of the
-	// MarkNewHeaviest method to check whether its execution will
-	// succeed. If the preflight call was successful, `true` is returned.
-	// In case the preflight returns an error, `false` is returned.
-	MarkNewHeaviestPreflight(
-		ancestorDigest btc.Digest,
-		currentBestHeader []byte,
-		newBestHeader []byte,
-		limit *big.Int,
-	) bool
+// LightRelay is an interface that provides ability to interact with the
+// tBTC v2 LightRelay contract. This contract does not store all headers
+// but only needs a proof at each difficulty retarget.
+type LightRelay interface {
+	LightRelayReader
+	LightRelayWriter
+}
+
+// LightRelayReader is an interface that provides ability to read the
+// LightRelay contract state.
+type LightRelayReader interface {
+	// GetProofLength returns the number of headers required on each side
+	// of the difficulty epoch boundary to prove a retarget.
+	GetProofLength(ctx context.Context) (uint64, error)
+
+	// GetCurrentEpoch returns the number of the latest difficulty epoch
+	// known by the light relay.
+	GetCurrentEpoch(ctx context.Context) (uint64, error)
+}
+
+// LightRelayWriter is an interface that provides ability to submit
+// transactions to the LightRelay contract.
+type LightRelayWriter interface {
+	// Retarget adds a new difficulty epoch to the light relay. Headers
+	// parameter should be a tightly-packed list of 80-byte Bitcoin headers
+	// consisting of proof length headers from the end of the current epoch
+	// and the same number of headers from the beginning of the new epoch.
+	Retarget(ctx context.Context, headers []byte) error
 }
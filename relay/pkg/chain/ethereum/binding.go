@@ -0,0 +1,310 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// RelayVersion identifies a known version of the relay contract ABI.
+type RelayVersion string
+
+const (
+	// RelayVersionAuto makes the relay version to be detected by inspecting
+	// the code deployed at the relay contract address.
+	RelayVersionAuto RelayVersion = "auto"
+)
+
+// relayBinding is an abstraction over a specific version of the relay
+// contract ABI. It allows the host chain handle to work with different
+// relay contract variants without changing the code using the handle.
+// Not all versions support all operations; unsupported operations return
+// an error.
+type relayBinding interface {
+	// GetBestKnownDigest returns the best known digest.
+	GetBestKnownDigest() (btc.Digest, error)
+
+	// IsAncestor checks if ancestorDigest is an ancestor of the
+	// descendantDigest within the given limit of blocks.
+	IsAncestor(
+		ancestorDigest btc.Digest,
+		descendantDigest btc.Digest,
+		limit *big.Int,
+	) (bool, error)
+
+	// FindHeight finds the height of a header by its digest. If blockNumber
+	// is nil, the latest host chain state is used.
+	FindHeight(digest btc.Digest, blockNumber *big.Int) (*big.Int, error)
+
+	// FindAncestor finds the digest of the ancestor of the header with the
+	// given digest, the given number of blocks below it.
+	FindAncestor(digest btc.Digest, offset *big.Int) (btc.Digest, error)
+
+	// AddHeaders submits an addHeaders transaction and returns its hash.
+	AddHeaders(anchorHeader []byte, headers []byte) (common.Hash, error)
+
+	// CallAddHeaders performs a call of the addHeaders function without
+	// submitting a transaction.
+	CallAddHeaders(anchorHeader []byte, headers []byte) (bool, error)
+
+	// AddHeadersWithRetarget submits an addHeadersWithRetarget transaction
+	// and returns its hash.
+	AddHeadersWithRetarget(
+		oldPeriodStartHeader []byte,
+		oldPeriodEndHeader []byte,
+		headers []byte,
+	) (common.Hash, error)
+
+	// CallAddHeadersWithRetarget performs a call of the
+	// addHeadersWithRetarget function without submitting a transaction.
+	CallAddHeadersWithRetarget(
+		oldPeriodStartHeader []byte,
+		oldPeriodEndHeader []byte,
+		headers []byte,
+	) (bool, error)
+
+	// MarkNewHeaviest submits a markNewHeaviest transaction and returns
+	// its hash.
+	MarkNewHeaviest(
+		ancestorDigest btc.Digest,
+		currentBestHeader []byte,
+		newBestHeader []byte,
+		limit *big.Int,
+	) (common.Hash, error)
+
+	// CallMarkNewHeaviest performs a call of the markNewHeaviest function
+	// without submitting a transaction.
+	CallMarkNewHeaviest(
+		ancestorDigest btc.Digest,
+		currentBestHeader []byte,
+		newBestHeader []byte,
+		limit *big.Int,
+	) (bool, error)
+
+	// GetProofLength returns the number of headers required on each side
+	// of the difficulty epoch boundary to prove a retarget.
+	GetProofLength() (uint64, error)
+
+	// GetCurrentEpoch returns the number of the latest difficulty epoch
+	// known by the relay.
+	GetCurrentEpoch() (uint64, error)
+
+	// Retarget submits a retarget transaction and returns its hash.
+	Retarget(headers []byte) (common.Hash, error)
+
+	// CallRetarget performs a call of the retarget function without
+	// submitting a transaction.
+	CallRetarget(headers []byte) error
+
+	// PastAdvanceLogs returns logs of all events emitted when the relay
+	// was advanced within the given range of blocks, both inclusive.
+	PastAdvanceLogs(fromBlock uint64, toBlock uint64) ([]types.Log, error)
+}
+
+// errUnsupportedByVersion is returned by relay bindings for operations not
+// supported by the given relay version.
+func errUnsupportedByVersion(operation string, version RelayVersion) error {
+	return fmt.Errorf(
+		"operation [%v] is not supported by relay version [%v]",
+		operation,
+		version,
+	)
+}
+
+// boundContractOptions returns the call and transaction options of bound
+// contracts used by bindings for which there are no generated contract
+// bindings. Transactions are signed with the account key.
+func boundContractOptions(
+	dependencies *bindingDependencies,
+) (*bind.CallOpts, *bind.TransactOpts) {
+	key := dependencies.accountKey.PrivateKey
+	keyAddress := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.NewEIP155Signer(dependencies.chainID)
+
+	callerOptions := &bind.CallOpts{
+		From: keyAddress,
+	}
+
+	transactorOptions := &bind.TransactOpts{
+		From: keyAddress,
+		Signer: func(
+			_ types.Signer,
+			address common.Address,
+			tx *types.Transaction,
+		) (*types.Transaction, error) {
+			if address != keyAddress {
+				return nil, fmt.Errorf("not authorized to sign this account")
+			}
+
+			signature, err := crypto.Sign(signer.Hash(tx).Bytes(), key)
+			if err != nil {
+				return nil, err
+			}
+
+			return tx.WithSignature(signer, signature)
+		},
+	}
+
+	return callerOptions, transactorOptions
+}
+
+// relayBindingFactory creates a relay binding for the given contract address.
+type relayBindingFactory func(
+	address common.Address,
+	dependencies *bindingDependencies,
+) (relayBinding, error)
+
+// knownRelayVersion describes a relay contract version supported by the
+// host chain handle.
+type knownRelayVersion struct {
+	version RelayVersion
+	// abi is the JSON ABI of the version, used to determine the function
+	// selectors the deployed code must contain to be recognized as this
+	// version.
+	abi string
+	// requiredMethods are the names of ABI methods used by the binding.
+	requiredMethods []string
+	factory         relayBindingFactory
+}
+
+// knownRelayVersions lists all supported relay versions in the order of
+// preference used during detection.
+var knownRelayVersions []*knownRelayVersion
+
+func registerRelayVersion(version *knownRelayVersion) {
+	knownRelayVersions = append(knownRelayVersions, version)
+}
+
+func findRelayVersion(version RelayVersion) (*knownRelayVersion, error) {
+	for _, known := range knownRelayVersions {
+		if known.version == version {
+			return known, nil
+		}
+	}
+
+	names := make([]string, len(knownRelayVersions))
+	for i, known := range knownRelayVersions {
+		names[i] = string(known.version)
+	}
+
+	return nil, fmt.Errorf(
+		"unknown relay version [%v]; supported versions: [%v]",
+		version,
+		strings.Join(names, ", "),
+	)
+}
+
+// matchesCode checks whether the deployed code contains selectors of all
+// methods required by the version. Solidity dispatchers embed selectors
+// of all external functions as PUSH4 operands so their presence is a good
+// indicator of the implemented ABI.
+func (krv *knownRelayVersion) matchesCode(code []byte) (bool, error) {
+	return codeImplements(code, krv.abi, krv.requiredMethods)
+}
+
+// codeImplements checks whether the deployed code contains selectors of all
+// the given methods of the given JSON ABI.
+func codeImplements(
+	code []byte,
+	abi string,
+	methods []string,
+) (bool, error) {
+	parsed, err := hostchainabi.JSON(strings.NewReader(abi))
+	if err != nil {
+		return false, fmt.Errorf("could not parse ABI: [%v]", err)
+	}
+
+	for _, name := range methods {
+		method, ok := parsed.Methods[name]
+		if !ok {
+			return false, fmt.Errorf("ABI has no method [%v]", name)
+		}
+
+		if !bytes.Contains(code, method.ID()) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// resolveRelayVersion returns the relay version to use. If the configured
+// version is empty or auto, the version is detected using the code deployed
+// at the relay contract address. Otherwise, the configured version is used
+// and a warning is logged if the deployed code does not seem to match it.
+func resolveRelayVersion(
+	configured RelayVersion,
+	address common.Address,
+	codeReader func(ctx context.Context, address common.Address) ([]byte, error),
+	logger logs.Logger,
+) (*knownRelayVersion, error) {
+	code, err := codeReader(context.Background(), address)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get code of relay contract [%v]: [%v]",
+			address.Hex(),
+			err,
+		)
+	}
+
+	if len(code) == 0 {
+		return nil, fmt.Errorf(
+			"no code deployed at relay contract address [%v]",
+			address.Hex(),
+		)
+	}
+
+	if configured != "" && configured != RelayVersionAuto {
+		version, err := findRelayVersion(configured)
+		if err != nil {
+			return nil, err
+		}
+
+		matches, err := version.matchesCode(code)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matches {
+			logger.Warnf(
+				"code deployed at [%v] does not seem to implement "+
+					"relay version [%v]",
+				address.Hex(),
+				version.version,
+			)
+		}
+
+		return version, nil
+	}
+
+	for _, version := range knownRelayVersions {
+		matches, err := version.matchesCode(code)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not check relay version [%v]: [%v]",
+				version.version,
+				err,
+			)
+		}
+
+		if matches {
+			logger.Infof("detected relay version [%v]", version.version)
+			return version, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"code deployed at [%v] does not match any known relay version",
+		address.Hex(),
+	)
+}
@@ -0,0 +1,100 @@
+package ethereum
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum/gen/abi"
+)
+
+func TestResolveRelayVersion(t *testing.T) {
+	parsed, err := hostchainabi.JSON(strings.NewReader(abi.RelayABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate the deployed code by concatenating selectors of all methods
+	// with some filler bytes in between.
+	summaCode := make([]byte, 0)
+	for _, method := range parsed.Methods {
+		summaCode = append(summaCode, 0x63) // PUSH4
+		summaCode = append(summaCode, method.ID()...)
+		summaCode = append(summaCode, 0x14, 0x61)
+	}
+
+	var tests = map[string]struct {
+		configured      RelayVersion
+		code            []byte
+		expectedVersion RelayVersion
+		expectError     bool
+	}{
+		"auto detection of summa relay": {
+			configured:      RelayVersionAuto,
+			code:            summaCode,
+			expectedVersion: RelayVersionSummaV1,
+		},
+		"empty version means auto detection": {
+			configured:      "",
+			code:            summaCode,
+			expectedVersion: RelayVersionSummaV1,
+		},
+		"explicit version": {
+			configured:      RelayVersionSummaV1,
+			code:            summaCode,
+			expectedVersion: RelayVersionSummaV1,
+		},
+		"unknown contract": {
+			configured:  RelayVersionAuto,
+			code:        []byte{0x60, 0x80, 0x60, 0x40},
+			expectError: true,
+		},
+		"no code": {
+			configured:  RelayVersionAuto,
+			code:        []byte{},
+			expectError: true,
+		},
+		"unknown explicit version": {
+			configured:  "unknown",
+			code:        summaCode,
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			version, err := resolveRelayVersion(
+				test.configured,
+				common.Address{},
+				func(context.Context, common.Address) ([]byte, error) {
+					return test.code, nil
+				},
+				log.Logger(loggerName),
+			)
+
+			if test.expectError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.expectedVersion != version.version {
+				t.Errorf(
+					"unexpected version:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedVersion,
+					version.version,
+				)
+			}
+		})
+	}
+}
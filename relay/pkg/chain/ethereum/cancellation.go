@@ -0,0 +1,245 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+)
+
+// cancellation.go file contains the logic which cancels transactions
+// submitted by the operator which could not get mined. All transactions sent
+// through the client are tracked by their nonces, including resubmissions
+// with a higher gas price performed by the mining waiter. A transaction is
+// cancelled by replacing it with a zero-value transaction sent by the
+// operator to itself at the same nonce and with a higher gas price. Once the
+// cancellation gets mined, the nonce is consumed and the transactions
+// submitted after it can get mined as well.
+
+// cancellationGasLimit is the gas limit of the zero-value transaction
+// replacing a cancelled transaction. It equals the intrinsic gas of a plain
+// value transfer.
+const cancellationGasLimit = 21000
+
+// submissionTrackingClient is a client wrapper which records all transactions
+// successfully sent to the node so the ones not mined yet can be cancelled.
+type submissionTrackingClient struct {
+	ethutil.EthereumClient
+
+	mutex       sync.Mutex
+	submissions map[uint64][]*types.Transaction
+}
+
+// wrapSubmissionTracking wraps the given client so all transactions it sends
+// are tracked by their nonces.
+func wrapSubmissionTracking(
+	client ethutil.EthereumClient,
+) *submissionTrackingClient {
+	return &submissionTrackingClient{
+		EthereumClient: client,
+		submissions:    make(map[uint64][]*types.Transaction),
+	}
+}
+
+func (stc *submissionTrackingClient) SendTransaction(
+	ctx context.Context,
+	tx *types.Transaction,
+) error {
+	if err := stc.EthereumClient.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	stc.mutex.Lock()
+	defer stc.mutex.Unlock()
+
+	stc.submissions[tx.Nonce()] = append(stc.submissions[tx.Nonce()], tx)
+
+	return nil
+}
+
+// pendingSubmissions returns the latest transaction sent at each tracked
+// nonce, ordered by nonces, for which none of the transactions sent at that
+// nonce has been mined yet. Nonces whose transaction has been mined are
+// no longer tracked.
+func (stc *submissionTrackingClient) pendingSubmissions(
+	ctx context.Context,
+) ([]*types.Transaction, error) {
+	stc.mutex.Lock()
+	defer stc.mutex.Unlock()
+
+	nonces := make([]uint64, 0, len(stc.submissions))
+	for nonce := range stc.submissions {
+		nonces = append(nonces, nonce)
+	}
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+
+	pending := make([]*types.Transaction, 0)
+
+	for _, nonce := range nonces {
+		transactions := stc.submissions[nonce]
+
+		mined, err := stc.isAnyMined(ctx, transactions)
+		if err != nil {
+			return nil, err
+		}
+
+		if mined {
+			delete(stc.submissions, nonce)
+			continue
+		}
+
+		pending = append(pending, transactions[len(transactions)-1])
+	}
+
+	return pending, nil
+}
+
+func (stc *submissionTrackingClient) isAnyMined(
+	ctx context.Context,
+	transactions []*types.Transaction,
+) (bool, error) {
+	for _, transaction := range transactions {
+		receipt, err := stc.EthereumClient.TransactionReceipt(
+			ctx,
+			transaction.Hash(),
+		)
+		if err == ethereum.NotFound {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf(
+				"could not get receipt of transaction [%v]: [%v]",
+				transaction.Hash().Hex(),
+				err,
+			)
+		}
+
+		if receipt != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// cancellationGasPrice returns the gas price of the transaction replacing
+// the one with the given gas price. The price is increased by 20%, the same
+// way the mining waiter does, so the node accepts the replacement. It is
+// never lower than the currently suggested gas price.
+func cancellationGasPrice(
+	pendingGasPrice *big.Int,
+	suggestedGasPrice *big.Int,
+) *big.Int {
+	twentyPercent := new(big.Int).Div(pendingGasPrice, big.NewInt(5))
+	gasPrice := new(big.Int).Add(pendingGasPrice, twentyPercent)
+
+	if gasPrice.Cmp(suggestedGasPrice) < 0 {
+		return new(big.Int).Set(suggestedGasPrice)
+	}
+
+	return gasPrice
+}
+
+// CancelPendingTransactions cancels all transactions submitted by the
+// operator which are not mined yet. Each of them is replaced with a
+// zero-value transaction sent by the operator to itself at the same nonce
+// and with a higher gas price. It returns the number of cancelled
+// transactions.
+func (ec *ethereumChain) CancelPendingTransactions(
+	ctx context.Context,
+) (int, error) {
+	if ec.watchOnly {
+		return 0, errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	ec.transactionMutex.Lock()
+	defer ec.transactionMutex.Unlock()
+
+	pending, err := ec.submissions.pendingSubmissions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not get pending transactions: [%v]", err)
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	suggestedGasPrice, err := ec.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not get gas price: [%v]", err)
+	}
+
+	for i, transaction := range pending {
+		cancellation, err := types.SignTx(
+			types.NewTransaction(
+				transaction.Nonce(),
+				ec.accountKey.Address,
+				big.NewInt(0),
+				cancellationGasLimit,
+				cancellationGasPrice(
+					transaction.GasPrice(),
+					suggestedGasPrice,
+				),
+				nil,
+			),
+			ec.signer,
+			ec.accountKey.PrivateKey,
+		)
+		if err != nil {
+			return i, fmt.Errorf(
+				"could not sign cancellation of transaction [%v]: [%v]",
+				transaction.Hash().Hex(),
+				err,
+			)
+		}
+
+		if err := ec.client.SendTransaction(ctx, cancellation); err != nil {
+			return i, fmt.Errorf(
+				"could not cancel transaction [%v] with nonce [%v]: [%v]",
+				transaction.Hash().Hex(),
+				transaction.Nonce(),
+				err,
+			)
+		}
+
+		ec.logger.Warnf(
+			"cancelled transaction [%v] with nonce [%v] by submitting "+
+				"transaction [%v] with gas price [%v]",
+			transaction.Hash().Hex(),
+			transaction.Nonce(),
+			cancellation.Hash().Hex(),
+			cancellation.GasPrice(),
+		)
+	}
+
+	return len(pending), nil
+}
+
+// PendingTransactions returns the number of transactions submitted by the
+// operator which are not mined yet. Only the transactions submitted since
+// the handle has been connected are known.
+func (ec *ethereumChain) PendingTransactions(ctx context.Context) (int, error) {
+	if ec.watchOnly {
+		return 0, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	pending, err := ec.submissions.pendingSubmissions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not get pending transactions: [%v]", err)
+	}
+
+	return len(pending), nil
+}
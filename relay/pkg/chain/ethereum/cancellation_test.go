@@ -0,0 +1,131 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+)
+
+// receiptsClient is a client which accepts all transactions and returns
+// receipts only for the transactions marked as mined.
+type receiptsClient struct {
+	ethutil.EthereumClient
+
+	mined map[common.Hash]bool
+}
+
+func (rc *receiptsClient) SendTransaction(
+	ctx context.Context,
+	tx *types.Transaction,
+) error {
+	return nil
+}
+
+func (rc *receiptsClient) TransactionReceipt(
+	ctx context.Context,
+	txHash common.Hash,
+) (*types.Receipt, error) {
+	if rc.mined[txHash] {
+		return &types.Receipt{TxHash: txHash}, nil
+	}
+
+	return nil, ethereum.NotFound
+}
+
+func TestPendingSubmissions(t *testing.T) {
+	ctx := context.Background()
+
+	newTransaction := func(nonce uint64, gasPrice int64) *types.Transaction {
+		return types.NewTransaction(
+			nonce,
+			common.Address{},
+			big.NewInt(0),
+			cancellationGasLimit,
+			big.NewInt(gasPrice),
+			nil,
+		)
+	}
+
+	minedTransaction := newTransaction(1, 10)
+	resubmittedTransaction := newTransaction(1, 12)
+	pendingTransaction := newTransaction(2, 10)
+	bumpedTransaction := newTransaction(2, 12)
+
+	client := &receiptsClient{
+		mined: map[common.Hash]bool{minedTransaction.Hash(): true},
+	}
+	submissions := wrapSubmissionTracking(client)
+
+	for _, transaction := range []*types.Transaction{
+		minedTransaction,
+		resubmittedTransaction,
+		pendingTransaction,
+		bumpedTransaction,
+	} {
+		if err := submissions.SendTransaction(ctx, transaction); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := submissions.pendingSubmissions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 1 || pending[0].Hash() != bumpedTransaction.Hash() {
+		t.Errorf(
+			"unexpected pending transactions:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			[]*types.Transaction{bumpedTransaction},
+			pending,
+		)
+	}
+
+	if _, ok := submissions.submissions[1]; ok {
+		t.Errorf("mined nonce is still tracked")
+	}
+}
+
+func TestCancellationGasPrice(t *testing.T) {
+	var tests = map[string]struct {
+		pendingGasPrice   int64
+		suggestedGasPrice int64
+		expectedGasPrice  int64
+	}{
+		"pending gas price bumped": {
+			pendingGasPrice:   100,
+			suggestedGasPrice: 50,
+			expectedGasPrice:  120,
+		},
+		"suggested gas price higher than the bumped one": {
+			pendingGasPrice:   100,
+			suggestedGasPrice: 150,
+			expectedGasPrice:  150,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := cancellationGasPrice(
+				big.NewInt(test.pendingGasPrice),
+				big.NewInt(test.suggestedGasPrice),
+			)
+
+			if actual.Cmp(big.NewInt(test.expectedGasPrice)) != 0 {
+				t.Errorf(
+					"unexpected gas price:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedGasPrice,
+					actual,
+				)
+			}
+		})
+	}
+}
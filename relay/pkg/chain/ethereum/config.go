@@ -0,0 +1,68 @@
+package ethereum
+
+import (
+	"github.com/keep-network/keep-common/pkg/chain/ethereum"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+// Config is the configuration of the Ethereum host chain. It extends the
+// common Ethereum configuration with relay-specific properties.
+type Config struct {
+	ethereum.Config
+
+	// RelayVersion determines the version of the relay contract ABI. If empty
+	// or set to auto, the version is detected by inspecting the code deployed
+	// at the relay contract address.
+	RelayVersion RelayVersion
+
+	// ChainID is the expected ID of the Ethereum chain. If set, the relay
+	// refuses to connect to a node running a different chain.
+	ChainID int64
+
+	// RequestTimeout is the maximum time, in seconds, a single RPC request
+	// can take. If zero, a default value is used.
+	RequestTimeout int
+
+	// ReadCacheTTL is the time, in seconds, for which the results of
+	// frequent reads, like the best known digest, are cached. If zero,
+	// a default value is used.
+	ReadCacheTTL int
+
+	// ReadCacheEvents determines whether the results of relay contract
+	// reads are cached until the relay contract emits an advance event
+	// instead of for ReadCacheTTL. The events are checked every
+	// ReadCacheTTL.
+	ReadCacheEvents bool
+
+	// SimulateSubmissions determines whether each headers submission is
+	// first executed with a call against the latest host chain state, so
+	// submissions rejected by the relay contract are not paid for.
+	SimulateSubmissions bool
+
+	// PrivateTransactions configures submission of transactions through
+	// a private transaction relay instead of the public mempool.
+	PrivateTransactions PrivateTransactionsConfig
+
+	// Sponsorship configures submission of header pushes paid for by
+	// a third party, through an ERC-4337 bundler or a meta-transaction
+	// relayer.
+	Sponsorship SponsorshipConfig
+
+	// Multisig configures the multisig operation mode, in which header
+	// pushes are proposed to a Gnosis Safe instead of being sent by the
+	// operator account.
+	Multisig MultisigConfig
+
+	// Multicall configures batching of identical header pushes of several
+	// relay targets into a single transaction calling a multicall contract.
+	Multicall MulticallConfig
+
+	// ENS configures the resolution of contract addresses configured as ENS
+	// names instead of hex addresses.
+	ENS ENSConfig
+
+	// HTTP configures the extra headers, proxy and TLS settings of the
+	// connection to the node. If set, the node is connected over HTTP using
+	// URLRPC when URL is a websocket URL.
+	HTTP transport.Config
+}
@@ -0,0 +1,288 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// deposit.go file contains the binding of the tBTC Deposit contracts. Each
+// deposit is a separate contract, so bound contracts are created on demand
+// for the addresses of the deposits the relay submits proofs for.
+
+// depositABI is the subset of the tBTC Deposit ABI used by the binding.
+const depositABI = `[
+	{"inputs":[],"name":"currentState","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"internalType":"bytes4","name":"_txVersion","type":"bytes4"},{"internalType":"bytes","name":"_txInputVector","type":"bytes"},{"internalType":"bytes","name":"_txOutputVector","type":"bytes"},{"internalType":"bytes4","name":"_txLocktime","type":"bytes4"},{"internalType":"uint8","name":"_fundingOutputIndex","type":"uint8"},{"internalType":"bytes","name":"_merkleProof","type":"bytes"},{"internalType":"uint256","name":"_txIndexInBlock","type":"uint256"},{"internalType":"bytes","name":"_bitcoinHeaders","type":"bytes"}],"name":"provideBTCFundingProof","outputs":[],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[{"internalType":"bytes4","name":"_txVersion","type":"bytes4"},{"internalType":"bytes","name":"_txInputVector","type":"bytes"},{"internalType":"bytes","name":"_txOutputVector","type":"bytes"},{"internalType":"bytes4","name":"_txLocktime","type":"bytes4"},{"internalType":"bytes","name":"_merkleProof","type":"bytes"},{"internalType":"uint256","name":"_txIndexInBlock","type":"uint256"},{"internalType":"bytes","name":"_bitcoinHeaders","type":"bytes"}],"name":"provideRedemptionProof","outputs":[],"stateMutability":"nonpayable","type":"function"}
+]`
+
+// depositBindings holds the bound Deposit contracts.
+type depositBindings struct {
+	abi               hostchainabi.ABI
+	dependencies      *bindingDependencies
+	callerOptions     *bind.CallOpts
+	transactorOptions *bind.TransactOpts
+
+	mutex     sync.Mutex
+	contracts map[common.Address]*bind.BoundContract
+}
+
+func newDepositBindings(
+	dependencies *bindingDependencies,
+) (*depositBindings, error) {
+	parsed, err := hostchainabi.JSON(strings.NewReader(depositABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	callerOptions, transactorOptions := boundContractOptions(dependencies)
+
+	return &depositBindings{
+		abi:               parsed,
+		dependencies:      dependencies,
+		callerOptions:     callerOptions,
+		transactorOptions: transactorOptions,
+		contracts:         make(map[common.Address]*bind.BoundContract),
+	}, nil
+}
+
+// contract returns the bound Deposit contract deployed at the given address.
+func (db *depositBindings) contract(
+	depositAddress string,
+) (*bind.BoundContract, error) {
+	if !common.IsHexAddress(depositAddress) {
+		return nil, fmt.Errorf(
+			"invalid deposit contract address [%v]",
+			depositAddress,
+		)
+	}
+
+	address := common.HexToAddress(depositAddress)
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if contract, ok := db.contracts[address]; ok {
+		return contract, nil
+	}
+
+	contract := bind.NewBoundContract(
+		address,
+		db.abi,
+		db.dependencies.client,
+		db.dependencies.client,
+		db.dependencies.client,
+	)
+	db.contracts[address] = contract
+
+	return contract, nil
+}
+
+// GetDepositState returns the state of the Deposit contract at the given
+// address.
+func (ec *ethereumChain) GetDepositState(
+	ctx context.Context,
+	depositAddress string,
+) (chain.DepositState, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	contract, err := ec.deposits.contract(depositAddress)
+	if err != nil {
+		return 0, err
+	}
+
+	var state *big.Int
+	if err := contract.Call(
+		ec.deposits.callerOptions,
+		&state,
+		"currentState",
+	); err != nil {
+		return 0, err
+	}
+
+	if !state.IsUint64() || state.Uint64() > math.MaxUint8 {
+		return 0, fmt.Errorf("unknown deposit state [%v]", state)
+	}
+
+	return chain.DepositState(state.Uint64()), nil
+}
+
+// ProvideFundingProof submits the proof of the funding transaction of the
+// Deposit contract at the given address.
+func (ec *ethereumChain) ProvideFundingProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *chain.TransactionProof,
+	fundingOutputIndex uint8,
+) error {
+	if ec.watchOnly {
+		return errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	contract, err := ec.deposits.contract(depositAddress)
+	if err != nil {
+		return err
+	}
+
+	txVersion, err := toBytes4(proof.TxVersion)
+	if err != nil {
+		return fmt.Errorf("invalid transaction version: [%v]", err)
+	}
+
+	txLocktime, err := toBytes4(proof.TxLocktime)
+	if err != nil {
+		return fmt.Errorf("invalid transaction lock time: [%v]", err)
+	}
+
+	ec.transactionMutex.Lock()
+	defer ec.transactionMutex.Unlock()
+
+	transaction, err := contract.Transact(
+		ec.deposits.transactorOptions,
+		"provideBTCFundingProof",
+		txVersion,
+		proof.TxInputVector,
+		proof.TxOutputVector,
+		txLocktime,
+		fundingOutputIndex,
+		proof.MerkleProof,
+		new(big.Int).SetUint64(proof.TxIndexInBlock),
+		proof.BitcoinHeaders,
+	)
+	if err != nil {
+		return err
+	}
+
+	ec.logger.Infof(
+		"submitted ProvideBTCFundingProof transaction for deposit [%v] "+
+			"with hash: [%x]",
+		depositAddress,
+		transaction.Hash(),
+	)
+
+	return nil
+}
+
+// PackFundingProof returns the ABI-encoded calldata of the
+// provideBTCFundingProof call of the Deposit contract with the given proof,
+// e.g. to submit the proof manually.
+func PackFundingProof(
+	proof *chain.TransactionProof,
+	fundingOutputIndex uint8,
+) ([]byte, error) {
+	parsed, err := hostchainabi.JSON(strings.NewReader(depositABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	txVersion, err := toBytes4(proof.TxVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction version: [%v]", err)
+	}
+
+	txLocktime, err := toBytes4(proof.TxLocktime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction lock time: [%v]", err)
+	}
+
+	return parsed.Pack(
+		"provideBTCFundingProof",
+		txVersion,
+		proof.TxInputVector,
+		proof.TxOutputVector,
+		txLocktime,
+		fundingOutputIndex,
+		proof.MerkleProof,
+		new(big.Int).SetUint64(proof.TxIndexInBlock),
+		proof.BitcoinHeaders,
+	)
+}
+
+// ProvideRedemptionProof submits the proof of the redemption transaction of
+// the Deposit contract at the given address.
+func (ec *ethereumChain) ProvideRedemptionProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *chain.TransactionProof,
+) error {
+	if ec.watchOnly {
+		return errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	contract, err := ec.deposits.contract(depositAddress)
+	if err != nil {
+		return err
+	}
+
+	txVersion, err := toBytes4(proof.TxVersion)
+	if err != nil {
+		return fmt.Errorf("invalid transaction version: [%v]", err)
+	}
+
+	txLocktime, err := toBytes4(proof.TxLocktime)
+	if err != nil {
+		return fmt.Errorf("invalid transaction lock time: [%v]", err)
+	}
+
+	ec.transactionMutex.Lock()
+	defer ec.transactionMutex.Unlock()
+
+	transaction, err := contract.Transact(
+		ec.deposits.transactorOptions,
+		"provideRedemptionProof",
+		txVersion,
+		proof.TxInputVector,
+		proof.TxOutputVector,
+		txLocktime,
+		proof.MerkleProof,
+		new(big.Int).SetUint64(proof.TxIndexInBlock),
+		proof.BitcoinHeaders,
+	)
+	if err != nil {
+		return err
+	}
+
+	ec.logger.Infof(
+		"submitted ProvideRedemptionProof transaction for deposit [%v] "+
+			"with hash: [%x]",
+		depositAddress,
+		transaction.Hash(),
+	)
+
+	return nil
+}
+
+func toBytes4(value []byte) ([4]byte, error) {
+	var result [4]byte
+
+	if len(value) != len(result) {
+		return result, fmt.Errorf(
+			"expected [%v] bytes, got [%v]",
+			len(result),
+			len(value),
+		)
+	}
+
+	copy(result[:], value)
+
+	return result, nil
+}
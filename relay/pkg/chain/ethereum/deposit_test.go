@@ -0,0 +1,75 @@
+package ethereum
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+func TestPackFundingProof(t *testing.T) {
+	proof := &chain.TransactionProof{
+		TxVersion:      []byte{1, 0, 0, 0},
+		TxInputVector:  []byte{1, 2, 3},
+		TxOutputVector: []byte{4, 5},
+		TxLocktime:     []byte{0, 0, 0, 0},
+		MerkleProof:    bytes.Repeat([]byte{6}, 64),
+		TxIndexInBlock: 7,
+		BitcoinHeaders: bytes.Repeat([]byte{8}, 160),
+	}
+
+	calldata, err := PackFundingProof(proof, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := hostchainabi.JSON(strings.NewReader(depositABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	method, err := parsed.MethodById(calldata[:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method.Name != "provideBTCFundingProof" {
+		t.Fatalf("unexpected method: [%v]", method.Name)
+	}
+
+	arguments, err := method.Inputs.UnpackValues(calldata[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if outputIndex := arguments[4].(uint8); outputIndex != 1 {
+		t.Errorf("unexpected funding output index: [%v]", outputIndex)
+	}
+
+	if index := arguments[6].(*big.Int); index.Uint64() != 7 {
+		t.Errorf("unexpected transaction index: [%v]", index)
+	}
+
+	if headers := arguments[7].([]byte); !bytes.Equal(
+		headers,
+		proof.BitcoinHeaders,
+	) {
+		t.Errorf("unexpected headers: [%x]", headers)
+	}
+}
+
+func TestPackFundingProof_InvalidVersion(t *testing.T) {
+	_, err := PackFundingProof(
+		&chain.TransactionProof{
+			TxVersion:  []byte{1},
+			TxLocktime: []byte{0, 0, 0, 0},
+		},
+		0,
+	)
+	if err == nil {
+		t.Fatal("expected error for invalid transaction version")
+	}
+}
@@ -0,0 +1,127 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+// diagnostics.go file contains checks of the Ethereum node and relay
+// contract configuration performed by the doctor command. Unlike Connect,
+// each check is run separately so that all problems can be reported at once.
+
+// Diagnostics runs checks against the configured Ethereum node.
+type Diagnostics struct {
+	client *ethclient.Client
+	config *Config
+}
+
+// NewDiagnostics creates diagnostics for the configured Ethereum node. The
+// returned diagnostics should be closed once no longer needed.
+func NewDiagnostics(config *Config) (*Diagnostics, error) {
+	client, err := dialNode(config)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not connect Ethereum node at [%v]: [%v]",
+			transport.RedactURL(config.URL),
+			err,
+		)
+	}
+
+	return &Diagnostics{client: client, config: config}, nil
+}
+
+// CheckSyncStatus checks whether the node is fully synced.
+func (d *Diagnostics) CheckSyncStatus(ctx context.Context) error {
+	progress, err := d.client.SyncProgress(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get sync progress: [%v]", err)
+	}
+
+	if progress != nil {
+		return fmt.Errorf(
+			"node is syncing; current block [%v] of [%v]",
+			progress.CurrentBlock,
+			progress.HighestBlock,
+		)
+	}
+
+	return nil
+}
+
+// CheckChainID returns the ID of the chain run by the node and checks it
+// against the configured one.
+func (d *Diagnostics) CheckChainID(ctx context.Context) (*big.Int, error) {
+	chainID, err := d.client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get chain ID: [%v]", err)
+	}
+
+	return chainID, verifyChainID(d.config, chainID)
+}
+
+// CheckRelayContract checks whether the relay contract is deployed at the
+// configured address and returns its resolved ABI version.
+func (d *Diagnostics) CheckRelayContract() (RelayVersion, error) {
+	config, err := resolveContractNames(
+		d.config,
+		d.client,
+		false,
+		log.Logger(loggerName),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	address, err := config.ContractAddress(RelayContractName)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := resolveRelayVersion(
+		d.config.RelayVersion,
+		address,
+		func(ctx context.Context, address common.Address) ([]byte, error) {
+			return d.client.CodeAt(ctx, address, nil)
+		},
+		log.Logger(loggerName),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return version.version, nil
+}
+
+// Balance returns the balance of the given account.
+func (d *Diagnostics) Balance(
+	ctx context.Context,
+	address common.Address,
+) (*big.Int, error) {
+	return d.client.BalanceAt(ctx, address, nil)
+}
+
+// LatestBlockTime returns the timestamp of the latest block known by the
+// node.
+func (d *Diagnostics) LatestBlockTime(ctx context.Context) (time.Time, error) {
+	header, err := d.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"could not get latest block header: [%v]",
+			err,
+		)
+	}
+
+	return time.Unix(int64(header.Time), 0), nil
+}
+
+// Close disconnects the diagnostics from the node.
+func (d *Diagnostics) Close() {
+	d.client.Close()
+}
@@ -0,0 +1,427 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// ens.go file contains the resolution of contract addresses configured as
+// ENS names, e.g. `relay.tbtc.eth`, instead of hex addresses. Names are
+// resolved once, when the host chain is connected, so the same configuration
+// follows the contracts across upgrades and networks. Resolved addresses are
+// cached, so a change of the name is detected and a temporary resolution
+// failure does not prevent the relay from starting.
+
+const (
+	// defaultENSRegistry is the address of the ENS registry, deployed at the
+	// same address on Ethereum mainnet and the public testnets.
+	defaultENSRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+	// Default interval, in seconds, in which the names of contracts are
+	// resolved again to detect changes.
+	defaultENSCheckInterval = 3600
+)
+
+// ensABI is the subset of the ENS registry and resolver ABIs used to resolve
+// names.
+const ensABI = `[
+	{"inputs":[{"internalType":"bytes32","name":"node","type":"bytes32"}],"name":"resolver","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"internalType":"bytes32","name":"node","type":"bytes32"}],"name":"addr","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"}
+]`
+
+// ENSConfig is the configuration of the resolution of contract addresses
+// configured as ENS names.
+type ENSConfig struct {
+	// Registry is the address of the ENS registry. If empty, the registry
+	// deployed on Ethereum mainnet and the public testnets is used.
+	Registry string
+
+	// CacheFile is the path of the file the resolved addresses are cached
+	// in. The cached address is used if the name cannot be resolved at
+	// startup. If empty, resolved addresses are not cached across restarts.
+	CacheFile string
+
+	// CheckInterval is the interval, in seconds, in which the names are
+	// resolved again to detect changes. If zero, a default value is used.
+	CheckInterval int
+}
+
+// isENSName checks whether the configured contract address is an ENS name
+// rather than a hex address.
+func isENSName(value string) bool {
+	return !common.IsHexAddress(value) && strings.Contains(value, ".")
+}
+
+// namehash computes the ENS node of the given name, as defined by EIP-137.
+// Names are expected to be normalized; only ASCII letters are lowercased.
+func namehash(name string) common.Hash {
+	node := common.Hash{}
+
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := crypto.Keccak256([]byte(labels[i]))
+		node = crypto.Keccak256Hash(node[:], label)
+	}
+
+	return node
+}
+
+// ValidateContractAddress checks whether the address of the given contract
+// is configured, either as a hex address or as an ENS name resolved when the
+// host chain is connected.
+func (c *Config) ValidateContractAddress(contractName string) error {
+	if value, ok := c.ContractAddresses[contractName]; ok && isENSName(value) {
+		return nil
+	}
+
+	_, err := c.ContractAddress(contractName)
+	return err
+}
+
+// ensResolver resolves ENS names using the registry and the resolver
+// contracts.
+type ensResolver struct {
+	registry common.Address
+	caller   bind.ContractCaller
+	abi      hostchainabi.ABI
+	timeout  time.Duration
+}
+
+func newENSResolver(
+	config *Config,
+	caller bind.ContractCaller,
+) (*ensResolver, error) {
+	registry := config.ENS.Registry
+	if registry == "" {
+		registry = defaultENSRegistry
+	}
+
+	if !common.IsHexAddress(registry) {
+		return nil, fmt.Errorf("invalid ENS registry address [%v]", registry)
+	}
+
+	parsedABI, err := hostchainabi.JSON(strings.NewReader(ensABI))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ENS ABI: [%v]", err)
+	}
+
+	return &ensResolver{
+		registry: common.HexToAddress(registry),
+		caller:   caller,
+		abi:      parsedABI,
+		timeout:  requestTimeout(config),
+	}, nil
+}
+
+// resolve returns the address the given name resolves to.
+func (er *ensResolver) resolve(name string) (common.Address, error) {
+	node := namehash(name)
+
+	resolver, err := er.call(er.registry, "resolver", node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf(
+			"could not get resolver of [%v]: [%v]",
+			name,
+			err,
+		)
+	}
+
+	if resolver == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("[%v] has no resolver", name)
+	}
+
+	address, err := er.call(resolver, "addr", node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf(
+			"could not resolve [%v] using resolver [%v]: [%v]",
+			name,
+			resolver.Hex(),
+			err,
+		)
+	}
+
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf(
+			"[%v] does not resolve to an address",
+			name,
+		)
+	}
+
+	return address, nil
+}
+
+func (er *ensResolver) call(
+	contract common.Address,
+	method string,
+	node common.Hash,
+) (common.Address, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), er.timeout)
+	defer cancelCtx()
+
+	var result common.Address
+	if err := bind.NewBoundContract(
+		contract,
+		er.abi,
+		er.caller,
+		nil,
+		nil,
+	).Call(&bind.CallOpts{Context: ctx}, &result, method, node); err != nil {
+		return common.Address{}, err
+	}
+
+	return result, nil
+}
+
+// ensCache holds the addresses the names resolved to, persisted in the cache
+// file if one is configured.
+type ensCache struct {
+	path string
+
+	mutex     sync.Mutex
+	addresses map[string]string
+}
+
+func openENSCache(path string) (*ensCache, error) {
+	cache := &ensCache{path: path, addresses: make(map[string]string)}
+
+	if path == "" {
+		return cache, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read ENS cache: [%v]", err)
+	}
+
+	if err := json.Unmarshal(data, &cache.addresses); err != nil {
+		return nil, fmt.Errorf("could not decode ENS cache: [%v]", err)
+	}
+
+	return cache, nil
+}
+
+// get returns the cached address of the given name.
+func (ec *ensCache) get(name string) (string, bool) {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+
+	address, ok := ec.addresses[name]
+	return address, ok
+}
+
+// put caches the address of the given name and persists the cache.
+func (ec *ensCache) put(name string, address string) error {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+
+	ec.addresses[name] = address
+
+	if ec.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(ec.addresses, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(ec.path, data, 0644)
+}
+
+// contractNames resolves the contract addresses configured as ENS names.
+type contractNames struct {
+	resolve func(name string) (common.Address, error)
+	cache   *ensCache
+	logger  logs.Logger
+}
+
+// resolveAll returns a copy of the given config with the contract addresses
+// configured as ENS names replaced by the addresses they resolve to. If
+// a name cannot be resolved, its cached address is used. The returned map
+// holds the names of the resolved contracts.
+func (cn *contractNames) resolveAll(
+	config *Config,
+) (*Config, map[string]string, error) {
+	names := make(map[string]string)
+	addresses := make(map[string]string, len(config.ContractAddresses))
+
+	for contractName, value := range config.ContractAddresses {
+		addresses[contractName] = value
+
+		if !isENSName(value) {
+			continue
+		}
+
+		cached, isCached := cn.cache.get(value)
+
+		address, err := cn.resolve(value)
+		if err != nil {
+			if !isCached {
+				return nil, nil, fmt.Errorf(
+					"could not resolve address of contract [%v]: [%v]",
+					contractName,
+					err,
+				)
+			}
+
+			cn.logger.Warnf(
+				"could not resolve address of contract [%v]; "+
+					"using cached address [%v] of [%v]: [%v]",
+				contractName,
+				cached,
+				value,
+				err,
+			)
+
+			addresses[contractName] = cached
+			names[contractName] = value
+			continue
+		}
+
+		if isCached && !strings.EqualFold(cached, address.Hex()) {
+			cn.logger.Warnf(
+				"[%v] of contract [%v] resolves to [%v]; "+
+					"previously resolved to [%v]",
+				value,
+				contractName,
+				address.Hex(),
+				cached,
+			)
+		}
+
+		if err := cn.cache.put(value, address.Hex()); err != nil {
+			cn.logger.Warnf("could not cache address of [%v]: [%v]", value, err)
+		}
+
+		cn.logger.Infof(
+			"resolved [%v] of contract [%v] to [%v]",
+			value,
+			contractName,
+			address.Hex(),
+		)
+
+		addresses[contractName] = address.Hex()
+		names[contractName] = value
+	}
+
+	resolved := *config
+	resolved.ContractAddresses = addresses
+
+	return &resolved, names, nil
+}
+
+// check resolves the given names again and warns about the ones resolving to
+// a different address than the one in use. The new address is used only
+// after a restart, as the contract bindings are created at startup.
+func (cn *contractNames) check(names map[string]string, config *Config) {
+	for contractName, name := range names {
+		address, err := cn.resolve(name)
+		if err != nil {
+			cn.logger.Warnf(
+				"could not check address of contract [%v]: [%v]",
+				contractName,
+				err,
+			)
+			continue
+		}
+
+		inUse := config.ContractAddresses[contractName]
+		if strings.EqualFold(inUse, address.Hex()) {
+			continue
+		}
+
+		if err := cn.cache.put(name, address.Hex()); err != nil {
+			cn.logger.Warnf("could not cache address of [%v]: [%v]", name, err)
+		}
+
+		cn.logger.Warnf(
+			"[%v] of contract [%v] now resolves to [%v] instead of [%v] "+
+				"in use; restart the relay to use the new address",
+			name,
+			contractName,
+			address.Hex(),
+			inUse,
+		)
+	}
+}
+
+// resolveContractNames resolves the contract addresses configured as ENS
+// names and returns the config with the resolved addresses. If any names are
+// resolved and watch is set, they are resolved again periodically and
+// changes are logged.
+func resolveContractNames(
+	config *Config,
+	caller bind.ContractCaller,
+	watch bool,
+	logger logs.Logger,
+) (*Config, error) {
+	hasNames := false
+	for _, value := range config.ContractAddresses {
+		if isENSName(value) {
+			hasNames = true
+			break
+		}
+	}
+
+	if !hasNames {
+		return config, nil
+	}
+
+	resolver, err := newENSResolver(config, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := openENSCache(config.ENS.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+
+	contractNames := &contractNames{
+		resolve: resolver.resolve,
+		cache:   cache,
+		logger:  logger,
+	}
+
+	resolved, names, err := contractNames.resolveAll(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if watch {
+		checkInterval := config.ENS.CheckInterval
+		if checkInterval <= 0 {
+			checkInterval = defaultENSCheckInterval
+		}
+
+		go func() {
+			ticker := time.NewTicker(time.Duration(checkInterval) * time.Second)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				contractNames.check(names, resolved)
+			}
+		}()
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,136 @@
+package ethereum
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum"
+	"github.com/keep-network/keep-common/pkg/chain/ethlike"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+func TestNamehash(t *testing.T) {
+	var tests = map[string]string{
+		"":        "0x0000000000000000000000000000000000000000000000000000000000000000",
+		"eth":     "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae",
+		"foo.eth": "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+		"Foo.ETH": "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+	}
+
+	for name, expectedNode := range tests {
+		if node := namehash(name).Hex(); node != expectedNode {
+			t.Errorf(
+				"unexpected node of [%v]\nexpected: [%v]\nactual:   [%v]",
+				name,
+				expectedNode,
+				node,
+			)
+		}
+	}
+}
+
+func TestContractNames_ResolveAll(t *testing.T) {
+	relayAddress := common.HexToAddress(
+		"0x1111111111111111111111111111111111111111",
+	)
+	oldRelayAddress := common.HexToAddress(
+		"0x2222222222222222222222222222222222222222",
+	)
+	systemAddress := "0x3333333333333333333333333333333333333333"
+
+	dir, err := ioutil.TempDir("", "ens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cachePath := filepath.Join(dir, "ens.json")
+
+	cache, err := openENSCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.put("relay.tbtc.eth", oldRelayAddress.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	resolvable := true
+	contractNames := &contractNames{
+		resolve: func(name string) (common.Address, error) {
+			if !resolvable || name != "relay.tbtc.eth" {
+				return common.Address{}, fmt.Errorf("cannot resolve [%v]", name)
+			}
+
+			return relayAddress, nil
+		},
+		cache:  cache,
+		logger: logs.OrDefault(nil, loggerName),
+	}
+
+	config := &Config{
+		Config: ethereum.Config{
+			Config: ethlike.Config{
+				ContractAddresses: map[string]string{
+					RelayContractName:      "relay.tbtc.eth",
+					TBTCSystemContractName: systemAddress,
+				},
+			},
+		},
+	}
+
+	resolved, names, err := contractNames.resolveAll(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if address, err := resolved.ContractAddress(RelayContractName); err != nil ||
+		address != relayAddress {
+		t.Errorf(
+			"unexpected relay address\nexpected: [%v]\nactual:   [%v] [%v]",
+			relayAddress.Hex(),
+			address.Hex(),
+			err,
+		)
+	}
+	if address := resolved.ContractAddresses[TBTCSystemContractName]; address !=
+		systemAddress {
+		t.Errorf("hex address has been changed to [%v]", address)
+	}
+	if names[RelayContractName] != "relay.tbtc.eth" || len(names) != 1 {
+		t.Errorf("unexpected resolved names [%v]", names)
+	}
+	if config.ContractAddresses[RelayContractName] != "relay.tbtc.eth" {
+		t.Errorf("original config has been modified")
+	}
+
+	// The changed address is cached and used if the name cannot be resolved.
+	reopenedCache, err := openENSCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contractNames.cache = reopenedCache
+	resolvable = false
+
+	resolved, _, err = contractNames.resolveAll(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address, _ := resolved.ContractAddress(RelayContractName); address !=
+		relayAddress {
+		t.Errorf(
+			"unexpected cached relay address\nexpected: [%v]\nactual:   [%v]",
+			relayAddress.Hex(),
+			address.Hex(),
+		)
+	}
+
+	// Names neither resolvable nor cached are rejected.
+	contractNames.cache, _ = openENSCache("")
+	if _, _, err := contractNames.resolveAll(config); err == nil {
+		t.Errorf("expected error for unresolvable name")
+	}
+}
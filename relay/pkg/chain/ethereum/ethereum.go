@@ -12,15 +12,19 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
 	"github.com/keep-network/keep-common/pkg/chain/ethlike"
-	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum/gen/contract"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ipfs/go-log"
-	"github.com/keep-network/keep-common/pkg/chain/ethereum"
 	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
 )
 
-var logger = log.Logger("tbtc-relay-ethereum")
+// Name of the default logger of the Ethereum chain handle.
+const loggerName = "tbtc-relay-ethereum"
 
 // RelayContractName defines the name of the Relay contract.
 const RelayContractName = "Relay"
@@ -40,15 +44,43 @@ var (
 	DefaultMaxGasPrice = big.NewInt(1000000000000) // 1000 Gwei
 )
 
+// errWatchOnly is returned by all transaction-submitting methods when the
+// chain handle has been connected without an operator key.
+var errWatchOnly = fmt.Errorf(
+	"chain handle is in watch-only mode and cannot submit transactions",
+)
+
 // ethereumChain is an implementation of the host chain interface for Ethereum.
+// The generated contract bindings do not accept a context so the context
+// passed to the handle methods is checked before each call, while each
+// underlying RPC request is bounded by the configured request timeout.
 type ethereumChain struct {
-	config        *ethereum.Config
-	accountKey    *keystore.Key
-	client        ethutil.EthereumClient
-	relayContract *contract.Relay
-	blockCounter  *ethlike.BlockCounter
-	miningWaiter  *ethlike.MiningWaiter
-	nonceManager  *ethlike.NonceManager
+	config       *Config
+	accountKey   *keystore.Key
+	client       ethutil.EthereumClient
+	relay        relayBinding
+	relayAddress common.Address
+	relayVersion RelayVersion
+	rewards      *rewardsBinding
+	deposits     *depositBindings
+	sparse       *sparseBinding
+	pausable     []*pausableBinding
+	blockCounter *ethlike.BlockCounter
+	miningWaiter *ethlike.MiningWaiter
+	nonceManager *ethlike.NonceManager
+	signer       types.Signer
+	submissions  *submissionTrackingClient
+	logger       logs.Logger
+
+	// external submits header pushes through an external service, paying
+	// for them or collecting their multisig approvals. It is nil if header
+	// pushes are sent as transactions of the operator account.
+	external externalSubmitter
+
+	// watchOnly is set when no operator key has been provided. In that case
+	// the chain handle supports only read-only calls, unless header pushes
+	// are submitted through a meta-transaction relayer.
+	watchOnly bool
 
 	// transactionMutex allows interested parties to forcibly serialize
 	// transaction submission.
@@ -66,23 +98,86 @@ type ethereumChain struct {
 }
 
 // Connect performs initialization for communication with Ethereum blockchain
-// based on provided config.
+// based on provided config. If the account key is nil, the returned handle
+// works in the watch-only mode and refuses to submit any transactions.
+// Messages are logged to the given logger or, if it is nil, to the default
+// logger.
 func Connect(
 	accountKey *keystore.Key,
-	config *ethereum.Config,
+	config *Config,
+	logger logs.Logger,
 ) (chain.Handle, error) {
+	logger = logs.OrDefault(logger, loggerName)
+
 	logger.Infof("connecting Ethereum host chain")
 
-	client, err := ethclient.Dial(config.URL)
+	watchOnly := accountKey == nil
+	if watchOnly {
+		if config.Sponsorship.IsKeyless() {
+			logger.Infof(
+				"no operator key provided; only header pushes are submitted",
+			)
+		} else {
+			logger.Infof(
+				"no operator key provided; connecting in watch-only mode",
+			)
+		}
+
+		// Contract bindings require a key even for read-only calls. Use an
+		// ephemeral one which is never used to sign anything.
+		ephemeralKey, err := newEphemeralKey()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to generate ephemeral key: [%v]",
+				err,
+			)
+		}
+
+		accountKey = ephemeralKey
+	}
+
+	client, err := dialNode(config)
 	if err != nil {
 		return nil, err
 	}
 
-	wrappedClient := addClientWrappers(client)
+	publicClient := addClientWrappers(client, config, logger)
+
+	if config.PrivateTransactions.Enabled {
+		privateClient, err := ethclient.Dial(config.PrivateTransactions.url())
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not connect private transaction relay: [%v]",
+				err,
+			)
+		}
+
+		// The URL is not logged as private relays often embed API keys in it.
+		logger.Infof("submitting transactions through private transaction relay")
+
+		publicClient = wrapPrivateSubmission(
+			publicClient,
+			addClientWrappers(privateClient, config, logger),
+			config.PrivateTransactions.fallbackTimeout(),
+			requestTimeout(config),
+			logger,
+		)
+	}
+
+	// All transactions are sent through the submission tracking client so
+	// the ones not mined yet can be cancelled.
+	submissions := wrapSubmissionTracking(publicClient)
+	wrappedClient := ethutil.EthereumClient(submissions)
 
 	transactionMutex := &sync.Mutex{}
 
-	chainID, err := client.ChainID(context.Background())
+	chainIDCtx, cancelChainIDCtx := context.WithTimeout(
+		context.Background(),
+		requestTimeout(config),
+	)
+	defer cancelChainIDCtx()
+
+	chainID, err := client.ChainID(chainIDCtx)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"failed to resolve Ethereum chain id: [%v]",
@@ -90,6 +185,15 @@ func Connect(
 		)
 	}
 
+	if err := verifyChainID(config, chainID); err != nil {
+		return nil, err
+	}
+
+	config, err = resolveContractNames(config, wrappedClient, true, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	nonceManager := ethutil.NewNonceManager(wrappedClient, accountKey.Address)
 
 	miningWaiter := ethutil.NewMiningWaiter(
@@ -113,72 +217,280 @@ func Connect(
 		return nil, err
 	}
 
-	relayContract, err := contract.NewRelay(
+	relayVersion, err := resolveRelayVersion(
+		config.RelayVersion,
 		relayContractAddress,
-		chainID,
-		accountKey,
-		wrappedClient,
-		nonceManager,
-		miningWaiter,
-		blockCounter,
-		transactionMutex,
+		func(ctx context.Context, address common.Address) ([]byte, error) {
+			return wrappedClient.CodeAt(ctx, address, nil)
+		},
+		logger,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve relay version: [%v]", err)
+	}
+
+	dependencies := &bindingDependencies{
+		chainID:          chainID,
+		accountKey:       accountKey,
+		client:           wrappedClient,
+		nonceManager:     nonceManager,
+		miningWaiter:     miningWaiter,
+		blockCounter:     blockCounter,
+		transactionMutex: transactionMutex,
+		logger:           logger,
+	}
+
+	relay, err := relayVersion.factory(relayContractAddress, dependencies)
 	if err != nil {
 		return nil, err
 	}
 
+	rewards, err := connectRewards(
+		config,
+		relayContractAddress,
+		dependencies,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect relay rewards: [%v]", err)
+	}
+
+	deposits, err := newDepositBindings(dependencies)
+	if err != nil {
+		return nil, fmt.Errorf("could not create deposit bindings: [%v]", err)
+	}
+
+	sparse, err := connectSparse(config, relayContractAddress, dependencies)
+	if err != nil {
+		return nil, fmt.Errorf("could not detect sparse relay: [%v]", err)
+	}
+
+	pausable, err := connectPausable(config, relayContractAddress, dependencies)
+	if err != nil {
+		return nil, fmt.Errorf("could not detect pausable contracts: [%v]", err)
+	}
+
+	external, err := newExternalSubmitter(config, dependencies, watchOnly)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not configure external submissions: [%v]",
+			err,
+		)
+	}
+
+	logger.Infof(
+		"using relay contract [%v] with version [%v]",
+		relayContractAddress.Hex(),
+		relayVersion.version,
+	)
+
 	return &ethereumChain{
 		config:           config,
 		accountKey:       accountKey,
 		client:           wrappedClient,
-		relayContract:    relayContract,
+		relay:            relay,
+		relayAddress:     relayContractAddress,
+		relayVersion:     relayVersion.version,
+		rewards:          rewards,
+		deposits:         deposits,
+		sparse:           sparse,
+		pausable:         pausable,
 		blockCounter:     blockCounter,
 		nonceManager:     nonceManager,
 		miningWaiter:     miningWaiter,
+		signer:           types.NewEIP155Signer(chainID),
+		submissions:      submissions,
+		logger:           logger,
+		external:         external,
+		watchOnly:        watchOnly,
 		transactionMutex: transactionMutex,
 	}, nil
 }
 
+// verifyChainID checks whether the node runs the configured chain. Any chain
+// is accepted if no chain ID is configured.
+func verifyChainID(config *Config, chainID *big.Int) error {
+	if config.ChainID != 0 && chainID.Cmp(big.NewInt(config.ChainID)) != 0 {
+		return fmt.Errorf(
+			"node runs the Ethereum chain with ID [%v] while the chain "+
+				"with ID [%v] is configured",
+			chainID,
+			config.ChainID,
+		)
+	}
+
+	return nil
+}
+
+func newEphemeralKey() (*keystore.Key, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &keystore.Key{
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, nil
+}
+
 func addClientWrappers(
 	client ethutil.EthereumClient,
+	config *Config,
+	logger logs.Logger,
 ) ethutil.EthereumClient {
-	loggingClient := ethutil.WrapCallLogging(logger, client)
+	timeoutClient := wrapRequestTimeout(client, requestTimeout(config))
+	loggingClient := ethutil.WrapCallLogging(eventLogger(logger), timeoutClient)
 
 	return loggingClient
 }
 
+// eventLogger adapts the given logger to the event logger required by the
+// call logging wrapper. Event logging methods of the adapter, unused by the
+// wrapper, are served by the default logger.
+func eventLogger(logger logs.Logger) log.EventLogger {
+	if el, ok := logger.(log.EventLogger); ok {
+		return el
+	}
+
+	return &eventLoggerAdapter{
+		EventLogger: log.Logger(loggerName),
+		logger:      logger,
+	}
+}
+
+type eventLoggerAdapter struct {
+	log.EventLogger
+
+	logger logs.Logger
+}
+
+func (ela *eventLoggerAdapter) Debugf(format string, args ...interface{}) {
+	ela.logger.Debugf(format, args...)
+}
+
+func (ela *eventLoggerAdapter) Infof(format string, args ...interface{}) {
+	ela.logger.Infof(format, args...)
+}
+
+func (ela *eventLoggerAdapter) Warnf(format string, args ...interface{}) {
+	ela.logger.Warnf(format, args...)
+}
+
+func (ela *eventLoggerAdapter) Errorf(format string, args ...interface{}) {
+	ela.logger.Errorf(format, args...)
+}
+
+func requestTimeout(config *Config) time.Duration {
+	if config.RequestTimeout > 0 {
+		return time.Duration(config.RequestTimeout) * time.Second
+	}
+
+	return DefaultRequestTimeout
+}
+
 // GetBestKnownDigest returns the best known digest.
-func (ec *ethereumChain) GetBestKnownDigest() (btc.Digest, error) {
-	return ec.relayContract.GetBestKnownDigest()
+func (ec *ethereumChain) GetBestKnownDigest(
+	ctx context.Context,
+) (btc.Digest, error) {
+	if err := ctx.Err(); err != nil {
+		return btc.Digest{}, err
+	}
+
+	return ec.relay.GetBestKnownDigest()
 }
 
 // IsAncestor checks if ancestorDigest is an ancestor of the descendantDigest.
 // The limit parameter determines the number of blocks to check.
 func (ec *ethereumChain) IsAncestor(
+	ctx context.Context,
 	ancestorDigest btc.Digest,
 	descendantDigest btc.Digest,
 	limit *big.Int,
 ) (bool, error) {
-	return ec.relayContract.IsAncestor(ancestorDigest, descendantDigest, limit)
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return ec.relay.IsAncestor(ancestorDigest, descendantDigest, limit)
 }
 
 // FindHeight finds the height of a header by its digest.
-func (ec *ethereumChain) FindHeight(digest btc.Digest) (*big.Int, error) {
-	return ec.relayContract.FindHeight(digest)
+func (ec *ethereumChain) FindHeight(
+	ctx context.Context,
+	digest btc.Digest,
+) (*big.Int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return ec.relay.FindHeight(digest, nil)
+}
+
+// FindAncestor finds the digest of the ancestor of the header with the given
+// digest, the given number of blocks below it.
+func (ec *ethereumChain) FindAncestor(
+	ctx context.Context,
+	digest btc.Digest,
+	offset *big.Int,
+) (btc.Digest, error) {
+	if err := ctx.Err(); err != nil {
+		return btc.Digest{}, err
+	}
+
+	return ec.relay.FindAncestor(digest, offset)
+}
+
+// FindHeightAtBlock finds the height of a header by its digest using
+// the relay contract state as of the given host chain block.
+func (ec *ethereumChain) FindHeightAtBlock(
+	ctx context.Context,
+	digest btc.Digest,
+	blockNumber uint64,
+) (*big.Int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return ec.relay.FindHeight(
+		digest,
+		new(big.Int).SetUint64(blockNumber),
+	)
 }
 
 // AddHeaders adds headers to storage after validating. The anchorHeader
 // parameter is the header immediately preceding the new chain. Headers
 // parameter should be a tightly-packed list of 80-byte Bitcoin headers.
-func (ec *ethereumChain) AddHeaders(anchorHeader []byte, headers []byte) error {
-	transaction, err := ec.relayContract.AddHeaders(anchorHeader, headers)
+func (ec *ethereumChain) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	if ec.watchOnly && ec.external == nil {
+		return errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := ec.simulateSubmission(ctx, "AddHeaders", func() (bool, error) {
+		return ec.relay.CallAddHeaders(anchorHeader, headers)
+	}); err != nil {
+		return err
+	}
+
+	if ec.external != nil {
+		return ec.submitExternal(ctx, "addHeaders", anchorHeader, headers)
+	}
+
+	transactionHash, err := ec.relay.AddHeaders(anchorHeader, headers)
 	if err != nil {
 		return err
 	}
 
-	logger.Debugf(
+	correlation.InjectedLogger(ctx, ec.logger).Infof(
 		"submitted AddHeaders transaction with hash: [%x]",
-		transaction.Hash(),
+		transactionHash,
 	)
 
 	return nil
@@ -189,11 +501,44 @@ func (ec *ethereumChain) AddHeaders(anchorHeader []byte, headers []byte) error {
 // difficulty period being closed while oldPeriodEndHeader is the last.
 // Headers parameter should be a tightly-packed list of 80-byte Bitcoin headers.
 func (ec *ethereumChain) AddHeadersWithRetarget(
+	ctx context.Context,
 	oldPeriodStartHeader []byte,
 	oldPeriodEndHeader []byte,
 	headers []byte,
 ) error {
-	transaction, err := ec.relayContract.AddHeadersWithRetarget(
+	if ec.watchOnly && ec.external == nil {
+		return errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := ec.simulateSubmission(
+		ctx,
+		"AddHeadersWithRetarget",
+		func() (bool, error) {
+			return ec.relay.CallAddHeadersWithRetarget(
+				oldPeriodStartHeader,
+				oldPeriodEndHeader,
+				headers,
+			)
+		},
+	); err != nil {
+		return err
+	}
+
+	if ec.external != nil {
+		return ec.submitExternal(
+			ctx,
+			"addHeadersWithRetarget",
+			oldPeriodStartHeader,
+			oldPeriodEndHeader,
+			headers,
+		)
+	}
+
+	transactionHash, err := ec.relay.AddHeadersWithRetarget(
 		oldPeriodStartHeader,
 		oldPeriodEndHeader,
 		headers,
@@ -202,9 +547,9 @@ func (ec *ethereumChain) AddHeadersWithRetarget(
 		return err
 	}
 
-	logger.Debugf(
+	correlation.InjectedLogger(ctx, ec.logger).Infof(
 		"submitted AddHeadersWithRetarget transaction with hash: [%x]",
-		transaction.Hash(),
+		transactionHash,
 	)
 
 	return nil
@@ -216,12 +561,32 @@ func (ec *ethereumChain) AddHeadersWithRetarget(
 // while the newBestHeader param should be the header to mark as new best.
 // Limit parameter limits the amount of traversal of the chain.
 func (ec *ethereumChain) MarkNewHeaviest(
+	ctx context.Context,
 	ancestorDigest btc.Digest,
 	currentBestHeader []byte,
 	newBestHeader []byte,
 	limit *big.Int,
 ) error {
-	transaction, err := ec.relayContract.MarkNewHeaviest(
+	if ec.watchOnly && ec.external == nil {
+		return errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if ec.external != nil {
+		return ec.submitExternal(
+			ctx,
+			"markNewHeaviest",
+			[32]byte(ancestorDigest),
+			currentBestHeader,
+			newBestHeader,
+			limit,
+		)
+	}
+
+	transactionHash, err := ec.relay.MarkNewHeaviest(
 		ancestorDigest,
 		currentBestHeader,
 		newBestHeader,
@@ -231,9 +596,9 @@ func (ec *ethereumChain) MarkNewHeaviest(
 		return err
 	}
 
-	logger.Debugf(
+	correlation.InjectedLogger(ctx, ec.logger).Infof(
 		"submitted MarkNewHeaviest transaction with hash: [%x]",
-		transaction.Hash(),
+		transactionHash,
 	)
 
 	return nil
@@ -243,22 +608,96 @@ func (ec *ethereumChain) MarkNewHeaviest(
 // MarkNewHeaviest method to check whether its execution will
 // succeed.
 func (ec *ethereumChain) MarkNewHeaviestPreflight(
+	ctx context.Context,
 	ancestorDigest btc.Digest,
 	currentBestHeader []byte,
 	newBestHeader []byte,
 	limit *big.Int,
 ) bool {
-	result, err := ec.relayContract.CallMarkNewHeaviest(
+	if ctx.Err() != nil {
+		return false
+	}
+
+	result, err := ec.relay.CallMarkNewHeaviest(
 		ancestorDigest,
 		currentBestHeader,
 		newBestHeader,
 		limit,
-		nil,
 	)
 	if err != nil {
-		logger.Warnf("MarkNewHeaviest preflight failed with: [%v]", err)
+		ec.logger.Warnf("MarkNewHeaviest preflight failed with: [%v]", err)
 		return false
 	}
 
 	return result
 }
+
+// GetProofLength returns the number of headers required on each side
+// of the difficulty epoch boundary to prove a retarget.
+func (ec *ethereumChain) GetProofLength(ctx context.Context) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return ec.relay.GetProofLength()
+}
+
+// GetCurrentEpoch returns the number of the latest difficulty epoch
+// known by the light relay.
+func (ec *ethereumChain) GetCurrentEpoch(ctx context.Context) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return ec.relay.GetCurrentEpoch()
+}
+
+// Retarget adds a new difficulty epoch to the light relay. Headers
+// parameter should be a tightly-packed list of 80-byte Bitcoin headers
+// consisting of proof length headers from the end of the current epoch
+// and the same number of headers from the beginning of the new epoch.
+func (ec *ethereumChain) Retarget(ctx context.Context, headers []byte) error {
+	if ec.watchOnly && ec.external == nil {
+		return errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := ec.simulateSubmission(ctx, "Retarget", func() (bool, error) {
+		return true, ec.relay.CallRetarget(headers)
+	}); err != nil {
+		return err
+	}
+
+	if ec.external != nil {
+		return ec.submitExternal(ctx, "retarget", headers)
+	}
+
+	transactionHash, err := ec.relay.Retarget(headers)
+	if err != nil {
+		return err
+	}
+
+	correlation.InjectedLogger(ctx, ec.logger).Infof(
+		"submitted Retarget transaction with hash: [%x]",
+		transactionHash,
+	)
+
+	return nil
+}
+
+// GetGasPrice returns the gas price currently suggested by the host chain.
+func (ec *ethereumChain) GetGasPrice(ctx context.Context) (*big.Int, error) {
+	return ec.client.SuggestGasPrice(ctx)
+}
+
+// CurrentBlock returns the number of the current host chain block.
+func (ec *ethereumChain) CurrentBlock(ctx context.Context) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return ec.blockCounter.CurrentBlock()
+}
@@ -0,0 +1,248 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// events.go file contains the logic resolving transactions which advanced
+// the relay contract from the events emitted by it. The submitter of each
+// transaction is recovered from the transaction signature and the number of
+// submitted headers is decoded from the transaction input.
+
+// RelayAdvanced checks whether the relay contract has been advanced within
+// the given range of host chain blocks, both inclusive.
+func (ec *ethereumChain) RelayAdvanced(
+	ctx context.Context,
+	fromBlock uint64,
+	toBlock uint64,
+) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	logs, err := ec.relay.PastAdvanceLogs(fromBlock, toBlock)
+	if err == chain.ErrRelayEventsNotSupported {
+		return false, err
+	} else if err != nil {
+		return false, fmt.Errorf("could not get past relay events: [%v]", err)
+	}
+
+	return len(logs) > 0, nil
+}
+
+// PastRelayAdvances returns advances of the relay contract made within
+// the given range of host chain blocks, both inclusive.
+func (ec *ethereumChain) PastRelayAdvances(
+	ctx context.Context,
+	fromBlock uint64,
+	toBlock uint64,
+) ([]*chain.RelayAdvance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	logs, err := ec.relay.PastAdvanceLogs(fromBlock, toBlock)
+	if err == chain.ErrRelayEventsNotSupported {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get past relay events: [%v]", err)
+	}
+
+	relayABI, err := ec.relayABI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Logs of different events are fetched separately; restore the order in
+	// which they were emitted.
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+
+	// A single transaction may emit several events, e.g. a summa relay
+	// transaction adding headers and marking a new heaviest one at once.
+	seen := make(map[common.Hash]bool)
+	blockTimes := make(map[uint64]time.Time)
+
+	advances := make([]*chain.RelayAdvance, 0)
+	for _, log := range logs {
+		if seen[log.TxHash] {
+			continue
+		}
+		seen[log.TxHash] = true
+
+		transaction, submitter, err := ec.transactionWithSender(
+			ctx,
+			log.TxHash,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		receipt, err := ec.client.TransactionReceipt(ctx, log.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not get receipt of transaction [%v]: [%v]",
+				log.TxHash.Hex(),
+				err,
+			)
+		}
+
+		blockTime, ok := blockTimes[log.BlockNumber]
+		if !ok {
+			header, err := ec.client.HeaderByNumber(
+				ctx,
+				new(big.Int).SetUint64(log.BlockNumber),
+			)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"could not get header of block [%v]: [%v]",
+					log.BlockNumber,
+					err,
+				)
+			}
+
+			blockTime = time.Unix(int64(header.Time), 0)
+			blockTimes[log.BlockNumber] = blockTime
+		}
+
+		advances = append(advances, &chain.RelayAdvance{
+			BlockNumber:     log.BlockNumber,
+			Timestamp:       blockTime,
+			TransactionHash: log.TxHash.Hex(),
+			Submitter:       submitter.Hex(),
+			Method:          calledMethod(relayABI, transaction.Data()),
+			GasUsed:         receipt.GasUsed,
+			HeadersCount:    submittedHeadersCount(relayABI, transaction.Data()),
+			Fee: new(big.Int).Mul(
+				new(big.Int).SetUint64(receipt.GasUsed),
+				transaction.GasPrice(),
+			),
+		})
+	}
+
+	return advances, nil
+}
+
+func (ec *ethereumChain) transactionWithSender(
+	ctx context.Context,
+	transactionHash common.Hash,
+) (*types.Transaction, common.Address, error) {
+	transaction, _, err := ec.client.TransactionByHash(ctx, transactionHash)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf(
+			"could not get transaction [%v]: [%v]",
+			transactionHash.Hex(),
+			err,
+		)
+	}
+
+	sender, err := types.Sender(ec.signer, transaction)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf(
+			"could not recover sender of transaction [%v]: [%v]",
+			transactionHash.Hex(),
+			err,
+		)
+	}
+
+	return transaction, sender, nil
+}
+
+func (ec *ethereumChain) relayABI() (*hostchainabi.ABI, error) {
+	version, err := findRelayVersion(ec.relayVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := hostchainabi.JSON(strings.NewReader(version.abi))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse relay ABI: [%v]", err)
+	}
+
+	return &parsed, nil
+}
+
+// Size of a serialized Bitcoin block header.
+const headerSize = 80
+
+// submittedHeadersCount returns the number of headers submitted by the relay
+// contract call with the given input. Headers are expected to be passed as
+// the last argument of the called method, as a tightly-packed list of 80-byte
+// headers. Zero is returned if the call does not submit headers or the input
+// cannot be decoded.
+func submittedHeadersCount(relayABI *hostchainabi.ABI, input []byte) int {
+	method := relayMethod(relayABI, input)
+	if method == nil {
+		return 0
+	}
+
+	switch method.Name {
+	case "addHeaders", "addHeadersWithRetarget", "retarget":
+	default:
+		return 0
+	}
+
+	values, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil || len(values) == 0 {
+		return 0
+	}
+
+	headers, ok := values[len(values)-1].([]byte)
+	if !ok {
+		return 0
+	}
+
+	return len(headers) / headerSize
+}
+
+// calledMethod returns the name of the relay contract method called with the
+// given input. An empty string is returned if the input cannot be decoded,
+// e.g. if the relay contract was called through another contract.
+func calledMethod(relayABI *hostchainabi.ABI, input []byte) string {
+	method := relayMethod(relayABI, input)
+	if method == nil {
+		return ""
+	}
+
+	return method.Name
+}
+
+func relayMethod(
+	relayABI *hostchainabi.ABI,
+	input []byte,
+) *hostchainabi.Method {
+	if len(input) < 4 {
+		return nil
+	}
+
+	method, err := relayABI.MethodById(input[:4])
+	if err != nil {
+		return nil
+	}
+
+	return method
+}
+
+// OperatorAddress returns the address of the account submitting relay
+// transactions. An empty string is returned in the watch-only mode.
+func (ec *ethereumChain) OperatorAddress() string {
+	if ec.watchOnly {
+		return ""
+	}
+
+	return ec.accountKey.Address.Hex()
+}
@@ -0,0 +1,85 @@
+package ethereum
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum/gen/abi"
+)
+
+func TestSubmittedHeadersCount(t *testing.T) {
+	relayABI, err := hostchainabi.JSON(strings.NewReader(abi.RelayABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := bytes.Repeat([]byte{1}, headerSize)
+
+	pack := func(method string, args ...interface{}) []byte {
+		input, err := relayABI.Pack(method, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return input
+	}
+
+	var tests = map[string]struct {
+		input         []byte
+		expectedCount int
+	}{
+		"add headers": {
+			input: pack(
+				"addHeaders",
+				header,
+				bytes.Repeat(header, 5),
+			),
+			expectedCount: 5,
+		},
+		"add headers with retarget": {
+			input: pack(
+				"addHeadersWithRetarget",
+				header,
+				header,
+				bytes.Repeat(header, 3),
+			),
+			expectedCount: 3,
+		},
+		"mark new heaviest": {
+			input: pack(
+				"markNewHeaviest",
+				[32]byte{},
+				header,
+				header,
+				big.NewInt(10),
+			),
+			expectedCount: 0,
+		},
+		"unknown method": {
+			input:         []byte{1, 2, 3, 4, 5},
+			expectedCount: 0,
+		},
+		"too short input": {
+			input:         []byte{1},
+			expectedCount: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			count := submittedHeadersCount(&relayABI, test.input)
+
+			if count != test.expectedCount {
+				t.Errorf(
+					"unexpected headers count:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedCount,
+					count,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// external.go file contains the submission of header pushes through an
+// external service instead of sending them as transactions of the operator
+// account. Header pushes are encoded as calls of the relay contract and
+// handed to a service which either pays for them, see sponsorship.go, or
+// collects the approvals of a multisig wallet executing them, see safe.go.
+// Header pushes of several relay targets can also be batched into a single
+// transaction of the operator account, see multicall.go.
+
+// externalSubmitter submits calls of host chain contracts through an
+// external service.
+type externalSubmitter interface {
+	fmt.Stringer
+
+	// submit submits the call of the contract at the given address with the
+	// given input and returns the identifier of the submission assigned by
+	// the external service.
+	submit(ctx context.Context, to common.Address, input []byte) (string, error)
+}
+
+// newExternalSubmitter creates the external submitter of header pushes
+// according to the given configuration. Returns nil if header pushes should
+// be sent as transactions of the operator account. In the watch-only mode,
+// only the submitters not needing the operator key are created.
+func newExternalSubmitter(
+	config *Config,
+	dependencies *bindingDependencies,
+	watchOnly bool,
+) (externalSubmitter, error) {
+	if config.Multisig.IsEnabled() && config.Sponsorship.IsEnabled() {
+		return nil, fmt.Errorf(
+			"multisig and sponsorship modes cannot be used together",
+		)
+	}
+
+	if config.Multicall.IsEnabled() &&
+		(config.Multisig.IsEnabled() || config.Sponsorship.IsEnabled()) {
+		return nil, fmt.Errorf(
+			"multicall batching cannot be used with the multisig or " +
+				"sponsorship modes",
+		)
+	}
+
+	switch {
+	case config.Multisig.IsEnabled() && !watchOnly:
+		dependencies.logger.Infof(
+			"proposing header pushes to the Safe [%v]",
+			config.Multisig.Safe,
+		)
+
+		return newSafeSubmitter(config, dependencies)
+	case config.Sponsorship.IsEnabled() &&
+		(!watchOnly || config.Sponsorship.IsKeyless()):
+		// The URLs are not logged as they often embed API keys.
+		dependencies.logger.Infof(
+			"submitting header pushes in the sponsorship mode [%v]",
+			config.Sponsorship.Mode,
+		)
+
+		return newSponsoredSubmitter(config, dependencies)
+	case config.Multicall.IsEnabled() && !watchOnly:
+		dependencies.logger.Infof(
+			"batching header pushes through the multicall contract [%v]",
+			config.Multicall.Address,
+		)
+
+		return newMulticallSubmitter(config, dependencies)
+	default:
+		return nil, nil
+	}
+}
+
+// submitExternal submits the call of the given relay contract method with
+// the given arguments through the external submitter.
+func (ec *ethereumChain) submitExternal(
+	ctx context.Context,
+	method string,
+	args ...interface{},
+) error {
+	relayABI, err := ec.relayABI()
+	if err != nil {
+		return err
+	}
+
+	input, err := relayABI.Pack(method, args...)
+	if err != nil {
+		return fmt.Errorf("could not encode [%v] call: [%v]", method, err)
+	}
+
+	id, err := ec.external.submit(ctx, ec.relayAddress, input)
+	if err != nil {
+		return fmt.Errorf(
+			"could not submit [%v] call through the %v: [%v]",
+			method,
+			ec.external,
+			err,
+		)
+	}
+
+	correlation.InjectedLogger(ctx, ec.logger).Infof(
+		"submitted [%v] call through the %v with ID: [%v]",
+		method,
+		ec.external,
+		id,
+	)
+
+	return nil
+}
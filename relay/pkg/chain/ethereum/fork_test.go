@@ -0,0 +1,323 @@
+//go:build integration
+// +build integration
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum"
+	"github.com/keep-network/keep-common/pkg/chain/ethlike"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// fork_test.go file contains the integration tests running the pushing code
+// against an anvil fork of the host chain pinned at a given block, so gas
+// estimation, retarget batching and revert reason decoding are validated
+// against the real relay contract state. The tests run only with the
+// `integration` build tag and are configured with the following environment
+// variables:
+//
+//	RELAY_FORK_URL           RPC URL of the forked host chain node
+//	RELAY_FORK_BLOCK         host chain block the fork is pinned at
+//	RELAY_FORK_CONTRACT      address of the relay contract
+//	RELAY_FORK_BTC_URL       Bitcoin node providing the pushed headers
+//	RELAY_FORK_BTC_USERNAME  Bitcoin node RPC username
+//	RELAY_FORK_BTC_PASSWORD  Bitcoin node RPC password
+
+const (
+	// Private key of the first account funded by anvil.
+	//
+	// #nosec G101 (look for hardcoded credentials)
+	// This is the well-known development key of anvil and hardhat.
+	forkAccountKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+	// Number of headers pushed in a single batch.
+	forkBatchSize = 5
+
+	// Maximum number of headers pushed to reach the next retarget. Forks
+	// pinned further away from a retarget skip the retarget test.
+	forkMaxRetargetDistance = 200
+
+	forkStartTimeout = 30 * time.Second
+)
+
+func TestFork_AddHeaders(t *testing.T) {
+	ctx := context.Background()
+	hostChain, btcChain := connectFork(t)
+
+	bestHeight := bestKnownHeight(ctx, t, hostChain, btcChain)
+
+	// Stop short of the next retarget so only addHeaders is called.
+	lastHeight := bestHeight + forkBatchSize
+	if nextRetarget := nextRetargetHeight(bestHeight); lastHeight >= nextRetarget {
+		lastHeight = nextRetarget - 1
+	}
+	if lastHeight <= bestHeight {
+		t.Skip("relay contract is right before a retarget")
+	}
+
+	pushHeaders(ctx, t, hostChain, btcChain, bestHeight+1, lastHeight)
+}
+
+func TestFork_AddHeadersWithRetarget(t *testing.T) {
+	ctx := context.Background()
+	hostChain, btcChain := connectFork(t)
+
+	bestHeight := bestKnownHeight(ctx, t, hostChain, btcChain)
+
+	nextRetarget := nextRetargetHeight(bestHeight)
+	if nextRetarget-bestHeight > forkMaxRetargetDistance {
+		t.Skipf(
+			"next retarget is [%v] headers away; pin the fork closer to it",
+			nextRetarget-bestHeight,
+		)
+	}
+
+	pushHeaders(
+		ctx,
+		t,
+		hostChain,
+		btcChain,
+		bestHeight+1,
+		nextRetarget+forkBatchSize-1,
+	)
+}
+
+func TestFork_RevertReason(t *testing.T) {
+	ctx := context.Background()
+	hostChain, _ := connectFork(t)
+
+	// Headers must be a tightly-packed list of 80-byte headers.
+	err := hostChain.AddHeaders(ctx, make([]byte, 80), make([]byte, 79))
+	if err == nil {
+		t.Fatal("malformed headers should be rejected")
+	}
+
+	if !strings.Contains(err.Error(), "revert") {
+		t.Errorf("revert reason should be decoded: [%v]", err)
+	}
+}
+
+// pushHeaders pushes the headers from the given range in batches split at
+// retargets, the same way the relay does, and checks the relay contract
+// knows the last header afterwards.
+func pushHeaders(
+	ctx context.Context,
+	t *testing.T,
+	hostChain chain.Handle,
+	btcChain btc.Handle,
+	fromHeight int64,
+	toHeight int64,
+) {
+	for first := fromHeight; first <= toHeight; {
+		last := first + forkBatchSize - 1
+		if nextRetarget := nextRetargetHeight(first); last >= nextRetarget {
+			last = nextRetarget - 1
+		}
+		if last > toHeight {
+			last = toHeight
+		}
+
+		firstHeader, err := btcChain.GetHeaderByHeight(ctx, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lastHeader, err := btcChain.GetHeaderByHeight(ctx, last)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = header.ReplayBatch(ctx, btcChain, hostChain, &store.JournalEntry{
+			ID:          store.NewBatchID(firstHeader.Hash, lastHeader.Hash),
+			FirstHeight: first,
+			LastHeight:  last,
+			LastDigest:  lastHeader.Hash,
+		})
+		if err != nil {
+			t.Fatalf("could not push headers [%v-%v]: [%v]", first, last, err)
+		}
+
+		if _, err := hostChain.FindHeight(ctx, lastHeader.Hash); err != nil {
+			t.Fatalf(
+				"pushed header [%v] is not known by the relay contract: [%v]",
+				last,
+				err,
+			)
+		}
+
+		ancestorDigest, err := hostChain.FindAncestor(
+			ctx,
+			lastHeader.Hash,
+			big.NewInt(last-first),
+		)
+		if err != nil {
+			t.Fatalf("could not find ancestor of header [%v]: [%v]", last, err)
+		}
+		if ancestorDigest != firstHeader.Hash {
+			t.Errorf(
+				"unexpected ancestor of header [%v]:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				last,
+				firstHeader.Hash,
+				ancestorDigest,
+			)
+		}
+
+		first = last + 1
+	}
+}
+
+func bestKnownHeight(
+	ctx context.Context,
+	t *testing.T,
+	hostChain chain.Handle,
+	btcChain btc.Handle,
+) int64 {
+	bestDigest, err := hostChain.GetBestKnownDigest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	height, err := btcChain.GetHeightByDigest(ctx, bestDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return height
+}
+
+// nextRetargetHeight returns the height of the first header of the
+// difficulty epoch following the one of the given height.
+func nextRetargetHeight(height int64) int64 {
+	const epochDuration = 2016
+	return (height/epochDuration + 1) * epochDuration
+}
+
+// connectFork starts an anvil fork of the host chain and connects both the
+// host chain and the Bitcoin chain. The fork is stopped once the test ends.
+func connectFork(t *testing.T) (chain.Handle, btc.Handle) {
+	forkURL := os.Getenv("RELAY_FORK_URL")
+	forkBlock := os.Getenv("RELAY_FORK_BLOCK")
+	contract := os.Getenv("RELAY_FORK_CONTRACT")
+	if forkURL == "" || forkBlock == "" || contract == "" {
+		t.Skip("host chain fork is not configured")
+	}
+
+	if _, err := strconv.ParseUint(forkBlock, 10, 64); err != nil {
+		t.Fatalf("fork block must be pinned to a block number: [%v]", err)
+	}
+
+	anvilPath, err := exec.LookPath("anvil")
+	if err != nil {
+		t.Skip("anvil is not installed")
+	}
+
+	port := freePort(t)
+
+	anvil := exec.Command(
+		anvilPath,
+		"--fork-url", forkURL,
+		"--fork-block-number", forkBlock,
+		"--port", strconv.Itoa(port),
+		"--silent",
+	)
+	if err := anvil.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = anvil.Process.Kill()
+		_ = anvil.Wait()
+	})
+
+	url := fmt.Sprintf("http://127.0.0.1:%v", port)
+	waitForNode(t, url)
+
+	privateKey, err := crypto.HexToECDSA(forkAccountKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostChain, err := Connect(
+		&keystore.Key{
+			Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+			PrivateKey: privateKey,
+		},
+		&Config{
+			Config: ethereum.Config{
+				Config: ethlike.Config{
+					URL:    url,
+					URLRPC: url,
+					ContractAddresses: map[string]string{
+						RelayContractName: contract,
+					},
+				},
+			},
+		},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain, err := btc.Connect(
+		context.Background(),
+		&btc.Config{
+			URL:      os.Getenv("RELAY_FORK_BTC_URL"),
+			Username: os.Getenv("RELAY_FORK_BTC_USERNAME"),
+			Password: os.Getenv("RELAY_FORK_BTC_PASSWORD"),
+		},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hostChain, btcChain
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func waitForNode(t *testing.T, url string) {
+	deadline := time.Now().Add(forkStartTimeout)
+
+	for time.Now().Before(deadline) {
+		client, err := ethclient.Dial(url)
+		if err == nil {
+			_, err = client.ChainID(context.Background())
+			client.Close()
+			if err == nil {
+				return
+			}
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	t.Fatalf("anvil fork did not start within [%v]", forkStartTimeout)
+}
@@ -0,0 +1,213 @@
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// RelayVersionLightV2 is the tBTC v2 LightRelay contract which does not
+// store all headers but only needs a proof at each difficulty retarget.
+const RelayVersionLightV2 RelayVersion = "light-v2"
+
+// lightRelayABI is the subset of the LightRelay contract ABI used by the
+// binding. There are no generated bindings for the LightRelay contract
+// so the required functions are called through a bound contract.
+const lightRelayABI = `[
+	{"inputs":[{"internalType":"bytes","name":"headers","type":"bytes"}],"name":"retarget","outputs":[],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[],"name":"proofLength","outputs":[{"internalType":"uint64","name":"","type":"uint64"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"currentEpoch","outputs":[{"internalType":"uint64","name":"","type":"uint64"}],"stateMutability":"view","type":"function"}
+]`
+
+func init() {
+	registerRelayVersion(&knownRelayVersion{
+		version: RelayVersionLightV2,
+		abi:     lightRelayABI,
+		requiredMethods: []string{
+			"retarget",
+			"proofLength",
+			"currentEpoch",
+		},
+		factory: newLightV2Binding,
+	})
+}
+
+// lightV2Binding is the relay binding for the tBTC v2 LightRelay contract.
+type lightV2Binding struct {
+	contract          *bind.BoundContract
+	callerOptions     *bind.CallOpts
+	transactorOptions *bind.TransactOpts
+	transactionMutex  *sync.Mutex
+}
+
+func newLightV2Binding(
+	address common.Address,
+	dependencies *bindingDependencies,
+) (relayBinding, error) {
+	parsed, err := hostchainabi.JSON(strings.NewReader(lightRelayABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	callerOptions, transactorOptions := boundContractOptions(dependencies)
+
+	return &lightV2Binding{
+		contract: bind.NewBoundContract(
+			address,
+			parsed,
+			dependencies.client,
+			dependencies.client,
+			dependencies.client,
+		),
+		callerOptions:     callerOptions,
+		transactorOptions: transactorOptions,
+		transactionMutex:  dependencies.transactionMutex,
+	}, nil
+}
+
+func (lb *lightV2Binding) GetBestKnownDigest() (btc.Digest, error) {
+	return btc.Digest{}, errUnsupportedByVersion(
+		"GetBestKnownDigest",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) IsAncestor(
+	ancestorDigest btc.Digest,
+	descendantDigest btc.Digest,
+	limit *big.Int,
+) (bool, error) {
+	return false, errUnsupportedByVersion("IsAncestor", RelayVersionLightV2)
+}
+
+func (lb *lightV2Binding) FindHeight(
+	digest btc.Digest,
+	blockNumber *big.Int,
+) (*big.Int, error) {
+	return nil, errUnsupportedByVersion("FindHeight", RelayVersionLightV2)
+}
+
+func (lb *lightV2Binding) FindAncestor(
+	digest btc.Digest,
+	offset *big.Int,
+) (btc.Digest, error) {
+	return btc.Digest{}, errUnsupportedByVersion(
+		"FindAncestor",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) AddHeaders(
+	anchorHeader []byte,
+	headers []byte,
+) (common.Hash, error) {
+	return common.Hash{}, errUnsupportedByVersion(
+		"AddHeaders",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) CallAddHeaders(
+	anchorHeader []byte,
+	headers []byte,
+) (bool, error) {
+	return false, errUnsupportedByVersion(
+		"CallAddHeaders",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) AddHeadersWithRetarget(
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) (common.Hash, error) {
+	return common.Hash{}, errUnsupportedByVersion(
+		"AddHeadersWithRetarget",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) CallAddHeadersWithRetarget(
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) (bool, error) {
+	return false, errUnsupportedByVersion(
+		"CallAddHeadersWithRetarget",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) MarkNewHeaviest(
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) (common.Hash, error) {
+	return common.Hash{}, errUnsupportedByVersion(
+		"MarkNewHeaviest",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) CallMarkNewHeaviest(
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) (bool, error) {
+	return false, errUnsupportedByVersion(
+		"CallMarkNewHeaviest",
+		RelayVersionLightV2,
+	)
+}
+
+func (lb *lightV2Binding) GetProofLength() (uint64, error) {
+	var result uint64
+	err := lb.contract.Call(lb.callerOptions, &result, "proofLength")
+	return result, err
+}
+
+func (lb *lightV2Binding) GetCurrentEpoch() (uint64, error) {
+	var result uint64
+	err := lb.contract.Call(lb.callerOptions, &result, "currentEpoch")
+	return result, err
+}
+
+func (lb *lightV2Binding) Retarget(headers []byte) (common.Hash, error) {
+	lb.transactionMutex.Lock()
+	defer lb.transactionMutex.Unlock()
+
+	transaction, err := lb.contract.Transact(
+		lb.transactorOptions,
+		"retarget",
+		headers,
+	)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return transaction.Hash(), nil
+}
+
+func (lb *lightV2Binding) CallRetarget(headers []byte) error {
+	// The retarget function has no outputs; the call fails only if the
+	// execution reverts.
+	return lb.contract.Call(lb.callerOptions, nil, "retarget", headers)
+}
+
+func (lb *lightV2Binding) PastAdvanceLogs(
+	fromBlock uint64,
+	toBlock uint64,
+) ([]types.Log, error) {
+	return nil, chain.ErrRelayEventsNotSupported
+}
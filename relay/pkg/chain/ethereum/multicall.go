@@ -0,0 +1,454 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/keep-common/pkg/chain/ethlike"
+)
+
+// multicall.go file contains the batching of header pushes of several relay
+// targets through a multicall contract. When several relay targets of the
+// process push the same headers to different relay contracts on the same host
+// chain, with the same operator account, the identical calls are collected
+// for a short time and sent in a single transaction calling the Multicall3
+// `aggregate3` function, so the per-transaction overhead is paid once.
+
+// Default time, in seconds, for which a header push waits for the identical
+// pushes of other relay targets.
+const defaultMulticallWindow = 10
+
+// multicallABI is the subset of the Multicall3 ABI used by the batcher.
+const multicallABI = `[
+	{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}
+]`
+
+// MulticallConfig is the configuration of header pushes batched through
+// a multicall contract.
+type MulticallConfig struct {
+	// Address is the address of the Multicall3 contract identical header
+	// pushes of relay targets sharing the host chain and the operator
+	// account are batched through. If empty, header pushes are not batched.
+	Address string
+
+	// Window is the time, in seconds, for which a header push waits for the
+	// identical pushes of other relay targets. The batch is sent earlier
+	// once all the relay targets join it. If zero, a default value is used.
+	Window int
+}
+
+// IsEnabled checks whether header pushes are batched.
+func (mc *MulticallConfig) IsEnabled() bool {
+	return mc.Address != ""
+}
+
+// multicallCall is a single call of the Multicall3 `aggregate3` function.
+// Field names match the ABI component names.
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// multicallBatch is a batch of identical calls of different contracts.
+type multicallBatch struct {
+	calls []multicallCall
+	done  chan struct{}
+	// id and err are the result of the batch, set before done is closed.
+	id  string
+	err error
+}
+
+// multicallBatcher collects the identical calls of relay targets sharing the
+// host chain and the operator account into batches.
+type multicallBatcher struct {
+	window time.Duration
+	// send sends a transaction calling the given contract with the given
+	// input and returns the transaction hash.
+	send func(ctx context.Context, to common.Address, input []byte) (string, error)
+	pack func(calls []multicallCall) (common.Address, []byte, error)
+
+	mutex   sync.Mutex
+	members int
+	batches map[string]*multicallBatch
+}
+
+// multicallBatchers are the batchers shared by the relay targets of the
+// process, keyed by the host chain, the multicall contract and the operator
+// account. Relay targets connect their host chains independently, so the
+// batchers cannot be passed to them.
+var multicallBatchers = struct {
+	mutex    sync.Mutex
+	batchers map[string]*multicallBatcher
+}{batchers: make(map[string]*multicallBatcher)}
+
+// multicallSubmitter submits calls of the relay contract through the
+// batcher shared with other relay targets.
+type multicallSubmitter struct {
+	address common.Address
+	batcher *multicallBatcher
+}
+
+func newMulticallSubmitter(
+	config *Config,
+	dependencies *bindingDependencies,
+) (*multicallSubmitter, error) {
+	if !common.IsHexAddress(config.Multicall.Address) {
+		return nil, fmt.Errorf(
+			"invalid multicall contract address [%v]",
+			config.Multicall.Address,
+		)
+	}
+
+	address := common.HexToAddress(config.Multicall.Address)
+
+	window := config.Multicall.Window
+	if window <= 0 {
+		window = defaultMulticallWindow
+	}
+
+	key := fmt.Sprintf(
+		"%v/%v/%v",
+		dependencies.chainID,
+		address.Hex(),
+		dependencies.accountKey.Address.Hex(),
+	)
+
+	multicallBatchers.mutex.Lock()
+	defer multicallBatchers.mutex.Unlock()
+
+	batcher, ok := multicallBatchers.batchers[key]
+	if !ok {
+		parsedABI, err := hostchainabi.JSON(strings.NewReader(multicallABI))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse multicall ABI: [%v]", err)
+		}
+
+		sender := &transactionSender{
+			dependencies: dependencies,
+			timeout:      requestTimeout(config),
+		}
+
+		batcher = newMulticallBatcher(
+			time.Duration(window)*time.Second,
+			sender.send,
+			func(calls []multicallCall) (common.Address, []byte, error) {
+				input, err := parsedABI.Pack("aggregate3", calls)
+				return address, input, err
+			},
+		)
+		multicallBatchers.batchers[key] = batcher
+	}
+
+	batcher.join()
+
+	return &multicallSubmitter{address: address, batcher: batcher}, nil
+}
+
+func (ms *multicallSubmitter) String() string {
+	return fmt.Sprintf("multicall contract [%v]", ms.address.Hex())
+}
+
+func (ms *multicallSubmitter) submit(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+) (string, error) {
+	return ms.batcher.submit(ctx, to, input)
+}
+
+func newMulticallBatcher(
+	window time.Duration,
+	send func(ctx context.Context, to common.Address, input []byte) (string, error),
+	pack func(calls []multicallCall) (common.Address, []byte, error),
+) *multicallBatcher {
+	return &multicallBatcher{
+		window:  window,
+		send:    send,
+		pack:    pack,
+		batches: make(map[string]*multicallBatch),
+	}
+}
+
+// join registers a relay target submitting calls through the batcher.
+func (mb *multicallBatcher) join() {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	mb.members++
+}
+
+// submit adds the call of the given contract to the batch of calls with the
+// same input and waits until the batch is sent. The batch is sent once all
+// the relay targets join it or the window elapses. If the context is done
+// before, the call is still sent with the batch.
+func (mb *multicallBatcher) submit(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+) (string, error) {
+	mb.mutex.Lock()
+
+	key := string(input)
+
+	batch, ok := mb.batches[key]
+	if !ok {
+		batch = &multicallBatch{done: make(chan struct{})}
+		mb.batches[key] = batch
+
+		time.AfterFunc(mb.window, func() { mb.flush(key, batch) })
+	}
+
+	// The same call is already in the batch, e.g. if the push has been
+	// retried; it shares the result of the batch.
+	duplicate := false
+	for _, call := range batch.calls {
+		if call.Target == to {
+			duplicate = true
+			break
+		}
+	}
+
+	if !duplicate {
+		batch.calls = append(batch.calls, multicallCall{
+			Target: to,
+			// Other contracts of the batch are still advanced if one of
+			// them rejects the call, e.g. because another maintainer has
+			// already pushed the headers.
+			AllowFailure: true,
+			CallData:     input,
+		})
+	}
+
+	full := len(batch.calls) >= mb.members
+
+	mb.mutex.Unlock()
+
+	if full {
+		mb.flush(key, batch)
+	}
+
+	select {
+	case <-batch.done:
+		return batch.id, batch.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// flush sends the given batch unless it has been sent already.
+func (mb *multicallBatcher) flush(key string, batch *multicallBatch) {
+	mb.mutex.Lock()
+	if mb.batches[key] != batch {
+		mb.mutex.Unlock()
+		return
+	}
+	delete(mb.batches, key)
+	mb.mutex.Unlock()
+
+	defer close(batch.done)
+
+	// A single call is sent directly, as the multicall would only add to its
+	// cost.
+	to := batch.calls[0].Target
+	input := batch.calls[0].CallData
+
+	if len(batch.calls) > 1 {
+		var err error
+		to, input, err = mb.pack(batch.calls)
+		if err != nil {
+			batch.err = fmt.Errorf("could not encode multicall: [%v]", err)
+			return
+		}
+	}
+
+	batch.id, batch.err = mb.send(context.Background(), to, input)
+}
+
+// transactionSender sends transactions with arbitrary input from the
+// operator account, sharing the nonce management and gas price bumping with
+// the relay contract bindings.
+type transactionSender struct {
+	dependencies *bindingDependencies
+	timeout      time.Duration
+}
+
+// Interval in which the receipts of transactions awaited by the sender are
+// checked.
+const senderMiningCheckInterval = 5 * time.Second
+
+func (ts *transactionSender) send(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+) (string, error) {
+	transaction, err := ts.submit(ctx, to, input, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return common.Hash(transaction.Hash).Hex(), nil
+}
+
+// sendAndWait sends the transaction like send does and waits until the
+// transaction, or any of its resubmissions with a bumped gas price, is mined.
+// Reverted transactions are reported as errors.
+func (ts *transactionSender) sendAndWait(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+) (*types.Receipt, error) {
+	sentMutex := &sync.Mutex{}
+	sent := make([]common.Hash, 0)
+
+	_, err := ts.submit(ctx, to, input, func(hash common.Hash) {
+		sentMutex.Lock()
+		defer sentMutex.Unlock()
+
+		sent = append(sent, hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(senderMiningCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sentMutex.Lock()
+			hashes := append([]common.Hash{}, sent...)
+			sentMutex.Unlock()
+
+			for _, hash := range hashes {
+				receipt, err := ts.dependencies.client.TransactionReceipt(
+					ctx,
+					hash,
+				)
+				if err != nil || receipt == nil {
+					continue
+				}
+
+				if receipt.Status == types.ReceiptStatusFailed {
+					return nil, fmt.Errorf(
+						"transaction [%v] reverted",
+						hash.Hex(),
+					)
+				}
+
+				return receipt, nil
+			}
+		case <-ctx.Done():
+			return nil, fmt.Errorf(
+				"transaction to [%v] not mined: [%v]",
+				to.Hex(),
+				ctx.Err(),
+			)
+		}
+	}
+}
+
+// submit sends the transaction, starts bumping its gas price until it is
+// mined and increments the account nonce. The optional onSent function is
+// called with the hash of the transaction and each of its resubmissions.
+func (ts *transactionSender) submit(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+	onSent func(hash common.Hash),
+) (*ethlike.Transaction, error) {
+	ctx, cancelCtx := context.WithTimeout(ctx, ts.timeout)
+	defer cancelCtx()
+
+	dependencies := ts.dependencies
+	client := dependencies.client
+	from := dependencies.accountKey.Address
+
+	dependencies.transactionMutex.Lock()
+	defer dependencies.transactionMutex.Unlock()
+
+	nonce, err := dependencies.nonceManager.CurrentNonce()
+	if err != nil {
+		return nil, fmt.Errorf("could not get account nonce: [%v]", err)
+	}
+
+	gasLimit, err := client.EstimateGas(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &to,
+		Data: input,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not estimate gas: [%v]", err)
+	}
+
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get gas price: [%v]", err)
+	}
+
+	signer := types.NewEIP155Signer(dependencies.chainID)
+
+	sendAt := func(gasPrice *big.Int) (*ethlike.Transaction, error) {
+		transaction, err := types.SignTx(
+			types.NewTransaction(
+				nonce,
+				to,
+				big.NewInt(0),
+				gasLimit,
+				gasPrice,
+				input,
+			),
+			signer,
+			dependencies.accountKey.PrivateKey,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not sign transaction: [%v]", err)
+		}
+
+		sendCtx, cancelSendCtx := context.WithTimeout(
+			context.Background(),
+			ts.timeout,
+		)
+		defer cancelSendCtx()
+
+		if err := client.SendTransaction(sendCtx, transaction); err != nil {
+			return nil, err
+		}
+
+		dependencies.logger.Infof(
+			"submitted transaction to [%v] with hash [%v] and nonce [%v]",
+			to.Hex(),
+			transaction.Hash().Hex(),
+			nonce,
+		)
+
+		if onSent != nil {
+			onSent(transaction.Hash())
+		}
+
+		return &ethlike.Transaction{
+			Hash:     ethlike.Hash(transaction.Hash()),
+			GasPrice: transaction.GasPrice(),
+		}, nil
+	}
+
+	transaction, err := sendAt(gasPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	go dependencies.miningWaiter.ForceMining(transaction, sendAt)
+
+	dependencies.nonceManager.IncrementNonce()
+
+	return transaction, nil
+}
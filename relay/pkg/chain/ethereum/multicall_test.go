@@ -0,0 +1,86 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMulticallBatcher(t *testing.T) {
+	multicall := common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+	relayA := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	relayB := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	parsedABI, err := hostchainabi.JSON(strings.NewReader(multicallABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	sent := make(map[common.Address][][]byte)
+
+	batcher := newMulticallBatcher(
+		50*time.Millisecond,
+		func(
+			ctx context.Context,
+			to common.Address,
+			input []byte,
+		) (string, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			sent[to] = append(sent[to], input)
+			return "0x01", nil
+		},
+		func(calls []multicallCall) (common.Address, []byte, error) {
+			input, err := parsedABI.Pack("aggregate3", calls)
+			return multicall, input, err
+		},
+	)
+	batcher.join()
+	batcher.join()
+
+	submit := func(to common.Address, input []byte) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			_, err := batcher.submit(context.Background(), to, input)
+			result <- err
+		}()
+		return result
+	}
+
+	// Identical calls of both relay targets are batched.
+	resultA := submit(relayA, []byte{0x01})
+	resultB := submit(relayB, []byte{0x01})
+	for _, result := range []<-chan error{resultA, resultB} {
+		if err := <-result; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sent[multicall]) != 1 || len(sent[relayA]) != 0 ||
+		len(sent[relayB]) != 0 {
+		t.Fatalf("identical calls have not been batched: [%v]", sent)
+	}
+
+	selector := parsedABI.Methods["aggregate3"].ID()
+	if !bytes.HasPrefix(sent[multicall][0], selector) {
+		t.Errorf("batch does not call the aggregate3 function")
+	}
+
+	// A call not joined by the other relay target is sent directly once the
+	// window elapses.
+	if err := <-submit(relayA, []byte{0x02}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent[relayA]) != 1 || !bytes.Equal(sent[relayA][0], []byte{0x02}) {
+		t.Errorf("single call has not been sent directly: [%v]", sent)
+	}
+}
@@ -0,0 +1,136 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// pause.go file contains the detection of the emergency pause of the relay
+// and tBTC system contracts. Contracts implementing the pausable ABI expose
+// their pause flag; the support is detected by inspecting the deployed code
+// so contracts which cannot be paused are never checked.
+
+// pausableABI is the subset of the pausable contract ABI used by the binding.
+const pausableABI = `[
+	{"inputs":[],"name":"paused","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"}
+]`
+
+// pausableBinding is the binding of a contract which can be paused.
+type pausableBinding struct {
+	name          string
+	contract      *bind.BoundContract
+	callerOptions *bind.CallOpts
+}
+
+// connectPausable creates the bindings of the relay contract and, if
+// configured, the tBTC system contract, which implement the pausable ABI.
+func connectPausable(
+	config *Config,
+	relayAddress common.Address,
+	dependencies *bindingDependencies,
+) ([]*pausableBinding, error) {
+	addresses := map[string]common.Address{chain.ContractRelay: relayAddress}
+	if address, err := config.ContractAddress(
+		TBTCSystemContractName,
+	); err == nil {
+		addresses[chain.ContractTBTCSystem] = address
+	}
+
+	parsed, err := hostchainabi.JSON(strings.NewReader(pausableABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	callerOptions, _ := boundContractOptions(dependencies)
+
+	bindings := make([]*pausableBinding, 0)
+	for _, name := range []string{
+		chain.ContractRelay,
+		chain.ContractTBTCSystem,
+	} {
+		address, ok := addresses[name]
+		if !ok {
+			continue
+		}
+
+		ctx, cancelCtx := context.WithTimeout(
+			context.Background(),
+			requestTimeout(config),
+		)
+		code, err := dependencies.client.CodeAt(ctx, address, nil)
+		cancelCtx()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not get code of contract [%v]: [%v]",
+				address.Hex(),
+				err,
+			)
+		}
+
+		implements, err := codeImplements(code, pausableABI, []string{"paused"})
+		if err != nil {
+			return nil, err
+		}
+
+		if !implements {
+			continue
+		}
+
+		dependencies.logger.Infof(
+			"contract [%v] at [%v] can be paused; watching its pause flag",
+			name,
+			address.Hex(),
+		)
+
+		bindings = append(bindings, &pausableBinding{
+			name: name,
+			contract: bind.NewBoundContract(
+				address,
+				parsed,
+				dependencies.client,
+				dependencies.client,
+				dependencies.client,
+			),
+			callerOptions: callerOptions,
+		})
+	}
+
+	return bindings, nil
+}
+
+// PausedContracts returns the names of the currently paused contracts.
+// Contracts which cannot be paused are never returned.
+func (ec *ethereumChain) PausedContracts(ctx context.Context) ([]string, error) {
+	paused := make([]string, 0)
+
+	for _, binding := range ec.pausable {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var result bool
+		if err := binding.contract.Call(
+			binding.callerOptions,
+			&result,
+			"paused",
+		); err != nil {
+			return nil, fmt.Errorf(
+				"could not check whether contract [%v] is paused: [%v]",
+				binding.name,
+				err,
+			)
+		}
+
+		if result {
+			paused = append(paused, binding.name)
+		}
+	}
+
+	return paused, nil
+}
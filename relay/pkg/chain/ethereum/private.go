@@ -0,0 +1,245 @@
+package ethereum
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// private.go file contains the client wrapper which submits transactions
+// through a private transaction relay, like Flashbots Protect, instead of
+// the public mempool. Transactions sent to the public mempool can be seen
+// by MEV bots which copy them to snipe the relay rewards. A nonce not mined
+// within the fallback timeout from its first private submission has its
+// latest transaction broadcast to the public mempool, so the relay does not
+// stall if the private relay does not include it.
+
+var (
+	// DefaultPrivateTransactionsURL is the default URL of the private
+	// transaction relay. It points to the Flashbots Protect RPC endpoint.
+	DefaultPrivateTransactionsURL = "https://rpc.flashbots.net"
+
+	// DefaultPrivateTransactionsFallbackTimeout is the default time after
+	// which a transaction submitted privately and still not mined is
+	// broadcast to the public mempool.
+	DefaultPrivateTransactionsFallbackTimeout = 3 * time.Minute
+)
+
+// PrivateTransactionsConfig is the configuration of transaction submission
+// through a private transaction relay.
+type PrivateTransactionsConfig struct {
+	// Enabled determines whether transactions are submitted through the
+	// private transaction relay.
+	Enabled bool
+
+	// URL is the RPC URL of the private transaction relay accepting
+	// `eth_sendRawTransaction` requests. If empty, Flashbots Protect is used.
+	URL string
+
+	// FallbackTimeout is the time, in seconds, after which a transaction
+	// still not mined is broadcast to the public mempool. If zero, a default
+	// value is used.
+	FallbackTimeout int
+}
+
+func (ptc *PrivateTransactionsConfig) url() string {
+	if ptc.URL != "" {
+		return ptc.URL
+	}
+
+	return DefaultPrivateTransactionsURL
+}
+
+func (ptc *PrivateTransactionsConfig) fallbackTimeout() time.Duration {
+	if ptc.FallbackTimeout > 0 {
+		return time.Duration(ptc.FallbackTimeout) * time.Second
+	}
+
+	return DefaultPrivateTransactionsFallbackTimeout
+}
+
+// privateSubmissionClient is a client wrapper which sends transactions
+// through the private transaction relay and falls back to the public client
+// for transactions not mined in time.
+type privateSubmissionClient struct {
+	ethutil.EthereumClient
+
+	privateClient   ethutil.EthereumClient
+	fallbackTimeout time.Duration
+	requestTimeout  time.Duration
+	logger          logs.Logger
+
+	mutex sync.Mutex
+	// nonces holds the transactions sent at each nonce not mined yet.
+	nonces map[uint64]*privateNonce
+}
+
+// privateNonce holds the transactions sent at a single nonce. The fallback
+// timeout is measured from the first private submission at the nonce, so
+// resubmissions with a higher gas price do not postpone the fallback.
+type privateNonce struct {
+	// latest is the latest transaction sent at the nonce. Resubmissions
+	// replace earlier transactions, so only the latest one is broadcast
+	// publicly.
+	latest *types.Transaction
+	// sent holds the hashes of all transactions sent at the nonce, as any
+	// of them can get mined.
+	sent []common.Hash
+	// public is set once the fallback timeout expires. Subsequent
+	// resubmissions are sent to the public mempool right away.
+	public bool
+}
+
+// wrapPrivateSubmission wraps the given public client so all transactions
+// are sent through the given private client first.
+func wrapPrivateSubmission(
+	publicClient ethutil.EthereumClient,
+	privateClient ethutil.EthereumClient,
+	fallbackTimeout time.Duration,
+	requestTimeout time.Duration,
+	logger logs.Logger,
+) *privateSubmissionClient {
+	return &privateSubmissionClient{
+		EthereumClient:  publicClient,
+		privateClient:   privateClient,
+		fallbackTimeout: fallbackTimeout,
+		requestTimeout:  requestTimeout,
+		logger:          logger,
+		nonces:          make(map[uint64]*privateNonce),
+	}
+}
+
+func (psc *privateSubmissionClient) SendTransaction(
+	ctx context.Context,
+	tx *types.Transaction,
+) error {
+	nonce := tx.Nonce()
+
+	psc.mutex.Lock()
+	pending, ok := psc.nonces[nonce]
+	if !ok {
+		pending = &privateNonce{}
+		psc.nonces[nonce] = pending
+
+		time.AfterFunc(psc.fallbackTimeout, func() {
+			psc.fallback(nonce)
+		})
+	}
+	pending.latest = tx
+	pending.sent = append(pending.sent, tx.Hash())
+	public := pending.public
+	psc.mutex.Unlock()
+
+	if public {
+		psc.logger.Infof(
+			"fallback timeout of nonce [%v] has expired; submitting "+
+				"transaction [%v] to the public mempool",
+			nonce,
+			tx.Hash().TerminalString(),
+		)
+
+		return psc.EthereumClient.SendTransaction(ctx, tx)
+	}
+
+	if err := psc.privateClient.SendTransaction(ctx, tx); err != nil {
+		psc.logger.Warnf(
+			"could not submit transaction [%v] privately; "+
+				"submitting to the public mempool: [%v]",
+			tx.Hash().TerminalString(),
+			err,
+		)
+
+		return psc.EthereumClient.SendTransaction(ctx, tx)
+	}
+
+	psc.logger.Infof(
+		"submitted transaction [%v] through the private transaction relay",
+		tx.Hash().TerminalString(),
+	)
+
+	return nil
+}
+
+// fallback broadcasts the latest transaction sent at the given nonce to the
+// public mempool unless any transaction sent at the nonce has been mined.
+// The nonce is checked again after another timeout until it is mined, so
+// its state is released.
+func (psc *privateSubmissionClient) fallback(nonce uint64) {
+	psc.mutex.Lock()
+	pending, ok := psc.nonces[nonce]
+	if !ok {
+		psc.mutex.Unlock()
+		return
+	}
+	tx := pending.latest
+	sent := append([]common.Hash{}, pending.sent...)
+	broadcast := !pending.public
+	pending.public = true
+	psc.mutex.Unlock()
+
+	ctx, cancelCtx := context.WithTimeout(
+		context.Background(),
+		psc.requestTimeout,
+	)
+	defer cancelCtx()
+
+	if psc.isMined(ctx, sent) {
+		psc.mutex.Lock()
+		delete(psc.nonces, nonce)
+		psc.mutex.Unlock()
+		return
+	}
+
+	time.AfterFunc(psc.fallbackTimeout, func() {
+		psc.fallback(nonce)
+	})
+
+	if !broadcast {
+		return
+	}
+
+	psc.logger.Warnf(
+		"transaction [%v] has not been mined within [%v] after the first "+
+			"private submission at nonce [%v]; submitting to the public "+
+			"mempool",
+		tx.Hash().TerminalString(),
+		psc.fallbackTimeout,
+		nonce,
+	)
+
+	if err := psc.EthereumClient.SendTransaction(ctx, tx); err != nil {
+		psc.logger.Warnf(
+			"could not submit transaction [%v] to the public mempool: [%v]",
+			tx.Hash().TerminalString(),
+			err,
+		)
+	}
+}
+
+// isMined checks whether any of the given transactions has been mined.
+func (psc *privateSubmissionClient) isMined(
+	ctx context.Context,
+	txHashes []common.Hash,
+) bool {
+	for _, txHash := range txHashes {
+		_, err := psc.EthereumClient.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			return true
+		}
+		if err != ethereum.NotFound {
+			psc.logger.Warnf(
+				"could not check receipt of transaction [%v]: [%v]",
+				txHash.TerminalString(),
+				err,
+			)
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,209 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+)
+
+// recordingClient is a client which records all sent transactions and
+// returns receipts only for the transactions marked as mined.
+type recordingClient struct {
+	ethutil.EthereumClient
+
+	sendErr error
+
+	mutex sync.Mutex
+	sent  []common.Hash
+	mined map[common.Hash]bool
+}
+
+func (rc *recordingClient) SendTransaction(
+	ctx context.Context,
+	tx *types.Transaction,
+) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if rc.sendErr != nil {
+		return rc.sendErr
+	}
+
+	rc.sent = append(rc.sent, tx.Hash())
+	return nil
+}
+
+func (rc *recordingClient) TransactionReceipt(
+	ctx context.Context,
+	txHash common.Hash,
+) (*types.Receipt, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if rc.mined[txHash] {
+		return &types.Receipt{TxHash: txHash}, nil
+	}
+
+	return nil, ethereum.NotFound
+}
+
+func (rc *recordingClient) sentTransactions() []common.Hash {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return append([]common.Hash{}, rc.sent...)
+}
+
+func TestPrivateSubmission(t *testing.T) {
+	newTransaction := func(nonce uint64, gasPrice int64) *types.Transaction {
+		return types.NewTransaction(
+			nonce,
+			common.Address{},
+			big.NewInt(0),
+			cancellationGasLimit,
+			big.NewInt(gasPrice),
+			nil,
+		)
+	}
+
+	minedTransaction := newTransaction(1, 10)
+	pendingTransaction := newTransaction(2, 10)
+	replacedTransaction := newTransaction(3, 10)
+	replacementTransaction := newTransaction(3, 12)
+
+	var tests = map[string]struct {
+		transactions   []*types.Transaction
+		privateErr     error
+		expectedPublic []common.Hash
+	}{
+		"mined transaction": {
+			transactions:   []*types.Transaction{minedTransaction},
+			expectedPublic: []common.Hash{},
+		},
+		"pending transaction": {
+			transactions:   []*types.Transaction{pendingTransaction},
+			expectedPublic: []common.Hash{pendingTransaction.Hash()},
+		},
+		"replaced transaction": {
+			transactions: []*types.Transaction{
+				replacedTransaction,
+				replacementTransaction,
+			},
+			expectedPublic: []common.Hash{replacementTransaction.Hash()},
+		},
+		"private relay failure": {
+			transactions:   []*types.Transaction{minedTransaction},
+			privateErr:     fmt.Errorf("unavailable"),
+			expectedPublic: []common.Hash{minedTransaction.Hash()},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			publicClient := &recordingClient{
+				mined: map[common.Hash]bool{minedTransaction.Hash(): true},
+			}
+			privateClient := &recordingClient{sendErr: test.privateErr}
+
+			client := wrapPrivateSubmission(
+				publicClient,
+				privateClient,
+				10*time.Millisecond,
+				time.Second,
+				log.Logger(loggerName),
+			)
+
+			for _, transaction := range test.transactions {
+				err := client.SendTransaction(context.Background(), transaction)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			time.Sleep(100 * time.Millisecond)
+
+			actualPublic := publicClient.sentTransactions()
+			if fmt.Sprintf("%v", test.expectedPublic) !=
+				fmt.Sprintf("%v", actualPublic) {
+				t.Errorf(
+					"unexpected public transactions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedPublic,
+					actualPublic,
+				)
+			}
+		})
+	}
+}
+
+func TestPrivateSubmission_FallbackFromFirstSubmission(t *testing.T) {
+	newTransaction := func(gasPrice int64) *types.Transaction {
+		return types.NewTransaction(
+			4,
+			common.Address{},
+			big.NewInt(0),
+			cancellationGasLimit,
+			big.NewInt(gasPrice),
+			nil,
+		)
+	}
+
+	transaction := newTransaction(10)
+	firstBump := newTransaction(12)
+	secondBump := newTransaction(14)
+
+	publicClient := &recordingClient{mined: map[common.Hash]bool{}}
+	privateClient := &recordingClient{}
+
+	client := wrapPrivateSubmission(
+		publicClient,
+		privateClient,
+		50*time.Millisecond,
+		time.Second,
+		log.Logger(loggerName),
+	)
+
+	send := func(tx *types.Transaction) {
+		if err := client.SendTransaction(context.Background(), tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertPublic := func(expectedPublic ...common.Hash) {
+		actualPublic := publicClient.sentTransactions()
+		if fmt.Sprintf("%v", expectedPublic) !=
+			fmt.Sprintf("%v", actualPublic) {
+			t.Errorf(
+				"unexpected public transactions:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				expectedPublic,
+				actualPublic,
+			)
+		}
+	}
+
+	send(transaction)
+	time.Sleep(30 * time.Millisecond)
+
+	// The bump does not postpone the fallback of the nonce.
+	send(firstBump)
+	time.Sleep(40 * time.Millisecond)
+
+	assertPublic(firstBump.Hash())
+
+	// Bumps sent once the fallback timeout expired go public right away.
+	send(secondBump)
+
+	assertPublic(firstBump.Hash(), secondBump.Hash())
+}
@@ -0,0 +1,220 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// rewards.go file contains the binding of relay contracts paying rewards for
+// header submissions. Rewards are exposed by the relay contract itself or by
+// a separate contract configured as RelayRewards in the contract addresses.
+// The support is detected by inspecting the deployed code so relay contracts
+// not paying rewards keep working as before.
+
+// RelayRewardsContractName defines the name of the optional contract paying
+// relay rewards. If its address is not configured, the relay contract is
+// expected to pay the rewards.
+const RelayRewardsContractName = "RelayRewards"
+
+// relayRewardsABI is the subset of the relay rewards ABI used by the binding.
+const relayRewardsABI = `[
+	{"inputs":[{"internalType":"address","name":"relayer","type":"address"}],"name":"pendingRewards","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"claimRewards","outputs":[],"stateMutability":"nonpayable","type":"function"}
+]`
+
+// Maximum time a rewards claim waits for its transaction to be mined.
+const claimMiningTimeout = 1 * time.Hour
+
+// rewardsBinding is the binding of the contract paying relay rewards.
+// Claims are sent through the transaction sender, sharing the nonce
+// management and gas price bumping with the relay contract bindings.
+type rewardsBinding struct {
+	address       common.Address
+	abi           hostchainabi.ABI
+	contract      *bind.BoundContract
+	callerOptions *bind.CallOpts
+	sender        *transactionSender
+}
+
+// newRewardsBinding creates the relay rewards binding for the contract
+// deployed at the given address. Nil is returned if the deployed code does
+// not implement the relay rewards ABI.
+func newRewardsBinding(
+	address common.Address,
+	code []byte,
+	dependencies *bindingDependencies,
+	timeout time.Duration,
+) (*rewardsBinding, error) {
+	implements, err := codeImplements(
+		code,
+		relayRewardsABI,
+		[]string{"pendingRewards", "claimRewards"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !implements {
+		return nil, nil
+	}
+
+	parsed, err := hostchainabi.JSON(strings.NewReader(relayRewardsABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	callerOptions, _ := boundContractOptions(dependencies)
+
+	return &rewardsBinding{
+		address: address,
+		abi:     parsed,
+		contract: bind.NewBoundContract(
+			address,
+			parsed,
+			dependencies.client,
+			dependencies.client,
+			dependencies.client,
+		),
+		callerOptions: callerOptions,
+		sender: &transactionSender{
+			dependencies: dependencies,
+			timeout:      timeout,
+		},
+	}, nil
+}
+
+// resolveRewardsAddress returns the address of the contract paying relay
+// rewards. If no such contract is configured, the relay contract address
+// is returned.
+func resolveRewardsAddress(
+	config *Config,
+	relayAddress common.Address,
+) (common.Address, error) {
+	if _, ok := config.ContractAddresses[RelayRewardsContractName]; !ok {
+		return relayAddress, nil
+	}
+
+	return config.ContractAddress(RelayRewardsContractName)
+}
+
+// connectRewards creates the relay rewards binding if the contract paying the
+// rewards implements the relay rewards ABI. Nil is returned otherwise.
+func connectRewards(
+	config *Config,
+	relayAddress common.Address,
+	dependencies *bindingDependencies,
+) (*rewardsBinding, error) {
+	address, err := resolveRewardsAddress(config, relayAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelCtx := context.WithTimeout(
+		context.Background(),
+		requestTimeout(config),
+	)
+	defer cancelCtx()
+
+	code, err := dependencies.client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get code of contract [%v]: [%v]",
+			address.Hex(),
+			err,
+		)
+	}
+
+	rewards, err := newRewardsBinding(
+		address,
+		code,
+		dependencies,
+		requestTimeout(config),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if rewards != nil {
+		dependencies.logger.Infof(
+			"relay rewards are paid by contract [%v]",
+			address.Hex(),
+		)
+	}
+
+	return rewards, nil
+}
+
+// PendingRewards returns the rewards accrued by the operator and not
+// claimed yet.
+func (ec *ethereumChain) PendingRewards(ctx context.Context) (*big.Int, error) {
+	if ec.rewards == nil {
+		return nil, chain.ErrRewardsNotSupported
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result *big.Int
+	err := ec.rewards.contract.Call(
+		ec.rewards.callerOptions,
+		&result,
+		"pendingRewards",
+		ec.rewards.callerOptions.From,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ClaimRewards submits a transaction claiming all rewards accrued by the
+// operator and waits until it is mined. The gas price of the transaction is
+// bumped like the gas price of header submissions.
+func (ec *ethereumChain) ClaimRewards(ctx context.Context) error {
+	if ec.rewards == nil {
+		return chain.ErrRewardsNotSupported
+	}
+
+	if ec.watchOnly {
+		return errWatchOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	input, err := ec.rewards.abi.Pack("claimRewards")
+	if err != nil {
+		return fmt.Errorf("could not pack claimRewards input: [%v]", err)
+	}
+
+	ctx, cancelCtx := context.WithTimeout(ctx, claimMiningTimeout)
+	defer cancelCtx()
+
+	receipt, err := ec.rewards.sender.sendAndWait(
+		ctx,
+		ec.rewards.address,
+		input,
+	)
+	if err != nil {
+		return err
+	}
+
+	ec.logger.Infof(
+		"ClaimRewards transaction [%v] mined in block [%v]",
+		receipt.TxHash.Hex(),
+		receipt.BlockNumber,
+	)
+
+	return nil
+}
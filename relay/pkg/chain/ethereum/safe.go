@@ -0,0 +1,338 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// safe.go file contains the multisig operation mode, in which header pushes
+// are not sent by the operator account but proposed to a Gnosis Safe through
+// the Safe transaction service. Each header push becomes a Safe transaction
+// calling the relay contract, signed with the operator key, which must be
+// one of the Safe owners. The push reaches the relay contract once enough
+// owners approve and execute the proposed transaction.
+
+// safeProposalOrigin is the origin of the proposed Safe transactions shown
+// by the Safe user interfaces.
+const safeProposalOrigin = "tbtc-relay"
+
+var (
+	// safeDomainTypeHash is the EIP-712 type hash of the Safe domain.
+	safeDomainTypeHash = crypto.Keccak256Hash(
+		[]byte("EIP712Domain(uint256 chainId,address verifyingContract)"),
+	)
+
+	// safeTxTypeHash is the EIP-712 type hash of the Safe transaction.
+	safeTxTypeHash = crypto.Keccak256Hash([]byte(
+		"SafeTx(address to,uint256 value,bytes data,uint8 operation," +
+			"uint256 safeTxGas,uint256 baseGas,uint256 gasPrice," +
+			"address gasToken,address refundReceiver,uint256 nonce)",
+	))
+)
+
+// MultisigConfig is the configuration of the multisig operation mode.
+type MultisigConfig struct {
+	// Safe is the address of the Gnosis Safe header pushes are proposed to.
+	// If empty, the multisig operation mode is disabled.
+	Safe string
+
+	// ServiceURL is the URL of the Safe transaction service of the host
+	// chain, e.g. `https://safe-transaction-mainnet.safe.global`.
+	ServiceURL string
+}
+
+// IsEnabled checks whether header pushes are proposed to a Safe.
+func (mc *MultisigConfig) IsEnabled() bool {
+	return mc.Safe != ""
+}
+
+// safeSubmitter proposes calls of host chain contracts as transactions of
+// a Safe through the Safe transaction service.
+type safeSubmitter struct {
+	safe       common.Address
+	serviceURL string
+	chainID    *big.Int
+	accountKey *keystore.Key
+	httpClient *http.Client
+
+	// Proposals are numbered by consecutive Safe nonces, so they must not be
+	// made concurrently.
+	mutex sync.Mutex
+}
+
+func newSafeSubmitter(
+	config *Config,
+	dependencies *bindingDependencies,
+) (*safeSubmitter, error) {
+	multisigConfig := &config.Multisig
+
+	if !common.IsHexAddress(multisigConfig.Safe) {
+		return nil, fmt.Errorf(
+			"invalid Safe address [%v]",
+			multisigConfig.Safe,
+		)
+	}
+
+	if multisigConfig.ServiceURL == "" {
+		return nil, fmt.Errorf("Safe transaction service URL is not set")
+	}
+
+	return &safeSubmitter{
+		safe:       common.HexToAddress(multisigConfig.Safe),
+		serviceURL: strings.TrimSuffix(multisigConfig.ServiceURL, "/"),
+		chainID:    dependencies.chainID,
+		accountKey: dependencies.accountKey,
+		httpClient: &http.Client{Timeout: requestTimeout(config)},
+	}, nil
+}
+
+func (ss *safeSubmitter) String() string {
+	return "Safe transaction service"
+}
+
+// safeTransaction is the proposed Safe transaction in the format of the
+// Safe transaction service API. Proposals never refund the executor, so
+// the gas and refund related fields are always zero.
+type safeTransaction struct {
+	To                      common.Address `json:"to"`
+	Value                   string         `json:"value"`
+	Data                    hexutil.Bytes  `json:"data"`
+	Operation               int            `json:"operation"`
+	SafeTxGas               string         `json:"safeTxGas"`
+	BaseGas                 string         `json:"baseGas"`
+	GasPrice                string         `json:"gasPrice"`
+	GasToken                common.Address `json:"gasToken"`
+	RefundReceiver          common.Address `json:"refundReceiver"`
+	Nonce                   uint64         `json:"nonce"`
+	ContractTransactionHash common.Hash    `json:"contractTransactionHash"`
+	Sender                  common.Address `json:"sender"`
+	Signature               hexutil.Bytes  `json:"signature"`
+	Origin                  string         `json:"origin"`
+}
+
+// hash returns the EIP-712 hash of the Safe transaction signed by the Safe
+// owners.
+func (st *safeTransaction) hash(
+	safe common.Address,
+	chainID *big.Int,
+) common.Hash {
+	domainSeparator := crypto.Keccak256(
+		safeDomainTypeHash.Bytes(),
+		common.LeftPadBytes(chainID.Bytes(), 32),
+		common.LeftPadBytes(safe.Bytes(), 32),
+	)
+
+	zero := make([]byte, 32)
+
+	structHash := crypto.Keccak256(
+		safeTxTypeHash.Bytes(),
+		common.LeftPadBytes(st.To.Bytes(), 32),
+		zero, // value
+		crypto.Keccak256(st.Data),
+		zero, // operation
+		zero, // safeTxGas
+		zero, // baseGas
+		zero, // gasPrice
+		common.LeftPadBytes(st.GasToken.Bytes(), 32),
+		common.LeftPadBytes(st.RefundReceiver.Bytes(), 32),
+		common.LeftPadBytes(new(big.Int).SetUint64(st.Nonce).Bytes(), 32),
+	)
+
+	return crypto.Keccak256Hash(
+		[]byte{0x19, 0x01},
+		domainSeparator,
+		structHash,
+	)
+}
+
+func (ss *safeSubmitter) submit(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+) (string, error) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	nonce, err := ss.nextNonce(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not get next Safe nonce: [%v]", err)
+	}
+
+	transaction := &safeTransaction{
+		To:        to,
+		Value:     "0",
+		Data:      input,
+		SafeTxGas: "0",
+		BaseGas:   "0",
+		GasPrice:  "0",
+		Nonce:     nonce,
+		Sender:    ss.accountKey.Address,
+		Origin:    safeProposalOrigin,
+	}
+
+	transaction.ContractTransactionHash = transaction.hash(ss.safe, ss.chainID)
+
+	signature, err := crypto.Sign(
+		transaction.ContractTransactionHash.Bytes(),
+		ss.accountKey.PrivateKey,
+	)
+	if err != nil {
+		return "", fmt.Errorf("could not sign Safe transaction: [%v]", err)
+	}
+	// The recovery ID is expected in the legacy Ethereum format.
+	signature[crypto.RecoveryIDOffset] += 27
+	transaction.Signature = signature
+
+	if err := ss.request(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf(
+			"/api/v1/safes/%v/multisig-transactions/",
+			ss.safe.Hex(),
+		),
+		transaction,
+		nil,
+	); err != nil {
+		return "", fmt.Errorf("could not propose Safe transaction: [%v]", err)
+	}
+
+	return transaction.ContractTransactionHash.Hex(), nil
+}
+
+// safeInfo is the part of the Safe details returned by the Safe transaction
+// service.
+type safeInfo struct {
+	Nonce rpcQuantity `json:"nonce"`
+}
+
+// safeTransactions is the part of the page of Safe transactions returned by
+// the Safe transaction service.
+type safeTransactions struct {
+	Results []struct {
+		Nonce rpcQuantity `json:"nonce"`
+	} `json:"results"`
+}
+
+// nextNonce returns the nonce of the next proposal. It follows the nonce of
+// the latest proposal not executed yet, including proposals made by others,
+// or is the current Safe nonce if there is no such proposal.
+func (ss *safeSubmitter) nextNonce(ctx context.Context) (uint64, error) {
+	info := &safeInfo{}
+	if err := ss.request(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("/api/v1/safes/%v/", ss.safe.Hex()),
+		nil,
+		info,
+	); err != nil {
+		return 0, err
+	}
+
+	if info.Nonce.value == nil {
+		return 0, fmt.Errorf("Safe nonce is not known")
+	}
+	nonce := info.Nonce.value.Uint64()
+
+	query := url.Values{}
+	query.Set("executed", "false")
+	query.Set("nonce__gte", fmt.Sprintf("%v", nonce))
+	query.Set("ordering", "-nonce")
+	query.Set("limit", "1")
+
+	pending := &safeTransactions{}
+	if err := ss.request(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf(
+			"/api/v1/safes/%v/multisig-transactions/?%v",
+			ss.safe.Hex(),
+			query.Encode(),
+		),
+		nil,
+		pending,
+	); err != nil {
+		return 0, err
+	}
+
+	if len(pending.Results) > 0 && pending.Results[0].Nonce.value != nil {
+		if latest := pending.Results[0].Nonce.value.Uint64(); latest >= nonce {
+			return latest + 1, nil
+		}
+	}
+
+	return nonce, nil
+}
+
+// request performs the given request of the Safe transaction service. The
+// body and the result are encoded as JSON; both are optional.
+func (ss *safeSubmitter) request(
+	ctx context.Context,
+	method string,
+	path string,
+	body interface{},
+	result interface{},
+) error {
+	var requestBody []byte
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		requestBody = encoded
+	}
+
+	request, err := http.NewRequest(
+		method,
+		ss.serviceURL+path,
+		bytes.NewReader(requestBody),
+	)
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := ss.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("could not read service response: [%v]", err)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf(
+			"service responded with status [%v]: [%v]",
+			response.StatusCode,
+			string(responseBody),
+		)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(responseBody, result); err != nil {
+		return fmt.Errorf("could not decode service response: [%v]", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSafeSubmitter(t *testing.T) {
+	safe := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	safePath := fmt.Sprintf("/api/v1/safes/%v/", safe.Hex())
+
+	var proposal *safeTransaction
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == safePath:
+				fmt.Fprint(w, `{"address":"`+safe.Hex()+`","nonce":5}`)
+			case r.Method == http.MethodGet &&
+				r.URL.Path == safePath+"multisig-transactions/":
+				if r.URL.Query().Get("executed") != "false" ||
+					r.URL.Query().Get("nonce__gte") != "5" {
+					http.Error(w, "unexpected query", http.StatusBadRequest)
+					return
+				}
+
+				fmt.Fprint(w, `{"count":2,"results":[{"nonce":"6"}]}`)
+			case r.Method == http.MethodPost &&
+				r.URL.Path == safePath+"multisig-transactions/":
+				proposal = &safeTransaction{}
+				if err := json.NewDecoder(r.Body).Decode(proposal); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				w.WriteHeader(http.StatusCreated)
+			default:
+				http.NotFound(w, r)
+			}
+		},
+	))
+	defer server.Close()
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	chainID := big.NewInt(5)
+
+	submitter := &safeSubmitter{
+		safe:       safe,
+		serviceURL: server.URL,
+		chainID:    chainID,
+		accountKey: &keystore.Key{Address: owner, PrivateKey: privateKey},
+		httpClient: &http.Client{Timeout: time.Second},
+	}
+
+	to := common.HexToAddress("0x9999999999999999999999999999999999999999")
+
+	hash, err := submitter.submit(context.Background(), to, []byte{0xab})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if proposal == nil {
+		t.Fatal("transaction has not been proposed")
+	}
+
+	// The proposal must follow the latest proposal not executed yet.
+	if proposal.Nonce != 7 {
+		t.Errorf(
+			"unexpected nonce\nexpected: [%v]\nactual:   [%v]",
+			7,
+			proposal.Nonce,
+		)
+	}
+
+	if proposal.To != to || string(proposal.Data) != string([]byte{0xab}) {
+		t.Errorf("unexpected call: [%v] [%x]", proposal.To.Hex(), proposal.Data)
+	}
+
+	expectedHash := proposal.hash(safe, chainID)
+	if proposal.ContractTransactionHash != expectedHash ||
+		hash != expectedHash.Hex() {
+		t.Errorf(
+			"unexpected transaction hash\nexpected: [%v]\nactual:   [%v]",
+			expectedHash.Hex(),
+			hash,
+		)
+	}
+
+	signature := append([]byte{}, proposal.Signature...)
+	signature[crypto.RecoveryIDOffset] -= 27
+
+	publicKey, err := crypto.SigToPub(expectedHash.Bytes(), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signer := crypto.PubkeyToAddress(*publicKey); signer != owner ||
+		proposal.Sender != owner {
+		t.Errorf(
+			"unexpected signer\nexpected: [%v]\nactual:   [%v]",
+			owner.Hex(),
+			signer.Hex(),
+		)
+	}
+}
+
+func TestSafeTransactionHash_DependsOnNonce(t *testing.T) {
+	safe := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	first := &safeTransaction{Data: []byte{0xab}, Nonce: 1}
+	second := &safeTransaction{Data: []byte{0xab}, Nonce: 2}
+
+	if first.hash(safe, big.NewInt(1)) == second.hash(safe, big.NewInt(1)) {
+		t.Errorf("transactions with different nonces have the same hash")
+	}
+
+	if first.hash(safe, big.NewInt(1)) == first.hash(safe, big.NewInt(5)) {
+		t.Errorf("transactions on different chains have the same hash")
+	}
+}
@@ -0,0 +1,71 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// simulation.go file contains the simulation of the headers submissions. If
+// enabled, each submission is first executed with a call against the latest
+// host chain state, so the relay contract bytecode validates the headers
+// before the transaction is paid for. A submission whose simulation reverts
+// is not sent, and the revert reason is returned instead.
+
+// simulateSubmission runs the given simulation of the named submission if
+// simulations are enabled. The simulation is skipped while transactions of
+// the operator account are pending, as the latest state does not reflect
+// them yet and the submission may depend on them, e.g. when a batch spanning
+// a retarget is split into two transactions. Submissions proposed to
+// a Safe are never simulated, as they are executed once approved and
+// usually depend on earlier proposals not executed yet.
+func (ec *ethereumChain) simulateSubmission(
+	ctx context.Context,
+	method string,
+	simulate func() (bool, error),
+) error {
+	if !ec.config.SimulateSubmissions || ec.config.Multisig.IsEnabled() {
+		return nil
+	}
+
+	submissionLogger := correlation.InjectedLogger(ctx, ec.logger)
+
+	pending, err := ec.submissions.pendingSubmissions(ctx)
+	if err != nil {
+		submissionLogger.Warnf(
+			"could not check pending transactions; "+
+				"skipping [%v] simulation: [%v]",
+			method,
+			err,
+		)
+		return nil
+	}
+	if len(pending) > 0 {
+		submissionLogger.Debugf(
+			"[%v] transactions pending; skipping [%v] simulation",
+			len(pending),
+			method,
+		)
+		return nil
+	}
+
+	succeeded, err := simulate()
+	if err != nil {
+		return fmt.Errorf(
+			"[%v] simulation failed; not submitting: [%v]",
+			method,
+			err,
+		)
+	}
+	if !succeeded {
+		return fmt.Errorf(
+			"[%v] simulation returned failure; not submitting",
+			method,
+		)
+	}
+
+	submissionLogger.Debugf("[%v] simulation succeeded", method)
+
+	return nil
+}
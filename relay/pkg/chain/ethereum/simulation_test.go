@@ -0,0 +1,114 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// simulatedBinding is a relay binding whose addHeaders calls fail with the
+// given error and which counts the submitted transactions.
+type simulatedBinding struct {
+	relayBinding
+
+	callErr   error
+	submitted int
+}
+
+func (sb *simulatedBinding) CallAddHeaders(
+	anchorHeader []byte,
+	headers []byte,
+) (bool, error) {
+	return sb.callErr == nil, sb.callErr
+}
+
+func (sb *simulatedBinding) AddHeaders(
+	anchorHeader []byte,
+	headers []byte,
+) (common.Hash, error) {
+	sb.submitted++
+	return common.Hash{}, nil
+}
+
+func TestSimulateSubmission(t *testing.T) {
+	var tests = map[string]struct {
+		simulate          bool
+		pending           bool
+		callErr           error
+		expectedErr       bool
+		expectedSubmitted int
+	}{
+		"simulation disabled": {
+			callErr:           fmt.Errorf("execution reverted"),
+			expectedSubmitted: 1,
+		},
+		"simulation succeeds": {
+			simulate:          true,
+			expectedSubmitted: 1,
+		},
+		"simulation reverts": {
+			simulate:          true,
+			callErr:           fmt.Errorf("execution reverted"),
+			expectedErr:       true,
+			expectedSubmitted: 0,
+		},
+		"transactions pending": {
+			simulate:          true,
+			pending:           true,
+			callErr:           fmt.Errorf("execution reverted"),
+			expectedSubmitted: 1,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := context.Background()
+
+			binding := &simulatedBinding{callErr: test.callErr}
+			submissions := wrapSubmissionTracking(&receiptsClient{})
+
+			if test.pending {
+				if err := submissions.SendTransaction(
+					ctx,
+					types.NewTransaction(
+						1,
+						common.Address{},
+						big.NewInt(0),
+						cancellationGasLimit,
+						big.NewInt(10),
+						nil,
+					),
+				); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ec := &ethereumChain{
+				config:      &Config{SimulateSubmissions: test.simulate},
+				relay:       binding,
+				submissions: submissions,
+				logger:      logs.OrDefault(nil, loggerName),
+			}
+
+			err := ec.AddHeaders(ctx, []byte{}, []byte{})
+			if (err != nil) != test.expectedErr {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+
+			if binding.submitted != test.expectedSubmitted {
+				t.Errorf(
+					"unexpected submitted transactions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedSubmitted,
+					binding.submitted,
+				)
+			}
+		})
+	}
+}
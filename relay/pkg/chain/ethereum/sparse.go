@@ -0,0 +1,113 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// sparse.go file contains the detection of relay contracts accepting
+// non-contiguous headers. Such contracts expose the maximum number of blocks
+// between consecutive submitted headers they accept. The support is detected
+// by inspecting the deployed code so relay contracts accepting only
+// contiguous headers keep working as before.
+
+// sparseRelayABI is the subset of the sparse relay ABI used by the binding.
+const sparseRelayABI = `[
+	{"inputs":[],"name":"maxHeaderGap","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}
+]`
+
+// sparseBinding is the binding of the relay contract accepting
+// non-contiguous headers.
+type sparseBinding struct {
+	contract      *bind.BoundContract
+	callerOptions *bind.CallOpts
+}
+
+// connectSparse creates the sparse relay binding if the relay contract
+// implements the sparse relay ABI. Nil is returned otherwise.
+func connectSparse(
+	config *Config,
+	relayAddress common.Address,
+	dependencies *bindingDependencies,
+) (*sparseBinding, error) {
+	ctx, cancelCtx := context.WithTimeout(
+		context.Background(),
+		requestTimeout(config),
+	)
+	defer cancelCtx()
+
+	code, err := dependencies.client.CodeAt(ctx, relayAddress, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get code of contract [%v]: [%v]",
+			relayAddress.Hex(),
+			err,
+		)
+	}
+
+	implements, err := codeImplements(
+		code,
+		sparseRelayABI,
+		[]string{"maxHeaderGap"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !implements {
+		return nil, nil
+	}
+
+	parsed, err := hostchainabi.JSON(strings.NewReader(sparseRelayABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	callerOptions, _ := boundContractOptions(dependencies)
+
+	dependencies.logger.Infof("relay contract accepts non-contiguous headers")
+
+	return &sparseBinding{
+		contract: bind.NewBoundContract(
+			relayAddress,
+			parsed,
+			dependencies.client,
+			dependencies.client,
+			dependencies.client,
+		),
+		callerOptions: callerOptions,
+	}, nil
+}
+
+// MaxHeaderGap returns the maximum number of blocks between consecutive
+// headers accepted by the relay contract.
+func (ec *ethereumChain) MaxHeaderGap(ctx context.Context) (uint64, error) {
+	if ec.sparse == nil {
+		return 1, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var result *big.Int
+	if err := ec.sparse.contract.Call(
+		ec.sparse.callerOptions,
+		&result,
+		"maxHeaderGap",
+	); err != nil {
+		return 0, err
+	}
+
+	if !result.IsUint64() || result.Uint64() == 0 {
+		return 0, fmt.Errorf("invalid maximum header gap [%v]", result)
+	}
+
+	return result.Uint64(), nil
+}
@@ -0,0 +1,225 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// sponsorship.go file contains the submission of header pushes paid for by
+// a third party, so organizations can fund the relay through a paymaster or
+// a meta-transaction relayer instead of distributing funded operator keys to
+// relay hosts. Header pushes are encoded as calls of the relay contract and
+// submitted either as ERC-4337 user operations of a smart account through
+// a bundler, or as sponsored calls through a meta-transaction relayer. Other
+// transactions, like rewards claims or proofs submissions, are still signed
+// with the operator key.
+
+// Supported sponsorship modes.
+const (
+	// SponsorshipBundler submits header pushes as ERC-4337 user operations
+	// of a smart account through a bundler. User operations are signed with
+	// the operator key, which owns the smart account and does not need to
+	// hold any funds.
+	SponsorshipBundler = "bundler"
+
+	// SponsorshipRelayer submits header pushes as sponsored calls through
+	// a meta-transaction relayer. No operator key is needed.
+	SponsorshipRelayer = "relayer"
+)
+
+var (
+	// DefaultEntryPoint is the address of the ERC-4337 v0.6 EntryPoint
+	// contract, deployed at the same address on all supported chains.
+	DefaultEntryPoint = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+
+	// DefaultRelayerURL is the default URL of the meta-transaction relayer.
+	// It points to the Gelato sponsored call endpoint.
+	DefaultRelayerURL = "https://api.gelato.digital/relays/v2/sponsored-call"
+)
+
+// SponsorshipConfig is the configuration of header pushes paid for by
+// a third party.
+type SponsorshipConfig struct {
+	// Mode determines how header pushes are submitted. Supported values are
+	// `bundler` and `relayer`. If empty, header pushes are sent as
+	// transactions of the operator account.
+	Mode string
+
+	// URL is the RPC URL of the bundler in the `bundler` mode or the URL of
+	// the sponsored call endpoint of the relayer in the `relayer` mode. If
+	// empty in the `relayer` mode, the Gelato relayer is used.
+	URL string
+
+	// Account is the address of the smart account submitting header pushes
+	// in the `bundler` mode. The account must expose the
+	// `execute(address,uint256,bytes)` function and accept user operations
+	// signed by the operator key.
+	Account string
+
+	// EntryPoint is the address of the ERC-4337 EntryPoint contract used in
+	// the `bundler` mode. If empty, the v0.6 EntryPoint is used.
+	EntryPoint string
+
+	// PaymasterURL is the RPC URL of the paymaster service sponsoring user
+	// operations in the `bundler` mode through `pm_sponsorUserOperation`
+	// requests. If empty, user operations are paid for by the smart account
+	// deposit.
+	PaymasterURL string
+
+	// APIKey is the sponsor API key passed to the relayer in the `relayer`
+	// mode.
+	APIKey string
+}
+
+// IsEnabled checks whether header pushes are paid for by a third party.
+func (sc *SponsorshipConfig) IsEnabled() bool {
+	return sc.Mode != ""
+}
+
+// IsKeyless checks whether header pushes are submitted without the operator
+// key.
+func (sc *SponsorshipConfig) IsKeyless() bool {
+	return sc.Mode == SponsorshipRelayer
+}
+
+func (sc *SponsorshipConfig) entryPoint() string {
+	if sc.EntryPoint != "" {
+		return sc.EntryPoint
+	}
+
+	return DefaultEntryPoint
+}
+
+func (sc *SponsorshipConfig) relayerURL() string {
+	if sc.URL != "" {
+		return sc.URL
+	}
+
+	return DefaultRelayerURL
+}
+
+// newSponsoredSubmitter creates the submitter of the configured sponsorship
+// mode.
+func newSponsoredSubmitter(
+	config *Config,
+	dependencies *bindingDependencies,
+) (externalSubmitter, error) {
+	switch config.Sponsorship.Mode {
+	case SponsorshipBundler:
+		return newBundlerSubmitter(config, dependencies)
+	case SponsorshipRelayer:
+		return &relayerSubmitter{
+			url:        config.Sponsorship.relayerURL(),
+			apiKey:     config.Sponsorship.APIKey,
+			chainID:    dependencies.chainID,
+			httpClient: &http.Client{Timeout: requestTimeout(config)},
+		}, nil
+	default:
+		return nil, fmt.Errorf(
+			"unknown sponsorship mode [%v]",
+			config.Sponsorship.Mode,
+		)
+	}
+}
+
+// relayerSubmitter submits sponsored calls through a meta-transaction
+// relayer implementing the Gelato sponsored call API. The relayer sends the
+// transactions from its own accounts and charges the sponsor identified by
+// the API key.
+type relayerSubmitter struct {
+	url        string
+	apiKey     string
+	chainID    *big.Int
+	httpClient *http.Client
+}
+
+// sponsoredCallRequest is the body of the sponsored call request.
+type sponsoredCallRequest struct {
+	ChainID       string `json:"chainId"`
+	Target        string `json:"target"`
+	Data          string `json:"data"`
+	SponsorAPIKey string `json:"sponsorApiKey,omitempty"`
+}
+
+// sponsoredCallResponse is the body of the sponsored call response.
+type sponsoredCallResponse struct {
+	TaskID  string `json:"taskId"`
+	Message string `json:"message"`
+}
+
+func (rs *relayerSubmitter) String() string {
+	return "relayer"
+}
+
+func (rs *relayerSubmitter) submit(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+) (string, error) {
+	body, err := json.Marshal(&sponsoredCallRequest{
+		ChainID:       rs.chainID.String(),
+		Target:        to.Hex(),
+		Data:          hexutil.Encode(input),
+		SponsorAPIKey: rs.apiKey,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, rs.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := rs.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("could not read relayer response: [%v]", err)
+	}
+
+	decoded := &sponsoredCallResponse{}
+	// The response may not be JSON in case of errors; the status is checked
+	// first in that case.
+	decodingErr := json.Unmarshal(responseBody, decoded)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message := decoded.Message
+		if decodingErr != nil || message == "" {
+			message = string(responseBody)
+		}
+
+		return "", fmt.Errorf(
+			"relayer responded with status [%v]: [%v]",
+			response.StatusCode,
+			message,
+		)
+	}
+
+	if decodingErr != nil {
+		return "", fmt.Errorf(
+			"could not decode relayer response: [%v]",
+			decodingErr,
+		)
+	}
+
+	if decoded.TaskID == "" {
+		return "", fmt.Errorf("relayer did not return the task ID")
+	}
+
+	return decoded.TaskID, nil
+}
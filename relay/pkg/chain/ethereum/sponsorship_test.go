@@ -0,0 +1,244 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestRelayerSubmitter(t *testing.T) {
+	var request *sponsoredCallRequest
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			request = &sponsoredCallRequest{}
+			if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+				t.Fatal(err)
+			}
+
+			fmt.Fprint(w, `{"taskId":"0x1234"}`)
+		},
+	))
+	defer server.Close()
+
+	submitter := &relayerSubmitter{
+		url:        server.URL,
+		apiKey:     "key",
+		chainID:    big.NewInt(5),
+		httpClient: &http.Client{Timeout: time.Second},
+	}
+
+	to := common.HexToAddress("0x9999999999999999999999999999999999999999")
+
+	taskID, err := submitter.submit(context.Background(), to, []byte{0xab})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if taskID != "0x1234" {
+		t.Errorf(
+			"unexpected task ID\nexpected: [%v]\nactual:   [%v]",
+			"0x1234",
+			taskID,
+		)
+	}
+
+	expectedRequest := &sponsoredCallRequest{
+		ChainID:       "5",
+		Target:        to.Hex(),
+		Data:          "0xab",
+		SponsorAPIKey: "key",
+	}
+	if *request != *expectedRequest {
+		t.Errorf(
+			"unexpected request\nexpected: [%+v]\nactual:   [%+v]",
+			expectedRequest,
+			request,
+		)
+	}
+}
+
+func TestRelayerSubmitter_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"insufficient balance"}`)
+		},
+	))
+	defer server.Close()
+
+	submitter := &relayerSubmitter{
+		url:        server.URL,
+		chainID:    big.NewInt(5),
+		httpClient: &http.Client{Timeout: time.Second},
+	}
+
+	_, err := submitter.submit(context.Background(), common.Address{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "insufficient balance") {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+}
+
+// bundlerServer is a stub of the bundler RPC API recording sent user
+// operations.
+type bundlerServer struct {
+	mutex      sync.Mutex
+	operations []*userOperation
+}
+
+func (bs *bundlerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := &struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result interface{}
+	switch request.Method {
+	case "eth_estimateUserOperationGas":
+		result = map[string]interface{}{
+			"preVerificationGas":   "0xc350",
+			"verificationGasLimit": 100000,
+			"callGasLimit":         "0x30d40",
+		}
+	case "eth_sendUserOperation":
+		operation := &userOperation{}
+		if err := json.Unmarshal(request.Params[0], operation); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		bs.mutex.Lock()
+		bs.operations = append(bs.operations, operation)
+		bs.mutex.Unlock()
+
+		result = "0xabcd"
+	default:
+		http.Error(w, "unknown method", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      request.ID,
+		"result":  result,
+	})
+}
+
+func TestBundlerSubmitter(t *testing.T) {
+	server := &bundlerServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	bundler, err := rpc.DialHTTP(httpServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	chainID := big.NewInt(5)
+	entryPoint := common.HexToAddress(DefaultEntryPoint)
+
+	submitter := &bundlerSubmitter{
+		account:        common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		entryPoint:     entryPoint,
+		chainID:        chainID,
+		accountKey:     &keystore.Key{Address: owner, PrivateKey: privateKey},
+		requestTimeout: time.Second,
+		bundler:        bundler,
+		accountNonce: func(ctx context.Context) (*big.Int, error) {
+			// The submitted user operations are never included.
+			return big.NewInt(7), nil
+		},
+		gasPrice: func(ctx context.Context) (*big.Int, error) {
+			return big.NewInt(1000000000), nil
+		},
+	}
+
+	to := common.HexToAddress("0x9999999999999999999999999999999999999999")
+
+	for i := 0; i < 2; i++ {
+		hash, err := submitter.submit(context.Background(), to, []byte{0xab})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hash != "0xabcd" {
+			t.Errorf("unexpected user operation hash: [%v]", hash)
+		}
+	}
+
+	if len(server.operations) != 2 {
+		t.Fatalf("unexpected number of operations: [%v]", len(server.operations))
+	}
+
+	for i, operation := range server.operations {
+		expectedNonce := int64(7 + i)
+		if operation.Nonce.ToInt().Int64() != expectedNonce {
+			t.Errorf(
+				"unexpected nonce of operation [%v]\nexpected: [%v]\nactual:   [%v]",
+				i,
+				expectedNonce,
+				operation.Nonce.ToInt(),
+			)
+		}
+
+		if operation.VerificationGasLimit.ToInt().Int64() != 100000 {
+			t.Errorf(
+				"unexpected verification gas limit: [%v]",
+				operation.VerificationGasLimit.ToInt(),
+			)
+		}
+
+		expectedCallData, err := executeCallData(to, []byte{0xab})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(operation.CallData) != string(expectedCallData) {
+			t.Errorf("unexpected call data of operation [%v]", i)
+		}
+
+		message := crypto.Keccak256(
+			[]byte("\x19Ethereum Signed Message:\n32"),
+			operation.hash(entryPoint, chainID).Bytes(),
+		)
+
+		signature := append([]byte{}, operation.Signature...)
+		signature[crypto.RecoveryIDOffset] -= 27
+
+		publicKey, err := crypto.SigToPub(message, signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if signer := crypto.PubkeyToAddress(*publicKey); signer != owner {
+			t.Errorf(
+				"unexpected signer of operation [%v]\nexpected: [%v]\nactual:   [%v]",
+				i,
+				owner.Hex(),
+				signer.Hex(),
+			)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package ethereum
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+	"github.com/keep-network/keep-common/pkg/chain/ethlike"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum/gen/abi"
+	"github.com/keep-network/tbtc/relay/pkg/chain/ethereum/gen/contract"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// RelayVersionSummaV1 is the summa-tx relay contract which requires all
+// headers to be submitted and a separate markNewHeaviest call to advance
+// the best known digest.
+const RelayVersionSummaV1 RelayVersion = "summa-v1"
+
+func init() {
+	registerRelayVersion(&knownRelayVersion{
+		version: RelayVersionSummaV1,
+		abi:     abi.RelayABI,
+		requiredMethods: []string{
+			"getBestKnownDigest",
+			"isAncestor",
+			"findHeight",
+			"addHeaders",
+			"addHeadersWithRetarget",
+			"markNewHeaviest",
+		},
+		factory: newSummaV1Binding,
+	})
+}
+
+// bindingDependencies groups the dependencies required to create
+// a relay binding.
+type bindingDependencies struct {
+	chainID          *big.Int
+	accountKey       *keystore.Key
+	client           ethutil.EthereumClient
+	nonceManager     *ethlike.NonceManager
+	miningWaiter     *ethlike.MiningWaiter
+	blockCounter     *ethlike.BlockCounter
+	transactionMutex *sync.Mutex
+	logger           logs.Logger
+}
+
+// summaV1Binding is the relay binding for the summa-tx relay contract.
+type summaV1Binding struct {
+	contract *contract.Relay
+}
+
+func newSummaV1Binding(
+	address common.Address,
+	dependencies *bindingDependencies,
+) (relayBinding, error) {
+	relayContract, err := contract.NewRelay(
+		address,
+		dependencies.chainID,
+		dependencies.accountKey,
+		dependencies.client,
+		dependencies.nonceManager,
+		dependencies.miningWaiter,
+		dependencies.blockCounter,
+		dependencies.transactionMutex,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &summaV1Binding{relayContract}, nil
+}
+
+func (sb *summaV1Binding) GetBestKnownDigest() (btc.Digest, error) {
+	return sb.contract.GetBestKnownDigest()
+}
+
+func (sb *summaV1Binding) IsAncestor(
+	ancestorDigest btc.Digest,
+	descendantDigest btc.Digest,
+	limit *big.Int,
+) (bool, error) {
+	return sb.contract.IsAncestor(ancestorDigest, descendantDigest, limit)
+}
+
+func (sb *summaV1Binding) FindHeight(
+	digest btc.Digest,
+	blockNumber *big.Int,
+) (*big.Int, error) {
+	if blockNumber == nil {
+		return sb.contract.FindHeight(digest)
+	}
+
+	return sb.contract.FindHeightAtBlock(digest, blockNumber)
+}
+
+func (sb *summaV1Binding) FindAncestor(
+	digest btc.Digest,
+	offset *big.Int,
+) (btc.Digest, error) {
+	return sb.contract.FindAncestor(digest, offset)
+}
+
+func (sb *summaV1Binding) AddHeaders(
+	anchorHeader []byte,
+	headers []byte,
+) (common.Hash, error) {
+	transaction, err := sb.contract.AddHeaders(anchorHeader, headers)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return transaction.Hash(), nil
+}
+
+func (sb *summaV1Binding) CallAddHeaders(
+	anchorHeader []byte,
+	headers []byte,
+) (bool, error) {
+	return sb.contract.CallAddHeaders(anchorHeader, headers, nil)
+}
+
+func (sb *summaV1Binding) AddHeadersWithRetarget(
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) (common.Hash, error) {
+	transaction, err := sb.contract.AddHeadersWithRetarget(
+		oldPeriodStartHeader,
+		oldPeriodEndHeader,
+		headers,
+	)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return transaction.Hash(), nil
+}
+
+func (sb *summaV1Binding) CallAddHeadersWithRetarget(
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) (bool, error) {
+	return sb.contract.CallAddHeadersWithRetarget(
+		oldPeriodStartHeader,
+		oldPeriodEndHeader,
+		headers,
+		nil,
+	)
+}
+
+func (sb *summaV1Binding) MarkNewHeaviest(
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) (common.Hash, error) {
+	transaction, err := sb.contract.MarkNewHeaviest(
+		ancestorDigest,
+		currentBestHeader,
+		newBestHeader,
+		limit,
+	)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return transaction.Hash(), nil
+}
+
+func (sb *summaV1Binding) CallMarkNewHeaviest(
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) (bool, error) {
+	return sb.contract.CallMarkNewHeaviest(
+		ancestorDigest,
+		currentBestHeader,
+		newBestHeader,
+		limit,
+		nil,
+	)
+}
+
+func (sb *summaV1Binding) GetProofLength() (uint64, error) {
+	return 0, errUnsupportedByVersion("GetProofLength", RelayVersionSummaV1)
+}
+
+func (sb *summaV1Binding) GetCurrentEpoch() (uint64, error) {
+	return 0, errUnsupportedByVersion("GetCurrentEpoch", RelayVersionSummaV1)
+}
+
+func (sb *summaV1Binding) Retarget(headers []byte) (common.Hash, error) {
+	return common.Hash{}, errUnsupportedByVersion(
+		"Retarget",
+		RelayVersionSummaV1,
+	)
+}
+
+func (sb *summaV1Binding) CallRetarget(headers []byte) error {
+	return errUnsupportedByVersion("CallRetarget", RelayVersionSummaV1)
+}
+
+func (sb *summaV1Binding) PastAdvanceLogs(
+	fromBlock uint64,
+	toBlock uint64,
+) ([]types.Log, error) {
+	extensionEvents, err := sb.contract.PastExtensionEvents(
+		fromBlock,
+		&toBlock,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	newTipEvents, err := sb.contract.PastNewTipEvents(
+		fromBlock,
+		&toBlock,
+		nil,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]types.Log, 0, len(extensionEvents)+len(newTipEvents))
+	for _, event := range extensionEvents {
+		logs = append(logs, event.Raw)
+	}
+	for _, event := range newTipEvents {
+		logs = append(logs, event.Raw)
+	}
+
+	return logs, nil
+}
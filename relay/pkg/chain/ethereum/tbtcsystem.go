@@ -0,0 +1,179 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	goethereum "github.com/ethereum/go-ethereum"
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// tbtcsystem.go file contains the binding of the events emitted by the tBTC
+// system contract on behalf of all deposits. The contract address must be
+// configured as TBTCSystem in the contract addresses.
+
+// TBTCSystemContractName defines the name of the tBTC system contract.
+const TBTCSystemContractName = "TBTCSystem"
+
+// tbtcSystemABI is the subset of the tBTC system ABI used by the binding.
+const tbtcSystemABI = `[
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"_depositContractAddress","type":"address"},{"indexed":true,"internalType":"address","name":"_requester","type":"address"},{"indexed":true,"internalType":"bytes32","name":"_digest","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"_utxoValue","type":"uint256"},{"indexed":false,"internalType":"bytes","name":"_redeemerOutputScript","type":"bytes"},{"indexed":false,"internalType":"uint256","name":"_requestedFee","type":"uint256"},{"indexed":false,"internalType":"bytes","name":"_outpoint","type":"bytes"}],"name":"RedemptionRequested","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"_depositContractAddress","type":"address"},{"indexed":false,"internalType":"bytes32","name":"_signingGroupPubkeyX","type":"bytes32"},{"indexed":false,"internalType":"bytes32","name":"_signingGroupPubkeyY","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"_timestamp","type":"uint256"}],"name":"RegisteredPubkey","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"_depositContractAddress","type":"address"},{"indexed":true,"internalType":"bytes32","name":"_txid","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"_timestamp","type":"uint256"}],"name":"Funded","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"_depositContractAddress","type":"address"},{"indexed":true,"internalType":"bytes32","name":"_txid","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"_timestamp","type":"uint256"}],"name":"Redeemed","type":"event"}
+]`
+
+// depositEventTypes maps the names of the tBTC system events to the types of
+// the deposit events.
+var depositEventTypes = map[string]chain.DepositEventType{
+	"RegisteredPubkey":    chain.DepositEventFundingAwaited,
+	"Funded":              chain.DepositEventFunded,
+	"RedemptionRequested": chain.DepositEventRedemptionRequested,
+	"Redeemed":            chain.DepositEventRedeemed,
+}
+
+// Length of a serialized Bitcoin outpoint.
+const outpointLength = 36
+
+// GetRedemptionRequest returns the latest redemption request of the Deposit
+// contract at the given address made since the given host chain block. Nil
+// is returned if there was no such request.
+func (ec *ethereumChain) GetRedemptionRequest(
+	ctx context.Context,
+	depositAddress string,
+	fromBlock uint64,
+) (*chain.RedemptionRequest, error) {
+	systemAddress, err := ec.config.ContractAddress(TBTCSystemContractName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !common.IsHexAddress(depositAddress) {
+		return nil, fmt.Errorf(
+			"invalid deposit contract address [%v]",
+			depositAddress,
+		)
+	}
+
+	parsed, err := hostchainabi.JSON(strings.NewReader(tbtcSystemABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	event := parsed.Events["RedemptionRequested"]
+
+	logs, err := ec.client.FilterLogs(ctx, goethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		Addresses: []common.Address{systemAddress},
+		Topics: [][]common.Hash{
+			{event.ID()},
+			{common.HexToAddress(depositAddress).Hash()},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get redemption requests: [%v]",
+			err,
+		)
+	}
+
+	if len(logs) == 0 {
+		return nil, nil
+	}
+
+	// The redemption fee may have been increased, each time with a new
+	// request. The latest one counts.
+	latest := logs[len(logs)-1]
+
+	values, err := event.Inputs.NonIndexed().UnpackValues(latest.Data)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not decode redemption request: [%v]",
+			err,
+		)
+	}
+
+	redeemerOutputScript, ok := values[1].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redeemer output script type")
+	}
+
+	serializedOutpoint, ok := values[3].([]byte)
+	if !ok || len(serializedOutpoint) != outpointLength {
+		return nil, fmt.Errorf("unexpected outpoint format")
+	}
+
+	outpoint := btc.Outpoint{
+		OutputIndex: binary.LittleEndian.Uint32(serializedOutpoint[32:]),
+	}
+	copy(outpoint.TxID[:], serializedOutpoint[:32])
+
+	return &chain.RedemptionRequest{
+		Outpoint:             outpoint,
+		RedeemerOutputScript: redeemerOutputScript,
+		BlockNumber:          latest.BlockNumber,
+	}, nil
+}
+
+// PastDepositEvents returns the events emitted by the tBTC system on behalf
+// of all deposits within the given range of host chain blocks, both
+// inclusive.
+func (ec *ethereumChain) PastDepositEvents(
+	ctx context.Context,
+	fromBlock uint64,
+	toBlock uint64,
+) ([]*chain.DepositEvent, error) {
+	systemAddress, err := ec.config.ContractAddress(TBTCSystemContractName)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := hostchainabi.JSON(strings.NewReader(tbtcSystemABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ABI: [%v]", err)
+	}
+
+	eventTypes := make(map[common.Hash]chain.DepositEventType)
+	eventIDs := make([]common.Hash, 0, len(depositEventTypes))
+	for name, eventType := range depositEventTypes {
+		id := parsed.Events[name].ID()
+		eventTypes[id] = eventType
+		eventIDs = append(eventIDs, id)
+	}
+
+	logs, err := ec.client.FilterLogs(ctx, goethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{systemAddress},
+		Topics:    [][]common.Hash{eventIDs},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get deposit events: [%v]", err)
+	}
+
+	events := make([]*chain.DepositEvent, 0, len(logs))
+	for _, log := range logs {
+		if len(log.Topics) < 2 {
+			continue
+		}
+
+		eventType, ok := eventTypes[log.Topics[0]]
+		if !ok {
+			continue
+		}
+
+		events = append(events, &chain.DepositEvent{
+			Type:           eventType,
+			DepositAddress: common.BytesToAddress(log.Topics[1].Bytes()).Hex(),
+			BlockNumber:    log.BlockNumber,
+		})
+	}
+
+	return events, nil
+}
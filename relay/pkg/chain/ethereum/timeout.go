@@ -0,0 +1,212 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+)
+
+// timeout.go file contains the client wrapper which bounds the duration of
+// each RPC request. Generated contract bindings perform their calls using
+// a background context so without the wrapper a hung node would block the
+// caller forever. Subscriptions are long-lived and are not bounded.
+
+// DefaultRequestTimeout is the default maximum time a single RPC request can
+// take. This value can be overwritten in the configuration file.
+var DefaultRequestTimeout = 30 * time.Second
+
+type timeoutClient struct {
+	ethutil.EthereumClient
+
+	timeout time.Duration
+}
+
+// wrapRequestTimeout wraps the given client so each of its RPC requests is
+// abandoned once the timeout is hit.
+func wrapRequestTimeout(
+	client ethutil.EthereumClient,
+	timeout time.Duration,
+) ethutil.EthereumClient {
+	return &timeoutClient{
+		EthereumClient: client,
+		timeout:        timeout,
+	}
+}
+
+func (tc *timeoutClient) CodeAt(
+	ctx context.Context,
+	contract common.Address,
+	blockNumber *big.Int,
+) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.CodeAt(ctx, contract, blockNumber)
+}
+
+func (tc *timeoutClient) CallContract(
+	ctx context.Context,
+	call ethereum.CallMsg,
+	blockNumber *big.Int,
+) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.CallContract(ctx, call, blockNumber)
+}
+
+func (tc *timeoutClient) PendingCodeAt(
+	ctx context.Context,
+	account common.Address,
+) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.PendingCodeAt(ctx, account)
+}
+
+func (tc *timeoutClient) PendingNonceAt(
+	ctx context.Context,
+	account common.Address,
+) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.PendingNonceAt(ctx, account)
+}
+
+func (tc *timeoutClient) SuggestGasPrice(
+	ctx context.Context,
+) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.SuggestGasPrice(ctx)
+}
+
+func (tc *timeoutClient) EstimateGas(
+	ctx context.Context,
+	call ethereum.CallMsg,
+) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.EstimateGas(ctx, call)
+}
+
+func (tc *timeoutClient) SendTransaction(
+	ctx context.Context,
+	tx *types.Transaction,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.SendTransaction(ctx, tx)
+}
+
+func (tc *timeoutClient) FilterLogs(
+	ctx context.Context,
+	query ethereum.FilterQuery,
+) ([]types.Log, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.FilterLogs(ctx, query)
+}
+
+func (tc *timeoutClient) BlockByHash(
+	ctx context.Context,
+	hash common.Hash,
+) (*types.Block, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.BlockByHash(ctx, hash)
+}
+
+func (tc *timeoutClient) BlockByNumber(
+	ctx context.Context,
+	number *big.Int,
+) (*types.Block, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.BlockByNumber(ctx, number)
+}
+
+func (tc *timeoutClient) HeaderByHash(
+	ctx context.Context,
+	hash common.Hash,
+) (*types.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.HeaderByHash(ctx, hash)
+}
+
+func (tc *timeoutClient) HeaderByNumber(
+	ctx context.Context,
+	number *big.Int,
+) (*types.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.HeaderByNumber(ctx, number)
+}
+
+func (tc *timeoutClient) TransactionCount(
+	ctx context.Context,
+	blockHash common.Hash,
+) (uint, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.TransactionCount(ctx, blockHash)
+}
+
+func (tc *timeoutClient) TransactionInBlock(
+	ctx context.Context,
+	blockHash common.Hash,
+	index uint,
+) (*types.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.TransactionInBlock(ctx, blockHash, index)
+}
+
+func (tc *timeoutClient) TransactionByHash(
+	ctx context.Context,
+	txHash common.Hash,
+) (*types.Transaction, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.TransactionByHash(ctx, txHash)
+}
+
+func (tc *timeoutClient) TransactionReceipt(
+	ctx context.Context,
+	txHash common.Hash,
+) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.TransactionReceipt(ctx, txHash)
+}
+
+func (tc *timeoutClient) BalanceAt(
+	ctx context.Context,
+	account common.Address,
+	blockNumber *big.Int,
+) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+	defer cancel()
+
+	return tc.EthereumClient.BalanceAt(ctx, account, blockNumber)
+}
@@ -0,0 +1,102 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/keep-network/tbtc/relay/pkg/transport"
+)
+
+// transport.go file contains the connection of the Ethereum node through the
+// configured HTTP transport. The websocket client cannot send extra headers
+// nor be routed through a proxy, so the node is connected over HTTP if the
+// transport is configured.
+
+// dialNode connects the configured Ethereum node.
+func dialNode(config *Config) (*ethclient.Client, error) {
+	if !config.HTTP.IsSet() {
+		return ethclient.Dial(config.URL)
+	}
+
+	if !config.HTTP.RequiresHTTPClient() && isWebsocketURL(config.URL) {
+		return dialWebsocket(config)
+	}
+
+	endpoint, err := httpEndpoint(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Request timeouts are enforced by the client wrappers.
+	httpClient, err := transport.NewClient(&config.HTTP, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure HTTP transport: [%v]", err)
+	}
+
+	rpcClient, err := rpc.DialHTTPWithClient(endpoint, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return ethclient.NewClient(rpcClient), nil
+}
+
+// httpEndpoint returns the HTTP URL of the configured node. URLRPC is used
+// if URL is not an HTTP URL.
+func httpEndpoint(config *Config) (string, error) {
+	for _, endpoint := range []string{config.URL, config.URLRPC} {
+		parsed, err := url.Parse(endpoint)
+		if err != nil {
+			continue
+		}
+
+		if parsed.Scheme == "http" || parsed.Scheme == "https" {
+			return endpoint, nil
+		}
+	}
+
+	return "", fmt.Errorf(
+		"HTTP transport requires an http or https node URL in URL or URLRPC",
+	)
+}
+
+// dialWebsocket connects the node at the configured websocket URL using the
+// configured dialing of the connections.
+func dialWebsocket(config *Config) (*ethclient.Client, error) {
+	dial, err := transport.NewDialer(&config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure HTTP transport: [%v]", err)
+	}
+
+	dialer := websocket.Dialer{
+		NetDial: func(network, address string) (net.Conn, error) {
+			return dial(context.Background(), network, address)
+		},
+	}
+
+	rpcClient, err := rpc.DialWebsocketWithDialer(
+		context.Background(),
+		config.URL,
+		"",
+		dialer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ethclient.NewClient(rpcClient), nil
+}
+
+func isWebsocketURL(endpoint string) bool {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+
+	return parsed.Scheme == "ws" || parsed.Scheme == "wss"
+}
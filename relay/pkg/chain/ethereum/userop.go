@@ -0,0 +1,449 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	hostchainabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// userop.go file contains the submission of header pushes as ERC-4337 v0.6
+// user operations. Each call of the relay contract is wrapped in a call of
+// the `execute` function of the smart account, signed with the operator key
+// owning the account and sent to the bundler. If a paymaster service is
+// configured, it sponsors the user operation before it is signed.
+
+// entryPointABI is the part of the EntryPoint contract ABI used to get the
+// nonces of the smart account.
+const entryPointABI = `[
+	{"inputs":[{"internalType":"address","name":"sender","type":"address"},{"internalType":"uint192","name":"key","type":"uint192"}],"name":"getNonce","outputs":[{"internalType":"uint256","name":"nonce","type":"uint256"}],"stateMutability":"view","type":"function"}
+]`
+
+// smartAccountABI is the part of the smart account ABI used to wrap the
+// calls of the relay contract.
+const smartAccountABI = `[
+	{"inputs":[{"internalType":"address","name":"dest","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"bytes","name":"func","type":"bytes"}],"name":"execute","outputs":[],"stateMutability":"nonpayable","type":"function"}
+]`
+
+// dummySignature is a well-formed signature passed with user operations
+// whose gas is estimated or which are sponsored before they are signed. The
+// signature verification must not revert for the estimation to succeed.
+var dummySignature = hexutil.MustDecode(
+	"0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007" +
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c",
+)
+
+// userOperation is the ERC-4337 v0.6 user operation in the format of the
+// bundler RPC API.
+type userOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// hash returns the hash of the user operation signed by the owner of the
+// smart account, as defined by the EntryPoint contract.
+func (uo *userOperation) hash(
+	entryPoint common.Address,
+	chainID *big.Int,
+) common.Hash {
+	packed := crypto.Keccak256(
+		common.LeftPadBytes(uo.Sender.Bytes(), 32),
+		math256(uo.Nonce),
+		crypto.Keccak256(uo.InitCode),
+		crypto.Keccak256(uo.CallData),
+		math256(uo.CallGasLimit),
+		math256(uo.VerificationGasLimit),
+		math256(uo.PreVerificationGas),
+		math256(uo.MaxFeePerGas),
+		math256(uo.MaxPriorityFeePerGas),
+		crypto.Keccak256(uo.PaymasterAndData),
+	)
+
+	return crypto.Keccak256Hash(
+		packed,
+		common.LeftPadBytes(entryPoint.Bytes(), 32),
+		common.LeftPadBytes(chainID.Bytes(), 32),
+	)
+}
+
+// math256 returns the given number as a 32-byte big-endian word.
+func math256(value *hexutil.Big) []byte {
+	if value == nil {
+		return make([]byte, 32)
+	}
+
+	return common.LeftPadBytes(value.ToInt().Bytes(), 32)
+}
+
+// gasEstimate holds the gas limits of a user operation estimated by the
+// bundler or set by the paymaster service. Services differ in whether they
+// return the limits as hex strings or numbers, so both are accepted.
+type gasEstimate struct {
+	PreVerificationGas   rpcQuantity `json:"preVerificationGas"`
+	VerificationGasLimit rpcQuantity `json:"verificationGasLimit"`
+	CallGasLimit         rpcQuantity `json:"callGasLimit"`
+}
+
+// paymasterSponsorship is the result of the `pm_sponsorUserOperation`
+// request. Some paymaster services return only the paymaster data as
+// a string, others an object with the paymaster data and the gas limits of
+// the sponsored user operation.
+type paymasterSponsorship struct {
+	gasEstimate
+
+	PaymasterAndData hexutil.Bytes `json:"paymasterAndData"`
+}
+
+func (s *paymasterSponsorship) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &s.PaymasterAndData)
+	}
+
+	type plain paymasterSponsorship
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// hasGasLimits checks whether the paymaster service set the gas limits of
+// the sponsored user operation.
+func (s *paymasterSponsorship) hasGasLimits() bool {
+	return s.CallGasLimit.value != nil &&
+		s.VerificationGasLimit.value != nil &&
+		s.PreVerificationGas.value != nil
+}
+
+// rpcQuantity is a quantity encoded either as a hex string or a number.
+type rpcQuantity struct {
+	value *big.Int
+}
+
+func (rq *rpcQuantity) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		return nil
+	}
+
+	value, ok := new(big.Int).SetString(text, 0)
+	if !ok {
+		return fmt.Errorf("invalid quantity [%v]", string(data))
+	}
+
+	rq.value = value
+	return nil
+}
+
+func (rq rpcQuantity) big() *hexutil.Big {
+	return (*hexutil.Big)(rq.value)
+}
+
+// bundlerSubmitter submits calls of the relay contract as user operations
+// of the smart account through the bundler.
+type bundlerSubmitter struct {
+	account        common.Address
+	entryPoint     common.Address
+	chainID        *big.Int
+	accountKey     *keystore.Key
+	requestTimeout time.Duration
+
+	bundler   *rpc.Client
+	paymaster *rpc.Client
+
+	// accountNonce returns the nonce of the smart account known by the
+	// EntryPoint contract.
+	accountNonce func(ctx context.Context) (*big.Int, error)
+	// gasPrice returns the gas price the user operation fees are set to.
+	gasPrice func(ctx context.Context) (*big.Int, error)
+
+	mutex sync.Mutex
+	// nextNonce is the nonce of the next user operation. User operations
+	// submitted but not included yet are not reflected in the nonce known
+	// by the EntryPoint contract, so subsequent pushes need to track it.
+	nextNonce *big.Int
+}
+
+func newBundlerSubmitter(
+	config *Config,
+	dependencies *bindingDependencies,
+) (*bundlerSubmitter, error) {
+	sponsorshipConfig := &config.Sponsorship
+
+	if sponsorshipConfig.URL == "" {
+		return nil, fmt.Errorf("bundler URL is not set")
+	}
+
+	if !common.IsHexAddress(sponsorshipConfig.Account) {
+		return nil, fmt.Errorf(
+			"invalid smart account address [%v]",
+			sponsorshipConfig.Account,
+		)
+	}
+
+	if !common.IsHexAddress(sponsorshipConfig.entryPoint()) {
+		return nil, fmt.Errorf(
+			"invalid EntryPoint address [%v]",
+			sponsorshipConfig.entryPoint(),
+		)
+	}
+
+	// Request timeouts are enforced with the context of each request.
+	bundler, err := rpc.DialHTTPWithClient(sponsorshipConfig.URL, &http.Client{})
+	if err != nil {
+		return nil, fmt.Errorf("could not connect bundler: [%v]", err)
+	}
+
+	var paymaster *rpc.Client
+	if sponsorshipConfig.PaymasterURL != "" {
+		paymaster, err = rpc.DialHTTPWithClient(
+			sponsorshipConfig.PaymasterURL,
+			&http.Client{},
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not connect paymaster service: [%v]",
+				err,
+			)
+		}
+	}
+
+	parsedEntryPointABI, err := hostchainabi.JSON(
+		strings.NewReader(entryPointABI),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse EntryPoint ABI: [%v]", err)
+	}
+
+	submitter := &bundlerSubmitter{
+		account:        common.HexToAddress(sponsorshipConfig.Account),
+		entryPoint:     common.HexToAddress(sponsorshipConfig.entryPoint()),
+		chainID:        dependencies.chainID,
+		accountKey:     dependencies.accountKey,
+		requestTimeout: requestTimeout(config),
+		bundler:        bundler,
+		paymaster:      paymaster,
+		gasPrice:       dependencies.client.SuggestGasPrice,
+	}
+
+	submitter.accountNonce = func(ctx context.Context) (*big.Int, error) {
+		input, err := parsedEntryPointABI.Pack(
+			"getNonce",
+			submitter.account,
+			big.NewInt(0),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := dependencies.client.CallContract(
+			ctx,
+			ethereum.CallMsg{To: &submitter.entryPoint, Data: input},
+			nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		nonce := new(big.Int)
+		if err := parsedEntryPointABI.Unpack(
+			&nonce,
+			"getNonce",
+			output,
+		); err != nil {
+			return nil, err
+		}
+
+		return nonce, nil
+	}
+
+	return submitter, nil
+}
+
+func (bs *bundlerSubmitter) String() string {
+	return "bundler"
+}
+
+func (bs *bundlerSubmitter) submit(
+	ctx context.Context,
+	to common.Address,
+	input []byte,
+) (string, error) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	callData, err := executeCallData(to, input)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := bs.nonce(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not get smart account nonce: [%v]", err)
+	}
+
+	gasPrice, err := bs.gasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not get gas price: [%v]", err)
+	}
+
+	operation := &userOperation{
+		Sender:               bs.account,
+		Nonce:                (*hexutil.Big)(nonce),
+		InitCode:             hexutil.Bytes{},
+		CallData:             callData,
+		MaxFeePerGas:         (*hexutil.Big)(gasPrice),
+		MaxPriorityFeePerGas: (*hexutil.Big)(gasPrice),
+		PaymasterAndData:     hexutil.Bytes{},
+		Signature:            dummySignature,
+	}
+
+	var limits *gasEstimate
+	if bs.paymaster != nil {
+		sponsorship := &paymasterSponsorship{}
+		if err := bs.call(
+			ctx,
+			bs.paymaster,
+			sponsorship,
+			"pm_sponsorUserOperation",
+			operation,
+			bs.entryPoint,
+		); err != nil {
+			return "", fmt.Errorf(
+				"could not sponsor user operation: [%v]",
+				err,
+			)
+		}
+
+		operation.PaymasterAndData = sponsorship.PaymasterAndData
+		if sponsorship.hasGasLimits() {
+			limits = &sponsorship.gasEstimate
+		}
+	}
+
+	if limits == nil {
+		limits = &gasEstimate{}
+		if err := bs.call(
+			ctx,
+			bs.bundler,
+			limits,
+			"eth_estimateUserOperationGas",
+			operation,
+			bs.entryPoint,
+		); err != nil {
+			return "", fmt.Errorf(
+				"could not estimate user operation gas: [%v]",
+				err,
+			)
+		}
+	}
+
+	operation.CallGasLimit = limits.CallGasLimit.big()
+	operation.VerificationGasLimit = limits.VerificationGasLimit.big()
+	operation.PreVerificationGas = limits.PreVerificationGas.big()
+
+	signature, err := signUserOperationHash(
+		operation.hash(bs.entryPoint, bs.chainID),
+		bs.accountKey,
+	)
+	if err != nil {
+		return "", fmt.Errorf("could not sign user operation: [%v]", err)
+	}
+	operation.Signature = signature
+
+	var userOperationHash string
+	if err := bs.call(
+		ctx,
+		bs.bundler,
+		&userOperationHash,
+		"eth_sendUserOperation",
+		operation,
+		bs.entryPoint,
+	); err != nil {
+		return "", err
+	}
+
+	bs.nextNonce = new(big.Int).Add(nonce, big.NewInt(1))
+
+	return userOperationHash, nil
+}
+
+// nonce returns the nonce of the next user operation, which is the nonce
+// known by the EntryPoint contract unless user operations submitted earlier
+// are not included yet.
+func (bs *bundlerSubmitter) nonce(ctx context.Context) (*big.Int, error) {
+	nonce, err := bs.accountNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if bs.nextNonce != nil && bs.nextNonce.Cmp(nonce) > 0 {
+		return new(big.Int).Set(bs.nextNonce), nil
+	}
+
+	return nonce, nil
+}
+
+// call performs the given RPC request bounded by the request timeout.
+func (bs *bundlerSubmitter) call(
+	ctx context.Context,
+	client *rpc.Client,
+	result interface{},
+	method string,
+	args ...interface{},
+) error {
+	ctx, cancelCtx := context.WithTimeout(ctx, bs.requestTimeout)
+	defer cancelCtx()
+
+	return client.CallContext(ctx, result, method, args...)
+}
+
+// executeCallData returns the call data of the smart account call executing
+// the call of the contract at the given address with the given input.
+func executeCallData(to common.Address, input []byte) ([]byte, error) {
+	parsed, err := hostchainabi.JSON(strings.NewReader(smartAccountABI))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse smart account ABI: [%v]", err)
+	}
+
+	return parsed.Pack("execute", to, big.NewInt(0), input)
+}
+
+// signUserOperationHash signs the given user operation hash as an Ethereum
+// signed message, the way the owner of the smart account does.
+func signUserOperationHash(
+	hash common.Hash,
+	key *keystore.Key,
+) ([]byte, error) {
+	message := crypto.Keccak256(
+		[]byte("\x19Ethereum Signed Message:\n32"),
+		hash.Bytes(),
+	)
+
+	signature, err := crypto.Sign(message, key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// The recovery ID is expected in the legacy Ethereum format.
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return signature, nil
+}
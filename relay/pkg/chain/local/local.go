@@ -1,49 +1,116 @@
 package local
 
 import (
+	"context"
 	"encoding/binary"
+	"fmt"
 	"math/big"
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 
-	"github.com/ipfs/go-log"
 	"github.com/keep-network/tbtc/relay/pkg/chain"
 )
 
-var logger = log.Logger("tbtc-relay-localchain")
-
 // Chain is a local implementation of the host chain interface.
 type Chain struct {
 	bestKnownDigest btc.Digest
+	gasPrice        *big.Int
+	currentBlock    uint64
+	headersHeights  map[btc.Digest]int64
+	proofLength     uint64
+	currentEpoch    uint64
+	maxHeaderGap    uint64
+	operatorAddress string
+	relayAdvances   []*chain.RelayAdvance
+
+	pendingTransactions   int
+	cancelledTransactions int
+
+	pausedContracts []string
+
+	pendingRewards *big.Int
+	claimedRewards []*big.Int
+
+	depositStates         map[string]chain.DepositState
+	redemptionRequests    map[string]*chain.RedemptionRequest
+	depositEvents         []*chain.DepositEvent
+	fundingProofEvents    []*FundingProofEvent
+	redemptionProofEvents []*RedemptionProofEvent
 
 	addHeadersEvents             []*AddHeadersEvent
 	addHeadersWithRetargetEvents []*AddHeadersWithRetargetEvent
 	markNewHeaviestEvent         []*MarkNewHeaviestEvent
+	retargetEvents               []*RetargetEvent
+
+	// relay is the model of the relay contract validating the submitted
+	// headers. If nil, all submissions are accepted.
+	relay *Relay
 }
 
 // Connect performs initialization for communication with the local blockchain.
 func Connect() (chain.Handle, error) {
-	logger.Infof("connecting local host chain")
-
 	return &Chain{
 		addHeadersEvents:             make([]*AddHeadersEvent, 0),
 		addHeadersWithRetargetEvents: make([]*AddHeadersWithRetargetEvent, 0),
 		markNewHeaviestEvent:         make([]*MarkNewHeaviestEvent, 0),
+		headersHeights:               make(map[btc.Digest]int64),
+		depositStates:                make(map[string]chain.DepositState),
+		redemptionRequests:           make(map[string]*chain.RedemptionRequest),
 	}, nil
 }
 
+// SimulateRelay makes the chain validate the submitted headers and track the
+// relay state using the model of the relay contract deployed with the given
+// genesis header at the given height. The periodStart is the digest of the
+// first header of the difficulty epoch of the genesis header. Once enabled,
+// the best known digest and the header heights set for testing purposes are
+// ignored.
+func (c *Chain) SimulateRelay(
+	genesisHeader []byte,
+	height int64,
+	periodStart btc.Digest,
+) error {
+	relay, err := NewRelay(genesisHeader, height, periodStart)
+	if err != nil {
+		return err
+	}
+
+	c.relay = relay
+
+	return nil
+}
+
+// SimulatedRelay returns the model of the relay contract or nil if the relay
+// is not simulated.
+func (c *Chain) SimulatedRelay() *Relay {
+	return c.relay
+}
+
 // GetBestKnownDigest returns the best known digest.
-func (c *Chain) GetBestKnownDigest() (btc.Digest, error) {
+func (c *Chain) GetBestKnownDigest(ctx context.Context) (btc.Digest, error) {
+	if c.relay != nil {
+		return c.relay.BestKnownDigest(), nil
+	}
+
 	return c.bestKnownDigest, nil
 }
 
 // IsAncestor checks if ancestorDigest is an ancestor of the descendantDigest.
 // The limit parameter determines the number of blocks to check.
 func (c *Chain) IsAncestor(
+	ctx context.Context,
 	ancestorDigest btc.Digest,
 	descendantDigest btc.Digest,
 	limit *big.Int,
 ) (bool, error) {
+	if c.relay != nil {
+		return c.relay.IsAncestor(
+			ancestorDigest,
+			descendantDigest,
+			limit.Uint64(),
+		), nil
+	}
+
 	// Naive implementation for testing purposes. If the int representation
 	// of the descendant digest is bigger than the ancestor's one, that
 	// means the condition is true.
@@ -53,14 +120,83 @@ func (c *Chain) IsAncestor(
 }
 
 // FindHeight finds the height of a header by its digest.
-func (c *Chain) FindHeight(digest btc.Digest) (*big.Int, error) {
-	panic("not implemented yet")
+func (c *Chain) FindHeight(
+	ctx context.Context,
+	digest btc.Digest,
+) (*big.Int, error) {
+	if c.relay != nil {
+		height, err := c.relay.FindHeight(digest)
+		if err != nil {
+			return nil, err
+		}
+
+		return big.NewInt(height), nil
+	}
+
+	height, ok := c.headersHeights[digest]
+	if !ok {
+		return nil, fmt.Errorf("unknown block [%v]", digest)
+	}
+
+	return big.NewInt(height), nil
+}
+
+// FindAncestor finds the digest of the ancestor of the header with the given
+// digest, the given number of blocks below it, among the header heights set
+// for testing purposes.
+func (c *Chain) FindAncestor(
+	ctx context.Context,
+	digest btc.Digest,
+	offset *big.Int,
+) (btc.Digest, error) {
+	if c.relay != nil {
+		return c.relay.FindAncestor(digest, offset.Int64())
+	}
+
+	height, err := c.FindHeight(ctx, digest)
+	if err != nil {
+		return btc.Digest{}, err
+	}
+
+	ancestorHeight := new(big.Int).Sub(height, offset).Int64()
+	for ancestorDigest, ancestorCandidateHeight := range c.headersHeights {
+		if ancestorCandidateHeight == ancestorHeight {
+			return ancestorDigest, nil
+		}
+	}
+
+	return btc.Digest{}, fmt.Errorf(
+		"unknown ancestor of block [%v] at offset [%v]",
+		digest,
+		offset,
+	)
+}
+
+// FindHeightAtBlock finds the height of a header by its digest using the
+// relay contract state as of the given host chain block. The local
+// implementation does not keep historical state and ignores the block.
+func (c *Chain) FindHeightAtBlock(
+	ctx context.Context,
+	digest btc.Digest,
+	blockNumber uint64,
+) (*big.Int, error) {
+	return c.FindHeight(ctx, digest)
 }
 
 // AddHeaders adds headers to storage after validating. The anchorHeader
 // parameter is the header immediately preceding the new chain. Headers
 // parameter should be a tightly-packed list of 80-byte Bitcoin headers.
-func (c *Chain) AddHeaders(anchorHeader []byte, headers []byte) error {
+func (c *Chain) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	if c.relay != nil {
+		if err := c.relay.AddHeaders(anchorHeader, headers); err != nil {
+			return err
+		}
+	}
+
 	c.addHeadersEvents = append(
 		c.addHeadersEvents,
 		&AddHeadersEvent{
@@ -77,10 +213,21 @@ func (c *Chain) AddHeaders(anchorHeader []byte, headers []byte) error {
 // difficulty period being closed while oldPeriodEndHeader is the last.
 // Headers parameter should be a tightly-packed list of 80-byte Bitcoin headers.
 func (c *Chain) AddHeadersWithRetarget(
+	ctx context.Context,
 	oldPeriodStartHeader []byte,
 	oldPeriodEndHeader []byte,
 	headers []byte,
 ) error {
+	if c.relay != nil {
+		if err := c.relay.AddHeadersWithRetarget(
+			oldPeriodStartHeader,
+			oldPeriodEndHeader,
+			headers,
+		); err != nil {
+			return err
+		}
+	}
+
 	c.addHeadersWithRetargetEvents = append(
 		c.addHeadersWithRetargetEvents,
 		&AddHeadersWithRetargetEvent{
@@ -99,11 +246,23 @@ func (c *Chain) AddHeadersWithRetarget(
 // while the newBestHeader param should be the header to mark as new best.
 // Limit parameter limits the amount of traversal of the chain.
 func (c *Chain) MarkNewHeaviest(
+	ctx context.Context,
 	ancestorDigest btc.Digest,
 	currentBestHeader []byte,
 	newBestHeader []byte,
 	limit *big.Int,
 ) error {
+	if c.relay != nil {
+		if err := c.relay.MarkNewHeaviest(
+			ancestorDigest,
+			currentBestHeader,
+			newBestHeader,
+			limit.Uint64(),
+		); err != nil {
+			return err
+		}
+	}
+
 	c.markNewHeaviestEvent = append(
 		c.markNewHeaviestEvent,
 		&MarkNewHeaviestEvent{
@@ -121,14 +280,238 @@ func (c *Chain) MarkNewHeaviest(
 // MarkNewHeaviest method to check whether its execution will
 // succeed.
 func (c *Chain) MarkNewHeaviestPreflight(
+	ctx context.Context,
 	ancestorDigest btc.Digest,
 	currentBestHeader []byte,
 	newBestHeader []byte,
 	limit *big.Int,
 ) bool {
+	if c.relay != nil {
+		return c.relay.CheckNewHeaviest(
+			ancestorDigest,
+			currentBestHeader,
+			newBestHeader,
+			limit.Uint64(),
+		) == nil
+	}
+
 	return true
 }
 
+// MaxHeaderGap returns the maximum number of blocks between consecutive
+// headers accepted by the relay contract. Only contiguous headers are
+// accepted unless set otherwise.
+func (c *Chain) MaxHeaderGap(ctx context.Context) (uint64, error) {
+	if c.maxHeaderGap == 0 {
+		return 1, nil
+	}
+
+	return c.maxHeaderGap, nil
+}
+
+// GetGasPrice returns the gas price currently suggested by the host chain.
+func (c *Chain) GetGasPrice(ctx context.Context) (*big.Int, error) {
+	if c.gasPrice == nil {
+		return big.NewInt(0), nil
+	}
+
+	return c.gasPrice, nil
+}
+
+// GetProofLength returns the number of headers required on each side
+// of the difficulty epoch boundary to prove a retarget.
+func (c *Chain) GetProofLength(ctx context.Context) (uint64, error) {
+	return c.proofLength, nil
+}
+
+// GetCurrentEpoch returns the number of the latest difficulty epoch
+// known by the light relay.
+func (c *Chain) GetCurrentEpoch(ctx context.Context) (uint64, error) {
+	return c.currentEpoch, nil
+}
+
+// Retarget adds a new difficulty epoch to the light relay. The local
+// implementation records the invocation and advances the current epoch.
+func (c *Chain) Retarget(ctx context.Context, headers []byte) error {
+	c.retargetEvents = append(c.retargetEvents, &RetargetEvent{headers})
+	c.currentEpoch++
+
+	return nil
+}
+
+// CurrentBlock returns the number of the current host chain block.
+func (c *Chain) CurrentBlock(ctx context.Context) (uint64, error) {
+	return c.currentBlock, nil
+}
+
+// PastRelayAdvances returns the relay advances set for testing purposes
+// which were made within the given range of blocks, both inclusive.
+func (c *Chain) PastRelayAdvances(
+	ctx context.Context,
+	fromBlock uint64,
+	toBlock uint64,
+) ([]*chain.RelayAdvance, error) {
+	advances := make([]*chain.RelayAdvance, 0)
+	for _, advance := range c.relayAdvances {
+		if advance.BlockNumber >= fromBlock && advance.BlockNumber <= toBlock {
+			advances = append(advances, advance)
+		}
+	}
+
+	return advances, nil
+}
+
+// RelayAdvanced checks whether any of the relay advances set for testing
+// purposes was made within the given range of blocks, both inclusive.
+func (c *Chain) RelayAdvanced(
+	ctx context.Context,
+	fromBlock uint64,
+	toBlock uint64,
+) (bool, error) {
+	advances, err := c.PastRelayAdvances(ctx, fromBlock, toBlock)
+	if err != nil {
+		return false, err
+	}
+
+	return len(advances) > 0, nil
+}
+
+// OperatorAddress returns the operator address set for testing purposes.
+func (c *Chain) OperatorAddress() string {
+	return c.operatorAddress
+}
+
+// CancelPendingTransactions cancels the pending transactions set for testing
+// purposes and returns their number.
+func (c *Chain) CancelPendingTransactions(ctx context.Context) (int, error) {
+	cancelled := c.pendingTransactions
+
+	c.cancelledTransactions += cancelled
+	c.pendingTransactions = 0
+
+	return cancelled, nil
+}
+
+// PendingTransactions returns the number of pending transactions set for
+// testing purposes.
+func (c *Chain) PendingTransactions(ctx context.Context) (int, error) {
+	return c.pendingTransactions, nil
+}
+
+// PendingRewards returns the pending rewards set for testing purposes. If no
+// rewards have been set, the relay is considered not to pay rewards.
+func (c *Chain) PendingRewards(ctx context.Context) (*big.Int, error) {
+	if c.pendingRewards == nil {
+		return nil, chain.ErrRewardsNotSupported
+	}
+
+	return new(big.Int).Set(c.pendingRewards), nil
+}
+
+// ClaimRewards claims the pending rewards set for testing purposes.
+func (c *Chain) ClaimRewards(ctx context.Context) error {
+	if c.pendingRewards == nil {
+		return chain.ErrRewardsNotSupported
+	}
+
+	c.claimedRewards = append(c.claimedRewards, c.pendingRewards)
+	c.pendingRewards = big.NewInt(0)
+
+	return nil
+}
+
+// GetDepositState returns the state of the deposit with the given address
+// set for testing purposes.
+func (c *Chain) GetDepositState(
+	ctx context.Context,
+	depositAddress string,
+) (chain.DepositState, error) {
+	return c.depositStates[depositAddress], nil
+}
+
+// GetRedemptionRequest returns the redemption request of the deposit with
+// the given address set for testing purposes. The local implementation
+// ignores the block the request is looked for from.
+func (c *Chain) GetRedemptionRequest(
+	ctx context.Context,
+	depositAddress string,
+	fromBlock uint64,
+) (*chain.RedemptionRequest, error) {
+	return c.redemptionRequests[depositAddress], nil
+}
+
+// PastDepositEvents returns the deposit events set for testing purposes which
+// were emitted within the given range of blocks, both inclusive.
+func (c *Chain) PastDepositEvents(
+	ctx context.Context,
+	fromBlock uint64,
+	toBlock uint64,
+) ([]*chain.DepositEvent, error) {
+	events := make([]*chain.DepositEvent, 0)
+	for _, event := range c.depositEvents {
+		if event.BlockNumber >= fromBlock && event.BlockNumber <= toBlock {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// ProvideFundingProof records the submitted funding proof. The deposit
+// becomes active afterwards.
+func (c *Chain) ProvideFundingProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *chain.TransactionProof,
+	fundingOutputIndex uint8,
+) error {
+	if c.depositStates[depositAddress] != chain.DepositAwaitingFundingProof {
+		return fmt.Errorf(
+			"deposit [%v] is not awaiting funding proof",
+			depositAddress,
+		)
+	}
+
+	c.fundingProofEvents = append(
+		c.fundingProofEvents,
+		&FundingProofEvent{
+			DepositAddress:     depositAddress,
+			Proof:              proof,
+			FundingOutputIndex: fundingOutputIndex,
+		},
+	)
+	c.depositStates[depositAddress] = chain.DepositActive
+
+	return nil
+}
+
+// ProvideRedemptionProof records the submitted redemption proof. The deposit
+// becomes redeemed afterwards.
+func (c *Chain) ProvideRedemptionProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *chain.TransactionProof,
+) error {
+	state := c.depositStates[depositAddress]
+	if state != chain.DepositAwaitingWithdrawalProof {
+		return fmt.Errorf(
+			"deposit [%v] is not awaiting redemption proof",
+			depositAddress,
+		)
+	}
+
+	c.redemptionProofEvents = append(
+		c.redemptionProofEvents,
+		&RedemptionProofEvent{
+			DepositAddress: depositAddress,
+			Proof:          proof,
+		},
+	)
+	c.depositStates[depositAddress] = chain.DepositRedeemed
+
+	return nil
+}
+
 // AddHeadersEvents returns all invocations of the AddHeaders method for
 // testing purposes.
 func (c *Chain) AddHeadersEvents() []*AddHeadersEvent {
@@ -152,6 +535,124 @@ func (c *Chain) SetBestKnownDigest(bestKnownDigest btc.Digest) {
 	c.bestKnownDigest = bestKnownDigest
 }
 
+// SetGasPrice sets the internal gas price for testing purposes.
+func (c *Chain) SetGasPrice(gasPrice *big.Int) {
+	c.gasPrice = gasPrice
+}
+
+// RetargetEvents returns all invocations of the Retarget method for testing
+// purposes.
+func (c *Chain) RetargetEvents() []*RetargetEvent {
+	return c.retargetEvents
+}
+
+// SetMaxHeaderGap sets the maximum number of blocks between consecutive
+// headers for testing purposes.
+func (c *Chain) SetMaxHeaderGap(maxHeaderGap uint64) {
+	c.maxHeaderGap = maxHeaderGap
+}
+
+// SetProofLength sets the light relay proof length for testing purposes.
+func (c *Chain) SetProofLength(proofLength uint64) {
+	c.proofLength = proofLength
+}
+
+// SetCurrentEpoch sets the light relay current epoch for testing purposes.
+func (c *Chain) SetCurrentEpoch(currentEpoch uint64) {
+	c.currentEpoch = currentEpoch
+}
+
+// SetCurrentBlock sets the current host chain block for testing purposes.
+func (c *Chain) SetCurrentBlock(currentBlock uint64) {
+	c.currentBlock = currentBlock
+}
+
+// SetHeaderHeight makes the header with given digest known at the given
+// height for testing purposes.
+func (c *Chain) SetHeaderHeight(digest btc.Digest, height int64) {
+	c.headersHeights[digest] = height
+}
+
+// SetRelayAdvances sets the relay advances for testing purposes.
+func (c *Chain) SetRelayAdvances(advances []*chain.RelayAdvance) {
+	c.relayAdvances = advances
+}
+
+// SetOperatorAddress sets the operator address for testing purposes.
+func (c *Chain) SetOperatorAddress(operatorAddress string) {
+	c.operatorAddress = operatorAddress
+}
+
+// PausedContracts returns the paused contracts set for testing purposes.
+func (c *Chain) PausedContracts(ctx context.Context) ([]string, error) {
+	return c.pausedContracts, nil
+}
+
+// SetPausedContracts sets the paused contracts for testing purposes.
+func (c *Chain) SetPausedContracts(contracts ...string) {
+	c.pausedContracts = contracts
+}
+
+// SetPendingTransactions sets the number of transactions not mined yet for
+// testing purposes.
+func (c *Chain) SetPendingTransactions(pendingTransactions int) {
+	c.pendingTransactions = pendingTransactions
+}
+
+// CancelledTransactions returns the number of transactions cancelled so far
+// for testing purposes.
+func (c *Chain) CancelledTransactions() int {
+	return c.cancelledTransactions
+}
+
+// SetPendingRewards sets the rewards accrued by the operator for testing
+// purposes.
+func (c *Chain) SetPendingRewards(pendingRewards *big.Int) {
+	c.pendingRewards = pendingRewards
+}
+
+// ClaimedRewards returns the amounts of all rewards claimed so far for
+// testing purposes.
+func (c *Chain) ClaimedRewards() []*big.Int {
+	return c.claimedRewards
+}
+
+// SetDepositState sets the state of the deposit with the given address for
+// testing purposes.
+func (c *Chain) SetDepositState(
+	depositAddress string,
+	state chain.DepositState,
+) {
+	c.depositStates[depositAddress] = state
+}
+
+// SetRedemptionRequest sets the redemption request of the deposit with the
+// given address for testing purposes.
+func (c *Chain) SetRedemptionRequest(
+	depositAddress string,
+	request *chain.RedemptionRequest,
+) {
+	c.redemptionRequests[depositAddress] = request
+}
+
+// SetDepositEvents sets the deposit events emitted by the tBTC system for
+// testing purposes.
+func (c *Chain) SetDepositEvents(events []*chain.DepositEvent) {
+	c.depositEvents = events
+}
+
+// FundingProofEvents returns all successful invocations of the
+// ProvideFundingProof method for testing purposes.
+func (c *Chain) FundingProofEvents() []*FundingProofEvent {
+	return c.fundingProofEvents
+}
+
+// RedemptionProofEvents returns all successful invocations of the
+// ProvideRedemptionProof method for testing purposes.
+func (c *Chain) RedemptionProofEvents() []*RedemptionProofEvent {
+	return c.redemptionProofEvents
+}
+
 // AddHeadersEvent represents an invocation of the AddHeaders method.
 type AddHeadersEvent struct {
 	AnchorHeader []byte
@@ -173,3 +674,23 @@ type MarkNewHeaviestEvent struct {
 	NewBestHeader     []byte
 	Limit             *big.Int
 }
+
+// RetargetEvent represents an invocation of the Retarget method.
+type RetargetEvent struct {
+	Headers []byte
+}
+
+// FundingProofEvent represents an invocation of the ProvideFundingProof
+// method.
+type FundingProofEvent struct {
+	DepositAddress     string
+	Proof              *chain.TransactionProof
+	FundingOutputIndex uint8
+}
+
+// RedemptionProofEvent represents an invocation of the ProvideRedemptionProof
+// method.
+type RedemptionProofEvent struct {
+	DepositAddress string
+	Proof          *chain.TransactionProof
+}
@@ -0,0 +1,640 @@
+package local
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// relay.go file contains the behavioral model of the relay contract. Unlike
+// the plain local chain, which records any submission, the model validates
+// the submitted headers and tracks the relay state the same way the Solidity
+// contract does: it checks the work and linkage of headers, the retarget
+// rules and the heaviest chain rule, and rejects invalid submissions with
+// the revert reasons of the contract.
+
+const (
+	// Number of blocks in a Bitcoin difficulty epoch.
+	epochLength = 2016
+
+	// Expected duration of a Bitcoin difficulty epoch, in seconds.
+	retargetPeriod = 2 * 7 * 24 * 60 * 60
+
+	// Size of a serialized Bitcoin header.
+	headerSize = 80
+)
+
+// diff1Target is the target of the difficulty 1, i.e. 0xffff * 256**26.
+var diff1Target = new(big.Int).Lsh(big.NewInt(0xffff), 208)
+
+// Revert reasons of the relay contract.
+var (
+	errBadHeaderLength         = errors.New("Bad args. Check header and array byte lengths.")
+	errHeadersLength           = errors.New("Header array length must be divisible by 80")
+	errAnchorLength            = errors.New("Anchor must be 80 bytes")
+	errSliceOutOfBounds        = errors.New("Slice out of bounds")
+	errUnknownBlock            = errors.New("Unknown block")
+	errUnexpectedRetarget      = errors.New("Unexpected retarget on external call")
+	errInsufficientWork        = errors.New("Header work is insufficient")
+	errTargetChanged           = errors.New("Target changed unexpectedly")
+	errInconsistentChain       = errors.New("Headers do not form a consistent chain")
+	errNotPeriodEnd            = errors.New("Must provide the last header of the closing difficulty period")
+	errNotOnePeriod            = errors.New("Must provide exactly 1 difficulty period")
+	errPeriodDifficulties      = errors.New("Period header difficulties do not match")
+	errInvalidRetarget         = errors.New("Invalid retarget provided")
+	errBestNotBestKnown        = errors.New("Passed in best is not best known")
+	errNewBestUnknown          = errors.New("New best is unknown")
+	errNotHeaviestAncestor     = errors.New("Ancestor must be heaviest common ancestor")
+	errNotMoreWork             = errors.New("New best hash does not have more work than previous")
+	errDescendantBelowAncestor = errors.New("A descendant height is below the ancestor height")
+)
+
+// Relay is the behavioral model of the relay contract.
+type Relay struct {
+	mutex sync.RWMutex
+
+	bestKnownDigest         btc.Digest
+	lastReorgCommonAncestor btc.Digest
+	currentEpochDifficulty  *big.Int
+	prevEpochDifficulty     *big.Int
+
+	previousBlock map[btc.Digest]btc.Digest
+	blockHeight   map[btc.Digest]int64
+}
+
+// NewRelay creates the model of the relay contract deployed with the given
+// genesis header at the given height. The periodStart is the digest of the
+// first header of the difficulty epoch of the genesis header.
+func NewRelay(
+	genesisHeader []byte,
+	height int64,
+	periodStart btc.Digest,
+) (*Relay, error) {
+	if len(genesisHeader) != headerSize {
+		return nil, errors.New("Stop being dumb")
+	}
+
+	// The digest is in the internal byte order, so its last bytes are the
+	// most significant ones, which are zero for any header with work.
+	if !bytes.Equal(periodStart[29:], []byte{0, 0, 0}) {
+		return nil, errors.New(
+			"Period start hash does not have work. Hint: wrong byte order?",
+		)
+	}
+
+	genesis, err := parseRelayHeader(genesisHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Relay{
+		bestKnownDigest:         genesis.digest,
+		lastReorgCommonAncestor: genesis.digest,
+		currentEpochDifficulty:  genesis.difficulty(),
+		prevEpochDifficulty:     big.NewInt(0),
+		previousBlock:           make(map[btc.Digest]btc.Digest),
+		blockHeight: map[btc.Digest]int64{
+			genesis.digest: height,
+			periodStart:    height - height%epochLength,
+		},
+	}, nil
+}
+
+// relayHeader is a Bitcoin header as seen by the relay contract.
+type relayHeader struct {
+	digest    btc.Digest
+	previous  btc.Digest
+	target    *big.Int
+	timestamp int64
+}
+
+func parseRelayHeader(raw []byte) (*relayHeader, error) {
+	blockHeader := &wire.BlockHeader{}
+	if err := blockHeader.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("could not deserialize header: [%v]", err)
+	}
+
+	return &relayHeader{
+		digest:    btc.Digest(blockHeader.BlockHash()),
+		previous:  btc.Digest(blockHeader.PrevBlock),
+		target:    blockchain.CompactToBig(blockHeader.Bits),
+		timestamp: blockHeader.Timestamp.Unix(),
+	}, nil
+}
+
+// difficulty returns the difficulty of the header the same way the contract
+// computes it, i.e. rounded down.
+func (rh *relayHeader) difficulty() *big.Int {
+	return new(big.Int).Div(diff1Target, rh.target)
+}
+
+// hasWork checks whether the header digest meets its target.
+func (rh *relayHeader) hasWork() bool {
+	hash := chainhash.Hash(rh.digest)
+	return blockchain.HashToBig(&hash).Cmp(rh.target) <= 0
+}
+
+// BestKnownDigest returns the digest of the best known header.
+func (r *Relay) BestKnownDigest() btc.Digest {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.bestKnownDigest
+}
+
+// LastReorgCommonAncestor returns the digest of the common ancestor of the
+// last new best header marked and the previous best header.
+func (r *Relay) LastReorgCommonAncestor() btc.Digest {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.lastReorgCommonAncestor
+}
+
+// CurrentEpochDifficulty returns the difficulty of the best known header.
+func (r *Relay) CurrentEpochDifficulty() *big.Int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return new(big.Int).Set(r.currentEpochDifficulty)
+}
+
+// PrevEpochDifficulty returns the difficulty of the difficulty epoch
+// preceding the best known header, or zero if not known yet.
+func (r *Relay) PrevEpochDifficulty() *big.Int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return new(big.Int).Set(r.prevEpochDifficulty)
+}
+
+// FindHeight finds the height of a header by its digest.
+func (r *Relay) FindHeight(digest btc.Digest) (int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.findHeight(digest)
+}
+
+func (r *Relay) findHeight(digest btc.Digest) (int64, error) {
+	height, ok := r.blockHeight[digest]
+	if !ok {
+		return 0, errUnknownBlock
+	}
+
+	return height, nil
+}
+
+// FindAncestor finds the digest of the ancestor of the header with the
+// given digest, the given number of blocks below it.
+func (r *Relay) FindAncestor(digest btc.Digest, offset int64) (btc.Digest, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, err := r.findHeight(digest); err != nil {
+		return btc.Digest{}, err
+	}
+
+	current := digest
+	for i := int64(0); i < offset; i++ {
+		previous, ok := r.previousBlock[current]
+		if !ok {
+			return btc.Digest{}, errUnknownBlock
+		}
+
+		current = previous
+	}
+
+	return current, nil
+}
+
+// IsAncestor checks if the ancestor is an ancestor of the descendant within
+// the given number of blocks.
+func (r *Relay) IsAncestor(
+	ancestor btc.Digest,
+	descendant btc.Digest,
+	limit uint64,
+) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	current := descendant
+	for i := uint64(0); i < limit; i++ {
+		if current == ancestor {
+			return true
+		}
+
+		current = r.previousBlock[current]
+	}
+
+	return false
+}
+
+// AddHeaders adds headers building on the known anchor header. Headers must
+// keep the target of the anchor header.
+func (r *Relay) AddHeaders(anchorHeader []byte, headers []byte) error {
+	if len(headers)%headerSize != 0 {
+		return errHeadersLength
+	}
+
+	if len(anchorHeader) != headerSize {
+		return errAnchorLength
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.addHeaders(anchorHeader, headers, false)
+}
+
+// AddHeadersWithRetarget adds headers starting a new difficulty epoch. The
+// oldPeriodStartHeader and oldPeriodEndHeader are the first and the last
+// header of the closing epoch and must be known.
+func (r *Relay) AddHeadersWithRetarget(
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) error {
+	if len(oldPeriodStartHeader) != headerSize ||
+		len(oldPeriodEndHeader) != headerSize {
+		return errBadHeaderLength
+	}
+
+	if len(headers) < headerSize {
+		return errSliceOutOfBounds
+	}
+
+	oldStart, err := parseRelayHeader(oldPeriodStartHeader)
+	if err != nil {
+		return err
+	}
+
+	oldEnd, err := parseRelayHeader(oldPeriodEndHeader)
+	if err != nil {
+		return err
+	}
+
+	newStart, err := parseRelayHeader(headers[:headerSize])
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	startHeight, err := r.findHeight(oldStart.digest)
+	if err != nil {
+		return err
+	}
+
+	endHeight, err := r.findHeight(oldEnd.digest)
+	if err != nil {
+		return err
+	}
+
+	if endHeight%epochLength != epochLength-1 {
+		return errNotPeriodEnd
+	}
+
+	if endHeight != startHeight+epochLength-1 {
+		return errNotOnePeriod
+	}
+
+	oldDifficulty := oldStart.difficulty()
+	if oldDifficulty.Cmp(oldEnd.difficulty()) != 0 {
+		return errPeriodDifficulties
+	}
+
+	// The comparison is not strict as the compact encoding of the target
+	// in the header truncates it.
+	actualTarget := newStart.target
+	expectedTarget := retargetAlgorithm(
+		oldStart.target,
+		oldStart.timestamp,
+		oldEnd.timestamp,
+	)
+	if new(big.Int).And(actualTarget, expectedTarget).Cmp(actualTarget) != 0 {
+		return errInvalidRetarget
+	}
+
+	// The previous epoch difficulty is updated only if the closed epoch is
+	// near the chain tip.
+	bestHeight, err := r.findHeight(r.bestKnownDigest)
+	if err != nil {
+		return err
+	}
+	if r.prevEpochDifficulty.Cmp(oldDifficulty) != 0 &&
+		endHeight > bestHeight-epochLength {
+		r.prevEpochDifficulty = oldDifficulty
+	}
+
+	return r.addHeaders(oldPeriodEndHeader, headers, true)
+}
+
+// addHeaders validates and stores the headers. Must be called with the
+// mutex locked.
+func (r *Relay) addHeaders(
+	anchorHeader []byte,
+	headers []byte,
+	internal bool,
+) error {
+	if len(headers) < headerSize {
+		return errSliceOutOfBounds
+	}
+
+	anchor, err := parseRelayHeader(anchorHeader)
+	if err != nil {
+		return err
+	}
+
+	anchorHeight, err := r.findHeight(anchor.digest)
+	if err != nil {
+		return err
+	}
+
+	first, err := parseRelayHeader(headers[:headerSize])
+	if err != nil {
+		return err
+	}
+	target := first.target
+
+	if !internal && anchor.target.Cmp(target) != 0 {
+		return errUnexpectedRetarget
+	}
+
+	// The contract reverts the whole batch on the first invalid header, so
+	// headers are stored only once all of them are validated.
+	previousBlock := make(map[btc.Digest]btc.Digest)
+	blockHeight := make(map[btc.Digest]int64)
+
+	previousDigest := anchor.digest
+	for i := 0; i < len(headers)/headerSize; i++ {
+		header, err := parseRelayHeader(
+			headers[i*headerSize : (i+1)*headerSize],
+		)
+		if err != nil {
+			return err
+		}
+
+		// Already known headers are not checked for work again.
+		if _, known := r.previousBlock[header.digest]; !known {
+			if !header.hasWork() {
+				return errInsufficientWork
+			}
+
+			previousBlock[header.digest] = previousDigest
+			blockHeight[header.digest] = anchorHeight + int64(i) + 1
+		}
+
+		if header.target.Cmp(target) != 0 {
+			return errTargetChanged
+		}
+
+		if header.previous != previousDigest {
+			return errInconsistentChain
+		}
+
+		previousDigest = header.digest
+	}
+
+	for digest, previous := range previousBlock {
+		r.previousBlock[digest] = previous
+		r.blockHeight[digest] = blockHeight[digest]
+	}
+
+	return nil
+}
+
+// retargetAlgorithm computes the target of the next difficulty epoch the
+// same way the contract does, including the rounding.
+func retargetAlgorithm(
+	previousTarget *big.Int,
+	firstTimestamp int64,
+	secondTimestamp int64,
+) *big.Int {
+	elapsedTime := secondTimestamp - firstTimestamp
+
+	// Normalize the ratio to the factor of 4 if very long or very short.
+	if elapsedTime < retargetPeriod/4 {
+		elapsedTime = retargetPeriod / 4
+	}
+	if elapsedTime > retargetPeriod*4 {
+		elapsedTime = retargetPeriod * 4
+	}
+
+	adjusted := new(big.Int).Div(previousTarget, big.NewInt(65536))
+	adjusted.Mul(adjusted, big.NewInt(elapsedTime))
+	adjusted.Div(adjusted, big.NewInt(retargetPeriod))
+
+	return adjusted.Mul(adjusted, big.NewInt(65536))
+}
+
+// MarkNewHeaviest marks the new best header. The ancestorDigest is the digest
+// of the most recent common ancestor of the current and the new best header
+// while the limit bounds the traversal of the chain.
+func (r *Relay) MarkNewHeaviest(
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit uint64,
+) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	newBest, err := r.checkNewHeaviest(
+		ancestorDigest,
+		currentBestHeader,
+		newBestHeader,
+		limit,
+	)
+	if err != nil {
+		return err
+	}
+
+	r.bestKnownDigest = newBest.digest
+	r.lastReorgCommonAncestor = ancestorDigest
+	r.currentEpochDifficulty = newBest.difficulty()
+
+	return nil
+}
+
+// CheckNewHeaviest checks whether marking the new best header would
+// succeed, without changing the relay state.
+func (r *Relay) CheckNewHeaviest(
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit uint64,
+) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	_, err := r.checkNewHeaviest(
+		ancestorDigest,
+		currentBestHeader,
+		newBestHeader,
+		limit,
+	)
+	return err
+}
+
+// checkNewHeaviest validates marking the new best header and returns the
+// parsed new best header. Must be called with the mutex locked.
+func (r *Relay) checkNewHeaviest(
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit uint64,
+) (*relayHeader, error) {
+	if len(newBestHeader) != headerSize ||
+		len(currentBestHeader) != headerSize {
+		return nil, errBadHeaderLength
+	}
+
+	newBest, err := parseRelayHeader(newBestHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	currentBest, err := parseRelayHeader(currentBestHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentBest.digest != r.bestKnownDigest {
+		return nil, errBestNotBestKnown
+	}
+
+	if _, known := r.previousBlock[newBest.digest]; !known {
+		return nil, errNewBestUnknown
+	}
+
+	if !r.isMostRecentAncestor(
+		ancestorDigest,
+		r.bestKnownDigest,
+		newBest.digest,
+		limit,
+	) {
+		return nil, errNotHeaviestAncestor
+	}
+
+	heaviest, err := r.heaviestFromAncestor(
+		ancestorDigest,
+		currentBest,
+		newBest,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if heaviest != newBest.digest {
+		return nil, errNotMoreWork
+	}
+
+	return newBest, nil
+}
+
+// isMostRecentAncestor checks whether the ancestor is the most recent common
+// ancestor of the left and right headers within the given number of blocks.
+// Must be called with the mutex locked.
+func (r *Relay) isMostRecentAncestor(
+	ancestor btc.Digest,
+	left btc.Digest,
+	right btc.Digest,
+	limit uint64,
+) bool {
+	if ancestor == left && ancestor == right {
+		return true
+	}
+
+	leftCurrent, rightCurrent := left, right
+	leftPrevious, rightPrevious := left, right
+
+	for i := uint64(0); i < limit; i++ {
+		if leftPrevious != ancestor {
+			leftCurrent = leftPrevious
+			leftPrevious = r.previousBlock[leftPrevious]
+		}
+		if rightPrevious != ancestor {
+			rightCurrent = rightPrevious
+			rightPrevious = r.previousBlock[rightPrevious]
+		}
+	}
+
+	// If both branches meet below the ancestor, there is a nearer one.
+	if leftCurrent == rightCurrent {
+		return false
+	}
+
+	// Both branches must reach the ancestor.
+	return leftPrevious == rightPrevious
+}
+
+// heaviestFromAncestor returns the digest of the header with more work since
+// the common ancestor. In case of a tie, the left header is returned. Must
+// be called with the mutex locked.
+func (r *Relay) heaviestFromAncestor(
+	ancestor btc.Digest,
+	left *relayHeader,
+	right *relayHeader,
+) (btc.Digest, error) {
+	ancestorHeight, err := r.findHeight(ancestor)
+	if err != nil {
+		return btc.Digest{}, err
+	}
+
+	leftHeight, err := r.findHeight(left.digest)
+	if err != nil {
+		return btc.Digest{}, err
+	}
+
+	rightHeight, err := r.findHeight(right.digest)
+	if err != nil {
+		return btc.Digest{}, err
+	}
+
+	if leftHeight < ancestorHeight || rightHeight < ancestorHeight {
+		return btc.Digest{}, errDescendantBelowAncestor
+	}
+
+	nextPeriodStartHeight := ancestorHeight + epochLength -
+		ancestorHeight%epochLength
+	leftInPeriod := leftHeight < nextPeriodStartHeight
+	rightInPeriod := rightHeight < nextPeriodStartHeight
+
+	switch {
+	case !leftInPeriod && rightInPeriod:
+		// Only the left header is in a new difficulty epoch.
+		return left.digest, nil
+	case leftInPeriod && !rightInPeriod:
+		// Only the right header is in a new difficulty epoch.
+		return right.digest, nil
+	case leftInPeriod && rightInPeriod:
+		// Both headers are in the same epoch; the higher one wins.
+		if leftHeight >= rightHeight {
+			return left.digest, nil
+		}
+		return right.digest, nil
+	default:
+		// Both headers are in new epochs; the heavier one wins.
+		leftWork := new(big.Int).Mul(
+			big.NewInt(leftHeight%epochLength),
+			left.difficulty(),
+		)
+		rightWork := new(big.Int).Mul(
+			big.NewInt(rightHeight%epochLength),
+			right.difficulty(),
+		)
+		if leftWork.Cmp(rightWork) < 0 {
+			return right.digest, nil
+		}
+		return left.digest, nil
+	}
+}
@@ -0,0 +1,389 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+const (
+	// Bits of the target of headers in the tests; roughly every other hash
+	// meets it.
+	testBits = 0x207fffff
+	// Bits of the target twice as high as the testBits one, truncated by
+	// the compact encoding.
+	testRetargetBits = 0x2100ffff
+)
+
+// testHeader creates a serialized header on top of the given header. If
+// withWork is set, the nonce is ground until the header meets its target;
+// otherwise until it does not.
+func testHeader(
+	t *testing.T,
+	previous []byte,
+	bits uint32,
+	timestamp int64,
+	withWork bool,
+) []byte {
+	blockHeader := &wire.BlockHeader{
+		Version:   4,
+		Bits:      bits,
+		Timestamp: time.Unix(timestamp, 0),
+	}
+	if previous != nil {
+		blockHeader.PrevBlock = chainhash.DoubleHashH(previous)
+	}
+
+	target := blockchain.CompactToBig(bits)
+	for {
+		hash := blockHeader.BlockHash()
+		if (blockchain.HashToBig(&hash).Cmp(target) <= 0) == withWork {
+			break
+		}
+		blockHeader.Nonce++
+	}
+
+	var raw bytes.Buffer
+	if err := blockHeader.Serialize(&raw); err != nil {
+		t.Fatal(err)
+	}
+
+	return raw.Bytes()
+}
+
+func digest(header []byte) btc.Digest {
+	return btc.Digest(chainhash.DoubleHashH(header))
+}
+
+func concat(headers ...[]byte) []byte {
+	return bytes.Join(headers, nil)
+}
+
+func TestRelay(t *testing.T) {
+	// The genesis header starts the first difficulty epoch, which takes
+	// twice as long as expected, so the target doubles in the next one.
+	periodStart := testHeader(t, nil, testBits, 0, true)
+
+	relay, err := NewRelay(periodStart, 0, btc.Digest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	epoch := make([][]byte, epochLength-1)
+	previous := periodStart
+	for i := range epoch {
+		epoch[i] = testHeader(
+			t,
+			previous,
+			testBits,
+			int64(i+1)*2*retargetPeriod/(epochLength-1),
+			true,
+		)
+		previous = epoch[i]
+	}
+	genesis := epoch[len(epoch)-1]
+
+	if err := relay.AddHeaders(periodStart, concat(epoch...)); err != nil {
+		t.Fatal(err)
+	}
+
+	n1 := testHeader(t, genesis, testRetargetBits, 2*retargetPeriod+600, true)
+	a2 := testHeader(t, n1, testRetargetBits, 2*retargetPeriod+1200, true)
+	a3 := testHeader(t, a2, testRetargetBits, 2*retargetPeriod+1800, true)
+	b2 := testHeader(t, n1, testRetargetBits, 2*retargetPeriod+1300, true)
+
+	rejectedRetargets := map[string]struct {
+		oldPeriodStart []byte
+		oldPeriodEnd   []byte
+		headers        []byte
+		expectedErr    error
+	}{
+		"unchanged target": {
+			oldPeriodStart: periodStart,
+			oldPeriodEnd:   genesis,
+			headers:        testHeader(t, genesis, testBits, 0, true),
+			expectedErr:    errInvalidRetarget,
+		},
+		"unknown period start": {
+			oldPeriodStart: testHeader(t, nil, testBits, 1, true),
+			oldPeriodEnd:   genesis,
+			headers:        n1,
+			expectedErr:    errUnknownBlock,
+		},
+		"bad header length": {
+			oldPeriodStart: periodStart[:79],
+			oldPeriodEnd:   genesis,
+			headers:        n1,
+			expectedErr:    errBadHeaderLength,
+		},
+	}
+	for name, test := range rejectedRetargets {
+		if err := relay.AddHeadersWithRetarget(
+			test.oldPeriodStart,
+			test.oldPeriodEnd,
+			test.headers,
+		); err != test.expectedErr {
+			t.Errorf(
+				"%v: unexpected error:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				name,
+				test.expectedErr,
+				err,
+			)
+		}
+	}
+
+	if err := relay.AddHeadersWithRetarget(
+		periodStart,
+		genesis,
+		concat(n1, a2, a3),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := relay.AddHeaders(n1, b2); err != nil {
+		t.Fatal(err)
+	}
+
+	if height, err := relay.FindHeight(digest(a3)); err != nil ||
+		height != epochLength+2 {
+		t.Errorf("unexpected height [%v]: [%v]", height, err)
+	}
+
+	if err := relay.AddHeadersWithRetarget(
+		periodStart,
+		a2,
+		testHeader(t, a2, testRetargetBits, 0, true),
+	); err != errNotPeriodEnd {
+		t.Errorf("unexpected error for retarget not closing period: [%v]", err)
+	}
+
+	rejectedBatches := map[string]struct {
+		anchor      []byte
+		headers     []byte
+		expectedErr error
+	}{
+		"unknown anchor": {
+			anchor:      testHeader(t, nil, testRetargetBits, 0, true),
+			headers:     testHeader(t, a3, testRetargetBits, 0, true),
+			expectedErr: errUnknownBlock,
+		},
+		"insufficient work": {
+			anchor:      a3,
+			headers:     testHeader(t, a3, testRetargetBits, 0, false),
+			expectedErr: errInsufficientWork,
+		},
+		"inconsistent chain": {
+			anchor:      a3,
+			headers:     testHeader(t, a2, testRetargetBits, 0, true),
+			expectedErr: errInconsistentChain,
+		},
+		"unexpected retarget": {
+			anchor:      a3,
+			headers:     testHeader(t, a3, testBits, 0, true),
+			expectedErr: errUnexpectedRetarget,
+		},
+		"target changed": {
+			anchor: a3,
+			headers: concat(
+				testHeader(t, a3, testRetargetBits, 1, true),
+				testHeader(
+					t,
+					testHeader(t, a3, testRetargetBits, 1, true),
+					testBits,
+					2,
+					true,
+				),
+			),
+			expectedErr: errTargetChanged,
+		},
+		"bad anchor length": {
+			anchor:      a3[:79],
+			headers:     testHeader(t, a3, testRetargetBits, 0, true),
+			expectedErr: errAnchorLength,
+		},
+		"bad headers length": {
+			anchor:      a3,
+			headers:     testHeader(t, a3, testRetargetBits, 0, true)[:79],
+			expectedErr: errHeadersLength,
+		},
+	}
+	for name, test := range rejectedBatches {
+		if err := relay.AddHeaders(
+			test.anchor,
+			test.headers,
+		); err != test.expectedErr {
+			t.Errorf(
+				"%v: unexpected error:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				name,
+				test.expectedErr,
+				err,
+			)
+		}
+
+		// The whole batch is reverted.
+		if _, err := relay.FindHeight(
+			digest(test.headers[:headerSize]),
+		); len(test.headers) >= headerSize && err == nil {
+			t.Errorf("%v: rejected header stored", name)
+		}
+	}
+
+	// The period start header is the best known one; the header of the new
+	// epoch is heavier.
+	if err := relay.MarkNewHeaviest(
+		digest(periodStart),
+		periodStart,
+		a3,
+		2*epochLength,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if relay.BestKnownDigest() != digest(a3) {
+		t.Errorf("best known digest not updated")
+	}
+
+	if relay.LastReorgCommonAncestor() != digest(periodStart) {
+		t.Errorf("last reorg common ancestor not updated")
+	}
+
+	rejectedBests := map[string]struct {
+		ancestor    btc.Digest
+		currentBest []byte
+		newBest     []byte
+		expectedErr error
+	}{
+		"shorter fork": {
+			ancestor:    digest(n1),
+			currentBest: a3,
+			newBest:     b2,
+			expectedErr: errNotMoreWork,
+		},
+		"not most recent ancestor": {
+			ancestor:    digest(genesis),
+			currentBest: a3,
+			newBest:     b2,
+			expectedErr: errNotHeaviestAncestor,
+		},
+		"stale best": {
+			ancestor:    digest(n1),
+			currentBest: a2,
+			newBest:     b2,
+			expectedErr: errBestNotBestKnown,
+		},
+		"unknown new best": {
+			ancestor:    digest(a3),
+			currentBest: a3,
+			newBest:     testHeader(t, a3, testRetargetBits, 0, true),
+			expectedErr: errNewBestUnknown,
+		},
+	}
+	for name, test := range rejectedBests {
+		if err := relay.MarkNewHeaviest(
+			test.ancestor,
+			test.currentBest,
+			test.newBest,
+			10,
+		); err != test.expectedErr {
+			t.Errorf(
+				"%v: unexpected error:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				name,
+				test.expectedErr,
+				err,
+			)
+		}
+	}
+
+	if !relay.IsAncestor(digest(n1), digest(a3), 3) {
+		t.Errorf("header should be an ancestor")
+	}
+
+	if relay.IsAncestor(digest(b2), digest(a3), 10) {
+		t.Errorf("fork header should not be an ancestor")
+	}
+}
+
+func TestChain_SimulateRelay(t *testing.T) {
+	ctx := context.Background()
+
+	genesis := testHeader(t, nil, testBits, 1, true)
+	header := testHeader(t, genesis, testBits, 2, true)
+
+	lc, err := Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*Chain)
+	if err := localChain.SimulateRelay(genesis, 100, btc.Digest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := localChain.AddHeaders(
+		ctx,
+		genesis,
+		testHeader(t, genesis, testBits, 2, false),
+	); err == nil {
+		t.Errorf("expected error for insufficient work")
+	}
+
+	if err := localChain.AddHeaders(ctx, genesis, header); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := len(localChain.AddHeadersEvents()); events != 1 {
+		t.Errorf("unexpected number of add headers events: [%v]", events)
+	}
+
+	limit := big.NewInt(5)
+	if !localChain.MarkNewHeaviestPreflight(
+		ctx,
+		digest(genesis),
+		genesis,
+		header,
+		limit,
+	) {
+		t.Errorf("preflight should succeed")
+	}
+
+	if err := localChain.MarkNewHeaviest(
+		ctx,
+		digest(genesis),
+		genesis,
+		header,
+		limit,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	bestKnownDigest, err := localChain.GetBestKnownDigest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bestKnownDigest != digest(header) {
+		t.Errorf("best known digest not updated")
+	}
+
+	height, err := localChain.FindHeight(ctx, bestKnownDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if height.Int64() != 101 {
+		t.Errorf("unexpected height of the best header: [%v]", height)
+	}
+}
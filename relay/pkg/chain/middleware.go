@@ -0,0 +1,457 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// middleware.go file contains the middlewares wrapping the host chain writer.
+// Each transaction submission goes through the chain of middlewares before it
+// reaches the wrapped writer, so behaviors like logging, metrics, gas price
+// gating, dry runs and rate limiting are composed instead of being built into
+// the code submitting the transactions. Reads are never affected.
+
+// Names of the submissions made through the writer.
+const (
+	SubmissionAddHeaders                = "addHeaders"
+	SubmissionAddHeadersWithRetarget    = "addHeadersWithRetarget"
+	SubmissionMarkNewHeaviest           = "markNewHeaviest"
+	SubmissionRetarget                  = "retarget"
+	SubmissionClaimRewards              = "claimRewards"
+	SubmissionCancelPendingTransactions = "cancelPendingTransactions"
+	SubmissionProvideFundingProof       = "provideBTCFundingProof"
+	SubmissionProvideRedemptionProof    = "provideRedemptionProof"
+)
+
+// WriterConfig is the configuration of the optional writer middlewares.
+type WriterConfig struct {
+	// DryRun makes the relay log the transactions it would submit instead
+	// of submitting them.
+	DryRun bool
+	// MinSubmissionInterval is the minimum time, in seconds, between the
+	// starts of consecutive transaction submissions. If zero, submissions
+	// are not rate limited.
+	MinSubmissionInterval int
+	// MaxGasPrice is the gas price, in Gwei, above which transaction
+	// submissions are rejected. Header pushes are deferred instead, as the
+	// gas price ceiling of the relay is capped at that price. If zero,
+	// submissions are not gated.
+	MaxGasPrice int64
+	// PauseTick is the interval, in seconds, in which the pause flags of
+	// the contracts are checked. If zero, a default value is used.
+	PauseTick int
+}
+
+// Size of a serialized Bitcoin header in bytes.
+const headerSize = 80
+
+// Submission describes a transaction submission made through the writer.
+type Submission struct {
+	// Method is the name of the submission, one of the Submission*
+	// constants.
+	Method string
+	// HeadersCount is the number of submitted headers. It is zero for
+	// submissions which do not add headers.
+	HeadersCount int
+}
+
+// WriterMiddleware intercepts the transaction submissions made through the
+// writer. It calls next to pass the submission to the next middleware or,
+// for the last one, to the wrapped writer. A middleware not calling next
+// drops the submission.
+type WriterMiddleware func(
+	ctx context.Context,
+	submission *Submission,
+	next func(ctx context.Context) error,
+) error
+
+// composedHandle is a host chain handle whose writer is wrapped with
+// middlewares.
+type composedHandle struct {
+	Reader
+
+	writer      Writer
+	middlewares []WriterMiddleware
+}
+
+// WrapWriter wraps the writer of the given host chain handle with the given
+// middlewares. Submissions go through the middlewares in the given order.
+func WrapWriter(handle Handle, middlewares ...WriterMiddleware) Handle {
+	if len(middlewares) == 0 {
+		return handle
+	}
+
+	return &composedHandle{
+		Reader:      handle,
+		writer:      handle,
+		middlewares: middlewares,
+	}
+}
+
+func (ch *composedHandle) submit(
+	ctx context.Context,
+	submission *Submission,
+	send func(ctx context.Context) error,
+) error {
+	next := send
+	for i := len(ch.middlewares) - 1; i >= 0; i-- {
+		middleware := ch.middlewares[i]
+		wrapped := next
+		next = func(ctx context.Context) error {
+			return middleware(ctx, submission, wrapped)
+		}
+	}
+
+	return next(ctx)
+}
+
+// AddHeaders passes the submission through the middlewares.
+func (ch *composedHandle) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	return ch.submit(
+		ctx,
+		&Submission{
+			Method:       SubmissionAddHeaders,
+			HeadersCount: len(headers) / headerSize,
+		},
+		func(ctx context.Context) error {
+			return ch.writer.AddHeaders(ctx, anchorHeader, headers)
+		},
+	)
+}
+
+// AddHeadersWithRetarget passes the submission through the middlewares.
+func (ch *composedHandle) AddHeadersWithRetarget(
+	ctx context.Context,
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) error {
+	return ch.submit(
+		ctx,
+		&Submission{
+			Method:       SubmissionAddHeadersWithRetarget,
+			HeadersCount: len(headers) / headerSize,
+		},
+		func(ctx context.Context) error {
+			return ch.writer.AddHeadersWithRetarget(
+				ctx,
+				oldPeriodStartHeader,
+				oldPeriodEndHeader,
+				headers,
+			)
+		},
+	)
+}
+
+// MarkNewHeaviest passes the submission through the middlewares.
+func (ch *composedHandle) MarkNewHeaviest(
+	ctx context.Context,
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) error {
+	return ch.submit(
+		ctx,
+		&Submission{Method: SubmissionMarkNewHeaviest},
+		func(ctx context.Context) error {
+			return ch.writer.MarkNewHeaviest(
+				ctx,
+				ancestorDigest,
+				currentBestHeader,
+				newBestHeader,
+				limit,
+			)
+		},
+	)
+}
+
+// Retarget passes the submission through the middlewares.
+func (ch *composedHandle) Retarget(ctx context.Context, headers []byte) error {
+	return ch.submit(
+		ctx,
+		&Submission{
+			Method:       SubmissionRetarget,
+			HeadersCount: len(headers) / headerSize,
+		},
+		func(ctx context.Context) error {
+			return ch.writer.Retarget(ctx, headers)
+		},
+	)
+}
+
+// ClaimRewards passes the submission through the middlewares.
+func (ch *composedHandle) ClaimRewards(ctx context.Context) error {
+	return ch.submit(
+		ctx,
+		&Submission{Method: SubmissionClaimRewards},
+		ch.writer.ClaimRewards,
+	)
+}
+
+// ProvideFundingProof passes the submission through the middlewares.
+func (ch *composedHandle) ProvideFundingProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *TransactionProof,
+	fundingOutputIndex uint8,
+) error {
+	return ch.submit(
+		ctx,
+		&Submission{
+			Method:       SubmissionProvideFundingProof,
+			HeadersCount: len(proof.BitcoinHeaders) / headerSize,
+		},
+		func(ctx context.Context) error {
+			return ch.writer.ProvideFundingProof(
+				ctx,
+				depositAddress,
+				proof,
+				fundingOutputIndex,
+			)
+		},
+	)
+}
+
+// ProvideRedemptionProof passes the submission through the middlewares.
+func (ch *composedHandle) ProvideRedemptionProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *TransactionProof,
+) error {
+	return ch.submit(
+		ctx,
+		&Submission{
+			Method:       SubmissionProvideRedemptionProof,
+			HeadersCount: len(proof.BitcoinHeaders) / headerSize,
+		},
+		func(ctx context.Context) error {
+			return ch.writer.ProvideRedemptionProof(ctx, depositAddress, proof)
+		},
+	)
+}
+
+// CancelPendingTransactions passes the submission through the middlewares.
+// Zero cancelled transactions are returned if the submission is dropped.
+func (ch *composedHandle) CancelPendingTransactions(
+	ctx context.Context,
+) (int, error) {
+	cancelled := 0
+
+	err := ch.submit(
+		ctx,
+		&Submission{Method: SubmissionCancelPendingTransactions},
+		func(ctx context.Context) error {
+			var err error
+			cancelled, err = ch.writer.CancelPendingTransactions(ctx)
+			return err
+		},
+	)
+
+	return cancelled, err
+}
+
+// PendingTransactions is not a submission so it is passed to the wrapped
+// writer directly.
+func (ch *composedHandle) PendingTransactions(ctx context.Context) (int, error) {
+	return ch.writer.PendingTransactions(ctx)
+}
+
+// LoggingMiddleware logs each submission along with its outcome and
+// duration.
+func LoggingMiddleware(logger logs.Logger) WriterMiddleware {
+	return func(
+		ctx context.Context,
+		submission *Submission,
+		next func(ctx context.Context) error,
+	) error {
+		startedAt := time.Now()
+
+		err := next(ctx)
+		if err != nil {
+			logger.Warnf(
+				"[%v] submission with [%v] headers failed after [%v]: [%v]",
+				submission.Method,
+				submission.HeadersCount,
+				time.Since(startedAt),
+				err,
+			)
+			return err
+		}
+
+		logger.Debugf(
+			"[%v] submission with [%v] headers completed in [%v]",
+			submission.Method,
+			submission.HeadersCount,
+			time.Since(startedAt),
+		)
+
+		return nil
+	}
+}
+
+// SubmissionStats counts the submissions made through the writer.
+type SubmissionStats struct {
+	mutex     sync.Mutex
+	submitted uint64
+	failed    uint64
+}
+
+// Submitted returns the number of successful submissions.
+func (ss *SubmissionStats) Submitted() uint64 {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	return ss.submitted
+}
+
+// Failed returns the number of failed submissions.
+func (ss *SubmissionStats) Failed() uint64 {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	return ss.failed
+}
+
+// MetricsMiddleware counts the successful and failed submissions in the
+// given stats.
+func MetricsMiddleware(stats *SubmissionStats) WriterMiddleware {
+	return func(
+		ctx context.Context,
+		submission *Submission,
+		next func(ctx context.Context) error,
+	) error {
+		err := next(ctx)
+
+		stats.mutex.Lock()
+		if err != nil {
+			stats.failed++
+		} else {
+			stats.submitted++
+		}
+		stats.mutex.Unlock()
+
+		return err
+	}
+}
+
+// GasGatingMiddleware rejects submissions while the gas price suggested by
+// the given oracle exceeds the given maximum, expressed in the smallest unit
+// of the host chain currency. Header pushes are passed through, as a failed
+// push restarts the relay; they should be deferred by the push schedule
+// instead, with its gas price ceiling set to the same maximum.
+func GasGatingMiddleware(
+	gasOracle GasOracle,
+	maxGasPrice *big.Int,
+) WriterMiddleware {
+	return func(
+		ctx context.Context,
+		submission *Submission,
+		next func(ctx context.Context) error,
+	) error {
+		if isHeaderPush(submission.Method) {
+			return next(ctx)
+		}
+
+		gasPrice, err := gasOracle.GetGasPrice(ctx)
+		if err != nil {
+			return fmt.Errorf("could not get gas price: [%v]", err)
+		}
+
+		if gasPrice.Cmp(maxGasPrice) > 0 {
+			return fmt.Errorf(
+				"[%v] submission rejected as gas price [%v] exceeds "+
+					"the maximum [%v]",
+				submission.Method,
+				gasPrice,
+				maxGasPrice,
+			)
+		}
+
+		return next(ctx)
+	}
+}
+
+// isHeaderPush checks whether the submission with the given method is a push
+// of headers made by the relay.
+func isHeaderPush(method string) bool {
+	switch method {
+	case SubmissionAddHeaders,
+		SubmissionAddHeadersWithRetarget,
+		SubmissionMarkNewHeaviest,
+		SubmissionRetarget:
+		return true
+	}
+
+	return false
+}
+
+// DryRunMiddleware logs the submissions instead of passing them to the
+// writer. All submissions are reported as successful.
+func DryRunMiddleware(logger logs.Logger) WriterMiddleware {
+	return func(
+		ctx context.Context,
+		submission *Submission,
+		next func(ctx context.Context) error,
+	) error {
+		logger.Infof(
+			"dry run; not submitting [%v] with [%v] headers",
+			submission.Method,
+			submission.HeadersCount,
+		)
+
+		return nil
+	}
+}
+
+// RateLimitMiddleware delays the submissions so consecutive ones are started
+// at least the given interval apart. A submission waiting for its turn is
+// abandoned once the context is done.
+func RateLimitMiddleware(
+	interval time.Duration,
+	timeSource clock.Clock,
+) WriterMiddleware {
+	var mutex sync.Mutex
+	var lastStartedAt time.Time
+
+	return func(
+		ctx context.Context,
+		submission *Submission,
+		next func(ctx context.Context) error,
+	) error {
+		mutex.Lock()
+		startAt := timeSource.Now()
+		if !lastStartedAt.IsZero() {
+			if earliest := lastStartedAt.Add(interval); earliest.After(startAt) {
+				startAt = earliest
+			}
+		}
+		lastStartedAt = startAt
+		mutex.Unlock()
+
+		if wait := startAt.Sub(timeSource.Now()); wait > 0 {
+			select {
+			case <-timeSource.After(wait):
+			case <-ctx.Done():
+				return fmt.Errorf(
+					"[%v] submission abandoned while rate limited: [%v]",
+					submission.Method,
+					ctx.Err(),
+				)
+			}
+		}
+
+		return next(ctx)
+	}
+}
@@ -0,0 +1,265 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// recordingHandle is a host chain handle recording the headers submissions
+// and returning the configured gas price.
+type recordingHandle struct {
+	Handle
+
+	gasPrice  *big.Int
+	submitted int
+	submitErr error
+}
+
+func (rh *recordingHandle) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	rh.submitted++
+	return rh.submitErr
+}
+
+func (rh *recordingHandle) ClaimRewards(ctx context.Context) error {
+	rh.submitted++
+	return rh.submitErr
+}
+
+func (rh *recordingHandle) GetGasPrice(ctx context.Context) (*big.Int, error) {
+	return rh.gasPrice, nil
+}
+
+func TestWrapWriter_Order(t *testing.T) {
+	var calls []string
+	tracing := func(name string) WriterMiddleware {
+		return func(
+			ctx context.Context,
+			submission *Submission,
+			next func(ctx context.Context) error,
+		) error {
+			calls = append(calls, fmt.Sprintf(
+				"%v:%v:%v",
+				name,
+				submission.Method,
+				submission.HeadersCount,
+			))
+			return next(ctx)
+		}
+	}
+
+	handle := &recordingHandle{}
+	wrapped := WrapWriter(handle, tracing("first"), tracing("second"))
+
+	err := wrapped.AddHeaders(context.Background(), nil, make([]byte, 160))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCalls := []string{"first:addHeaders:2", "second:addHeaders:2"}
+	if !reflect.DeepEqual(expectedCalls, calls) {
+		t.Errorf(
+			"unexpected middleware calls:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedCalls,
+			calls,
+		)
+	}
+
+	if handle.submitted != 1 {
+		t.Errorf("unexpected number of submissions: [%v]", handle.submitted)
+	}
+}
+
+func TestWriterMiddlewares(t *testing.T) {
+	logger := logs.OrDefault(nil, "tbtc-relay-writer-test")
+
+	var tests = map[string]struct {
+		middleware        func(stats *SubmissionStats) []WriterMiddleware
+		gasPrice          int64
+		submitErr         error
+		expectedSubmitted int
+		expectedStats     [2]uint64
+		expectedError     bool
+	}{
+		"logged and counted submission": {
+			middleware: func(stats *SubmissionStats) []WriterMiddleware {
+				return []WriterMiddleware{
+					LoggingMiddleware(logger),
+					MetricsMiddleware(stats),
+				}
+			},
+			expectedSubmitted: 1,
+			expectedStats:     [2]uint64{1, 0},
+		},
+		"failed submission": {
+			middleware: func(stats *SubmissionStats) []WriterMiddleware {
+				return []WriterMiddleware{MetricsMiddleware(stats)}
+			},
+			submitErr:         fmt.Errorf("reverted"),
+			expectedSubmitted: 1,
+			expectedStats:     [2]uint64{0, 1},
+			expectedError:     true,
+		},
+		"gas price below maximum": {
+			middleware: func(stats *SubmissionStats) []WriterMiddleware {
+				return []WriterMiddleware{
+					MetricsMiddleware(stats),
+					GasGatingMiddleware(
+						&recordingHandle{gasPrice: big.NewInt(10)},
+						big.NewInt(10),
+					),
+				}
+			},
+			expectedSubmitted: 1,
+			expectedStats:     [2]uint64{1, 0},
+		},
+		"header push with gas price above maximum": {
+			middleware: func(stats *SubmissionStats) []WriterMiddleware {
+				return []WriterMiddleware{
+					MetricsMiddleware(stats),
+					GasGatingMiddleware(
+						&recordingHandle{gasPrice: big.NewInt(11)},
+						big.NewInt(10),
+					),
+				}
+			},
+			expectedSubmitted: 1,
+			expectedStats:     [2]uint64{1, 0},
+		},
+		"dry run": {
+			middleware: func(stats *SubmissionStats) []WriterMiddleware {
+				return []WriterMiddleware{
+					MetricsMiddleware(stats),
+					DryRunMiddleware(logger),
+				}
+			},
+			expectedStats: [2]uint64{1, 0},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			stats := &SubmissionStats{}
+			handle := &recordingHandle{submitErr: test.submitErr}
+			wrapped := WrapWriter(handle, test.middleware(stats)...)
+
+			err := wrapped.AddHeaders(context.Background(), nil, nil)
+			if test.expectedError != (err != nil) {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+
+			if handle.submitted != test.expectedSubmitted {
+				t.Errorf(
+					"unexpected number of submissions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedSubmitted,
+					handle.submitted,
+				)
+			}
+
+			actualStats := [2]uint64{stats.Submitted(), stats.Failed()}
+			if actualStats != test.expectedStats {
+				t.Errorf(
+					"unexpected stats:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedStats,
+					actualStats,
+				)
+			}
+		})
+	}
+}
+
+func TestGasGatingMiddleware_OtherSubmissions(t *testing.T) {
+	var tests = map[string]struct {
+		gasPrice          int64
+		expectedSubmitted int
+		expectedError     bool
+	}{
+		"gas price below maximum": {
+			gasPrice:          10,
+			expectedSubmitted: 1,
+		},
+		"gas price above maximum": {
+			gasPrice:      11,
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			handle := &recordingHandle{}
+			wrapped := WrapWriter(handle, GasGatingMiddleware(
+				&recordingHandle{gasPrice: big.NewInt(test.gasPrice)},
+				big.NewInt(10),
+			))
+
+			err := wrapped.ClaimRewards(context.Background())
+			if test.expectedError != (err != nil) {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+
+			if handle.submitted != test.expectedSubmitted {
+				t.Errorf(
+					"unexpected number of submissions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedSubmitted,
+					handle.submitted,
+				)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	handle := &recordingHandle{}
+	wrapped := WrapWriter(
+		handle,
+		RateLimitMiddleware(time.Minute, fakeClock),
+	)
+
+	ctx := context.Background()
+
+	if err := wrapped.AddHeaders(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- wrapped.AddHeaders(ctx, nil, nil)
+	}()
+
+	fakeClock.BlockUntil(1)
+
+	select {
+	case <-done:
+		t.Fatal("submission should wait for the rate limit")
+	default:
+	}
+
+	fakeClock.Advance(time.Minute)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if handle.submitted != 2 {
+		t.Errorf("unexpected number of submissions: [%v]", handle.submitted)
+	}
+}
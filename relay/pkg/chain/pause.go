@@ -0,0 +1,186 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// pause.go file contains the awareness of the emergency pause of the
+// contracts the relay submits transactions to. The pause flags are checked
+// periodically and submissions to a paused contract are rejected before they
+// reach the host chain, so an emergency pause does not result in a stream of
+// reverted transactions. Submissions are resumed as soon as the contract is
+// unpaused.
+
+// Default interval in which the pause flags are checked.
+const DefaultPauseCheckInterval = time.Minute
+
+// PauseHook is called when a contract gets paused or unpaused.
+type PauseHook func(contract string, paused bool)
+
+// PauseGuard tracks the emergency pause of the contracts.
+type PauseGuard struct {
+	reader PauseReader
+	hook   PauseHook
+	logger logs.Logger
+
+	mutex  sync.RWMutex
+	paused map[string]bool
+}
+
+// NewPauseGuard creates a guard tracking the pause of the contracts using
+// the given reader. The hook, which may be nil, is called on each change of
+// the pause of a contract. Messages are logged to the given logger or, if it
+// is nil, to the default logger.
+func NewPauseGuard(
+	reader PauseReader,
+	hook PauseHook,
+	logger logs.Logger,
+) *PauseGuard {
+	return &PauseGuard{
+		reader: reader,
+		hook:   hook,
+		logger: logs.OrDefault(logger, "tbtc-relay-pause"),
+		paused: make(map[string]bool),
+	}
+}
+
+// Start checks the pause flags right away and then in the given interval
+// until the context is done.
+func (pg *PauseGuard) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPauseCheckInterval
+	}
+
+	check := func() {
+		if err := pg.Check(ctx); err != nil {
+			pg.logger.Warnf("could not check contracts pause: [%v]", err)
+		}
+	}
+
+	check()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Check reads the pause flags and updates the tracked pause of the
+// contracts. If the flags cannot be read, the last known pause is kept.
+func (pg *PauseGuard) Check(ctx context.Context) error {
+	contracts, err := pg.reader.PausedContracts(ctx)
+	if err != nil {
+		return err
+	}
+
+	paused := make(map[string]bool, len(contracts))
+	for _, contract := range contracts {
+		paused[contract] = true
+	}
+
+	pg.mutex.Lock()
+	previous := pg.paused
+	pg.paused = paused
+	pg.mutex.Unlock()
+
+	for _, contract := range contracts {
+		if previous[contract] {
+			continue
+		}
+
+		pg.logger.Warnf(
+			"contract [%v] has been paused; holding its submissions",
+			contract,
+		)
+		if pg.hook != nil {
+			pg.hook(contract, true)
+		}
+	}
+
+	for contract := range previous {
+		if paused[contract] {
+			continue
+		}
+
+		pg.logger.Infof(
+			"contract [%v] has been unpaused; resuming its submissions",
+			contract,
+		)
+		if pg.hook != nil {
+			pg.hook(contract, false)
+		}
+	}
+
+	return nil
+}
+
+// IsPaused checks whether the given contract is paused.
+func (pg *PauseGuard) IsPaused(contract string) bool {
+	pg.mutex.RLock()
+	defer pg.mutex.RUnlock()
+
+	return pg.paused[contract]
+}
+
+// PausedContracts returns the names of the paused contracts, sorted.
+func (pg *PauseGuard) PausedContracts() []string {
+	pg.mutex.RLock()
+	defer pg.mutex.RUnlock()
+
+	contracts := make([]string, 0, len(pg.paused))
+	for contract := range pg.paused {
+		contracts = append(contracts, contract)
+	}
+	sort.Strings(contracts)
+
+	return contracts
+}
+
+// submissionContracts maps the submissions to the contracts whose pause
+// holds them. Submissions not listed are never held.
+var submissionContracts = map[string]string{
+	SubmissionAddHeaders:             ContractRelay,
+	SubmissionAddHeadersWithRetarget: ContractRelay,
+	SubmissionMarkNewHeaviest:        ContractRelay,
+	SubmissionRetarget:               ContractRelay,
+	SubmissionClaimRewards:           ContractRelay,
+	SubmissionProvideFundingProof:    ContractTBTCSystem,
+	SubmissionProvideRedemptionProof: ContractTBTCSystem,
+}
+
+// PauseMiddleware rejects submissions to the contracts the given guard
+// tracks as paused. Cancellations of pending transactions are never
+// rejected.
+func PauseMiddleware(guard *PauseGuard) WriterMiddleware {
+	return func(
+		ctx context.Context,
+		submission *Submission,
+		next func(ctx context.Context) error,
+	) error {
+		contract, ok := submissionContracts[submission.Method]
+		if ok && guard.IsPaused(contract) {
+			return fmt.Errorf(
+				"[%v] submission held as contract [%v] is paused",
+				submission.Method,
+				contract,
+			)
+		}
+
+		return next(ctx)
+	}
+}
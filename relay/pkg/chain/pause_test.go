@@ -0,0 +1,99 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// pausingHandle is a host chain handle reporting the configured paused
+// contracts.
+type pausingHandle struct {
+	recordingHandle
+
+	paused  []string
+	readErr error
+}
+
+func (ph *pausingHandle) PausedContracts(ctx context.Context) ([]string, error) {
+	return ph.paused, ph.readErr
+}
+
+func TestPauseGuard(t *testing.T) {
+	ctx := context.Background()
+
+	type transition struct {
+		contract string
+		paused   bool
+	}
+
+	var transitions []transition
+	handle := &pausingHandle{}
+	guard := NewPauseGuard(
+		handle,
+		func(contract string, paused bool) {
+			transitions = append(transitions, transition{contract, paused})
+		},
+		logs.OrDefault(nil, "tbtc-relay-pause-test"),
+	)
+	wrapped := WrapWriter(handle, PauseMiddleware(guard))
+
+	handle.paused = []string{ContractRelay}
+	if err := guard.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wrapped.AddHeaders(ctx, nil, nil); err == nil {
+		t.Errorf("expected error for paused relay contract")
+	}
+
+	cancellation := &Submission{Method: SubmissionCancelPendingTransactions}
+	if err := PauseMiddleware(guard)(
+		ctx,
+		cancellation,
+		func(ctx context.Context) error { return nil },
+	); err != nil {
+		t.Errorf("cancellations should not be held: [%v]", err)
+	}
+
+	// The last known pause is kept if the flags cannot be read.
+	handle.readErr = fmt.Errorf("connection refused")
+	if err := guard.Check(ctx); err == nil {
+		t.Errorf("expected error for unreadable pause flags")
+	}
+
+	if !guard.IsPaused(ContractRelay) {
+		t.Errorf("relay contract should still be paused")
+	}
+
+	handle.paused = nil
+	handle.readErr = nil
+	if err := guard.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wrapped.AddHeaders(ctx, nil, nil); err != nil {
+		t.Errorf("submission should resume once unpaused: [%v]", err)
+	}
+
+	if handle.submitted != 1 {
+		t.Errorf("unexpected number of submissions: [%v]", handle.submitted)
+	}
+
+	expectedTransitions := []transition{
+		{ContractRelay, true},
+		{ContractRelay, false},
+	}
+	if !reflect.DeepEqual(expectedTransitions, transitions) {
+		t.Errorf(
+			"unexpected transitions:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedTransitions,
+			transitions,
+		)
+	}
+}
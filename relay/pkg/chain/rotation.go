@@ -0,0 +1,359 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// rotation.go file contains the rotation of the operator account without
+// restarting the relay. Submissions go through a rotating handle which holds
+// the handle of the current operator account. A rotation waits until no
+// submission is in flight and blocks the new ones, waits until all
+// transactions of the current account are mined and only then switches the
+// submissions to the handle of the new account. If the transactions of the
+// current account are not mined before the rotation deadline, the rotation
+// is aborted and the current account is kept.
+
+// Interval in which the pending transactions of the rotated account are
+// checked while draining.
+const defaultDrainCheckInterval = 15 * time.Second
+
+// States of the operator account rotation.
+const (
+	RotationIdle      = "idle"
+	RotationDraining  = "draining"
+	RotationCompleted = "completed"
+	RotationFailed    = "failed"
+)
+
+// RotationStatus is the status of the latest operator account rotation.
+type RotationStatus struct {
+	State string
+	// OldAddress is the address of the rotated operator account.
+	OldAddress string
+	// NewAddress is the address of the operator account submissions are
+	// switched to.
+	NewAddress string
+	// PendingTransactions is the number of transactions of the rotated
+	// account not mined yet, as of the latest drain check.
+	PendingTransactions int
+	// Error is the reason of the failed rotation.
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// SwitchFunc is called by the rotation at the safe point, once no submission
+// is in flight and all transactions of the old handle are mined, right before
+// the submissions are switched to the new handle. The rotation is aborted if
+// it returns an error.
+type SwitchFunc func(oldHandle Handle, newHandle Handle) error
+
+// RotatingHandle is a host chain handle whose operator account can be
+// rotated at runtime. Reads of the relay contract state do not depend on the
+// operator account and are always served by the initial handle.
+type RotatingHandle struct {
+	Reader
+
+	// submissionMutex is held for reading by the submissions in flight and
+	// for writing by the rotation waiting for the safe point.
+	submissionMutex sync.RWMutex
+
+	currentMutex sync.RWMutex
+	current      Handle
+
+	statusMutex sync.Mutex
+	status      *RotationStatus
+
+	drainCheckInterval time.Duration
+}
+
+// NewRotatingHandle wraps the given host chain handle so its operator
+// account can be rotated.
+func NewRotatingHandle(handle Handle) *RotatingHandle {
+	return &RotatingHandle{
+		Reader:             handle,
+		current:            handle,
+		status:             &RotationStatus{State: RotationIdle},
+		drainCheckInterval: defaultDrainCheckInterval,
+	}
+}
+
+// Status returns the status of the latest rotation.
+func (rh *RotatingHandle) Status() *RotationStatus {
+	rh.statusMutex.Lock()
+	defer rh.statusMutex.Unlock()
+
+	status := *rh.status
+	return &status
+}
+
+// StartRotation checks no other rotation is in progress and marks the
+// rotation to the given handle as started. Rotate must be called afterwards.
+func (rh *RotatingHandle) StartRotation(newHandle Handle) error {
+	rh.statusMutex.Lock()
+	defer rh.statusMutex.Unlock()
+
+	if rh.status.State == RotationDraining {
+		return fmt.Errorf(
+			"rotation to account [%v] is already in progress",
+			rh.status.NewAddress,
+		)
+	}
+
+	rh.status = &RotationStatus{
+		State:      RotationDraining,
+		OldAddress: rh.handle().OperatorAddress(),
+		NewAddress: newHandle.OperatorAddress(),
+		StartedAt:  time.Now(),
+	}
+
+	return nil
+}
+
+// Rotate switches the submissions to the given handle at the safe point, see
+// SwitchFunc. The rotation must have been started with StartRotation. If the
+// context is done before the safe point is reached, the rotation is aborted
+// and the current handle is kept.
+func (rh *RotatingHandle) Rotate(
+	ctx context.Context,
+	newHandle Handle,
+	onSwitch SwitchFunc,
+) error {
+	err := rh.rotate(ctx, newHandle, onSwitch)
+
+	rh.statusMutex.Lock()
+	defer rh.statusMutex.Unlock()
+
+	rh.status.FinishedAt = time.Now()
+	if err != nil {
+		rh.status.State = RotationFailed
+		rh.status.Error = err.Error()
+	} else {
+		rh.status.State = RotationCompleted
+	}
+
+	return err
+}
+
+func (rh *RotatingHandle) rotate(
+	ctx context.Context,
+	newHandle Handle,
+	onSwitch SwitchFunc,
+) error {
+	locked := make(chan struct{})
+	go func() {
+		rh.submissionMutex.Lock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		// Release the lock once the submissions in flight complete.
+		go func() {
+			<-locked
+			rh.submissionMutex.Unlock()
+		}()
+
+		return fmt.Errorf(
+			"submissions in flight did not complete: [%v]",
+			ctx.Err(),
+		)
+	}
+	defer rh.submissionMutex.Unlock()
+
+	oldHandle := rh.handle()
+
+	if err := rh.drain(ctx, oldHandle); err != nil {
+		return err
+	}
+
+	if onSwitch != nil {
+		if err := onSwitch(oldHandle, newHandle); err != nil {
+			return err
+		}
+	}
+
+	rh.currentMutex.Lock()
+	rh.current = newHandle
+	rh.currentMutex.Unlock()
+
+	return nil
+}
+
+// drain waits until all transactions of the given handle are mined.
+func (rh *RotatingHandle) drain(ctx context.Context, handle Handle) error {
+	ticker := time.NewTicker(rh.drainCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		pending, err := handle.PendingTransactions(ctx)
+		if err != nil {
+			return fmt.Errorf("could not get pending transactions: [%v]", err)
+		}
+
+		rh.statusMutex.Lock()
+		rh.status.PendingTransactions = pending
+		rh.statusMutex.Unlock()
+
+		if pending == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf(
+				"[%v] transactions of the rotated account are still "+
+					"pending: [%v]",
+				pending,
+				ctx.Err(),
+			)
+		}
+	}
+}
+
+func (rh *RotatingHandle) handle() Handle {
+	rh.currentMutex.RLock()
+	defer rh.currentMutex.RUnlock()
+
+	return rh.current
+}
+
+func (rh *RotatingHandle) submit(send func(handle Handle) error) error {
+	rh.submissionMutex.RLock()
+	defer rh.submissionMutex.RUnlock()
+
+	return send(rh.handle())
+}
+
+// OperatorAddress returns the address of the current operator account.
+func (rh *RotatingHandle) OperatorAddress() string {
+	return rh.handle().OperatorAddress()
+}
+
+// PendingRewards returns the rewards accrued by the current operator account.
+func (rh *RotatingHandle) PendingRewards(ctx context.Context) (*big.Int, error) {
+	return rh.handle().PendingRewards(ctx)
+}
+
+// AddHeaders submits the headers using the current operator account.
+func (rh *RotatingHandle) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	return rh.submit(func(handle Handle) error {
+		return handle.AddHeaders(ctx, anchorHeader, headers)
+	})
+}
+
+// AddHeadersWithRetarget submits the headers using the current operator
+// account.
+func (rh *RotatingHandle) AddHeadersWithRetarget(
+	ctx context.Context,
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) error {
+	return rh.submit(func(handle Handle) error {
+		return handle.AddHeadersWithRetarget(
+			ctx,
+			oldPeriodStartHeader,
+			oldPeriodEndHeader,
+			headers,
+		)
+	})
+}
+
+// MarkNewHeaviest submits the new heaviest header using the current
+// operator account.
+func (rh *RotatingHandle) MarkNewHeaviest(
+	ctx context.Context,
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) error {
+	return rh.submit(func(handle Handle) error {
+		return handle.MarkNewHeaviest(
+			ctx,
+			ancestorDigest,
+			currentBestHeader,
+			newBestHeader,
+			limit,
+		)
+	})
+}
+
+// Retarget submits the retarget using the current operator account.
+func (rh *RotatingHandle) Retarget(ctx context.Context, headers []byte) error {
+	return rh.submit(func(handle Handle) error {
+		return handle.Retarget(ctx, headers)
+	})
+}
+
+// ClaimRewards claims the rewards of the current operator account.
+func (rh *RotatingHandle) ClaimRewards(ctx context.Context) error {
+	return rh.submit(func(handle Handle) error {
+		return handle.ClaimRewards(ctx)
+	})
+}
+
+// ProvideFundingProof submits the funding proof using the current operator
+// account.
+func (rh *RotatingHandle) ProvideFundingProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *TransactionProof,
+	fundingOutputIndex uint8,
+) error {
+	return rh.submit(func(handle Handle) error {
+		return handle.ProvideFundingProof(
+			ctx,
+			depositAddress,
+			proof,
+			fundingOutputIndex,
+		)
+	})
+}
+
+// ProvideRedemptionProof submits the redemption proof using the current
+// operator account.
+func (rh *RotatingHandle) ProvideRedemptionProof(
+	ctx context.Context,
+	depositAddress string,
+	proof *TransactionProof,
+) error {
+	return rh.submit(func(handle Handle) error {
+		return handle.ProvideRedemptionProof(ctx, depositAddress, proof)
+	})
+}
+
+// CancelPendingTransactions cancels the pending transactions of the current
+// operator account.
+func (rh *RotatingHandle) CancelPendingTransactions(
+	ctx context.Context,
+) (int, error) {
+	cancelled := 0
+
+	err := rh.submit(func(handle Handle) error {
+		var err error
+		cancelled, err = handle.CancelPendingTransactions(ctx)
+		return err
+	})
+
+	return cancelled, err
+}
+
+// PendingTransactions returns the number of pending transactions of the
+// current operator account.
+func (rh *RotatingHandle) PendingTransactions(ctx context.Context) (int, error) {
+	return rh.handle().PendingTransactions(ctx)
+}
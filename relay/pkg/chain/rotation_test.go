@@ -0,0 +1,201 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// accountHandle is a host chain handle of an operator account with the
+// configured number of pending transactions. Headers submissions block until
+// the release channel, if set, is closed.
+type accountHandle struct {
+	Handle
+
+	address   string
+	pending   int32
+	submitted int32
+	release   chan struct{}
+}
+
+func (ah *accountHandle) OperatorAddress() string {
+	return ah.address
+}
+
+func (ah *accountHandle) PendingTransactions(ctx context.Context) (int, error) {
+	return int(atomic.LoadInt32(&ah.pending)), nil
+}
+
+func (ah *accountHandle) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	if ah.release != nil {
+		<-ah.release
+	}
+
+	atomic.AddInt32(&ah.submitted, 1)
+	return nil
+}
+
+func newRotatingHandle(oldHandle *accountHandle) *RotatingHandle {
+	handle := NewRotatingHandle(oldHandle)
+	handle.drainCheckInterval = 10 * time.Millisecond
+
+	return handle
+}
+
+func TestRotatingHandle_Rotate(t *testing.T) {
+	oldHandle := &accountHandle{address: "0xold", pending: 1}
+	newHandle := &accountHandle{address: "0xnew"}
+	handle := newRotatingHandle(oldHandle)
+
+	if err := handle.StartRotation(newHandle); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handle.StartRotation(newHandle); err == nil {
+		t.Errorf("expected error for rotation already in progress")
+	}
+
+	// The old account gets its transaction mined while draining.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&oldHandle.pending, 0)
+	}()
+
+	var switched []string
+	err := handle.Rotate(
+		context.Background(),
+		newHandle,
+		func(oldHandle Handle, newHandle Handle) error {
+			switched = append(
+				switched,
+				oldHandle.OperatorAddress(),
+				newHandle.OperatorAddress(),
+			)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(switched) != 2 || switched[0] != "0xold" || switched[1] != "0xnew" {
+		t.Errorf("unexpected switch [%v]", switched)
+	}
+
+	if err := handle.AddHeaders(context.Background(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if oldHandle.submitted != 0 || newHandle.submitted != 1 {
+		t.Errorf("submission not switched to the new account")
+	}
+
+	if address := handle.OperatorAddress(); address != "0xnew" {
+		t.Errorf("unexpected operator address [%v]", address)
+	}
+
+	status := handle.Status()
+	if status.State != RotationCompleted ||
+		status.OldAddress != "0xold" ||
+		status.NewAddress != "0xnew" {
+		t.Errorf("unexpected status [%+v]", status)
+	}
+}
+
+func TestRotatingHandle_RotateWaitsForSubmissions(t *testing.T) {
+	oldHandle := &accountHandle{address: "0xold", release: make(chan struct{})}
+	newHandle := &accountHandle{address: "0xnew"}
+	handle := newRotatingHandle(oldHandle)
+
+	submitted := make(chan error)
+	go func() {
+		submitted <- handle.AddHeaders(context.Background(), nil, nil)
+	}()
+
+	// Let the submission start before the rotation.
+	time.Sleep(20 * time.Millisecond)
+
+	if err := handle.StartRotation(newHandle); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := make(chan error)
+	go func() {
+		rotated <- handle.Rotate(context.Background(), newHandle, nil)
+	}()
+
+	select {
+	case <-rotated:
+		t.Fatal("rotation completed with a submission in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(oldHandle.release)
+
+	if err := <-submitted; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-rotated; err != nil {
+		t.Fatal(err)
+	}
+
+	if oldHandle.submitted != 1 {
+		t.Errorf("submission in flight not completed by the old account")
+	}
+}
+
+func TestRotatingHandle_RotateAborted(t *testing.T) {
+	oldHandle := &accountHandle{address: "0xold", pending: 2}
+	newHandle := &accountHandle{address: "0xnew"}
+	handle := newRotatingHandle(oldHandle)
+
+	ctx, cancelCtx := context.WithTimeout(
+		context.Background(),
+		50*time.Millisecond,
+	)
+	defer cancelCtx()
+
+	if err := handle.StartRotation(newHandle); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handle.Rotate(ctx, newHandle, nil); err == nil {
+		t.Errorf("expected error for pending transactions")
+	}
+
+	if address := handle.OperatorAddress(); address != "0xold" {
+		t.Errorf("unexpected operator address [%v]", address)
+	}
+
+	status := handle.Status()
+	if status.State != RotationFailed || status.PendingTransactions != 2 {
+		t.Errorf("unexpected status [%+v]", status)
+	}
+
+	// A failing switch keeps the old account as well.
+	atomic.StoreInt32(&oldHandle.pending, 0)
+
+	if err := handle.StartRotation(newHandle); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handle.Rotate(
+		context.Background(),
+		newHandle,
+		func(oldHandle Handle, newHandle Handle) error {
+			return fmt.Errorf("could not archive journal")
+		},
+	); err == nil {
+		t.Errorf("expected error for failed switch")
+	}
+
+	if address := handle.OperatorAddress(); address != "0xold" {
+		t.Errorf("unexpected operator address [%v]", address)
+	}
+}
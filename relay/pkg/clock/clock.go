@@ -0,0 +1,96 @@
+package clock
+
+import (
+	"time"
+)
+
+// clock.go file contains the time abstraction used by the timing-dependent
+// parts of the relay, like the scheduled loops, retry back-offs and
+// watchdogs. The system clock is used in production while tests use the fake
+// clock advanced manually, without real delays.
+
+// Clock provides the current time and the timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since the given time.
+	Since(t time.Time) time.Duration
+
+	// After returns a channel receiving the current time once the given
+	// duration elapses.
+	After(duration time.Duration) <-chan time.Time
+
+	// NewTimer creates a timer firing once the given duration elapses.
+	NewTimer(duration time.Duration) Timer
+
+	// NewTicker creates a ticker firing repeatedly in the given interval.
+	NewTicker(interval time.Duration) Ticker
+}
+
+// Timer is a single event timer, like time.Timer.
+type Timer interface {
+	// C returns the channel receiving the current time once the timer
+	// fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It returns false if the timer
+	// has already fired or has been stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire once the given duration elapses. It
+	// returns false if the timer has already fired or has been stopped.
+	// The timer should be stopped and its channel drained before the
+	// reset, just like time.Timer.
+	Reset(duration time.Duration) bool
+}
+
+// Ticker is a periodic timer, like time.Ticker.
+type Ticker interface {
+	// C returns the channel receiving the current time on each tick.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// System is the clock backed by the system time.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (systemClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
+
+func (systemClock) NewTimer(duration time.Duration) Timer {
+	return &systemTimer{time.NewTimer(duration)}
+}
+
+func (systemClock) NewTicker(interval time.Duration) Ticker {
+	return &systemTicker{time.NewTicker(interval)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (st *systemTimer) C() <-chan time.Time {
+	return st.Timer.C
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (st *systemTicker) C() <-chan time.Time {
+	return st.Ticker.C
+}
@@ -0,0 +1,227 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// fake.go file contains the fake clock used in tests. The fake clock does not
+// move on its own; its time changes only once it is advanced, and all timers
+// and tickers due by the new time fire right away.
+
+// Fake is a clock advanced manually.
+type Fake struct {
+	mutex   sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a timer or a ticker waiting for the fake clock to reach its
+// deadline. Tickers have a nonzero interval.
+type fakeWaiter struct {
+	clock    *Fake
+	deadline time.Time
+	interval time.Duration
+	channel  chan time.Time
+}
+
+// NewFake creates a new fake clock set to the given time.
+func NewFake(now time.Time) *Fake {
+	fake := &Fake{now: now}
+	fake.changed = sync.NewCond(&fake.mutex)
+	return fake
+}
+
+// Now returns the current time of the fake clock.
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.now
+}
+
+// Since returns the time elapsed since the given time according to the fake
+// clock.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After returns a channel receiving the time once the fake clock is advanced
+// by the given duration.
+func (f *Fake) After(duration time.Duration) <-chan time.Time {
+	return f.NewTimer(duration).C()
+}
+
+// NewTimer creates a timer firing once the fake clock is advanced by the
+// given duration.
+func (f *Fake) NewTimer(duration time.Duration) Timer {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	waiter := &fakeWaiter{clock: f, channel: make(chan time.Time, 1)}
+	f.schedule(waiter, duration)
+
+	return &fakeTimer{waiter}
+}
+
+// NewTicker creates a ticker firing each time the fake clock is advanced by
+// the given interval.
+func (f *Fake) NewTicker(interval time.Duration) Ticker {
+	if interval <= 0 {
+		panic("non-positive interval for fake ticker")
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	waiter := &fakeWaiter{
+		clock:    f,
+		interval: interval,
+		channel:  make(chan time.Time, 1),
+	}
+	f.schedule(waiter, interval)
+
+	return &fakeTicker{waiter}
+}
+
+// Advance moves the fake clock forward by the given duration and fires all
+// timers and tickers due by the new time. Tickers fire at most once per
+// advance, dropping the missed ticks just like time.Ticker does.
+func (f *Fake) Advance(duration time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(duration)
+	f.fireDue()
+}
+
+// Waiters returns the number of timers and tickers which have not fired or
+// have not been stopped yet.
+func (f *Fake) Waiters() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return len(f.waiters)
+}
+
+// BlockUntil blocks until at least the given number of timers and tickers
+// are waiting for the fake clock. It lets tests advance the clock only once
+// the tested code started waiting.
+func (f *Fake) BlockUntil(waiters int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for len(f.waiters) < waiters {
+		f.changed.Wait()
+	}
+}
+
+// schedule registers the waiter to fire after the given duration. Waiters
+// whose duration is not positive fire right away. Must be called with the
+// mutex held.
+func (f *Fake) schedule(waiter *fakeWaiter, duration time.Duration) {
+	waiter.deadline = f.now.Add(duration)
+
+	if duration <= 0 {
+		waiter.fire(f.now)
+		if waiter.interval == 0 {
+			return
+		}
+		waiter.deadline = f.now.Add(waiter.interval)
+	}
+
+	f.waiters = append(f.waiters, waiter)
+	f.changed.Broadcast()
+}
+
+// unschedule removes the waiter. It returns false if the waiter was not
+// registered. Must be called with the mutex held.
+func (f *Fake) unschedule(waiter *fakeWaiter) bool {
+	for i, registered := range f.waiters {
+		if registered == waiter {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+
+	return false
+}
+
+// fireDue fires waiters due by the current time in the order of their
+// deadlines. Must be called with the mutex held.
+func (f *Fake) fireDue() {
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+	})
+
+	pending := f.waiters[:0]
+	for _, waiter := range f.waiters {
+		if waiter.deadline.After(f.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+
+		waiter.fire(f.now)
+
+		if waiter.interval > 0 {
+			for !waiter.deadline.After(f.now) {
+				waiter.deadline = waiter.deadline.Add(waiter.interval)
+			}
+			pending = append(pending, waiter)
+		}
+	}
+
+	f.waiters = pending
+	f.changed.Broadcast()
+}
+
+func (fw *fakeWaiter) fire(now time.Time) {
+	select {
+	case fw.channel <- now:
+	default:
+		// The previous tick has not been received yet.
+	}
+}
+
+type fakeTimer struct {
+	*fakeWaiter
+}
+
+func (ft *fakeTimer) C() <-chan time.Time {
+	return ft.channel
+}
+
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.mutex.Lock()
+	defer ft.clock.mutex.Unlock()
+
+	return ft.clock.unschedule(ft.fakeWaiter)
+}
+
+func (ft *fakeTimer) Reset(duration time.Duration) bool {
+	ft.clock.mutex.Lock()
+	defer ft.clock.mutex.Unlock()
+
+	active := ft.clock.unschedule(ft.fakeWaiter)
+	ft.clock.schedule(ft.fakeWaiter, duration)
+
+	return active
+}
+
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (ft *fakeTicker) C() <-chan time.Time {
+	return ft.channel
+}
+
+func (ft *fakeTicker) Stop() {
+	ft.clock.mutex.Lock()
+	defer ft.clock.mutex.Unlock()
+
+	ft.clock.unschedule(ft.fakeWaiter)
+}
@@ -0,0 +1,123 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_Timer(t *testing.T) {
+	fake := NewFake(time.Unix(1000, 0))
+
+	timer := fake.NewTimer(10 * time.Second)
+
+	fake.Advance(9 * time.Second)
+	expectNotFired(t, timer.C())
+
+	fake.Advance(time.Second)
+	expectFired(t, timer.C(), time.Unix(1010, 0))
+
+	if timer.Stop() {
+		t.Error("fired timer should not be stoppable")
+	}
+
+	if timer.Reset(5 * time.Second) {
+		t.Error("reset of a fired timer should return false")
+	}
+
+	if !timer.Stop() {
+		t.Error("reset timer should be stoppable")
+	}
+
+	fake.Advance(time.Minute)
+	expectNotFired(t, timer.C())
+
+	if fake.Waiters() != 0 {
+		t.Errorf("unexpected waiters: [%v]", fake.Waiters())
+	}
+}
+
+func TestFake_Ticker(t *testing.T) {
+	fake := NewFake(time.Unix(1000, 0))
+
+	ticker := fake.NewTicker(10 * time.Second)
+
+	fake.Advance(10 * time.Second)
+	expectFired(t, ticker.C(), time.Unix(1010, 0))
+
+	// Missed ticks are dropped.
+	fake.Advance(35 * time.Second)
+	expectFired(t, ticker.C(), time.Unix(1045, 0))
+	expectNotFired(t, ticker.C())
+
+	fake.Advance(5 * time.Second)
+	expectFired(t, ticker.C(), time.Unix(1050, 0))
+
+	ticker.Stop()
+
+	fake.Advance(time.Minute)
+	expectNotFired(t, ticker.C())
+}
+
+func TestFake_AfterNonPositiveDuration(t *testing.T) {
+	fake := NewFake(time.Unix(1000, 0))
+
+	expectFired(t, fake.After(-time.Second), time.Unix(1000, 0))
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	fake := NewFake(time.Unix(1000, 0))
+
+	done := make(chan struct{})
+	go func() {
+		<-fake.After(time.Minute)
+		close(done)
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waiter should be released")
+	}
+
+	if since := fake.Since(time.Unix(1000, 0)); since != time.Minute {
+		t.Errorf(
+			"unexpected elapsed time:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			time.Minute,
+			since,
+		)
+	}
+}
+
+func expectFired(t *testing.T, channel <-chan time.Time, expected time.Time) {
+	t.Helper()
+
+	select {
+	case actual := <-channel:
+		if !expected.Equal(actual) {
+			t.Errorf(
+				"unexpected fire time:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				expected,
+				actual,
+			)
+		}
+	default:
+		t.Error("expected fired channel")
+	}
+}
+
+func expectNotFired(t *testing.T, channel <-chan time.Time) {
+	t.Helper()
+
+	select {
+	case <-channel:
+		t.Error("channel should not fire")
+	default:
+	}
+}
@@ -0,0 +1,355 @@
+package competition
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// competition.go file contains the tracker of transactions advancing the
+// relay contract. The tracker resolves submitters of all such transactions,
+// whether sent by this relay maintainer or by other relayers, and computes
+// the share of pushes made by this instance.
+
+var logger = log.Logger("tbtc-relay-competition")
+
+const (
+	// DefaultTick is the default interval in which relay events are fetched.
+	DefaultTick = 10 * time.Minute
+
+	// DefaultLookbackBlocks is the default number of host chain blocks
+	// scanned on start. It roughly corresponds to one day of Ethereum blocks.
+	DefaultLookbackBlocks = 6500
+
+	// MaxBlocksPerQuery bounds the range of blocks fetched in a single
+	// events query as many nodes refuse too broad queries.
+	MaxBlocksPerQuery = 2000
+
+	// window is the period over which the push shares are reported.
+	window = 24 * time.Hour
+)
+
+// Advance is a transaction which advanced the relay contract annotated with
+// the information whether it was submitted by this relay maintainer.
+type Advance struct {
+	BlockNumber     uint64
+	Timestamp       time.Time
+	TransactionHash string
+	Submitter       string
+	Own             bool
+	GasUsed         uint64
+	HeadersCount    int
+	// Fee is the fee paid for the transaction, expressed in the smallest
+	// unit of the host chain currency. It is nil if the fee is not known.
+	Fee *big.Int
+}
+
+// Recorder is an interface of a store the tracked advances are persisted to.
+type Recorder interface {
+	RecordAdvances(advances []*Advance) error
+}
+
+// Recorders dispatches tracked advances to multiple recorders.
+type Recorders []Recorder
+
+// RecordAdvances passes the given advances to all recorders. The first
+// error is returned once all recorders have been called.
+func (r Recorders) RecordAdvances(advances []*Advance) error {
+	var firstErr error
+
+	for _, recorder := range r {
+		if err := recorder.RecordAdvances(advances); err != nil &&
+			firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Tracker tracks transactions advancing the relay contract.
+type Tracker struct {
+	hostChain chain.Handle
+	recorder  Recorder
+
+	mutex     sync.RWMutex
+	advances  []*Advance
+	nextBlock uint64
+}
+
+// NewTracker creates a new relay competition tracker. The recorder is
+// optional and can be nil.
+func NewTracker(hostChain chain.Handle, recorder Recorder) *Tracker {
+	return &Tracker{
+		hostChain: hostChain,
+		recorder:  recorder,
+		advances:  make([]*Advance, 0),
+	}
+}
+
+// Start starts fetching relay events in the given tick. Tracking stops once
+// the passed context is done. Failed fetches are retried in the next tick.
+func (t *Tracker) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	go func() {
+		if err := t.update(ctx, time.Now()); err != nil {
+			logger.Warnf("could not fetch relay events: [%v]", err)
+		}
+
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				if err := t.update(ctx, now); err != nil {
+					logger.Warnf("could not fetch relay events: [%v]", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// update fetches relay events emitted since the last processed block and
+// drops advances which fell out of the reporting window.
+func (t *Tracker) update(ctx context.Context, now time.Time) error {
+	currentBlock, err := t.hostChain.CurrentBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get current block: [%v]", err)
+	}
+
+	t.mutex.RLock()
+	fromBlock := t.nextBlock
+	t.mutex.RUnlock()
+
+	if fromBlock == 0 && currentBlock > DefaultLookbackBlocks {
+		fromBlock = currentBlock - DefaultLookbackBlocks
+	}
+
+	operatorAddress := t.hostChain.OperatorAddress()
+
+	for fromBlock <= currentBlock {
+		toBlock := fromBlock + MaxBlocksPerQuery - 1
+		if toBlock > currentBlock {
+			toBlock = currentBlock
+		}
+
+		relayAdvances, err := t.hostChain.PastRelayAdvances(
+			ctx,
+			fromBlock,
+			toBlock,
+		)
+		if err != nil {
+			return err
+		}
+
+		advances := make([]*Advance, len(relayAdvances))
+		for i, relayAdvance := range relayAdvances {
+			advances[i] = &Advance{
+				BlockNumber:     relayAdvance.BlockNumber,
+				Timestamp:       relayAdvance.Timestamp,
+				TransactionHash: relayAdvance.TransactionHash,
+				Submitter:       relayAdvance.Submitter,
+				Own: operatorAddress != "" && strings.EqualFold(
+					relayAdvance.Submitter,
+					operatorAddress,
+				),
+				GasUsed:      relayAdvance.GasUsed,
+				HeadersCount: relayAdvance.HeadersCount,
+				Fee:          relayAdvance.Fee,
+			}
+		}
+
+		if t.recorder != nil && len(advances) > 0 {
+			if err := t.recorder.RecordAdvances(advances); err != nil {
+				logger.Warnf("could not record relay advances: [%v]", err)
+			}
+		}
+
+		t.mutex.Lock()
+		t.advances = append(t.advances, advances...)
+		t.nextBlock = toBlock + 1
+		t.mutex.Unlock()
+
+		fromBlock = toBlock + 1
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	retained := make([]*Advance, 0, len(t.advances))
+	for _, advance := range t.advances {
+		if now.Sub(advance.Timestamp) <= window {
+			retained = append(retained, advance)
+		}
+	}
+	t.advances = retained
+
+	return nil
+}
+
+// OwnPushes returns the number of relay advances made by this relay
+// maintainer during the last 24 hours.
+func (t *Tracker) OwnPushes() int {
+	own, _ := t.pushes()
+	return own
+}
+
+// OtherPushes returns the number of relay advances made by other relayers
+// during the last 24 hours.
+func (t *Tracker) OtherPushes() int {
+	_, other := t.pushes()
+	return other
+}
+
+// OwnPushShare returns the share of relay advances made by this relay
+// maintainer during the last 24 hours. Zero is returned if there were no
+// advances at all.
+func (t *Tracker) OwnPushShare() float64 {
+	own, other := t.pushes()
+	if own+other == 0 {
+		return 0
+	}
+
+	return float64(own) / float64(own+other)
+}
+
+func (t *Tracker) pushes() (int, int) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	own := 0
+	for _, advance := range t.advances {
+		if advance.Own {
+			own++
+		}
+	}
+
+	return own, len(t.advances) - own
+}
+
+// DailySummary summarizes relay advances made during a single day.
+type DailySummary struct {
+	Day         time.Time
+	OwnPushes   int
+	OtherPushes int
+	// Submitters holds the number of advances per submitter address.
+	Submitters map[string]int
+}
+
+// OwnShare returns the share of relay advances made by this relay
+// maintainer during the day.
+func (ds *DailySummary) OwnShare() float64 {
+	total := ds.OwnPushes + ds.OtherPushes
+	if total == 0 {
+		return 0
+	}
+
+	return float64(ds.OwnPushes) / float64(total)
+}
+
+// SummarizeDaily groups the given advances by UTC days. Returned summaries
+// are ordered by their days.
+func SummarizeDaily(advances []*Advance) []*DailySummary {
+	summaries := make(map[time.Time]*DailySummary)
+
+	for _, advance := range advances {
+		day := advance.Timestamp.UTC().Truncate(24 * time.Hour)
+
+		summary, ok := summaries[day]
+		if !ok {
+			summary = &DailySummary{
+				Day:        day,
+				Submitters: make(map[string]int),
+			}
+			summaries[day] = summary
+		}
+
+		if advance.Own {
+			summary.OwnPushes++
+		} else {
+			summary.OtherPushes++
+		}
+
+		summary.Submitters[advance.Submitter]++
+	}
+
+	result := make([]*DailySummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, summary)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Day.Before(result[j].Day)
+	})
+
+	return result
+}
+
+// PrintDaily writes the daily summaries in a human readable form.
+func PrintDaily(writer io.Writer, summaries []*DailySummary) error {
+	if len(summaries) == 0 {
+		_, err := fmt.Fprintf(writer, "relay pushes:     none recorded\n")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(writer, "relay pushes:\n"); err != nil {
+		return err
+	}
+
+	for _, summary := range summaries {
+		_, err := fmt.Fprintf(
+			writer,
+			"  %v: own %v, others %v (%.1f%% own)\n",
+			summary.Day.Format("2006-01-02"),
+			summary.OwnPushes,
+			summary.OtherPushes,
+			summary.OwnShare()*100,
+		)
+		if err != nil {
+			return err
+		}
+
+		submitters := make([]string, 0, len(summary.Submitters))
+		for submitter := range summary.Submitters {
+			submitters = append(submitters, submitter)
+		}
+
+		sort.Slice(submitters, func(i, j int) bool {
+			if summary.Submitters[submitters[i]] ==
+				summary.Submitters[submitters[j]] {
+				return submitters[i] < submitters[j]
+			}
+
+			return summary.Submitters[submitters[i]] >
+				summary.Submitters[submitters[j]]
+		})
+
+		for _, submitter := range submitters {
+			_, err := fmt.Fprintf(
+				writer,
+				"    %v: %v\n",
+				submitter,
+				summary.Submitters[submitter],
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
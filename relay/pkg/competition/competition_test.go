@@ -0,0 +1,195 @@
+package competition
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+)
+
+type recorderMock struct {
+	advances []*Advance
+}
+
+func (rm *recorderMock) RecordAdvances(advances []*Advance) error {
+	rm.advances = append(rm.advances, advances...)
+	return nil
+}
+
+func TestTracker_Update(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1600000000, 0)
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+	localChain.SetOperatorAddress("0xAbC")
+	localChain.SetCurrentBlock(10000)
+	localChain.SetRelayAdvances([]*chain.RelayAdvance{
+		// Before the lookback range.
+		{BlockNumber: 3000, Timestamp: now, Submitter: "0xabc"},
+		// Outside the reporting window.
+		{BlockNumber: 4000, Timestamp: now.Add(-25 * time.Hour), Submitter: "0xabc"},
+		{BlockNumber: 5000, Timestamp: now, Submitter: "0xabc"},
+		{BlockNumber: 7000, Timestamp: now, Submitter: "0xdef"},
+		{BlockNumber: 9000, Timestamp: now, Submitter: "0xdef"},
+		{BlockNumber: 10000, Timestamp: now, Submitter: "0xabc"},
+	})
+
+	recorder := &recorderMock{}
+	tracker := NewTracker(localChain, recorder)
+
+	if err := tracker.update(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorder.advances) != 5 {
+		t.Errorf(
+			"unexpected number of recorded advances:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			5,
+			len(recorder.advances),
+		)
+	}
+
+	if tracker.OwnPushes() != 2 {
+		t.Errorf(
+			"unexpected number of own pushes:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			2,
+			tracker.OwnPushes(),
+		)
+	}
+
+	if tracker.OtherPushes() != 2 {
+		t.Errorf(
+			"unexpected number of other pushes:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			2,
+			tracker.OtherPushes(),
+		)
+	}
+
+	if tracker.OwnPushShare() != 0.5 {
+		t.Errorf(
+			"unexpected own push share:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			0.5,
+			tracker.OwnPushShare(),
+		)
+	}
+
+	// Subsequent update should not fetch already processed blocks again.
+	if err := tracker.update(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorder.advances) != 5 {
+		t.Errorf("advances have been fetched twice")
+	}
+}
+
+func TestSummarizeDaily(t *testing.T) {
+	day := time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC)
+
+	summaries := SummarizeDaily([]*Advance{
+		{Timestamp: day.Add(25 * time.Hour), Submitter: "0xdef"},
+		{Timestamp: day.Add(1 * time.Hour), Submitter: "0xabc", Own: true},
+		{Timestamp: day.Add(2 * time.Hour), Submitter: "0xdef"},
+		{Timestamp: day.Add(3 * time.Hour), Submitter: "0xabc", Own: true},
+		{Timestamp: day.Add(4 * time.Hour), Submitter: "0x123"},
+	})
+
+	if len(summaries) != 2 {
+		t.Fatalf("unexpected number of summaries: [%v]", len(summaries))
+	}
+
+	var tests = map[string]struct {
+		summary             *DailySummary
+		expectedDay         time.Time
+		expectedOwnPushes   int
+		expectedOtherPushes int
+		expectedSubmitters  int
+		expectedOwnShare    float64
+	}{
+		"first day": {
+			summary:             summaries[0],
+			expectedDay:         day,
+			expectedOwnPushes:   2,
+			expectedOtherPushes: 2,
+			expectedSubmitters:  3,
+			expectedOwnShare:    0.5,
+		},
+		"second day": {
+			summary:             summaries[1],
+			expectedDay:         day.Add(24 * time.Hour),
+			expectedOwnPushes:   0,
+			expectedOtherPushes: 1,
+			expectedSubmitters:  1,
+			expectedOwnShare:    0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if !test.summary.Day.Equal(test.expectedDay) {
+				t.Errorf(
+					"unexpected day:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedDay,
+					test.summary.Day,
+				)
+			}
+
+			if test.summary.OwnPushes != test.expectedOwnPushes {
+				t.Errorf(
+					"unexpected own pushes:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedOwnPushes,
+					test.summary.OwnPushes,
+				)
+			}
+
+			if test.summary.OtherPushes != test.expectedOtherPushes {
+				t.Errorf(
+					"unexpected other pushes:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedOtherPushes,
+					test.summary.OtherPushes,
+				)
+			}
+
+			if len(test.summary.Submitters) != test.expectedSubmitters {
+				t.Errorf(
+					"unexpected number of submitters:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedSubmitters,
+					len(test.summary.Submitters),
+				)
+			}
+
+			if test.summary.OwnShare() != test.expectedOwnShare {
+				t.Errorf(
+					"unexpected own share:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedOwnShare,
+					test.summary.OwnShare(),
+				)
+			}
+		})
+	}
+}
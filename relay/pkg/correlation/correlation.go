@@ -0,0 +1,105 @@
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"go.uber.org/zap"
+)
+
+// Name of the log field holding the correlation ID.
+const logField = "correlationID"
+
+// ID is a correlation ID which ties together log messages emitted by
+// different packages while processing the same headers batch. The ID is
+// carried by the context passed along the processing pipeline.
+type ID string
+
+type contextKey struct{}
+
+// New generates a new random correlation ID.
+func New() ID {
+	bytes := make([]byte, 4)
+	if _, err := rand.Read(bytes); err != nil {
+		// Correlation IDs are not critical; an empty ID only makes the
+		// log messages harder to correlate.
+		return ""
+	}
+
+	return ID(hex.EncodeToString(bytes))
+}
+
+// WithID returns a copy of the context carrying the given correlation ID.
+func WithID(ctx context.Context, id ID) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID carried by the context or an empty
+// ID if the context does not carry any.
+func FromContext(ctx context.Context) ID {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(contextKey{}).(ID)
+	return id
+}
+
+// Logger returns a logger which adds the correlation ID carried by the
+// context to all log messages. If the context does not carry any ID,
+// the base logger is returned as is.
+func Logger(ctx context.Context, base *log.ZapEventLogger) *zap.SugaredLogger {
+	if id := FromContext(ctx); id != "" {
+		return base.With(logField, id)
+	}
+
+	return &base.SugaredLogger
+}
+
+// InjectedLogger returns a logger which adds the correlation ID carried by
+// the context to all messages of the given injected logger. The ID is added
+// as a log field for the zap loggers, including the default ones, and as
+// a message prefix otherwise.
+func InjectedLogger(ctx context.Context, base logs.Logger) logs.Logger {
+	id := FromContext(ctx)
+	if id == "" {
+		return base
+	}
+
+	switch zapLogger := base.(type) {
+	case *log.ZapEventLogger:
+		return Logger(ctx, zapLogger)
+	case *zap.SugaredLogger:
+		return zapLogger.With(logField, id)
+	}
+
+	return &prefixedLogger{
+		base:   base,
+		prefix: fmt.Sprintf("[%v=%v] ", logField, id),
+	}
+}
+
+type prefixedLogger struct {
+	base   logs.Logger
+	prefix string
+}
+
+func (pl *prefixedLogger) Debugf(format string, args ...interface{}) {
+	pl.base.Debugf(pl.prefix+format, args...)
+}
+
+func (pl *prefixedLogger) Infof(format string, args ...interface{}) {
+	pl.base.Infof(pl.prefix+format, args...)
+}
+
+func (pl *prefixedLogger) Warnf(format string, args ...interface{}) {
+	pl.base.Warnf(pl.prefix+format, args...)
+}
+
+func (pl *prefixedLogger) Errorf(format string, args ...interface{}) {
+	pl.base.Errorf(pl.prefix+format, args...)
+}
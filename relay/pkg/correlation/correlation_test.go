@@ -0,0 +1,110 @@
+package correlation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	id := New()
+	if len(id) != 8 {
+		t.Fatalf("unexpected correlation ID length: [%v]", len(id))
+	}
+
+	ctx := WithID(context.Background(), id)
+
+	actual := FromContext(ctx)
+	if id != actual {
+		t.Errorf(
+			"unexpected correlation ID:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			id,
+			actual,
+		)
+	}
+}
+
+func TestFromContext_NoID(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("unexpected correlation ID: [%v]", id)
+	}
+}
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (rl *recordingLogger) Debugf(format string, args ...interface{}) {
+	rl.messages = append(rl.messages, fmt.Sprintf(format, args...))
+}
+
+func (rl *recordingLogger) Infof(format string, args ...interface{}) {
+	rl.messages = append(rl.messages, fmt.Sprintf(format, args...))
+}
+
+func (rl *recordingLogger) Warnf(format string, args ...interface{}) {
+	rl.messages = append(rl.messages, fmt.Sprintf(format, args...))
+}
+
+func (rl *recordingLogger) Errorf(format string, args ...interface{}) {
+	rl.messages = append(rl.messages, fmt.Sprintf(format, args...))
+}
+
+func TestInjectedLogger(t *testing.T) {
+	base := &recordingLogger{}
+
+	ctx := WithID(context.Background(), ID("a1b2c3d4"))
+	InjectedLogger(ctx, base).Infof("pushed [%v] headers", 5)
+	InjectedLogger(context.Background(), base).Infof("pushed [%v] headers", 6)
+
+	expectedMessages := []string{
+		"[correlationID=a1b2c3d4] pushed [5] headers",
+		"pushed [6] headers",
+	}
+	if !reflect.DeepEqual(expectedMessages, base.messages) {
+		t.Errorf(
+			"unexpected messages:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedMessages,
+			base.messages,
+		)
+	}
+}
+
+func TestInjectedLogger_SugaredLogger(t *testing.T) {
+	core, recorded := observer.New(zap.InfoLevel)
+	base := zap.New(core).Sugar().With("target", "testnet")
+
+	ctx := WithID(context.Background(), ID("a1b2c3d4"))
+	InjectedLogger(ctx, base).Infof("pushed [%v] headers", 5)
+
+	entries := recorded.All()
+	if len(entries) != 1 {
+		t.Fatalf("unexpected number of entries [%v]", len(entries))
+	}
+
+	expectedFields := map[string]interface{}{
+		"target":        "testnet",
+		"correlationID": ID("a1b2c3d4"),
+	}
+	if !reflect.DeepEqual(expectedFields, entries[0].ContextMap()) {
+		t.Errorf(
+			"unexpected fields:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedFields,
+			entries[0].ContextMap(),
+		)
+	}
+
+	if entries[0].Message != "pushed [5] headers" {
+		t.Errorf("unexpected message [%v]", entries[0].Message)
+	}
+}
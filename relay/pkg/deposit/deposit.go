@@ -0,0 +1,347 @@
+package deposit
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// deposit.go file contains the deposit monitor which watches the Bitcoin
+// mempool for transactions funding watched deposit addresses. Users can be
+// told their deposit is on the way long before its first confirmation.
+// Funding transactions found in the mempool are tracked until they confirm,
+// see tracking.go. Payments confirmed in the past can be found by scanning
+// block ranges, see scan.go.
+
+var logger = log.Logger("tbtc-relay-deposit")
+
+// DefaultMempoolTick is the default interval in which the mempool is scanned.
+const DefaultMempoolTick = 30 * time.Second
+
+// Config holds the configuration of the deposit monitor.
+type Config struct {
+	// Enabled determines whether the deposit monitor runs.
+	Enabled bool
+
+	// Addresses are the deposit addresses watched from the start. More
+	// addresses can be watched at runtime through the admin API.
+	Addresses []string
+
+	// MempoolTick is the interval, in seconds, in which the mempool is
+	// scanned. If zero, a default value is used.
+	MempoolTick int
+}
+
+// Monitor watches the Bitcoin chain for transactions paying watched deposit
+// addresses.
+type Monitor struct {
+	btcChain btc.Handle
+	params   *chaincfg.Params
+	feed     *Feed
+
+	mutex sync.Mutex
+	// watched maps hex-encoded output scripts to the watched addresses.
+	watched map[string]string
+	// seen holds IDs of the mempool transactions which have already been
+	// checked for outputs paying watched addresses.
+	seen map[btc.Digest]bool
+	// unscanned maps output scripts of the addresses watched since the
+	// previous mempool scan to the addresses. Already seen transactions are
+	// checked against them once.
+	unscanned map[string]string
+	// tracked holds the transactions watched for conflicts.
+	tracked map[btc.Digest]*btc.Transaction
+	// trackedInputs maps outputs spent by tracked transactions to their IDs.
+	trackedInputs map[btc.Outpoint]btc.Digest
+	// filtersUnsupported is set once the Bitcoin node turns out not to serve
+	// compact block filters.
+	filtersUnsupported bool
+}
+
+// NewMonitor creates a new deposit monitor watching the configured
+// addresses.
+func NewMonitor(btcChain btc.Handle, config *Config) (*Monitor, error) {
+	monitor := &Monitor{
+		btcChain:  btcChain,
+		params:    btcChain.NetworkParams(),
+		feed:      NewFeed(),
+		watched:   make(map[string]string),
+		seen:      make(map[btc.Digest]bool),
+		unscanned: make(map[string]string),
+
+		tracked:       make(map[btc.Digest]*btc.Transaction),
+		trackedInputs: make(map[btc.Outpoint]btc.Digest),
+	}
+
+	for _, address := range config.Addresses {
+		if err := monitor.Watch(address); err != nil {
+			return nil, err
+		}
+	}
+
+	return monitor, nil
+}
+
+// Feed returns the feed of deposit events.
+func (m *Monitor) Feed() *Feed {
+	return m.feed
+}
+
+// Watch starts watching the given deposit address. The whole mempool is
+// checked for the address during the next scan so transactions which entered
+// the mempool before the address was watched are found as well.
+func (m *Monitor) Watch(address string) error {
+	decodedAddress, err := btcutil.DecodeAddress(address, m.params)
+	if err != nil {
+		return fmt.Errorf("invalid address [%v]: [%v]", address, err)
+	}
+
+	if !decodedAddress.IsForNet(m.params) {
+		return fmt.Errorf(
+			"address [%v] is not valid for the [%v] network",
+			address,
+			m.params.Name,
+		)
+	}
+
+	script, err := txscript.PayToAddrScript(decodedAddress)
+	if err != nil {
+		return fmt.Errorf(
+			"could not build output script of address [%v]: [%v]",
+			address,
+			err,
+		)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.watched[hex.EncodeToString(script)] = address
+	m.unscanned[hex.EncodeToString(script)] = address
+
+	logger.Infof("watching deposit address [%v]", address)
+
+	return nil
+}
+
+// Unwatch stops watching the given deposit address.
+func (m *Monitor) Unwatch(address string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for script, watchedAddress := range m.watched {
+		if watchedAddress == address {
+			delete(m.watched, script)
+			delete(m.unscanned, script)
+		}
+	}
+}
+
+// watchedScripts returns a copy of the watched output scripts mapped to the
+// watched addresses. Must be called with the mutex held.
+func (m *Monitor) watchedScripts() map[string]string {
+	watched := make(map[string]string, len(m.watched))
+	for script, address := range m.watched {
+		watched[script] = address
+	}
+
+	return watched
+}
+
+// Addresses returns all watched deposit addresses.
+func (m *Monitor) Addresses() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	addresses := make([]string, 0, len(m.watched))
+	for _, address := range m.watched {
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
+
+// Start starts scanning the mempool in the given tick. Scanning stops once
+// the passed context is done.
+func (m *Monitor) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultMempoolTick
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.scanMempool(ctx); err != nil {
+					logger.Warnf("could not scan mempool: [%v]", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// scanMempool checks transactions which entered the mempool since the
+// previous scan, emits events for their outputs paying watched addresses
+// and for their conflicts with tracked transactions. Transactions seen
+// before are checked only against the addresses watched since the previous
+// scan. Then, tracked transactions which left the mempool are checked. The
+// monitor state is copied under the mutex and the Bitcoin node is called
+// without holding it.
+func (m *Monitor) scanMempool(ctx context.Context) error {
+	m.mutex.Lock()
+	watched := m.watchedScripts()
+	unscanned := m.unscanned
+	m.unscanned = make(map[string]string)
+	tracking := len(m.tracked) > 0
+	m.mutex.Unlock()
+
+	if len(watched) == 0 && !tracking {
+		return nil
+	}
+
+	txIDs, err := m.btcChain.GetMempoolTxIDs(ctx)
+	if err != nil {
+		m.restoreUnscanned(unscanned)
+		return err
+	}
+
+	inMempool := make(map[btc.Digest]bool, len(txIDs))
+
+	for _, txID := range txIDs {
+		inMempool[txID] = true
+
+		m.mutex.Lock()
+		seen := m.seen[txID]
+		m.mutex.Unlock()
+
+		scripts := watched
+		if seen {
+			if len(unscanned) == 0 {
+				continue
+			}
+
+			scripts = unscanned
+		}
+
+		transaction, err := m.btcChain.GetTransaction(ctx, txID)
+		if err != nil {
+			// The transaction may have left the mempool in the meantime.
+			logger.Debugf("could not get mempool transaction: [%v]", err)
+			continue
+		}
+
+		m.mutex.Lock()
+		m.seen[txID] = true
+		m.mutex.Unlock()
+
+		if !seen {
+			m.checkConflicts(ctx, transaction)
+		}
+		m.checkOutputs(ctx, transaction, scripts)
+	}
+
+	// Transactions which left the mempool are not checked again.
+	m.mutex.Lock()
+	for txID := range m.seen {
+		if !inMempool[txID] {
+			delete(m.seen, txID)
+		}
+	}
+	m.mutex.Unlock()
+
+	m.checkTracked(ctx, inMempool)
+
+	return nil
+}
+
+// restoreUnscanned puts back the given addresses, which are still watched,
+// so the mempool is checked for them during the next scan.
+func (m *Monitor) restoreUnscanned(unscanned map[string]string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for script, address := range unscanned {
+		if _, ok := m.watched[script]; ok {
+			m.unscanned[script] = address
+		}
+	}
+}
+
+// checkOutputs emits events for all outputs of the given unconfirmed
+// transaction paying the given watched output scripts.
+func (m *Monitor) checkOutputs(
+	ctx context.Context,
+	transaction *btc.Transaction,
+	watched map[string]string,
+) {
+	var entry *btc.MempoolEntry
+	funding := false
+
+	for index, output := range transaction.Outputs {
+		address, ok := watched[hex.EncodeToString(output.PublicKeyScript)]
+		if !ok {
+			continue
+		}
+
+		if entry == nil {
+			entry = m.mempoolEntry(ctx, transaction.TxID)
+		}
+
+		event := &Event{
+			Type:        EventSeenUnconfirmed,
+			Address:     address,
+			TxID:        transaction.TxID,
+			OutputIndex: uint32(index),
+			Value:       output.Value,
+			Fee:         entry.Fee,
+			VirtualSize: entry.VirtualSize,
+			FeeRate:     entry.FeeRate(),
+		}
+
+		logger.Infof(
+			"seen unconfirmed transaction paying [%v] satoshis to "+
+				"deposit address [%v] with fee rate [%.2f] sat/vB",
+			event.Value,
+			event.Address,
+			event.FeeRate,
+		)
+
+		m.feed.emit(event)
+
+		funding = true
+	}
+
+	if funding {
+		m.mutex.Lock()
+		m.track(transaction)
+		m.mutex.Unlock()
+	}
+}
+
+// mempoolEntry returns fee data of the given mempool transaction. Empty fee
+// data are returned if they could not be retrieved.
+func (m *Monitor) mempoolEntry(
+	ctx context.Context,
+	txID btc.Digest,
+) *btc.MempoolEntry {
+	entry, err := m.btcChain.GetMempoolEntry(ctx, txID)
+	if err != nil {
+		logger.Warnf("could not get transaction fee data: [%v]", err)
+		return &btc.MempoolEntry{}
+	}
+
+	return entry
+}
@@ -0,0 +1,238 @@
+package deposit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// P2WPKH address on the regtest network.
+const depositAddress = "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"
+
+func TestMonitor_ScanMempool(t *testing.T) {
+	ctx := context.Background()
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	monitor, err := NewMonitor(btcChain, &Config{
+		Addresses: []string{depositAddress},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := monitor.Feed().Subscribe()
+	defer subscription.Unsubscribe()
+
+	depositTx := &btc.Transaction{
+		TxID: btc.Digest{0x01},
+		Outputs: []*btc.TransactionOutput{
+			{Value: 10, PublicKeyScript: []byte{0x6a}},
+			{Value: 100000, PublicKeyScript: outputScript(t, depositAddress)},
+		},
+	}
+	otherTx := &btc.Transaction{
+		TxID: btc.Digest{0x02},
+		Outputs: []*btc.TransactionOutput{
+			{Value: 20, PublicKeyScript: []byte{0x6a}},
+		},
+	}
+
+	btcChain.SetTransactions([]*btc.Transaction{depositTx, otherTx})
+	btcChain.SetMempool(map[btc.Digest]*btc.MempoolEntry{
+		depositTx.TxID: {Fee: 2000, VirtualSize: 200},
+		otherTx.TxID:   {Fee: 100, VirtualSize: 100},
+	})
+
+	if err := monitor.scanMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Subsequent scans should not emit events for already seen transactions.
+	if err := monitor.scanMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(subscription.Events()) != 1 {
+		t.Fatalf(
+			"unexpected number of events:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			1,
+			len(subscription.Events()),
+		)
+	}
+
+	event := <-subscription.Events()
+
+	expectedEvent := Event{
+		Type:        EventSeenUnconfirmed,
+		Address:     depositAddress,
+		TxID:        depositTx.TxID,
+		OutputIndex: 1,
+		Value:       100000,
+		Fee:         2000,
+		VirtualSize: 200,
+		FeeRate:     10,
+	}
+	if *event != expectedEvent {
+		t.Errorf(
+			"unexpected event:\n"+
+				"expected: [%+v]\n"+
+				"actual:   [%+v]\n",
+			expectedEvent,
+			*event,
+		)
+	}
+}
+
+func TestMonitor_ScanMempoolForNewlyWatchedAddress(t *testing.T) {
+	ctx := context.Background()
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	monitor, err := NewMonitor(btcChain, &Config{
+		Addresses: []string{depositAddress},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := monitor.Feed().Subscribe()
+	defer subscription.Unsubscribe()
+
+	otherAddress := "bcrt1q6rz28mcfaxtmd6v789l9rrlrusdprr9pz3cppk"
+
+	depositTx := &btc.Transaction{
+		TxID: btc.Digest{0x01},
+		Outputs: []*btc.TransactionOutput{
+			{Value: 100000, PublicKeyScript: outputScript(t, depositAddress)},
+			{Value: 200000, PublicKeyScript: outputScript(t, otherAddress)},
+		},
+	}
+
+	btcChain.SetTransactions([]*btc.Transaction{depositTx})
+	btcChain.SetMempool(map[btc.Digest]*btc.MempoolEntry{
+		depositTx.TxID: {Fee: 2000, VirtualSize: 200},
+	})
+
+	if err := monitor.scanMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor.Watch(otherAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	// The seen transaction should be checked only for the newly watched
+	// address, and only once.
+	for i := 0; i < 2; i++ {
+		if err := monitor.scanMempool(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedAddresses := []string{depositAddress, otherAddress}
+
+	if len(subscription.Events()) != len(expectedAddresses) {
+		t.Fatalf(
+			"unexpected number of events:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			len(expectedAddresses),
+			len(subscription.Events()),
+		)
+	}
+
+	for _, expectedAddress := range expectedAddresses {
+		event := <-subscription.Events()
+		if event.Address != expectedAddress {
+			t.Errorf(
+				"unexpected event address:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				expectedAddress,
+				event.Address,
+			)
+		}
+	}
+}
+
+func TestMonitor_Watch(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor, err := NewMonitor(bc, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = map[string]struct {
+		address       string
+		expectedError bool
+	}{
+		"valid address": {
+			address: depositAddress,
+		},
+		"malformed address": {
+			address:       "not-an-address",
+			expectedError: true,
+		},
+		"mainnet address": {
+			address:       "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := monitor.Watch(test.address)
+			if test.expectedError != (err != nil) {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+		})
+	}
+
+	if len(monitor.Addresses()) != 1 {
+		t.Errorf("unexpected watched addresses: [%v]", monitor.Addresses())
+	}
+
+	monitor.Unwatch(depositAddress)
+
+	if len(monitor.Addresses()) != 0 {
+		t.Errorf("unexpected watched addresses: [%v]", monitor.Addresses())
+	}
+}
+
+func outputScript(t *testing.T, address string) []byte {
+	decodedAddress, err := btcutil.DecodeAddress(
+		address,
+		&chaincfg.RegressionNetParams,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := txscript.PayToAddrScript(decodedAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return script
+}
@@ -0,0 +1,136 @@
+package deposit
+
+import (
+	"sync"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// Size of the events buffer of a single feed subscription.
+const subscriptionBufferSize = 100
+
+// EventType is the type of an event emitted by the deposit monitor.
+type EventType string
+
+const (
+	// EventSeenUnconfirmed is emitted for every output paying a watched
+	// deposit address found in the mempool.
+	EventSeenUnconfirmed EventType = "seen-unconfirmed"
+
+	// EventSeenConfirmed is emitted for every output paying a watched
+	// deposit address found while scanning a block range.
+	EventSeenConfirmed EventType = "seen-confirmed"
+
+	// EventReplaced is emitted when a mempool transaction spending inputs
+	// of a tracked transaction appears, e.g. an RBF replacement.
+	EventReplaced EventType = "replaced"
+
+	// EventDoubleSpent is emitted when inputs of a tracked transaction are
+	// spent by a confirmed conflicting transaction.
+	EventDoubleSpent EventType = "double-spent"
+
+	// EventConfirmed is emitted when a tracked transaction gets confirmed.
+	EventConfirmed EventType = "confirmed"
+)
+
+// Event is a single event emitted by the deposit monitor. Address, output
+// index and value are set only for seen-unconfirmed and seen-confirmed
+// events.
+type Event struct {
+	Type        EventType
+	Address     string
+	TxID        btc.Digest
+	OutputIndex uint32
+	// BlockHeight is the height of the block including the transaction. It
+	// is set only for seen-confirmed events.
+	BlockHeight int64
+	// ConflictingTxID is the ID of the transaction conflicting with the
+	// tracked one. It is set only for replaced events.
+	ConflictingTxID *btc.Digest
+	// Value is the output value in satoshis.
+	Value int64
+	// Fee is the transaction fee in satoshis.
+	Fee int64
+	// VirtualSize is the transaction virtual size in vbytes.
+	VirtualSize int64
+	// FeeRate is the transaction fee rate in satoshis per vbyte. For
+	// replaced events, fee data concern the conflicting transaction.
+	FeeRate float64
+}
+
+// Feed broadcasts deposit events to subscribers.
+type Feed struct {
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]bool
+}
+
+// NewFeed creates a new deposit events feed.
+func NewFeed() *Feed {
+	return &Feed{
+		subscriptions: make(map[*Subscription]bool),
+	}
+}
+
+// Subscription represents a subscription to the deposit events feed.
+type Subscription struct {
+	feed   *Feed
+	events chan *Event
+}
+
+// Subscribe creates a new subscription to the deposit events feed. If the
+// subscription buffer gets full, the subscription is cancelled and its
+// events channel closed, so the subscriber knows it missed some events.
+func (f *Feed) Subscribe() *Subscription {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	subscription := &Subscription{
+		feed:   f,
+		events: make(chan *Event, subscriptionBufferSize),
+	}
+
+	f.subscriptions[subscription] = true
+
+	return subscription
+}
+
+// Events returns the channel delivering subscription events. The channel
+// is closed once the subscription is cancelled.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Unsubscribe cancels the subscription.
+func (s *Subscription) Unsubscribe() {
+	s.feed.mutex.Lock()
+	defer s.feed.mutex.Unlock()
+
+	s.feed.cancel(s)
+}
+
+// cancel must be called with the feed mutex held.
+func (f *Feed) cancel(subscription *Subscription) {
+	if !f.subscriptions[subscription] {
+		return
+	}
+
+	delete(f.subscriptions, subscription)
+	close(subscription.events)
+}
+
+func (f *Feed) emit(event *Event) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for subscription := range f.subscriptions {
+		select {
+		case subscription.events <- event:
+		default:
+			logger.Warnf(
+				"cancelling deposit feed subscription as it " +
+					"does not keep up with events",
+			)
+			f.cancel(subscription)
+		}
+	}
+}
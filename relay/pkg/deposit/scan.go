@@ -0,0 +1,224 @@
+package deposit
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil/gcs"
+	"github.com/btcsuite/btcutil/gcs/builder"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// scan.go file contains the scanning of block ranges for payments to watched
+// deposit addresses, e.g. to find deposits funded while the monitor was not
+// running. If the Bitcoin node serves BIP-158 compact block filters, only
+// blocks whose filter matches any watched output script are fetched. As
+// filters have false positives but no false negatives, no payment is missed.
+// Otherwise, every block in the range is fetched. Transactions are decoded
+// from the fetched blocks, and those already reported by the mempool scans
+// of the running monitor are skipped, so no payment is reported twice.
+
+// ScanResult summarizes a block range scan.
+type ScanResult struct {
+	// Blocks is the number of scanned blocks.
+	Blocks int64
+	// FetchedBlocks is the number of blocks whose transactions were
+	// fetched and checked.
+	FetchedBlocks int64
+	// Payments is the number of found outputs paying watched addresses.
+	Payments int
+	// UsedFilters determines whether compact block filters were used.
+	UsedFilters bool
+}
+
+// ScanBlocks scans blocks from the given range, both ends inclusive, for
+// outputs paying watched addresses and emits a seen-confirmed event for each
+// of them, unless the monitor has already seen their transaction in the
+// mempool. Addresses watched after the scan started are not looked for.
+func (m *Monitor) ScanBlocks(
+	ctx context.Context,
+	fromHeight int64,
+	toHeight int64,
+) (*ScanResult, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf(
+			"invalid block range [%v-%v]",
+			fromHeight,
+			toHeight,
+		)
+	}
+
+	m.mutex.Lock()
+	watched := m.watchedScripts()
+	useFilters := !m.filtersUnsupported
+	m.mutex.Unlock()
+
+	result := &ScanResult{UsedFilters: useFilters}
+
+	if len(watched) == 0 {
+		return result, nil
+	}
+
+	scripts := make([][]byte, 0, len(watched))
+	for script := range watched {
+		decoded, err := hex.DecodeString(script)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, decoded)
+	}
+
+	logger.Infof(
+		"scanning blocks [%v-%v] for payments to [%v] watched addresses",
+		fromHeight,
+		toHeight,
+		len(watched),
+	)
+
+	for height := fromHeight; height <= toHeight; height++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		header, err := m.btcChain.GetHeaderByHeight(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Blocks++
+
+		if useFilters {
+			matched, err := m.matchBlockFilter(ctx, header.Hash, scripts)
+			if err == btc.ErrBlockFiltersUnsupported {
+				logger.Infof(
+					"Bitcoin node does not serve compact block filters; " +
+						"fetching all blocks",
+				)
+
+				m.mutex.Lock()
+				m.filtersUnsupported = true
+				m.mutex.Unlock()
+
+				useFilters = false
+				result.UsedFilters = false
+			} else if err != nil {
+				logger.Warnf(
+					"could not match filter of block [%v]; "+
+						"fetching the block: [%v]",
+					height,
+					err,
+				)
+			} else if !matched {
+				continue
+			}
+		}
+
+		payments, err := m.scanBlock(ctx, header, watched)
+		if err != nil {
+			return nil, err
+		}
+
+		result.FetchedBlocks++
+		result.Payments += payments
+	}
+
+	logger.Infof(
+		"scanned [%v] blocks, fetched [%v] of them, found [%v] payments",
+		result.Blocks,
+		result.FetchedBlocks,
+		result.Payments,
+	)
+
+	return result, nil
+}
+
+// matchBlockFilter returns whether the compact filter of the block with
+// the given digest matches any of the given output scripts.
+func (m *Monitor) matchBlockFilter(
+	ctx context.Context,
+	digest btc.Digest,
+	scripts [][]byte,
+) (bool, error) {
+	rawFilter, err := m.btcChain.GetBlockFilter(ctx, digest)
+	if err != nil {
+		return false, err
+	}
+
+	filter, err := gcs.FromNBytes(builder.DefaultP, builder.DefaultM, rawFilter)
+	if err != nil {
+		return false, fmt.Errorf("could not decode block filter: [%v]", err)
+	}
+
+	if filter.N() == 0 {
+		return false, nil
+	}
+
+	hash := chainhash.Hash(digest)
+	return filter.MatchAny(builder.DeriveKey(&hash), scripts)
+}
+
+// scanBlock emits seen-confirmed events for outputs of transactions of the
+// given block paying the given watched scripts. Transactions seen or tracked
+// by the mempool scans are skipped. Returns the number of found outputs.
+func (m *Monitor) scanBlock(
+	ctx context.Context,
+	header *btc.Header,
+	watched map[string]string,
+) (int, error) {
+	transactions, err := m.btcChain.GetBlockTransactions(ctx, header.Hash)
+	if err != nil {
+		return 0, err
+	}
+
+	payments := 0
+	for _, transaction := range transactions {
+		if m.isReported(transaction.TxID) {
+			logger.Debugf(
+				"skipping transaction [%v] already seen in the mempool",
+				chainhash.Hash(transaction.TxID),
+			)
+			continue
+		}
+
+		for index, output := range transaction.Outputs {
+			address, ok := watched[hex.EncodeToString(output.PublicKeyScript)]
+			if !ok {
+				continue
+			}
+
+			logger.Infof(
+				"seen transaction in block [%v] paying [%v] satoshis to "+
+					"deposit address [%v]",
+				header.Height,
+				output.Value,
+				address,
+			)
+
+			m.feed.emit(&Event{
+				Type:        EventSeenConfirmed,
+				Address:     address,
+				TxID:        transaction.TxID,
+				OutputIndex: uint32(index),
+				BlockHeight: header.Height,
+				Value:       output.Value,
+			})
+
+			payments++
+		}
+	}
+
+	return payments, nil
+}
+
+// isReported returns whether the transaction with the given ID has already
+// been checked by the mempool scans, so its payments have been reported as
+// unconfirmed.
+func (m *Monitor) isReported(txID btc.Digest) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, tracked := m.tracked[txID]
+	return m.seen[txID] || tracked
+}
@@ -0,0 +1,224 @@
+package deposit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil/gcs/builder"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestMonitor_ScanBlocks(t *testing.T) {
+	var tests = map[string]struct {
+		withFilters           bool
+		expectedFetchedBlocks int64
+	}{
+		"with block filters": {
+			withFilters:           true,
+			expectedFetchedBlocks: 1,
+		},
+		"without block filters": {
+			withFilters:           false,
+			expectedFetchedBlocks: 3,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			btcChain := bc.(*btc.LocalChain)
+
+			depositScript := outputScript(t, depositAddress)
+
+			depositTx := &btc.Transaction{
+				TxID: btc.Digest{0x01},
+				Outputs: []*btc.TransactionOutput{
+					{Value: 100000, PublicKeyScript: depositScript},
+				},
+			}
+			otherTx := &btc.Transaction{
+				TxID: btc.Digest{0x02},
+				Outputs: []*btc.TransactionOutput{
+					{Value: 20, PublicKeyScript: []byte{0x6a}},
+				},
+			}
+			btcChain.SetTransactions([]*btc.Transaction{depositTx, otherTx})
+
+			blockTxs := map[int64]*btc.Transaction{
+				1: otherTx,
+				2: depositTx,
+				3: otherTx,
+			}
+			for height := int64(1); height <= 3; height++ {
+				header := &btc.Header{
+					Height: height,
+					Hash:   btc.Digest{0xb0, byte(height)},
+				}
+				btcChain.AppendHeader(header)
+
+				transaction := blockTxs[height]
+				btcChain.SetBlockTxIDs(
+					header.Hash,
+					[]btc.Digest{transaction.TxID},
+				)
+
+				if test.withFilters {
+					btcChain.SetBlockFilter(
+						header.Hash,
+						blockFilter(t, header.Hash, transaction),
+					)
+				}
+			}
+
+			monitor, err := NewMonitor(btcChain, &Config{
+				Addresses: []string{depositAddress},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			subscription := monitor.Feed().Subscribe()
+			defer subscription.Unsubscribe()
+
+			result, err := monitor.ScanBlocks(context.Background(), 1, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedResult := ScanResult{
+				Blocks:        3,
+				FetchedBlocks: test.expectedFetchedBlocks,
+				Payments:      1,
+				UsedFilters:   test.withFilters,
+			}
+			if *result != expectedResult {
+				t.Errorf(
+					"unexpected scan result:\n"+
+						"expected: [%+v]\n"+
+						"actual:   [%+v]\n",
+					expectedResult,
+					*result,
+				)
+			}
+
+			event := <-subscription.Events()
+
+			expectedEvent := Event{
+				Type:        EventSeenConfirmed,
+				Address:     depositAddress,
+				TxID:        depositTx.TxID,
+				BlockHeight: 2,
+				Value:       100000,
+			}
+			if *event != expectedEvent {
+				t.Errorf(
+					"unexpected event:\n"+
+						"expected: [%+v]\n"+
+						"actual:   [%+v]\n",
+					expectedEvent,
+					*event,
+				)
+			}
+		})
+	}
+}
+
+func TestMonitor_ScanBlocks_SeenInMempool(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	depositTx := &btc.Transaction{
+		TxID: btc.Digest{0x01},
+		Outputs: []*btc.TransactionOutput{
+			{Value: 100000, PublicKeyScript: outputScript(t, depositAddress)},
+		},
+	}
+	btcChain.SetTransactions([]*btc.Transaction{depositTx})
+
+	header := &btc.Header{Height: 1, Hash: btc.Digest{0xb0, 0x01}}
+	btcChain.AppendHeader(header)
+	btcChain.SetBlockTxIDs(header.Hash, []btc.Digest{depositTx.TxID})
+
+	monitor, err := NewMonitor(btcChain, &Config{
+		Addresses: []string{depositAddress},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The payment has already been reported by a mempool scan.
+	monitor.seen[depositTx.TxID] = true
+
+	subscription := monitor.Feed().Subscribe()
+	defer subscription.Unsubscribe()
+
+	result, err := monitor.ScanBlocks(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Payments != 0 {
+		t.Errorf(
+			"unexpected number of payments\nexpected: [0]\nactual:   [%v]",
+			result.Payments,
+		)
+	}
+
+	select {
+	case event := <-subscription.Events():
+		t.Errorf("unexpected event: [%+v]", *event)
+	default:
+	}
+}
+
+func TestMonitor_ScanBlocks_InvalidRange(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor, err := NewMonitor(bc, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := monitor.ScanBlocks(context.Background(), 5, 4); err == nil {
+		t.Errorf("scan of an invalid range should fail")
+	}
+}
+
+// blockFilter builds the serialized compact filter of a block containing
+// outputs of the given transaction.
+func blockFilter(
+	t *testing.T,
+	digest btc.Digest,
+	transaction *btc.Transaction,
+) []byte {
+	hash := chainhash.Hash(digest)
+
+	filterBuilder := builder.WithKeyHash(&hash)
+	for _, output := range transaction.Outputs {
+		filterBuilder.AddEntry(output.PublicKeyScript)
+	}
+
+	filter, err := filterBuilder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized, err := filter.NBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serialized
+}
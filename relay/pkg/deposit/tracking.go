@@ -0,0 +1,233 @@
+package deposit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// tracking.go file contains the logic detecting conflicts of tracked
+// transactions. A tracked transaction is replaced once another mempool
+// transaction spends any of its inputs, e.g. by the RBF rules. It is
+// double-spent once any of its inputs is spent by a confirmed transaction
+// other than the tracked one. Both are critical for catching fraud attempts
+// during deposit funding and redemption.
+
+// Track starts tracking the transaction with the given ID for conflicts,
+// e.g. a redemption transaction. Funding transactions of watched deposit
+// addresses are tracked automatically.
+func (m *Monitor) Track(ctx context.Context, txID btc.Digest) error {
+	transaction, err := m.btcChain.GetTransaction(ctx, txID)
+	if err != nil {
+		return err
+	}
+
+	if transaction.IsConfirmed() {
+		return fmt.Errorf(
+			"transaction [%v] is already confirmed",
+			chainhash.Hash(txID),
+		)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.track(transaction)
+
+	return nil
+}
+
+// Tracked returns IDs of all tracked transactions.
+func (m *Monitor) Tracked() []btc.Digest {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	txIDs := make([]btc.Digest, 0, len(m.tracked))
+	for txID := range m.tracked {
+		txIDs = append(txIDs, txID)
+	}
+
+	return txIDs
+}
+
+// track must be called with the mutex held.
+func (m *Monitor) track(transaction *btc.Transaction) {
+	if _, ok := m.tracked[transaction.TxID]; ok {
+		return
+	}
+
+	m.tracked[transaction.TxID] = transaction
+	for _, input := range transaction.Inputs {
+		m.trackedInputs[input.Outpoint()] = transaction.TxID
+	}
+
+	logger.Infof(
+		"tracking transaction [%v] for conflicts",
+		chainhash.Hash(transaction.TxID),
+	)
+}
+
+// untrack stops tracking the given transaction.
+func (m *Monitor) untrack(txID btc.Digest) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	transaction, ok := m.tracked[txID]
+	if !ok {
+		return
+	}
+
+	for _, input := range transaction.Inputs {
+		if m.trackedInputs[input.Outpoint()] == txID {
+			delete(m.trackedInputs, input.Outpoint())
+		}
+	}
+
+	delete(m.tracked, txID)
+}
+
+// checkConflicts emits replaced events if the given mempool transaction
+// spends inputs of any tracked transaction.
+func (m *Monitor) checkConflicts(
+	ctx context.Context,
+	transaction *btc.Transaction,
+) {
+	replaced := make([]btc.Digest, 0)
+
+	m.mutex.Lock()
+	for _, input := range transaction.Inputs {
+		trackedTxID, ok := m.trackedInputs[input.Outpoint()]
+		if !ok || trackedTxID == transaction.TxID {
+			continue
+		}
+
+		if !containsDigest(replaced, trackedTxID) {
+			replaced = append(replaced, trackedTxID)
+		}
+	}
+	m.mutex.Unlock()
+
+	if len(replaced) == 0 {
+		return
+	}
+
+	entry := m.mempoolEntry(ctx, transaction.TxID)
+	conflictingTxID := transaction.TxID
+
+	for _, trackedTxID := range replaced {
+		logger.Warnf(
+			"tracked transaction [%v] is replaced by mempool transaction "+
+				"[%v] with fee rate [%.2f] sat/vB",
+			chainhash.Hash(trackedTxID),
+			chainhash.Hash(conflictingTxID),
+			entry.FeeRate(),
+		)
+
+		m.feed.emit(&Event{
+			Type:            EventReplaced,
+			TxID:            trackedTxID,
+			ConflictingTxID: &conflictingTxID,
+			Fee:             entry.Fee,
+			VirtualSize:     entry.VirtualSize,
+			FeeRate:         entry.FeeRate(),
+		})
+	}
+}
+
+// checkTracked checks tracked transactions which are not in the mempool.
+// Confirmed transactions and transactions whose inputs are spent by
+// a confirmed conflicting transaction are no longer tracked. The tracked
+// transactions are copied under the mutex and checked without holding it.
+func (m *Monitor) checkTracked(
+	ctx context.Context,
+	inMempool map[btc.Digest]bool,
+) {
+	m.mutex.Lock()
+	tracked := make(map[btc.Digest]*btc.Transaction, len(m.tracked))
+	for txID, transaction := range m.tracked {
+		if !inMempool[txID] {
+			tracked[txID] = transaction
+		}
+	}
+	m.mutex.Unlock()
+
+	for txID, transaction := range tracked {
+		if m.isConfirmed(ctx, txID) {
+			logger.Infof(
+				"tracked transaction [%v] is confirmed",
+				chainhash.Hash(txID),
+			)
+			m.feed.emit(&Event{Type: EventConfirmed, TxID: txID})
+			m.untrack(txID)
+			continue
+		}
+
+		if !m.hasSpentInput(ctx, transaction) {
+			// The transaction may have been evicted from the mempool
+			// and can still be mined.
+			continue
+		}
+
+		// The transaction could have been confirmed in the meantime.
+		if m.isConfirmed(ctx, txID) {
+			m.feed.emit(&Event{Type: EventConfirmed, TxID: txID})
+			m.untrack(txID)
+			continue
+		}
+
+		logger.Errorf(
+			"inputs of tracked transaction [%v] are spent by a confirmed "+
+				"conflicting transaction",
+			chainhash.Hash(txID),
+		)
+		m.feed.emit(&Event{Type: EventDoubleSpent, TxID: txID})
+		m.untrack(txID)
+	}
+}
+
+func containsDigest(digests []btc.Digest, digest btc.Digest) bool {
+	for _, d := range digests {
+		if d == digest {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Monitor) isConfirmed(ctx context.Context, txID btc.Digest) bool {
+	transaction, err := m.btcChain.GetTransaction(ctx, txID)
+	if err != nil {
+		logger.Debugf("could not get tracked transaction: [%v]", err)
+		return false
+	}
+
+	return transaction.IsConfirmed()
+}
+
+func (m *Monitor) hasSpentInput(
+	ctx context.Context,
+	transaction *btc.Transaction,
+) bool {
+	for _, input := range transaction.Inputs {
+		unspent, err := m.btcChain.IsOutputUnspent(
+			ctx,
+			input.PrevTxID,
+			input.PrevOutputIndex,
+		)
+		if err != nil {
+			logger.Warnf("could not check tracked transaction input: [%v]", err)
+			continue
+		}
+
+		// Outputs of unconfirmed transactions are not in the confirmed
+		// unspent outputs set either.
+		if !unspent && m.isConfirmed(ctx, input.PrevTxID) {
+			return true
+		}
+	}
+
+	return false
+}
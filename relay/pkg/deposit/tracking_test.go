@@ -0,0 +1,169 @@
+package deposit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestMonitor_TrackedTransactionReplaced(t *testing.T) {
+	ctx := context.Background()
+
+	btcChain, monitor := newTrackingMonitor(t)
+
+	input := &btc.TransactionInput{PrevTxID: btc.Digest{0xaa}}
+
+	trackedTx := &btc.Transaction{
+		TxID:   btc.Digest{0x01},
+		Inputs: []*btc.TransactionInput{input},
+	}
+	replacementTx := &btc.Transaction{
+		TxID:   btc.Digest{0x02},
+		Inputs: []*btc.TransactionInput{input},
+	}
+
+	btcChain.SetTransactions([]*btc.Transaction{trackedTx, replacementTx})
+	btcChain.SetMempool(map[btc.Digest]*btc.MempoolEntry{
+		trackedTx.TxID: {Fee: 1000, VirtualSize: 100},
+	})
+
+	if err := monitor.Track(ctx, trackedTx.TxID); err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := monitor.Feed().Subscribe()
+	defer subscription.Unsubscribe()
+
+	btcChain.SetMempool(map[btc.Digest]*btc.MempoolEntry{
+		replacementTx.TxID: {Fee: 3000, VirtualSize: 100},
+	})
+
+	if err := monitor.scanMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEventTypes(t, subscription, EventReplaced)
+
+	// The replacement is confirmed so the tracked transaction inputs are
+	// spent.
+	btcChain.SetMempool(map[btc.Digest]*btc.MempoolEntry{})
+	btcChain.SetOutputSpent(input.Outpoint())
+	btcChain.SetTransactions([]*btc.Transaction{
+		trackedTx,
+		replacementTx,
+		confirmedTransaction(input.PrevTxID),
+	})
+
+	if err := monitor.scanMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEventTypes(t, subscription, EventDoubleSpent)
+
+	if len(monitor.Tracked()) != 0 {
+		t.Errorf("double-spent transaction should not be tracked")
+	}
+}
+
+func TestMonitor_TrackedTransactionConfirmed(t *testing.T) {
+	ctx := context.Background()
+
+	btcChain, monitor := newTrackingMonitor(t)
+
+	trackedTx := &btc.Transaction{
+		TxID: btc.Digest{0x01},
+		Inputs: []*btc.TransactionInput{
+			{PrevTxID: btc.Digest{0xaa}},
+		},
+	}
+
+	btcChain.SetTransactions([]*btc.Transaction{trackedTx})
+
+	if err := monitor.Track(ctx, trackedTx.TxID); err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := monitor.Feed().Subscribe()
+	defer subscription.Unsubscribe()
+
+	// Unconfirmed transaction out of the mempool may still be mined.
+	if err := monitor.scanMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEventTypes(t, subscription)
+
+	confirmedTx := confirmedTransaction(trackedTx.TxID)
+	confirmedTx.Inputs = trackedTx.Inputs
+	btcChain.SetTransactions([]*btc.Transaction{confirmedTx})
+	btcChain.SetOutputSpent(trackedTx.Inputs[0].Outpoint())
+
+	if err := monitor.scanMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEventTypes(t, subscription, EventConfirmed)
+
+	if len(monitor.Tracked()) != 0 {
+		t.Errorf("confirmed transaction should not be tracked")
+	}
+}
+
+func newTrackingMonitor(t *testing.T) (*btc.LocalChain, *Monitor) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor, err := NewMonitor(bc, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bc.(*btc.LocalChain), monitor
+}
+
+func confirmedTransaction(txID btc.Digest) *btc.Transaction {
+	blockHash := btc.Digest{0xff}
+
+	return &btc.Transaction{
+		TxID:          txID,
+		BlockHash:     &blockHash,
+		Confirmations: 1,
+	}
+}
+
+func assertEventTypes(
+	t *testing.T,
+	subscription *Subscription,
+	expectedTypes ...EventType,
+) {
+	actualTypes := make([]EventType, 0)
+	for len(subscription.Events()) > 0 {
+		actualTypes = append(actualTypes, (<-subscription.Events()).Type)
+	}
+
+	if len(actualTypes) != len(expectedTypes) {
+		t.Fatalf(
+			"unexpected events:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedTypes,
+			actualTypes,
+		)
+	}
+
+	for i := range expectedTypes {
+		if actualTypes[i] != expectedTypes[i] {
+			t.Errorf(
+				"unexpected event [%v]:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				i,
+				expectedTypes[i],
+				actualTypes[i],
+			)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ipfs/go-log"
+)
+
+var logger = log.Logger("tbtc-relay-doctor")
+
+// doctor.go file contains the runner of the startup self-test. The self-test
+// consists of a list of independent checks. All checks are run even if some
+// of them fail so the operator can fix all problems at once.
+
+// checkTimeout is the maximum time a single check can take.
+const checkTimeout = 30 * time.Second
+
+// Check represents a single self-test check. The run function returns
+// details of the check outcome or an error if the check failed.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result represents the outcome of a single check.
+type Result struct {
+	Name    string
+	Passed  bool
+	Details string
+}
+
+// Report represents the outcome of all checks.
+type Report struct {
+	Results []*Result
+}
+
+// Passed checks whether all checks passed.
+func (r *Report) Passed() bool {
+	for _, result := range r.Results {
+		if !result.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Print writes the human-readable report to the given writer.
+func (r *Report) Print(writer io.Writer) error {
+	failed := 0
+
+	for _, result := range r.Results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+			failed++
+		}
+
+		line := fmt.Sprintf("[%v] %v", status, result.Name)
+		if result.Details != "" {
+			line += ": " + result.Details
+		}
+
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return err
+		}
+	}
+
+	summary := fmt.Sprintf("%v checks passed", len(r.Results))
+	if failed > 0 {
+		summary = fmt.Sprintf(
+			"%v of %v checks failed",
+			failed,
+			len(r.Results),
+		)
+	}
+
+	_, err := fmt.Fprintln(writer, summary)
+	return err
+}
+
+// Run runs all given checks in order and returns the report. Each check is
+// given checkTimeout to complete.
+func Run(ctx context.Context, checks []*Check) *Report {
+	report := &Report{}
+
+	for _, check := range checks {
+		logger.Debugf("running check [%v]", check.Name)
+
+		report.Results = append(report.Results, runCheck(ctx, check))
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, check *Check) *Result {
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	type outcome struct {
+		details string
+		err     error
+	}
+
+	outcomeChan := make(chan outcome, 1)
+
+	go func() {
+		details, err := check.Run(checkCtx)
+		outcomeChan <- outcome{details, err}
+	}()
+
+	select {
+	case outcome := <-outcomeChan:
+		if outcome.err != nil {
+			return &Result{
+				Name:    check.Name,
+				Passed:  false,
+				Details: outcome.err.Error(),
+			}
+		}
+
+		return &Result{Name: check.Name, Passed: true, Details: outcome.details}
+	case <-checkCtx.Done():
+		return &Result{
+			Name:    check.Name,
+			Passed:  false,
+			Details: fmt.Sprintf("check timed out: [%v]", checkCtx.Err()),
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	report := Run(context.Background(), []*Check{
+		{
+			Name: "passing check",
+			Run: func(ctx context.Context) (string, error) {
+				return "all good", nil
+			},
+		},
+		{
+			Name: "failing check",
+			Run: func(ctx context.Context) (string, error) {
+				return "", fmt.Errorf("something is wrong")
+			},
+		},
+	})
+
+	if report.Passed() {
+		t.Fatal("expected the report to fail")
+	}
+
+	var buffer bytes.Buffer
+	if err := report.Print(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[PASS] passing check: all good\n" +
+		"[FAIL] failing check: something is wrong\n" +
+		"1 of 2 checks failed\n"
+	if expected != buffer.String() {
+		t.Errorf(
+			"unexpected report:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expected,
+			buffer.String(),
+		)
+	}
+}
+
+func TestRun_AllPassed(t *testing.T) {
+	report := Run(context.Background(), []*Check{
+		{
+			Name: "passing check",
+			Run: func(ctx context.Context) (string, error) {
+				return "", nil
+			},
+		},
+	})
+
+	if !report.Passed() {
+		t.Fatal("expected the report to pass")
+	}
+}
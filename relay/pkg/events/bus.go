@@ -0,0 +1,192 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+var logger = log.Logger("tbtc-relay-events")
+
+// Size of the events buffer of a single bus subscriber. Headers are pulled
+// in bursts while the relay catches up, so the buffer holds several batches
+// of them.
+const subscriberBufferSize = 1000
+
+// Topic is the topic of an event published on the bus.
+type Topic string
+
+const (
+	// TopicHeaderPulled is published for every header pulled from the
+	// Bitcoin chain.
+	TopicHeaderPulled Topic = "header.pulled"
+
+	// TopicBatchPushed is published for every batch of headers pushed to
+	// the host chain.
+	TopicBatchPushed Topic = "batch.pushed"
+
+	// TopicBatchConfirmed is published for every pushed batch of headers
+	// once it is known by the host chain. The event time is the time of the
+	// confirmation.
+	TopicBatchConfirmed Topic = "batch.confirmed"
+
+	// TopicRelayLag is published whenever the relay lag is observed.
+	TopicRelayLag Topic = "relay.lag"
+
+	// TopicForks is published whenever competing Bitcoin forks near the
+	// chain tip are observed.
+	TopicForks Topic = "btc.forks"
+
+	// TopicRetargetSubmitted is published once a retarget to a new
+	// difficulty epoch is submitted to the host chain.
+	TopicRetargetSubmitted Topic = "retarget.submitted"
+
+	// TopicEpochEndApproaching is published once per difficulty epoch when
+	// the Bitcoin chain tip gets close to the retarget.
+	TopicEpochEndApproaching Topic = "epoch.end-approaching"
+
+	// TopicReorgDetected is published once the relay records a reorg.
+	TopicReorgDetected Topic = "reorg.detected"
+
+	// TopicErrorOccurred is published for every error which restarted the
+	// headers relay.
+	TopicErrorOccurred Topic = "error.occurred"
+)
+
+// Event is a single event published on the bus. Only the fields relevant
+// to the event topic are set.
+type Event struct {
+	Topic Topic
+	Time  time.Time
+	// Header is the pulled header, the first header of the new epoch for
+	// retargets, the chain tip for epoch ends and the new best header
+	// for reorgs.
+	Header *btc.Header
+	// Headers are the headers of the pushed or confirmed batch.
+	Headers []*btc.Header
+	// Epoch is the difficulty epoch the retarget switches to.
+	Epoch uint64
+	// Lag is the observed relay lag.
+	Lag int64
+	// Forks is the number of competing forks and ForkLength the branch
+	// length of the longest one.
+	Forks      int
+	ForkLength int64
+	// Reorg is the recorded reorg.
+	Reorg *store.Reorg
+	// Err is the error which restarted the headers relay.
+	Err error
+}
+
+// Handler handles events delivered to a bus subscriber.
+type Handler func(event *Event)
+
+// Bus is an in-memory bus decoupling the components publishing relay events
+// from the components consuming them, like metrics, webhooks or the status
+// API. Publishing never blocks the publisher; each subscriber gets the events
+// in the publishing order from its own goroutine. Events published while the
+// buffer of a subscriber is full are dropped for that subscriber.
+type Bus struct {
+	mutex       sync.Mutex
+	subscribers map[*subscriber]bool
+
+	dropped uint64
+}
+
+// NewBus creates a new event bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[*subscriber]bool),
+	}
+}
+
+type subscriber struct {
+	name   string
+	topics map[Topic]bool
+	events chan *Event
+
+	// dropping determines whether the subscriber is dropping events, so
+	// only the first dropped event of a series is logged.
+	dropping bool
+}
+
+// Subscribe registers the given handler for events of the given topics, or
+// of all topics if none is given. The name identifies the subscriber in
+// logs. Returns the function cancelling the subscription.
+func (b *Bus) Subscribe(
+	name string,
+	handler Handler,
+	topics ...Topic,
+) (unsubscribe func()) {
+	s := &subscriber{
+		name:   name,
+		topics: make(map[Topic]bool, len(topics)),
+		events: make(chan *Event, subscriberBufferSize),
+	}
+	for _, topic := range topics {
+		s.topics[topic] = true
+	}
+
+	b.mutex.Lock()
+	b.subscribers[s] = true
+	b.mutex.Unlock()
+
+	go func() {
+		for event := range s.events {
+			handler(event)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.subscribers, s)
+			b.mutex.Unlock()
+
+			close(s.events)
+		})
+	}
+}
+
+// Publish publishes the given event to all subscribers of its topic. The
+// event time is set to the current time if not set.
+func (b *Bus) Publish(event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for s := range b.subscribers {
+		if len(s.topics) > 0 && !s.topics[event.Topic] {
+			continue
+		}
+
+		select {
+		case s.events <- event:
+			s.dropping = false
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+
+			if !s.dropping {
+				logger.Warnf(
+					"subscriber [%v] does not keep up; dropping events",
+					s.name,
+				)
+				s.dropping = true
+			}
+		}
+	}
+}
+
+// Dropped returns the total number of events dropped because the buffers
+// of their subscribers were full.
+func (b *Bus) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
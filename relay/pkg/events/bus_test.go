@@ -0,0 +1,101 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+func TestBus_DeliversSubscribedTopics(t *testing.T) {
+	bus := NewBus()
+
+	received := make(chan *Event, 10)
+	unsubscribe := bus.Subscribe(
+		"test",
+		func(event *Event) { received <- event },
+		TopicHeaderPulled,
+		TopicErrorOccurred,
+	)
+	defer unsubscribe()
+
+	publisher := RelayPublisher(bus)
+	publisher.NotifyHeaderPulled(&btc.Header{Height: 1})
+	publisher.NotifyRelayLag(3)
+	bus.Publish(&Event{Topic: TopicErrorOccurred, Err: fmt.Errorf("test")})
+
+	expectedTopics := []Topic{TopicHeaderPulled, TopicErrorOccurred}
+
+	for _, expectedTopic := range expectedTopics {
+		select {
+		case event := <-received:
+			if event.Topic != expectedTopic {
+				t.Errorf(
+					"unexpected topic\nexpected: [%v]\nactual:   [%v]",
+					expectedTopic,
+					event.Topic,
+				)
+			}
+			if event.Time.IsZero() {
+				t.Errorf("event time is not set")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event [%v] has not been delivered", expectedTopic)
+		}
+	}
+
+	select {
+	case event := <-received:
+		t.Errorf("unexpected event [%v]", event.Topic)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBus_DropsEventsOfSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+
+	release := make(chan struct{})
+	unsubscribe := bus.Subscribe("slow", func(event *Event) { <-release })
+	defer unsubscribe()
+	defer close(release)
+
+	// The first event is taken by the blocked handler, the next ones fill
+	// the subscriber buffer.
+	for i := 0; i <= subscriberBufferSize+5; i++ {
+		bus.Publish(&Event{Topic: TopicHeaderPulled})
+	}
+
+	if bus.Dropped() == 0 {
+		t.Errorf("events of the slow subscriber have not been dropped")
+	}
+}
+
+func TestRelayHandler(t *testing.T) {
+	bus := NewBus()
+
+	feed := header.NewFeed(nil)
+	subscription := feed.Subscribe()
+	defer subscription.Unsubscribe()
+
+	unsubscribe := bus.Subscribe("feed", RelayHandler(feed), RelayTopics...)
+	defer unsubscribe()
+
+	RelayPublisher(bus).NotifyHeaderPulled(&btc.Header{Height: 7})
+
+	select {
+	case event := <-subscription.Events():
+		if event.Type != header.EventHeaderPulled || event.Header.Height != 7 {
+			t.Errorf(
+				"unexpected event\nexpected: [%v %v]\nactual:   [%v %v]",
+				header.EventHeaderPulled,
+				7,
+				event.Type,
+				event.Header.Height,
+			)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("observer has not been notified")
+	}
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// RelayTopics are the topics of the events published by the headers relay.
+var RelayTopics = []Topic{
+	TopicHeaderPulled,
+	TopicBatchPushed,
+	TopicBatchConfirmed,
+	TopicRelayLag,
+	TopicForks,
+	TopicRetargetSubmitted,
+	TopicEpochEndApproaching,
+	TopicReorgDetected,
+}
+
+// RelayPublisher returns the relay observer publishing the headers relay
+// events on the given bus.
+func RelayPublisher(bus *Bus) header.RelayObserver {
+	return &relayPublisher{bus}
+}
+
+type relayPublisher struct {
+	bus *Bus
+}
+
+func (rp *relayPublisher) NotifyHeaderPulled(header *btc.Header) {
+	rp.bus.Publish(&Event{Topic: TopicHeaderPulled, Header: header})
+}
+
+func (rp *relayPublisher) NotifyHeadersPushed(headers []*btc.Header) {
+	rp.bus.Publish(&Event{Topic: TopicBatchPushed, Headers: headers})
+}
+
+func (rp *relayPublisher) NotifyHeadersConfirmed(
+	headers []*btc.Header,
+	confirmedAt time.Time,
+) {
+	rp.bus.Publish(&Event{
+		Topic:   TopicBatchConfirmed,
+		Time:    confirmedAt,
+		Headers: headers,
+	})
+}
+
+func (rp *relayPublisher) NotifyRelayLag(lag int64) {
+	rp.bus.Publish(&Event{Topic: TopicRelayLag, Lag: lag})
+}
+
+func (rp *relayPublisher) NotifyForks(forks int, length int64) {
+	rp.bus.Publish(&Event{Topic: TopicForks, Forks: forks, ForkLength: length})
+}
+
+func (rp *relayPublisher) NotifyRetargetSubmitted(
+	epoch uint64,
+	header *btc.Header,
+) {
+	rp.bus.Publish(&Event{
+		Topic:  TopicRetargetSubmitted,
+		Header: header,
+		Epoch:  epoch,
+	})
+}
+
+func (rp *relayPublisher) NotifyEpochEndApproaching(
+	epoch uint64,
+	header *btc.Header,
+) {
+	rp.bus.Publish(&Event{
+		Topic:  TopicEpochEndApproaching,
+		Header: header,
+		Epoch:  epoch,
+	})
+}
+
+func (rp *relayPublisher) NotifyReorg(header *btc.Header, reorg *store.Reorg) {
+	rp.bus.Publish(&Event{
+		Topic:  TopicReorgDetected,
+		Header: header,
+		Reorg:  reorg,
+	})
+}
+
+// RelayHandler returns the handler passing the headers relay events to the
+// given relay observer, so existing observers can subscribe to the bus.
+// Events of other topics are ignored.
+func RelayHandler(observer header.RelayObserver) Handler {
+	return func(event *Event) {
+		switch event.Topic {
+		case TopicHeaderPulled:
+			observer.NotifyHeaderPulled(event.Header)
+		case TopicBatchPushed:
+			observer.NotifyHeadersPushed(event.Headers)
+		case TopicBatchConfirmed:
+			observer.NotifyHeadersConfirmed(event.Headers, event.Time)
+		case TopicRelayLag:
+			observer.NotifyRelayLag(event.Lag)
+		case TopicForks:
+			observer.NotifyForks(event.Forks, event.ForkLength)
+		case TopicRetargetSubmitted:
+			observer.NotifyRetargetSubmitted(event.Epoch, event.Header)
+		case TopicEpochEndApproaching:
+			observer.NotifyEpochEndApproaching(event.Epoch, event.Header)
+		case TopicReorgDetected:
+			observer.NotifyReorg(event.Header, event.Reorg)
+		}
+	}
+}
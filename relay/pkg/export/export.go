@@ -0,0 +1,157 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// export.go file contains the export of the relay contract history in the
+// JSON and CSV formats. Each exported record describes a single transaction
+// which advanced the relay contract, classified as a tip extension,
+// a retarget or a new best header, along with the submitter address.
+
+const (
+	// FormatJSON is the JSON export format.
+	FormatJSON = "json"
+
+	// FormatCSV is the CSV export format.
+	FormatCSV = "csv"
+)
+
+// Kind describes how a transaction advanced the relay contract.
+type Kind string
+
+const (
+	// KindExtension is a transaction extending the relayed chain with
+	// headers within a single difficulty epoch.
+	KindExtension Kind = "extension"
+
+	// KindRetarget is a transaction submitting headers starting a new
+	// difficulty epoch.
+	KindRetarget Kind = "retarget"
+
+	// KindNewTip is a transaction marking a new best header.
+	KindNewTip Kind = "new-tip"
+
+	// KindUnknown is a transaction calling the relay contract in a way
+	// which could not be decoded.
+	KindUnknown Kind = "unknown"
+)
+
+// Record is a single exported transaction which advanced the relay contract.
+type Record struct {
+	BlockNumber     uint64    `json:"blockNumber"`
+	Timestamp       time.Time `json:"timestamp"`
+	TransactionHash string    `json:"transactionHash"`
+	Submitter       string    `json:"submitter"`
+	Kind            Kind      `json:"kind"`
+	Method          string    `json:"method,omitempty"`
+	HeadersCount    int       `json:"headersCount"`
+	GasUsed         uint64    `json:"gasUsed"`
+	// Fee is expressed in the smallest unit of the host chain currency. It
+	// is empty if the fee is not known.
+	Fee string `json:"fee,omitempty"`
+}
+
+// NewRecords converts the given relay advances to export records.
+func NewRecords(advances []*chain.RelayAdvance) []*Record {
+	records := make([]*Record, len(advances))
+
+	for i, advance := range advances {
+		record := &Record{
+			BlockNumber:     advance.BlockNumber,
+			Timestamp:       advance.Timestamp.UTC(),
+			TransactionHash: advance.TransactionHash,
+			Submitter:       advance.Submitter,
+			Kind:            kindOf(advance.Method),
+			Method:          advance.Method,
+			HeadersCount:    advance.HeadersCount,
+			GasUsed:         advance.GasUsed,
+		}
+
+		if advance.Fee != nil {
+			record.Fee = advance.Fee.String()
+		}
+
+		records[i] = record
+	}
+
+	return records
+}
+
+func kindOf(method string) Kind {
+	switch method {
+	case "addHeaders":
+		return KindExtension
+	case "addHeadersWithRetarget", "retarget":
+		return KindRetarget
+	case "markNewHeaviest":
+		return KindNewTip
+	default:
+		return KindUnknown
+	}
+}
+
+// Write writes the given records in the given format.
+func Write(writer io.Writer, format string, records []*Record) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(writer, records)
+	case FormatCSV:
+		return WriteCSV(writer, records)
+	default:
+		return fmt.Errorf("unknown export format [%v]", format)
+	}
+}
+
+// WriteJSON writes the given records as a JSON array.
+func WriteJSON(writer io.Writer, records []*Record) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(records)
+}
+
+// WriteCSV writes the given records as CSV with a header row.
+func WriteCSV(writer io.Writer, records []*Record) error {
+	csvWriter := csv.NewWriter(writer)
+
+	if err := csvWriter.Write([]string{
+		"blockNumber",
+		"timestamp",
+		"transactionHash",
+		"submitter",
+		"kind",
+		"method",
+		"headersCount",
+		"gasUsed",
+		"fee",
+	}); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := csvWriter.Write([]string{
+			strconv.FormatUint(record.BlockNumber, 10),
+			record.Timestamp.Format(time.RFC3339),
+			record.TransactionHash,
+			record.Submitter,
+			string(record.Kind),
+			record.Method,
+			strconv.Itoa(record.HeadersCount),
+			strconv.FormatUint(record.GasUsed, 10),
+			record.Fee,
+		}); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
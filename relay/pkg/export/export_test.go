@@ -0,0 +1,106 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+var testAdvances = []*chain.RelayAdvance{
+	{
+		BlockNumber:     100,
+		Timestamp:       time.Unix(1600000000, 0),
+		TransactionHash: "0x01",
+		Submitter:       "0xAA",
+		Method:          "addHeaders",
+		GasUsed:         50000,
+		HeadersCount:    5,
+		Fee:             big.NewInt(1000),
+	},
+	{
+		BlockNumber:     101,
+		Timestamp:       time.Unix(1600000013, 0),
+		TransactionHash: "0x02",
+		Submitter:       "0xBB",
+		Method:          "markNewHeaviest",
+		GasUsed:         30000,
+	},
+}
+
+func TestNewRecords(t *testing.T) {
+	records := NewRecords(testAdvances)
+
+	expectedKinds := []Kind{KindExtension, KindNewTip}
+	actualKinds := []Kind{records[0].Kind, records[1].Kind}
+
+	if !reflect.DeepEqual(expectedKinds, actualKinds) {
+		t.Errorf(
+			"unexpected kinds:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedKinds,
+			actualKinds,
+		)
+	}
+
+	if records[0].Fee != "1000" || records[1].Fee != "" {
+		t.Errorf("unexpected fees: [%v] [%v]", records[0].Fee, records[1].Fee)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := Write(&buffer, FormatCSV, NewRecords(testAdvances)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "blockNumber,timestamp,transactionHash,submitter,kind,method," +
+		"headersCount,gasUsed,fee\n" +
+		"100,2020-09-13T12:26:40Z,0x01,0xAA,extension,addHeaders,5,50000,1000\n" +
+		"101,2020-09-13T12:26:53Z,0x02,0xBB,new-tip,markNewHeaviest,0,30000,\n"
+
+	if buffer.String() != expected {
+		t.Errorf(
+			"unexpected CSV:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expected,
+			buffer.String(),
+		)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	records := NewRecords(testAdvances)
+
+	var buffer bytes.Buffer
+	if err := Write(&buffer, FormatJSON, records); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []*Record
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(records, decoded) {
+		t.Errorf(
+			"unexpected records:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			records,
+			decoded,
+		)
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
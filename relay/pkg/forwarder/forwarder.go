@@ -0,0 +1,200 @@
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/node"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// forwarder.go file contains the embeddable variant of the relay. Unlike the
+// relay binary, the forwarder does not read the config file, configure
+// logging nor handle process signals; it relays headers between the chains
+// handed over by the embedding service for as long as it is started. Its
+// messages are logged to the logger handed over by the embedding service.
+
+// Dependencies holds the collaborators of the forwarder.
+type Dependencies struct {
+	// BtcChain is the Bitcoin chain the headers are pulled from. Required.
+	BtcChain btc.Handle
+
+	// HostChain is the host chain the headers are pushed to. Required.
+	HostChain chain.Handle
+
+	// Store is the local relay storage. If nil, the relay data are kept
+	// in memory only.
+	Store *store.Store
+
+	// Profitability gates the pushes by their profitability. Optional.
+	Profitability header.ProfitabilityEstimator
+
+	// QueueStore persists the headers queue across restarts. Optional.
+	QueueStore header.QueueStore
+
+	// Pipeline customizes the flow of the relayed headers. Optional.
+	Pipeline *header.Pipeline
+
+	// Logger receives the messages of the relay node and its headers relay.
+	// If nil, the default relay loggers are used. Optional.
+	Logger logs.Logger
+}
+
+// Forwarder relays Bitcoin headers to the host chain as a part of another
+// Go service.
+type Forwarder struct {
+	config *header.Config
+	deps   Dependencies
+
+	mutex  sync.Mutex
+	node   *node.Node
+	cancel context.CancelFunc
+	errors chan error
+}
+
+// New creates a forwarder using the given relay configuration and
+// dependencies. The forwarder does not relay anything until it is started.
+func New(config *header.Config, deps *Dependencies) (*Forwarder, error) {
+	if config == nil {
+		return nil, fmt.Errorf("relay config is required")
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid relay config: [%v]", err)
+	}
+
+	if deps == nil || deps.BtcChain == nil {
+		return nil, fmt.Errorf("BTC chain is required")
+	}
+
+	if deps.HostChain == nil {
+		return nil, fmt.Errorf("host chain is required")
+	}
+
+	forwarder := &Forwarder{
+		config: config,
+		deps:   *deps,
+		errors: make(chan error),
+	}
+
+	if forwarder.deps.Store == nil {
+		forwarder.deps.Store = store.OpenMemory()
+	}
+
+	return forwarder, nil
+}
+
+// Start starts relaying headers. The forwarder stops once the given context
+// is cancelled or Stop is called. A forwarder can be started only once.
+func (f *Forwarder) Start(ctx context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.node != nil {
+		return fmt.Errorf("forwarder has already been started")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	f.cancel = cancel
+	f.node = node.Initialize(
+		ctx,
+		f.deps.BtcChain,
+		f.deps.HostChain,
+		f.deps.Store,
+		f.config,
+		f.deps.Profitability,
+		f.deps.QueueStore,
+		f.deps.Pipeline,
+		f.deps.Logger,
+	)
+
+	go f.forwardErrors(f.node)
+
+	return nil
+}
+
+// forwardErrors passes the errors raised by the node to the consumer of
+// the forwarder errors until the node stops.
+func (f *Forwarder) forwardErrors(node *node.Node) {
+	defer close(f.errors)
+
+	for {
+		select {
+		case err := <-node.Errors():
+			select {
+			case f.errors <- err:
+			case <-node.Stopped():
+				return
+			}
+		case <-node.Stopped():
+			return
+		}
+	}
+}
+
+// Stop stops relaying headers and blocks until the relay has persisted its
+// state. Stop does nothing if the forwarder has not been started.
+func (f *Forwarder) Stop() {
+	f.mutex.Lock()
+	node, cancel := f.node, f.cancel
+	f.mutex.Unlock()
+
+	if node == nil {
+		return
+	}
+
+	cancel()
+	<-node.Stopped()
+}
+
+// Err returns the channel delivering errors raised while relaying headers.
+// The relay is restarted after each error, so the errors are informational.
+// The channel is closed once the forwarder stops.
+func (f *Forwarder) Err() <-chan error {
+	return f.errors
+}
+
+// Control returns the runtime control of the relay or nil if the forwarder
+// has not been started.
+func (f *Forwarder) Control() *header.Control {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.node == nil {
+		return nil
+	}
+
+	return f.node.Control()
+}
+
+// Feed returns the feed of relayed headers or nil if the forwarder has not
+// been started.
+func (f *Forwarder) Feed() *header.Feed {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.node == nil {
+		return nil
+	}
+
+	return f.node.Feed()
+}
+
+// Stats returns the relay statistics or nil if the forwarder has not been
+// started.
+func (f *Forwarder) Stats() node.Stats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.node == nil {
+		return nil
+	}
+
+	return f.node.Stats()
+}
@@ -0,0 +1,235 @@
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+func connectLocalChains(t *testing.T) (btc.Handle, chain.Handle) {
+	btcChain, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostChain, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return btcChain, hostChain
+}
+
+func TestNew(t *testing.T) {
+	btcChain, hostChain := connectLocalChains(t)
+
+	var tests = map[string]struct {
+		config        *header.Config
+		deps          *Dependencies
+		expectedError bool
+	}{
+		"all dependencies": {
+			config: &header.Config{},
+			deps: &Dependencies{
+				BtcChain:  btcChain,
+				HostChain: hostChain,
+			},
+			expectedError: false,
+		},
+		"invalid config": {
+			config: &header.Config{Mode: "unknown"},
+			deps: &Dependencies{
+				BtcChain:  btcChain,
+				HostChain: hostChain,
+			},
+			expectedError: true,
+		},
+		"no config": {
+			config: nil,
+			deps: &Dependencies{
+				BtcChain:  btcChain,
+				HostChain: hostChain,
+			},
+			expectedError: true,
+		},
+		"no BTC chain": {
+			config:        &header.Config{},
+			deps:          &Dependencies{HostChain: hostChain},
+			expectedError: true,
+		},
+		"no host chain": {
+			config:        &header.Config{},
+			deps:          &Dependencies{BtcChain: btcChain},
+			expectedError: true,
+		},
+		"no dependencies": {
+			config:        &header.Config{},
+			deps:          nil,
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := New(test.config, test.deps)
+
+			if test.expectedError != (err != nil) {
+				t.Errorf(
+					"unexpected error:\n"+
+						"expected error: [%v]\n"+
+						"actual:         [%v]\n",
+					test.expectedError,
+					err,
+				)
+			}
+		})
+	}
+}
+
+func TestForwarder_StartStop(t *testing.T) {
+	btcChain, hostChain := connectLocalChains(t)
+
+	forwarder, err := New(
+		&header.Config{},
+		&Dependencies{BtcChain: btcChain, HostChain: hostChain},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if forwarder.Control() != nil {
+		t.Errorf("control should not be available before start")
+	}
+
+	if err := forwarder.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := forwarder.Start(context.Background()); err == nil {
+		t.Errorf("forwarder should not be started twice")
+	}
+
+	// The best header known by the host chain is missing in the empty
+	// Bitcoin chain, so the relay raises an error.
+	select {
+	case err := <-forwarder.Err():
+		if err == nil {
+			t.Errorf("relay error should be delivered")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("relay error should be delivered")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		forwarder.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("forwarder should stop without waiting for the restart")
+	}
+
+	select {
+	case _, ok := <-forwarder.Err():
+		if ok {
+			t.Errorf("errors channel should be closed once stopped")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("errors channel should be closed once stopped")
+	}
+
+	if forwarder.Stats().HeadersRelayActive() {
+		t.Errorf("headers relay should not be active once stopped")
+	}
+}
+
+type recordingLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (rl *recordingLogger) record(format string, args ...interface{}) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.messages = append(rl.messages, fmt.Sprintf(format, args...))
+}
+
+func (rl *recordingLogger) Debugf(format string, args ...interface{}) {
+	rl.record(format, args...)
+}
+
+func (rl *recordingLogger) Infof(format string, args ...interface{}) {
+	rl.record(format, args...)
+}
+
+func (rl *recordingLogger) Warnf(format string, args ...interface{}) {
+	rl.record(format, args...)
+}
+
+func (rl *recordingLogger) Errorf(format string, args ...interface{}) {
+	rl.record(format, args...)
+}
+
+func (rl *recordingLogger) contains(message string) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for _, recorded := range rl.messages {
+		if recorded == message {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestForwarder_Logger(t *testing.T) {
+	btcChain, hostChain := connectLocalChains(t)
+
+	logger := &recordingLogger{}
+
+	forwarder, err := New(
+		&header.Config{},
+		&Dependencies{
+			BtcChain:  btcChain,
+			HostChain: hostChain,
+			Logger:    logger,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := forwarder.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer forwarder.Stop()
+
+	select {
+	case <-forwarder.Err():
+	case <-time.After(time.Second):
+		t.Fatal("relay error should be delivered")
+	}
+
+	// Messages of both the node and its headers relay are routed to the
+	// injected logger.
+	for _, message := range []string{
+		"initializing relay node",
+		"starting new headers pulling loop",
+	} {
+		if !logger.contains(message) {
+			t.Errorf("message [%v] should be logged", message)
+		}
+	}
+}
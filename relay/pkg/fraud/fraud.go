@@ -0,0 +1,279 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+)
+
+// fraud.go file contains the watcher of the digests recorded by the relay
+// contract. Each recorded best known digest is checked against the active
+// chain of the relay Bitcoin node and of the configured secondary Bitcoin
+// nodes. If none of the reachable sources has the digest in its active
+// chain, the relay contract diverged from the real heaviest chain, e.g.
+// because a fraudulent fork has been pushed, and a critical alert is raised.
+
+var logger = log.Logger("tbtc-relay-fraud")
+
+// DefaultTick is the default interval in which the relay contract is
+// checked.
+const DefaultTick = 5 * time.Minute
+
+// Config holds the configuration of the relay contract watcher.
+type Config struct {
+	// Enabled determines whether the relay contract is watched.
+	Enabled bool
+
+	// Tick is the interval, in seconds, in which the relay contract is
+	// checked. If zero, a default value is used.
+	Tick int
+
+	// Sources are secondary Bitcoin nodes the recorded digests are checked
+	// against, besides the relay Bitcoin node.
+	Sources []btc.Config
+
+	// ChallengeCommand is the shell command run once for each divergent
+	// digest, e.g. to challenge the relay contract. The divergent digest is
+	// passed in the RELAY_DIVERGENT_DIGEST environment variable. If empty,
+	// no command is run.
+	ChallengeCommand string
+}
+
+// Source is a Bitcoin chain the recorded digests are checked against.
+type Source struct {
+	// Name identifies the source in logs.
+	Name  string
+	Chain btc.Handle
+}
+
+// Divergence describes a digest recorded by the relay contract which is not
+// in the active chain of any reachable source.
+type Divergence struct {
+	Digest btc.Digest
+	// Disagreeing is the number of reachable sources which do not have the
+	// digest in their active chains.
+	Disagreeing int
+	DetectedAt  time.Time
+}
+
+// ChallengeHook is called once for each divergent digest.
+type ChallengeHook func(ctx context.Context, divergence *Divergence) error
+
+// CommandHook returns the challenge hook running the given shell command.
+func CommandHook(command string) ChallengeHook {
+	return func(ctx context.Context, divergence *Divergence) error {
+		// The command comes from the operator's configuration file.
+		cmd := exec.CommandContext(ctx, "sh", "-c", command) // #nosec G204
+		cmd.Env = append(
+			os.Environ(),
+			fmt.Sprintf(
+				"RELAY_DIVERGENT_DIGEST=%v",
+				chainhash.Hash(divergence.Digest),
+			),
+		)
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf(
+				"challenge command failed: [%v]; output: [%s]",
+				err,
+				output,
+			)
+		}
+
+		return nil
+	}
+}
+
+// Watcher checks the digests recorded by the relay contract against the
+// Bitcoin sources.
+type Watcher struct {
+	hostChain chain.RelayReader
+	sources   []*Source
+	hook      ChallengeHook
+
+	mutex sync.RWMutex
+	// divergence is the current divergence. It is nil if the relay contract
+	// follows the active chain.
+	divergence *Divergence
+	// lastDigest is the most recently checked digest.
+	lastDigest btc.Digest
+	// challenged holds the digests the challenge hook has been called for.
+	challenged map[btc.Digest]bool
+}
+
+// NewWatcher creates the watcher checking the relay contract of the given
+// host chain against the given sources. The challenge hook may be nil.
+func NewWatcher(
+	hostChain chain.RelayReader,
+	sources []*Source,
+	hook ChallengeHook,
+) *Watcher {
+	return &Watcher{
+		hostChain:  hostChain,
+		sources:    sources,
+		hook:       hook,
+		challenged: make(map[btc.Digest]bool),
+	}
+}
+
+// Start starts checking the relay contract in the given tick. It stops once
+// the passed context is done.
+func (w *Watcher) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			if err := w.check(ctx); err != nil {
+				logger.Warnf("could not check relay contract: [%v]", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Divergent returns whether the relay contract currently diverges from the
+// active chain of the sources.
+func (w *Watcher) Divergent() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.divergence != nil
+}
+
+// Divergence returns the current divergence or nil if the relay contract
+// follows the active chain.
+func (w *Watcher) Divergence() *Divergence {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.divergence
+}
+
+// check checks the current best known digest of the relay contract against
+// all sources.
+func (w *Watcher) check(ctx context.Context) error {
+	digest, err := w.hostChain.GetBestKnownDigest(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get best known digest: [%v]", err)
+	}
+
+	agreeing, disagreeing := 0, 0
+	for _, source := range w.sources {
+		confirmed, err := isOnActiveChain(ctx, source.Chain, digest)
+		if err != nil {
+			logger.Warnf(
+				"could not check digest [%v] with source [%v]: [%v]",
+				chainhash.Hash(digest),
+				source.Name,
+				err,
+			)
+			continue
+		}
+
+		if confirmed {
+			agreeing++
+			continue
+		}
+
+		logger.Warnf(
+			"digest [%v] recorded by relay contract is not "+
+				"in active chain of source [%v]",
+			chainhash.Hash(digest),
+			source.Name,
+		)
+		disagreeing++
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if digest != w.lastDigest {
+		logger.Debugf(
+			"relay contract recorded new digest [%v]",
+			chainhash.Hash(digest),
+		)
+		w.lastDigest = digest
+	}
+
+	if agreeing > 0 || disagreeing == 0 {
+		if w.divergence != nil && agreeing > 0 {
+			logger.Infof("relay contract follows active chain again")
+			w.divergence = nil
+		}
+		return nil
+	}
+
+	if w.divergence == nil || w.divergence.Digest != digest {
+		w.divergence = &Divergence{
+			Digest:      digest,
+			Disagreeing: disagreeing,
+			DetectedAt:  time.Now(),
+		}
+	}
+
+	logger.Errorf(
+		"relay contract diverges from active Bitcoin chain; "+
+			"digest [%v] is not confirmed by any of [%v] sources",
+		chainhash.Hash(digest),
+		disagreeing,
+	)
+
+	if w.hook != nil && !w.challenged[digest] {
+		w.challenged[digest] = true
+
+		if err := w.hook(ctx, w.divergence); err != nil {
+			logger.Errorf(
+				"could not challenge digest [%v]: [%v]",
+				chainhash.Hash(digest),
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// isOnActiveChain checks whether the block with the given digest is in the
+// active chain of the given Bitcoin chain. An error is returned only if the
+// chain cannot be reached.
+func isOnActiveChain(
+	ctx context.Context,
+	btcChain btc.Handle,
+	digest btc.Digest,
+) (bool, error) {
+	if _, err := btcChain.GetBlockCount(ctx); err != nil {
+		return false, err
+	}
+
+	header, err := btcChain.GetHeaderByDigest(ctx, digest)
+	if err != nil {
+		// The digest is unknown to the chain.
+		return false, nil
+	}
+
+	activeHeader, err := btcChain.GetHeaderByHeight(ctx, header.Height)
+	if err != nil {
+		return false, err
+	}
+
+	return activeHeader.Hash == digest, nil
+}
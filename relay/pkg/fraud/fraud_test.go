@@ -0,0 +1,162 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+)
+
+var (
+	activeDigest   = btc.Digest{0xa2}
+	orphanedDigest = btc.Digest{0xf2}
+	unknownDigest  = btc.Digest{0xee}
+)
+
+func TestWatcher_Check(t *testing.T) {
+	var tests = map[string]struct {
+		primaryDigest     btc.Digest
+		secondaryDigest   btc.Digest
+		relayDigest       btc.Digest
+		expectedDivergent bool
+	}{
+		"digest in active chain of all sources": {
+			primaryDigest:     activeDigest,
+			secondaryDigest:   activeDigest,
+			relayDigest:       activeDigest,
+			expectedDivergent: false,
+		},
+		"digest in active chain of one source": {
+			primaryDigest:     orphanedDigest,
+			secondaryDigest:   activeDigest,
+			relayDigest:       activeDigest,
+			expectedDivergent: false,
+		},
+		"digest orphaned": {
+			relayDigest:       orphanedDigest,
+			expectedDivergent: true,
+		},
+		"digest unknown": {
+			relayDigest:       unknownDigest,
+			expectedDivergent: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			hostChain := localHostChain(t, test.relayDigest)
+
+			primary := localChain(t, test.primaryDigest == orphanedDigest)
+			secondary := localChain(t, test.secondaryDigest == orphanedDigest)
+
+			challenges := 0
+			watcher := NewWatcher(
+				hostChain,
+				[]*Source{
+					{Name: "primary", Chain: primary},
+					{Name: "secondary", Chain: secondary},
+				},
+				func(ctx context.Context, divergence *Divergence) error {
+					challenges++
+					return nil
+				},
+			)
+
+			for i := 0; i < 2; i++ {
+				if err := watcher.check(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if watcher.Divergent() != test.expectedDivergent {
+				t.Errorf(
+					"unexpected divergence:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedDivergent,
+					watcher.Divergent(),
+				)
+			}
+
+			expectedChallenges := 0
+			if test.expectedDivergent {
+				expectedChallenges = 1
+			}
+
+			if challenges != expectedChallenges {
+				t.Errorf(
+					"unexpected number of challenges:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedChallenges,
+					challenges,
+				)
+			}
+		})
+	}
+}
+
+func TestWatcher_Recovery(t *testing.T) {
+	hostChain := localHostChain(t, orphanedDigest)
+
+	watcher := NewWatcher(
+		hostChain,
+		[]*Source{{Name: "primary", Chain: localChain(t, false)}},
+		nil,
+	)
+
+	if err := watcher.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !watcher.Divergent() {
+		t.Fatalf("divergence not detected")
+	}
+
+	hostChain.SetBestKnownDigest(activeDigest)
+
+	if err := watcher.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if watcher.Divergent() {
+		t.Errorf("divergence not cleared")
+	}
+}
+
+// localChain returns a local Bitcoin chain with active chain of three
+// headers. If orphaned is set, the active header at height 2 is replaced by
+// a header of a competing fork, orphaning the relay digest.
+func localChain(t *testing.T, orphaned bool) *btc.LocalChain {
+	handle, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	btcChain := handle.(*btc.LocalChain)
+
+	btcChain.AppendHeader(&btc.Header{Height: 1, Hash: btc.Digest{0xa1}})
+
+	second := &btc.Header{Height: 2, Hash: activeDigest}
+	fork := &btc.Header{Height: 2, Hash: orphanedDigest}
+	if orphaned {
+		second, fork = fork, second
+	}
+	btcChain.AppendHeader(second)
+	btcChain.SetOrphanedHeaders([]*btc.Header{fork})
+
+	btcChain.AppendHeader(&btc.Header{Height: 3, Hash: btc.Digest{0xa3}})
+
+	return btcChain
+}
+
+func localHostChain(t *testing.T, digest btc.Digest) *chainlocal.Chain {
+	handle, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostChain := handle.(*chainlocal.Chain)
+	hostChain.SetBestKnownDigest(digest)
+
+	return hostChain
+}
@@ -0,0 +1,196 @@
+package gasusage
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// gasusage.go file contains the detector of regressions of gas used per
+// header by pushes of this relay maintainer. Each push is compared with the
+// baseline, i.e. the median gas per header of the recent pushes of the same
+// number of headers, as the fixed cost of a push is spread over all of its
+// headers. A push using significantly more gas than the baseline usually
+// indicates a problem with the relay contract state, like pushing into
+// a non-canonical branch, or an unfavorable choice of batch boundaries. The
+// baseline is kept in the relay store so it survives restarts.
+
+var logger = log.Logger("tbtc-relay-gasusage")
+
+const (
+	// Default deviation from the baseline, in percent, above which a push
+	// is considered a regression.
+	defaultDeviationThreshold = 50
+
+	// Default number of recent pushes the baseline is computed from.
+	defaultBaselineWindow = 50
+
+	// Minimum number of pushes needed to compute the baseline.
+	minBaselineSamples = 10
+)
+
+// Config holds the configuration of the gas usage regression detector.
+type Config struct {
+	// DeviationThreshold is the increase of gas used per header over the
+	// baseline, in percent, above which a push is reported as a regression.
+	// If zero, a default value is used.
+	DeviationThreshold int
+
+	// BaselineWindow is the number of recent pushes the baseline is computed
+	// from. If zero, a default value is used.
+	BaselineWindow int
+}
+
+// Detector detects regressions of gas used per header by pushes of this
+// relay maintainer. It implements the competition.Recorder interface so it
+// can be fed with advances tracked by the competition tracker.
+type Detector struct {
+	deviationThreshold float64
+	baselineWindow     int
+	store              *store.Store
+
+	mutex sync.RWMutex
+	// samples holds the gas used per header by the recent pushes, keyed by
+	// the number of pushed headers.
+	samples          store.GasUsageSamples
+	lastSample       float64
+	lastHeadersCount int
+	regressions      int
+}
+
+// NewDetector creates a new gas usage regression detector keeping the
+// samples the baseline is computed from in the given relay store.
+func NewDetector(config *Config, relayStore *store.Store) *Detector {
+	deviationThreshold := config.DeviationThreshold
+	if deviationThreshold <= 0 {
+		deviationThreshold = defaultDeviationThreshold
+	}
+
+	baselineWindow := config.BaselineWindow
+	if baselineWindow < minBaselineSamples {
+		baselineWindow = defaultBaselineWindow
+	}
+
+	samples, err := relayStore.LoadGasUsageSamples()
+	if err != nil {
+		logger.Warnf("could not load gas usage baseline: [%v]", err)
+	}
+	if samples == nil {
+		samples = make(store.GasUsageSamples)
+	}
+
+	return &Detector{
+		deviationThreshold: float64(deviationThreshold) / 100,
+		baselineWindow:     baselineWindow,
+		store:              relayStore,
+		samples:            samples,
+	}
+}
+
+// RecordAdvances checks the gas used per header by the given advances made
+// by this relay maintainer against the baseline of pushes of the same number
+// of headers. Advances which did not submit any headers are ignored.
+func (d *Detector) RecordAdvances(advances []*competition.Advance) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	recorded := false
+
+	for _, advance := range advances {
+		if !advance.Own || advance.HeadersCount == 0 || advance.GasUsed == 0 {
+			continue
+		}
+
+		sample := float64(advance.GasUsed) / float64(advance.HeadersCount)
+
+		if baseline, ok := d.baseline(advance.HeadersCount); ok {
+			deviation := (sample - baseline) / baseline
+
+			if deviation > d.deviationThreshold {
+				d.regressions++
+
+				logger.Warnf(
+					"gas used per header by push [%v] of [%v] headers is "+
+						"[%.0f] and exceeds the baseline [%.0f] by "+
+						"[%.0f%%]; check whether headers are pushed to the "+
+						"canonical branch and the batch boundaries are "+
+						"chosen well",
+					advance.TransactionHash,
+					advance.HeadersCount,
+					sample,
+					baseline,
+					deviation*100,
+				)
+			}
+		}
+
+		d.lastSample = sample
+		d.lastHeadersCount = advance.HeadersCount
+
+		samples := append(d.samples[advance.HeadersCount], sample)
+		if len(samples) > d.baselineWindow {
+			samples = samples[len(samples)-d.baselineWindow:]
+		}
+		d.samples[advance.HeadersCount] = samples
+
+		recorded = true
+	}
+
+	if !recorded {
+		return nil
+	}
+
+	return d.store.SaveGasUsageSamples(d.samples)
+}
+
+// baseline returns the median of recent samples of pushes of the given
+// number of headers. False is returned if there are not enough samples yet.
+// Must be called with the mutex held.
+func (d *Detector) baseline(headersCount int) (float64, bool) {
+	samples := d.samples[headersCount]
+	if len(samples) < minBaselineSamples {
+		return 0, false
+	}
+
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2, true
+	}
+
+	return sorted[middle], true
+}
+
+// LastGasPerHeader returns the gas used per header by the most recent push
+// of this relay maintainer or zero if there were no pushes yet.
+func (d *Detector) LastGasPerHeader() float64 {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.lastSample
+}
+
+// BaselineGasPerHeader returns the current baseline of gas used per header
+// by pushes of as many headers as the most recent push or zero if there are
+// not enough pushes to compute it.
+func (d *Detector) BaselineGasPerHeader() float64 {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	baseline, _ := d.baseline(d.lastHeadersCount)
+	return baseline
+}
+
+// Regressions returns the total number of detected regressions.
+func (d *Detector) Regressions() int {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.regressions
+}
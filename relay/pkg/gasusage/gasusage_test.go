@@ -0,0 +1,174 @@
+package gasusage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+func TestDetector(t *testing.T) {
+	var tests = map[string]struct {
+		gasUsed             uint64
+		headersCount        int
+		own                 bool
+		expectedRegressions int
+	}{
+		"usual gas per header": {
+			gasUsed:             55000,
+			headersCount:        5,
+			own:                 true,
+			expectedRegressions: 0,
+		},
+		"gas per header above baseline": {
+			gasUsed:             100000,
+			headersCount:        5,
+			own:                 true,
+			expectedRegressions: 1,
+		},
+		"gas per header below baseline": {
+			gasUsed:             20000,
+			headersCount:        5,
+			own:                 true,
+			expectedRegressions: 0,
+		},
+		"push of other number of headers": {
+			gasUsed:             30000,
+			headersCount:        2,
+			own:                 true,
+			expectedRegressions: 0,
+		},
+		"push of other relayer": {
+			gasUsed:             100000,
+			headersCount:        5,
+			own:                 false,
+			expectedRegressions: 0,
+		},
+		"push without headers": {
+			gasUsed:             100000,
+			headersCount:        0,
+			own:                 true,
+			expectedRegressions: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			detector := NewDetector(&Config{}, store.OpenMemory())
+
+			// Build the baseline of 10 000 gas per header.
+			baseline := make([]*competition.Advance, minBaselineSamples)
+			for i := range baseline {
+				baseline[i] = &competition.Advance{
+					TransactionHash: fmt.Sprintf("0x%v", i),
+					Own:             true,
+					GasUsed:         50000,
+					HeadersCount:    5,
+				}
+			}
+
+			if err := detector.RecordAdvances(baseline); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := detector.RecordAdvances([]*competition.Advance{
+				{
+					TransactionHash: "0xff",
+					Own:             test.own,
+					GasUsed:         test.gasUsed,
+					HeadersCount:    test.headersCount,
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if detector.Regressions() != test.expectedRegressions {
+				t.Errorf(
+					"unexpected number of regressions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedRegressions,
+					detector.Regressions(),
+				)
+			}
+		})
+	}
+}
+
+func TestDetector_NotEnoughSamples(t *testing.T) {
+	detector := NewDetector(&Config{}, store.OpenMemory())
+
+	if err := detector.RecordAdvances([]*competition.Advance{
+		{TransactionHash: "0x1", Own: true, GasUsed: 50000, HeadersCount: 5},
+		{TransactionHash: "0x2", Own: true, GasUsed: 500000, HeadersCount: 5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if detector.Regressions() != 0 {
+		t.Errorf("expected no regressions without baseline")
+	}
+
+	if detector.BaselineGasPerHeader() != 0 {
+		t.Errorf("expected no baseline")
+	}
+
+	if detector.LastGasPerHeader() != 100000 {
+		t.Errorf(
+			"unexpected last gas per header:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			100000,
+			detector.LastGasPerHeader(),
+		)
+	}
+}
+
+func TestDetector_PersistsBaseline(t *testing.T) {
+	dataDir := t.TempDir()
+
+	relayStore, err := store.Open(&store.Config{DataDir: dataDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detector := NewDetector(&Config{}, relayStore)
+
+	advances := make([]*competition.Advance, minBaselineSamples)
+	for i := range advances {
+		advances[i] = &competition.Advance{
+			TransactionHash: fmt.Sprintf("0x%v", i),
+			Own:             true,
+			GasUsed:         50000,
+			HeadersCount:    5,
+		}
+	}
+
+	if err := detector.RecordAdvances(advances); err != nil {
+		t.Fatal(err)
+	}
+
+	reopenedStore, err := store.Open(&store.Config{DataDir: dataDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewDetector(&Config{}, reopenedStore)
+
+	if err := restarted.RecordAdvances([]*competition.Advance{
+		{TransactionHash: "0xff", Own: true, GasUsed: 100000, HeadersCount: 5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if restarted.Regressions() != 1 {
+		t.Errorf(
+			"unexpected number of regressions:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			1,
+			restarted.Regressions(),
+		)
+	}
+}
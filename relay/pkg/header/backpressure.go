@@ -0,0 +1,122 @@
+package header
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// backpressure.go file contains the throttle of the pulling loop. Once the
+// host chain is the bottleneck, pulling headers ahead of the pushing loop
+// only loads the Bitcoin node, as the pulled headers just wait in the queue.
+// The pulling loop never gets more than a few batches ahead of the pushing
+// loop and, once a full batch is waiting in the queue, it paces its pulls to
+// the rate at which the pushing loop takes headers from the queue.
+
+// Weight of the most recent sample in the smoothed push throughput.
+const pushThroughputSmoothing = 0.5
+
+// pushThroughput tracks the rate, in headers per second, at which the pushing
+// loop takes headers from the headers queue.
+type pushThroughput struct {
+	mutex     sync.Mutex
+	rate      float64
+	lastTaken time.Time
+}
+
+// observe records a batch of the given size taken from the queue at the
+// given time.
+func (pt *pushThroughput) observe(size int, now time.Time) {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	if !pt.lastTaken.IsZero() {
+		if elapsed := now.Sub(pt.lastTaken).Seconds(); elapsed > 0 {
+			sample := float64(size) / elapsed
+			if pt.rate == 0 {
+				pt.rate = sample
+			} else {
+				pt.rate = pushThroughputSmoothing*sample +
+					(1-pushThroughputSmoothing)*pt.rate
+			}
+		}
+	}
+
+	pt.lastTaken = now
+}
+
+// interval returns the time the pushing loop needs to take a single header
+// from the queue, given its recent throughput. It returns zero if the
+// throughput is not known yet.
+func (pt *pushThroughput) interval() time.Duration {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	if pt.rate == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(time.Second) / pt.rate)
+}
+
+// notifyBatchTaken records the batch taken from the headers queue by the
+// pushing loop and wakes up the throttled pulling loop.
+func (r *Relay) notifyBatchTaken(size int) {
+	r.throughput.observe(size, r.timeSource().Now())
+
+	select {
+	case r.batchTaken <- struct{}{}:
+	default:
+		// The pulling loop has not been woken up by the previous batch yet.
+	}
+}
+
+// throttlePull blocks the pulling loop as long as it is too far ahead of the
+// pushing loop. Returns an error only if the context has been cancelled.
+func (r *Relay) throttlePull(ctx context.Context) error {
+	if r.maxPullAhead <= 0 {
+		return nil
+	}
+
+	defer Idle(ctx)()
+
+	for len(r.headersQueue) >= r.maxPullAhead {
+		r.logger.Debugf(
+			"[%v] headers waiting for push; throttling headers pulling",
+			len(r.headersQueue),
+		)
+
+		select {
+		case <-r.batchTaken:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if len(r.headersQueue) < headersBatchSize {
+		// The next batch is not full yet so the header is needed right away.
+		return nil
+	}
+
+	if r.awaitsAbove(r.nextPullHeaderHeight - 1) {
+		// Pending proofs await the header so it is needed right away.
+		return nil
+	}
+
+	interval := r.throughput.interval()
+	if interval <= 0 {
+		return nil
+	}
+	if interval > r.pullingSleepTime {
+		interval = r.pullingSleepTime
+	}
+
+	select {
+	case <-r.timeSource().After(interval):
+	case <-r.batchTaken:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+func TestPushThroughput(t *testing.T) {
+	throughput := &pushThroughput{}
+	start := time.Unix(1000, 0)
+
+	if throughput.interval() != 0 {
+		t.Fatal("interval should not be known before any batch is taken")
+	}
+
+	throughput.observe(5, start)
+	if throughput.interval() != 0 {
+		t.Fatal("interval should not be known after the first batch")
+	}
+
+	// 5 headers in 10 seconds.
+	throughput.observe(5, start.Add(10*time.Second))
+	assertInterval(t, throughput, 2*time.Second)
+
+	// 5 headers in 50 seconds, smoothed with the previous rate to 0.3
+	// headers per second.
+	throughput.observe(5, start.Add(60*time.Second))
+	assertInterval(t, throughput, 10*time.Second/3)
+}
+
+func assertInterval(
+	t *testing.T,
+	throughput *pushThroughput,
+	expectedInterval time.Duration,
+) {
+	t.Helper()
+
+	interval := throughput.interval()
+	difference := interval - expectedInterval
+	if difference < -time.Millisecond || difference > time.Millisecond {
+		t.Errorf(
+			"unexpected interval:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedInterval,
+			interval,
+		)
+	}
+}
+
+func newThrottledRelay(fakeClock *clock.Fake, queued int) *Relay {
+	relay := &Relay{
+		logger:           testLogger,
+		clock:            fakeClock,
+		maxPullAhead:     2 * headersBatchSize,
+		pullingSleepTime: time.Minute,
+		headersQueue:     make(chan *btc.Header, headersQueueSize),
+		batchTaken:       make(chan struct{}, 1),
+	}
+
+	for i := 0; i < queued; i++ {
+		relay.headersQueue <- &btc.Header{Height: int64(i)}
+	}
+
+	return relay
+}
+
+func throttleInBackground(relay *Relay) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- relay.throttlePull(context.Background())
+	}()
+	return done
+}
+
+func TestThrottlePull_QueueFull(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	relay := newThrottledRelay(fakeClock, 2*headersBatchSize)
+
+	done := throttleInBackground(relay)
+
+	select {
+	case <-done:
+		t.Fatal("pulling should be throttled while the queue is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The pushing loop takes a batch, leaving a full batch in the queue.
+	for i := 0; i < headersBatchSize; i++ {
+		<-relay.headersQueue
+	}
+	relay.notifyBatchTaken(headersBatchSize)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pulling should resume once a batch is taken")
+	}
+}
+
+func TestThrottlePull_PacedToThroughput(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	relay := newThrottledRelay(fakeClock, headersBatchSize)
+
+	// The pushing loop takes 5 headers in 10 seconds.
+	relay.throughput.observe(5, fakeClock.Now().Add(-10*time.Second))
+	relay.throughput.observe(5, fakeClock.Now())
+
+	done := throttleInBackground(relay)
+	fakeClock.BlockUntil(1)
+
+	fakeClock.Advance(time.Second)
+	select {
+	case <-done:
+		t.Fatal("pulling should be paced to the push throughput")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fakeClock.Advance(time.Second)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pulling should resume after the push interval")
+	}
+}
+
+func TestThrottlePull_NotAhead(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	relay := newThrottledRelay(fakeClock, headersBatchSize-1)
+
+	relay.throughput.observe(5, fakeClock.Now().Add(-10*time.Second))
+	relay.throughput.observe(5, fakeClock.Now())
+
+	if err := relay.throttlePull(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if fakeClock.Waiters() != 0 {
+		t.Error("pulling should not be paced until a full batch is waiting")
+	}
+}
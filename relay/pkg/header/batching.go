@@ -0,0 +1,61 @@
+package header
+
+// batching.go file contains the batching strategies of the relay. The
+// summa-v1 relay contract stores the digest of the parent of every added
+// header but the height only of headers at heights being multiples of its
+// height interval. Whenever the contract needs the height of a header, e.g.
+// of the anchor of added headers or of the current and the new best header
+// while marking a new heaviest one, it walks back through the parents until
+// it finds a header with a stored height, reading one storage slot per step.
+// The number of written storage slots does not depend on the batch
+// boundaries, but the number of read ones does. Aligning the end of each
+// batch to the height interval makes every such lookup a single read and, as
+// aligned batches are one header shorter, the best header is marked once
+// every two batches.
+
+const (
+	// BatchStrategyFixed forms batches of up to the maximum batch size,
+	// regardless of the heights of batched headers.
+	BatchStrategyFixed = "fixed"
+
+	// BatchStrategyAligned ends batches at heights being multiples of the
+	// batch alignment interval whenever the batch size allows.
+	BatchStrategyAligned = "aligned"
+)
+
+// Default interval to which the ends of batches are aligned. It is the
+// height interval of the summa-v1 relay contract.
+const defaultBatchAlignmentInterval = 4
+
+// alignedBatchSize returns the size of the batch starting with the header
+// at the given height, so the batch ends at a height being a multiple of the
+// given interval. The batch is not longer than the given maximum size. If no
+// height within the maximum size is aligned, the maximum size is returned.
+func alignedBatchSize(firstHeight int64, maxSize int, interval int64) int {
+	if interval <= 1 {
+		return maxSize
+	}
+
+	lastHeight := firstHeight + int64(maxSize) - 1
+	size := maxSize - int(lastHeight%interval)
+	if size <= 0 {
+		return maxSize
+	}
+
+	return size
+}
+
+// batchAlignment returns the interval to which the ends of batches are
+// aligned according to the given configuration. Zero is returned if batches
+// are not aligned.
+func batchAlignment(config *Config) int64 {
+	if config.BatchStrategy != BatchStrategyAligned {
+		return 0
+	}
+
+	if config.BatchAlignmentInterval > 0 {
+		return config.BatchAlignmentInterval
+	}
+
+	return defaultBatchAlignmentInterval
+}
@@ -0,0 +1,136 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestAlignedBatchSize(t *testing.T) {
+	var tests = map[string]struct {
+		firstHeight  int64
+		maxSize      int
+		interval     int64
+		expectedSize int
+	}{
+		"aligned start": {
+			firstHeight:  101,
+			maxSize:      5,
+			interval:     4,
+			expectedSize: 4,
+		},
+		"misaligned start": {
+			firstHeight:  99,
+			maxSize:      5,
+			interval:     4,
+			expectedSize: 2,
+		},
+		"maximum size aligned": {
+			firstHeight:  100,
+			maxSize:      5,
+			interval:     4,
+			expectedSize: 5,
+		},
+		"no aligned height within maximum size": {
+			firstHeight:  101,
+			maxSize:      2,
+			interval:     4,
+			expectedSize: 2,
+		},
+		"alignment disabled": {
+			firstHeight:  99,
+			maxSize:      5,
+			interval:     0,
+			expectedSize: 5,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			size := alignedBatchSize(
+				test.firstHeight,
+				test.maxSize,
+				test.interval,
+			)
+
+			if size != test.expectedSize {
+				t.Errorf(
+					"unexpected batch size\nexpected: [%v]\nactual:   [%v]",
+					test.expectedSize,
+					size,
+				)
+			}
+		})
+	}
+}
+
+func TestNewPlan_AlignedBatches(t *testing.T) {
+	estimates := &PlanEstimates{
+		GasPerHeader:           10000,
+		GasPerBestHeaderUpdate: 50000,
+	}
+
+	fixedPlan, err := NewPlan(&Config{}, 98, 138, estimates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alignedPlan, err := NewPlan(
+		&Config{BatchStrategy: BatchStrategyAligned},
+		98,
+		138,
+		estimates,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, call := range alignedPlan.Calls {
+		if call.Method == MarkNewHeaviestMethod && call.ToHeight%4 != 0 {
+			t.Errorf(
+				"best header [%v] is not aligned",
+				call.ToHeight,
+			)
+		}
+	}
+
+	if alignedPlan.EstimatedGas >= fixedPlan.EstimatedGas {
+		t.Errorf(
+			"aligned batches should use less gas\nfixed:   [%v]\naligned: [%v]",
+			fixedPlan.EstimatedGas,
+			alignedPlan.EstimatedGas,
+		)
+	}
+}
+
+func TestGetHeadersFromQueue_AlignedBatches(t *testing.T) {
+	relay := &Relay{
+		logger:         testLogger,
+		headersQueue:   make(chan *btc.Header, headersQueueSize),
+		batchAlignment: 4,
+	}
+
+	for height := int64(99); height < 109; height++ {
+		relay.headersQueue <- &btc.Header{Height: height}
+	}
+
+	expectedBatches := [][]int64{{99, 100}, {101, 102, 103, 104}}
+
+	for i, expectedHeights := range expectedBatches {
+		heights := make([]int64, 0)
+		for _, header := range relay.getHeadersFromQueue(context.Background()) {
+			heights = append(heights, header.Height)
+		}
+
+		if !reflect.DeepEqual(expectedHeights, heights) {
+			t.Errorf(
+				"unexpected batch number [%v]\nexpected: [%v]\nactual:   [%v]",
+				i+1,
+				expectedHeights,
+				heights,
+			)
+		}
+	}
+}
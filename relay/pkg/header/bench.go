@@ -0,0 +1,427 @@
+package header
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// bench.go file contains the throughput benchmark of the relay. The relay is
+// run against an in-memory Bitcoin chain of synthetic headers and a host
+// chain mock which accepts every submission after the configured latency, so
+// the measured throughput covers pulling the headers, assembling the batches
+// and pushing them, without the noise of real nodes. The benchmark guides
+// the tuning of the batch size and the headers queue and, run in CI, catches
+// performance regressions of the relay loops.
+
+const (
+	// Default number of headers relayed by a benchmark run.
+	defaultBenchHeaders = 2000
+
+	// Interval in which the benchmark checks whether the relay has pushed
+	// all headers.
+	benchProgressCheckInterval = 10 * time.Millisecond
+
+	// Rest of the pulling loop once it has pulled all headers.
+	benchPullingSleepTime = 10 * time.Millisecond
+
+	// Size of a serialized Bitcoin header in bytes.
+	benchHeaderSize = 80
+)
+
+// BenchConfig holds the configuration of a single benchmark run.
+type BenchConfig struct {
+	// Headers is the number of relayed headers. It is rounded up to
+	// a multiple of the batch size. If zero, a default value is used.
+	Headers int
+
+	// BatchSize is the maximum number of headers in a pushed batch. If zero
+	// or above the default batch size, the default batch size is used.
+	BatchSize int
+
+	// BatchesAhead is the maximum number of batches the pulling loop can
+	// get ahead of the pushing loop. If zero, a default value is used.
+	BatchesAhead int
+
+	// PushLatency is the time each submission to the host chain mock takes.
+	PushLatency time.Duration
+}
+
+// BenchResult holds the outcome of a single benchmark run.
+type BenchResult struct {
+	// Headers is the number of relayed headers.
+	Headers int
+	// BatchSize is the maximum number of headers in a pushed batch.
+	BatchSize int
+	// BatchesAhead is the maximum number of batches the pulling loop could
+	// get ahead of the pushing loop.
+	BatchesAhead int
+	// Submissions is the number of submissions made to the host chain mock,
+	// including the ones marking the new best header.
+	Submissions int
+	// Duration is the time between the start of the relay and the moment
+	// the last header has been marked as the new best.
+	Duration time.Duration
+}
+
+// HeadersPerSecond returns the throughput of the relay.
+func (br *BenchResult) HeadersPerSecond() float64 {
+	if br.Duration <= 0 {
+		return 0
+	}
+
+	return float64(br.Headers) / br.Duration.Seconds()
+}
+
+// RunBenchmark runs the relay with the given configuration until it pushes
+// all synthetic headers and returns the measured throughput. The benchmark
+// fails if the relay exits with an error or the passed context is done
+// first.
+func RunBenchmark(
+	ctx context.Context,
+	config *BenchConfig,
+) (*BenchResult, error) {
+	batchSize := config.BatchSize
+	if batchSize <= 0 || batchSize > headersBatchSize {
+		batchSize = headersBatchSize
+	}
+
+	headersCount := config.Headers
+	if headersCount <= 0 {
+		headersCount = defaultBenchHeaders
+	}
+	if remainder := headersCount % batchSize; remainder != 0 {
+		headersCount += batchSize - remainder
+	}
+
+	batchesAhead := config.BatchesAhead
+	if batchesAhead <= 0 {
+		batchesAhead = defaultMaxBatchesAhead
+	}
+
+	// The header at height zero is considered relayed already.
+	headers := benchHeaders(headersCount + 1)
+
+	btcChain := newBenchBtcChain(headers)
+
+	relayConfig := &Config{
+		HeaderValidation: HeaderValidationOff,
+		// Keep the relay in the catch-up phase, so it never rests between
+		// pushes.
+		CatchUpLagThreshold: 1,
+		MaxBatchesAhead:     batchesAhead,
+	}
+	if err := relayConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	hostChain, err := newBenchHostChain(
+		headers,
+		config.PushLatency,
+		clock.System,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	control := NewControl(nil)
+	if batchSize < headersBatchSize {
+		control.EnterLowLatency(benchPullingSleepTime, batchSize)
+	}
+
+	relayCtx, cancelRelayCtx := context.WithCancel(ctx)
+	defer cancelRelayCtx()
+
+	startedAt := hostChain.clock.Now()
+
+	relay := startRelay(
+		relayCtx,
+		btcChain,
+		hostChain,
+		store.OpenMemory(),
+		relayConfig,
+		control,
+		btcDifficultyEpochDuration,
+		benchPullingSleepTime,
+		0,
+		NewFeed(nil),
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+
+	lastDigest := headers[len(headers)-1].Hash
+
+	for !hostChain.isBest(lastDigest) {
+		select {
+		case <-hostChain.clock.After(benchProgressCheckInterval):
+		case err := <-relay.ErrChan():
+			return nil, fmt.Errorf("relay failed: [%v]", err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return &BenchResult{
+		Headers:      headersCount,
+		BatchSize:    batchSize,
+		BatchesAhead: batchesAhead,
+		Submissions:  hostChain.submissionsCount(),
+		Duration:     hostChain.clock.Since(startedAt),
+	}, nil
+}
+
+// benchHeaders returns the given number of synthetic headers starting at
+// height zero. The digest of each header encodes its height, so it is
+// ordered the same way by the ancestry checks of the host chain mock.
+func benchHeaders(count int) []*btc.Header {
+	headers := make([]*btc.Header, count)
+
+	for height := range headers {
+		var digest btc.Digest
+		binary.LittleEndian.PutUint32(digest[:], uint32(height))
+
+		var prevDigest btc.Digest
+		if height > 0 {
+			prevDigest = headers[height-1].Hash
+		}
+
+		raw := make([]byte, benchHeaderSize)
+		binary.LittleEndian.PutUint32(raw, 1)
+		copy(raw[4:36], prevDigest[:])
+		copy(raw[36:68], digest[:])
+		binary.LittleEndian.PutUint32(raw[68:], uint32(height*600))
+		binary.LittleEndian.PutUint32(raw[72:], 0x207fffff)
+
+		headers[height] = &btc.Header{
+			Hash:       digest,
+			Height:     int64(height),
+			PrevHash:   prevDigest,
+			MerkleRoot: digest,
+			Raw:        raw,
+		}
+	}
+
+	return headers
+}
+
+// benchBtcChain is the Bitcoin chain of the benchmark. Unlike the local
+// chain, it looks headers up in constant time, so the benchmark measures the
+// relay rather than the chain mock.
+type benchBtcChain struct {
+	btc.Handle
+
+	headers  []*btc.Header
+	byDigest map[btc.Digest]*btc.Header
+}
+
+func newBenchBtcChain(headers []*btc.Header) *benchBtcChain {
+	localChain, _ := btc.ConnectLocal()
+
+	byDigest := make(map[btc.Digest]*btc.Header, len(headers))
+	for _, header := range headers {
+		byDigest[header.Hash] = header
+	}
+
+	return &benchBtcChain{
+		Handle:   localChain,
+		headers:  headers,
+		byDigest: byDigest,
+	}
+}
+
+func (bbc *benchBtcChain) GetHeaderByHeight(
+	ctx context.Context,
+	height int64,
+) (*btc.Header, error) {
+	if height < 0 || height >= int64(len(bbc.headers)) {
+		return nil, fmt.Errorf("no header with height [%v]", height)
+	}
+
+	return bbc.headers[height], nil
+}
+
+func (bbc *benchBtcChain) GetHeaderByDigest(
+	ctx context.Context,
+	digest btc.Digest,
+) (*btc.Header, error) {
+	header, ok := bbc.byDigest[digest]
+	if !ok {
+		return nil, fmt.Errorf("no header with digest [%v]", digest)
+	}
+
+	return header, nil
+}
+
+func (bbc *benchBtcChain) GetHeightByDigest(
+	ctx context.Context,
+	digest btc.Digest,
+) (int64, error) {
+	header, err := bbc.GetHeaderByDigest(ctx, digest)
+	if err != nil {
+		return 0, err
+	}
+
+	return header.Height, nil
+}
+
+func (bbc *benchBtcChain) GetBlockCount(ctx context.Context) (int64, error) {
+	return int64(len(bbc.headers) - 1), nil
+}
+
+// benchHostChain is the host chain mock of the benchmark. It accepts every
+// submission after the configured latency and, unlike the local chain,
+// learns the submitted headers, so the relay observes its own progress.
+type benchHostChain struct {
+	*chainlocal.Chain
+
+	latency time.Duration
+	clock   clock.Clock
+	byRaw   map[string]*btc.Header
+
+	mutex       sync.Mutex
+	heights     map[btc.Digest]int64
+	best        btc.Digest
+	submissions int
+}
+
+func newBenchHostChain(
+	headers []*btc.Header,
+	latency time.Duration,
+	clock clock.Clock,
+) (*benchHostChain, error) {
+	handle, err := chainlocal.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	byRaw := make(map[string]*btc.Header, len(headers))
+	for _, header := range headers {
+		byRaw[string(header.Raw)] = header
+	}
+
+	return &benchHostChain{
+		Chain:   handle.(*chainlocal.Chain),
+		latency: latency,
+		clock:   clock,
+		byRaw:   byRaw,
+		heights: map[btc.Digest]int64{headers[0].Hash: 0},
+		best:    headers[0].Hash,
+	}, nil
+}
+
+// submit waits for the submission latency and records the given packed
+// headers as known by the host chain.
+func (bhc *benchHostChain) submit(ctx context.Context, packed []byte) error {
+	if bhc.latency > 0 {
+		select {
+		case <-bhc.clock.After(bhc.latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	bhc.mutex.Lock()
+	defer bhc.mutex.Unlock()
+
+	bhc.submissions++
+
+	for i := 0; i+benchHeaderSize <= len(packed); i += benchHeaderSize {
+		header, ok := bhc.byRaw[string(packed[i:i+benchHeaderSize])]
+		if !ok {
+			return fmt.Errorf("unknown header submitted")
+		}
+
+		bhc.heights[header.Hash] = header.Height
+	}
+
+	return nil
+}
+
+func (bhc *benchHostChain) GetBestKnownDigest(
+	ctx context.Context,
+) (btc.Digest, error) {
+	bhc.mutex.Lock()
+	defer bhc.mutex.Unlock()
+
+	return bhc.best, nil
+}
+
+func (bhc *benchHostChain) FindHeight(
+	ctx context.Context,
+	digest btc.Digest,
+) (*big.Int, error) {
+	bhc.mutex.Lock()
+	defer bhc.mutex.Unlock()
+
+	height, ok := bhc.heights[digest]
+	if !ok {
+		return nil, fmt.Errorf("unknown block [%v]", digest)
+	}
+
+	return big.NewInt(height), nil
+}
+
+func (bhc *benchHostChain) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	return bhc.submit(ctx, headers)
+}
+
+func (bhc *benchHostChain) AddHeadersWithRetarget(
+	ctx context.Context,
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) error {
+	return bhc.submit(ctx, headers)
+}
+
+func (bhc *benchHostChain) MarkNewHeaviest(
+	ctx context.Context,
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) error {
+	if err := bhc.submit(ctx, nil); err != nil {
+		return err
+	}
+
+	header, ok := bhc.byRaw[string(newBestHeader)]
+	if !ok {
+		return fmt.Errorf("unknown new best header")
+	}
+
+	bhc.mutex.Lock()
+	defer bhc.mutex.Unlock()
+
+	bhc.best = header.Hash
+
+	return nil
+}
+
+func (bhc *benchHostChain) isBest(digest btc.Digest) bool {
+	bhc.mutex.Lock()
+	defer bhc.mutex.Unlock()
+
+	return bhc.best == digest
+}
+
+func (bhc *benchHostChain) submissionsCount() int {
+	bhc.mutex.Lock()
+	defer bhc.mutex.Unlock()
+
+	return bhc.submissions
+}
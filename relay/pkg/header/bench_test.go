@@ -0,0 +1,82 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	result, err := RunBenchmark(ctx, &BenchConfig{Headers: 98, BatchSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The number of headers is rounded up to a multiple of the batch size.
+	if result.Headers != 99 {
+		t.Errorf(
+			"unexpected number of headers:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			99,
+			result.Headers,
+		)
+	}
+
+	// Each batch is added and then marked as the new best.
+	if result.Submissions != 66 {
+		t.Errorf(
+			"unexpected number of submissions:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			66,
+			result.Submissions,
+		)
+	}
+
+	if result.HeadersPerSecond() <= 0 {
+		t.Errorf("unexpected throughput: [%v]", result.HeadersPerSecond())
+	}
+}
+
+// BenchmarkRelay measures the time the relay takes to pull and push a single
+// header under varying batch sizes, headers queue sizes and push latencies.
+func BenchmarkRelay(b *testing.B) {
+	batchSizes := []int{1, headersBatchSize}
+	batchesAheads := []int{1, headersQueueSize / headersBatchSize}
+	pushLatencies := []time.Duration{0, time.Millisecond}
+
+	for _, batchSize := range batchSizes {
+		for _, batchesAhead := range batchesAheads {
+			for _, pushLatency := range pushLatencies {
+				name := fmt.Sprintf(
+					"batch=%v/ahead=%v/latency=%v",
+					batchSize,
+					batchesAhead,
+					pushLatency,
+				)
+
+				b.Run(name, func(b *testing.B) {
+					result, err := RunBenchmark(
+						context.Background(),
+						&BenchConfig{
+							Headers:      b.N,
+							BatchSize:    batchSize,
+							BatchesAhead: batchesAhead,
+							PushLatency:  pushLatency,
+						},
+					)
+					if err != nil {
+						b.Fatal(err)
+					}
+
+					b.ReportMetric(result.HeadersPerSecond(), "headers/s")
+				})
+			}
+		}
+	}
+}
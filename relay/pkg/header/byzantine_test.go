@@ -0,0 +1,239 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+// byzantineChain creates a Byzantine chain serving valid serialized headers
+// from the genesis to the given height.
+func byzantineChain(t *testing.T, height int64) *btc.ByzantineChain {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := bc.(*btc.LocalChain)
+
+	genesisTime := time.Now().Add(-24 * time.Hour)
+
+	headers := []*btc.Header{
+		serializedHeader(t, btc.Digest{}, 0, genesisTime, 4),
+	}
+	for i := int64(1); i <= height; i++ {
+		headers = append(headers, serializedHeader(
+			t,
+			headers[i-1].Hash,
+			i,
+			genesisTime.Add(time.Duration(i)*10*time.Minute),
+			4,
+		))
+	}
+
+	localChain.SetHeaders(headers)
+
+	return btc.NewByzantineChain(localChain)
+}
+
+func TestValidateHeader_ByzantineNode(t *testing.T) {
+	const pulledHeight = 15
+
+	var tests = map[string]struct {
+		faultHeight int64
+		fault       btc.ByzantineFault
+		expectError bool
+	}{
+		"honest node": {
+			faultHeight: pulledHeight,
+			fault:       btc.FaultNone,
+			expectError: false,
+		},
+		"wrong previous hash of pulled header": {
+			faultHeight: pulledHeight,
+			fault:       btc.FaultWrongPrevHash,
+			expectError: true,
+		},
+		"serialized pulled header not matching its hash": {
+			faultHeight: pulledHeight,
+			fault:       btc.FaultMismatchedRaw,
+			expectError: true,
+		},
+		"wrong height of pulled header": {
+			faultHeight: pulledHeight,
+			fault:       btc.FaultWrongHeight,
+			expectError: true,
+		},
+		"wrong previous hash of ancestor": {
+			faultHeight: pulledHeight - 3,
+			fault:       btc.FaultWrongPrevHash,
+			expectError: true,
+		},
+		"serialized ancestor not matching its hash": {
+			faultHeight: pulledHeight - 1,
+			fault:       btc.FaultMismatchedRaw,
+			expectError: true,
+		},
+		"wrong height of ancestor": {
+			faultHeight: pulledHeight - 5,
+			fault:       btc.FaultWrongHeight,
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := context.Background()
+
+			btcChain := byzantineChain(t, pulledHeight)
+			btcChain.SetFault(test.faultHeight, test.fault)
+
+			relay := &Relay{
+				logger:           testLogger,
+				btcChain:         btcChain,
+				headerValidation: HeaderValidationEnforce,
+				headerValidator:  btc.NewHeaderValidator(btcChain.NetworkParams()),
+			}
+
+			header, err := btcChain.GetHeaderByHeight(ctx, pulledHeight)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = relay.validateHeader(ctx, header)
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Errorf(
+					"unexpected validation result:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+		})
+	}
+}
+
+func TestValidateHeader_ByzantineNodeInWarnMode(t *testing.T) {
+	ctx := context.Background()
+
+	btcChain := byzantineChain(t, 15)
+	btcChain.SetFault(14, btc.FaultWrongPrevHash)
+
+	relay := &Relay{
+		logger:           testLogger,
+		btcChain:         btcChain,
+		headerValidation: HeaderValidationWarn,
+		headerValidator:  btc.NewHeaderValidator(btcChain.NetworkParams()),
+	}
+
+	consistentHeader, err := btcChain.GetHeaderByHeight(ctx, 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := relay.validateHeader(ctx, consistentHeader); err != nil {
+		t.Fatal(err)
+	}
+
+	inconsistentHeader, err := btcChain.GetHeaderByHeight(ctx, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := relay.validateHeader(ctx, inconsistentHeader); err != nil {
+		t.Fatalf("unexpected error in warn mode: [%v]", err)
+	}
+
+	// The inconsistent header must not become part of the validation
+	// context.
+	nextHeader, err := btcChain.GetHeaderByHeight(ctx, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if relay.headerValidator.Extends(nextHeader) {
+		t.Errorf("inconsistent header made part of the validation context")
+	}
+}
+
+func TestForkGateStage_ByzantineFlipFloppingTips(t *testing.T) {
+	btcChain := byzantineChain(t, 10)
+
+	lostHeader := serializedHeader(
+		t,
+		to32Bytes(9),
+		10,
+		time.Now(),
+		4,
+	)
+
+	contested := []*btc.ChainTip{
+		{Height: 10, Status: btc.ChainTipActive},
+		{Height: 10, BranchLength: 1, Status: btc.ChainTipValidFork},
+	}
+	resolved := []*btc.ChainTip{
+		{Height: 10, Status: btc.ChainTipActive},
+	}
+	btcChain.FlipFlopTips(contested, resolved)
+
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+	relay := &Relay{
+		logger:   testLogger,
+		btcChain: btcChain,
+		clock:    fakeClock,
+		forks:    &forkMonitor{depth: 6},
+		observer: &mockObserver{},
+	}
+
+	relay.checkForks(context.Background())
+
+	type stageResult struct {
+		passed bool
+		err    error
+	}
+
+	done := make(chan stageResult, 1)
+	go func() {
+		passed, err := relay.forkGateStage(context.Background(), lostHeader)
+		done <- stageResult{passed, err}
+	}()
+
+	fakeClock.BlockUntil(1)
+
+	// The tips flip to no fork and back before the gate checks them again.
+	relay.checkForks(context.Background())
+	relay.checkForks(context.Background())
+	fakeClock.Advance(forkMonitoringTick)
+
+	select {
+	case <-done:
+		t.Fatal("header contested again should be held back")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Even once released, the header is checked against the active chain
+	// and dropped as it is not part of it.
+	relay.checkForks(context.Background())
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(forkMonitoringTick)
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		if result.passed {
+			t.Errorf("header not on the active chain should be dropped")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("header should be released once the fork is resolved")
+	}
+}
@@ -0,0 +1,155 @@
+package header
+
+import (
+	"context"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// catchup.go file contains the logic of the catch-up phase. Once the relay
+// falls far behind the Bitcoin chain, e.g. because the host chain could not
+// be written to for a while, it stops resting between pushes and submits
+// subsequent batches right away. Submitted transactions are ordered by their
+// nonces so batches can be pipelined without waiting for the previous ones
+// to get mined. Both the number of batches not yet known by the host chain
+// and the number of operator transactions pending according to the
+// transaction monitor are bounded to not flood the host chain mempool.
+// Marking a new best header depends on the pushed headers being mined, so it
+// is deferred while transactions are in flight, for a bounded number of
+// batches. The phase ends once the number of Bitcoin blocks not pushed yet
+// drops below the threshold.
+
+// updateCatchUpPhase determines whether the relay should stay in the
+// catch-up phase after pushing the given header. It returns true if the next
+// batch should be pushed right away.
+func (r *Relay) updateCatchUpPhase(
+	ctx context.Context,
+	lastPushedHeader *btc.Header,
+) bool {
+	batchLogger := correlation.InjectedLogger(ctx, r.logger)
+
+	chainHeight, err := r.btcChain.GetBlockCount(ctx)
+	if err != nil {
+		batchLogger.Warnf(
+			"could not get block count; leaving catch-up phase: [%v]",
+			err,
+		)
+		r.catchingUp = false
+		return false
+	}
+
+	pushLag := chainHeight - lastPushedHeader.Height
+
+	if pushLag >= r.catchUpLagThreshold {
+		if !r.catchingUp {
+			batchLogger.Infof(
+				"entering catch-up phase as [%v] blocks are not pushed yet",
+				pushLag,
+			)
+		}
+
+		r.catchingUp = true
+		return true
+	}
+
+	if r.catchingUp {
+		batchLogger.Infof(
+			"leaving catch-up phase as [%v] blocks are not pushed yet",
+			pushLag,
+		)
+	}
+
+	r.catchingUp = false
+	return false
+}
+
+// waitForPendingBatches blocks until the number of pushed batches not yet
+// known by the host chain and the number of in-flight transactions drop
+// below their limits.
+func (r *Relay) waitForPendingBatches(ctx context.Context) error {
+	for {
+		pending := r.countPendingBatches(ctx)
+		inFlight := r.countInFlightTransactions(ctx)
+		if pending < r.maxPendingBatches && inFlight < r.maxInFlight {
+			return nil
+		}
+
+		correlation.InjectedLogger(ctx, r.logger).Infof(
+			"[%v] pushed batches are not known by the host chain yet and "+
+				"[%v] transactions are in flight; waiting before pushing "+
+				"the next batch",
+			pending,
+			inFlight,
+		)
+
+		if _, err := r.control.Scheduler().wait(
+			ctx,
+			TaskPendingBatches,
+			pendingBatchesCheckInterval,
+			true,
+			nil,
+		); err != nil {
+			return err
+		}
+	}
+}
+
+// countPendingBatches returns the number of tracked batches whose last header
+// is not known by the host chain yet.
+func (r *Relay) countPendingBatches(ctx context.Context) int {
+	pending := 0
+
+	for _, batch := range r.finalityTracker.pending() {
+		if _, err := r.hostChain.FindHeight(ctx, batch.lastDigest); err != nil {
+			pending++
+		}
+	}
+
+	return pending
+}
+
+// countInFlightTransactions returns the number of operator transactions
+// submitted to the host chain and not mined yet, as tracked by
+// the transaction monitor. If the number cannot be determined, zero is
+// returned and only the number of pending batches is bounded.
+func (r *Relay) countInFlightTransactions(ctx context.Context) int {
+	inFlight, err := r.hostChain.PendingTransactions(ctx)
+	if err != nil {
+		correlation.InjectedLogger(ctx, r.logger).Warnf(
+			"could not get number of in-flight transactions: [%v]",
+			err,
+		)
+		return 0
+	}
+
+	return inFlight
+}
+
+// deferBestHeaderUpdate determines whether marking a new best header should
+// be deferred. During the catch-up phase, the headers of the pipelined
+// batches are not known by the host chain until their transactions get
+// mined, so marking a new best header right away would wait for them and
+// stall the pipeline. The update is deferred while transactions are in
+// flight, but for no more headers than fit in the in-flight batches.
+func (r *Relay) deferBestHeaderUpdate(ctx context.Context) bool {
+	if !r.catchingUp {
+		return false
+	}
+
+	if r.processedHeaders >= r.maxInFlight*r.control.batchSize() {
+		return false
+	}
+
+	inFlight := r.countInFlightTransactions(ctx)
+	if inFlight == 0 {
+		return false
+	}
+
+	correlation.InjectedLogger(ctx, r.logger).Infof(
+		"deferring update of best header as [%v] transactions are in flight",
+		inFlight,
+	)
+
+	return true
+}
@@ -0,0 +1,218 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+)
+
+func TestUpdateCatchUpPhase(t *testing.T) {
+	ctx := context.Background()
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	btcChain.SetHeaders([]*btc.Header{
+		{Hash: to32Bytes(100), Height: 100},
+	})
+
+	relay := &Relay{
+		logger:              testLogger,
+		btcChain:            btcChain,
+		catchUpLagThreshold: 10,
+	}
+
+	var tests = map[string]struct {
+		lastPushedHeight int64
+		expected         bool
+	}{
+		"far behind the tip": {
+			lastPushedHeight: 80,
+			expected:         true,
+		},
+		"at the threshold": {
+			lastPushedHeight: 90,
+			expected:         true,
+		},
+		"close to the tip": {
+			lastPushedHeight: 95,
+			expected:         false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := relay.updateCatchUpPhase(
+				ctx,
+				&btc.Header{Height: test.lastPushedHeight},
+			)
+			if test.expected != actual {
+				t.Errorf(
+					"unexpected catch-up phase:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expected,
+					actual,
+				)
+			}
+
+			if relay.catchingUp != actual {
+				t.Errorf("catch-up phase state not updated")
+			}
+		})
+	}
+}
+
+func TestCountPendingBatches(t *testing.T) {
+	ctx := context.Background()
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	relay := &Relay{
+		logger:            testLogger,
+		hostChain:         localChain,
+		finalityTracker:   &finalityTracker{},
+		maxPendingBatches: 2,
+		maxInFlight:       2,
+	}
+
+	relay.trackPushedBatch(ctx, []*btc.Header{{Hash: to32Bytes(1), Height: 1}})
+	relay.trackPushedBatch(ctx, []*btc.Header{{Hash: to32Bytes(2), Height: 2}})
+
+	// The first batch is already known by the host chain.
+	localChain.SetHeaderHeight(to32Bytes(1), 1)
+
+	pending := relay.countPendingBatches(ctx)
+	if pending != 1 {
+		t.Errorf(
+			"unexpected number of pending batches:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			1,
+			pending,
+		)
+	}
+
+	// One pending batch is below the limit so the wait should not block.
+	if err := relay.waitForPendingBatches(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForPendingBatches_InFlightTransactions(t *testing.T) {
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	relay := &Relay{
+		logger:            testLogger,
+		hostChain:         localChain,
+		control:           NewControl(nil),
+		finalityTracker:   &finalityTracker{},
+		maxPendingBatches: 3,
+		maxInFlight:       2,
+	}
+
+	// All batches are known by the host chain but the transactions marking
+	// the new best header are still in flight.
+	localChain.SetPendingTransactions(2)
+
+	ctx, cancelCtx := context.WithTimeout(
+		context.Background(),
+		100*time.Millisecond,
+	)
+	defer cancelCtx()
+
+	if err := relay.waitForPendingBatches(ctx); err == nil {
+		t.Errorf("wait should block while the in-flight limit is reached")
+	}
+
+	localChain.SetPendingTransactions(1)
+
+	if err := relay.waitForPendingBatches(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeferBestHeaderUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	var tests = map[string]struct {
+		catchingUp          bool
+		processedHeaders    int
+		pendingTransactions int
+		expected            bool
+	}{
+		"transactions in flight during catch-up": {
+			catchingUp:          true,
+			processedHeaders:    headersBatchSize,
+			pendingTransactions: 1,
+			expected:            true,
+		},
+		"no transactions in flight": {
+			catchingUp:          true,
+			processedHeaders:    headersBatchSize,
+			pendingTransactions: 0,
+			expected:            false,
+		},
+		"deferred for all in-flight batches": {
+			catchingUp:          true,
+			processedHeaders:    2 * headersBatchSize,
+			pendingTransactions: 1,
+			expected:            false,
+		},
+		"not catching up": {
+			catchingUp:          false,
+			processedHeaders:    headersBatchSize,
+			pendingTransactions: 1,
+			expected:            false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			localChain.SetPendingTransactions(test.pendingTransactions)
+
+			relay := &Relay{
+				logger:           testLogger,
+				hostChain:        localChain,
+				catchingUp:       test.catchingUp,
+				processedHeaders: test.processedHeaders,
+				maxInFlight:      2,
+			}
+
+			actual := relay.deferBestHeaderUpdate(ctx)
+			if test.expected != actual {
+				t.Errorf(
+					"unexpected deferral:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expected,
+					actual,
+				)
+			}
+		})
+	}
+}
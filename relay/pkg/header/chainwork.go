@@ -0,0 +1,154 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// chainwork.go file contains the logic tracking the cumulative proof of work
+// of the headers observed by the relay. Branches are compared by their
+// chainwork instead of their heights, because a shorter branch can contain
+// more work once the difficulty changes.
+
+// Default maximum number of headers whose chainwork is tracked. Once it is
+// exceeded, the tracking starts over from the next observed header.
+const chainworkTrackerSize = 10 * btcDifficultyEpochDuration
+
+// chainworkTracker keeps the cumulative chainwork of the observed headers,
+// counted from the parent of the first observed header. The zero value is
+// ready to use.
+type chainworkTracker struct {
+	// limit is the maximum number of tracked headers. If zero, a default
+	// value is used.
+	limit int
+
+	mutex     sync.Mutex
+	chainwork map[btc.Digest]*big.Int
+}
+
+// observe records the chainwork of the given header. If the parent of the
+// header has not been observed, the tracking starts over from the header.
+func (ct *chainworkTracker) observe(header *btc.Header) error {
+	work, err := header.Work()
+	if err != nil {
+		return fmt.Errorf(
+			"could not compute work of header [%v]: [%v]",
+			header.Height,
+			err,
+		)
+	}
+
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	limit := ct.limit
+	if limit <= 0 {
+		limit = chainworkTrackerSize
+	}
+
+	parentChainwork, ok := ct.chainwork[header.PrevHash]
+	if !ok || len(ct.chainwork) >= limit {
+		ct.chainwork = make(map[btc.Digest]*big.Int)
+		parentChainwork = big.NewInt(0)
+	}
+
+	ct.chainwork[header.Hash] = new(big.Int).Add(parentChainwork, work)
+
+	return nil
+}
+
+// workSince returns the chainwork of the given tip and its ancestors up to,
+// but excluding, the given ancestor header, provided both were observed.
+func (ct *chainworkTracker) workSince(
+	tip *btc.Header,
+	ancestor *btc.Header,
+) (*big.Int, bool) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	tipChainwork, ok := ct.chainwork[tip.Hash]
+	if !ok {
+		return nil, false
+	}
+
+	ancestorChainwork, ok := ct.chainwork[ancestor.Hash]
+	if !ok {
+		return nil, false
+	}
+
+	return new(big.Int).Sub(tipChainwork, ancestorChainwork), true
+}
+
+// branchWork returns the chainwork of the branch from the given ancestor,
+// exclusive, to the given tip, inclusive. The tracked chainwork is used if
+// available; otherwise, the branch headers are fetched from the Bitcoin
+// chain.
+func (r *Relay) branchWork(
+	ctx context.Context,
+	tip *btc.Header,
+	ancestor *btc.Header,
+) (*big.Int, error) {
+	if work, ok := r.chainwork.workSince(tip, ancestor); ok {
+		return work, nil
+	}
+
+	work := big.NewInt(0)
+
+	header := tip
+	for header.Hash != ancestor.Hash {
+		if header.Height <= ancestor.Height {
+			return nil, fmt.Errorf(
+				"header [%v] does not descend from header [%v]",
+				tip.Hash,
+				ancestor.Hash,
+			)
+		}
+
+		headerWork, err := header.Work()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not compute work of header [%v]: [%v]",
+				header.Height,
+				err,
+			)
+		}
+
+		work.Add(work, headerWork)
+
+		header, err = r.btcChain.GetHeaderByDigest(ctx, header.PrevHash)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not get header by digest: [%v]",
+				err,
+			)
+		}
+	}
+
+	return work, nil
+}
+
+// isHeavierBranch checks whether the branch ending with the new best header
+// contains more chainwork than the branch ending with the current best
+// header, both counted from their last common ancestor.
+func (r *Relay) isHeavierBranch(
+	ctx context.Context,
+	newBestHeader *btc.Header,
+	currentBestHeader *btc.Header,
+	lastCommonAncestor *btc.Header,
+) (bool, error) {
+	newWork, err := r.branchWork(ctx, newBestHeader, lastCommonAncestor)
+	if err != nil {
+		return false, err
+	}
+
+	currentWork, err := r.branchWork(ctx, currentBestHeader, lastCommonAncestor)
+	if err != nil {
+		return false, err
+	}
+
+	return newWork.Cmp(currentWork) > 0, nil
+}
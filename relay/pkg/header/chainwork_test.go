@@ -0,0 +1,178 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+const (
+	// Difficulty bits of the lowest possible regtest difficulty.
+	lowDifficultyBits = 0x207fffff
+
+	// Difficulty bits of the lowest possible mainnet difficulty.
+	highDifficultyBits = 0x1d00ffff
+)
+
+func buildHeader(
+	t *testing.T,
+	parent *btc.Header,
+	bits uint32,
+) *btc.Header {
+	blockHeader := &wire.BlockHeader{
+		Version:   4,
+		PrevBlock: chainhash.Hash(parent.Hash),
+		Timestamp: time.Unix(1600000000+parent.Height*600, 0),
+		Bits:      bits,
+	}
+
+	var buffer bytes.Buffer
+	if err := blockHeader.Serialize(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := btc.ParseHeader(parent.Height+1, buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return header
+}
+
+func TestIsHeavierBranch(t *testing.T) {
+	ctx := context.Background()
+
+	ancestor := buildHeader(t, &btc.Header{}, lowDifficultyBits)
+
+	// The longer branch is built with a lower difficulty than the shorter
+	// one.
+	longBranch := []*btc.Header{buildHeader(t, ancestor, lowDifficultyBits)}
+	for i := 0; i < 3; i++ {
+		longBranch = append(
+			longBranch,
+			buildHeader(t, longBranch[len(longBranch)-1], lowDifficultyBits),
+		)
+	}
+	shortBranch := []*btc.Header{buildHeader(t, ancestor, highDifficultyBits)}
+
+	longTip := longBranch[len(longBranch)-1]
+	shortTip := shortBranch[len(shortBranch)-1]
+
+	var tests = map[string]struct {
+		newBestHeader     *btc.Header
+		currentBestHeader *btc.Header
+		observed          []*btc.Header
+		expectedHeavier   bool
+	}{
+		"shorter branch with more work": {
+			newBestHeader:     shortTip,
+			currentBestHeader: longTip,
+			expectedHeavier:   true,
+		},
+		"longer branch with less work": {
+			newBestHeader:     longTip,
+			currentBestHeader: shortTip,
+			expectedHeavier:   false,
+		},
+		"extension of the current best header": {
+			newBestHeader:     longTip,
+			currentBestHeader: ancestor,
+			expectedHeavier:   true,
+		},
+		"observed headers": {
+			newBestHeader:     longTip,
+			currentBestHeader: ancestor,
+			observed:          append([]*btc.Header{ancestor}, longBranch...),
+			expectedHeavier:   true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			btcChain := bc.(*btc.LocalChain)
+			btcChain.SetHeaders(append([]*btc.Header{ancestor}, longBranch...))
+			btcChain.SetOrphanedHeaders(shortBranch)
+
+			relay := &Relay{logger: testLogger, btcChain: btcChain}
+
+			for _, header := range test.observed {
+				if err := relay.chainwork.observe(header); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			heavier, err := relay.isHeavierBranch(
+				ctx,
+				test.newBestHeader,
+				test.currentBestHeader,
+				ancestor,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.expectedHeavier != heavier {
+				t.Errorf(
+					"unexpected comparison result:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedHeavier,
+					heavier,
+				)
+			}
+		})
+	}
+}
+
+func TestChainworkTracker(t *testing.T) {
+	first := buildHeader(t, &btc.Header{}, lowDifficultyBits)
+	second := buildHeader(t, first, highDifficultyBits)
+	unrelated := buildHeader(t, &btc.Header{Height: 10}, lowDifficultyBits)
+
+	tracker := &chainworkTracker{}
+
+	for _, header := range []*btc.Header{first, second} {
+		if err := tracker.observe(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedWork, err := second.Work()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work, ok := tracker.workSince(second, first)
+	if !ok {
+		t.Fatal("expected chainwork of observed headers")
+	}
+
+	if work.Cmp(expectedWork) != 0 {
+		t.Errorf(
+			"unexpected chainwork:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedWork,
+			work,
+		)
+	}
+
+	// A header not extending the observed ones starts the tracking over.
+	if err := tracker.observe(unrelated); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tracker.workSince(second, first); ok {
+		t.Errorf("expected tracking to start over")
+	}
+}
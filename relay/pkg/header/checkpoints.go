@@ -0,0 +1,31 @@
+package header
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// checkpoints.go file contains the logic protecting the relay against
+// an alternate history fed by a malicious Bitcoin node. The relay refuses
+// to relay a chain which does not pass through the checkpoints built into
+// the network parameters and the ones set in the relay configuration.
+
+// newCheckpointVerifier creates a verifier of the built-in checkpoints of
+// the given Bitcoin network and the checkpoints from the relay configuration.
+func newCheckpointVerifier(
+	params *chaincfg.Params,
+	config *Config,
+	logger logs.Logger,
+) (*btc.CheckpointVerifier, error) {
+	configured, err := btc.ParseCheckpoints(config.Checkpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := append(btc.BuiltinCheckpoints(params), configured...)
+
+	logger.Infof("using [%v] known checkpoints", len(checkpoints))
+
+	return btc.NewCheckpointVerifier(checkpoints)
+}
@@ -0,0 +1,53 @@
+package header
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestNewCheckpointVerifier(t *testing.T) {
+	var tests = map[string]struct {
+		params      *chaincfg.Params
+		checkpoints []string
+		expectError bool
+	}{
+		"built-in checkpoints only": {
+			params: &chaincfg.MainNetParams,
+		},
+		"configured checkpoint": {
+			params: &chaincfg.RegressionNetParams,
+			checkpoints: []string{
+				"1:0f9188f13cb7b2c71f2a335e3a4fc328bf5beb436012afca590b1a11466e2206",
+			},
+		},
+		"configured checkpoint conflicting with built-in one": {
+			params: &chaincfg.MainNetParams,
+			checkpoints: []string{
+				"11111:0f9188f13cb7b2c71f2a335e3a4fc328bf5beb436012afca590b1a11466e2206",
+			},
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := newCheckpointVerifier(
+				test.params,
+				&Config{Checkpoints: test.checkpoints},
+				testLogger,
+			)
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Errorf(
+					"unexpected error:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+		})
+	}
+}
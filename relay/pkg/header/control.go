@@ -0,0 +1,160 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// ErrResyncRequested is raised by the relay once the operator requested
+// a resync of the best header. The relay control loop should restart
+// the relay immediately, without treating it as a failure.
+var ErrResyncRequested = errors.New("resync of the best header requested")
+
+// Control allows the operator to intervene in the work of the headers relay
+// at runtime. The same control instance should be passed to all subsequent
+// relay instances so that its state survives relay restarts.
+type Control struct {
+	mutex   sync.Mutex
+	paused  bool
+	resumed chan struct{}
+
+	pushRequests   chan struct{}
+	resyncRequests chan struct{}
+
+	// lowLatencyBatchSize is the maximum size of pushed batches in the
+	// low-latency mode. It is zero if the mode is not active.
+	lowLatencyBatchSize int
+
+	// priorities maps the pending proofs to the heights of the last
+	// headers they await.
+	priorities map[string]int64
+
+	scheduler *Scheduler
+
+	logger logs.Logger
+}
+
+// NewControl creates a new relay control. The relay is not paused initially.
+// Messages are logged to the given logger or, if it is nil, to the default
+// logger.
+func NewControl(logger logs.Logger) *Control {
+	logger = logs.OrDefault(logger, loggerName)
+
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &Control{
+		resumed:        resumed,
+		pushRequests:   make(chan struct{}, 1),
+		resyncRequests: make(chan struct{}, 1),
+		priorities:     make(map[string]int64),
+		scheduler:      NewScheduler(clock.System, logger),
+		logger:         logger,
+	}
+}
+
+// Pause pauses pushing headers to the host chain. Headers are still pulled
+// and queued while the relay is paused.
+func (c *Control) Pause() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.paused {
+		return
+	}
+
+	c.logger.Infof("pausing headers pushing")
+
+	c.paused = true
+	c.resumed = make(chan struct{})
+}
+
+// Resume resumes pushing headers to the host chain.
+func (c *Control) Resume() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.paused {
+		return
+	}
+
+	c.logger.Infof("resuming headers pushing")
+
+	c.paused = false
+	close(c.resumed)
+}
+
+// IsPaused returns whether pushing headers is paused.
+func (c *Control) IsPaused() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.paused
+}
+
+// TriggerPush makes the relay push the next headers batch immediately,
+// skipping the rest time and the push schedule deferral.
+func (c *Control) TriggerPush() {
+	c.logger.Infof("immediate push requested")
+
+	select {
+	case c.pushRequests <- struct{}{}:
+	default:
+		// Another request is already pending.
+	}
+}
+
+// TriggerResync makes the relay restart from the best header known by the
+// host chain.
+func (c *Control) TriggerResync() {
+	c.logger.Infof("resync of the best header requested")
+
+	select {
+	case c.resyncRequests <- struct{}{}:
+	default:
+		// Another request is already pending.
+	}
+}
+
+// waitWhilePaused blocks until pushing is resumed or the context is done.
+func (c *Control) waitWhilePaused(ctx context.Context) error {
+	c.mutex.Lock()
+	resumed := c.resumed
+	paused := c.paused
+	c.mutex.Unlock()
+
+	if paused {
+		c.logger.Infof("headers pushing is paused; waiting for resume")
+	}
+
+	defer Idle(ctx)()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Scheduler returns the scheduler timing the relay tasks. It returns nil for
+// a nil control so the relay tasks run in their default intervals.
+func (c *Control) Scheduler() *Scheduler {
+	if c == nil {
+		return nil
+	}
+
+	return c.scheduler
+}
+
+func (c *Control) pushRequested() <-chan struct{} {
+	return c.pushRequests
+}
+
+func (c *Control) resyncRequested() <-chan struct{} {
+	return c.resyncRequests
+}
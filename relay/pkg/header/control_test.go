@@ -0,0 +1,98 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestControl_PauseResume(t *testing.T) {
+	control := NewControl(nil)
+
+	if control.IsPaused() {
+		t.Fatal("control should not be paused initially")
+	}
+
+	control.Pause()
+
+	if !control.IsPaused() {
+		t.Fatal("control should be paused")
+	}
+
+	resumed := make(chan error, 1)
+	go func() {
+		resumed <- control.waitWhilePaused(context.Background())
+	}()
+
+	select {
+	case <-resumed:
+		t.Fatal("wait should block while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	control.Resume()
+
+	select {
+	case err := <-resumed:
+		if err != nil {
+			t.Fatalf("unexpected error: [%v]", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait should return once resumed")
+	}
+}
+
+func TestControl_WaitWhilePausedContextCancelled(t *testing.T) {
+	control := NewControl(nil)
+	control.Pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := control.waitWhilePaused(ctx); err == nil {
+		t.Fatal("expected error on context cancellation")
+	}
+}
+
+func TestControl_TriggerPushCoalescesRequests(t *testing.T) {
+	control := NewControl(nil)
+
+	control.TriggerPush()
+	control.TriggerPush()
+
+	select {
+	case <-control.pushRequested():
+	default:
+		t.Fatal("expected pending push request")
+	}
+
+	select {
+	case <-control.pushRequested():
+		t.Fatal("expected requests to be coalesced")
+	default:
+	}
+}
+
+func TestRelay_ResyncMonitoringLoopCancelsLoops(t *testing.T) {
+	relay := &Relay{
+		logger:  testLogger,
+		control: NewControl(nil),
+		errChan: make(chan error, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	relay.control.TriggerResync()
+	relay.resyncMonitoringLoop(ctx, cancel)
+
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("loops should be cancelled before the resync is raised")
+	}
+
+	if err := <-relay.errChan; err != ErrResyncRequested {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+}
@@ -0,0 +1,80 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// deadline.go file contains the logic which abandons pushes that could not
+// get mined before the push deadline. Transactions are ordered by their
+// nonces so a single transaction which can never get mined, e.g. because it
+// has been dropped by the node or is underpriced, blocks all transactions
+// submitted after it. Once a pushed batch is still not known by the host
+// chain after the deadline, all pending transactions are cancelled and the
+// journaled submissions are marked as abandoned. The relay is then restarted
+// from the best header known by the host chain so the affected batches are
+// rebuilt and resubmitted.
+
+// checkPushDeadline checks whether any of the pushed batches passed the push
+// deadline without becoming known by the host chain. If so, the pending
+// transactions are cancelled and an error is returned so the relay gets
+// restarted.
+func (r *Relay) checkPushDeadline(ctx context.Context) error {
+	if r.pushDeadline <= 0 {
+		return nil
+	}
+
+	for _, batch := range r.finalityTracker.pending() {
+		if r.timeSource().Since(batch.submittedAt) < r.pushDeadline {
+			// Subsequent batches were submitted later.
+			return nil
+		}
+
+		if _, err := r.hostChain.FindHeight(ctx, batch.lastDigest); err == nil {
+			continue
+		}
+
+		correlation.InjectedLogger(
+			correlation.WithID(ctx, batch.correlationID),
+			r.logger,
+		).Warnf(
+			"headers from [%v] to [%v] are not known by the host chain "+
+				"[%v] after submission; abandoning the push",
+			batch.firstHeight,
+			batch.lastHeight,
+			r.timeSource().Since(batch.submittedAt).Round(time.Second),
+		)
+
+		return r.abandonPushes(ctx)
+	}
+
+	return nil
+}
+
+// abandonPushes cancels all pending host chain transactions and marks the
+// journaled submissions as abandoned so they can be resubmitted right away.
+// If the cancellation fails, nil is returned and the deadline is checked
+// again in the next tick.
+func (r *Relay) abandonPushes(ctx context.Context) error {
+	cancelled, err := r.hostChain.CancelPendingTransactions(ctx)
+	if err != nil {
+		r.logger.Errorf("could not cancel pending transactions: [%v]", err)
+		return nil
+	}
+
+	abandoned, err := r.store.AbandonSubmittedEntries()
+	if err != nil {
+		return fmt.Errorf("could not abandon journaled batches: [%v]", err)
+	}
+
+	return fmt.Errorf(
+		"push deadline of [%v] passed; cancelled [%v] pending transactions "+
+			"and abandoned [%v] journaled batches which will be resubmitted",
+		r.pushDeadline,
+		cancelled,
+		abandoned,
+	)
+}
@@ -0,0 +1,132 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+func TestCheckPushDeadline(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = map[string]struct {
+		pushDeadline      time.Duration
+		submittedAgo      time.Duration
+		knownByHostChain  bool
+		expectAbandonment bool
+	}{
+		"push deadline disabled": {
+			submittedAgo:      time.Hour,
+			expectAbandonment: false,
+		},
+		"push deadline not passed": {
+			pushDeadline:      time.Hour,
+			submittedAgo:      time.Minute,
+			expectAbandonment: false,
+		},
+		"batch known by the host chain": {
+			pushDeadline:      time.Minute,
+			submittedAgo:      time.Hour,
+			knownByHostChain:  true,
+			expectAbandonment: false,
+		},
+		"push deadline passed": {
+			pushDeadline:      time.Minute,
+			submittedAgo:      time.Hour,
+			expectAbandonment: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			lc, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			localChain := lc.(*chainlocal.Chain)
+			localChain.SetPendingTransactions(2)
+			if test.knownByHostChain {
+				localChain.SetHeaderHeight(to32Bytes(2), 2)
+			}
+
+			id := store.NewBatchID(to32Bytes(1), to32Bytes(2))
+
+			relayStore := store.OpenMemory()
+			if err := relayStore.SaveJournalEntry(&store.JournalEntry{
+				ID:     id,
+				Status: store.BatchSubmitted,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+			relay := &Relay{
+				logger:          testLogger,
+				hostChain:       localChain,
+				store:           relayStore,
+				clock:           fakeClock,
+				finalityTracker: &finalityTracker{},
+				pushDeadline:    test.pushDeadline,
+			}
+
+			relay.trackPushedBatch(ctx, []*btc.Header{
+				{Hash: to32Bytes(1), Height: 1},
+				{Hash: to32Bytes(2), Height: 2},
+			})
+
+			fakeClock.Advance(test.submittedAgo)
+
+			err = relay.checkPushDeadline(ctx)
+
+			actualAbandonment := err != nil
+			if test.expectAbandonment != actualAbandonment {
+				t.Errorf(
+					"unexpected abandonment:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectAbandonment,
+					err,
+				)
+			}
+
+			expectedCancelled := 0
+			expectedStatus := store.BatchSubmitted
+			if test.expectAbandonment {
+				expectedCancelled = 2
+				expectedStatus = store.BatchAbandoned
+			}
+
+			if localChain.CancelledTransactions() != expectedCancelled {
+				t.Errorf(
+					"unexpected number of cancelled transactions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedCancelled,
+					localChain.CancelledTransactions(),
+				)
+			}
+
+			entry, err := relayStore.LoadJournalEntry(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if entry.Status != expectedStatus {
+				t.Errorf(
+					"unexpected journal entry status:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedStatus,
+					entry.Status,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package header
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// epoch.go file contains the notifications about difficulty epochs. The
+// retarget is the riskiest submission of each difficulty epoch as it is
+// validated against the previous epoch and a failed one stops the relay from
+// advancing. The relay notifies operators once the Bitcoin chain tip gets
+// close to the retarget and once the retarget is submitted, so they know
+// when to watch the relay closely.
+
+func (r *Relay) epochMonitoringLoop(ctx context.Context) {
+	r.logger.Infof("starting new difficulty epoch monitoring loop")
+	defer r.logger.Infof("stopping current difficulty epoch monitoring loop")
+
+	ticker := r.timeSource().NewTicker(epochMonitoringTick)
+	defer ticker.Stop()
+
+	for {
+		if err := r.checkEpochEnd(ctx); err != nil {
+			r.logger.Warnf("could not check difficulty epoch end: [%v]", err)
+		}
+
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkEpochEnd notifies the observer once per difficulty epoch when the
+// Bitcoin chain tip is within the configured number of blocks before the
+// retarget.
+func (r *Relay) checkEpochEnd(ctx context.Context) error {
+	chainHeight, err := r.btcChain.GetBlockCount(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get block count: [%v]", err)
+	}
+
+	nextEpoch := chainHeight/r.difficultyEpochDuration + 1
+	remainingBlocks := nextEpoch*r.difficultyEpochDuration - chainHeight
+
+	if remainingBlocks > r.epochEndNoticeBlocks ||
+		uint64(nextEpoch) == r.lastEpochEndNotice {
+		return nil
+	}
+
+	chainTip, err := r.btcChain.GetHeaderByHeight(ctx, chainHeight)
+	if err != nil {
+		return fmt.Errorf(
+			"could not get header by height [%v]: [%v]",
+			chainHeight,
+			err,
+		)
+	}
+
+	r.logger.Warnf(
+		"Bitcoin chain tip [%v] is [%v] blocks before the retarget to "+
+			"difficulty epoch [%v]",
+		chainHeight,
+		remainingBlocks,
+		nextEpoch,
+	)
+
+	r.lastEpochEndNotice = uint64(nextEpoch)
+	r.observer.NotifyEpochEndApproaching(uint64(nextEpoch), chainTip)
+
+	return nil
+}
+
+// notifyRetargetSubmitted notifies the observer about a retarget submitted
+// to the host chain. The given header is the first header of the new
+// difficulty epoch.
+func (r *Relay) notifyRetargetSubmitted(
+	ctx context.Context,
+	header *btc.Header,
+) {
+	epoch := uint64(header.Height / r.difficultyEpochDuration)
+
+	correlation.InjectedLogger(ctx, r.logger).Infof(
+		"submitted retarget to difficulty epoch [%v] starting with "+
+			"header [%v]",
+		epoch,
+		header.Height,
+	)
+
+	r.observer.NotifyRetargetSubmitted(epoch, header)
+}
@@ -0,0 +1,67 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// epochObserver records the difficulty epochs of the epoch end
+// notifications.
+type epochObserver struct {
+	mockObserver
+
+	epochEnds []uint64
+}
+
+func (eo *epochObserver) NotifyEpochEndApproaching(
+	epoch uint64,
+	header *btc.Header,
+) {
+	eo.epochEnds = append(eo.epochEnds, epoch)
+}
+
+func TestCheckEpochEnd(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	observer := &epochObserver{}
+
+	relay := &Relay{
+		logger:                  testLogger,
+		btcChain:                btcChain,
+		difficultyEpochDuration: testDifficultyEpochDuration,
+		epochEndNoticeBlocks:    2,
+		observer:                observer,
+	}
+
+	// With the epoch duration of eight blocks, operators are notified once
+	// the chain tip reaches headers 6 and 14.
+	for height := 0; height <= 16; height++ {
+		btcChain.AppendHeader(&btc.Header{
+			Hash:   to32Bytes(height),
+			Height: int64(height),
+		})
+
+		if err := relay.checkEpochEnd(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedEpochEnds := []uint64{1, 2}
+	if !reflect.DeepEqual(expectedEpochEnds, observer.epochEnds) {
+		t.Errorf(
+			"unexpected epoch end notifications:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedEpochEnds,
+			observer.epochEnds,
+		)
+	}
+}
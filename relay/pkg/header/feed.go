@@ -0,0 +1,214 @@
+package header
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// Size of the events buffer of a single feed subscription.
+const subscriptionBufferSize = 100
+
+// EventType is the type of an event emitted by the headers feed.
+type EventType string
+
+const (
+	// EventHeaderPulled is emitted for every header pulled from the
+	// Bitcoin chain.
+	EventHeaderPulled EventType = "pulled"
+
+	// EventHeaderPushed is emitted for every header pushed to the
+	// host chain, once the header is known by the host chain.
+	EventHeaderPushed EventType = "pushed"
+
+	// EventRetargetSubmitted is emitted once a retarget to a new difficulty
+	// epoch is submitted to the host chain. The header is the first header
+	// of the new epoch.
+	EventRetargetSubmitted EventType = "retarget"
+
+	// EventEpochEndApproaching is emitted once per difficulty epoch when the
+	// Bitcoin chain tip gets close to the retarget. The header is the chain
+	// tip.
+	EventEpochEndApproaching EventType = "epoch-end"
+
+	// EventReorg is emitted once the relay records a reorg. The header is
+	// the new best header.
+	EventReorg EventType = "reorg"
+)
+
+// Event is a single event emitted by the headers feed.
+type Event struct {
+	Type   EventType
+	Header *btc.Header
+	// Epoch is the difficulty epoch the retarget switches to. It is set only
+	// for retarget and epoch end events.
+	Epoch uint64
+	// Reorg is the recorded reorg. It is set only for reorg events.
+	Reorg *store.Reorg
+}
+
+// Feed broadcasts headers pulled and pushed by the relay to subscribers.
+// The feed implements RelayObserver so it can be notified by the relay
+// directly. The same feed instance should be passed to all subsequent relay
+// instances so that subscriptions survive relay restarts.
+type Feed struct {
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]bool
+	logger        logs.Logger
+}
+
+// NewFeed creates a new headers feed. Messages are logged to the given logger
+// or, if it is nil, to the default logger.
+func NewFeed(logger logs.Logger) *Feed {
+	return &Feed{
+		subscriptions: make(map[*Subscription]bool),
+		logger:        logs.OrDefault(logger, loggerName),
+	}
+}
+
+// Subscription represents a subscription to the headers feed.
+type Subscription struct {
+	feed   *Feed
+	events chan *Event
+}
+
+// Subscribe creates a new subscription to the headers feed. The subscriber
+// should consume events promptly; if the subscription buffer gets full, the
+// subscription is cancelled and its events channel closed, so the subscriber
+// knows it missed some events.
+func (f *Feed) Subscribe() *Subscription {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	subscription := &Subscription{
+		feed:   f,
+		events: make(chan *Event, subscriptionBufferSize),
+	}
+
+	f.subscriptions[subscription] = true
+
+	return subscription
+}
+
+// Events returns the channel delivering subscription events. The channel
+// is closed once the subscription is cancelled.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Unsubscribe cancels the subscription.
+func (s *Subscription) Unsubscribe() {
+	s.feed.mutex.Lock()
+	defer s.feed.mutex.Unlock()
+
+	s.feed.cancel(s)
+}
+
+// cancel must be called with the feed mutex held.
+func (f *Feed) cancel(subscription *Subscription) {
+	if !f.subscriptions[subscription] {
+		return
+	}
+
+	delete(f.subscriptions, subscription)
+	close(subscription.events)
+}
+
+func (f *Feed) emit(eventType EventType, headers ...*btc.Header) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, header := range headers {
+		f.broadcast(&Event{Type: eventType, Header: header})
+	}
+}
+
+// broadcast must be called with the feed mutex held.
+func (f *Feed) broadcast(event *Event) {
+	for subscription := range f.subscriptions {
+		select {
+		case subscription.events <- event:
+		default:
+			f.logger.Warnf(
+				"cancelling headers feed subscription as it " +
+					"does not keep up with events",
+			)
+			f.cancel(subscription)
+		}
+	}
+}
+
+// NotifyHeaderPulled notifies about new header pulled from the
+// Bitcoin chain.
+func (f *Feed) NotifyHeaderPulled(header *btc.Header) {
+	f.emit(EventHeaderPulled, header)
+}
+
+// NotifyHeadersPushed notifies about new headers pushed to the host chain.
+// Submitted headers may still be rejected or dropped, so they are broadcast
+// only once confirmed.
+func (f *Feed) NotifyHeadersPushed(headers []*btc.Header) {
+	// no-op
+}
+
+// NotifyHeadersConfirmed notifies about pushed headers known by the host
+// chain.
+func (f *Feed) NotifyHeadersConfirmed(
+	headers []*btc.Header,
+	confirmedAt time.Time,
+) {
+	f.emit(EventHeaderPushed, headers...)
+}
+
+// NotifyRelayLag notifies about the current relay lag. Relay lag is not
+// broadcast by the feed.
+func (f *Feed) NotifyRelayLag(lag int64) {
+	// no-op
+}
+
+// NotifyForks notifies about the competing Bitcoin forks. Forks are not
+// broadcast by the feed.
+func (f *Feed) NotifyForks(forks int, length int64) {
+	// no-op
+}
+
+// NotifyRetargetSubmitted notifies about a retarget to the given difficulty
+// epoch submitted to the host chain.
+func (f *Feed) NotifyRetargetSubmitted(epoch uint64, header *btc.Header) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.broadcast(&Event{
+		Type:   EventRetargetSubmitted,
+		Header: header,
+		Epoch:  epoch,
+	})
+}
+
+// NotifyEpochEndApproaching notifies that the Bitcoin chain tip gets close
+// to the retarget to the given difficulty epoch.
+func (f *Feed) NotifyEpochEndApproaching(epoch uint64, header *btc.Header) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.broadcast(&Event{
+		Type:   EventEpochEndApproaching,
+		Header: header,
+		Epoch:  epoch,
+	})
+}
+
+// NotifyReorg notifies about the given reorg recorded by the relay.
+func (f *Feed) NotifyReorg(header *btc.Header, reorg *store.Reorg) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.broadcast(&Event{
+		Type:   EventReorg,
+		Header: header,
+		Reorg:  reorg,
+	})
+}
@@ -0,0 +1,77 @@
+package header
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestFeed_Emit(t *testing.T) {
+	feed := NewFeed(nil)
+
+	subscription := feed.Subscribe()
+	defer subscription.Unsubscribe()
+
+	feed.NotifyHeaderPulled(&btc.Header{Height: 1})
+	// Submitted headers are not emitted until confirmed.
+	feed.NotifyHeadersPushed([]*btc.Header{{Height: 1}, {Height: 2}})
+	feed.NotifyHeadersConfirmed(
+		[]*btc.Header{{Height: 1}, {Height: 2}},
+		time.Now(),
+	)
+
+	expectedEvents := []struct {
+		eventType EventType
+		height    int64
+	}{
+		{EventHeaderPulled, 1},
+		{EventHeaderPushed, 1},
+		{EventHeaderPushed, 2},
+	}
+
+	for _, expected := range expectedEvents {
+		event := <-subscription.Events()
+
+		if expected.eventType != event.Type ||
+			expected.height != event.Header.Height {
+			t.Errorf(
+				"unexpected event:\n"+
+					"expected: [%v %v]\n"+
+					"actual:   [%v %v]\n",
+				expected.eventType,
+				expected.height,
+				event.Type,
+				event.Header.Height,
+			)
+		}
+	}
+}
+
+func TestFeed_CancelsSlowSubscription(t *testing.T) {
+	feed := NewFeed(nil)
+
+	subscription := feed.Subscribe()
+
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		feed.NotifyHeaderPulled(&btc.Header{Height: int64(i)})
+	}
+
+	received := 0
+	for range subscription.Events() {
+		received++
+	}
+
+	if received != subscriptionBufferSize {
+		t.Errorf(
+			"unexpected number of received events:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			subscriptionBufferSize,
+			received,
+		)
+	}
+
+	// Unsubscribing a cancelled subscription should be a no-op.
+	subscription.Unsubscribe()
+}
@@ -0,0 +1,247 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// finality.go file contains the logic which tracks pushed headers batches
+// until they reach the required finality depth on the host chain. Only
+// then the persistent checkpoint is advanced. If a batch is not known by
+// the host chain once the finality depth is reached, it was most probably
+// dropped by a host chain reorg. In that case, the relay raises an error
+// and gets restarted which makes it resubmit the affected headers.
+
+// pushedBatch represents a headers batch which has been submitted to the host
+// chain but has not reached the finality depth yet.
+type pushedBatch struct {
+	correlationID   correlation.ID
+	firstHeight     int64
+	lastHeight      int64
+	lastDigest      btc.Digest
+	submissionBlock uint64
+	submittedAt     time.Time
+	headers         []*btc.Header
+	// confirmed determines whether the batch is already known by the host
+	// chain at the latest block.
+	confirmed bool
+}
+
+// finalityTracker keeps track of the pushed batches awaiting finality.
+type finalityTracker struct {
+	mutex   sync.Mutex
+	batches []*pushedBatch
+}
+
+func (ft *finalityTracker) add(batch *pushedBatch) {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+
+	ft.batches = append(ft.batches, batch)
+}
+
+func (ft *finalityTracker) pending() []*pushedBatch {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+
+	return append([]*pushedBatch{}, ft.batches...)
+}
+
+func (ft *finalityTracker) remove(batch *pushedBatch) {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+
+	for i, pending := range ft.batches {
+		if pending == batch {
+			ft.batches = append(ft.batches[:i], ft.batches[i+1:]...)
+			return
+		}
+	}
+}
+
+// trackPushedBatch registers the given headers batch as submitted to the host
+// chain so its finality can be tracked.
+func (r *Relay) trackPushedBatch(ctx context.Context, headers []*btc.Header) {
+	submissionBlock, err := r.hostChain.CurrentBlock(ctx)
+	if err != nil {
+		correlation.InjectedLogger(ctx, r.logger).Warnf(
+			"could not get current host chain block; "+
+				"finality of %v will not be tracked: [%v]",
+			headersSummary(headers),
+			err,
+		)
+		return
+	}
+
+	r.finalityTracker.add(&pushedBatch{
+		correlationID:   correlation.FromContext(ctx),
+		firstHeight:     headers[0].Height,
+		lastHeight:      headers[len(headers)-1].Height,
+		lastDigest:      headers[len(headers)-1].Hash,
+		submissionBlock: submissionBlock,
+		submittedAt:     r.timeSource().Now(),
+		headers:         headers,
+	})
+}
+
+func (r *Relay) finalityMonitoringLoop(ctx context.Context) {
+	r.logger.Infof("starting new finality monitoring loop")
+	defer r.logger.Infof("stopping current finality monitoring loop")
+
+	ticker := r.timeSource().NewTicker(finalityMonitoringTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			if err := r.checkPushDeadline(ctx); err != nil {
+				r.raiseError(err)
+				return
+			}
+
+			if err := r.checkPushedBatchesFinality(ctx); err != nil {
+				r.raiseError(fmt.Errorf("finality check failed: [%v]", err))
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkPushedBatchesFinality checks all pending batches against the host
+// chain state as of the finalized block. Batches known by the host chain at
+// that block advance the checkpoint. An error is returned if a batch is
+// still unknown to the host chain after the finality timeout.
+func (r *Relay) checkPushedBatchesFinality(ctx context.Context) error {
+	currentBlock, err := r.hostChain.CurrentBlock(ctx)
+	if err != nil {
+		r.logger.Warnf("could not get current host chain block: [%v]", err)
+		return nil
+	}
+
+	r.checkPushedBatchesConfirmation(ctx, currentBlock)
+
+	if currentBlock < r.finalityDepth {
+		return nil
+	}
+
+	finalizedBlock := currentBlock - r.finalityDepth
+
+	for _, batch := range r.finalityTracker.pending() {
+		batchLogger := correlation.InjectedLogger(
+			correlation.WithID(ctx, batch.correlationID),
+			r.logger,
+		)
+
+		if finalizedBlock < batch.submissionBlock {
+			// Not enough confirmations yet; this also holds for all
+			// subsequent batches.
+			return nil
+		}
+
+		_, err := r.hostChain.FindHeightAtBlock(
+			ctx,
+			batch.lastDigest,
+			finalizedBlock,
+		)
+		if err != nil {
+			if finalizedBlock-batch.submissionBlock < r.finalityDepth {
+				// The transaction may not be mined yet; give it more time.
+				batchLogger.Debugf(
+					"batch ending at header [%v] is not final yet: [%v]",
+					batch.lastHeight,
+					err,
+				)
+				return nil
+			}
+
+			return fmt.Errorf(
+				"headers from [%v] to [%v] submitted at block [%v] are "+
+					"not known by the host chain at finalized block [%v]; "+
+					"they were probably dropped by a reorg: [%v]",
+				batch.firstHeight,
+				batch.lastHeight,
+				batch.submissionBlock,
+				finalizedBlock,
+				err,
+			)
+		}
+
+		batchLogger.Infof(
+			"headers from [%v] to [%v] reached finality at block [%v]",
+			batch.firstHeight,
+			batch.lastHeight,
+			finalizedBlock,
+		)
+
+		if err := r.store.SaveCheckpoint(&store.Checkpoint{
+			Height:    batch.lastHeight,
+			Digest:    batch.lastDigest,
+			HostBlock: finalizedBlock,
+		}); err != nil {
+			batchLogger.Errorf("could not save checkpoint: [%v]", err)
+		}
+
+		r.finalityTracker.remove(batch)
+	}
+
+	return nil
+}
+
+// checkPushedBatchesConfirmation confirms the journal entries of pending
+// batches which got known by the host chain at the given latest block since
+// the last check and notifies the observer about them. The confirmation time is therefore accurate up to the finality
+// monitoring tick.
+func (r *Relay) checkPushedBatchesConfirmation(
+	ctx context.Context,
+	currentBlock uint64,
+) {
+	for _, batch := range r.finalityTracker.pending() {
+		if batch.confirmed {
+			continue
+		}
+
+		if _, err := r.hostChain.FindHeightAtBlock(
+			ctx,
+			batch.lastDigest,
+			currentBlock,
+		); err != nil {
+			// Batches are mined in the submission order so the subsequent
+			// batches are not known either.
+			return
+		}
+
+		batch.confirmed = true
+
+		if err := r.confirmJournaledBatch(batch.headers); err != nil {
+			r.logger.Warnf(
+				"could not confirm journaled batch with %v: [%v]",
+				headersSummary(batch.headers),
+				err,
+			)
+		}
+
+		r.observer.NotifyHeadersConfirmed(batch.headers, r.timeSource().Now())
+	}
+}
+
+// raiseError passes the error to the error channel unless another error is
+// already waiting there. Loops exit on the first error anyway, so subsequent
+// errors would only block the caller.
+func (r *Relay) raiseError(err error) {
+	select {
+	case r.errChan <- err:
+	default:
+		r.logger.Errorf(
+			"dropping relay error as another one is pending: [%v]",
+			err,
+		)
+	}
+}
@@ -0,0 +1,283 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+func TestCheckPushedBatchesFinality(t *testing.T) {
+	ctx := context.Background()
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	relay := &Relay{
+		logger:          testLogger,
+		hostChain:       localChain,
+		store:           store.OpenMemory(),
+		observer:        &mockObserver{},
+		finalityDepth:   5,
+		finalityTracker: &finalityTracker{},
+	}
+
+	localChain.SetCurrentBlock(100)
+
+	relay.trackPushedBatch(ctx, []*btc.Header{
+		{Hash: to32Bytes(1), Height: 1},
+		{Hash: to32Bytes(2), Height: 2},
+	})
+
+	localChain.SetHeaderHeight(to32Bytes(2), 2)
+
+	// The finality depth is not reached yet so the checkpoint should not be
+	// stored.
+	localChain.SetCurrentBlock(104)
+
+	if err := relay.checkPushedBatchesFinality(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := relay.store.LoadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if checkpoint != nil {
+		t.Fatalf("unexpected checkpoint: [%+v]", checkpoint)
+	}
+
+	// The finality depth is reached so the checkpoint should be advanced.
+	localChain.SetCurrentBlock(105)
+
+	if err := relay.checkPushedBatchesFinality(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err = relay.store.LoadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCheckpoint := &store.Checkpoint{
+		Height:    2,
+		Digest:    to32Bytes(2),
+		HostBlock: 100,
+	}
+	if checkpoint == nil || *expectedCheckpoint != *checkpoint {
+		t.Errorf(
+			"unexpected checkpoint:\n"+
+				"expected: [%+v]\n"+
+				"actual:   [%+v]\n",
+			expectedCheckpoint,
+			checkpoint,
+		)
+	}
+
+	if pending := len(relay.finalityTracker.pending()); pending != 0 {
+		t.Errorf("unexpected number of pending batches: [%v]", pending)
+	}
+}
+
+func TestCheckPushedBatchesFinality_DroppedBatch(t *testing.T) {
+	ctx := context.Background()
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	relay := &Relay{
+		logger:          testLogger,
+		hostChain:       localChain,
+		store:           store.OpenMemory(),
+		observer:        &mockObserver{},
+		finalityDepth:   5,
+		finalityTracker: &finalityTracker{},
+	}
+
+	localChain.SetCurrentBlock(100)
+
+	relay.trackPushedBatch(ctx, []*btc.Header{
+		{Hash: to32Bytes(1), Height: 1},
+	})
+
+	// The header is never known by the host chain. Once the batch had the
+	// finality depth worth of blocks to get mined, it should be considered
+	// dropped.
+	localChain.SetCurrentBlock(105)
+
+	if err := relay.checkPushedBatchesFinality(ctx); err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+
+	localChain.SetCurrentBlock(110)
+
+	if err := relay.checkPushedBatchesFinality(ctx); err == nil {
+		t.Fatal("expected error for dropped batch")
+	}
+}
+
+// confirmationObserver records the heights of the headers the relay notifies
+// as confirmed.
+type confirmationObserver struct {
+	mockObserver
+
+	confirmed []int64
+}
+
+func (co *confirmationObserver) NotifyHeadersConfirmed(
+	headers []*btc.Header,
+	confirmedAt time.Time,
+) {
+	for _, header := range headers {
+		co.confirmed = append(co.confirmed, header.Height)
+	}
+}
+
+func TestCheckPushedBatchesFinality_Confirmation(t *testing.T) {
+	ctx := context.Background()
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	observer := &confirmationObserver{}
+	relay := &Relay{
+		logger:          testLogger,
+		hostChain:       localChain,
+		store:           store.OpenMemory(),
+		observer:        observer,
+		finalityDepth:   5,
+		finalityTracker: &finalityTracker{},
+	}
+
+	localChain.SetCurrentBlock(100)
+
+	relay.trackPushedBatch(ctx, []*btc.Header{
+		{Hash: to32Bytes(1), Height: 1},
+		{Hash: to32Bytes(2), Height: 2},
+	})
+	relay.trackPushedBatch(ctx, []*btc.Header{
+		{Hash: to32Bytes(3), Height: 3},
+	})
+
+	// Nothing is mined yet.
+	if err := relay.checkPushedBatchesFinality(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(observer.confirmed) != 0 {
+		t.Fatalf("unexpected confirmed headers: [%v]", observer.confirmed)
+	}
+
+	// The first batch is mined but not final yet. It should be confirmed
+	// exactly once.
+	localChain.SetHeaderHeight(to32Bytes(2), 2)
+	localChain.SetCurrentBlock(101)
+
+	for i := 0; i < 2; i++ {
+		if err := relay.checkPushedBatchesFinality(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedConfirmed := []int64{1, 2}
+	if !reflect.DeepEqual(expectedConfirmed, observer.confirmed) {
+		t.Errorf(
+			"unexpected confirmed headers:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedConfirmed,
+			observer.confirmed,
+		)
+	}
+}
+
+func TestVerifyCheckpoint(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = map[string]struct {
+		checkpointHeight int64
+		knownByHostChain bool
+		expectDiscarded  bool
+	}{
+		"checkpoint known by host chain": {
+			checkpointHeight: 8,
+			knownByHostChain: true,
+			expectDiscarded:  false,
+		},
+		"checkpoint above best header": {
+			checkpointHeight: 12,
+			knownByHostChain: true,
+			expectDiscarded:  true,
+		},
+		"checkpoint not known by host chain": {
+			checkpointHeight: 8,
+			knownByHostChain: false,
+			expectDiscarded:  true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			lc, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			localChain := lc.(*chainlocal.Chain)
+
+			relay := &Relay{
+				logger:    testLogger,
+				hostChain: localChain,
+				store:     store.OpenMemory(),
+			}
+
+			if test.knownByHostChain {
+				localChain.SetHeaderHeight(
+					to32Bytes(int(test.checkpointHeight)),
+					test.checkpointHeight,
+				)
+			}
+
+			if err := relay.store.SaveCheckpoint(&store.Checkpoint{
+				Height: test.checkpointHeight,
+				Digest: to32Bytes(int(test.checkpointHeight)),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			relay.verifyCheckpoint(ctx, &btc.Header{Height: 10})
+
+			checkpoint, err := relay.store.LoadCheckpoint()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if discarded := checkpoint == nil; discarded != test.expectDiscarded {
+				t.Errorf(
+					"unexpected checkpoint discarding:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectDiscarded,
+					discarded,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package header
+
+import (
+	"context"
+	"sync"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// fork.go file contains the monitoring of competing Bitcoin forks. The chain
+// tips known by the Bitcoin node are periodically checked for valid forks
+// branching off the active chain near its tip. While such a fork exists, the
+// active chain above the fork point is contested and headers from that range
+// are held back, as pushing them would likely be followed by a reorg once
+// the competing fork wins. Held headers are pulled again once the fork is
+// resolved, so the headers of the winning chain are relayed.
+
+// forkMonitor tracks the competing forks near the Bitcoin chain tip.
+type forkMonitor struct {
+	depth int64
+
+	mutex sync.RWMutex
+	forks int
+	// length is the branch length of the longest competing fork.
+	length int64
+	// contestedFrom is the lowest height of the active chain contested by
+	// a competing fork. It is zero if no height is contested.
+	contestedFrom int64
+}
+
+// update recomputes the competing forks from the given chain tips. Forks
+// whose tip is more than the monitoring depth below the active chain tip are
+// considered resolved.
+func (fm *forkMonitor) update(tips []*btc.ChainTip) {
+	var activeHeight int64
+	for _, tip := range tips {
+		if tip.Status == btc.ChainTipActive {
+			activeHeight = tip.Height
+		}
+	}
+
+	forks := 0
+	var length, contestedFrom int64
+	for _, tip := range tips {
+		if !tip.IsCompeting() || tip.Height+fm.depth < activeHeight {
+			continue
+		}
+
+		forks++
+
+		if tip.BranchLength > length {
+			length = tip.BranchLength
+		}
+
+		forkPoint := tip.Height - tip.BranchLength + 1
+		if contestedFrom == 0 || forkPoint < contestedFrom {
+			contestedFrom = forkPoint
+		}
+	}
+
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+
+	fm.forks = forks
+	fm.length = length
+	fm.contestedFrom = contestedFrom
+}
+
+// contests returns whether the given height of the active chain is contested
+// by a competing fork.
+func (fm *forkMonitor) contests(height int64) bool {
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+
+	return fm.contestedFrom > 0 && height >= fm.contestedFrom
+}
+
+func (fm *forkMonitor) state() (int, int64) {
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+
+	return fm.forks, fm.length
+}
+
+func (r *Relay) forkMonitoringLoop(ctx context.Context) {
+	r.logger.Infof("starting new fork monitoring loop")
+	defer r.logger.Infof("stopping current fork monitoring loop")
+
+	ticker := r.timeSource().NewTicker(forkMonitoringTick)
+	defer ticker.Stop()
+
+	for {
+		r.checkForks(ctx)
+
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkForks updates the competing forks using the current chain tips of the
+// Bitcoin node.
+func (r *Relay) checkForks(ctx context.Context) {
+	tips, err := r.btcChain.GetChainTips(ctx)
+	if err != nil {
+		r.logger.Warnf("could not get Bitcoin chain tips: [%v]", err)
+		return
+	}
+
+	previousForks, _ := r.forks.state()
+
+	r.forks.update(tips)
+
+	forks, length := r.forks.state()
+	r.observer.NotifyForks(forks, length)
+
+	if forks > 0 {
+		r.logger.Warnf(
+			"detected [%v] competing Bitcoin forks near the tip; "+
+				"the longest one has [%v] blocks",
+			forks,
+			length,
+		)
+	} else if previousForks > 0 {
+		r.logger.Infof("competing Bitcoin forks have been resolved")
+	}
+}
+
+// forkGateStage holds back headers contested by a competing fork until the
+// fork is resolved. Headers which are no longer on the active chain once the
+// fork is resolved are dropped, so the headers of the winning chain are
+// pulled instead.
+func (r *Relay) forkGateStage(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	if !r.forks.contests(header.Height) {
+		return true, nil
+	}
+
+	r.logger.Infof(
+		"holding back header [%v] contested by a competing Bitcoin fork",
+		header.Height,
+	)
+
+	resume := Idle(ctx)
+	defer resume()
+
+	for r.forks.contests(header.Height) {
+		select {
+		case <-r.timeSource().After(forkMonitoringTick):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	activeHeader, err := r.btcChain.GetHeaderByHeight(ctx, header.Height)
+	if err != nil {
+		return false, err
+	}
+
+	if activeHeader.Hash != header.Hash {
+		r.logger.Infof(
+			"held header [%v] lost to a competing Bitcoin fork; "+
+				"pulling the winning header",
+			header.Height,
+		)
+		return false, nil
+	}
+
+	r.logger.Infof("releasing held header [%v]", header.Height)
+
+	return true, nil
+}
@@ -0,0 +1,214 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+func TestForkMonitor_Update(t *testing.T) {
+	var tests = map[string]struct {
+		tips                  []*btc.ChainTip
+		expectedForks         int
+		expectedLength        int64
+		expectedContestedFrom int64
+	}{
+		"active tip only": {
+			tips: []*btc.ChainTip{
+				{Height: 100, Status: btc.ChainTipActive},
+			},
+			expectedForks:         0,
+			expectedLength:        0,
+			expectedContestedFrom: 0,
+		},
+		"competing fork near the tip": {
+			tips: []*btc.ChainTip{
+				{Height: 100, Status: btc.ChainTipActive},
+				{Height: 100, BranchLength: 2, Status: btc.ChainTipValidFork},
+			},
+			expectedForks:         1,
+			expectedLength:        2,
+			expectedContestedFrom: 99,
+		},
+		"multiple competing forks": {
+			tips: []*btc.ChainTip{
+				{Height: 100, Status: btc.ChainTipActive},
+				{Height: 99, BranchLength: 1, Status: btc.ChainTipHeadersOnly},
+				{Height: 98, BranchLength: 3, Status: btc.ChainTipValidHeaders},
+			},
+			expectedForks:         2,
+			expectedLength:        3,
+			expectedContestedFrom: 96,
+		},
+		"stale fork below the monitoring depth": {
+			tips: []*btc.ChainTip{
+				{Height: 100, Status: btc.ChainTipActive},
+				{Height: 93, BranchLength: 1, Status: btc.ChainTipValidFork},
+			},
+			expectedForks:         0,
+			expectedLength:        0,
+			expectedContestedFrom: 0,
+		},
+		"invalid fork": {
+			tips: []*btc.ChainTip{
+				{Height: 100, Status: btc.ChainTipActive},
+				{Height: 101, BranchLength: 2, Status: btc.ChainTipInvalid},
+			},
+			expectedForks:         0,
+			expectedLength:        0,
+			expectedContestedFrom: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			monitor := &forkMonitor{depth: 6}
+			monitor.update(test.tips)
+
+			forks, length := monitor.state()
+			if test.expectedForks != forks || test.expectedLength != length {
+				t.Errorf(
+					"unexpected forks:\n"+
+						"expected: [%v forks, length %v]\n"+
+						"actual:   [%v forks, length %v]\n",
+					test.expectedForks,
+					test.expectedLength,
+					forks,
+					length,
+				)
+			}
+
+			if test.expectedContestedFrom != monitor.contestedFrom {
+				t.Errorf(
+					"unexpected contested height:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedContestedFrom,
+					monitor.contestedFrom,
+				)
+			}
+		})
+	}
+}
+
+func TestForkGateStage(t *testing.T) {
+	chainHeaders := linkedHeaders(1, 10)
+
+	forkedHeader := &btc.Header{
+		Height:   10,
+		Hash:     to32Bytes(110),
+		PrevHash: to32Bytes(9),
+	}
+
+	var tests = map[string]struct {
+		header         *btc.Header
+		expectedPassed bool
+	}{
+		"header won the fork": {
+			header:         chainHeaders[9],
+			expectedPassed: true,
+		},
+		"header lost the fork": {
+			header:         forkedHeader,
+			expectedPassed: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			btcChain := bc.(*btc.LocalChain)
+			btcChain.SetHeaders(chainHeaders)
+
+			fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+			relay := &Relay{
+				logger:   testLogger,
+				btcChain: btcChain,
+				clock:    fakeClock,
+				forks:    &forkMonitor{depth: 6},
+			}
+
+			relay.forks.update([]*btc.ChainTip{
+				{Height: 10, Status: btc.ChainTipActive},
+				{Height: 10, BranchLength: 1, Status: btc.ChainTipValidFork},
+			})
+
+			type stageResult struct {
+				passed bool
+				err    error
+			}
+
+			done := make(chan stageResult, 1)
+			go func() {
+				passed, err := relay.forkGateStage(
+					context.Background(),
+					test.header,
+				)
+				done <- stageResult{passed, err}
+			}()
+
+			fakeClock.BlockUntil(1)
+
+			select {
+			case <-done:
+				t.Fatal("contested header should be held back")
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			// The competing fork falls behind the active chain.
+			relay.forks.update([]*btc.ChainTip{
+				{Height: 17, Status: btc.ChainTipActive},
+				{Height: 10, BranchLength: 1, Status: btc.ChainTipValidFork},
+			})
+			fakeClock.Advance(forkMonitoringTick)
+
+			select {
+			case result := <-done:
+				if result.err != nil {
+					t.Fatal(result.err)
+				}
+
+				if test.expectedPassed != result.passed {
+					t.Errorf(
+						"unexpected stage result:\n"+
+							"expected: [%v]\n"+
+							"actual:   [%v]\n",
+						test.expectedPassed,
+						result.passed,
+					)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("header should be released once the fork is resolved")
+			}
+		})
+	}
+}
+
+func TestForkGateStage_NotContested(t *testing.T) {
+	relay := &Relay{logger: testLogger, forks: &forkMonitor{depth: 6}}
+
+	relay.forks.update([]*btc.ChainTip{
+		{Height: 10, Status: btc.ChainTipActive},
+		{Height: 10, BranchLength: 1, Status: btc.ChainTipValidFork},
+	})
+
+	passed, err := relay.forkGateStage(
+		context.Background(),
+		&btc.Header{Height: 9},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !passed {
+		t.Errorf("header below the fork point should pass")
+	}
+}
@@ -0,0 +1,501 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// TestPushingGolden pushes real mainnet headers sequences the same way the
+// pushing loop does and compares the formed batches and the relay contract
+// calls with the golden files. Headers in the golden files are identified by
+// the hashes of the submitted header bytes, in the block explorer byte order,
+// so any change in the batching or in the byte order of submitted headers
+// shows up as a golden file mismatch.
+func TestPushingGolden(t *testing.T) {
+	headers := loadMainnetHeaders(t)
+
+	var tests = map[string]struct {
+		anchorHeight int64
+		tipHeight    int64
+		goldenFile   string
+	}{
+		"batch spanning retarget": {
+			anchorHeight: 556404,
+			tipHeight:    556427,
+			goldenFile:   "push-spanning-retarget.golden.json",
+		},
+		"batch starting with retarget": {
+			anchorHeight: 558421,
+			tipHeight:    558443,
+			goldenFile:   "push-starting-with-retarget.golden.json",
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			btcChain := bc.(*btc.LocalChain)
+			btcChain.SetHeaders(headers)
+
+			lc, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hostChain := &recordingHostChain{
+				Chain:    lc.(*chainlocal.Chain),
+				btcChain: btcChain,
+			}
+
+			fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+			relay := &Relay{
+				logger:                  testLogger,
+				btcChain:                btcChain,
+				hostChain:               hostChain,
+				store:                   store.OpenMemory(),
+				clock:                   fakeClock,
+				difficultyEpochDuration: btcDifficultyEpochDuration,
+				headersQueue:            make(chan *btc.Header, headersQueueSize),
+				observer:                &mockObserver{},
+			}
+
+			pushed := 0
+			for _, header := range headers {
+				if header.Height == test.anchorHeight {
+					hostChain.SetBestKnownDigest(header.Hash)
+				}
+
+				if header.Height > test.anchorHeight &&
+					header.Height <= test.tipHeight {
+					relay.headersQueue <- header
+					pushed++
+				}
+			}
+
+			actual := &goldenPush{}
+
+			for pushed > 0 {
+				batch := takeBatch(t, relay, fakeClock)
+
+				heights := make([]int64, len(batch))
+				for i, header := range batch {
+					heights[i] = header.Height
+				}
+				actual.Batches = append(actual.Batches, heights)
+
+				if err := relay.pushHeadersToHostChain(
+					context.Background(),
+					batch,
+				); err != nil {
+					t.Fatal(err)
+				}
+
+				pushed -= len(batch)
+			}
+
+			actual.Calls = hostChain.calls
+
+			compareGolden(t, test.goldenFile, actual)
+		})
+	}
+}
+
+// TestPushingSimulatedRelay pushes real mainnet headers sequences the same
+// way the pushing loop does to the simulated relay contract, which rejects
+// any submission the deployed contract would reject.
+func TestPushingSimulatedRelay(t *testing.T) {
+	headers := loadMainnetHeaders(t)
+
+	byHeight := make(map[int64]*btc.Header)
+	for _, header := range headers {
+		byHeight[header.Height] = header
+	}
+
+	var tests = map[string]struct {
+		anchorHeight      int64
+		periodStartHeight int64
+		tipHeight         int64
+	}{
+		"batch spanning retarget": {
+			anchorHeight:      556404,
+			periodStartHeight: 554400,
+			tipHeight:         556427,
+		},
+		"batch starting with retarget": {
+			anchorHeight:      558421,
+			periodStartHeight: 556416,
+			tipHeight:         558443,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			btcChain := bc.(*btc.LocalChain)
+			btcChain.SetHeaders(headers)
+
+			lc, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hostChain := lc.(*chainlocal.Chain)
+			if err := hostChain.SimulateRelay(
+				byHeight[test.anchorHeight].Raw,
+				test.anchorHeight,
+				byHeight[test.periodStartHeight].Hash,
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+			relay := &Relay{
+				logger:                  testLogger,
+				btcChain:                btcChain,
+				hostChain:               hostChain,
+				store:                   store.OpenMemory(),
+				clock:                   fakeClock,
+				difficultyEpochDuration: btcDifficultyEpochDuration,
+				headersQueue:            make(chan *btc.Header, headersQueueSize),
+				observer:                &mockObserver{},
+			}
+
+			pushed := 0
+			for _, header := range headers {
+				if header.Height > test.anchorHeight &&
+					header.Height <= test.tipHeight {
+					relay.headersQueue <- header
+					pushed++
+				}
+			}
+
+			for pushed > 0 {
+				batch := takeBatch(t, relay, fakeClock)
+
+				if err := relay.pushHeadersToHostChain(
+					context.Background(),
+					batch,
+				); err != nil {
+					t.Fatal(err)
+				}
+
+				pushed -= len(batch)
+			}
+
+			height, err := hostChain.FindHeight(
+				context.Background(),
+				byHeight[test.tipHeight].Hash,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if height.Int64() != test.tipHeight {
+				t.Errorf("unexpected height of the tip: [%v]", height)
+			}
+
+			if len(hostChain.AddHeadersWithRetargetEvents()) != 1 {
+				t.Errorf("retarget should be submitted once")
+			}
+
+			bestKnownHeight, err := hostChain.SimulatedRelay().FindHeight(
+				hostChain.SimulatedRelay().BestKnownDigest(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bestKnownHeight <= test.anchorHeight {
+				t.Errorf(
+					"best known header not advanced: [%v]",
+					bestKnownHeight,
+				)
+			}
+		})
+	}
+}
+
+// goldenPush is the content of a pushing golden file.
+type goldenPush struct {
+	Batches [][]int64     `json:"batches"`
+	Calls   []*goldenCall `json:"calls"`
+}
+
+// goldenCall is a single relay contract call. Header arguments are
+// represented by their hashes.
+type goldenCall struct {
+	Method               string   `json:"method"`
+	AnchorHeader         string   `json:"anchorHeader,omitempty"`
+	OldPeriodStartHeader string   `json:"oldPeriodStartHeader,omitempty"`
+	OldPeriodEndHeader   string   `json:"oldPeriodEndHeader,omitempty"`
+	Headers              []string `json:"headers,omitempty"`
+	AncestorDigest       string   `json:"ancestorDigest,omitempty"`
+	CurrentBestHeader    string   `json:"currentBestHeader,omitempty"`
+	NewBestHeader        string   `json:"newBestHeader,omitempty"`
+	Limit                int64    `json:"limit,omitempty"`
+}
+
+// recordingHostChain is a local host chain recording the relay contract
+// calls in their order. Unlike the local chain, it checks the ancestry of
+// headers against the Bitcoin chain and updates the best known digest once
+// a new best header is marked.
+type recordingHostChain struct {
+	*chainlocal.Chain
+	btcChain *btc.LocalChain
+
+	calls []*goldenCall
+}
+
+func (rhc *recordingHostChain) IsAncestor(
+	ctx context.Context,
+	ancestorDigest btc.Digest,
+	descendantDigest btc.Digest,
+	limit *big.Int,
+) (bool, error) {
+	header, err := rhc.btcChain.GetHeaderByDigest(ctx, descendantDigest)
+	if err != nil {
+		return false, err
+	}
+
+	for i := int64(0); i < limit.Int64(); i++ {
+		if header.Hash == ancestorDigest {
+			return true, nil
+		}
+
+		header, err = rhc.btcChain.GetHeaderByDigest(ctx, header.PrevHash)
+		if err != nil {
+			return false, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (rhc *recordingHostChain) AddHeaders(
+	ctx context.Context,
+	anchorHeader []byte,
+	headers []byte,
+) error {
+	rhc.calls = append(rhc.calls, &goldenCall{
+		Method:       AddHeadersMethod,
+		AnchorHeader: headerHash(anchorHeader),
+		Headers:      headerHashes(headers),
+	})
+
+	return rhc.Chain.AddHeaders(ctx, anchorHeader, headers)
+}
+
+func (rhc *recordingHostChain) AddHeadersWithRetarget(
+	ctx context.Context,
+	oldPeriodStartHeader []byte,
+	oldPeriodEndHeader []byte,
+	headers []byte,
+) error {
+	rhc.calls = append(rhc.calls, &goldenCall{
+		Method:               AddHeadersWithRetargetMethod,
+		OldPeriodStartHeader: headerHash(oldPeriodStartHeader),
+		OldPeriodEndHeader:   headerHash(oldPeriodEndHeader),
+		Headers:              headerHashes(headers),
+	})
+
+	return rhc.Chain.AddHeadersWithRetarget(
+		ctx,
+		oldPeriodStartHeader,
+		oldPeriodEndHeader,
+		headers,
+	)
+}
+
+func (rhc *recordingHostChain) MarkNewHeaviest(
+	ctx context.Context,
+	ancestorDigest btc.Digest,
+	currentBestHeader []byte,
+	newBestHeader []byte,
+	limit *big.Int,
+) error {
+	rhc.calls = append(rhc.calls, &goldenCall{
+		Method:            MarkNewHeaviestMethod,
+		AncestorDigest:    explorerHash(ancestorDigest),
+		CurrentBestHeader: headerHash(currentBestHeader),
+		NewBestHeader:     headerHash(newBestHeader),
+		Limit:             limit.Int64(),
+	})
+
+	newBest, err := btc.ParseHeader(0, newBestHeader)
+	if err != nil {
+		return err
+	}
+	rhc.SetBestKnownDigest(newBest.Hash)
+
+	return rhc.Chain.MarkNewHeaviest(
+		ctx,
+		ancestorDigest,
+		currentBestHeader,
+		newBestHeader,
+		limit,
+	)
+}
+
+// loadMainnetHeaders loads the mainnet headers from the test data and checks
+// their hashes.
+func loadMainnetHeaders(t *testing.T) []*btc.Header {
+	content, err := ioutil.ReadFile(
+		filepath.Join("testdata", "mainnet-headers.json"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []struct {
+		Height int64  `json:"height"`
+		Hash   string `json:"hash"`
+		Raw    string `json:"raw"`
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	headers := make([]*btc.Header, len(entries))
+	for i, entry := range entries {
+		raw, err := hex.DecodeString(entry.Raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header, err := btc.ParseHeader(entry.Height, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if explorerHash(header.Hash) != entry.Hash {
+			t.Fatalf(
+				"unexpected hash of header [%v]:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				entry.Height,
+				entry.Hash,
+				explorerHash(header.Hash),
+			)
+		}
+
+		headers[i] = header
+	}
+
+	return headers
+}
+
+// takeBatch takes the next batch from the headers queue, advancing the clock
+// until the header timeout elapses once the queue is drained.
+func takeBatch(t *testing.T, relay *Relay, fakeClock *clock.Fake) []*btc.Header {
+	result := make(chan []*btc.Header, 1)
+	go func() {
+		result <- relay.getHeadersFromQueue(context.Background())
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case batch := <-result:
+			return batch
+		case <-time.After(10 * time.Millisecond):
+			if len(relay.headersQueue) == 0 {
+				fakeClock.Advance(headerTimeout)
+			}
+		case <-deadline:
+			t.Fatal("batch should be formed")
+		}
+	}
+}
+
+func compareGolden(t *testing.T, goldenFile string, actual *goldenPush) {
+	actualContent, err := json.MarshalIndent(actual, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualContent = append(actualContent, '\n')
+
+	path := filepath.Join("testdata", goldenFile)
+
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, actualContent, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expectedContent, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expectedContent, actualContent) {
+		t.Errorf(
+			"unexpected pushing result; run with -update to regenerate "+
+				"[%v]:\n"+
+				"expected: [%s]\n"+
+				"actual:   [%s]\n",
+			path,
+			expectedContent,
+			actualContent,
+		)
+	}
+}
+
+// explorerHash returns the hash in the byte order used by block explorers.
+func explorerHash(digest btc.Digest) string {
+	reversed := make([]byte, len(digest))
+	for i := range digest {
+		reversed[i] = digest[len(digest)-1-i]
+	}
+
+	return hex.EncodeToString(reversed)
+}
+
+func headerHash(raw []byte) string {
+	header, err := btc.ParseHeader(0, raw)
+	if err != nil {
+		return fmt.Sprintf("invalid header: [%v]", err)
+	}
+
+	return explorerHash(header.Hash)
+}
+
+func headerHashes(packed []byte) []string {
+	hashes := make([]string, 0)
+	for len(packed) >= 80 {
+		hashes = append(hashes, headerHash(packed[:80]))
+		packed = packed[80:]
+	}
+
+	if len(packed) > 0 {
+		hashes = append(hashes, fmt.Sprintf("trailing [%v] bytes", len(packed)))
+	}
+
+	return hashes
+}
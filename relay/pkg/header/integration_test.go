@@ -9,6 +9,7 @@ import (
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/store"
 )
 
 const (
@@ -76,10 +77,17 @@ func TestRelay_Integration(t *testing.T) {
 		ctx,
 		btcChain,
 		localChain,
+		store.OpenMemory(),
+		&Config{},
+		NewControl(nil),
 		testDifficultyEpochDuration,
 		relayPullingSleepTime,
 		testRelayPushingSleepTime,
 		&mockObserver{},
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	// Sleep for a moment, so the relay can start processing headers
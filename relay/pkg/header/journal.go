@@ -0,0 +1,152 @@
+package header
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// journal.go file contains the logic which guarantees a headers batch is
+// submitted to the host chain at most once, even across crashes. Each batch
+// gets a deterministic ID and its submission is recorded in the journal
+// before the transaction is sent. Before submitting a batch again, the relay
+// checks the journal and the host chain state. A batch already known by the
+// host chain is never resubmitted. A batch whose earlier submission may still
+// be pending is resubmitted only once the submission had the finality depth
+// worth of blocks to get mined. The entry of a submitted batch is confirmed
+// only once the host chain knows the last header of the batch, as the
+// submission may still be dropped or reverted.
+
+// submitBatch submits the given headers batch to the host chain using the
+// passed submit function unless the batch has already been submitted.
+func (r *Relay) submitBatch(
+	ctx context.Context,
+	headers []*btc.Header,
+	submit func() error,
+) error {
+	firstHeader := headers[0]
+	lastHeader := headers[len(headers)-1]
+	id := store.NewBatchID(firstHeader.Hash, lastHeader.Hash)
+
+	batchLogger := correlation.InjectedLogger(ctx, r.logger)
+
+	for {
+		entry, err := r.store.LoadJournalEntry(id)
+		if err != nil {
+			return fmt.Errorf("could not load journal entry: [%v]", err)
+		}
+
+		if _, err := r.hostChain.FindHeight(ctx, lastHeader.Hash); err == nil {
+			batchLogger.Infof(
+				"batch [%v] with %v is already known by the host chain; "+
+					"skipping submission",
+				id,
+				headersSummary(headers),
+			)
+
+			return r.confirmJournalEntry(id, entry, headers)
+		}
+
+		if entry == nil || entry.Status != store.BatchSubmitted {
+			break
+		}
+
+		currentBlock, err := r.hostChain.CurrentBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("could not get current block: [%v]", err)
+		}
+
+		if currentBlock >= entry.SubmissionBlock+r.finalityDepth {
+			batchLogger.Warnf(
+				"batch [%v] submitted at block [%v] is not known by the "+
+					"host chain; resubmitting",
+				id,
+				entry.SubmissionBlock,
+			)
+			break
+		}
+
+		batchLogger.Infof(
+			"batch [%v] submitted at block [%v] may still be pending; "+
+				"waiting before resubmitting",
+			id,
+			entry.SubmissionBlock,
+		)
+
+		if _, err := r.control.Scheduler().wait(
+			ctx,
+			TaskJournal,
+			journaledBatchCheckInterval,
+			true,
+			nil,
+		); err != nil {
+			return err
+		}
+	}
+
+	submissionBlock, err := r.hostChain.CurrentBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get current block: [%v]", err)
+	}
+
+	// Record the submission before sending the transaction so that
+	// a crash in between does not lead to a duplicate submission.
+	if err := r.store.SaveJournalEntry(&store.JournalEntry{
+		ID:              id,
+		FirstHeight:     firstHeader.Height,
+		LastHeight:      lastHeader.Height,
+		LastDigest:      lastHeader.Hash,
+		Status:          store.BatchSubmitted,
+		SubmissionBlock: submissionBlock,
+		UpdatedAt:       r.timeSource().Now(),
+	}); err != nil {
+		return fmt.Errorf("could not journal batch [%v]: [%v]", id, err)
+	}
+
+	return submit()
+}
+
+// confirmJournaledBatch marks the journal entry of the given pushed batch as
+// confirmed once the batch is known by the host chain.
+func (r *Relay) confirmJournaledBatch(headers []*btc.Header) error {
+	id := store.NewBatchID(headers[0].Hash, headers[len(headers)-1].Hash)
+
+	entry, err := r.store.LoadJournalEntry(id)
+	if err != nil {
+		return fmt.Errorf("could not load journal entry: [%v]", err)
+	}
+
+	return r.confirmJournalEntry(id, entry, headers)
+}
+
+func (r *Relay) confirmJournalEntry(
+	id store.BatchID,
+	entry *store.JournalEntry,
+	headers []*btc.Header,
+) error {
+	if entry != nil && entry.Status == store.BatchConfirmed {
+		return nil
+	}
+
+	lastHeader := headers[len(headers)-1]
+
+	if err := r.store.SaveJournalEntry(&store.JournalEntry{
+		ID:          id,
+		FirstHeight: headers[0].Height,
+		LastHeight:  lastHeader.Height,
+		LastDigest:  lastHeader.Hash,
+		Status:      store.BatchConfirmed,
+		UpdatedAt:   r.timeSource().Now(),
+	}); err != nil {
+		return fmt.Errorf(
+			"could not confirm journaled batch [%v]: [%v]",
+			id,
+			err,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+func TestSubmitBatch(t *testing.T) {
+	headers := []*btc.Header{
+		{Hash: to32Bytes(1), Height: 1},
+		{Hash: to32Bytes(2), Height: 2},
+	}
+	id := store.NewBatchID(headers[0].Hash, headers[1].Hash)
+
+	var tests = map[string]struct {
+		knownByHostChain bool
+		journalEntry     *store.JournalEntry
+		currentBlock     uint64
+		expectSubmission bool
+		expectError      bool
+		expectedStatus   store.BatchStatus
+	}{
+		"new batch": {
+			currentBlock:     100,
+			expectSubmission: true,
+			expectedStatus:   store.BatchSubmitted,
+		},
+		"batch known by host chain": {
+			knownByHostChain: true,
+			currentBlock:     100,
+			expectSubmission: false,
+			expectedStatus:   store.BatchConfirmed,
+		},
+		"journaled batch known by host chain": {
+			knownByHostChain: true,
+			journalEntry: &store.JournalEntry{
+				ID:              id,
+				Status:          store.BatchSubmitted,
+				SubmissionBlock: 99,
+			},
+			currentBlock:     100,
+			expectSubmission: false,
+			expectedStatus:   store.BatchConfirmed,
+		},
+		"journaled batch which may still be pending": {
+			journalEntry: &store.JournalEntry{
+				ID:              id,
+				Status:          store.BatchSubmitted,
+				SubmissionBlock: 99,
+			},
+			currentBlock:     100,
+			expectSubmission: false,
+			expectError:      true,
+		},
+		"journaled batch abandoned": {
+			journalEntry: &store.JournalEntry{
+				ID:              id,
+				Status:          store.BatchAbandoned,
+				SubmissionBlock: 99,
+			},
+			currentBlock:     100,
+			expectSubmission: true,
+			expectedStatus:   store.BatchSubmitted,
+		},
+		"journaled batch dropped by the host chain": {
+			journalEntry: &store.JournalEntry{
+				ID:              id,
+				Status:          store.BatchSubmitted,
+				SubmissionBlock: 90,
+			},
+			currentBlock:     100,
+			expectSubmission: true,
+			expectedStatus:   store.BatchSubmitted,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			lc, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			localChain := lc.(*chainlocal.Chain)
+			localChain.SetCurrentBlock(test.currentBlock)
+			if test.knownByHostChain {
+				localChain.SetHeaderHeight(headers[1].Hash, headers[1].Height)
+			}
+
+			relayStore := store.OpenMemory()
+			if test.journalEntry != nil {
+				if err := relayStore.SaveJournalEntry(test.journalEntry); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			relay := &Relay{
+				logger:        testLogger,
+				hostChain:     localChain,
+				store:         relayStore,
+				finalityDepth: 5,
+			}
+
+			// The context is cancelled shortly so that waiting for
+			// a pending batch does not block the test.
+			ctx, cancelCtx := context.WithTimeout(
+				context.Background(),
+				100*time.Millisecond,
+			)
+			defer cancelCtx()
+
+			submitted := false
+			err = relay.submitBatch(ctx, headers, func() error {
+				submitted = true
+
+				// The journal should record the submission before
+				// the transaction is sent.
+				entry, err := relayStore.LoadJournalEntry(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if entry == nil || entry.Status != store.BatchSubmitted {
+					t.Errorf("batch not journaled before submission")
+				}
+
+				return nil
+			})
+
+			if test.expectSubmission != submitted {
+				t.Errorf(
+					"unexpected submission:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectSubmission,
+					submitted,
+				)
+			}
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Errorf(
+					"unexpected error:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+
+			if test.expectError {
+				return
+			}
+
+			entry, err := relayStore.LoadJournalEntry(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if entry == nil || entry.Status != test.expectedStatus {
+				t.Errorf("unexpected journal entry: [%+v]", entry)
+			}
+		})
+	}
+}
+
+func TestSubmitBatch_ConfirmedOnceKnownByHostChain(t *testing.T) {
+	ctx := context.Background()
+
+	headers := []*btc.Header{
+		{Hash: to32Bytes(1), Height: 1},
+		{Hash: to32Bytes(2), Height: 2},
+	}
+	id := store.NewBatchID(headers[0].Hash, headers[1].Hash)
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+	localChain.SetCurrentBlock(100)
+
+	relay := &Relay{
+		logger:          testLogger,
+		hostChain:       localChain,
+		store:           store.OpenMemory(),
+		observer:        &mockObserver{},
+		finalityDepth:   5,
+		finalityTracker: &finalityTracker{},
+	}
+
+	if err := relay.submitBatch(ctx, headers, func() error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	relay.trackPushedBatch(ctx, headers)
+
+	assertStatus := func(expectedStatus store.BatchStatus) {
+		entry, err := relay.store.LoadJournalEntry(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry == nil || entry.Status != expectedStatus {
+			t.Errorf(
+				"unexpected journal entry status:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%+v]\n",
+				expectedStatus,
+				entry,
+			)
+		}
+	}
+
+	// The submission is not mined yet.
+	relay.checkPushedBatchesConfirmation(ctx, 101)
+	assertStatus(store.BatchSubmitted)
+
+	localChain.SetHeaderHeight(headers[1].Hash, headers[1].Height)
+
+	relay.checkPushedBatchesConfirmation(ctx, 102)
+	assertStatus(store.BatchConfirmed)
+}
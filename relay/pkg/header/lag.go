@@ -0,0 +1,70 @@
+package header
+
+import (
+	"context"
+	"fmt"
+)
+
+// lag.go file contains the logic which periodically computes the relay lag,
+// i.e. the distance between the Bitcoin chain tip and the best header known
+// by the host chain. The lag is computed regardless of whether the relay
+// pushes headers by itself or runs in the watch-only mode.
+
+func (r *Relay) lagMonitoringLoop(ctx context.Context) {
+	r.logger.Infof("starting new relay lag monitoring loop")
+	defer r.logger.Infof("stopping current relay lag monitoring loop")
+
+	ticker := r.timeSource().NewTicker(relayLagMonitoringTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			lag, err := r.computeLag(ctx)
+			if err != nil {
+				r.logger.Warnf("could not compute relay lag: [%v]", err)
+				continue
+			}
+
+			r.observer.NotifyRelayLag(lag)
+
+			if lag > r.lagWarningThreshold {
+				r.logger.Warnf(
+					"relay lag [%v] exceeds the warning threshold [%v]",
+					lag,
+					r.lagWarningThreshold,
+				)
+			} else {
+				r.logger.Debugf("current relay lag is [%v]", lag)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// computeLag returns the number of Bitcoin blocks above the best header
+// known by the host chain.
+func (r *Relay) computeLag(ctx context.Context) (int64, error) {
+	chainHeight, err := r.btcChain.GetBlockCount(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not get block count: [%v]", err)
+	}
+
+	bestDigest, err := r.hostChain.GetBestKnownDigest(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not get best known digest: [%v]", err)
+	}
+
+	bestHeight, err := r.btcChain.GetHeightByDigest(ctx, bestDigest)
+	if err != nil {
+		return 0, fmt.Errorf("could not get best header height: [%v]", err)
+	}
+
+	lag := chainHeight - bestHeight
+	if lag < 0 {
+		return 0, nil
+	}
+
+	return lag, nil
+}
@@ -0,0 +1,58 @@
+package header
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+)
+
+func TestComputeLag(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	btcChain.SetHeaders([]*btc.Header{
+		{Hash: to32Bytes(1), Height: 1},
+		{Hash: to32Bytes(2), Height: 2},
+		{Hash: to32Bytes(3), Height: 3},
+		{Hash: to32Bytes(4), Height: 4},
+	})
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	// The host chain knows the second header so two Bitcoin blocks
+	// are not relayed yet.
+	localChain.SetBestKnownDigest(to32Bytes(2))
+
+	relay := &Relay{
+		logger:    testLogger,
+		btcChain:  btcChain,
+		hostChain: localChain,
+	}
+
+	lag, err := relay.computeLag(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLag := int64(2)
+	if expectedLag != lag {
+		t.Errorf(
+			"unexpected relay lag:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedLag,
+			lag,
+		)
+	}
+}
@@ -0,0 +1,85 @@
+package header
+
+import (
+	"time"
+)
+
+// latency.go file contains the low-latency mode of the relay. While users
+// wait for the relay contract to learn new headers, e.g. to prove their
+// deposit funding or redemption, the relay can be switched into that mode.
+// The rest between pushes and the checks for new Bitcoin headers are capped
+// at a short interval and the pushed batches are smaller, so new headers
+// reach the host chain soon after they are mined. Once nobody waits, the
+// relay is switched back to the economical mode, pushing full batches in
+// the default intervals.
+
+// EnterLowLatency switches the relay into the low-latency mode. The rest
+// between pushes and the checks for new headers take at most the given
+// interval and the pushed batches have at most the given size. Does nothing
+// if the relay is already in the low-latency mode.
+func (c *Control) EnterLowLatency(interval time.Duration, batchSize int) {
+	if batchSize <= 0 || batchSize > headersBatchSize {
+		batchSize = headersBatchSize
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.lowLatencyBatchSize > 0 {
+		return
+	}
+
+	c.logger.Infof(
+		"entering low-latency mode with [%v] interval and batches of [%v]",
+		interval,
+		batchSize,
+	)
+
+	c.lowLatencyBatchSize = batchSize
+	c.scheduler.setLatencyCap(interval)
+}
+
+// LeaveLowLatency switches the relay back into the economical mode. Does
+// nothing if the relay is not in the low-latency mode.
+func (c *Control) LeaveLowLatency() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.lowLatencyBatchSize == 0 {
+		return
+	}
+
+	c.logger.Infof("leaving low-latency mode")
+
+	c.lowLatencyBatchSize = 0
+	c.scheduler.setLatencyCap(0)
+}
+
+// IsLowLatency returns whether the relay is in the low-latency mode.
+func (c *Control) IsLowLatency() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.lowLatencyBatchSize > 0
+}
+
+// batchSize returns the maximum size of the pushed batches. A nil control
+// always uses the default size.
+func (c *Control) batchSize() int {
+	if c == nil {
+		return headersBatchSize
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.lowLatencyBatchSize > 0 {
+		return c.lowLatencyBatchSize
+	}
+
+	return headersBatchSize
+}
@@ -0,0 +1,139 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestControl_LowLatency(t *testing.T) {
+	control := NewControl(nil)
+	from := time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)
+
+	if err := control.Scheduler().SetSchedule(TaskPush, "5m"); err != nil {
+		t.Fatal(err)
+	}
+
+	control.EnterLowLatency(10*time.Second, 1)
+
+	if !control.IsLowLatency() {
+		t.Fatalf("low-latency mode not entered")
+	}
+
+	if batchSize := control.batchSize(); batchSize != 1 {
+		t.Errorf("unexpected batch size: [%v]", batchSize)
+	}
+
+	var tests = map[string]struct {
+		task         string
+		expectedNext time.Time
+	}{
+		"scheduled push is capped": {
+			task:         TaskPush,
+			expectedNext: from.Add(10 * time.Second),
+		},
+		"default pull is capped": {
+			task:         TaskPull,
+			expectedNext: from.Add(10 * time.Second),
+		},
+		"other tasks are not capped": {
+			task:         TaskPushDeferral,
+			expectedNext: from.Add(time.Minute),
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			next := control.Scheduler().nextRun(
+				test.task,
+				time.Minute,
+				from,
+				false,
+			)
+
+			if !next.Equal(test.expectedNext) {
+				t.Errorf(
+					"unexpected next run:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedNext,
+					next,
+				)
+			}
+		})
+	}
+
+	control.LeaveLowLatency()
+
+	if control.IsLowLatency() {
+		t.Fatalf("low-latency mode not left")
+	}
+
+	if batchSize := control.batchSize(); batchSize != headersBatchSize {
+		t.Errorf("unexpected batch size: [%v]", batchSize)
+	}
+
+	next := control.Scheduler().nextRun(TaskPush, time.Minute, from, false)
+	if !next.Equal(from.Add(5 * time.Minute)) {
+		t.Errorf("unexpected next run of scheduled push: [%v]", next)
+	}
+}
+
+func TestGetHeadersFromQueue_LowLatency(t *testing.T) {
+	control := NewControl(nil)
+	control.EnterLowLatency(10*time.Second, 2)
+
+	relay := &Relay{
+		logger:       testLogger,
+		control:      control,
+		headersQueue: make(chan *btc.Header, headersQueueSize),
+	}
+
+	for i := 0; i < 5; i++ {
+		relay.headersQueue <- &btc.Header{Height: int64(i)}
+	}
+
+	headers := relay.getHeadersFromQueue(context.Background())
+
+	if len(headers) != 2 {
+		t.Errorf(
+			"unexpected batch size:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			2,
+			len(headers),
+		)
+	}
+}
+
+func TestScheduler_LatencyCapWakesWaitingTasks(t *testing.T) {
+	control := NewControl(nil)
+
+	result := make(chan error)
+	go func() {
+		_, err := control.Scheduler().wait(
+			context.Background(),
+			TaskPush,
+			time.Hour,
+			false,
+			nil,
+		)
+		result <- err
+	}()
+
+	// Let the task start waiting before the cap is set.
+	time.Sleep(50 * time.Millisecond)
+
+	control.EnterLowLatency(10*time.Millisecond, 1)
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("waiting task not woken by low-latency mode")
+	}
+}
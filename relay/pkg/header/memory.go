@@ -0,0 +1,81 @@
+package header
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+)
+
+// memory.go file contains the guard of the pulling loop against memory
+// pressure. Once the heap grows above the configured limit, the relay stops
+// pulling new headers and returns the freed memory to the operating system,
+// so the process degrades gracefully instead of getting killed in the middle
+// of a push. Pushing continues in the meantime and drains the headers queue.
+
+// processHeapSize returns the number of bytes of allocated heap objects.
+func processHeapSize() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return stats.HeapAlloc
+}
+
+// currentHeapSize returns the heap size reported by the heap size function
+// of the relay or, if there is none, the heap size of the process.
+func (r *Relay) currentHeapSize() uint64 {
+	if r.heapSize == nil {
+		return processHeapSize()
+	}
+
+	return r.heapSize()
+}
+
+// waitForMemory blocks the pulling loop as long as the heap size exceeds the
+// memory limit. Returns an error only if the context has been cancelled.
+func (r *Relay) waitForMemory(ctx context.Context) error {
+	if r.memoryLimit == 0 {
+		return nil
+	}
+
+	heapSize := r.currentHeapSize()
+	if heapSize <= r.memoryLimit {
+		return nil
+	}
+
+	// The limit may be exceeded only by garbage not collected yet.
+	debug.FreeOSMemory()
+
+	heapSize = r.currentHeapSize()
+	if heapSize <= r.memoryLimit {
+		return nil
+	}
+
+	resume := Idle(ctx)
+	defer resume()
+
+	for heapSize > r.memoryLimit {
+		r.logger.Warnf(
+			"heap size of [%v] MiB exceeds the memory limit of [%v] MiB; "+
+				"pausing headers pulling",
+			heapSize/1024/1024,
+			r.memoryLimit/1024/1024,
+		)
+
+		select {
+		case <-r.timeSource().After(memoryPressureCheckInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		debug.FreeOSMemory()
+		heapSize = r.currentHeapSize()
+	}
+
+	r.logger.Infof(
+		"heap size of [%v] MiB is within the memory limit; "+
+			"resuming headers pulling",
+		heapSize/1024/1024,
+	)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package header
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+func TestWaitForMemory(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+	var mutex sync.Mutex
+	heapSize := uint64(300 * 1024 * 1024)
+
+	relay := &Relay{
+		logger:      testLogger,
+		clock:       fakeClock,
+		memoryLimit: 256 * 1024 * 1024,
+		heapSize: func() uint64 {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return heapSize
+		},
+	}
+
+	result := make(chan error)
+	go func() {
+		result <- relay.waitForMemory(context.Background())
+	}()
+
+	fakeClock.BlockUntil(1)
+
+	select {
+	case <-result:
+		t.Fatal("pulling not paused under memory pressure")
+	default:
+	}
+
+	mutex.Lock()
+	heapSize = 100 * 1024 * 1024
+	mutex.Unlock()
+
+	fakeClock.Advance(memoryPressureCheckInterval)
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pulling not resumed once memory is freed")
+	}
+}
+
+func TestWaitForMemory_NoLimit(t *testing.T) {
+	relay := &Relay{
+		logger: testLogger,
+		heapSize: func() uint64 {
+			return 1024 * 1024 * 1024
+		},
+	}
+
+	if err := relay.waitForMemory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
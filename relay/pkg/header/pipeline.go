@@ -0,0 +1,254 @@
+package header
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// pipeline.go file contains the pipeline connecting the source of headers
+// with the sink submitting them to the host chain. Headers delivered by the
+// source pass through a chain of stages, like fork gating, checkpoint
+// verification, contextual validation, deduplication and metrics, before they
+// are queued for the pushing loop. New header sources, sinks and processing
+// stages can be plugged in without changing the relay loops:
+//
+//   Source -> Stage -> ... -> Stage -> headersQueue -> Sink
+
+// Source delivers the stream of headers to relay.
+type Source interface {
+	// Next blocks until the header following the previously delivered one
+	// is available and returns it. Returns an error if the header cannot be
+	// delivered or the context is cancelled.
+	Next(ctx context.Context) (*btc.Header, error)
+}
+
+// Sink submits batches of headers to the host chain.
+type Sink interface {
+	// Submit submits the given batch of subsequent headers.
+	Submit(ctx context.Context, headers []*btc.Header) error
+}
+
+// Stage processes the headers delivered by the source before they are
+// queued for the sink. Headers submitted to the relay contract must be
+// subsequent, so a stage should drop only headers delivered again by the
+// source, otherwise the source is expected to skip the dropped header.
+type Stage interface {
+	// Process processes the given header. Returns false if the header
+	// should be dropped and an error if the relay should stop.
+	Process(ctx context.Context, header *btc.Header) (bool, error)
+}
+
+// StageFunc adapts an ordinary function to the Stage interface.
+type StageFunc func(ctx context.Context, header *btc.Header) (bool, error)
+
+// Process calls the function itself.
+func (sf StageFunc) Process(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	return sf(ctx, header)
+}
+
+// Pipeline customizes the flow of headers through the relay. Fields left
+// empty fall back to the defaults.
+type Pipeline struct {
+	// Source overrides the Bitcoin chain as the source of headers.
+	Source Source
+
+	// Sink overrides the relay contract as the sink of headers.
+	Sink Sink
+
+	// Stages are run for every header after the built-in stages.
+	Stages []Stage
+}
+
+// Size of the recent headers window used to drop duplicated headers.
+const dedupWindowSize = headersQueueSize + headersBatchSize
+
+// headerPipeline is the configured pipeline of a single relay instance.
+type headerPipeline struct {
+	source Source
+	stages []Stage
+	logger logs.Logger
+}
+
+// newPipeline assembles the pipeline of the relay from the built-in
+// stages and the given customizations, which can be nil.
+func (r *Relay) newPipeline(custom *Pipeline) *headerPipeline {
+	p := &headerPipeline{
+		source: &btcSource{relay: r},
+		logger: r.logger,
+	}
+
+	// Contested headers are held back before any other stage sees them, as
+	// they are dropped if they lose to the competing fork.
+	if r.forks != nil {
+		p.stages = append(p.stages, StageFunc(r.forkGateStage))
+	}
+
+	p.stages = append(
+		p.stages,
+		StageFunc(r.verifyCheckpointStage),
+		StageFunc(r.validationStage),
+		&dedupStage{logger: r.logger},
+		StageFunc(r.chainworkStage),
+	)
+
+	if custom != nil {
+		if custom.Source != nil {
+			p.source = custom.Source
+		}
+		if custom.Sink != nil {
+			r.sink = custom.Sink
+		}
+		p.stages = append(p.stages, custom.Stages...)
+	}
+
+	// Observers are notified only about headers passing all other stages.
+	p.stages = append(p.stages, StageFunc(r.observerStage))
+
+	return p
+}
+
+// next returns the next header delivered by the source which passes
+// all stages.
+func (p *headerPipeline) next(ctx context.Context) (*btc.Header, error) {
+	for {
+		header, err := p.source.Next(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not pull header: [%v]", err)
+		}
+
+		correlation.InjectedLogger(ctx, p.logger).Infof(
+			"pulled header [%v] from BTC chain",
+			header.Height,
+		)
+
+		passed, err := p.process(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+
+		if passed {
+			return header, nil
+		}
+	}
+}
+
+func (p *headerPipeline) process(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	for _, stage := range p.stages {
+		passed, err := stage.Process(ctx, header)
+		if err != nil || !passed {
+			return passed, err
+		}
+	}
+
+	return true, nil
+}
+
+// btcSource is the default source pulling headers from the Bitcoin chain.
+type btcSource struct {
+	relay *Relay
+}
+
+func (bs *btcSource) Next(ctx context.Context) (*btc.Header, error) {
+	return bs.relay.pullHeaderFromBtcChain(ctx)
+}
+
+// submit submits the batch to the configured sink or pushes it to the relay
+// contract if no sink is configured.
+func (r *Relay) submit(ctx context.Context, headers []*btc.Header) error {
+	if r.sink != nil {
+		return r.sink.Submit(ctx, headers)
+	}
+
+	return r.pushHeadersToHostChain(ctx, headers)
+}
+
+// verifyCheckpointStage rejects headers conflicting with the checkpoints.
+// Checkpoints are enforced regardless of the header validation mode.
+func (r *Relay) verifyCheckpointStage(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	if err := r.checkpointVerifier.Verify(header); err != nil {
+		return false, fmt.Errorf("header rejected: [%v]", err)
+	}
+
+	return true, nil
+}
+
+// validationStage checks the header against the contextual validation
+// rules, as configured by the header validation mode.
+func (r *Relay) validationStage(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	if err := r.validateHeader(ctx, header); err != nil {
+		return false, fmt.Errorf("invalid header: [%v]", err)
+	}
+
+	return true, nil
+}
+
+// chainworkStage tracks the cumulative chainwork of the header.
+func (r *Relay) chainworkStage(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	if err := r.chainwork.observe(header); err != nil {
+		r.logger.Warnf("could not track chainwork: [%v]", err)
+	}
+
+	return true, nil
+}
+
+// observerStage notifies the relay observer, e.g. the metrics, about the
+// pulled header.
+func (r *Relay) observerStage(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	r.observer.NotifyHeaderPulled(header)
+
+	return true, nil
+}
+
+// dedupStage drops headers delivered again by the source within the recent
+// headers window.
+type dedupStage struct {
+	recent []btc.Digest
+	seen   map[btc.Digest]bool
+	logger logs.Logger
+}
+
+func (ds *dedupStage) Process(
+	ctx context.Context,
+	header *btc.Header,
+) (bool, error) {
+	if ds.seen == nil {
+		ds.seen = make(map[btc.Digest]bool)
+	}
+
+	if ds.seen[header.Hash] {
+		ds.logger.Debugf("dropping duplicated header [%v]", header.Height)
+		return false, nil
+	}
+
+	if len(ds.recent) >= dedupWindowSize {
+		delete(ds.seen, ds.recent[0])
+		ds.recent = ds.recent[1:]
+	}
+
+	ds.recent = append(ds.recent, header.Hash)
+	ds.seen[header.Hash] = true
+
+	return true, nil
+}
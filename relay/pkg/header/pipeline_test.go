@@ -0,0 +1,154 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+type sliceSource struct {
+	headers []*btc.Header
+}
+
+func (ss *sliceSource) Next(ctx context.Context) (*btc.Header, error) {
+	if len(ss.headers) == 0 {
+		return nil, fmt.Errorf("no more headers")
+	}
+
+	header := ss.headers[0]
+	ss.headers = ss.headers[1:]
+
+	return header, nil
+}
+
+type recordingSink struct {
+	batches [][]*btc.Header
+}
+
+func (rs *recordingSink) Submit(
+	ctx context.Context,
+	headers []*btc.Header,
+) error {
+	rs.batches = append(rs.batches, headers)
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	headers := linkedHeaders(1, 4)
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpointVerifier, err := newCheckpointVerifier(
+		bc.NetworkParams(),
+		&Config{},
+		testLogger,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observer := &mockObserver{}
+	sink := &recordingSink{}
+
+	relay := &Relay{
+		logger:             testLogger,
+		checkpointVerifier: checkpointVerifier,
+		headerValidation:   HeaderValidationOff,
+		observer:           observer,
+	}
+	relay.pipeline = relay.newPipeline(&Pipeline{
+		Source: &sliceSource{
+			// The second header is delivered twice.
+			headers: []*btc.Header{
+				headers[0],
+				headers[1],
+				headers[1],
+				headers[2],
+				headers[3],
+			},
+		},
+		Sink: sink,
+		Stages: []Stage{
+			StageFunc(func(
+				ctx context.Context,
+				header *btc.Header,
+			) (bool, error) {
+				if header.Height == 4 {
+					return false, fmt.Errorf("header [4] rejected")
+				}
+				return true, nil
+			}),
+		},
+	})
+
+	heights := make([]int64, 0)
+	for {
+		header, err := relay.pipeline.next(context.Background())
+		if err != nil {
+			expectedError := "header [4] rejected"
+			if err.Error() != expectedError {
+				t.Errorf(
+					"unexpected error:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedError,
+					err,
+				)
+			}
+			break
+		}
+
+		heights = append(heights, header.Height)
+	}
+
+	expectedHeights := []int64{1, 2, 3}
+	if !reflect.DeepEqual(expectedHeights, heights) {
+		t.Errorf(
+			"unexpected headers passing the pipeline:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedHeights,
+			heights,
+		)
+	}
+
+	if err := relay.submit(context.Background(), headers[:3]); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 3 {
+		t.Errorf("batch should be submitted to the configured sink")
+	}
+}
+
+func TestDedupStage_Window(t *testing.T) {
+	stage := &dedupStage{logger: testLogger}
+	headers := linkedHeaders(1, dedupWindowSize+1)
+
+	for _, header := range headers {
+		passed, err := stage.Process(context.Background(), header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !passed {
+			t.Fatalf("header [%v] should pass", header.Height)
+		}
+	}
+
+	// The first header left the window.
+	passed, _ := stage.Process(context.Background(), headers[0])
+	if !passed {
+		t.Errorf("header outside the window should pass")
+	}
+
+	passed, _ = stage.Process(context.Background(), headers[len(headers)-1])
+	if passed {
+		t.Errorf("duplicated header should be dropped")
+	}
+}
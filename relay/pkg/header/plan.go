@@ -0,0 +1,276 @@
+package header
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// plan.go file contains the planner precomputing the relay contract calls
+// the relay makes to push all headers between the current relay position and
+// the Bitcoin tip. The planner splits headers into batches exactly the same
+// way as the pushing loop does, so operators can review the calls, their
+// estimated gas and the estimated duration before a big catch-up.
+
+const (
+	// AddHeadersMethod is the relay contract method adding headers within
+	// a single difficulty epoch.
+	AddHeadersMethod = "addHeaders"
+
+	// AddHeadersWithRetargetMethod is the relay contract method adding
+	// headers starting a new difficulty epoch.
+	AddHeadersWithRetargetMethod = "addHeadersWithRetarget"
+
+	// MarkNewHeaviestMethod is the relay contract method marking a new best
+	// header.
+	MarkNewHeaviestMethod = "markNewHeaviest"
+)
+
+// PlanEstimates holds the figures the push plan estimates are based on.
+// Estimates based on figures which are zero are not computed.
+type PlanEstimates struct {
+	// GasPerHeader is the gas expected to be used per pushed header.
+	GasPerHeader float64
+
+	// GasPerBestHeaderUpdate is the gas expected to be used by a single
+	// best header update.
+	GasPerBestHeaderUpdate float64
+
+	// HostBlockTime is the average time between host chain blocks.
+	HostBlockTime time.Duration
+}
+
+// PlannedCall is a single relay contract call of the push plan.
+type PlannedCall struct {
+	// Method is the name of the called relay contract method.
+	Method string
+	// FromHeight and ToHeight are the heights of the first and the last
+	// header submitted by the call. Both are equal to the height of the new
+	// best header for best header updates.
+	FromHeight int64
+	ToHeight   int64
+	// HeadersCount is the number of headers submitted by the call. It is
+	// zero for best header updates.
+	HeadersCount int
+	// EstimatedGas is the gas the call is expected to use. It is zero if
+	// the gas is not known.
+	EstimatedGas uint64
+}
+
+// Plan is the sequence of relay contract calls pushing all headers between
+// the current relay position and the Bitcoin tip.
+type Plan struct {
+	// FromHeight is the height of the best header known by the relay
+	// contract.
+	FromHeight int64
+	// TipHeight is the height of the Bitcoin tip.
+	TipHeight int64
+	// Batches is the number of headers batches formed by the relay.
+	Batches int
+	// Calls are the planned relay contract calls in the submission order.
+	Calls []*PlannedCall
+	// EstimatedGas is the total gas the calls are expected to use. It is
+	// zero if the gas is not known.
+	EstimatedGas uint64
+	// EstimatedDuration is the time the relay is expected to need to submit
+	// all the calls. It is zero if the host chain block time is not known.
+	EstimatedDuration time.Duration
+}
+
+// NewPlan plans the relay contract calls pushing headers following the best
+// header known by the relay contract up to the Bitcoin tip, with the given
+// relay configuration. Only the full relay mode can be planned.
+func NewPlan(
+	config *Config,
+	fromHeight int64,
+	tipHeight int64,
+	estimates *PlanEstimates,
+) (*Plan, error) {
+	if config.Mode == ModeRetargetOnly {
+		return nil, fmt.Errorf(
+			"push plan is not available in the retarget-only mode",
+		)
+	}
+
+	if config.SkipInterval > 1 {
+		return nil, fmt.Errorf(
+			"push plan is not available in the sparse mode",
+		)
+	}
+
+	catchUpLagThreshold := config.CatchUpLagThreshold
+	if catchUpLagThreshold <= 0 {
+		catchUpLagThreshold = defaultCatchUpLagThreshold
+	}
+
+	maxPendingBatches := config.MaxPendingBatches
+	if maxPendingBatches <= 0 {
+		maxPendingBatches = defaultMaxPendingBatches
+	}
+
+	plan := &Plan{
+		FromHeight: fromHeight,
+		TipHeight:  tipHeight,
+	}
+
+	addCall := func(method string, from, to int64, headersCount int) {
+		call := &PlannedCall{
+			Method:       method,
+			FromHeight:   from,
+			ToHeight:     to,
+			HeadersCount: headersCount,
+		}
+
+		gas := estimates.GasPerHeader * float64(headersCount)
+		if headersCount == 0 {
+			gas = estimates.GasPerBestHeaderUpdate
+		}
+		call.EstimatedGas = uint64(math.Round(gas))
+
+		plan.Calls = append(plan.Calls, call)
+		plan.EstimatedGas += call.EstimatedGas
+	}
+
+	alignment := batchAlignment(config)
+
+	processedHeaders := 0
+
+	for first := fromHeight + 1; first <= tipHeight; {
+		batchSize := headersBatchSize
+		if alignment > 0 {
+			batchSize = alignedBatchSize(first, batchSize, alignment)
+		}
+
+		last := first + int64(batchSize) - 1
+		if last > tipHeight {
+			last = tipHeight
+		}
+
+		plan.Batches++
+
+		// Same split as in pushHeadersToHostChain.
+		startMod := first % btcDifficultyEpochDuration
+		endMod := last % btcDifficultyEpochDuration
+
+		if startMod == 0 {
+			addCall(
+				AddHeadersWithRetargetMethod,
+				first,
+				last,
+				int(last-first+1),
+			)
+		} else if startMod > endMod {
+			retargetHeight := last - endMod
+			addCall(
+				AddHeadersMethod,
+				first,
+				retargetHeight-1,
+				int(retargetHeight-first),
+			)
+			addCall(
+				AddHeadersWithRetargetMethod,
+				retargetHeight,
+				last,
+				int(last-retargetHeight+1),
+			)
+		} else {
+			addCall(AddHeadersMethod, first, last, int(last-first+1))
+		}
+
+		processedHeaders += int(last - first + 1)
+		if processedHeaders >= headersBatchSize {
+			addCall(MarkNewHeaviestMethod, last, last, 0)
+			processedHeaders = 0
+		}
+
+		if estimates.HostBlockTime > 0 {
+			if tipHeight-last > catchUpLagThreshold {
+				// Batches are pipelined in the catch-up phase.
+				plan.EstimatedDuration += estimates.HostBlockTime /
+					time.Duration(maxPendingBatches)
+			} else if last < tipHeight {
+				plan.EstimatedDuration += relayPushingSleepTime
+			} else {
+				plan.EstimatedDuration += estimates.HostBlockTime
+			}
+		}
+
+		first = last + 1
+	}
+
+	return plan, nil
+}
+
+// Print writes the plan in a human readable form.
+func (p *Plan) Print(writer io.Writer) error {
+	if _, err := fmt.Fprintf(
+		writer,
+		"relay position:  %v\n"+
+			"bitcoin tip:     %v\n"+
+			"headers:         %v in %v batches\n"+
+			"calls:           %v\n"+
+			"estimated gas:   %v\n"+
+			"estimated time:  %v\n",
+		p.FromHeight,
+		p.TipHeight,
+		p.TipHeight-p.FromHeight,
+		p.Batches,
+		len(p.Calls),
+		formatEstimate(p.EstimatedGas),
+		formatDuration(p.EstimatedDuration),
+	); err != nil {
+		return err
+	}
+
+	if len(p.Calls) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(
+		writer,
+		"\n%-24v %-17v %-8v %v\n",
+		"method",
+		"heights",
+		"headers",
+		"gas",
+	); err != nil {
+		return err
+	}
+
+	for _, call := range p.Calls {
+		heights := fmt.Sprintf("%v", call.ToHeight)
+		if call.HeadersCount > 0 {
+			heights = fmt.Sprintf("%v-%v", call.FromHeight, call.ToHeight)
+		}
+
+		if _, err := fmt.Fprintf(
+			writer,
+			"%-24v %-17v %-8v %v\n",
+			call.Method,
+			heights,
+			call.HeadersCount,
+			formatEstimate(call.EstimatedGas),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatEstimate(value uint64) string {
+	if value == 0 {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+func formatDuration(duration time.Duration) string {
+	if duration == 0 {
+		return "unknown"
+	}
+
+	return duration.Round(time.Second).String()
+}
@@ -0,0 +1,162 @@
+package header
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPlan(t *testing.T) {
+	estimates := &PlanEstimates{
+		GasPerHeader:           10000,
+		GasPerBestHeaderUpdate: 50000,
+	}
+
+	var tests = map[string]struct {
+		fromHeight    int64
+		tipHeight     int64
+		expectedCalls []*PlannedCall
+	}{
+		"no headers to push": {
+			fromHeight:    100,
+			tipHeight:     100,
+			expectedCalls: nil,
+		},
+		"partial batch": {
+			fromHeight: 100,
+			tipHeight:  103,
+			expectedCalls: []*PlannedCall{
+				{AddHeadersMethod, 101, 103, 3, 30000},
+			},
+		},
+		"full batches": {
+			fromHeight: 100,
+			tipHeight:  110,
+			expectedCalls: []*PlannedCall{
+				{AddHeadersMethod, 101, 105, 5, 50000},
+				{MarkNewHeaviestMethod, 105, 105, 0, 50000},
+				{AddHeadersMethod, 106, 110, 5, 50000},
+				{MarkNewHeaviestMethod, 110, 110, 0, 50000},
+			},
+		},
+		"batch starting with retarget": {
+			fromHeight: 4031,
+			tipHeight:  4036,
+			expectedCalls: []*PlannedCall{
+				{AddHeadersWithRetargetMethod, 4032, 4036, 5, 50000},
+				{MarkNewHeaviestMethod, 4036, 4036, 0, 50000},
+			},
+		},
+		"batch spanning retarget": {
+			fromHeight: 4029,
+			tipHeight:  4034,
+			expectedCalls: []*PlannedCall{
+				{AddHeadersMethod, 4030, 4031, 2, 20000},
+				{AddHeadersWithRetargetMethod, 4032, 4034, 3, 30000},
+				{MarkNewHeaviestMethod, 4034, 4034, 0, 50000},
+			},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			plan, err := NewPlan(
+				&Config{},
+				test.fromHeight,
+				test.tipHeight,
+				estimates,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(test.expectedCalls, plan.Calls) {
+				t.Errorf(
+					"unexpected calls:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedCalls,
+					plan.Calls,
+				)
+			}
+
+			var expectedGas uint64
+			for _, call := range test.expectedCalls {
+				expectedGas += call.EstimatedGas
+			}
+
+			if expectedGas != plan.EstimatedGas {
+				t.Errorf(
+					"unexpected estimated gas:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedGas,
+					plan.EstimatedGas,
+				)
+			}
+		})
+	}
+}
+
+func TestNewPlan_EstimatedDuration(t *testing.T) {
+	plan, err := NewPlan(
+		&Config{CatchUpLagThreshold: 5, MaxPendingBatches: 2},
+		0,
+		20,
+		&PlanEstimates{HostBlockTime: 12 * time.Second},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two batches pipelined in the catch-up phase, one batch followed by the
+	// regular rest and the last batch waiting for a host chain block.
+	expectedDuration := 2*6*time.Second + relayPushingSleepTime + 12*time.Second
+
+	if expectedDuration != plan.EstimatedDuration {
+		t.Errorf(
+			"unexpected estimated duration:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedDuration,
+			plan.EstimatedDuration,
+		)
+	}
+}
+
+func TestNewPlan_RetargetOnly(t *testing.T) {
+	_, err := NewPlan(&Config{Mode: ModeRetargetOnly}, 0, 10, &PlanEstimates{})
+	if err == nil {
+		t.Fatal("expected error in the retarget-only mode")
+	}
+}
+
+func TestPlanPrint(t *testing.T) {
+	plan, err := NewPlan(&Config{}, 100, 103, &PlanEstimates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err := plan.Print(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"headers:         3 in 1 batches\n",
+		"estimated gas:   unknown\n",
+		"addHeaders               101-103           3        unknown\n",
+	} {
+		if !strings.Contains(buffer.String(), expected) {
+			t.Errorf(
+				"unexpected plan output:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				expected,
+				buffer.String(),
+			)
+		}
+	}
+}
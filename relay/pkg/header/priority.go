@@ -0,0 +1,112 @@
+package header
+
+import (
+	"context"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// priority.go file contains the prioritization of headers awaited by pending
+// proofs. Once the services submitting proofs know a transaction is included
+// in a block at a given height, its proof waits for the relay contract to
+// learn the headers from that block up to the one providing the required
+// confirmations. Until the header at the highest awaited height is pushed,
+// the relay does not rest between pushes, does not defer pushes, pulls
+// headers without pacing and pushes a batch as soon as it reaches that
+// height instead of waiting for it to fill up.
+
+// Prioritize makes the relay push the headers up to the given height ahead
+// of routine work, as the proof with the given key awaits them. Registering
+// the same proof again updates the awaited height.
+func (c *Control) Prioritize(key string, height int64) {
+	c.mutex.Lock()
+	previous, ok := c.priorities[key]
+	c.priorities[key] = height
+	c.mutex.Unlock()
+
+	if ok && previous == height {
+		return
+	}
+
+	c.logger.Infof(
+		"prioritizing headers up to height [%v] awaited by proof [%v]",
+		height,
+		key,
+	)
+
+	// Wake the pushing loop up so it does not finish its rest first.
+	c.TriggerPush()
+}
+
+// Deprioritize stops prioritizing the headers awaited by the proof with the
+// given key, e.g. once the proof has been submitted.
+func (c *Control) Deprioritize(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.priorities[key]; !ok {
+		return
+	}
+
+	c.logger.Infof("no longer prioritizing headers awaited by proof [%v]", key)
+
+	delete(c.priorities, key)
+}
+
+// priorityHeight returns the highest height of the headers awaited by
+// pending proofs. It is zero if no proofs are pending or the control is nil.
+func (c *Control) priorityHeight() int64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var height int64
+	for _, awaited := range c.priorities {
+		if awaited > height {
+			height = awaited
+		}
+	}
+
+	return height
+}
+
+// awaitsAbove returns whether pending proofs await headers above the given
+// height.
+func (r *Relay) awaitsAbove(height int64) bool {
+	priorityHeight := r.control.priorityHeight()
+
+	return priorityHeight > 0 && priorityHeight > height
+}
+
+// reachesPriority returns whether the given batch contains the header at the
+// highest height awaited by pending proofs, so it should be pushed without
+// waiting for more headers.
+func (r *Relay) reachesPriority(headers []*btc.Header) bool {
+	if len(headers) == 0 {
+		return false
+	}
+
+	height := r.control.priorityHeight()
+
+	return height > 0 &&
+		height >= headers[0].Height &&
+		height <= headers[len(headers)-1].Height
+}
+
+// coversPriority returns whether the given batch contains headers awaited by
+// pending proofs, so its push should not be deferred.
+func (r *Relay) coversPriority(ctx context.Context, headers []*btc.Header) bool {
+	if len(headers) == 0 || !r.awaitsAbove(headers[0].Height-1) {
+		return false
+	}
+
+	correlation.InjectedLogger(ctx, r.logger).Infof(
+		"batch contains headers awaited by pending proofs; not deferring push",
+	)
+
+	return true
+}
@@ -0,0 +1,78 @@
+package header
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestControl_Prioritize(t *testing.T) {
+	control := NewControl(nil)
+
+	if height := control.priorityHeight(); height != 0 {
+		t.Errorf("unexpected priority height without proofs: [%v]", height)
+	}
+
+	control.Prioritize("funding:a", 105)
+	control.Prioritize("redemption:b", 110)
+
+	select {
+	case <-control.pushRequested():
+	default:
+		t.Errorf("push not requested for prioritized headers")
+	}
+
+	// Registering the same proof again does not request another push.
+	control.Prioritize("funding:a", 105)
+
+	select {
+	case <-control.pushRequested():
+		t.Errorf("push requested for already prioritized headers")
+	default:
+	}
+
+	if height := control.priorityHeight(); height != 110 {
+		t.Errorf("unexpected priority height: [%v]", height)
+	}
+
+	control.Deprioritize("redemption:b")
+
+	if height := control.priorityHeight(); height != 105 {
+		t.Errorf("unexpected priority height: [%v]", height)
+	}
+}
+
+func TestGetHeadersFromQueue_Priority(t *testing.T) {
+	relay := &Relay{
+		logger:       testLogger,
+		control:      NewControl(nil),
+		headersQueue: make(chan *btc.Header, headersQueueSize),
+	}
+
+	relay.control.Prioritize("funding:a", 11)
+
+	for height := int64(10); height <= 13; height++ {
+		relay.headersQueue <- &btc.Header{Height: height}
+	}
+
+	// The batch is returned as soon as it reaches the awaited header,
+	// without waiting for the full batch.
+	headers := relay.getHeadersFromQueue(context.Background())
+	if len(headers) != 2 || headers[1].Height != 11 {
+		t.Fatalf("unexpected batch: [%v]", headersSummary(headers))
+	}
+
+	if !relay.awaitsAbove(10) {
+		t.Errorf("headers above the batch start should be awaited")
+	}
+
+	if relay.awaitsAbove(11) {
+		t.Errorf("headers above the awaited height should not be awaited")
+	}
+
+	// Awaited headers already pushed do not shorten further batches.
+	if relay.reachesPriority([]*btc.Header{{Height: 12}, {Height: 13}}) {
+		t.Errorf("batch above the awaited height should not reach priority")
+	}
+}
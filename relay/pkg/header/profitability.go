@@ -0,0 +1,84 @@
+package header
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// profitability.go file contains the logic which decides whether a push of
+// headers batch should be deferred because it is not profitable. The cost of
+// the push is estimated from the expected gas used per header and the current
+// gas price, and it is compared with the reward expected for the pushed
+// headers. If any of the estimates is not known yet, the push is not
+// deferred. The relay lag safety bound of the push schedule applies as well,
+// so the relay liveness is preserved.
+
+// ProfitabilityEstimator estimates the economics of pushing headers to the
+// host chain.
+type ProfitabilityEstimator interface {
+	// RewardPerHeader returns the reward expected for a single pushed header,
+	// expressed in the smallest unit of the host chain currency. False is
+	// returned if the reward is not known.
+	RewardPerHeader() (*big.Int, bool)
+
+	// GasPerHeader returns the gas expected to be used per pushed header.
+	// False is returned if the gas is not known.
+	GasPerHeader() (float64, bool)
+}
+
+// profitabilityGate defers pushes whose estimated cost exceeds the expected
+// reward by more than the margin.
+type profitabilityGate struct {
+	estimator ProfitabilityEstimator
+	// margin is expressed in percent of the expected reward.
+	margin int64
+}
+
+func (pg *profitabilityGate) isEnabled() bool {
+	return pg != nil
+}
+
+// deferralReason returns the reason why the push of the given number of
+// headers should be deferred at the given gas price. An empty string means
+// the push should not be deferred.
+func (pg *profitabilityGate) deferralReason(
+	headersCount int,
+	gasPrice *big.Int,
+) string {
+	if gasPrice == nil {
+		return ""
+	}
+
+	rewardPerHeader, ok := pg.estimator.RewardPerHeader()
+	if !ok {
+		return ""
+	}
+
+	gasPerHeader, ok := pg.estimator.GasPerHeader()
+	if !ok {
+		return ""
+	}
+
+	gas, _ := big.NewFloat(gasPerHeader * float64(headersCount)).Int(nil)
+	cost := new(big.Int).Mul(gas, gasPrice)
+
+	reward := new(big.Int).Mul(rewardPerHeader, big.NewInt(int64(headersCount)))
+
+	// The cost can exceed the reward by at most the margin.
+	maxCost := new(big.Int).Div(
+		new(big.Int).Mul(reward, big.NewInt(100+pg.margin)),
+		big.NewInt(100),
+	)
+
+	if cost.Cmp(maxCost) > 0 {
+		return fmt.Sprintf(
+			"estimated cost [%v] exceeds expected reward [%v] by more "+
+				"than [%v]%%",
+			cost,
+			reward,
+			pg.margin,
+		)
+	}
+
+	return ""
+}
@@ -0,0 +1,101 @@
+package header
+
+import (
+	"math/big"
+	"testing"
+)
+
+type mockProfitabilityEstimator struct {
+	rewardPerHeader *big.Int
+	gasPerHeader    float64
+}
+
+func (mpe *mockProfitabilityEstimator) RewardPerHeader() (*big.Int, bool) {
+	return mpe.rewardPerHeader, mpe.rewardPerHeader != nil
+}
+
+func (mpe *mockProfitabilityEstimator) GasPerHeader() (float64, bool) {
+	return mpe.gasPerHeader, mpe.gasPerHeader > 0
+}
+
+func TestProfitabilityGate_DeferralReason(t *testing.T) {
+	gasPrice := big.NewInt(100000000000) // 100 Gwei
+
+	var tests = map[string]struct {
+		estimator      *mockProfitabilityEstimator
+		margin         int64
+		gasPrice       *big.Int
+		expectDeferral bool
+	}{
+		"reward exceeds cost": {
+			// Cost: 10000 gas * 100 Gwei = 1000000 Gwei per header.
+			estimator: &mockProfitabilityEstimator{
+				rewardPerHeader: big.NewInt(2000000000000000),
+				gasPerHeader:    10000,
+			},
+			gasPrice:       gasPrice,
+			expectDeferral: false,
+		},
+		"cost exceeds reward": {
+			estimator: &mockProfitabilityEstimator{
+				rewardPerHeader: big.NewInt(500000000000000),
+				gasPerHeader:    10000,
+			},
+			gasPrice:       gasPrice,
+			expectDeferral: true,
+		},
+		"cost exceeds reward within margin": {
+			estimator: &mockProfitabilityEstimator{
+				rewardPerHeader: big.NewInt(500000000000000),
+				gasPerHeader:    10000,
+			},
+			margin:         100,
+			gasPrice:       gasPrice,
+			expectDeferral: false,
+		},
+		"unknown reward": {
+			estimator: &mockProfitabilityEstimator{
+				gasPerHeader: 10000,
+			},
+			gasPrice:       gasPrice,
+			expectDeferral: false,
+		},
+		"unknown gas": {
+			estimator: &mockProfitabilityEstimator{
+				rewardPerHeader: big.NewInt(1),
+			},
+			gasPrice:       gasPrice,
+			expectDeferral: false,
+		},
+		"unknown gas price": {
+			estimator: &mockProfitabilityEstimator{
+				rewardPerHeader: big.NewInt(1),
+				gasPerHeader:    10000,
+			},
+			expectDeferral: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			gate := &profitabilityGate{
+				estimator: test.estimator,
+				margin:    test.margin,
+			}
+
+			reason := gate.deferralReason(5, test.gasPrice)
+
+			actualDeferral := reason != ""
+			if test.expectDeferral != actualDeferral {
+				t.Errorf(
+					"unexpected deferral:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v] (reason: %v)\n",
+					test.expectDeferral,
+					actualDeferral,
+					reason,
+				)
+			}
+		})
+	}
+}
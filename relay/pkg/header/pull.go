@@ -3,19 +3,21 @@ package header
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
 )
 
 // pull.go file contains the logic which performs the following flow:
-// pullHeaderFromBtcChain -> putHeaderToQueue -> headersQueue
+// pullHeaderFromBtcChain -> pipeline stages -> putHeaderToQueue -> headersQueue
 
 func (r *Relay) pullHeaderFromBtcChain(
 	ctx context.Context,
 ) (*btc.Header, error) {
+	waits := 0
+
 	for {
-		chainHeight, err := r.btcChain.GetBlockCount()
+		chainHeight, err := r.btcChain.GetBlockCount(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("could not get block count [%v]", err)
 		}
@@ -23,7 +25,10 @@ func (r *Relay) pullHeaderFromBtcChain(
 		// Check if there are more headers to pull or we are above the chain's
 		// tip and need to sleep until the Bitcoin chain adds more blocks.
 		if r.nextPullHeaderHeight <= chainHeight {
-			nextHeader, err := r.btcChain.GetHeaderByHeight(r.nextPullHeaderHeight)
+			nextHeader, err := r.btcChain.GetHeaderByHeight(
+				ctx,
+				r.nextPullHeaderHeight,
+			)
 			if err != nil {
 				return nil, fmt.Errorf(
 					"could not get header by height at [%d]: [%v]",
@@ -40,27 +45,76 @@ func (r *Relay) pullHeaderFromBtcChain(
 			}
 		}
 
-		select {
-		case <-time.After(r.pullingSleepTime):
-		case <-ctx.Done():
-			return nil, ctx.Err()
+		// The first wait after pulling a header is not idle so adaptive
+		// schedules check for the next header soon.
+		if _, err := r.control.Scheduler().wait(
+			ctx,
+			TaskPull,
+			r.pullingSleepTime,
+			waits > 0,
+			nil,
+		); err != nil {
+			return nil, err
 		}
+		waits++
 	}
 }
 
-func (r *Relay) putHeaderToQueue(header *btc.Header) {
-	r.headersQueue <- header
+// putHeaderToQueue puts the header to the headers queue unless it is skipped.
+// The correlation ID carried by the context, if any, is remembered so the
+// batch of the header can be traced back to its pull.
+func (r *Relay) putHeaderToQueue(ctx context.Context, header *btc.Header) {
+	if !r.isSkipped(header.Height) {
+		if id := correlation.FromContext(ctx); id != "" {
+			r.pullIDs.Store(header.Hash, id)
+		}
+
+		r.headersQueue <- header
+	}
 	r.lastPulledHeader = header
 	r.nextPullHeaderHeight++
 }
 
-func (r *Relay) findBestHeader() (*btc.Header, error) {
-	currentBestDigest, err := r.hostChain.GetBestKnownDigest()
+// takePullIDs returns the correlation ID of the batch formed of the given
+// headers, which is the ID the first header was pulled with, and the IDs the
+// other headers were pulled with. Headers restored from the queue
+// store have no pull ID, so a new ID is generated for their batch.
+func (r *Relay) takePullIDs(
+	headers []*btc.Header,
+) (correlation.ID, []correlation.ID) {
+	var batchID correlation.ID
+	pullIDs := make([]correlation.ID, 0)
+
+	for i, header := range headers {
+		value, ok := r.pullIDs.Load(header.Hash)
+		if !ok {
+			continue
+		}
+		r.pullIDs.Delete(header.Hash)
+
+		id := value.(correlation.ID)
+		if i == 0 {
+			batchID = id
+			continue
+		}
+
+		pullIDs = append(pullIDs, id)
+	}
+
+	if batchID == "" {
+		batchID = correlation.New()
+	}
+
+	return batchID, pullIDs
+}
+
+func (r *Relay) findBestHeader(ctx context.Context) (*btc.Header, error) {
+	currentBestDigest, err := r.hostChain.GetBestKnownDigest(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	bestHeader, err := r.btcChain.GetHeaderByDigest(currentBestDigest)
+	bestHeight, err := r.btcChain.GetHeightByDigest(ctx, currentBestDigest)
 	if err != nil {
 		return nil, err
 	}
@@ -69,19 +123,33 @@ func (r *Relay) findBestHeader() (*btc.Header, error) {
 	// longer part of the longest Bitcoin blockchain (perhaps we registered
 	// a header on the host chain and crashed and reorg happened on the Bitcoin
 	// chain before we recovered from the crash).
-	betterOrSameHeader, err := r.btcChain.GetHeaderByHeight(bestHeader.Height)
+	betterOrSameHeader, err := r.btcChain.GetHeaderByHeight(ctx, bestHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	// In the common case the best header is still on the longest chain and
+	// the full header is not fetched by its digest at all.
+	if betterOrSameHeader.Hash == currentBestDigest {
+		return betterOrSameHeader, nil
+	}
+
+	bestHeader, err := r.btcChain.GetHeaderByDigest(ctx, currentBestDigest)
 	if err != nil {
 		return nil, err
 	}
 
 	// See if there's a better header at that height. If so, crawl backwards.
 	for !bestHeader.Equals(betterOrSameHeader) {
-		bestHeader, err = r.btcChain.GetHeaderByDigest(bestHeader.PrevHash)
+		bestHeader, err = r.btcChain.GetHeaderByDigest(ctx, bestHeader.PrevHash)
 		if err != nil {
 			return nil, err
 		}
 
-		betterOrSameHeader, err = r.btcChain.GetHeaderByHeight(bestHeader.Height)
+		betterOrSameHeader, err = r.btcChain.GetHeaderByHeight(
+			ctx,
+			bestHeader.Height,
+		)
 		if err != nil {
 			return nil, err
 		}
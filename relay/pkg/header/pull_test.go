@@ -8,10 +8,12 @@ import (
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
 )
 
 func TestPutHeaderToQueue(t *testing.T) {
 	relay := &Relay{
+		logger:               testLogger,
 		headersQueue:         make(chan *btc.Header, headersQueueSize),
 		nextPullHeaderHeight: 1,
 	}
@@ -22,7 +24,7 @@ func TestPutHeaderToQueue(t *testing.T) {
 	}
 
 	for _, header := range headers {
-		relay.putHeaderToQueue(header)
+		relay.putHeaderToQueue(context.Background(), header)
 	}
 
 	// Check nextPullHeaderHeight
@@ -83,6 +85,7 @@ func TestPullHeaderFromBtcChain(t *testing.T) {
 	})
 
 	relay := &Relay{
+		logger:               testLogger,
 		btcChain:             btcChain,
 		pullingSleepTime:     300 * time.Millisecond,
 		nextPullHeaderHeight: 1,
@@ -198,11 +201,12 @@ func TestFindBestHeader_HostChainReturnsBestHeader(t *testing.T) {
 	localChain.SetBestKnownDigest([32]byte{2})
 
 	relay := &Relay{
+		logger:    testLogger,
 		btcChain:  btcChain,
 		hostChain: localChain,
 	}
 
-	header, err := relay.findBestHeader()
+	header, err := relay.findBestHeader(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -257,11 +261,12 @@ func TestFindBestHeader_HostChainDoesNotReturnBestHeader(t *testing.T) {
 	localChain.SetBestKnownDigest([32]byte{7})
 
 	relay := &Relay{
+		logger:    testLogger,
 		btcChain:  btcChain,
 		hostChain: localChain,
 	}
 
-	header, err := relay.findBestHeader()
+	header, err := relay.findBestHeader(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -280,3 +285,50 @@ func TestFindBestHeader_HostChainDoesNotReturnBestHeader(t *testing.T) {
 		)
 	}
 }
+
+func TestTakePullIDs(t *testing.T) {
+	relay := &Relay{
+		logger:               testLogger,
+		headersQueue:         make(chan *btc.Header, headersQueueSize),
+		nextPullHeaderHeight: 1,
+	}
+
+	headers := []*btc.Header{
+		{Hash: [32]byte{1}, Height: 1},
+		{Hash: [32]byte{2}, Height: 2},
+		{Hash: [32]byte{3}, Height: 3},
+	}
+
+	// The last header is restored from the queue store, so it has no pull
+	// ID.
+	pullIDs := []correlation.ID{"first", "second"}
+	for i, header := range headers {
+		ctx := context.Background()
+		if i < len(pullIDs) {
+			ctx = correlation.WithID(ctx, pullIDs[i])
+		}
+
+		relay.putHeaderToQueue(ctx, header)
+	}
+
+	batchID, otherIDs := relay.takePullIDs(headers)
+
+	if batchID != "first" {
+		t.Errorf("unexpected batch ID: [%v]", batchID)
+	}
+
+	if !reflect.DeepEqual([]correlation.ID{"second"}, otherIDs) {
+		t.Errorf("unexpected pull IDs: [%v]", otherIDs)
+	}
+
+	// The IDs are forgotten once taken, so a new batch ID is generated.
+	batchID, otherIDs = relay.takePullIDs(headers)
+
+	if batchID == "" || batchID == "first" {
+		t.Errorf("unexpected batch ID: [%v]", batchID)
+	}
+
+	if len(otherIDs) != 0 {
+		t.Errorf("unexpected pull IDs: [%v]", otherIDs)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
 )
 
 // push.go file contains the logic which performs the following flow:
@@ -17,37 +18,61 @@ import (
 // in the queue. Normally, this function returns headers from the queue but not
 // less than one and no more than `headersBatchSize` headers. Empty headers
 // slice can be returned only in case the provided context is cancelled.
+// In the low-latency mode, batches are limited to the low-latency batch size.
+// If batches are aligned, the batch size is reduced so the batch ends at
+// an aligned height.
 func (r *Relay) getHeadersFromQueue(ctx context.Context) []*btc.Header {
+	defer Idle(ctx)()
+
 	headers := make([]*btc.Header, 0)
 
-	headerTimer := time.NewTimer(headerTimeout)
+	headerTimer := r.timeSource().NewTimer(headerTimeout)
 	defer headerTimer.Stop()
 
-	for len(headers) < headersBatchSize {
-		logger.Debugf("waiting for new header appear on queue")
+	batchSize := r.control.batchSize()
+
+	for len(headers) < batchSize {
+		r.logger.Debugf("waiting for new header appear on queue")
 
 		select {
 		case header := <-r.headersQueue:
-			logger.Debugf("got header (%v) from queue", header.Height)
+			r.logger.Debugf("got header (%v) from queue", header.Height)
 
 			headers = append(headers, header)
 
+			if len(headers) == 1 && r.batchAlignment > 0 {
+				batchSize = alignedBatchSize(
+					header.Height,
+					batchSize,
+					r.batchAlignment,
+				)
+			}
+
+			if r.reachesPriority(headers) {
+				r.logger.Debugf(
+					"header (%v) is awaited by pending proofs; "+
+						"returning headers pulled so far",
+					header.Height,
+				)
+				return headers
+			}
+
 			// Stop the timer. In case it already expired, drain the channel
 			// before performing reset.
 			if !headerTimer.Stop() {
-				<-headerTimer.C
+				<-headerTimer.C()
 			}
 			headerTimer.Reset(headerTimeout)
-		case <-headerTimer.C:
+		case <-headerTimer.C():
 			if len(headers) > 0 {
-				logger.Debugf(
+				r.logger.Debugf(
 					"new header did not appear in the given timeout; " +
 						"returning headers pulled so far",
 				)
 				return headers
 			}
 
-			logger.Debugf(
+			r.logger.Debugf(
 				"new header did not appear in the given timeout; " +
 					"resetting timer as no headers have been pulled so far",
 			)
@@ -70,22 +95,24 @@ func (r *Relay) pushHeadersToHostChain(
 		return nil
 	}
 
+	batchLogger := correlation.InjectedLogger(ctx, r.logger)
+
 	startMod := headers[0].Height % r.difficultyEpochDuration
 	endMod := headers[len(headers)-1].Height % r.difficultyEpochDuration
 
 	if startMod == 0 {
 		// we have a difficulty change first
-		logger.Info(
+		batchLogger.Infof(
 			"adding all headers with retarget as there is a difficulty " +
 				"change at the beginning of headers batch",
 		)
 
-		if err := r.addHeadersWithRetarget(headers); err != nil {
+		if err := r.addHeadersWithRetarget(ctx, headers); err != nil {
 			return fmt.Errorf("could not add headers with retarget: [%v]", err)
 		}
 	} else if startMod > endMod {
 		// we span a difficulty change
-		logger.Info(
+		batchLogger.Infof(
 			"adding some headers with retarget as there is a difficulty " +
 				"change in the middle of headers batch",
 		)
@@ -93,13 +120,16 @@ func (r *Relay) pushHeadersToHostChain(
 		preChangeHeaders, postChangeHeaders := r.splitBatch(headers, startMod)
 
 		if len(preChangeHeaders) > 0 {
-			if err := r.addHeaders(preChangeHeaders); err != nil {
+			if err := r.addHeaders(ctx, preChangeHeaders); err != nil {
 				return fmt.Errorf("could not add headers: [%v]", err)
 			}
 		}
 
 		if len(postChangeHeaders) > 0 {
-			if err := r.addHeadersWithRetarget(postChangeHeaders); err != nil {
+			if err := r.addHeadersWithRetarget(
+				ctx,
+				postChangeHeaders,
+			); err != nil {
 				return fmt.Errorf(
 					"could not add headers with retarget: [%v]",
 					err,
@@ -108,18 +138,19 @@ func (r *Relay) pushHeadersToHostChain(
 		}
 	} else {
 		// no difficulty change
-		logger.Info(
+		batchLogger.Infof(
 			"adding all headers without retarget as there is no " +
 				"difficulty change within headers batch",
 		)
 
-		if err := r.addHeaders(headers); err != nil {
+		if err := r.addHeaders(ctx, headers); err != nil {
 			return fmt.Errorf("could not add headers: [%v]", err)
 		}
 	}
 
 	r.processedHeaders += len(headers)
-	if r.processedHeaders >= headersBatchSize {
+	if r.processedHeaders >= r.control.batchSize() &&
+		!r.deferBestHeaderUpdate(ctx) {
 		newBestHeader := headers[len(headers)-1]
 
 		if err := r.updateBestHeader(ctx, newBestHeader); err != nil {
@@ -132,10 +163,8 @@ func (r *Relay) pushHeadersToHostChain(
 	return nil
 }
 
-func (r *Relay) addHeaders(headers []*btc.Header) error {
-	anchorDigest := headers[0].PrevHash
-
-	anchorHeader, err := r.btcChain.GetHeaderByDigest(anchorDigest)
+func (r *Relay) addHeaders(ctx context.Context, headers []*btc.Header) error {
+	anchorHeader, err := r.getAnchorHeader(ctx, headers[0])
 	if err != nil {
 		return fmt.Errorf(
 			"could not get anchor header by digest: [%v]",
@@ -143,14 +172,23 @@ func (r *Relay) addHeaders(headers []*btc.Header) error {
 		)
 	}
 
-	return r.hostChain.AddHeaders(anchorHeader.Raw, packHeaders(headers))
+	return r.submitBatch(ctx, headers, func() error {
+		return r.hostChain.AddHeaders(
+			ctx,
+			anchorHeader.Raw,
+			packHeaders(headers),
+		)
+	})
 }
 
-func (r *Relay) addHeadersWithRetarget(headers []*btc.Header) error {
+func (r *Relay) addHeadersWithRetarget(
+	ctx context.Context,
+	headers []*btc.Header,
+) error {
 	epochStart := headers[0].Height - r.difficultyEpochDuration
 	epochEnd := epochStart + r.difficultyEpochDuration - 1
 
-	oldPeriodStartHeader, err := r.btcChain.GetHeaderByHeight(epochStart)
+	oldPeriodStartHeader, err := r.btcChain.GetHeaderByHeight(ctx, epochStart)
 	if err != nil {
 		return fmt.Errorf(
 			"could not get header by height [%v]: [%v]",
@@ -159,7 +197,7 @@ func (r *Relay) addHeadersWithRetarget(headers []*btc.Header) error {
 		)
 	}
 
-	oldPeriodEndHeader, err := r.btcChain.GetHeaderByHeight(epochEnd)
+	oldPeriodEndHeader, err := r.btcChain.GetHeaderByHeight(ctx, epochEnd)
 	if err != nil {
 		return fmt.Errorf(
 			"could not get header by height [%v]: [%v]",
@@ -168,30 +206,42 @@ func (r *Relay) addHeadersWithRetarget(headers []*btc.Header) error {
 		)
 	}
 
-	return r.hostChain.AddHeadersWithRetarget(
-		oldPeriodStartHeader.Raw,
-		oldPeriodEndHeader.Raw,
-		packHeaders(headers),
-	)
+	if err := r.submitBatch(ctx, headers, func() error {
+		return r.hostChain.AddHeadersWithRetarget(
+			ctx,
+			oldPeriodStartHeader.Raw,
+			oldPeriodEndHeader.Raw,
+			packHeaders(headers),
+		)
+	}); err != nil {
+		return err
+	}
+
+	r.notifyRetargetSubmitted(ctx, headers[0])
+
+	return nil
 }
 
 func (r *Relay) updateBestHeader(
 	ctx context.Context,
 	newBestHeader *btc.Header,
 ) error {
+	batchLogger := correlation.InjectedLogger(ctx, r.logger)
+
 	for attempt := 1; attempt <= updateBestHeaderMaxAttempts; attempt++ {
-		logger.Infof(
+		batchLogger.Infof(
 			"attempt [%v] to set header [%v] as new best",
 			attempt,
 			newBestHeader.Height,
 		)
 
-		currentBestDigest, err := r.hostChain.GetBestKnownDigest()
+		currentBestDigest, err := r.hostChain.GetBestKnownDigest(ctx)
 		if err != nil {
 			return fmt.Errorf("could not get best known digest: [%v]", err)
 		}
 
 		currentBestHeader, err := r.btcChain.GetHeaderByDigest(
+			ctx,
 			currentBestDigest,
 		)
 		if err != nil {
@@ -210,27 +260,70 @@ func (r *Relay) updateBestHeader(
 			return fmt.Errorf("could not find last common ancestor: [%v]", err)
 		}
 
+		// Compare the branches by their chainwork rather than by their
+		// heights as a shorter branch can be heavier across difficulty
+		// changes. If the chainwork cannot be determined, the decision is
+		// left to the host chain.
+		heavier, err := r.isHeavierBranch(
+			ctx,
+			newBestHeader,
+			currentBestHeader,
+			lastCommonAncestor,
+		)
+		if err != nil {
+			batchLogger.Warnf(
+				"could not compare chainwork of header [%v] and current "+
+					"best header [%v]: [%v]",
+				newBestHeader.Height,
+				currentBestHeader.Height,
+				err,
+			)
+		} else if !heavier {
+			batchLogger.Infof(
+				"header [%v] is not heavier than current best header [%v]; "+
+					"not setting it as new best",
+				newBestHeader.Height,
+				currentBestHeader.Height,
+			)
+			return nil
+		}
+
 		limit := big.NewInt(
 			newBestHeader.Height - lastCommonAncestor.Height + 1,
 		)
 
 		if willSucceed := r.hostChain.MarkNewHeaviestPreflight(
+			ctx,
 			lastCommonAncestor.Hash,
 			currentBestHeader.Raw,
 			newBestHeader.Raw,
 			limit,
 		); willSucceed {
-			return r.hostChain.MarkNewHeaviest(
+			if err := r.hostChain.MarkNewHeaviest(
+				ctx,
 				lastCommonAncestor.Hash,
 				currentBestHeader.Raw,
 				newBestHeader.Raw,
 				limit,
-			)
+			); err != nil {
+				return err
+			}
+
+			if lastCommonAncestor.Hash != currentBestHeader.Hash {
+				r.recordReorg(
+					ctx,
+					lastCommonAncestor,
+					currentBestHeader,
+					newBestHeader,
+				)
+			}
+
+			return nil
 		}
 
 		// wait a constant back-off time
 		select {
-		case <-time.After(updateBestHeaderBackoffTime):
+		case <-r.timeSource().After(updateBestHeaderBackoffTime):
 		case <-ctx.Done():
 			return ctx.Err()
 		}
@@ -250,8 +343,10 @@ func (r *Relay) findLastCommonAncestor(
 ) (*btc.Header, error) {
 	totalAttempts := 10
 
+	batchLogger := correlation.InjectedLogger(ctx, r.logger)
+
 	for attempt := 1; attempt <= totalAttempts; attempt++ {
-		logger.Infof(
+		batchLogger.Infof(
 			"attempt [%v] to find LCA in in previous 20 headers",
 			attempt,
 		)
@@ -266,6 +361,7 @@ func (r *Relay) findLastCommonAncestor(
 			}
 
 			isAncestor, err := r.hostChain.IsAncestor(
+				ctx,
 				ancestorHeader.Hash,
 				newBestHeader.Hash,
 				big.NewInt(240), // default value used in legacy relay
@@ -279,6 +375,7 @@ func (r *Relay) findLastCommonAncestor(
 			}
 
 			ancestorHeader, err = r.btcChain.GetHeaderByDigest(
+				ctx,
 				ancestorHeader.PrevHash,
 			)
 			if err != nil {
@@ -291,7 +388,7 @@ func (r *Relay) findLastCommonAncestor(
 
 		// wait a constant back-off time
 		select {
-		case <-time.After(30 * time.Second):
+		case <-r.timeSource().After(30 * time.Second):
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
@@ -323,7 +420,7 @@ func (r *Relay) splitBatch(headers []*btc.Header, startMod int64) (
 		} else if header.Height%r.difficultyEpochDuration < startMod {
 			postChangeHeaders = append(postChangeHeaders, header)
 		} else {
-			logger.Errorf(
+			r.logger.Errorf(
 				"could not assign header [%v] to pre/post-change "+
 					"part where start mod is [%v]",
 				header,
@@ -11,6 +11,8 @@ import (
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/store"
 )
 
 func TestGetHeadersFromQueue(t *testing.T) {
@@ -18,6 +20,7 @@ func TestGetHeadersFromQueue(t *testing.T) {
 	defer cancelCtx()
 
 	relay := &Relay{
+		logger:       testLogger,
 		headersQueue: make(chan *btc.Header, headersQueueSize),
 	}
 
@@ -92,6 +95,47 @@ func TestGetHeadersFromQueue(t *testing.T) {
 	}
 }
 
+func TestGetHeadersFromQueue_HeaderTimeout(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+	relay := &Relay{
+		logger:       testLogger,
+		clock:        fakeClock,
+		headersQueue: make(chan *btc.Header, headersQueueSize),
+	}
+
+	relay.headersQueue <- &btc.Header{Height: 0}
+	relay.headersQueue <- &btc.Header{Height: 1}
+
+	result := make(chan []*btc.Header, 1)
+	go func() {
+		result <- relay.getHeadersFromQueue(context.Background())
+	}()
+
+	// Headers are taken from the queue concurrently, so keep advancing the
+	// clock until the timeout following the last header is hit.
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case headers := <-result:
+			if len(headers) != 2 {
+				t.Errorf(
+					"unexpected number of headers:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					2,
+					len(headers),
+				)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			fakeClock.Advance(headerTimeout)
+		case <-deadline:
+			t.Fatal("headers should be returned once the timeout is hit")
+		}
+	}
+}
+
 func TestPushHeadersToHostChain_NoDifficultyChange(t *testing.T) {
 	ctx, cancelCtx := context.WithCancel(context.Background())
 	defer cancelCtx()
@@ -118,9 +162,12 @@ func TestPushHeadersToHostChain_NoDifficultyChange(t *testing.T) {
 	localChain := lc.(*chainlocal.Chain)
 
 	relay := &Relay{
+		logger:                  testLogger,
 		btcChain:                btcChain,
 		hostChain:               localChain,
+		store:                   store.OpenMemory(),
 		difficultyEpochDuration: btcDifficultyEpochDuration,
+		observer:                &mockObserver{},
 	}
 
 	headers := []*btc.Header{
@@ -215,9 +262,12 @@ func TestPushHeadersToHostChain_NoDifficultyChange_WithUpdateBestHeader(
 	localChain.SetBestKnownDigest([32]byte{1})
 
 	relay := &Relay{
+		logger:                  testLogger,
 		btcChain:                btcChain,
 		hostChain:               localChain,
+		store:                   store.OpenMemory(),
 		difficultyEpochDuration: btcDifficultyEpochDuration,
+		observer:                &mockObserver{},
 	}
 
 	headers := []*btc.Header{
@@ -325,9 +375,12 @@ func TestPushHeadersToHostChain_DifficultyChangeAtBeginning(t *testing.T) {
 	localChain := lc.(*chainlocal.Chain)
 
 	relay := &Relay{
+		logger:                  testLogger,
 		btcChain:                btcChain,
 		hostChain:               localChain,
+		store:                   store.OpenMemory(),
 		difficultyEpochDuration: btcDifficultyEpochDuration,
+		observer:                &mockObserver{},
 	}
 
 	headers := []*btc.Header{
@@ -422,9 +475,12 @@ func TestPushHeadersToHostChain_DifficultyChangeInMiddle(t *testing.T) {
 	localChain := lc.(*chainlocal.Chain)
 
 	relay := &Relay{
+		logger:                  testLogger,
 		btcChain:                btcChain,
 		hostChain:               localChain,
+		store:                   store.OpenMemory(),
 		difficultyEpochDuration: btcDifficultyEpochDuration,
+		observer:                &mockObserver{},
 	}
 
 	headers := []*btc.Header{
@@ -522,6 +578,16 @@ func TestPushHeadersToHostChain_DifficultyChangeInMiddle(t *testing.T) {
 	}
 }
 
+func BenchmarkPackHeaders(b *testing.B) {
+	headers := benchHeaders(headersBatchSize)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		packHeaders(headers)
+	}
+}
+
 func toBytes(values ...int) []byte {
 	result := make([]byte, 0)
 
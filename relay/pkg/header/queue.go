@@ -0,0 +1,123 @@
+package header
+
+import (
+	"context"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// queue.go file contains the persistence of the headers queue. Once the relay
+// stops, headers pulled from the Bitcoin chain but not pushed yet are saved
+// to the queue store. The next relay instance restores them instead of
+// fetching them from the Bitcoin node again, which matters during a large
+// catch-up. Restored headers are used only if they continue the best header
+// known by the host chain and are still on the best chain of the Bitcoin node.
+
+// QueueStore persists the headers queue across relay restarts.
+type QueueStore interface {
+	// SaveQueue replaces the persisted queue with the given headers.
+	SaveQueue(headers []*btc.Header) error
+
+	// LoadQueue returns the persisted queue in the queue order.
+	LoadQueue() ([]*btc.Header, error)
+}
+
+// spillQueue saves the batch which has not been pushed yet and headers left
+// in the headers queue to the queue store. Must be called once the pulling
+// and pushing loops are stopped.
+func (r *Relay) spillQueue() {
+	if r.queueStore == nil {
+		return
+	}
+
+	headers := make([]*btc.Header, 0, len(r.unpushedBatch)+len(r.headersQueue))
+	headers = append(headers, r.unpushedBatch...)
+	for len(r.headersQueue) > 0 {
+		headers = append(headers, <-r.headersQueue)
+	}
+
+	if err := r.queueStore.SaveQueue(headers); err != nil {
+		r.logger.Errorf("could not persist headers queue: [%v]", err)
+		return
+	}
+
+	if len(headers) > 0 {
+		r.logger.Infof("persisted queue of %v", headersSummary(headers))
+	}
+}
+
+// restoreQueue puts headers persisted by the previous relay instance into
+// the headers queue, provided they directly follow the given best header.
+// The previous instance must be stopped before, otherwise the headers it
+// spills afterwards are lost or restored twice.
+func (r *Relay) restoreQueue(ctx context.Context, bestHeader *btc.Header) {
+	if r.queueStore == nil {
+		return
+	}
+
+	headers, err := r.queueStore.LoadQueue()
+	if err != nil {
+		r.logger.Warnf("could not load persisted headers queue: [%v]", err)
+		return
+	}
+
+	restored := make([]*btc.Header, 0, len(headers))
+	previous := bestHeader
+	for _, header := range headers {
+		if header.Height <= bestHeader.Height {
+			// Already known by the host chain.
+			continue
+		}
+
+		if header.Height != previous.Height+1 ||
+			header.PrevHash != previous.Hash {
+			break
+		}
+
+		restored = append(restored, header)
+		previous = header
+	}
+
+	if len(restored) == 0 {
+		if len(headers) > 0 {
+			r.logger.Infof(
+				"persisted headers queue does not follow the best header "+
+					"[%v]; ignoring it",
+				bestHeader.Height,
+			)
+		}
+		return
+	}
+
+	// All restored headers are linked, so the whole sequence is on the best
+	// chain of the Bitcoin node if the last header is.
+	last := restored[len(restored)-1]
+	nodeHeader, err := r.btcChain.GetHeaderByHeight(ctx, last.Height)
+	if err != nil {
+		r.logger.Warnf(
+			"could not get header [%v] to verify persisted headers "+
+				"queue: [%v]",
+			last.Height,
+			err,
+		)
+		return
+	}
+
+	if nodeHeader.Hash != last.Hash {
+		r.logger.Warnf(
+			"persisted headers queue is not on the best chain of the " +
+				"Bitcoin node; ignoring it",
+		)
+		return
+	}
+
+	r.logger.Infof("restoring persisted queue of %v", headersSummary(restored))
+
+	for _, header := range restored {
+		if err := r.chainwork.observe(header); err != nil {
+			r.logger.Warnf("could not track chainwork: [%v]", err)
+		}
+
+		r.putHeaderToQueue(ctx, header)
+	}
+}
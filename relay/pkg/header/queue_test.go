@@ -0,0 +1,158 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+type mockQueueStore struct {
+	headers []*btc.Header
+}
+
+func (mqs *mockQueueStore) SaveQueue(headers []*btc.Header) error {
+	mqs.headers = headers
+	return nil
+}
+
+func (mqs *mockQueueStore) LoadQueue() ([]*btc.Header, error) {
+	return mqs.headers, nil
+}
+
+func linkedHeaders(from, to int) []*btc.Header {
+	headers := make([]*btc.Header, 0)
+	for height := from; height <= to; height++ {
+		headers = append(headers, &btc.Header{
+			Height:   int64(height),
+			Hash:     to32Bytes(height),
+			PrevHash: to32Bytes(height - 1),
+		})
+	}
+	return headers
+}
+
+func TestRestoreQueue(t *testing.T) {
+	chainHeaders := linkedHeaders(1, 10)
+
+	forkedHeader := &btc.Header{
+		Height:   8,
+		Hash:     to32Bytes(108),
+		PrevHash: to32Bytes(7),
+	}
+
+	var tests = map[string]struct {
+		persisted       []*btc.Header
+		expectedHeights []int64
+	}{
+		"queue following best header": {
+			persisted:       linkedHeaders(6, 9),
+			expectedHeights: []int64{6, 7, 8, 9},
+		},
+		"queue partially pushed already": {
+			persisted:       linkedHeaders(3, 7),
+			expectedHeights: []int64{6, 7},
+		},
+		"queue with a gap": {
+			persisted: append(
+				linkedHeaders(6, 7),
+				linkedHeaders(9, 10)...,
+			),
+			expectedHeights: []int64{6, 7},
+		},
+		"queue not following best header": {
+			persisted:       linkedHeaders(7, 9),
+			expectedHeights: []int64{},
+		},
+		"queue not on the best chain": {
+			persisted:       append(linkedHeaders(6, 7), forkedHeader),
+			expectedHeights: []int64{},
+		},
+		"empty queue": {
+			persisted:       []*btc.Header{},
+			expectedHeights: []int64{},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			btcChain := bc.(*btc.LocalChain)
+			btcChain.SetHeaders(chainHeaders)
+
+			relay := &Relay{
+				logger:               testLogger,
+				btcChain:             btcChain,
+				queueStore:           &mockQueueStore{headers: test.persisted},
+				headersQueue:         make(chan *btc.Header, headersQueueSize),
+				nextPullHeaderHeight: 6,
+			}
+
+			relay.restoreQueue(context.Background(), chainHeaders[4])
+
+			heights := make([]int64, 0)
+			for len(relay.headersQueue) > 0 {
+				heights = append(heights, (<-relay.headersQueue).Height)
+			}
+
+			if !reflect.DeepEqual(test.expectedHeights, heights) {
+				t.Errorf(
+					"unexpected restored headers:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedHeights,
+					heights,
+				)
+			}
+
+			expectedNextPullHeight := int64(6 + len(test.expectedHeights))
+			if expectedNextPullHeight != relay.nextPullHeaderHeight {
+				t.Errorf(
+					"unexpected next pull header height:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedNextPullHeight,
+					relay.nextPullHeaderHeight,
+				)
+			}
+		})
+	}
+}
+
+func TestSpillQueue(t *testing.T) {
+	headers := linkedHeaders(1, 7)
+
+	queueStore := &mockQueueStore{}
+
+	relay := &Relay{
+		logger:        testLogger,
+		queueStore:    queueStore,
+		unpushedBatch: headers[:3],
+		headersQueue:  make(chan *btc.Header, headersQueueSize),
+	}
+
+	for _, header := range headers[3:] {
+		relay.headersQueue <- header
+	}
+
+	relay.spillQueue()
+
+	if !reflect.DeepEqual(headers, queueStore.headers) {
+		t.Errorf(
+			"unexpected persisted queue:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			headers,
+			queueStore.headers,
+		)
+	}
+
+	if len(relay.headersQueue) != 0 {
+		t.Errorf("headers queue should be drained")
+	}
+}
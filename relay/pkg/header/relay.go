@@ -3,11 +3,15 @@ package header
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
-	"github.com/ipfs/go-log"
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/store"
 )
 
 const (
@@ -40,18 +44,352 @@ const (
 	// Back-off time which should be applied between updating best header
 	// attempts.
 	updateBestHeaderBackoffTime = 30 * time.Second
+
+	// Tick of the relay lag monitoring loop.
+	relayLagMonitoringTick = 60 * time.Second
+
+	// Default relay lag expressed in blocks, above which a warning is logged.
+	defaultRelayLagWarningThreshold = 6
+
+	// Default relay lag expressed in blocks, above which pushes are never
+	// deferred by the push schedule.
+	defaultMaxDeferralLag = 12
+
+	// Interval in which the deferred push is re-checked against the
+	// push schedule.
+	pushDeferralCheckInterval = 60 * time.Second
+
+	// Default number of host chain blocks after which a pushed batch is
+	// considered final.
+	defaultFinalityDepth = 12
+
+	// Interval in which a journaled batch whose submission may still be
+	// pending is re-checked against the host chain state.
+	journaledBatchCheckInterval = 30 * time.Second
+
+	// Tick of the finality monitoring loop.
+	finalityMonitoringTick = 30 * time.Second
+
+	// Time after which a retarget for the same epoch can be resubmitted if
+	// the host chain still does not know it.
+	retargetResubmissionTimeout = 30 * time.Minute
+
+	// Tick of the difficulty epoch monitoring loop.
+	epochMonitoringTick = 60 * time.Second
+
+	// Default number of Bitcoin blocks before a retarget at which operators
+	// are notified that the difficulty epoch ends.
+	defaultEpochEndNoticeBlocks = 10
+
+	// Default number of Bitcoin blocks not pushed yet, above which the relay
+	// enters the catch-up phase.
+	defaultCatchUpLagThreshold = 24
+
+	// Default maximum number of batches submitted during the catch-up phase
+	// which can be not yet known by the host chain at the same time.
+	defaultMaxPendingBatches = 3
+
+	// Default maximum number of host chain transactions of the operator
+	// which can be pending at the same time during the catch-up phase.
+	defaultMaxInFlightTransactions = 3
+
+	// Interval in which the number of pending batches is re-checked during
+	// the catch-up phase.
+	pendingBatchesCheckInterval = 15 * time.Second
+
+	// Default maximum number of batches the pulling loop can get ahead of
+	// the pushing loop.
+	defaultMaxBatchesAhead = 3
+
+	// Tick of the fork monitoring loop.
+	forkMonitoringTick = 30 * time.Second
+
+	// Interval in which the memory usage is re-checked while pulling is
+	// paused under memory pressure.
+	memoryPressureCheckInterval = 15 * time.Second
+
+	// Default number of blocks below the Bitcoin chain tip within which
+	// competing forks are monitored.
+	defaultForkMonitoringDepth = 6
+)
+
+const (
+	// ModeFull is the relay mode in which all headers are pushed to the
+	// host chain.
+	ModeFull = "full"
+
+	// ModeRetargetOnly is the relay mode in which only difficulty retarget
+	// proofs are submitted to the host chain. This mode should be used
+	// with the tBTC v2 LightRelay contract.
+	ModeRetargetOnly = "retarget-only"
+)
+
+const (
+	// HeaderValidationEnforce makes the relay stop once a pulled header
+	// breaks contextual validation rules.
+	HeaderValidationEnforce = "enforce"
+
+	// HeaderValidationWarn makes the relay log contextual validation rules
+	// violations but relay the affected headers anyway.
+	HeaderValidationWarn = "warn"
+
+	// HeaderValidationOff disables the contextual validation of pulled
+	// headers.
+	HeaderValidationOff = "off"
 )
 
-var logger = log.Logger("tbtc-relay-header")
+// Name of the default logger of the headers relay.
+const loggerName = "tbtc-relay-header"
+
+// Config holds the configuration of the headers relay.
+type Config struct {
+	// Mode determines which headers are submitted to the host chain.
+	// Supported values are `full` (default) and `retarget-only`.
+	Mode string
+
+	// WatchOnly determines whether the relay should run in the watch-only
+	// mode. In that mode, the relay pulls headers and observes the state of
+	// the host chain but never submits any transactions. This mode is
+	// enabled automatically if no operator key is configured.
+	WatchOnly bool
+
+	// LagWarningThreshold is the relay lag, expressed in blocks, above which
+	// the relay logs a warning. If zero, a default value is used.
+	LagWarningThreshold int64
+
+	// GasPriceCeiling is the host chain gas price, expressed in Gwei, above
+	// which pushes are deferred. If zero, pushes are never deferred due to
+	// the gas price.
+	GasPriceCeiling int64
+
+	// PushWindows is a list of daily UTC time windows in the HH:MM-HH:MM
+	// format during which pushes are allowed. Pushes attempted outside all
+	// windows are deferred. If empty, pushes are allowed at any time.
+	PushWindows []string
+
+	// MaxDeferralLag is the relay lag, expressed in blocks, above which
+	// pushes are never deferred, regardless of the gas price and push
+	// windows. If zero, a default value is used.
+	MaxDeferralLag int64
+
+	// FinalityDepth is the number of host chain blocks after which a pushed
+	// batch is considered final and the persistent checkpoint is advanced.
+	// If zero, a default value is used.
+	FinalityDepth uint64
+
+	// HeaderValidation determines how violations of contextual validation
+	// rules by pulled headers are handled. Supported values are `enforce`
+	// (default), `warn` and `off`.
+	HeaderValidation string
+
+	// CatchUpLagThreshold is the number of Bitcoin blocks not pushed yet,
+	// above which the relay enters the catch-up phase. In that phase, the
+	// relay does not rest between pushes until the number of blocks not
+	// pushed yet drops below the threshold. If zero, a default value is
+	// used.
+	CatchUpLagThreshold int64
+
+	// MaxPendingBatches is the maximum number of batches submitted during
+	// the catch-up phase which can be not yet known by the host chain at the
+	// same time. If zero, a default value is used.
+	MaxPendingBatches int
+
+	// MaxInFlightTransactions is the maximum number of host chain
+	// transactions of the operator, as tracked by the transaction monitor,
+	// which can be pending at the same time during the catch-up phase. Up to
+	// that many batches are pushed before a new best header is marked. If
+	// zero, a default value is used.
+	MaxInFlightTransactions int
+
+	// MaxBatchesAhead is the maximum number of batches pulled from the
+	// Bitcoin chain which can wait in the headers queue for the pushing
+	// loop. Once it is reached, the pulling loop waits until the pushing
+	// loop catches up. If zero, a default value is used.
+	MaxBatchesAhead int
+
+	// PushDeadline is the maximum time, in seconds, a pushed batch can stay
+	// unknown by the host chain. Once it passes, the pending transactions
+	// are cancelled and the batch is rebuilt and resubmitted. If zero,
+	// pushes are never abandoned.
+	PushDeadline int
+
+	// Checkpoints is a list of additional headers known to be part of the
+	// Bitcoin chain in the `height:digest` format. Together with the
+	// checkpoints built into the mainnet and testnet parameters, they must
+	// be passed through by the relayed chain.
+	Checkpoints []string
+
+	// ProfitabilityGate determines whether pushes are deferred while their
+	// estimated cost exceeds the expected reward by more than the
+	// profitability margin. Pushes are never deferred once the relay lag
+	// reaches MaxDeferralLag.
+	ProfitabilityGate bool
+
+	// ProfitabilityMargin is the margin, in percent of the expected reward,
+	// by which the estimated cost of a push can exceed the expected reward
+	// before the push is deferred.
+	ProfitabilityMargin int64
+
+	// ForkMonitoring determines whether competing Bitcoin forks near the
+	// chain tip are monitored. While a competing fork exists, headers of the
+	// active chain above the fork point are held back until the fork is
+	// resolved.
+	ForkMonitoring bool
+
+	// ForkMonitoringDepth is the number of blocks below the Bitcoin chain
+	// tip within which competing forks are monitored. Forks whose tip falls
+	// deeper are considered resolved. If zero, a default value is used.
+	ForkMonitoringDepth int64
+
+	// MaxCachedHeaders is the maximum number of headers whose chainwork is
+	// kept in memory. Once it is reached, the tracking starts over. If
+	// zero, a default value is used.
+	MaxCachedHeaders int
+
+	// MemoryLimit is the heap size, in MiB, above which the relay pauses
+	// pulling headers until the memory is freed. Pushing continues, so the
+	// headers queue drains in the meantime. If zero, pulling is never
+	// paused due to the memory usage.
+	MemoryLimit int
+
+	// EpochEndNoticeBlocks is the number of Bitcoin blocks before a retarget
+	// at which the relay notifies operators that the difficulty epoch ends,
+	// so they can watch the retarget submission closely. If zero, a default
+	// value is used.
+	EpochEndNoticeBlocks int64
+
+	// Schedules maps the relay task names to the schedules overriding the
+	// default intervals of the tasks. See ParseSchedule for the supported
+	// schedule formats.
+	Schedules map[string]string
+
+	// WatchdogTimeout is the time, in seconds, for which the pulling or
+	// pushing loop can stay busy without reaching any of its waits. A loop
+	// busy for longer is considered stuck. If zero, the loops are not
+	// monitored.
+	WatchdogTimeout int
+
+	// WatchdogAction determines what happens once a stuck loop is detected.
+	// Supported values are `restart` (default), restarting the relay, and
+	// `crash`, crashing the process.
+	WatchdogAction string
+
+	// BatchStrategy determines how pulled headers are split into batches.
+	// Supported values are `fixed` (default) and `aligned`, ending batches
+	// at heights being multiples of BatchAlignmentInterval to reduce the
+	// storage reads of the summa-v1 relay contract.
+	BatchStrategy string
+
+	// BatchAlignmentInterval is the height interval to which the ends of
+	// batches are aligned in the `aligned` batch strategy. If zero, the
+	// height interval of the summa-v1 relay contract is used.
+	BatchAlignmentInterval int64
+
+	// SkipInterval enables the sparse mode if greater than one. In that
+	// mode, only headers at heights being multiples of the interval and the
+	// headers at difficulty epoch boundaries are pushed. It requires a relay
+	// contract accepting non-contiguous headers.
+	SkipInterval int64
+}
+
+// Validate checks whether the headers relay configuration is correct.
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", ModeFull, ModeRetargetOnly:
+	default:
+		return fmt.Errorf("unknown relay mode [%v]", c.Mode)
+	}
+
+	switch c.HeaderValidation {
+	case "", HeaderValidationEnforce, HeaderValidationWarn, HeaderValidationOff:
+	default:
+		return fmt.Errorf(
+			"unknown header validation mode [%v]",
+			c.HeaderValidation,
+		)
+	}
+
+	if _, err := btc.ParseCheckpoints(c.Checkpoints); err != nil {
+		return fmt.Errorf("invalid checkpoints: [%v]", err)
+	}
+
+	if c.MaxBatchesAhead > headersQueueSize/headersBatchSize {
+		return fmt.Errorf(
+			"max batches ahead [%v] exceeds the headers queue capacity of "+
+				"[%v] batches",
+			c.MaxBatchesAhead,
+			headersQueueSize/headersBatchSize,
+		)
+	}
+
+	switch c.WatchdogAction {
+	case "", WatchdogRestart, WatchdogCrash:
+	default:
+		return fmt.Errorf("unknown watchdog action [%v]", c.WatchdogAction)
+	}
+
+	switch c.BatchStrategy {
+	case "", BatchStrategyFixed, BatchStrategyAligned:
+	default:
+		return fmt.Errorf("unknown batch strategy [%v]", c.BatchStrategy)
+	}
+
+	if c.SkipInterval > 1 && c.BatchStrategy == BatchStrategyAligned {
+		return fmt.Errorf(
+			"aligned batch strategy cannot be used in the sparse mode",
+		)
+	}
+
+	if c.BatchAlignmentInterval > headersBatchSize {
+		return fmt.Errorf(
+			"batch alignment interval [%v] exceeds the batch size [%v]",
+			c.BatchAlignmentInterval,
+			headersBatchSize,
+		)
+	}
+
+	if err := validateSchedules(c.Schedules); err != nil {
+		return err
+	}
+
+	_, err := newPushSchedule(c)
+	return err
+}
 
 // RelayObserver represents an observer of headers relay events.
 type RelayObserver interface {
 	// NotifyHeaderPulled notifies about new header pulled from the
 	// Bitcoin chain.
-	NotifyHeaderPulled(headerHeight int64)
+	NotifyHeaderPulled(header *btc.Header)
 
 	// NotifyHeadersPushed notifies about new headers pushed to the host chain.
-	NotifyHeadersPushed(headersHeights []int64)
+	NotifyHeadersPushed(headers []*btc.Header)
+
+	// NotifyHeadersConfirmed notifies about pushed headers which got known
+	// by the host chain at the given time, i.e. their transaction was mined.
+	NotifyHeadersConfirmed(headers []*btc.Header, confirmedAt time.Time)
+
+	// NotifyRelayLag notifies about the current relay lag, i.e. the number
+	// of Bitcoin blocks which are not yet known by the host chain.
+	NotifyRelayLag(lag int64)
+
+	// NotifyForks notifies about the number of competing Bitcoin forks near
+	// the chain tip and the branch length of the longest one.
+	NotifyForks(forks int, length int64)
+
+	// NotifyRetargetSubmitted notifies about a retarget to the given
+	// difficulty epoch submitted to the host chain. The header is the first
+	// header of the epoch.
+	NotifyRetargetSubmitted(epoch uint64, header *btc.Header)
+
+	// NotifyEpochEndApproaching notifies that the Bitcoin chain tip, passed
+	// as the header, is within the configured number of blocks before the
+	// retarget to the given difficulty epoch.
+	NotifyEpochEndApproaching(epoch uint64, header *btc.Header)
+
+	// NotifyReorg notifies about the given reorg recorded by the relay. The
+	// header is the new best header.
+	NotifyReorg(header *btc.Header, reorg *store.Reorg)
 }
 
 // Relay takes headers from the Bitcoin chain and relays them to the
@@ -59,9 +397,47 @@ type RelayObserver interface {
 type Relay struct {
 	btcChain  btc.Handle
 	hostChain chain.Handle
+	store     *store.Store
+	control   *Control
+	clock     clock.Clock
+	logger    logs.Logger
 
 	difficultyEpochDuration int64
 
+	watchOnly           bool
+	lagWarningThreshold int64
+	pushSchedule        *pushSchedule
+	profitabilityGate   *profitabilityGate
+	finalityDepth       uint64
+	finalityTracker     *finalityTracker
+	headerValidation    string
+	headerValidator     *btc.HeaderValidator
+	checkpointVerifier  *btc.CheckpointVerifier
+	chainwork           chainworkTracker
+	memoryLimit         uint64
+	heapSize            func() uint64
+	catchUpLagThreshold int64
+	maxPendingBatches   int
+	maxInFlight         int
+	maxPullAhead        int
+	batchAlignment      int64
+	skipInterval        int64
+	sparseStartHeight   int64
+	throughput          pushThroughput
+	catchingUp          bool
+	pushDeadline        time.Duration
+	forks               *forkMonitor
+	watchdog            *watchdog
+	watchdogTimeout     time.Duration
+	watchdogAction      string
+
+	lastRetargetEpoch       uint64
+	lastRetargetTime        time.Time
+	lastPulledRetargetEpoch uint64
+
+	epochEndNoticeBlocks int64
+	lastEpochEndNotice   uint64
+
 	pullingSleepTime time.Duration
 	pushingSleepTime time.Duration
 
@@ -70,28 +446,58 @@ type Relay struct {
 	lastPulledHeader     *btc.Header
 
 	headersQueue chan *btc.Header
+	batchTaken   chan struct{}
 	errChan      chan error
 
+	pipeline      *headerPipeline
+	sink          Sink
+	queueStore    QueueStore
+	unpushedBatch []*btc.Header
+	stopped       chan struct{}
+
+	// pullIDs holds the correlation IDs the queued headers were pulled with,
+	// keyed by the header digest.
+	pullIDs sync.Map
+
 	observer RelayObserver
 }
 
 // StartRelay creates an instance of the headers relay and runs its
 // processing loops. The lifecycle of the relay can be managed using the
 // passed context. The relay exits automatically once an error occurs.
+// The profitability estimator, the queue store and the pipeline
+// customizations are optional and can be nil. A relay sharing the queue store
+// with a previous instance must not be started before the previous instance
+// is stopped, see Stopped. Messages are logged to the given logger or, if it
+// is nil, to the default logger.
 func StartRelay(
 	ctx context.Context,
 	btcChain btc.Handle,
 	hostChain chain.Handle,
+	relayStore *store.Store,
+	config *Config,
+	control *Control,
 	observer RelayObserver,
+	profitability ProfitabilityEstimator,
+	queueStore QueueStore,
+	pipeline *Pipeline,
+	logger logs.Logger,
 ) *Relay {
 	return startRelay(
 		ctx,
 		btcChain,
 		hostChain,
+		relayStore,
+		config,
+		control,
 		btcDifficultyEpochDuration,
 		relayPullingSleepTime,
 		relayPushingSleepTime,
 		observer,
+		profitability,
+		queueStore,
+		pipeline,
+		logger,
 	)
 }
 
@@ -99,42 +505,206 @@ func startRelay(
 	ctx context.Context,
 	btcChain btc.Handle,
 	hostChain chain.Handle,
+	relayStore *store.Store,
+	config *Config,
+	control *Control,
 	difficultyEpochDuration int64,
 	pullingSleepTime time.Duration,
 	pushingSleepTime time.Duration,
 	observer RelayObserver,
+	profitability ProfitabilityEstimator,
+	queueStore QueueStore,
+	pipeline *Pipeline,
+	logger logs.Logger,
 ) *Relay {
 	loopCtx, cancelLoopCtx := context.WithCancel(ctx)
 
+	lagWarningThreshold := config.LagWarningThreshold
+	if lagWarningThreshold <= 0 {
+		lagWarningThreshold = defaultRelayLagWarningThreshold
+	}
+
+	headerValidation := config.HeaderValidation
+	if headerValidation == "" {
+		headerValidation = HeaderValidationEnforce
+	}
+
+	finalityDepth := config.FinalityDepth
+	if finalityDepth == 0 {
+		finalityDepth = defaultFinalityDepth
+	}
+
+	catchUpLagThreshold := config.CatchUpLagThreshold
+	if catchUpLagThreshold <= 0 {
+		catchUpLagThreshold = defaultCatchUpLagThreshold
+	}
+
+	maxPendingBatches := config.MaxPendingBatches
+	if maxPendingBatches <= 0 {
+		maxPendingBatches = defaultMaxPendingBatches
+	}
+
+	maxInFlight := config.MaxInFlightTransactions
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlightTransactions
+	}
+
+	maxBatchesAhead := config.MaxBatchesAhead
+	if maxBatchesAhead <= 0 {
+		maxBatchesAhead = defaultMaxBatchesAhead
+	}
+
 	relay := &Relay{
 		btcChain:                btcChain,
 		hostChain:               hostChain,
+		store:                   relayStore,
+		control:                 control,
+		clock:                   clock.System,
+		logger:                  logs.OrDefault(logger, loggerName),
 		difficultyEpochDuration: difficultyEpochDuration,
+		watchOnly:               config.WatchOnly,
+		lagWarningThreshold:     lagWarningThreshold,
+		finalityDepth:           finalityDepth,
+		finalityTracker:         &finalityTracker{},
+		headerValidation:        headerValidation,
+		headerValidator:         btc.NewHeaderValidator(btcChain.NetworkParams()),
+		catchUpLagThreshold:     catchUpLagThreshold,
+		maxPendingBatches:       maxPendingBatches,
+		maxInFlight:             maxInFlight,
+		maxPullAhead:            maxBatchesAhead * headersBatchSize,
+		batchAlignment:          batchAlignment(config),
+		skipInterval:            config.SkipInterval,
+		chainwork:               chainworkTracker{limit: config.MaxCachedHeaders},
+		memoryLimit:             uint64(config.MemoryLimit) * 1024 * 1024,
+		pushDeadline:            time.Duration(config.PushDeadline) * time.Second,
 		pullingSleepTime:        pullingSleepTime,
 		pushingSleepTime:        pushingSleepTime,
 		headersQueue:            make(chan *btc.Header, headersQueueSize),
+		batchTaken:              make(chan struct{}, 1),
 		errChan:                 make(chan error, 1),
+		queueStore:              queueStore,
+		stopped:                 make(chan struct{}),
 		observer:                observer,
 	}
 
+	if config.ProfitabilityGate && profitability != nil {
+		relay.profitabilityGate = &profitabilityGate{
+			estimator: profitability,
+			margin:    config.ProfitabilityMargin,
+		}
+	}
+
+	if config.ForkMonitoring {
+		forkMonitoringDepth := config.ForkMonitoringDepth
+		if forkMonitoringDepth <= 0 {
+			forkMonitoringDepth = defaultForkMonitoringDepth
+		}
+
+		relay.forks = &forkMonitor{depth: forkMonitoringDepth}
+	}
+
+	relay.epochEndNoticeBlocks = config.EpochEndNoticeBlocks
+	if relay.epochEndNoticeBlocks <= 0 {
+		relay.epochEndNoticeBlocks = defaultEpochEndNoticeBlocks
+	}
+
+	if config.WatchdogTimeout > 0 {
+		relay.watchdog = newWatchdog(relay.clock)
+		relay.watchdogTimeout = time.Duration(config.WatchdogTimeout) * time.Second
+		relay.watchdogAction = config.WatchdogAction
+		if relay.watchdogAction == "" {
+			relay.watchdogAction = WatchdogRestart
+		}
+	}
+
+	relay.pipeline = relay.newPipeline(pipeline)
+
+	pushSchedule, err := newPushSchedule(config)
+	if err != nil {
+		relay.errChan <- fmt.Errorf("invalid push schedule: [%v]", err)
+		cancelLoopCtx()
+		close(relay.stopped)
+		return relay
+	}
+	relay.pushSchedule = pushSchedule
+
+	checkpointVerifier, err := newCheckpointVerifier(
+		btcChain.NetworkParams(),
+		config,
+		relay.logger,
+	)
+	if err != nil {
+		relay.errChan <- fmt.Errorf("invalid checkpoints: [%v]", err)
+		cancelLoopCtx()
+		close(relay.stopped)
+		return relay
+	}
+	relay.checkpointVerifier = checkpointVerifier
+
+	go relay.resyncMonitoringLoop(loopCtx, cancelLoopCtx)
+	go relay.epochMonitoringLoop(loopCtx)
+
+	if config.Mode == ModeRetargetOnly {
+		relay.logger.Infof("starting relay in the retarget-only mode")
+
+		go func() {
+			relay.retargetLoop(loopCtx)
+			cancelLoopCtx() // loop exited, cancel the context
+			close(relay.stopped)
+		}()
+
+		return relay
+	}
+
+	// Headers left in the queue can be persisted only once both loops
+	// touching the queue have exited.
+	queueLoops := &sync.WaitGroup{}
+	queueLoops.Add(2)
+
 	go func() {
+		defer queueLoops.Done()
 		relay.pullingLoop(loopCtx)
 		cancelLoopCtx() // loop exited, cancel the context
 	}()
 
 	go func() {
+		defer queueLoops.Done()
 		relay.pushingLoop(loopCtx)
 		cancelLoopCtx() // loop exited, cancel the context
 	}()
 
+	go func() {
+		queueLoops.Wait()
+		relay.spillQueue()
+		close(relay.stopped)
+	}()
+
+	go func() {
+		relay.finalityMonitoringLoop(loopCtx)
+		cancelLoopCtx() // loop exited, cancel the context
+	}()
+
+	go relay.lagMonitoringLoop(loopCtx)
+
+	if relay.forks != nil {
+		go relay.forkMonitoringLoop(loopCtx)
+	}
+
+	if relay.watchdog != nil {
+		go relay.watchdogLoop(loopCtx, cancelLoopCtx)
+	}
+
 	return relay
 }
 
 func (r *Relay) pullingLoop(ctx context.Context) {
-	logger.Infof("starting new headers pulling loop")
-	defer logger.Infof("stopping current headers pulling loop")
+	r.logger.Infof("starting new headers pulling loop")
+	defer r.logger.Infof("stopping current headers pulling loop")
+
+	ctx = r.watchdog.watch(ctx, pullingLoopName)
+	defer r.watchdog.unwatch(pullingLoopName)
 
-	latestHeader, err := r.findBestHeader()
+	latestHeader, err := r.findBestHeader(ctx)
 	if err != nil {
 		r.errChan <- fmt.Errorf(
 			"could not find best header for pulling loop: [%v]",
@@ -143,12 +713,29 @@ func (r *Relay) pullingLoop(ctx context.Context) {
 		return
 	}
 
+	// Set before any header is queued, so the pushing loop sees it.
+	r.sparseStartHeight = latestHeader.Height
+
+	if err := r.checkpointVerifier.VerifyChain(ctx, r.btcChain); err != nil {
+		r.errChan <- fmt.Errorf(
+			"Bitcoin chain does not pass through known checkpoints: [%v]",
+			err,
+		)
+		return
+	}
+
+	r.verifyCheckpoint(ctx, latestHeader)
+
 	// Start pulling Bitcoin headers with the one above the latest header
 	r.nextPullHeaderHeight = latestHeader.Height + 1
 
-	logger.Infof(
+	// Headers pulled by the previous relay instance but not pushed yet are
+	// put to the queue first, so they are not fetched again.
+	r.restoreQueue(ctx, latestHeader)
+
+	r.logger.Infof(
 		"starting pulling from header: [%d]",
-		latestHeader.Height+1,
+		r.nextPullHeaderHeight,
 	)
 
 	for {
@@ -156,26 +743,51 @@ func (r *Relay) pullingLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		default:
-			logger.Infof("starting pulling header from BTC chain")
+			if err := r.waitForMemory(ctx); err != nil {
+				// The wait can be interrupted only by context
+				// cancellation.
+				continue
+			}
 
-			header, err := r.pullHeaderFromBtcChain(ctx)
-			if err != nil {
-				r.errChan <- fmt.Errorf("could not pull header: [%v]", err)
-				return
+			if err := r.throttlePull(ctx); err != nil {
+				// The throttle can be interrupted only by context
+				// cancellation.
+				continue
 			}
 
-			logger.Infof("pulled header [%v] from BTC chain", header.Height)
+			// All log messages and Bitcoin node requests related to the
+			// pulled header carry the same correlation ID, which is taken
+			// over by the batch of the header once it is pushed.
+			pullCtx := correlation.WithID(ctx, correlation.New())
 
-			r.putHeaderToQueue(header)
+			correlation.InjectedLogger(pullCtx, r.logger).Infof(
+				"starting pulling header from BTC chain",
+			)
 
-			r.observer.NotifyHeaderPulled(header.Height)
+			header, err := r.pipeline.next(pullCtx)
+			if err != nil {
+				r.errChan <- err
+				return
+			}
+
+			r.putHeaderToQueue(pullCtx, header)
 		}
 	}
 }
 
 func (r *Relay) pushingLoop(ctx context.Context) {
-	logger.Infof("starting new headers pushing loop")
-	defer logger.Infof("stopping current headers pushing loop")
+	r.logger.Infof("starting new headers pushing loop")
+	defer r.logger.Infof("stopping current headers pushing loop")
+
+	ctx = r.watchdog.watch(ctx, pushingLoopName)
+	defer r.watchdog.unwatch(pushingLoopName)
+
+	if !r.watchOnly {
+		if err := r.verifySparseSupport(ctx); err != nil {
+			r.errChan <- err
+			return
+		}
+	}
 
 	for {
 		select {
@@ -189,12 +801,62 @@ func (r *Relay) pushingLoop(ctx context.Context) {
 				continue
 			}
 
-			logger.Infof(
+			r.notifyBatchTaken(len(headers))
+
+			// All log messages related to this batch carry the same
+			// correlation ID so the batch can be traced across packages,
+			// back to the pull of its first header.
+			batchID, pullIDs := r.takePullIDs(headers)
+			batchCtx := correlation.WithID(ctx, batchID)
+			batchLogger := correlation.InjectedLogger(batchCtx, r.logger)
+
+			if len(pullIDs) > 0 {
+				batchLogger.Infof(
+					"formed batch of %v; other headers pulled as %v",
+					headersSummary(headers),
+					pullIDs,
+				)
+			} else {
+				batchLogger.Infof("formed batch of %v", headersSummary(headers))
+			}
+
+			// Keep the batch until it is pushed so it can be persisted
+			// together with the queue if the relay stops in the meantime.
+			r.unpushedBatch = headers
+
+			if err := r.control.waitWhilePaused(ctx); err != nil {
+				// The wait can be interrupted only by context cancellation.
+				continue
+			}
+
+			if r.watchOnly {
+				batchLogger.Infof(
+					"watch-only mode is enabled; skipping push of %v",
+					headersSummary(headers),
+				)
+				r.unpushedBatch = nil
+				continue
+			}
+
+			if err := r.waitForPushSchedule(batchCtx, headers); err != nil {
+				// The wait can be interrupted only by context cancellation.
+				continue
+			}
+
+			if r.catchingUp {
+				if err := r.waitForPendingBatches(batchCtx); err != nil {
+					// The wait can be interrupted only by context
+					// cancellation.
+					continue
+				}
+			}
+
+			batchLogger.Infof(
 				"starting pushing %v to host chain",
 				headersSummary(headers),
 			)
 
-			if err := r.pushHeadersToHostChain(ctx, headers); err != nil {
+			if err := r.submit(batchCtx, headers); err != nil {
 				r.errChan <- fmt.Errorf("could not push headers: [%v]", err)
 				// We exit on the first error letting the code controlling the
 				// relay to restart it. The relay is stateful and it is easier
@@ -203,33 +865,142 @@ func (r *Relay) pushingLoop(ctx context.Context) {
 				return
 			}
 
-			logger.Infof(
+			batchLogger.Infof(
 				"pushed %v to host chain",
 				headersSummary(headers),
 			)
 
-			r.observer.NotifyHeadersPushed(headersHeights(headers))
+			r.unpushedBatch = nil
 
-			logger.Infof(
+			r.observer.NotifyHeadersPushed(headers)
+
+			r.trackPushedBatch(batchCtx, headers)
+
+			if r.updateCatchUpPhase(batchCtx, headers[len(headers)-1]) {
+				// Push the next batch right away to reduce the lag.
+				continue
+			}
+
+			if r.awaitsAbove(headers[len(headers)-1].Height) {
+				// Push the next batch right away as pending proofs await
+				// its headers.
+				continue
+			}
+
+			r.logger.Infof(
 				"suspending headers pushing loop for [%v]",
 				r.pushingSleepTime,
 			)
 
 			// Sleep for a while to achieve a limited rate.
-			select {
-			case <-time.After(r.pushingSleepTime):
-			case <-ctx.Done():
-			}
+			_, _ = r.control.Scheduler().wait(
+				ctx,
+				TaskPush,
+				r.pushingSleepTime,
+				false,
+				r.control.pushRequested(),
+			)
 		}
 	}
 }
 
+// resyncMonitoringLoop raises ErrResyncRequested once the operator requests
+// a resync so the relay gets restarted from the best header known by the
+// host chain. The loops are cancelled before the error is raised, so the relay
+// stops and persists its queue before the restarted relay reads it.
+func (r *Relay) resyncMonitoringLoop(
+	ctx context.Context,
+	cancelLoops func(),
+) {
+	select {
+	case <-r.control.resyncRequested():
+		cancelLoops()
+		r.raiseError(ErrResyncRequested)
+	case <-ctx.Done():
+	}
+}
+
+// verifyCheckpoint compares the persistent checkpoint with the best header
+// the relay starts from. If the checkpoint is above the best header or is not
+// known by the host chain, the host chain lost some final headers. They are
+// resubmitted as pulling starts above the best header, and the stale
+// checkpoint is discarded so it is not trusted anymore, e.g. when importing
+// header snapshots. It gets advanced again once the resubmitted headers reach
+// finality.
+func (r *Relay) verifyCheckpoint(ctx context.Context, bestHeader *btc.Header) {
+	checkpoint, err := r.store.LoadCheckpoint()
+	if err != nil {
+		r.logger.Warnf("could not load checkpoint: [%v]", err)
+		return
+	}
+
+	if checkpoint == nil {
+		r.logger.Infof("no checkpoint stored yet")
+		return
+	}
+
+	if checkpoint.Height > bestHeader.Height {
+		r.logger.Errorf(
+			"checkpoint header [%v] is above the best header [%v] known "+
+				"by the host chain; headers above the best header will "+
+				"be resubmitted",
+			checkpoint.Height,
+			bestHeader.Height,
+		)
+		r.discardCheckpoint()
+		return
+	}
+
+	if _, err := r.hostChain.FindHeight(ctx, checkpoint.Digest); err != nil {
+		r.logger.Errorf(
+			"checkpoint header [%v] with digest [%v] is not known by the "+
+				"host chain: [%v]",
+			checkpoint.Height,
+			checkpoint.Digest,
+			err,
+		)
+		r.discardCheckpoint()
+		return
+	}
+
+	r.logger.Infof(
+		"checkpoint header [%v] confirmed at host chain block [%v]",
+		checkpoint.Height,
+		checkpoint.HostBlock,
+	)
+}
+
+func (r *Relay) discardCheckpoint() {
+	if err := r.store.DeleteCheckpoint(); err != nil {
+		r.logger.Errorf("could not discard stale checkpoint: [%v]", err)
+		return
+	}
+
+	r.logger.Warnf("discarded stale checkpoint")
+}
+
 // ErrChan returns the error channel of the relay. Once an error
 // appears here, all relay loops are immediately terminated.
 func (r *Relay) ErrChan() <-chan error {
 	return r.errChan
 }
 
+// Stopped returns a channel which is closed once all relay loops touching the
+// headers queue have exited and the queue has been persisted, if enabled.
+func (r *Relay) Stopped() <-chan struct{} {
+	return r.stopped
+}
+
+// timeSource returns the clock timing the relay loops, back-offs and
+// timeouts. Relays without a clock use the system clock.
+func (r *Relay) timeSource() clock.Clock {
+	if r.clock == nil {
+		return clock.System
+	}
+
+	return r.clock
+}
+
 func headersSummary(headers []*btc.Header) string {
 	if len(headers) == 0 {
 		return "no headers"
@@ -249,13 +1020,3 @@ func headersSummary(headers []*btc.Header) string {
 		lastHeaderHeight,
 	)
 }
-
-func headersHeights(headers []*btc.Header) []int64 {
-	result := make([]int64, len(headers))
-
-	for i, header := range headers {
-		result[i] = header.Height
-	}
-
-	return result
-}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/store"
 )
 
+var testLogger = logs.OrDefault(nil, loggerName)
+
 func TestRelay_PullingLoop_ContextCancellationShutdown(t *testing.T) {
 	ctx, cancelCtx := context.WithCancel(context.Background())
 	defer cancelCtx()
@@ -28,7 +33,19 @@ func TestRelay_PullingLoop_ContextCancellationShutdown(t *testing.T) {
 
 	// Run relay with an empty Bitcoin chain and wait for a moment so
 	// the pulling loop goes to sleep
-	relay := StartRelay(ctx, btcChain, localChain, &mockObserver{})
+	relay := StartRelay(
+		ctx,
+		btcChain,
+		localChain,
+		store.OpenMemory(),
+		&Config{},
+		NewControl(nil),
+		&mockObserver{},
+		nil,
+		nil,
+		nil,
+		nil,
+	)
 	time.Sleep(100 * time.Millisecond)
 
 	// While the pulling loop is sleeping, add headers to Bitcoin chain and
@@ -80,7 +97,19 @@ func TestRelay_PullingLoop_ErrorShutdown(t *testing.T) {
 
 	localChain.SetBestKnownDigest([32]byte{2})
 
-	relay := StartRelay(ctx, btcChain, localChain, &mockObserver{})
+	relay := StartRelay(
+		ctx,
+		btcChain,
+		localChain,
+		store.OpenMemory(),
+		&Config{},
+		NewControl(nil),
+		&mockObserver{},
+		nil,
+		nil,
+		nil,
+		nil,
+	)
 
 	select {
 	case err = <-relay.ErrChan():
@@ -134,7 +163,19 @@ func TestRelay_PushingLoop_ContextCancellationShutdown(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	relay := StartRelay(ctx, btcChain, localChain, &mockObserver{})
+	relay := StartRelay(
+		ctx,
+		btcChain,
+		localChain,
+		store.OpenMemory(),
+		&Config{},
+		NewControl(nil),
+		&mockObserver{},
+		nil,
+		nil,
+		nil,
+		nil,
+	)
 
 	// Shutdown the pushing loop.
 	cancelCtx()
@@ -188,7 +229,19 @@ func TestRelay_PushingLoop_ErrorShutdown(t *testing.T) {
 	// pushing loop.
 	localChain.(*chainlocal.Chain).SetBestKnownDigest([32]byte{255})
 
-	relay := StartRelay(ctx, btcChain, localChain, &mockObserver{})
+	relay := StartRelay(
+		ctx,
+		btcChain,
+		localChain,
+		store.OpenMemory(),
+		&Config{},
+		NewControl(nil),
+		&mockObserver{},
+		nil,
+		nil,
+		nil,
+		nil,
+	)
 
 	// Fill the queue with two headers batches.
 	for i := 1; i <= 10; i++ {
@@ -246,12 +299,58 @@ func TestRelay_PushingLoop_ErrorShutdown(t *testing.T) {
 	}
 }
 
-type mockObserver struct{}
+type mockObserver struct {
+	mutex         sync.Mutex
+	pulledHeaders int
+}
+
+func (mo *mockObserver) NotifyHeaderPulled(header *btc.Header) {
+	mo.mutex.Lock()
+	defer mo.mutex.Unlock()
+
+	mo.pulledHeaders++
+}
+
+func (mo *mockObserver) pulled() int {
+	mo.mutex.Lock()
+	defer mo.mutex.Unlock()
+
+	return mo.pulledHeaders
+}
+
+func (mo *mockObserver) NotifyHeadersPushed(headers []*btc.Header) {
+	// no-op
+}
+
+func (mo *mockObserver) NotifyHeadersConfirmed(
+	headers []*btc.Header,
+	confirmedAt time.Time,
+) {
+	// no-op
+}
+
+func (mo *mockObserver) NotifyRelayLag(lag int64) {
+	// no-op
+}
+
+func (mo *mockObserver) NotifyForks(forks int, length int64) {
+	// no-op
+}
+
+func (mo *mockObserver) NotifyRetargetSubmitted(
+	epoch uint64,
+	header *btc.Header,
+) {
+	// no-op
+}
 
-func (mo *mockObserver) NotifyHeaderPulled(headerHeight int64) {
+func (mo *mockObserver) NotifyEpochEndApproaching(
+	epoch uint64,
+	header *btc.Header,
+) {
 	// no-op
 }
 
-func (mo *mockObserver) NotifyHeadersPushed(headersHeights []int64) {
+func (mo *mockObserver) NotifyReorg(header *btc.Header, reorg *store.Reorg) {
 	// no-op
 }
@@ -0,0 +1,81 @@
+package header
+
+import (
+	"context"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// reorg.go file contains the recording of Bitcoin reorgs observed by the
+// relay. Once the relay sets a new best header which does not descend from
+// the previous best header known by the host chain, the replaced branch is
+// recorded in the reorg history of the relay store. The history tells the
+// operators how deep reorgs get and how many confirmations proven
+// transactions should have.
+
+// recordReorg records the reorg replacing the branch from the given last
+// common ancestor, exclusive, to the given previous best header, inclusive,
+// with the branch ending with the given new best header. Failures are only
+// logged as the reorg history is informational.
+func (r *Relay) recordReorg(
+	ctx context.Context,
+	lastCommonAncestor *btc.Header,
+	previousBestHeader *btc.Header,
+	newBestHeader *btc.Header,
+) {
+	batchLogger := correlation.InjectedLogger(ctx, r.logger)
+
+	depth := previousBestHeader.Height - lastCommonAncestor.Height
+
+	batchLogger.Warnf(
+		"reorg of depth [%v] replaced headers above header [%v]; "+
+			"new best header is [%v]",
+		depth,
+		lastCommonAncestor.Height,
+		newBestHeader.Height,
+	)
+
+	// Digests are collected from the previous best header down, so the
+	// ones below a header which cannot be fetched are left out.
+	replacedDigests := make([]btc.Digest, depth)
+
+	header := previousBestHeader
+	for i := depth - 1; i >= 0; i-- {
+		replacedDigests[i] = header.Hash
+
+		if i == 0 {
+			break
+		}
+
+		parentHeader, err := r.btcChain.GetHeaderByDigest(ctx, header.PrevHash)
+		if err != nil {
+			batchLogger.Warnf(
+				"could not get replaced header [%v]: [%v]",
+				header.PrevHash,
+				err,
+			)
+			replacedDigests = replacedDigests[i:]
+			break
+		}
+
+		header = parentHeader
+	}
+
+	reorg := &store.Reorg{
+		Depth:           depth,
+		AncestorHeight:  lastCommonAncestor.Height,
+		AncestorDigest:  lastCommonAncestor.Hash,
+		ReplacedDigests: replacedDigests,
+		NewBestHeight:   newBestHeader.Height,
+		NewBestDigest:   newBestHeader.Hash,
+		ObservedAt:      r.timeSource().Now(),
+	}
+
+	if err := r.store.SaveReorg(reorg); err != nil {
+		batchLogger.Warnf("could not record reorg: [%v]", err)
+	}
+
+	r.observer.NotifyReorg(newBestHeader, reorg)
+}
@@ -0,0 +1,101 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// reorgObserver records the reorgs the relay notifies about.
+type reorgObserver struct {
+	mockObserver
+
+	reorgs []*store.Reorg
+}
+
+func (ro *reorgObserver) NotifyReorg(header *btc.Header, reorg *store.Reorg) {
+	ro.reorgs = append(ro.reorgs, reorg)
+}
+
+func TestRecordReorg(t *testing.T) {
+	ancestor := &btc.Header{Height: 10, Hash: btc.Digest{0x10}}
+	replaced := []*btc.Header{
+		{Height: 11, Hash: btc.Digest{0x11}, PrevHash: btc.Digest{0x10}},
+		{Height: 12, Hash: btc.Digest{0x12}, PrevHash: btc.Digest{0x11}},
+	}
+	newBest := &btc.Header{
+		Height:   13,
+		Hash:     btc.Digest{0x23},
+		PrevHash: btc.Digest{0x22},
+	}
+
+	handle, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	btcChain := handle.(*btc.LocalChain)
+	btcChain.SetOrphanedHeaders(replaced)
+
+	relayStore := store.OpenMemory()
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+	observer := &reorgObserver{}
+
+	relay := &Relay{
+		logger:   testLogger,
+		btcChain: btcChain,
+		store:    relayStore,
+		clock:    fakeClock,
+		observer: observer,
+	}
+
+	relay.recordReorg(context.Background(), ancestor, replaced[1], newBest)
+
+	reorgs, err := relayStore.LoadReorgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedReorgs := []*store.Reorg{
+		{
+			Depth:           2,
+			AncestorHeight:  10,
+			AncestorDigest:  ancestor.Hash,
+			ReplacedDigests: []btc.Digest{replaced[0].Hash, replaced[1].Hash},
+			NewBestHeight:   13,
+			NewBestDigest:   newBest.Hash,
+			ObservedAt:      time.Unix(1000, 0),
+		},
+	}
+
+	if len(reorgs) != 1 || !reorgs[0].ObservedAt.Equal(time.Unix(1000, 0)) {
+		t.Fatalf("unexpected reorgs: [%v]", reorgs)
+	}
+
+	// Compare the times separately as they lose their location once stored.
+	reorgs[0].ObservedAt = expectedReorgs[0].ObservedAt
+
+	if !reflect.DeepEqual(expectedReorgs, reorgs) {
+		t.Errorf(
+			"unexpected reorgs:\n"+
+				"expected: [%+v]\n"+
+				"actual:   [%+v]\n",
+			expectedReorgs[0],
+			reorgs[0],
+		)
+	}
+
+	if len(observer.reorgs) != 1 || observer.reorgs[0].Depth != 2 {
+		t.Errorf("unexpected reorg notifications: [%v]", observer.reorgs)
+	}
+
+	stats := store.SummarizeReorgs(reorgs, 6)
+	if stats.MaxDepth != 2 || stats.RecommendedConfirmations != 6 {
+		t.Errorf("unexpected reorg stats: [%+v]", stats)
+	}
+}
@@ -0,0 +1,144 @@
+package header
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// replay.go file contains the replay of journaled headers batches. A batch is
+// rebuilt from the Bitcoin chain exactly as it was submitted, walking back
+// from its last header digest, so batches made of headers which have since
+// been reorged out are rebuilt as well if the Bitcoin node still knows them.
+// The rebuilt batch is then submitted with the same relay contract call the
+// relay made, which lets an incident be reproduced against a local chain or
+// a fork of the host chain.
+
+// ReplayBatch rebuilds the journaled batch and submits it to the given host
+// chain. The journal is neither checked nor updated, so the batch is always
+// submitted.
+func ReplayBatch(
+	ctx context.Context,
+	btcChain btc.Handle,
+	hostChain chain.Handle,
+	entry *store.JournalEntry,
+) error {
+	relay := &Relay{
+		btcChain:                btcChain,
+		hostChain:               hostChain,
+		difficultyEpochDuration: btcDifficultyEpochDuration,
+	}
+
+	return relay.replayBatch(ctx, entry)
+}
+
+// replayBatch rebuilds the journaled batch and submits it, splitting the
+// difficulty epochs by the epoch duration of the relay.
+func (r *Relay) replayBatch(
+	ctx context.Context,
+	entry *store.JournalEntry,
+) error {
+	headers, err := rebuildBatch(ctx, r.btcChain, entry)
+	if err != nil {
+		return err
+	}
+
+	if entry.FirstHeight%r.difficultyEpochDuration == 0 {
+		epochStart := entry.FirstHeight - r.difficultyEpochDuration
+		epochEnd := entry.FirstHeight - 1
+
+		oldPeriodStartHeader, err := r.btcChain.GetHeaderByHeight(
+			ctx,
+			epochStart,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"could not get header by height [%v]: [%v]",
+				epochStart,
+				err,
+			)
+		}
+
+		oldPeriodEndHeader, err := r.btcChain.GetHeaderByHeight(ctx, epochEnd)
+		if err != nil {
+			return fmt.Errorf(
+				"could not get header by height [%v]: [%v]",
+				epochEnd,
+				err,
+			)
+		}
+
+		return r.hostChain.AddHeadersWithRetarget(
+			ctx,
+			oldPeriodStartHeader.Raw,
+			oldPeriodEndHeader.Raw,
+			packHeaders(headers),
+		)
+	}
+
+	anchorHeader, err := r.btcChain.GetHeaderByDigest(ctx, headers[0].PrevHash)
+	if err != nil {
+		return fmt.Errorf("could not get anchor header by digest: [%v]", err)
+	}
+
+	return r.hostChain.AddHeaders(ctx, anchorHeader.Raw, packHeaders(headers))
+}
+
+// rebuildBatch fetches the headers of the journaled batch, walking back from
+// its last header digest. The rebuilt batch must match the journaled batch
+// ID.
+func rebuildBatch(
+	ctx context.Context,
+	btcChain btc.Handle,
+	entry *store.JournalEntry,
+) ([]*btc.Header, error) {
+	if entry.LastHeight < entry.FirstHeight {
+		return nil, fmt.Errorf(
+			"invalid heights range [%v-%v] of batch [%v]",
+			entry.FirstHeight,
+			entry.LastHeight,
+			entry.ID,
+		)
+	}
+
+	headers := make([]*btc.Header, entry.LastHeight-entry.FirstHeight+1)
+
+	digest := entry.LastDigest
+	for i := len(headers) - 1; i >= 0; i-- {
+		header, err := btcChain.GetHeaderByDigest(ctx, digest)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not get header [%v] of batch [%v]: [%v]",
+				digest,
+				entry.ID,
+				err,
+			)
+		}
+
+		headers[i] = header
+		digest = header.PrevHash
+	}
+
+	if headers[0].Height != entry.FirstHeight {
+		return nil, fmt.Errorf(
+			"first header of batch [%v] has height [%v] instead of [%v]",
+			entry.ID,
+			headers[0].Height,
+			entry.FirstHeight,
+		)
+	}
+
+	id := store.NewBatchID(headers[0].Hash, headers[len(headers)-1].Hash)
+	if id != entry.ID {
+		return nil, fmt.Errorf(
+			"rebuilt batch [%v] does not match journaled batch [%v]",
+			id,
+			entry.ID,
+		)
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,157 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+func TestReplayBatch(t *testing.T) {
+	chainHeaders := linkedHeaders(0, 2020)
+	for _, header := range chainHeaders {
+		raw := to32Bytes(int(header.Height))
+		header.Raw = raw[:]
+	}
+
+	journalEntry := func(from, to int) *store.JournalEntry {
+		return &store.JournalEntry{
+			ID: store.NewBatchID(
+				chainHeaders[from].Hash,
+				chainHeaders[to].Hash,
+			),
+			FirstHeight: int64(from),
+			LastHeight:  int64(to),
+			LastDigest:  chainHeaders[to].Hash,
+		}
+	}
+
+	var tests = map[string]struct {
+		entry                   *store.JournalEntry
+		difficultyEpochDuration int64
+		expectedAddHeaders      int
+		expectedWithRetarget    int
+		expectedAnchorOrStart   []byte
+		expectedPackedHeaders   []byte
+		expectedError           bool
+	}{
+		"batch without retarget": {
+			entry:                 journalEntry(5, 7),
+			expectedAddHeaders:    1,
+			expectedAnchorOrStart: chainHeaders[4].Raw,
+			expectedPackedHeaders: packHeaders(chainHeaders[5:8]),
+		},
+		"batch with retarget": {
+			entry:                 journalEntry(2016, 2018),
+			expectedWithRetarget:  1,
+			expectedAnchorOrStart: chainHeaders[0].Raw,
+			expectedPackedHeaders: packHeaders(chainHeaders[2016:2019]),
+		},
+		"batch with retarget in shortened epoch": {
+			entry:                   journalEntry(16, 18),
+			difficultyEpochDuration: testDifficultyEpochDuration,
+			expectedWithRetarget:    1,
+			expectedAnchorOrStart:   chainHeaders[8].Raw,
+			expectedPackedHeaders:   packHeaders(chainHeaders[16:19]),
+		},
+		"batch not matching its ID": {
+			entry: &store.JournalEntry{
+				ID:          journalEntry(5, 7).ID,
+				FirstHeight: 6,
+				LastHeight:  7,
+				LastDigest:  chainHeaders[7].Hash,
+			},
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			btcChain := bc.(*btc.LocalChain)
+			btcChain.SetHeaders(chainHeaders)
+
+			hc, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+			hostChain := hc.(*chainlocal.Chain)
+
+			if test.difficultyEpochDuration != 0 {
+				relay := &Relay{
+					btcChain:                btcChain,
+					hostChain:               hostChain,
+					difficultyEpochDuration: test.difficultyEpochDuration,
+				}
+				err = relay.replayBatch(context.Background(), test.entry)
+			} else {
+				err = ReplayBatch(
+					context.Background(),
+					btcChain,
+					hostChain,
+					test.entry,
+				)
+			}
+			if test.expectedError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			addHeaders := hostChain.AddHeadersEvents()
+			withRetarget := hostChain.AddHeadersWithRetargetEvents()
+
+			if len(addHeaders) != test.expectedAddHeaders ||
+				len(withRetarget) != test.expectedWithRetarget {
+				t.Fatalf(
+					"unexpected submissions:\n"+
+						"expected: [%v addHeaders, %v with retarget]\n"+
+						"actual:   [%v addHeaders, %v with retarget]\n",
+					test.expectedAddHeaders,
+					test.expectedWithRetarget,
+					len(addHeaders),
+					len(withRetarget),
+				)
+			}
+
+			var anchorOrStart, packedHeaders []byte
+			if len(addHeaders) > 0 {
+				anchorOrStart = addHeaders[0].AnchorHeader
+				packedHeaders = addHeaders[0].Headers
+			} else {
+				anchorOrStart = withRetarget[0].OldPeriodStartHeader
+				packedHeaders = withRetarget[0].Headers
+			}
+
+			if !bytes.Equal(test.expectedAnchorOrStart, anchorOrStart) {
+				t.Errorf(
+					"unexpected anchor header:\n"+
+						"expected: [%x]\n"+
+						"actual:   [%x]\n",
+					test.expectedAnchorOrStart,
+					anchorOrStart,
+				)
+			}
+
+			if !bytes.Equal(test.expectedPackedHeaders, packedHeaders) {
+				t.Errorf(
+					"unexpected headers:\n"+
+						"expected: [%x]\n"+
+						"actual:   [%x]\n",
+					test.expectedPackedHeaders,
+					packedHeaders,
+				)
+			}
+		})
+	}
+}
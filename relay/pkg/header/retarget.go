@@ -0,0 +1,170 @@
+package header
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// retarget.go file contains the logic of the retarget-only mode used with
+// the tBTC v2 LightRelay contract. In that mode, the relay does not push all
+// headers but only submits a retarget proof once per difficulty epoch. The
+// proof consists of proof length headers from the end of the epoch known by
+// the light relay and the same number of headers from the beginning of the
+// next epoch.
+
+func (r *Relay) retargetLoop(ctx context.Context) {
+	r.logger.Infof("starting new retarget loop")
+	defer r.logger.Infof("stopping current retarget loop")
+
+	for {
+		if err := r.control.waitWhilePaused(ctx); err != nil {
+			return
+		}
+
+		if err := r.retargetIfReady(ctx); err != nil {
+			r.raiseError(fmt.Errorf("could not retarget: [%v]", err))
+			return
+		}
+
+		if _, err := r.control.Scheduler().wait(
+			ctx,
+			TaskRetarget,
+			r.pushingSleepTime,
+			true,
+			r.control.pushRequested(),
+		); err != nil {
+			return
+		}
+	}
+}
+
+// retargetIfReady submits a retarget proof for the epoch following the
+// current light relay epoch if the Bitcoin chain already contains all
+// headers required by the proof. Each submission attempt gets its own
+// correlation ID.
+func (r *Relay) retargetIfReady(ctx context.Context) error {
+	currentEpoch, err := r.hostChain.GetCurrentEpoch(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get current epoch: [%v]", err)
+	}
+
+	nextEpoch := currentEpoch + 1
+
+	if nextEpoch == r.lastRetargetEpoch &&
+		r.timeSource().Since(r.lastRetargetTime) < retargetResubmissionTimeout {
+		r.logger.Debugf(
+			"retarget for epoch [%v] already submitted; "+
+				"waiting for the host chain to confirm it",
+			nextEpoch,
+		)
+		return nil
+	}
+
+	proofLength, err := r.hostChain.GetProofLength(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get proof length: [%v]", err)
+	}
+
+	headers, err := r.retargetProofHeaders(ctx, nextEpoch, int64(proofLength))
+	if err != nil {
+		return err
+	}
+
+	if headers == nil {
+		// Not enough Bitcoin blocks yet.
+		return nil
+	}
+
+	if r.watchOnly {
+		r.logger.Infof(
+			"watch-only mode is enabled; skipping retarget for epoch [%v]",
+			nextEpoch,
+		)
+		return nil
+	}
+
+	ctx = correlation.WithID(ctx, correlation.New())
+
+	correlation.InjectedLogger(ctx, r.logger).Infof(
+		"submitting retarget for epoch [%v] using %v",
+		nextEpoch,
+		headersSummary(headers),
+	)
+
+	if err := r.hostChain.Retarget(ctx, packHeaders(headers)); err != nil {
+		return fmt.Errorf(
+			"could not submit retarget for epoch [%v]: [%v]",
+			nextEpoch,
+			err,
+		)
+	}
+
+	r.lastRetargetEpoch = nextEpoch
+	r.lastRetargetTime = r.timeSource().Now()
+
+	r.observer.NotifyHeadersPushed(headers)
+	r.notifyRetargetSubmitted(ctx, headers[proofLength])
+
+	return nil
+}
+
+// retargetProofHeaders returns headers proving the retarget to the given
+// epoch or nil if the Bitcoin chain does not contain all of them yet. The
+// headers are fetched on each poll until the retarget is confirmed, but the
+// observer is notified about them only once per epoch.
+func (r *Relay) retargetProofHeaders(
+	ctx context.Context,
+	epoch uint64,
+	proofLength int64,
+) ([]*btc.Header, error) {
+	if proofLength <= 0 {
+		return nil, fmt.Errorf("invalid proof length [%v]", proofLength)
+	}
+
+	epochStart := int64(epoch) * r.difficultyEpochDuration
+	firstHeight := epochStart - proofLength
+	lastHeight := epochStart + proofLength - 1
+
+	chainHeight, err := r.btcChain.GetBlockCount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block count: [%v]", err)
+	}
+
+	if chainHeight < lastHeight {
+		r.logger.Infof(
+			"waiting for header [%v] required to prove retarget "+
+				"for epoch [%v]; current chain height is [%v]",
+			lastHeight,
+			epoch,
+			chainHeight,
+		)
+		return nil, nil
+	}
+
+	headers := make([]*btc.Header, 0, 2*proofLength)
+	for height := firstHeight; height <= lastHeight; height++ {
+		header, err := r.btcChain.GetHeaderByHeight(ctx, height)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not get header by height [%v]: [%v]",
+				height,
+				err,
+			)
+		}
+
+		headers = append(headers, header)
+	}
+
+	if epoch != r.lastPulledRetargetEpoch {
+		for _, header := range headers {
+			r.observer.NotifyHeaderPulled(header)
+		}
+
+		r.lastPulledRetargetEpoch = epoch
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,107 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+)
+
+func TestRetargetIfReady(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	// The light relay knows the first epoch and requires two headers on each
+	// side of the epoch boundary. With the epoch duration of eight blocks,
+	// the proof for the second epoch consists of headers 14, 15, 16 and 17.
+	localChain.SetCurrentEpoch(1)
+	localChain.SetProofLength(2)
+
+	observer := &mockObserver{}
+
+	relay := &Relay{
+		logger:                  testLogger,
+		btcChain:                btcChain,
+		hostChain:               localChain,
+		difficultyEpochDuration: testDifficultyEpochDuration,
+		observer:                observer,
+	}
+
+	for height := 0; height <= 16; height++ {
+		btcChain.AppendHeader(&btc.Header{
+			Hash:   to32Bytes(height),
+			Height: int64(height),
+			Raw:    toBytes(height),
+		})
+	}
+
+	// The last header required by the proof is not available yet.
+	if err := relay.retargetIfReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := localChain.RetargetEvents(); len(events) != 0 {
+		t.Fatalf("unexpected number of retarget events: [%v]", len(events))
+	}
+
+	btcChain.AppendHeader(&btc.Header{
+		Hash:   to32Bytes(17),
+		Height: 17,
+		Raw:    toBytes(17),
+	})
+
+	if err := relay.retargetIfReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := localChain.RetargetEvents()
+	if len(events) != 1 {
+		t.Fatalf("unexpected number of retarget events: [%v]", len(events))
+	}
+
+	expectedEvent := &chainlocal.RetargetEvent{
+		Headers: toBytes(14, 15, 16, 17),
+	}
+	if !reflect.DeepEqual(expectedEvent, events[0]) {
+		t.Errorf(
+			"unexpected retarget event:\n"+
+				"expected: [%+v]\n"+
+				"actual:   [%+v]\n",
+			expectedEvent,
+			events[0],
+		)
+	}
+
+	// Once the resubmission timeout elapses without the host chain
+	// confirming the retarget, the same headers are fetched again, but they
+	// must not be reported as pulled again.
+	localChain.SetCurrentEpoch(1)
+	relay.lastRetargetTime = time.Time{}
+	if err := relay.retargetIfReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if pulled := observer.pulled(); pulled != 4 {
+		t.Errorf(
+			"unexpected number of pulled headers:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			4,
+			pulled,
+		)
+	}
+}
@@ -0,0 +1,236 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+// schedule.go file contains the logic which decides whether a push of
+// headers batch should be deferred to reduce the operating cost. A push is
+// deferred if the current gas price exceeds the configured ceiling or the
+// current time is outside all configured push windows. A push is never
+// deferred if the relay lag reaches the configured safety bound.
+
+// pushWindow represents a daily time window, expressed in UTC, during which
+// headers can be pushed to the host chain. The window can span midnight,
+// e.g. 22:00-06:00.
+type pushWindow struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// parsePushWindow parses a push window given in the HH:MM-HH:MM format.
+func parsePushWindow(value string) (*pushWindow, error) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf(
+			"push window [%v] is not in the HH:MM-HH:MM format",
+			value,
+		)
+	}
+
+	start, err := parseTimeOfDay(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid push window start: [%v]", err)
+	}
+
+	end, err := parseTimeOfDay(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid push window end: [%v]", err)
+	}
+
+	if start == end {
+		return nil, fmt.Errorf(
+			"push window [%v] must have different start and end",
+			value,
+		)
+	}
+
+	return &pushWindow{start, end}, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(parsed.Hour())*time.Hour +
+		time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// contains checks whether the given time falls into the push window.
+func (pw *pushWindow) contains(t time.Time) bool {
+	t = t.UTC()
+	timeOfDay := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute
+
+	if pw.start < pw.end {
+		return timeOfDay >= pw.start && timeOfDay < pw.end
+	}
+
+	// The window spans midnight.
+	return timeOfDay >= pw.start || timeOfDay < pw.end
+}
+
+// pushSchedule holds the configuration of push deferral.
+type pushSchedule struct {
+	windows         []*pushWindow
+	gasPriceCeiling *big.Int
+	maxDeferralLag  int64
+}
+
+func newPushSchedule(config *Config) (*pushSchedule, error) {
+	schedule := &pushSchedule{
+		maxDeferralLag: config.MaxDeferralLag,
+	}
+
+	for _, value := range config.PushWindows {
+		window, err := parsePushWindow(value)
+		if err != nil {
+			return nil, err
+		}
+
+		schedule.windows = append(schedule.windows, window)
+	}
+
+	if config.GasPriceCeiling > 0 {
+		schedule.gasPriceCeiling = new(big.Int).Mul(
+			big.NewInt(config.GasPriceCeiling),
+			big.NewInt(1e9), // Gwei to Wei
+		)
+	}
+
+	if schedule.maxDeferralLag <= 0 {
+		schedule.maxDeferralLag = defaultMaxDeferralLag
+	}
+
+	return schedule, nil
+}
+
+func (ps *pushSchedule) isEnabled() bool {
+	return ps != nil && (len(ps.windows) > 0 || ps.gasPriceCeiling != nil)
+}
+
+// deferralReason returns the reason why the push should be deferred at the
+// given time and gas price. An empty string means the push should not be
+// deferred.
+func (ps *pushSchedule) deferralReason(
+	now time.Time,
+	gasPrice *big.Int,
+) string {
+	if len(ps.windows) > 0 {
+		withinWindow := false
+		for _, window := range ps.windows {
+			if window.contains(now) {
+				withinWindow = true
+				break
+			}
+		}
+
+		if !withinWindow {
+			return "current time is outside all push windows"
+		}
+	}
+
+	if ps.gasPriceCeiling != nil &&
+		gasPrice != nil &&
+		gasPrice.Cmp(ps.gasPriceCeiling) > 0 {
+		return fmt.Sprintf(
+			"gas price [%v] exceeds the ceiling [%v]",
+			gasPrice,
+			ps.gasPriceCeiling,
+		)
+	}
+
+	return ""
+}
+
+// waitForPushSchedule blocks until the push of the given headers batch is
+// allowed by the push schedule and the profitability gate or the relay lag
+// reaches the safety bound. Errors which occur while gathering the lag and
+// gas price do not block the push.
+func (r *Relay) waitForPushSchedule(
+	ctx context.Context,
+	headers []*btc.Header,
+) error {
+	if !r.pushSchedule.isEnabled() && !r.profitabilityGate.isEnabled() {
+		return nil
+	}
+
+	if r.coversPriority(ctx, headers) {
+		return nil
+	}
+
+	batchLogger := correlation.InjectedLogger(ctx, r.logger)
+
+	for {
+		lag, err := r.computeLag(ctx)
+		if err != nil {
+			batchLogger.Warnf(
+				"could not compute relay lag; not deferring push: [%v]",
+				err,
+			)
+			return nil
+		}
+
+		if lag >= r.pushSchedule.maxDeferralLag {
+			batchLogger.Infof(
+				"relay lag [%v] reached the safety bound [%v]; "+
+					"not deferring push",
+				lag,
+				r.pushSchedule.maxDeferralLag,
+			)
+			return nil
+		}
+
+		var gasPrice *big.Int
+		if r.pushSchedule.gasPriceCeiling != nil ||
+			r.profitabilityGate.isEnabled() {
+			gasPrice, err = r.hostChain.GetGasPrice(ctx)
+			if err != nil {
+				batchLogger.Warnf(
+					"could not get gas price; not deferring push: [%v]",
+					err,
+				)
+				return nil
+			}
+		}
+
+		reason := r.pushSchedule.deferralReason(r.timeSource().Now(), gasPrice)
+		if reason == "" && r.profitabilityGate.isEnabled() {
+			reason = r.profitabilityGate.deferralReason(len(headers), gasPrice)
+		}
+		if reason == "" {
+			return nil
+		}
+
+		batchLogger.Infof(
+			"deferring push for [%v] as %v; current relay lag is [%v]",
+			pushDeferralCheckInterval,
+			reason,
+			lag,
+		)
+
+		woken, err := r.control.Scheduler().wait(
+			ctx,
+			TaskPushDeferral,
+			pushDeferralCheckInterval,
+			true,
+			r.control.pushRequested(),
+		)
+		if err != nil {
+			return err
+		}
+		if woken {
+			batchLogger.Infof("immediate push requested; not deferring push")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package header
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPushWindow_Contains(t *testing.T) {
+	var tests = map[string]struct {
+		window   string
+		time     time.Time
+		expected bool
+	}{
+		"inside regular window": {
+			window:   "08:00-16:00",
+			time:     time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		"at regular window start": {
+			window:   "08:00-16:00",
+			time:     time.Date(2021, 1, 1, 8, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		"at regular window end": {
+			window:   "08:00-16:00",
+			time:     time.Date(2021, 1, 1, 16, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		"outside regular window": {
+			window:   "08:00-16:00",
+			time:     time.Date(2021, 1, 1, 20, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		"inside window spanning midnight before midnight": {
+			window:   "22:00-06:00",
+			time:     time.Date(2021, 1, 1, 23, 30, 0, 0, time.UTC),
+			expected: true,
+		},
+		"inside window spanning midnight after midnight": {
+			window:   "22:00-06:00",
+			time:     time.Date(2021, 1, 1, 1, 30, 0, 0, time.UTC),
+			expected: true,
+		},
+		"outside window spanning midnight": {
+			window:   "22:00-06:00",
+			time:     time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			window, err := parsePushWindow(test.window)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := window.contains(test.time)
+			if test.expected != actual {
+				t.Errorf(
+					"unexpected result:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expected,
+					actual,
+				)
+			}
+		})
+	}
+}
+
+func TestParsePushWindow_Invalid(t *testing.T) {
+	for _, value := range []string{"", "08:00", "25:00-06:00", "08:00-08:00"} {
+		if _, err := parsePushWindow(value); err == nil {
+			t.Errorf("expected error for push window [%v]", value)
+		}
+	}
+}
+
+func TestPushSchedule_DeferralReason(t *testing.T) {
+	schedule, err := newPushSchedule(&Config{
+		GasPriceCeiling: 100,
+		PushWindows:     []string{"08:00-16:00"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insideWindow := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	outsideWindow := time.Date(2021, 1, 1, 20, 0, 0, 0, time.UTC)
+	cheapGas := big.NewInt(50000000000)      // 50 Gwei
+	expensiveGas := big.NewInt(150000000000) // 150 Gwei
+
+	var tests = map[string]struct {
+		time           time.Time
+		gasPrice       *big.Int
+		expectDeferral bool
+	}{
+		"inside window with cheap gas": {
+			time:           insideWindow,
+			gasPrice:       cheapGas,
+			expectDeferral: false,
+		},
+		"inside window with expensive gas": {
+			time:           insideWindow,
+			gasPrice:       expensiveGas,
+			expectDeferral: true,
+		},
+		"outside window with cheap gas": {
+			time:           outsideWindow,
+			gasPrice:       cheapGas,
+			expectDeferral: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reason := schedule.deferralReason(test.time, test.gasPrice)
+
+			actualDeferral := reason != ""
+			if test.expectDeferral != actualDeferral {
+				t.Errorf(
+					"unexpected deferral:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v] (reason: %v)\n",
+					test.expectDeferral,
+					actualDeferral,
+					reason,
+				)
+			}
+		})
+	}
+}
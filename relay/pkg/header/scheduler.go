@@ -0,0 +1,553 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+)
+
+// scheduler.go file contains the scheduler timing the recurring tasks of the
+// relay loops, like pulling new headers or resting between pushes. Each task
+// runs in the default interval of the relay unless a schedule is configured
+// for it. Schedules can be fixed intervals, adaptive intervals backing off
+// while the task has nothing to do, or cron-like expressions. Schedules can
+// be changed at runtime and all scheduled tasks can be paused.
+
+// Names of the scheduled relay tasks.
+const (
+	// TaskPull is the check for new Bitcoin headers once the relay pulled
+	// all headers known by the Bitcoin node.
+	TaskPull = "pull"
+
+	// TaskPush is the rest between subsequent pushes of headers batches.
+	TaskPush = "push"
+
+	// TaskRetarget is the check for the next retarget in the retarget-only
+	// mode.
+	TaskRetarget = "retarget"
+
+	// TaskPushDeferral is the re-check of a deferred push.
+	TaskPushDeferral = "push-deferral"
+
+	// TaskPendingBatches is the check for pending batches in the catch-up
+	// phase.
+	TaskPendingBatches = "pending-batches"
+
+	// TaskJournal is the check for a journaled batch submitted before
+	// a restart.
+	TaskJournal = "journal"
+)
+
+// tasks are all the scheduled relay tasks.
+var tasks = []string{
+	TaskPull,
+	TaskPush,
+	TaskRetarget,
+	TaskPushDeferral,
+	TaskPendingBatches,
+	TaskJournal,
+}
+
+// Schedule determines when a scheduled task runs next.
+type Schedule interface {
+	// Next returns the time the task runs next, given the time the wait
+	// for the next run started. The idle flag determines whether the task
+	// had nothing to do since the previous run.
+	Next(from time.Time, idle bool) time.Time
+
+	// String returns the specification the schedule was parsed from.
+	String() string
+}
+
+// ParseSchedule parses the schedule specification. Supported
+// specifications are:
+//   - a duration, like `30s`, or `@every 30s`, running the task in a fixed
+//     interval,
+//   - `@adaptive <min> <max>`, like `@adaptive 10s 5m`, running the task in
+//     the minimum interval and doubling it up to the maximum interval while
+//     the task is idle,
+//   - a cron expression with minute, hour, day of month, month and day of
+//     week fields, like `*/5 22-23,0-5 * * *`, evaluated in UTC.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	fields := strings.Fields(spec)
+
+	switch {
+	case len(fields) == 0:
+		return nil, fmt.Errorf("empty schedule")
+	case fields[0] == "@every" && len(fields) == 2:
+		return parseFixedSchedule(spec, fields[1])
+	case fields[0] == "@adaptive" && len(fields) == 3:
+		return parseAdaptiveSchedule(spec, fields[1], fields[2])
+	case len(fields) == 1:
+		return parseFixedSchedule(spec, fields[0])
+	case len(fields) == 5:
+		return parseCronSchedule(spec, fields)
+	default:
+		return nil, fmt.Errorf("invalid schedule [%v]", spec)
+	}
+}
+
+type fixedSchedule struct {
+	spec     string
+	interval time.Duration
+}
+
+func parseFixedSchedule(spec string, interval string) (*fixedSchedule, error) {
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration <= 0 {
+		return nil, fmt.Errorf("invalid interval [%v]", interval)
+	}
+
+	return &fixedSchedule{spec, duration}, nil
+}
+
+func (fs *fixedSchedule) Next(from time.Time, idle bool) time.Time {
+	return from.Add(fs.interval)
+}
+
+func (fs *fixedSchedule) String() string {
+	return fs.spec
+}
+
+type adaptiveSchedule struct {
+	spec    string
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+func parseAdaptiveSchedule(
+	spec string,
+	min string,
+	max string,
+) (*adaptiveSchedule, error) {
+	minInterval, err := time.ParseDuration(min)
+	if err != nil || minInterval <= 0 {
+		return nil, fmt.Errorf("invalid minimum interval [%v]", min)
+	}
+
+	maxInterval, err := time.ParseDuration(max)
+	if err != nil || maxInterval < minInterval {
+		return nil, fmt.Errorf("invalid maximum interval [%v]", max)
+	}
+
+	return &adaptiveSchedule{
+		spec:    spec,
+		min:     minInterval,
+		max:     maxInterval,
+		current: minInterval,
+	}, nil
+}
+
+func (as *adaptiveSchedule) Next(from time.Time, idle bool) time.Time {
+	if !idle {
+		as.current = as.min
+		return from.Add(as.current)
+	}
+
+	interval := as.current
+
+	as.current *= 2
+	if as.current > as.max {
+		as.current = as.max
+	}
+
+	return from.Add(interval)
+}
+
+func (as *adaptiveSchedule) String() string {
+	return as.spec
+}
+
+// Ranges of the cron expression fields.
+var cronFieldRanges = [5][2]int{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week
+}
+
+// Maximum time searched for the next run of a cron schedule.
+const maxCronLookahead = 366 * 24 * time.Hour
+
+type cronSchedule struct {
+	spec   string
+	fields [5]map[int]bool
+}
+
+func parseCronSchedule(spec string, fields []string) (*cronSchedule, error) {
+	schedule := &cronSchedule{spec: spec}
+
+	for i, field := range fields {
+		values, err := parseCronField(field, cronFieldRanges[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron field [%v]: [%v]", field, err)
+		}
+		schedule.fields[i] = values
+	}
+
+	return schedule, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps,
+// like `*/5`, `1-5` or `0,30`.
+func parseCronField(field string, bounds [2]int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if index := strings.Index(part, "/"); index >= 0 {
+			parsed, err := strconv.Atoi(part[index+1:])
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid step [%v]", part[index+1:])
+			}
+			step = parsed
+			part = part[:index]
+		}
+
+		start, end := bounds[0], bounds[1]
+		if part != "*" {
+			rangeBounds := strings.SplitN(part, "-", 2)
+
+			parsed, err := strconv.Atoi(rangeBounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value [%v]", rangeBounds[0])
+			}
+			start, end = parsed, parsed
+
+			if len(rangeBounds) == 2 {
+				parsed, err := strconv.Atoi(rangeBounds[1])
+				if err != nil {
+					return nil, fmt.Errorf(
+						"invalid value [%v]",
+						rangeBounds[1],
+					)
+				}
+				end = parsed
+			}
+		}
+
+		if start < bounds[0] || end > bounds[1] || start > end {
+			return nil, fmt.Errorf(
+				"values out of range [%v-%v]",
+				bounds[0],
+				bounds[1],
+			)
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (cs *cronSchedule) Next(from time.Time, idle bool) time.Time {
+	next := from.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := next.Add(maxCronLookahead)
+
+	for next.Before(limit) {
+		if cs.matches(next) {
+			return next
+		}
+		next = next.Add(time.Minute)
+	}
+
+	// The expression never matches, e.g. the 31st of February.
+	return limit
+}
+
+func (cs *cronSchedule) matches(t time.Time) bool {
+	return cs.fields[0][t.Minute()] &&
+		cs.fields[1][t.Hour()] &&
+		cs.fields[2][t.Day()] &&
+		cs.fields[3][int(t.Month())] &&
+		cs.fields[4][int(t.Weekday())]
+}
+
+func (cs *cronSchedule) String() string {
+	return cs.spec
+}
+
+// Scheduler times the recurring tasks of the relay loops. Tasks without
+// a configured schedule run in the default interval given by the relay.
+// The same scheduler instance should be used by all subsequent relay
+// instances so that the configured schedules survive relay restarts.
+type Scheduler struct {
+	mutex     sync.Mutex
+	schedules map[string]Schedule
+	// tuned is closed and replaced once the schedules change so the
+	// waiting tasks can pick up the new schedules.
+	tuned   chan struct{}
+	paused  bool
+	resumed chan struct{}
+	// latencyCap is the maximum interval of the latency-sensitive tasks in
+	// the low-latency mode. It is zero if the mode is not active.
+	latencyCap time.Duration
+
+	clock  clock.Clock
+	logger logs.Logger
+}
+
+// NewScheduler creates a new scheduler without any configured schedules,
+// timing the tasks with the given clock. Scheduled tasks are not paused
+// initially. Messages are logged to the given logger or, if it is nil, to
+// the default logger.
+func NewScheduler(clock clock.Clock, logger logs.Logger) *Scheduler {
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &Scheduler{
+		schedules: make(map[string]Schedule),
+		tuned:     make(chan struct{}),
+		resumed:   resumed,
+		clock:     clock,
+		logger:    logs.OrDefault(logger, loggerName),
+	}
+}
+
+// SetSchedule sets the schedule of the given task. An empty specification
+// restores the default interval of the task.
+func (s *Scheduler) SetSchedule(task string, spec string) error {
+	if !isTask(task) {
+		return fmt.Errorf("unknown task [%v]", task)
+	}
+
+	var schedule Schedule
+	if spec != "" {
+		parsed, err := ParseSchedule(spec)
+		if err != nil {
+			return err
+		}
+		schedule = parsed
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if schedule == nil {
+		s.logger.Infof("restoring default schedule of task [%v]", task)
+		delete(s.schedules, task)
+	} else {
+		s.logger.Infof("setting schedule of task [%v] to [%v]", task, spec)
+		s.schedules[task] = schedule
+	}
+
+	close(s.tuned)
+	s.tuned = make(chan struct{})
+
+	return nil
+}
+
+// Schedules returns the specifications of the configured schedules by the
+// task names.
+func (s *Scheduler) Schedules() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedules := make(map[string]string, len(s.schedules))
+	for task, schedule := range s.schedules {
+		schedules[task] = schedule.String()
+	}
+
+	return schedules
+}
+
+// Pause pauses all scheduled tasks. Tasks waiting for their next run do not
+// run until the scheduler is resumed.
+func (s *Scheduler) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.paused {
+		return
+	}
+
+	s.logger.Infof("pausing scheduled tasks")
+
+	s.paused = true
+	s.resumed = make(chan struct{})
+}
+
+// Resume resumes all scheduled tasks. Tasks whose run is overdue run right
+// away.
+func (s *Scheduler) Resume() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.paused {
+		return
+	}
+
+	s.logger.Infof("resuming scheduled tasks")
+
+	s.paused = false
+	close(s.resumed)
+}
+
+// IsPaused returns whether the scheduled tasks are paused.
+func (s *Scheduler) IsPaused() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.paused
+}
+
+// wait blocks until the next run of the given task, the wake channel
+// receives a value or the context is done. It returns true if the wait was
+// interrupted by the wake channel. If no schedule is configured for the task,
+// it runs after the default interval. A nil scheduler always uses the default
+// interval.
+func (s *Scheduler) wait(
+	ctx context.Context,
+	task string,
+	defaultInterval time.Duration,
+	idle bool,
+	wake <-chan struct{},
+) (bool, error) {
+	defer Idle(ctx)()
+
+	if s == nil {
+		select {
+		case <-clock.System.After(defaultInterval):
+			return false, nil
+		case <-wake:
+			return true, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	start := s.clock.Now()
+	next := s.nextRun(task, defaultInterval, start, idle)
+
+	for {
+		s.mutex.Lock()
+		tuned := s.tuned
+		paused := s.paused
+		resumed := s.resumed
+		s.mutex.Unlock()
+
+		// Only one of the due and resumed channels is set; once resumed,
+		// the timer is set again so overdue runs fire right away.
+		var timer clock.Timer
+		var due <-chan time.Time
+		if !paused {
+			timer = s.clock.NewTimer(next.Sub(s.clock.Now()))
+			due = timer.C()
+			resumed = nil
+		}
+
+		select {
+		case <-due:
+			return false, nil
+		case <-resumed:
+		case <-tuned:
+			stopTimer(timer)
+			next = s.nextRun(task, defaultInterval, start, idle)
+		case <-wake:
+			stopTimer(timer)
+			return true, nil
+		case <-ctx.Done():
+			stopTimer(timer)
+			return false, ctx.Err()
+		}
+	}
+}
+
+func stopTimer(timer clock.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// setLatencyCap caps the intervals of the latency-sensitive tasks at the
+// given interval. A zero interval removes the cap.
+func (s *Scheduler) setLatencyCap(interval time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.latencyCap == interval {
+		return
+	}
+
+	s.latencyCap = interval
+
+	close(s.tuned)
+	s.tuned = make(chan struct{})
+}
+
+func (s *Scheduler) nextRun(
+	task string,
+	defaultInterval time.Duration,
+	from time.Time,
+	idle bool,
+) time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	next := from.Add(defaultInterval)
+	if schedule, ok := s.schedules[task]; ok {
+		next = schedule.Next(from, idle)
+	}
+
+	if s.latencyCap > 0 && isLatencySensitive(task) {
+		if capped := from.Add(s.latencyCap); capped.Before(next) {
+			return capped
+		}
+	}
+
+	return next
+}
+
+// isLatencySensitive checks whether the given task delays relaying new
+// headers, so its interval is capped in the low-latency mode.
+func isLatencySensitive(task string) bool {
+	return task == TaskPull || task == TaskPush
+}
+
+func isTask(task string) bool {
+	for _, known := range tasks {
+		if task == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateSchedules checks whether the given schedules refer to known tasks
+// and can be parsed.
+func validateSchedules(schedules map[string]string) error {
+	names := make([]string, 0, len(schedules))
+	for task := range schedules {
+		names = append(names, task)
+	}
+	sort.Strings(names)
+
+	for _, task := range names {
+		if !isTask(task) {
+			return fmt.Errorf("unknown scheduled task [%v]", task)
+		}
+
+		if _, err := ParseSchedule(schedules[task]); err != nil {
+			return fmt.Errorf(
+				"invalid schedule of task [%v]: [%v]",
+				task,
+				err,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2020, 11, 2, 10, 7, 30, 0, time.UTC) // Monday
+
+	var tests = map[string]struct {
+		spec          string
+		expectedNext  time.Time
+		expectedError bool
+	}{
+		"duration": {
+			spec:         "30s",
+			expectedNext: from.Add(30 * time.Second),
+		},
+		"every": {
+			spec:         "@every 5m",
+			expectedNext: from.Add(5 * time.Minute),
+		},
+		"adaptive": {
+			spec:         "@adaptive 10s 1m",
+			expectedNext: from.Add(10 * time.Second),
+		},
+		"cron step": {
+			spec:         "*/15 * * * *",
+			expectedNext: time.Date(2020, 11, 2, 10, 15, 0, 0, time.UTC),
+		},
+		"cron list and range": {
+			spec:         "0,30 22-23 * * *",
+			expectedNext: time.Date(2020, 11, 2, 22, 0, 0, 0, time.UTC),
+		},
+		"cron day of week": {
+			spec:         "0 3 * * 0",
+			expectedNext: time.Date(2020, 11, 8, 3, 0, 0, 0, time.UTC),
+		},
+		"empty": {
+			spec:          "",
+			expectedError: true,
+		},
+		"negative duration": {
+			spec:          "-5s",
+			expectedError: true,
+		},
+		"adaptive with max below min": {
+			spec:          "@adaptive 1m 10s",
+			expectedError: true,
+		},
+		"cron value out of range": {
+			spec:          "0 24 * * *",
+			expectedError: true,
+		},
+		"cron invalid step": {
+			spec:          "*/0 * * * *",
+			expectedError: true,
+		},
+		"wrong number of fields": {
+			spec:          "0 0 * *",
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			schedule, err := ParseSchedule(test.spec)
+			if test.expectedError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next := schedule.Next(from, false)
+			if !test.expectedNext.Equal(next) {
+				t.Errorf(
+					"unexpected next run:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedNext,
+					next,
+				)
+			}
+		})
+	}
+}
+
+func TestAdaptiveSchedule(t *testing.T) {
+	schedule, err := ParseSchedule("@adaptive 10s 35s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Unix(0, 0)
+
+	var intervals []time.Duration
+	for _, idle := range []bool{true, true, true, true, false, true} {
+		intervals = append(intervals, schedule.Next(from, idle).Sub(from))
+	}
+
+	expectedIntervals := []time.Duration{
+		10 * time.Second,
+		20 * time.Second,
+		35 * time.Second,
+		35 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	}
+
+	if !reflect.DeepEqual(expectedIntervals, intervals) {
+		t.Errorf(
+			"unexpected intervals:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedIntervals,
+			intervals,
+		)
+	}
+}
+
+func waitInBackground(
+	ctx context.Context,
+	scheduler *Scheduler,
+	wake <-chan struct{},
+) <-chan bool {
+	done := make(chan bool, 1)
+	go func() {
+		woken, _ := scheduler.wait(ctx, TaskPull, time.Minute, false, wake)
+		done <- woken
+	}()
+	return done
+}
+
+func expectWaitDone(t *testing.T, done <-chan bool, expectedWoken bool) {
+	t.Helper()
+
+	select {
+	case woken := <-done:
+		if expectedWoken != woken {
+			t.Errorf(
+				"unexpected woken flag:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				expectedWoken,
+				woken,
+			)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait should be done")
+	}
+}
+
+func TestScheduler_Wait(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	scheduler := NewScheduler(fakeClock, nil)
+
+	done := waitInBackground(context.Background(), scheduler, nil)
+	fakeClock.BlockUntil(1)
+
+	fakeClock.Advance(59 * time.Second)
+	if fakeClock.Waiters() != 1 {
+		t.Fatal("task should wait for the default interval")
+	}
+
+	fakeClock.Advance(time.Second)
+	expectWaitDone(t, done, false)
+
+	if err := scheduler.SetSchedule(TaskPull, "@every 10s"); err != nil {
+		t.Fatal(err)
+	}
+
+	done = waitInBackground(context.Background(), scheduler, nil)
+	fakeClock.BlockUntil(1)
+
+	fakeClock.Advance(10 * time.Second)
+	expectWaitDone(t, done, false)
+}
+
+func TestScheduler_SetScheduleWakesWaitingTasks(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	scheduler := NewScheduler(fakeClock, nil)
+
+	done := waitInBackground(context.Background(), scheduler, nil)
+	fakeClock.BlockUntil(1)
+
+	// Part of the default interval has already passed.
+	fakeClock.Advance(5 * time.Second)
+
+	if err := scheduler.SetSchedule(TaskPull, "20s"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The task is rescheduled 20 seconds after it started waiting.
+	fakeClock.Advance(15 * time.Second)
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(0)
+	expectWaitDone(t, done, false)
+
+	expectedSchedules := map[string]string{TaskPull: "20s"}
+	if !reflect.DeepEqual(expectedSchedules, scheduler.Schedules()) {
+		t.Errorf(
+			"unexpected schedules:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedSchedules,
+			scheduler.Schedules(),
+		)
+	}
+
+	if err := scheduler.SetSchedule(TaskPull, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scheduler.Schedules()) != 0 {
+		t.Errorf("default schedule should be restored")
+	}
+}
+
+func TestScheduler_PauseResume(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	scheduler := NewScheduler(fakeClock, nil)
+	scheduler.Pause()
+
+	if !scheduler.IsPaused() {
+		t.Fatal("scheduler should be paused")
+	}
+
+	done := waitInBackground(context.Background(), scheduler, nil)
+
+	select {
+	case <-done:
+		t.Fatal("wait should block while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if fakeClock.Waiters() != 0 {
+		t.Fatal("no timer should be set while paused")
+	}
+
+	fakeClock.Advance(2 * time.Minute)
+	scheduler.Resume()
+
+	// The run is overdue so the task runs right away.
+	expectWaitDone(t, done, false)
+}
+
+func TestScheduler_Wake(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	scheduler := NewScheduler(fakeClock, nil)
+
+	wake := make(chan struct{}, 1)
+	done := waitInBackground(context.Background(), scheduler, wake)
+	fakeClock.BlockUntil(1)
+
+	wake <- struct{}{}
+	expectWaitDone(t, done, true)
+}
+
+func TestScheduler_WaitContextCancelled(t *testing.T) {
+	scheduler := NewScheduler(clock.NewFake(time.Unix(1000, 0)), nil)
+	scheduler.Pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := scheduler.wait(ctx, TaskPull, time.Minute, false, nil); err == nil {
+		t.Fatal("expected error on context cancellation")
+	}
+}
+
+func TestScheduler_SetScheduleErrors(t *testing.T) {
+	scheduler := NewScheduler(clock.System, nil)
+
+	if err := scheduler.SetSchedule("unknown", "10s"); err == nil {
+		t.Error("expected error for unknown task")
+	}
+
+	if err := scheduler.SetSchedule(TaskPush, "sometimes"); err == nil {
+		t.Error("expected error for invalid schedule")
+	}
+}
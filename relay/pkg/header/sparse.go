@@ -0,0 +1,87 @@
+package header
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// sparse.go file contains the sparse mode of the relay, for operators
+// prioritizing the cost over the proof granularity. Relay contracts
+// accepting non-contiguous headers can be pushed only every n-th header,
+// plus the last header of each difficulty epoch and the first header of the
+// next one, so retargets can still be validated. Each pushed batch is
+// anchored by the previously pushed header instead of the parent of its
+// first header.
+
+// isSkipped checks whether the header at the given height is not pushed in
+// the sparse mode. No header is skipped if the sparse mode is disabled.
+func (r *Relay) isSkipped(height int64) bool {
+	if r.skipInterval <= 1 {
+		return false
+	}
+
+	epochMod := height % r.difficultyEpochDuration
+	if epochMod == 0 || epochMod == r.difficultyEpochDuration-1 {
+		return false
+	}
+
+	return height%r.skipInterval != 0
+}
+
+// anchorHeight returns the height of the header anchoring the batch starting
+// with the header at the given height. It is the parent of that header
+// unless headers are skipped in the sparse mode. In that mode, it is the
+// previously pushed header or the best header the relay started from.
+func (r *Relay) anchorHeight(height int64) int64 {
+	for anchor := height - 1; anchor > r.sparseStartHeight; anchor-- {
+		if !r.isSkipped(anchor) {
+			return anchor
+		}
+	}
+
+	return r.sparseStartHeight
+}
+
+// getAnchorHeader returns the header anchoring the batch starting with the
+// given header.
+func (r *Relay) getAnchorHeader(
+	ctx context.Context,
+	first *btc.Header,
+) (*btc.Header, error) {
+	if r.skipInterval <= 1 {
+		return r.btcChain.GetHeaderByDigest(ctx, first.PrevHash)
+	}
+
+	return r.btcChain.GetHeaderByHeight(ctx, r.anchorHeight(first.Height))
+}
+
+// verifySparseSupport checks whether the relay contract accepts headers
+// as far apart as they are pushed in the sparse mode.
+func (r *Relay) verifySparseSupport(ctx context.Context) error {
+	if r.skipInterval <= 1 {
+		return nil
+	}
+
+	maxGap, err := r.hostChain.MaxHeaderGap(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get maximum header gap: [%v]", err)
+	}
+
+	if int64(maxGap) < r.skipInterval {
+		return fmt.Errorf(
+			"relay contract accepts headers at most [%v] blocks apart; "+
+				"cannot push every [%v] header",
+			maxGap,
+			r.skipInterval,
+		)
+	}
+
+	r.logger.Infof(
+		"sparse mode enabled; pushing every [%v] header",
+		r.skipInterval,
+	)
+
+	return nil
+}
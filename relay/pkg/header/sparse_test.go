@@ -0,0 +1,160 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+func TestIsSkipped(t *testing.T) {
+	relay := &Relay{
+		logger:                  testLogger,
+		difficultyEpochDuration: 20,
+		skipInterval:            6,
+	}
+
+	pushed := make([]int64, 0)
+	for height := int64(30); height <= 45; height++ {
+		if !relay.isSkipped(height) {
+			pushed = append(pushed, height)
+		}
+	}
+
+	// Multiples of the interval and the epoch boundaries at 39 and 40.
+	expectedPushed := []int64{30, 36, 39, 40, 42}
+	if !reflect.DeepEqual(expectedPushed, pushed) {
+		t.Errorf(
+			"unexpected pushed heights\nexpected: [%v]\nactual:   [%v]",
+			expectedPushed,
+			pushed,
+		)
+	}
+
+	relay.skipInterval = 0
+	if relay.isSkipped(31) {
+		t.Errorf("no header should be skipped if the sparse mode is disabled")
+	}
+}
+
+func TestAnchorHeight(t *testing.T) {
+	relay := &Relay{
+		logger:                  testLogger,
+		difficultyEpochDuration: 20,
+		skipInterval:            6,
+		sparseStartHeight:       33,
+	}
+
+	var tests = map[string]struct {
+		height         int64
+		expectedAnchor int64
+	}{
+		"first pushed header": {
+			height:         36,
+			expectedAnchor: 33,
+		},
+		"previously pushed header": {
+			height:         42,
+			expectedAnchor: 40,
+		},
+		"epoch end": {
+			height:         39,
+			expectedAnchor: 36,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			anchor := relay.anchorHeight(test.height)
+			if anchor != test.expectedAnchor {
+				t.Errorf(
+					"unexpected anchor\nexpected: [%v]\nactual:   [%v]",
+					test.expectedAnchor,
+					anchor,
+				)
+			}
+		})
+	}
+}
+
+func TestPushHeadersToHostChain_Sparse(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+	btcChain.SetHeaders([]*btc.Header{
+		{Hash: [32]byte{1}, Height: 1, Raw: []byte{1}},
+		{Hash: [32]byte{2}, Height: 2, Raw: []byte{2}},
+		{Hash: [32]byte{3}, Height: 3, Raw: []byte{3}},
+		{Hash: [32]byte{4}, Height: 4, Raw: []byte{4}},
+	})
+
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	relay := &Relay{
+		logger:                  testLogger,
+		btcChain:                btcChain,
+		hostChain:               localChain,
+		store:                   store.OpenMemory(),
+		difficultyEpochDuration: btcDifficultyEpochDuration,
+		skipInterval:            4,
+		sparseStartHeight:       1,
+		observer:                &mockObserver{},
+	}
+
+	headers := []*btc.Header{
+		{Hash: [32]byte{8}, Height: 8, PrevHash: [32]byte{7}, Raw: []byte{8}},
+		{Hash: [32]byte{12}, Height: 12, PrevHash: [32]byte{11}, Raw: []byte{12}},
+	}
+
+	if err := relay.pushHeadersToHostChain(
+		context.Background(),
+		headers,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedEvents := []*chainlocal.AddHeadersEvent{
+		{AnchorHeader: []byte{4}, Headers: []byte{8, 12}},
+	}
+	if !reflect.DeepEqual(expectedEvents, localChain.AddHeadersEvents()) {
+		t.Errorf(
+			"unexpected add headers events\nexpected: [%+v]\nactual:   [%+v]",
+			expectedEvents,
+			localChain.AddHeadersEvents(),
+		)
+	}
+}
+
+func TestVerifySparseSupport(t *testing.T) {
+	lc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localChain := lc.(*chainlocal.Chain)
+
+	relay := &Relay{logger: testLogger, hostChain: localChain, skipInterval: 6}
+
+	err = relay.verifySparseSupport(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "at most [1] blocks") {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+
+	localChain.SetMaxHeaderGap(10)
+
+	if err := relay.verifySparseSupport(context.Background()); err != nil {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+}
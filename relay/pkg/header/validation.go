@@ -0,0 +1,168 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// validation.go file contains the logic which checks pulled headers against
+// the contextual rules applied by Bitcoin full nodes. The relay does not
+// verify headers the way full nodes do, so a misbehaving or misconfigured
+// Bitcoin node could make it relay headers the rest of the network rejects.
+// Checking the contextual rules lets the relay report such headers instead
+// of silently relaying them.
+
+// validateHeader checks the pulled header against the contextual rules.
+// Depending on the header validation mode, an error is returned for
+// an invalid header or the violations are only logged.
+func (r *Relay) validateHeader(
+	ctx context.Context,
+	header *btc.Header,
+) error {
+	if r.headerValidation == HeaderValidationOff {
+		return nil
+	}
+
+	if !r.headerValidator.Extends(header) {
+		err := r.resetHeaderValidator(ctx, header)
+		if validationErr, ok := err.(*btc.ValidationError); ok {
+			return r.rejectInconsistent(validationErr)
+		}
+		if err != nil {
+			// A header not matching its own serialized form makes the
+			// ancestors of an unrelated chain be looked up, so it must not
+			// skip the validation.
+			if violations, _ := btc.Inconsistencies(header); len(violations) > 0 {
+				return r.rejectInconsistent(&btc.ValidationError{
+					Header:     header,
+					Violations: violations,
+				})
+			}
+
+			r.logger.Warnf(
+				"could not load ancestors of header [%v]; "+
+					"skipping contextual validation: [%v]",
+				header.Height,
+				err,
+			)
+			return nil
+		}
+	}
+
+	err := r.headerValidator.Validate(header, r.timeSource().Now())
+	if err == nil {
+		return nil
+	}
+
+	if r.headerValidation == HeaderValidationWarn {
+		r.logger.Errorf("relaying header despite failed validation: [%v]", err)
+
+		// Make the header part of the context anyway so the subsequent
+		// headers can be validated against it.
+		if err := r.headerValidator.Accept(header); err != nil {
+			r.logger.Warnf(
+				"could not add header [%v] to validation context: [%v]",
+				header.Height,
+				err,
+			)
+		}
+
+		return nil
+	}
+
+	return err
+}
+
+// rejectInconsistent handles data returned by the Bitcoin node which is not
+// consistent with itself. Such data is never made part of the validator
+// context, even if only warnings are logged.
+func (r *Relay) rejectInconsistent(err *btc.ValidationError) error {
+	if r.headerValidation == HeaderValidationWarn {
+		r.logger.Errorf("relaying header despite failed validation: [%v]", err)
+		return nil
+	}
+
+	return err
+}
+
+// resetHeaderValidator loads the ancestors of the given header from the
+// Bitcoin chain and sets them as the header validator context. A
+// *btc.ValidationError is returned if the loaded ancestors are not
+// consistent with the header.
+func (r *Relay) resetHeaderValidator(
+	ctx context.Context,
+	header *btc.Header,
+) error {
+	ancestors := make([]*btc.Header, 0)
+
+	digest := header.PrevHash
+	for len(ancestors) < btc.MedianTimeBlocks &&
+		header.Height-int64(len(ancestors)) > 0 {
+		ancestor, err := r.btcChain.GetHeaderByDigest(ctx, digest)
+		if err != nil {
+			return fmt.Errorf(
+				"could not get header [%v]: [%v]",
+				digest,
+				err,
+			)
+		}
+
+		if violation := ancestorInconsistency(
+			ancestor,
+			digest,
+			header.Height-int64(len(ancestors))-1,
+		); violation != "" {
+			return &btc.ValidationError{
+				Header:     header,
+				Violations: []string{violation},
+			}
+		}
+
+		ancestors = append([]*btc.Header{ancestor}, ancestors...)
+		digest = ancestor.PrevHash
+	}
+
+	return r.headerValidator.Reset(ancestors)
+}
+
+// ancestorInconsistency checks whether the ancestor returned by the Bitcoin
+// node for the given digest is the header with that digest at the given
+// height. A description of the inconsistency is returned, or an empty string
+// if the ancestor is consistent.
+func ancestorInconsistency(
+	ancestor *btc.Header,
+	digest btc.Digest,
+	height int64,
+) string {
+	if ancestor.Hash != digest {
+		return fmt.Sprintf(
+			"ancestor [%v] returned for digest [%v]",
+			ancestor.Hash,
+			digest,
+		)
+	}
+
+	if ancestor.Height != height {
+		return fmt.Sprintf(
+			"ancestor [%v] has height [%v] instead of [%v]",
+			digest,
+			ancestor.Height,
+			height,
+		)
+	}
+
+	// Ancestors which cannot be deserialized at all are reported when
+	// setting the validator context.
+	if violations, _ := btc.Inconsistencies(ancestor); len(violations) > 0 {
+		return fmt.Sprintf(
+			"ancestor [%v] is inconsistent: [%v]",
+			digest,
+			strings.Join(violations, "; "),
+		)
+	}
+
+	return ""
+}
@@ -0,0 +1,112 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestValidateHeader(t *testing.T) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	genesisTime := time.Now().Add(-24 * time.Hour)
+
+	ancestor := serializedHeader(t, btc.Digest{}, 0, genesisTime, 4)
+	btcChain.SetHeaders([]*btc.Header{ancestor})
+
+	validHeader := serializedHeader(
+		t,
+		ancestor.Hash,
+		1,
+		genesisTime.Add(10*time.Minute),
+		4,
+	)
+	staleHeader := serializedHeader(t, ancestor.Hash, 1, genesisTime, 4)
+
+	var tests = map[string]struct {
+		mode        string
+		header      *btc.Header
+		expectError bool
+	}{
+		"valid header in enforce mode": {
+			mode:        HeaderValidationEnforce,
+			header:      validHeader,
+			expectError: false,
+		},
+		"invalid header in enforce mode": {
+			mode:        HeaderValidationEnforce,
+			header:      staleHeader,
+			expectError: true,
+		},
+		"invalid header in warn mode": {
+			mode:        HeaderValidationWarn,
+			header:      staleHeader,
+			expectError: false,
+		},
+		"invalid header with validation off": {
+			mode:        HeaderValidationOff,
+			header:      staleHeader,
+			expectError: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			relay := &Relay{
+				logger:           testLogger,
+				btcChain:         btcChain,
+				headerValidation: test.mode,
+				headerValidator:  btc.NewHeaderValidator(btcChain.NetworkParams()),
+			}
+
+			err := relay.validateHeader(context.Background(), test.header)
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Errorf(
+					"unexpected validation result:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+		})
+	}
+}
+
+func serializedHeader(
+	t *testing.T,
+	prevHash btc.Digest,
+	height int64,
+	timestamp time.Time,
+	version int32,
+) *btc.Header {
+	blockHeader := &wire.BlockHeader{
+		Version:   version,
+		PrevBlock: chainhash.Hash(prevHash),
+		Timestamp: timestamp,
+	}
+
+	var buffer bytes.Buffer
+	if err := blockHeader.Serialize(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	return &btc.Header{
+		Hash:     btc.Digest(blockHeader.BlockHash()),
+		Height:   height,
+		PrevHash: prevHash,
+		Raw:      buffer.Bytes(),
+	}
+}
@@ -0,0 +1,198 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+// watchdog.go file contains the watchdog detecting a silently stuck pulling
+// or pushing loop, e.g. one blocked forever on a channel or on a call not
+// respecting the context, so the relay does not appear alive while doing
+// nothing. The loops are busy unless they legitimately wait, e.g. for the
+// next scheduled run, for the operator to resume pushing or for the other
+// loop to catch up. A loop busy for longer than the watchdog timeout is
+// considered stuck and either the relay is restarted or the process crashes
+// loudly, dumping the stacks of all goroutines.
+
+const (
+	// WatchdogRestart makes the relay restart once the watchdog detects
+	// a stuck loop. The stuck loop cannot be interrupted and is abandoned.
+	WatchdogRestart = "restart"
+
+	// WatchdogCrash makes the relay process crash once the watchdog detects
+	// a stuck loop, dumping the stacks of all goroutines.
+	WatchdogCrash = "crash"
+)
+
+// Names of the loops monitored by the watchdog.
+const (
+	pullingLoopName = "pulling"
+	pushingLoopName = "pushing"
+)
+
+// Tick of the watchdog loop.
+const watchdogTick = 30 * time.Second
+
+// watchdog tracks the time for which the monitored relay loops are busy.
+type watchdog struct {
+	mutex sync.Mutex
+	clock clock.Clock
+	loops map[string]*loopState
+}
+
+// loopState is the state of a single loop monitored by the watchdog.
+type loopState struct {
+	// busySince is the time the loop became busy the last time.
+	busySince time.Time
+	// idleDepth is the number of nested waits the loop is in. The loop is
+	// busy if it is zero.
+	idleDepth int
+}
+
+// watchedLoop is the loop monitored by the watchdog, carried by the context
+// of the loop.
+type watchedLoop struct {
+	watchdog *watchdog
+	name     string
+}
+
+type watchedLoopKey struct{}
+
+func newWatchdog(clock clock.Clock) *watchdog {
+	return &watchdog{
+		clock: clock,
+		loops: make(map[string]*loopState),
+	}
+}
+
+// watch starts monitoring the loop with the given name, which is busy from
+// now on. Returns the context the loop should run with, so its waits can
+// mark it idle. A nil watchdog returns the given context.
+func (w *watchdog) watch(ctx context.Context, name string) context.Context {
+	if w == nil {
+		return ctx
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.loops[name] = &loopState{busySince: w.clock.Now()}
+
+	return context.WithValue(ctx, watchedLoopKey{}, &watchedLoop{w, name})
+}
+
+// unwatch stops monitoring the loop with the given name once it exits.
+func (w *watchdog) unwatch(name string) {
+	if w == nil {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	delete(w.loops, name)
+}
+
+func (w *watchdog) setIdle(name string, idle bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	state, ok := w.loops[name]
+	if !ok {
+		return
+	}
+
+	if idle {
+		state.idleDepth++
+		return
+	}
+
+	state.idleDepth--
+	if state.idleDepth == 0 {
+		state.busySince = w.clock.Now()
+	}
+}
+
+// stuckLoops returns the names of the loops busy for longer than the given
+// timeout.
+func (w *watchdog) stuckLoops(timeout time.Duration) []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := w.clock.Now()
+
+	stuck := make([]string, 0)
+	for name, state := range w.loops {
+		if state.idleDepth == 0 && now.Sub(state.busySince) > timeout {
+			stuck = append(stuck, name)
+		}
+	}
+	sort.Strings(stuck)
+
+	return stuck
+}
+
+// Idle marks the relay loop running with the given context as idle until
+// the returned function is called, so the watchdog does not consider
+// a legitimately long wait a stuck loop. Pipeline stages holding back
+// headers for long should call it while they wait. It has no effect if the
+// context is not a context of a loop monitored by the watchdog.
+func Idle(ctx context.Context) (resume func()) {
+	loop, ok := ctx.Value(watchedLoopKey{}).(*watchedLoop)
+	if !ok {
+		return func() {}
+	}
+
+	loop.watchdog.setIdle(loop.name, true)
+
+	return func() {
+		loop.watchdog.setIdle(loop.name, false)
+	}
+}
+
+// watchdogLoop checks the monitored loops periodically. Once a stuck loop is
+// detected, the relay is restarted or the process crashes, depending on the
+// configured action.
+func (r *Relay) watchdogLoop(ctx context.Context, cancelLoops func()) {
+	r.logger.Infof("starting new watchdog loop")
+	defer r.logger.Infof("stopping current watchdog loop")
+
+	ticker := r.timeSource().NewTicker(watchdogTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+
+		stuck := r.watchdog.stuckLoops(r.watchdogTimeout)
+		if len(stuck) == 0 {
+			continue
+		}
+
+		err := fmt.Errorf(
+			"headers %v loops are stuck for more than [%v]",
+			stuck,
+			r.watchdogTimeout,
+		)
+
+		if r.watchdogAction == WatchdogCrash {
+			debug.SetTraceback("all")
+			panic(err)
+		}
+
+		r.logger.Errorf("%v; abandoning stuck loops and restarting relay", err)
+
+		r.raiseError(err)
+		cancelLoops()
+		return
+	}
+}
@@ -0,0 +1,138 @@
+package header
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+func TestWatchdog_StuckLoops(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	watchdog := newWatchdog(fakeClock)
+
+	pullingCtx := watchdog.watch(context.Background(), pullingLoopName)
+	_ = watchdog.watch(context.Background(), pushingLoopName)
+
+	resume := Idle(pullingCtx)
+
+	fakeClock.Advance(10 * time.Minute)
+
+	expectedStuck := []string{pushingLoopName}
+	if stuck := watchdog.stuckLoops(5 * time.Minute); !reflect.DeepEqual(
+		stuck,
+		expectedStuck,
+	) {
+		t.Errorf(
+			"unexpected stuck loops\nexpected: [%v]\nactual:   [%v]",
+			expectedStuck,
+			stuck,
+		)
+	}
+
+	// The loop is busy again since the wait is over.
+	resume()
+	watchdog.unwatch(pushingLoopName)
+
+	fakeClock.Advance(4 * time.Minute)
+
+	if stuck := watchdog.stuckLoops(5 * time.Minute); len(stuck) != 0 {
+		t.Errorf("unexpected stuck loops: [%v]", stuck)
+	}
+
+	fakeClock.Advance(2 * time.Minute)
+
+	expectedStuck = []string{pullingLoopName}
+	if stuck := watchdog.stuckLoops(5 * time.Minute); !reflect.DeepEqual(
+		stuck,
+		expectedStuck,
+	) {
+		t.Errorf(
+			"unexpected stuck loops\nexpected: [%v]\nactual:   [%v]",
+			expectedStuck,
+			stuck,
+		)
+	}
+}
+
+func TestWatchdog_NestedIdle(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	watchdog := newWatchdog(fakeClock)
+
+	ctx := watchdog.watch(context.Background(), pushingLoopName)
+
+	resumeOuter := Idle(ctx)
+	resumeInner := Idle(ctx)
+	resumeInner()
+
+	fakeClock.Advance(10 * time.Minute)
+
+	if stuck := watchdog.stuckLoops(5 * time.Minute); len(stuck) != 0 {
+		t.Errorf("loop in the outer wait should be idle: [%v]", stuck)
+	}
+
+	resumeOuter()
+}
+
+func TestIdle_NotWatched(t *testing.T) {
+	// Must not panic for contexts of loops not monitored by the watchdog.
+	Idle(context.Background())()
+}
+
+func TestWatchdogLoop_Restart(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+	relay := &Relay{
+		logger:          testLogger,
+		clock:           fakeClock,
+		watchdog:        newWatchdog(fakeClock),
+		watchdogTimeout: 5 * time.Minute,
+		watchdogAction:  WatchdogRestart,
+		errChan:         make(chan error, 1),
+	}
+
+	loopCtx, cancelLoopCtx := context.WithCancel(context.Background())
+	defer cancelLoopCtx()
+
+	_ = relay.watchdog.watch(loopCtx, pushingLoopName)
+
+	exited := make(chan struct{})
+	go func() {
+		relay.watchdogLoop(loopCtx, cancelLoopCtx)
+		close(exited)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(watchdogTick)
+
+	select {
+	case err := <-relay.ErrChan():
+		t.Fatalf("unexpected error before the timeout: [%v]", err)
+	default:
+	}
+
+	for i := 0; i < 10; i++ {
+		fakeClock.Advance(watchdogTick)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchdog loop did not exit")
+	}
+
+	select {
+	case err := <-relay.ErrChan():
+		if err == nil {
+			t.Fatal("expected stuck loop error")
+		}
+	default:
+		t.Fatal("stuck loop error not raised")
+	}
+
+	if loopCtx.Err() == nil {
+		t.Errorf("relay loops should be cancelled")
+	}
+}
@@ -0,0 +1,313 @@
+package headerstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// compact.go file contains the compact encodings of headers used by the
+// header store and snapshots. Headers of a chain are highly redundant: the
+// previous header digest is the digest of the previous header, the version
+// and the difficulty bits rarely change and timestamps are close to each
+// other. Only the merkle root and the nonce carry new information.
+//
+// Stored headers linking to the previous stored header omit the previous
+// header digest, taking 48 instead of 80 bytes. Snapshots encode the whole
+// range of headers with a delta encoding, taking about 40 bytes per header,
+// instead of hex-encoding every header with its digest.
+
+// Offsets of the header fields in the 80-byte serialized header.
+const (
+	versionOffset    = 0
+	prevHashOffset   = 4
+	merkleRootOffset = 36
+	timestampOffset  = 68
+	bitsOffset       = 72
+	nonceOffset      = 76
+	headerSize       = 80
+
+	// Size of a stored header without the previous header digest.
+	linkedRowSize = headerSize - 32
+)
+
+// Flags of a header in the delta encoding.
+const (
+	// deltaUnlinked means the header does not link to the previous header,
+	// so its previous header digest is encoded.
+	deltaUnlinked = 1 << iota
+	// deltaVersion means the version differs from the previous header.
+	deltaVersion
+	// deltaBits means the difficulty bits differ from the previous header.
+	deltaBits
+)
+
+// encodeRow encodes the header for the header store. If the header links to
+// the previous header, its previous header digest is omitted.
+func encodeRow(header *btc.Header, linked bool) []byte {
+	if !linked {
+		return header.Raw
+	}
+
+	row := make([]byte, 0, linkedRowSize)
+	row = append(row, header.Raw[:prevHashOffset]...)
+	row = append(row, header.Raw[merkleRootOffset:]...)
+
+	return row
+}
+
+// decodeRow decodes the header at the given height from the header store.
+// The previous header digest is required only if the row omits it. The
+// decoded header must match the given digest, so a row whose previous header
+// has been replaced, e.g. by a reorg, is never decoded into a wrong header.
+func decodeRow(
+	height int64,
+	row []byte,
+	digest []byte,
+	prevDigest []byte,
+) (*btc.Header, error) {
+	raw := row
+
+	if len(row) == linkedRowSize {
+		if len(prevDigest) != 32 {
+			return nil, fmt.Errorf(
+				"previous header of stored header [%v] is missing",
+				height,
+			)
+		}
+
+		raw = make([]byte, 0, headerSize)
+		raw = append(raw, row[:prevHashOffset]...)
+		raw = append(raw, prevDigest...)
+		raw = append(raw, row[prevHashOffset:]...)
+	}
+
+	header, err := btc.ParseHeader(height, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header.Hash[:], digest) {
+		return nil, fmt.Errorf(
+			"stored header [%v] does not match its digest",
+			height,
+		)
+	}
+
+	return header, nil
+}
+
+// encodeDelta encodes the given headers of contiguous heights with the delta
+// encoding. The encoding starts with the height of the first header and the
+// number of headers. Each header is then encoded as its flags, the fields
+// which cannot be derived from the previous header, the timestamp difference,
+// the merkle root and the nonce.
+func encodeDelta(headers []*btc.Header) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	writeVarint := func(value int64) {
+		encoded := make([]byte, binary.MaxVarintLen64)
+		buffer.Write(encoded[:binary.PutVarint(encoded, value)])
+	}
+
+	if len(headers) == 0 {
+		return buffer.Bytes(), nil
+	}
+
+	writeVarint(headers[0].Height)
+	writeVarint(int64(len(headers)))
+
+	var previous *btc.Header
+	for _, header := range headers {
+		if len(header.Raw) != headerSize {
+			return nil, fmt.Errorf(
+				"header [%v] has invalid size [%v]",
+				header.Height,
+				len(header.Raw),
+			)
+		}
+
+		var flags byte
+		var previousTimestamp int64
+
+		if previous == nil || header.PrevHash != previous.Hash {
+			flags |= deltaUnlinked
+		}
+		if previous == nil || !bytes.Equal(
+			header.Raw[versionOffset:prevHashOffset],
+			previous.Raw[versionOffset:prevHashOffset],
+		) {
+			flags |= deltaVersion
+		}
+		if previous == nil || !bytes.Equal(
+			header.Raw[bitsOffset:nonceOffset],
+			previous.Raw[bitsOffset:nonceOffset],
+		) {
+			flags |= deltaBits
+		}
+
+		if previous != nil {
+			if header.Height != previous.Height+1 {
+				return nil, fmt.Errorf(
+					"header [%v] does not follow header [%v]",
+					header.Height,
+					previous.Height,
+				)
+			}
+
+			previousTimestamp = timestamp(previous.Raw)
+		}
+
+		buffer.WriteByte(flags)
+		if flags&deltaUnlinked != 0 {
+			buffer.Write(header.Raw[prevHashOffset:merkleRootOffset])
+		}
+		if flags&deltaVersion != 0 {
+			buffer.Write(header.Raw[versionOffset:prevHashOffset])
+		}
+		if flags&deltaBits != 0 {
+			buffer.Write(header.Raw[bitsOffset:nonceOffset])
+		}
+		writeVarint(timestamp(header.Raw) - previousTimestamp)
+		buffer.Write(header.Raw[merkleRootOffset:timestampOffset])
+		buffer.Write(header.Raw[nonceOffset:])
+
+		previous = header
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// decodeDelta decodes the headers encoded with the delta encoding.
+func decodeDelta(data []byte) ([]*btc.Header, error) {
+	reader := bytes.NewReader(data)
+
+	if reader.Len() == 0 {
+		return []*btc.Header{}, nil
+	}
+
+	height, err := binary.ReadVarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode first height: [%v]", err)
+	}
+
+	count, err := binary.ReadVarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode headers count: [%v]", err)
+	}
+
+	// Each header takes dozens of bytes, so the number of remaining bytes
+	// bounds the allocation for a malformed count.
+	if count < 0 || count > int64(reader.Len()) {
+		return nil, fmt.Errorf("invalid headers count [%v]", count)
+	}
+
+	headers := make([]*btc.Header, 0, count)
+
+	var previous *btc.Header
+	for i := int64(0); i < count; i++ {
+		flags, err := reader.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode header [%v]: [%v]",
+				height+i,
+				err,
+			)
+		}
+
+		raw := make([]byte, headerSize)
+		var previousTimestamp int64
+
+		if previous != nil {
+			copy(raw[versionOffset:prevHashOffset], previous.Raw)
+			copy(raw[prevHashOffset:merkleRootOffset], previous.Hash[:])
+			copy(
+				raw[bitsOffset:nonceOffset],
+				previous.Raw[bitsOffset:nonceOffset],
+			)
+			previousTimestamp = timestamp(previous.Raw)
+		} else if flags&(deltaUnlinked|deltaVersion|deltaBits) !=
+			deltaUnlinked|deltaVersion|deltaBits {
+			return nil, fmt.Errorf("first header is not fully encoded")
+		}
+
+		fields := []struct {
+			flag   byte
+			offset int
+			end    int
+		}{
+			{deltaUnlinked, prevHashOffset, merkleRootOffset},
+			{deltaVersion, versionOffset, prevHashOffset},
+			{deltaBits, bitsOffset, nonceOffset},
+		}
+		for _, field := range fields {
+			if flags&field.flag == 0 {
+				continue
+			}
+
+			if _, err := io.ReadFull(reader, raw[field.offset:field.end]); err != nil {
+				return nil, fmt.Errorf(
+					"could not decode header [%v]: [%v]",
+					height+i,
+					err,
+				)
+			}
+		}
+
+		timestampDelta, err := binary.ReadVarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode timestamp of header [%v]: [%v]",
+				height+i,
+				err,
+			)
+		}
+		binary.LittleEndian.PutUint32(
+			raw[timestampOffset:bitsOffset],
+			uint32(previousTimestamp+timestampDelta),
+		)
+
+		if _, err := io.ReadFull(
+			reader,
+			raw[merkleRootOffset:timestampOffset],
+		); err != nil {
+			return nil, fmt.Errorf(
+				"could not decode header [%v]: [%v]",
+				height+i,
+				err,
+			)
+		}
+		if _, err := io.ReadFull(reader, raw[nonceOffset:]); err != nil {
+			return nil, fmt.Errorf(
+				"could not decode header [%v]: [%v]",
+				height+i,
+				err,
+			)
+		}
+
+		header, err := btc.ParseHeader(height+i, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		headers = append(headers, header)
+		previous = header
+	}
+
+	if reader.Len() != 0 {
+		return nil, fmt.Errorf(
+			"unexpected [%v] bytes after encoded headers",
+			reader.Len(),
+		)
+	}
+
+	return headers, nil
+}
+
+// timestamp returns the timestamp of the given serialized header.
+func timestamp(raw []byte) int64 {
+	return int64(binary.LittleEndian.Uint32(raw[timestampOffset:bitsOffset]))
+}
@@ -0,0 +1,120 @@
+package headerstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestDeltaEncoding(t *testing.T) {
+	headers := mineHeaders(t, 10)
+
+	// The version changes and a header does not link to the previous one,
+	// which the encoding must preserve. Proof of work is not checked.
+	modified := func(index int, modify func(raw []byte)) *btc.Header {
+		raw := append([]byte{}, headers[index].Raw...)
+		modify(raw)
+
+		header, err := btc.ParseHeader(headers[index].Height, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return header
+	}
+	headers[4] = modified(4, func(raw []byte) {
+		binary.LittleEndian.PutUint32(raw[versionOffset:], 0x20000000)
+	})
+	headers[7] = modified(7, func(raw []byte) {
+		raw[prevHashOffset] ^= 0xff
+	})
+
+	encoded, err := encodeDelta(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := len(encoded); size >= len(headers)*headerSize*3/4 {
+		t.Errorf("encoded headers are not compact: [%v] bytes", size)
+	}
+
+	decoded, err := decodeDelta(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded) != len(headers) {
+		t.Fatalf(
+			"unexpected number of decoded headers\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]",
+			len(headers),
+			len(decoded),
+		)
+	}
+
+	for i, header := range headers {
+		if !bytes.Equal(header.Raw, decoded[i].Raw) || !header.Equals(decoded[i]) {
+			t.Errorf(
+				"unexpected decoded header [%v]\n"+
+					"expected: [%x]\n"+
+					"actual:   [%x]",
+				header.Height,
+				header.Raw,
+				decoded[i].Raw,
+			)
+		}
+	}
+
+	if _, err := decodeDelta(encoded[:len(encoded)-1]); err == nil {
+		t.Errorf("expected error for truncated encoding")
+	}
+
+	if _, err := encodeDelta(
+		[]*btc.Header{headers[0], headers[2]},
+	); err == nil {
+		t.Errorf("expected error for headers of non-contiguous heights")
+	}
+}
+
+func TestRowEncoding(t *testing.T) {
+	headers := mineHeaders(t, 2)
+
+	row := encodeRow(headers[1], true)
+	if len(row) != linkedRowSize {
+		t.Fatalf("unexpected row size: [%v]", len(row))
+	}
+
+	decoded, err := decodeRow(
+		headers[1].Height,
+		row,
+		headers[1].Hash[:],
+		headers[0].Hash[:],
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !headers[1].Equals(decoded) {
+		t.Errorf("unexpected decoded header: [%v]", decoded)
+	}
+
+	// The previous header has been replaced, e.g. by a reorg.
+	if _, err := decodeRow(
+		headers[1].Height,
+		row,
+		headers[1].Hash[:],
+		headers[1].Hash[:],
+	); err == nil {
+		t.Errorf("expected error for replaced previous header")
+	}
+
+	if _, err := decodeRow(
+		headers[1].Height,
+		row,
+		headers[1].Hash[:],
+		nil,
+	); err == nil {
+		t.Errorf("expected error for missing previous header")
+	}
+}
@@ -0,0 +1,519 @@
+package headerstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+
+	// Registers the sqlite3 database driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var logger = log.Logger("tbtc-relay-headerstore")
+
+const schema = `
+CREATE TABLE IF NOT EXISTS headers (
+	height INTEGER PRIMARY KEY,
+	digest BLOB NOT NULL UNIQUE,
+	raw    BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS queue (
+	position INTEGER PRIMARY KEY,
+	height   INTEGER NOT NULL,
+	raw      BLOB NOT NULL
+);
+`
+
+// Config holds the configuration of the local header store.
+type Config struct {
+	// File is the path to the SQLite database file. If empty, the header
+	// store is not used.
+	File string
+
+	// SnapshotKeys is a list of hex-encoded Ed25519 public keys whose
+	// signatures are trusted on imported header snapshots.
+	SnapshotKeys []string
+
+	// Checkpoints is a list of headers known to be part of the Bitcoin
+	// chain in the `height:digest` format. An imported snapshot must pass
+	// through at least one of them.
+	Checkpoints []string
+
+	// PersistQueue enables persisting the headers queue of the relay in the
+	// header store, so headers pulled but not pushed yet survive a restart.
+	PersistQueue bool
+
+	// RetainHeaders is the number of the most recent headers kept in the
+	// header store. Older headers are pruned on startup and by the prune
+	// command. If zero, all headers are kept.
+	RetainHeaders int64
+}
+
+// IsEnabled checks whether the header store is configured.
+func (c *Config) IsEnabled() bool {
+	return c.File != ""
+}
+
+// Store is a local store of Bitcoin headers of the best chain, indexed by
+// their heights and digests.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the header store database, creating it if needed.
+func Open(config *Config) (*Store, error) {
+	db, err := sql.Open("sqlite3", config.File)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not open header store database [%v]: [%v]",
+			config.File,
+			err,
+		)
+	}
+
+	// SQLite does not support concurrent writers.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf(
+			"could not initialize header store database [%v]: [%v]",
+			config.File,
+			err,
+		)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the header store database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put stores the given headers replacing the headers stored at the same
+// heights.
+func (s *Store) Put(headers []*btc.Header) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: [%v]", err)
+	}
+
+	for i, header := range headers {
+		// Headers linking to the previous stored header are stored without
+		// the previous header digest.
+		linked := i > 0 && headers[i-1].Height == header.Height-1 &&
+			headers[i-1].Hash == header.PrevHash
+		if !linked {
+			var prevDigest []byte
+			err := tx.QueryRow(
+				"SELECT digest FROM headers WHERE height = ?",
+				header.Height-1,
+			).Scan(&prevDigest)
+			if err != nil && err != sql.ErrNoRows {
+				_ = tx.Rollback()
+				return fmt.Errorf(
+					"could not query header [%v]: [%v]",
+					header.Height-1,
+					err,
+				)
+			}
+
+			linked = bytes.Equal(prevDigest, header.PrevHash[:])
+		}
+
+		if _, err := tx.Exec(
+			"INSERT OR REPLACE INTO headers VALUES (?, ?, ?)",
+			header.Height,
+			header.Hash[:],
+			encodeRow(header, linked),
+		); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf(
+				"could not store header [%v]: [%v]",
+				header.Height,
+				err,
+			)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit headers: [%v]", err)
+	}
+
+	return nil
+}
+
+// HeaderByHeight returns the header stored at the given height or nil if
+// there is no such header.
+func (s *Store) HeaderByHeight(height int64) (*btc.Header, error) {
+	return queryHeader(s.db, selectHeader+"WHERE h.height = ?", height)
+}
+
+// HeaderByDigest returns the header with the given digest or nil if there is
+// no such header.
+func (s *Store) HeaderByDigest(digest btc.Digest) (*btc.Header, error) {
+	return queryHeader(s.db, selectHeader+"WHERE h.digest = ?", digest[:])
+}
+
+// HeightByDigest returns the height of the header with the given digest.
+// The second return value is false if there is no such header.
+func (s *Store) HeightByDigest(digest btc.Digest) (int64, bool, error) {
+	var height int64
+
+	err := s.db.QueryRow(
+		"SELECT height FROM headers WHERE digest = ?",
+		digest[:],
+	).Scan(&height)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("could not query header height: [%v]", err)
+	}
+
+	return height, true, nil
+}
+
+// Tip returns the highest stored header or nil if the store is empty.
+func (s *Store) Tip() (*btc.Header, error) {
+	return queryHeader(
+		s.db,
+		selectHeader+"ORDER BY h.height DESC LIMIT 1",
+	)
+}
+
+// Count returns the number of stored headers.
+func (s *Store) Count() (int64, error) {
+	var count int64
+	err := s.db.QueryRow("SELECT COUNT(*) FROM headers").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("could not count headers: [%v]", err)
+	}
+
+	return count, nil
+}
+
+// Prune removes the headers below the given number of the most recent
+// stored headers and returns the number of removed headers.
+func (s *Store) Prune(retain int64) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: [%v]", err)
+	}
+
+	var tip sql.NullInt64
+	if err := tx.QueryRow("SELECT MAX(height) FROM headers").Scan(
+		&tip,
+	); err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("could not query tip height: [%v]", err)
+	}
+
+	if !tip.Valid {
+		_ = tx.Rollback()
+		return 0, nil
+	}
+
+	// The lowest retained header loses its previous header, so it is
+	// stored in full once the older headers are removed.
+	lowest, err := queryHeader(
+		tx,
+		selectHeader+"WHERE h.height = ?",
+		tip.Int64-retain+1,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	result, err := tx.Exec(
+		"DELETE FROM headers WHERE height <= ?",
+		tip.Int64-retain,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("could not prune headers: [%v]", err)
+	}
+
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("could not count pruned headers: [%v]", err)
+	}
+
+	if lowest != nil {
+		if _, err := tx.Exec(
+			"UPDATE headers SET raw = ? WHERE height = ?",
+			encodeRow(lowest, false),
+			lowest.Height,
+		); err != nil {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf(
+				"could not store header [%v]: [%v]",
+				lowest.Height,
+				err,
+			)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit pruning: [%v]", err)
+	}
+
+	return pruned, nil
+}
+
+// Compact rebuilds the header store database, returning the space freed by
+// removed headers to the file system.
+func (s *Store) Compact() error {
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("could not compact header store: [%v]", err)
+	}
+
+	return nil
+}
+
+// SaveQueue replaces the persisted headers queue with the given headers.
+func (s *Store) SaveQueue(headers []*btc.Header) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: [%v]", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM queue"); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("could not clear queue: [%v]", err)
+	}
+
+	for position, header := range headers {
+		if _, err := tx.Exec(
+			"INSERT INTO queue VALUES (?, ?, ?)",
+			position,
+			header.Height,
+			header.Raw,
+		); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf(
+				"could not store queued header [%v]: [%v]",
+				header.Height,
+				err,
+			)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit queue: [%v]", err)
+	}
+
+	return nil
+}
+
+// LoadQueue returns the persisted headers queue in the queue order.
+func (s *Store) LoadQueue() ([]*btc.Header, error) {
+	rows, err := s.db.Query(
+		"SELECT height, raw FROM queue ORDER BY position",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query queue: [%v]", err)
+	}
+	defer rows.Close()
+
+	headers := make([]*btc.Header, 0)
+	for rows.Next() {
+		var height int64
+		var raw []byte
+
+		if err := rows.Scan(&height, &raw); err != nil {
+			return nil, fmt.Errorf("could not read queued header: [%v]", err)
+		}
+
+		header, err := btc.ParseHeader(height, raw)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not parse queued header [%v]: [%v]",
+				height,
+				err,
+			)
+		}
+
+		headers = append(headers, header)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read queue: [%v]", err)
+	}
+
+	return headers, nil
+}
+
+// selectHeader selects the stored header along with the digest of the
+// previous stored header, needed to decode headers stored without it.
+const selectHeader = "SELECT h.height, h.digest, h.raw, p.digest " +
+	"FROM headers h LEFT JOIN headers p ON p.height = h.height - 1 "
+
+// rowQuerier is implemented by both the database and its transactions.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func queryHeader(
+	querier rowQuerier,
+	query string,
+	args ...interface{},
+) (*btc.Header, error) {
+	var height int64
+	var digest, raw, prevDigest []byte
+
+	err := querier.QueryRow(query, args...).Scan(
+		&height,
+		&digest,
+		&raw,
+		&prevDigest,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query header: [%v]", err)
+	}
+
+	header, err := decodeRow(height, raw, digest, prevDigest)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not decode stored header [%v]: [%v]",
+			height,
+			err,
+		)
+	}
+
+	return header, nil
+}
+
+// cachedChain is a Bitcoin chain handle serving headers from the header
+// store. Headers missing in the store are fetched from the Bitcoin node.
+type cachedChain struct {
+	btc.Handle
+
+	store *Store
+}
+
+// WrapChain wraps the given Bitcoin chain handle so the headers available in
+// the header store are served without querying the Bitcoin node. The stored
+// tip is checked against the Bitcoin node first; if the node does not agree,
+// the store is not used and the original handle is returned.
+func WrapChain(
+	ctx context.Context,
+	btcChain btc.Handle,
+	store *Store,
+) (btc.Handle, error) {
+	tip, err := store.Tip()
+	if err != nil {
+		return nil, err
+	}
+
+	if tip == nil {
+		logger.Infof("header store is empty")
+		return btcChain, nil
+	}
+
+	nodeHeader, err := btcChain.GetHeaderByHeight(ctx, tip.Height)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get header [%v] from the Bitcoin node: [%v]",
+			tip.Height,
+			err,
+		)
+	}
+
+	if nodeHeader.Hash != tip.Hash {
+		logger.Warnf(
+			"stored header [%v] with digest [%v] is not on the best chain "+
+				"of the Bitcoin node; header store will not be used",
+			tip.Height,
+			tip.Hash,
+		)
+		return btcChain, nil
+	}
+
+	count, err := store.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof(
+		"serving [%v] headers up to height [%v] from the header store",
+		count,
+		tip.Height,
+	)
+
+	return &cachedChain{Handle: btcChain, store: store}, nil
+}
+
+// GetHeaderByHeight returns the stored header at the given height or fetches
+// it from the Bitcoin node if the header is not stored.
+func (cc *cachedChain) GetHeaderByHeight(
+	ctx context.Context,
+	height int64,
+) (*btc.Header, error) {
+	header, err := cc.store.HeaderByHeight(height)
+	if err != nil {
+		logger.Warnf(
+			"could not read header [%v] from store: [%v]",
+			height,
+			err,
+		)
+	}
+	if header != nil {
+		return header, nil
+	}
+
+	return cc.Handle.GetHeaderByHeight(ctx, height)
+}
+
+// GetHeaderByDigest returns the stored header with the given digest or
+// fetches it from the Bitcoin node if the header is not stored.
+func (cc *cachedChain) GetHeaderByDigest(
+	ctx context.Context,
+	digest btc.Digest,
+) (*btc.Header, error) {
+	header, err := cc.store.HeaderByDigest(digest)
+	if err != nil {
+		logger.Warnf(
+			"could not read header [%v] from store: [%v]",
+			digest,
+			err,
+		)
+	}
+	if header != nil {
+		return header, nil
+	}
+
+	return cc.Handle.GetHeaderByDigest(ctx, digest)
+}
+
+// GetHeightByDigest returns the height of the stored header with the given
+// digest or fetches it from the Bitcoin node if the header is not stored.
+func (cc *cachedChain) GetHeightByDigest(
+	ctx context.Context,
+	digest btc.Digest,
+) (int64, error) {
+	height, ok, err := cc.store.HeightByDigest(digest)
+	if err != nil {
+		logger.Warnf(
+			"could not read height of header [%v] from store: [%v]",
+			digest,
+			err,
+		)
+	}
+	if ok {
+		return height, nil
+	}
+
+	return cc.Handle.GetHeightByDigest(ctx, digest)
+}
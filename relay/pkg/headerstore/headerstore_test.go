@@ -0,0 +1,294 @@
+package headerstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestStore(t *testing.T) {
+	headers := mineHeaders(t, 5)
+
+	store, err := Open(&Config{File: filepath.Join(t.TempDir(), "headers.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	tip, err := store.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tip != nil {
+		t.Errorf("unexpected tip of empty store: [%v]", tip)
+	}
+
+	if err := store.Put(headers); err != nil {
+		t.Fatal(err)
+	}
+
+	byHeight, err := store.HeaderByHeight(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !headers[2].Equals(byHeight) {
+		t.Errorf(
+			"unexpected header by height:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			headers[2],
+			byHeight,
+		)
+	}
+
+	byDigest, err := store.HeaderByDigest(headers[1].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !headers[1].Equals(byDigest) {
+		t.Errorf(
+			"unexpected header by digest:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			headers[1],
+			byDigest,
+		)
+	}
+
+	height, ok, err := store.HeightByDigest(headers[3].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || height != headers[3].Height {
+		t.Errorf(
+			"unexpected height by digest:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			headers[3].Height,
+			height,
+		)
+	}
+
+	if _, ok, err := store.HeightByDigest(btc.Digest{0xff}); err != nil || ok {
+		t.Errorf("unexpected height of missing header: [%v] [%v]", ok, err)
+	}
+
+	missing, err := store.HeaderByHeight(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != nil {
+		t.Errorf("unexpected header: [%v]", missing)
+	}
+
+	tip, err = store.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !headers[4].Equals(tip) {
+		t.Errorf(
+			"unexpected tip:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			headers[4],
+			tip,
+		)
+	}
+}
+
+func TestWrapChain(t *testing.T) {
+	ctx := context.Background()
+	headers := mineHeaders(t, 5)
+
+	var tests = map[string]struct {
+		nodeHeaders  []*btc.Header
+		expectCached bool
+	}{
+		"node agrees with stored tip": {
+			nodeHeaders:  headers,
+			expectCached: true,
+		},
+		"node does not agree with stored tip": {
+			nodeHeaders: append(
+				append([]*btc.Header{}, headers[:4]...),
+				&btc.Header{Hash: headers[0].Hash, Height: 5},
+			),
+			expectCached: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			store, err := Open(&Config{
+				File: filepath.Join(t.TempDir(), "headers.db"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			if err := store.Put(headers); err != nil {
+				t.Fatal(err)
+			}
+
+			bc, err := btc.ConnectLocal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			btcChain := bc.(*btc.LocalChain)
+			btcChain.SetHeaders(test.nodeHeaders)
+
+			wrapped, err := WrapChain(ctx, btcChain, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, cached := wrapped.(*cachedChain)
+			if test.expectCached != cached {
+				t.Fatalf(
+					"unexpected header store usage:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectCached,
+					cached,
+				)
+			}
+
+			if !cached {
+				return
+			}
+
+			// Headers are served from the store even if the node loses them.
+			btcChain.SetHeaders([]*btc.Header{})
+
+			header, err := wrapped.GetHeaderByHeight(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !headers[1].Equals(header) {
+				t.Errorf("unexpected header: [%v]", header)
+			}
+
+			height, err := wrapped.GetHeightByDigest(ctx, headers[2].Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if height != headers[2].Height {
+				t.Errorf("unexpected height: [%v]", height)
+			}
+		})
+	}
+}
+
+func TestStore_Queue(t *testing.T) {
+	headers := mineHeaders(t, 5)
+
+	store, err := Open(&Config{File: filepath.Join(t.TempDir(), "headers.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	queue, err := store.LoadQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 0 {
+		t.Errorf("unexpected queue of empty store: [%v]", queue)
+	}
+
+	if err := store.SaveQueue(headers); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving the queue replaces the previously saved one.
+	if err := store.SaveQueue(headers[2:]); err != nil {
+		t.Fatal(err)
+	}
+
+	queue, err = store.LoadQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queue) != 3 {
+		t.Fatalf(
+			"unexpected queue length:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			3,
+			len(queue),
+		)
+	}
+
+	for i, header := range queue {
+		if !headers[i+2].Equals(header) {
+			t.Errorf(
+				"unexpected queued header [%v]:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				i,
+				headers[i+2],
+				header,
+			)
+		}
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	headers := mineHeaders(t, 5)
+
+	store, err := Open(&Config{File: filepath.Join(t.TempDir(), "headers.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err := store.Put(headers); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := store.Prune(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pruned != 3 {
+		t.Errorf(
+			"unexpected number of pruned headers\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]",
+			3,
+			pruned,
+		)
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if header, err := store.HeaderByHeight(headers[2].Height); err != nil ||
+		header != nil {
+		t.Errorf("header [%v] has not been pruned", headers[2].Height)
+	}
+
+	// The lowest retained header is stored without its previous header
+	// and must still be served.
+	lowest, err := store.HeaderByHeight(headers[3].Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !headers[3].Equals(lowest) {
+		t.Errorf("unexpected lowest header after pruning: [%v]", lowest)
+	}
+
+	tip, err := store.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !headers[4].Equals(tip) {
+		t.Errorf("unexpected tip after pruning: [%v]", tip)
+	}
+}
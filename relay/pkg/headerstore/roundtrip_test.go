@@ -0,0 +1,117 @@
+//go:build integration
+// +build integration
+
+package headerstore
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// roundtrip_test.go file contains the integration test checking that headers
+// fetched from a bitcoind node are served by the header store as byte-exact
+// copies. The node RPC backends themselves are checked against the node by
+// the round-trip tests of the btc package. The test runs only with the
+// `integration` build tag and is configured with the same environment
+// variables as those tests: RELAY_BTC_URL, RELAY_BTC_USERNAME,
+// RELAY_BTC_PASSWORD and RELAY_BTC_NETWORK.
+
+// Number of the most recent headers of the node checked by the test.
+const roundTripHeaders = 10
+
+func TestRoundTrip_StoredHeaders(t *testing.T) {
+	url := os.Getenv("RELAY_BTC_URL")
+	if url == "" {
+		t.Skip("RELAY_BTC_URL is not set")
+	}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	btcChain, err := btc.Connect(
+		ctx,
+		&btc.Config{
+			URL:      url,
+			Username: os.Getenv("RELAY_BTC_USERNAME"),
+			Password: os.Getenv("RELAY_BTC_PASSWORD"),
+			Network:  os.Getenv("RELAY_BTC_NETWORK"),
+		},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tip, err := btcChain.GetBlockCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := make([]*btc.Header, 0)
+	for height := tip - roundTripHeaders + 1; height <= tip; height++ {
+		if height < 0 {
+			continue
+		}
+
+		header, err := btcChain.GetHeaderByHeight(ctx, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers = append(headers, header)
+	}
+
+	store, err := Open(&Config{File: filepath.Join(t.TempDir(), "headers.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err := store.Put(headers); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SaveQueue(headers); err != nil {
+		t.Fatal(err)
+	}
+
+	cachedChain, err := WrapChain(ctx, btcChain, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue, err := store.LoadQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, header := range headers {
+		byHeight, err := cachedChain.GetHeaderByHeight(ctx, header.Height)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		byDigest, err := cachedChain.GetHeaderByDigest(ctx, header.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, stored := range []*btc.Header{byHeight, byDigest, queue[i]} {
+			if !bytes.Equal(header.Raw, stored.Raw) || !header.Equals(stored) {
+				t.Errorf(
+					"unexpected stored header [%v]\n"+
+						"expected: [%x]\n"+
+						"actual:   [%x]",
+					header.Height,
+					header.Raw,
+					stored.Raw,
+				)
+			}
+		}
+	}
+}
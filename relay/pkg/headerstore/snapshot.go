@@ -0,0 +1,414 @@
+package headerstore
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// snapshot.go file contains the logic of header snapshots used to bootstrap
+// the header store. A snapshot is a signed file containing a contiguous range
+// of historical headers. Before the headers are imported, the signature is
+// checked against the trusted keys, each header is checked to link to the
+// previous one with a valid proof of work and the whole range is checked to
+// pass through at least one known checkpoint, with no checkpoint conflicting
+// with the snapshot.
+
+// Snapshot is a signed, contiguous range of Bitcoin headers.
+type Snapshot struct {
+	// Network is the name of the Bitcoin network the headers belong to.
+	Network string
+	// Headers are the snapshot headers ordered by their heights.
+	Headers []*SnapshotHeader
+	// PublicKey is the hex-encoded Ed25519 public key of the signer.
+	PublicKey string
+	// Signature is the hex-encoded Ed25519 signature of the snapshot
+	// network and headers.
+	Signature string
+}
+
+// SnapshotHeader is a single header of the snapshot.
+type SnapshotHeader struct {
+	Height int64
+	Digest btc.Digest
+	// Raw is the hex-encoded 80-byte serialized header.
+	Raw string
+}
+
+// NewSnapshot creates an unsigned snapshot of the given headers of the
+// Bitcoin network with the given parameters.
+func NewSnapshot(params *chaincfg.Params, headers []*btc.Header) *Snapshot {
+	return &Snapshot{
+		Network: params.Name,
+		Headers: newSnapshotHeaders(headers),
+	}
+}
+
+func newSnapshotHeaders(headers []*btc.Header) []*SnapshotHeader {
+	snapshotHeaders := make([]*SnapshotHeader, len(headers))
+	for i, header := range headers {
+		snapshotHeaders[i] = &SnapshotHeader{
+			Height: header.Height,
+			Digest: header.Hash,
+			Raw:    hex.EncodeToString(header.Raw),
+		}
+	}
+
+	return snapshotHeaders
+}
+
+// snapshotEncodingDelta is the encoding of snapshot files storing headers
+// with the delta encoding.
+const snapshotEncodingDelta = "delta"
+
+// snapshotFile is the format of the snapshot file. Snapshots are written
+// with their headers delta-encoded, see encodeDelta. Snapshots written by
+// earlier relay versions, with hex-encoded headers, are still read.
+type snapshotFile struct {
+	Network  string
+	Encoding string `json:",omitempty"`
+	// Headers are set only in snapshots not using any encoding.
+	Headers []*SnapshotHeader `json:",omitempty"`
+	// EncodedHeaders are the headers encoded with the snapshot encoding.
+	EncodedHeaders []byte `json:",omitempty"`
+	PublicKey      string
+	Signature      string
+}
+
+// ReadSnapshot reads the snapshot from the given file.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read snapshot [%v]: [%v]", path, err)
+	}
+
+	file := &snapshotFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("could not decode snapshot [%v]: [%v]", path, err)
+	}
+
+	snapshot := &Snapshot{
+		Network:   file.Network,
+		Headers:   file.Headers,
+		PublicKey: file.PublicKey,
+		Signature: file.Signature,
+	}
+
+	switch file.Encoding {
+	case "":
+	case snapshotEncodingDelta:
+		headers, err := decodeDelta(file.EncodedHeaders)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode headers of snapshot [%v]: [%v]",
+				path,
+				err,
+			)
+		}
+
+		snapshot.Headers = newSnapshotHeaders(headers)
+	default:
+		return nil, fmt.Errorf(
+			"unsupported encoding [%v] of snapshot [%v]",
+			file.Encoding,
+			path,
+		)
+	}
+
+	return snapshot, nil
+}
+
+// Write writes the snapshot to the given file, with the headers
+// delta-encoded.
+func (s *Snapshot) Write(path string) error {
+	headers := make([]*btc.Header, len(s.Headers))
+	for i, snapshotHeader := range s.Headers {
+		raw, err := hex.DecodeString(snapshotHeader.Raw)
+		if err != nil {
+			return fmt.Errorf(
+				"could not decode snapshot header [%v]: [%v]",
+				snapshotHeader.Height,
+				err,
+			)
+		}
+
+		header, err := btc.ParseHeader(snapshotHeader.Height, raw)
+		if err != nil {
+			return fmt.Errorf(
+				"could not parse snapshot header [%v]: [%v]",
+				snapshotHeader.Height,
+				err,
+			)
+		}
+
+		headers[i] = header
+	}
+
+	encodedHeaders, err := encodeDelta(headers)
+	if err != nil {
+		return fmt.Errorf("could not encode snapshot headers: [%v]", err)
+	}
+
+	data, err := json.MarshalIndent(&snapshotFile{
+		Network:        s.Network,
+		Encoding:       snapshotEncodingDelta,
+		EncodedHeaders: encodedHeaders,
+		PublicKey:      s.PublicKey,
+		Signature:      s.Signature,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode snapshot: [%v]", err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("could not write snapshot [%v]: [%v]", path, err)
+	}
+
+	return nil
+}
+
+// Sign signs the snapshot with the given Ed25519 private key.
+func (s *Snapshot) Sign(privateKey ed25519.PrivateKey) error {
+	message, err := s.signedMessage()
+	if err != nil {
+		return err
+	}
+
+	s.PublicKey = hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	s.Signature = hex.EncodeToString(ed25519.Sign(privateKey, message))
+
+	return nil
+}
+
+// Verify checks whether the snapshot has been signed by one of the trusted
+// keys, forms a valid chain of headers of the Bitcoin network with the given
+// parameters and passes through at least one of the given checkpoints without
+// conflicting with any of them. Verified headers are returned.
+func (s *Snapshot) Verify(
+	params *chaincfg.Params,
+	trustedKeys []string,
+	checkpoints []*btc.Checkpoint,
+) ([]*btc.Header, error) {
+	if s.Network != params.Name {
+		return nil, fmt.Errorf(
+			"snapshot of network [%v] cannot be used with network [%v]",
+			s.Network,
+			params.Name,
+		)
+	}
+
+	if len(s.Headers) == 0 {
+		return nil, fmt.Errorf("snapshot has no headers")
+	}
+
+	if err := s.verifySignature(trustedKeys); err != nil {
+		return nil, err
+	}
+
+	headers, err := s.verifyChain(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyCheckpoints(headers, checkpoints); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+func (s *Snapshot) verifySignature(trustedKeys []string) error {
+	trusted := false
+	for _, key := range trustedKeys {
+		if key == s.PublicKey {
+			trusted = true
+			break
+		}
+	}
+
+	if !trusted {
+		return fmt.Errorf(
+			"snapshot is signed by untrusted key [%v]",
+			s.PublicKey,
+		)
+	}
+
+	publicKey, err := hex.DecodeString(s.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid snapshot public key [%v]", s.PublicKey)
+	}
+
+	signature, err := hex.DecodeString(s.Signature)
+	if err != nil {
+		return fmt.Errorf("could not decode snapshot signature: [%v]", err)
+	}
+
+	message, err := s.signedMessage()
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, message, signature) {
+		return fmt.Errorf("invalid snapshot signature")
+	}
+
+	return nil
+}
+
+// signedMessage returns the hash of the snapshot network and all headers
+// with their heights and digests, which is the message being signed.
+func (s *Snapshot) signedMessage() ([]byte, error) {
+	hash := sha256.New()
+	hash.Write([]byte(s.Network))
+
+	for _, header := range s.Headers {
+		raw, err := hex.DecodeString(header.Raw)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode snapshot header [%v]: [%v]",
+				header.Height,
+				err,
+			)
+		}
+
+		height := make([]byte, 8)
+		binary.BigEndian.PutUint64(height, uint64(header.Height))
+
+		hash.Write(height)
+		hash.Write(header.Digest[:])
+		hash.Write(raw)
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// verifyChain parses the snapshot headers and checks each of them matches
+// its digest, has enough proof of work and links to the previous header.
+func (s *Snapshot) verifyChain(params *chaincfg.Params) ([]*btc.Header, error) {
+	headers := make([]*btc.Header, len(s.Headers))
+
+	for i, snapshotHeader := range s.Headers {
+		raw, err := hex.DecodeString(snapshotHeader.Raw)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode snapshot header [%v]: [%v]",
+				snapshotHeader.Height,
+				err,
+			)
+		}
+
+		header, err := btc.ParseHeader(snapshotHeader.Height, raw)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not parse snapshot header [%v]: [%v]",
+				snapshotHeader.Height,
+				err,
+			)
+		}
+
+		if header.Hash != snapshotHeader.Digest {
+			return nil, fmt.Errorf(
+				"digest of snapshot header [%v] does not match its data",
+				header.Height,
+			)
+		}
+
+		if err := checkProofOfWork(params, raw, header); err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			previous := headers[i-1]
+
+			if header.Height != previous.Height+1 ||
+				header.PrevHash != previous.Hash {
+				return nil, fmt.Errorf(
+					"snapshot header [%v] does not link to header [%v]",
+					header.Height,
+					previous.Height,
+				)
+			}
+		}
+
+		headers[i] = header
+	}
+
+	return headers, nil
+}
+
+func checkProofOfWork(
+	params *chaincfg.Params,
+	raw []byte,
+	header *btc.Header,
+) error {
+	// The difficulty bits are serialized at the bytes 72-76 of the header.
+	target := blockchain.CompactToBig(binary.LittleEndian.Uint32(raw[72:76]))
+
+	if target.Sign() <= 0 || target.Cmp(params.PowLimit) > 0 {
+		return fmt.Errorf(
+			"snapshot header [%v] has target out of range",
+			header.Height,
+		)
+	}
+
+	hash := chainhash.Hash(header.Hash)
+	if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return fmt.Errorf(
+			"snapshot header [%v] does not have enough proof of work",
+			header.Height,
+		)
+	}
+
+	return nil
+}
+
+// verifyCheckpoints checks the headers pass through at least one of the
+// checkpoints and none of the checkpoints within the range of the headers
+// conflicts with them.
+func verifyCheckpoints(
+	headers []*btc.Header,
+	checkpoints []*btc.Checkpoint,
+) error {
+	first := headers[0].Height
+	last := headers[len(headers)-1].Height
+
+	matched := 0
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Height < first || checkpoint.Height > last {
+			continue
+		}
+
+		header := headers[checkpoint.Height-first]
+		if header.Hash != checkpoint.Digest {
+			return fmt.Errorf(
+				"snapshot header [%v] with digest [%v] conflicts with "+
+					"checkpoint [%v]",
+				header.Height,
+				header.Hash,
+				checkpoint,
+			)
+		}
+
+		matched++
+	}
+
+	if matched == 0 {
+		return fmt.Errorf(
+			"snapshot headers from [%v] to [%v] do not pass through any "+
+				"known checkpoint",
+			first,
+			last,
+		)
+	}
+
+	return nil
+}
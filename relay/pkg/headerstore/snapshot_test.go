@@ -0,0 +1,278 @@
+package headerstore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// mineHeaders creates a chain of the given number of regtest headers
+// starting right after the regtest genesis block.
+func mineHeaders(t *testing.T, count int) []*btc.Header {
+	params := &chaincfg.RegressionNetParams
+
+	headers := make([]*btc.Header, 0, count)
+	prevHash := *params.GenesisHash
+
+	for height := int64(1); height <= int64(count); height++ {
+		blockHeader := &wire.BlockHeader{
+			Version:   4,
+			PrevBlock: prevHash,
+			Timestamp: time.Unix(1600000000+height*600, 0),
+			Bits:      params.PowLimitBits,
+		}
+
+		target := blockchain.CompactToBig(blockHeader.Bits)
+		for {
+			hash := blockHeader.BlockHash()
+			if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+				break
+			}
+			blockHeader.Nonce++
+		}
+
+		var buffer bytes.Buffer
+		if err := blockHeader.Serialize(&buffer); err != nil {
+			t.Fatal(err)
+		}
+
+		header, err := btc.ParseHeader(height, buffer.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers = append(headers, header)
+		prevHash = chainhash.Hash(header.Hash)
+	}
+
+	return headers
+}
+
+func TestSnapshotVerify(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	headers := mineHeaders(t, 10)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedKeys := []string{hex.EncodeToString(publicKey)}
+
+	checkpoint := &btc.Checkpoint{Height: 5, Digest: headers[4].Hash}
+
+	signedSnapshot := func(modify func(snapshot *Snapshot)) *Snapshot {
+		snapshot := NewSnapshot(params, headers)
+		if modify != nil {
+			modify(snapshot)
+		}
+		if err := snapshot.Sign(privateKey); err != nil {
+			t.Fatal(err)
+		}
+		return snapshot
+	}
+
+	var tests = map[string]struct {
+		snapshot    *Snapshot
+		params      *chaincfg.Params
+		trustedKeys []string
+		checkpoints []*btc.Checkpoint
+		expectError bool
+	}{
+		"valid snapshot": {
+			snapshot:    signedSnapshot(nil),
+			params:      params,
+			trustedKeys: trustedKeys,
+			checkpoints: []*btc.Checkpoint{checkpoint},
+		},
+		"untrusted key": {
+			snapshot:    signedSnapshot(nil),
+			params:      params,
+			trustedKeys: []string{},
+			checkpoints: []*btc.Checkpoint{checkpoint},
+			expectError: true,
+		},
+		"tampered signed data": {
+			snapshot: func() *Snapshot {
+				snapshot := signedSnapshot(nil)
+				snapshot.Headers[3].Height = 100
+				return snapshot
+			}(),
+			params:      params,
+			trustedKeys: trustedKeys,
+			checkpoints: []*btc.Checkpoint{checkpoint},
+			expectError: true,
+		},
+		"digest not matching header data": {
+			snapshot: signedSnapshot(func(snapshot *Snapshot) {
+				snapshot.Headers[3].Digest = headers[2].Hash
+			}),
+			params:      params,
+			trustedKeys: trustedKeys,
+			checkpoints: []*btc.Checkpoint{checkpoint},
+			expectError: true,
+		},
+		"headers not linked": {
+			snapshot: signedSnapshot(func(snapshot *Snapshot) {
+				snapshot.Headers = append(
+					snapshot.Headers[:3],
+					snapshot.Headers[4:]...,
+				)
+			}),
+			params:      params,
+			trustedKeys: trustedKeys,
+			checkpoints: []*btc.Checkpoint{checkpoint},
+			expectError: true,
+		},
+		"no checkpoint within snapshot": {
+			snapshot:    signedSnapshot(nil),
+			params:      params,
+			trustedKeys: trustedKeys,
+			checkpoints: []*btc.Checkpoint{
+				{Height: 50, Digest: headers[4].Hash},
+			},
+			expectError: true,
+		},
+		"conflicting checkpoint": {
+			snapshot:    signedSnapshot(nil),
+			params:      params,
+			trustedKeys: trustedKeys,
+			checkpoints: []*btc.Checkpoint{
+				checkpoint,
+				{Height: 6, Digest: headers[4].Hash},
+			},
+			expectError: true,
+		},
+		"other network": {
+			snapshot:    signedSnapshot(nil),
+			params:      &chaincfg.MainNetParams,
+			trustedKeys: trustedKeys,
+			checkpoints: []*btc.Checkpoint{checkpoint},
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			verified, err := test.snapshot.Verify(
+				test.params,
+				test.trustedKeys,
+				test.checkpoints,
+			)
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Fatalf(
+					"unexpected error:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+
+			if !test.expectError && len(verified) != len(headers) {
+				t.Errorf(
+					"unexpected number of verified headers:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					len(headers),
+					len(verified),
+				)
+			}
+		})
+	}
+}
+
+func TestCheckProofOfWork_TargetAboveLimit(t *testing.T) {
+	// Regtest headers have a target above the mainnet limit.
+	headers := mineHeaders(t, 1)
+
+	if err := checkProofOfWork(
+		&chaincfg.MainNetParams,
+		headers[0].Raw,
+		headers[0],
+	); err == nil {
+		t.Errorf("expected error for target above the limit")
+	}
+}
+
+func TestSnapshotWriteRead(t *testing.T) {
+	headers := mineHeaders(t, 3)
+
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := NewSnapshot(&chaincfg.RegressionNetParams, headers)
+	if err := snapshot.Sign(privateKey); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := read.Verify(
+		&chaincfg.RegressionNetParams,
+		[]string{snapshot.PublicKey},
+		[]*btc.Checkpoint{{Height: 1, Digest: headers[0].Hash}},
+	); err != nil {
+		t.Errorf("could not verify read snapshot: [%v]", err)
+	}
+}
+
+func TestReadSnapshot_Unencoded(t *testing.T) {
+	headers := mineHeaders(t, 3)
+
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := NewSnapshot(&chaincfg.RegressionNetParams, headers)
+	if err := snapshot.Sign(privateKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshots written by earlier relay versions keep hex-encoded headers.
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := read.Verify(
+		&chaincfg.RegressionNetParams,
+		[]string{snapshot.PublicKey},
+		[]*btc.Checkpoint{{Height: 1, Digest: headers[0].Hash}},
+	); err != nil {
+		t.Errorf("could not verify read snapshot: [%v]", err)
+	}
+}
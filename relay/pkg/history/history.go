@@ -0,0 +1,486 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/node"
+	"github.com/keep-network/tbtc/relay/pkg/rewards"
+
+	// Registers the sqlite3 database driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var logger = log.Logger("tbtc-relay-history")
+
+const (
+	// DefaultTick is the default interval in which samples are recorded.
+	DefaultTick = 60 * time.Second
+
+	// DefaultRetentionDays is the default number of days for which samples
+	// are kept.
+	DefaultRetentionDays = 30
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS samples (
+	timestamp      INTEGER NOT NULL,
+	relay_lag      INTEGER NOT NULL,
+	headers_pulled INTEGER NOT NULL,
+	headers_pushed INTEGER NOT NULL,
+	relay_errors   INTEGER NOT NULL,
+	gas_price_gwei REAL
+);
+CREATE INDEX IF NOT EXISTS samples_timestamp ON samples (timestamp);
+CREATE TABLE IF NOT EXISTS relay_advances (
+	transaction_hash TEXT PRIMARY KEY,
+	block_number     INTEGER NOT NULL,
+	timestamp        INTEGER NOT NULL,
+	submitter        TEXT NOT NULL,
+	own              INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS relay_advances_timestamp
+	ON relay_advances (timestamp);
+CREATE TABLE IF NOT EXISTS own_advance_fees (
+	transaction_hash TEXT PRIMARY KEY,
+	timestamp        INTEGER NOT NULL,
+	fee              TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS reward_claims (
+	timestamp INTEGER NOT NULL,
+	amount    TEXT NOT NULL
+);
+`
+
+// Config holds the configuration of the metrics history.
+type Config struct {
+	// File is the path to the SQLite database file. If empty, the history
+	// is not recorded.
+	File string
+
+	// RetentionDays is the number of days for which samples are kept.
+	// If zero, a default value is used.
+	RetentionDays int
+
+	// MaxSamples is the maximum number of samples kept. The oldest samples
+	// exceeding it are pruned even within the retention period. If zero,
+	// samples are pruned only by their age.
+	MaxSamples int
+
+	// Tick is the interval, in seconds, in which samples are recorded.
+	// If zero, a default value is used.
+	Tick int
+}
+
+// IsEnabled checks whether the metrics history is configured.
+func (c *Config) IsEnabled() bool {
+	return c.File != ""
+}
+
+// Sample is a single record of the metrics history.
+type Sample struct {
+	Timestamp     time.Time
+	RelayLag      int64
+	HeadersPulled int64
+	HeadersPushed int64
+	RelayErrors   int64
+	// GasPriceGwei is nil if the gas price could not be determined.
+	GasPriceGwei *float64
+}
+
+// History is a local, rotating store of the relay metrics.
+type History struct {
+	db         *sql.DB
+	retention  time.Duration
+	maxSamples int
+}
+
+// Open opens the metrics history database, creating it if needed.
+func Open(config *Config) (*History, error) {
+	db, err := sql.Open("sqlite3", config.File)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not open history database [%v]: [%v]",
+			config.File,
+			err,
+		)
+	}
+
+	// SQLite does not support concurrent writers.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf(
+			"could not initialize history database [%v]: [%v]",
+			config.File,
+			err,
+		)
+	}
+
+	retentionDays := config.RetentionDays
+	if retentionDays <= 0 {
+		retentionDays = DefaultRetentionDays
+	}
+
+	return &History{
+		db:         db,
+		retention:  time.Duration(retentionDays) * 24 * time.Hour,
+		maxSamples: config.MaxSamples,
+	}, nil
+}
+
+// Close closes the history database.
+func (h *History) Close() error {
+	return h.db.Close()
+}
+
+// Record stores the given sample.
+func (h *History) Record(sample *Sample) error {
+	var gasPrice sql.NullFloat64
+	if sample.GasPriceGwei != nil {
+		gasPrice = sql.NullFloat64{Float64: *sample.GasPriceGwei, Valid: true}
+	}
+
+	_, err := h.db.Exec(
+		"INSERT INTO samples VALUES (?, ?, ?, ?, ?, ?)",
+		sample.Timestamp.Unix(),
+		sample.RelayLag,
+		sample.HeadersPulled,
+		sample.HeadersPushed,
+		sample.RelayErrors,
+		gasPrice,
+	)
+	if err != nil {
+		return fmt.Errorf("could not record sample: [%v]", err)
+	}
+
+	return nil
+}
+
+// RecordAdvances stores the given relay advances. Advances which are
+// already stored are ignored.
+func (h *History) RecordAdvances(advances []*competition.Advance) error {
+	for _, advance := range advances {
+		_, err := h.db.Exec(
+			"INSERT OR IGNORE INTO relay_advances VALUES (?, ?, ?, ?, ?)",
+			advance.TransactionHash,
+			advance.BlockNumber,
+			advance.Timestamp.Unix(),
+			advance.Submitter,
+			advance.Own,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"could not record relay advance [%v]: [%v]",
+				advance.TransactionHash,
+				err,
+			)
+		}
+
+		if !advance.Own || advance.Fee == nil {
+			continue
+		}
+
+		_, err = h.db.Exec(
+			"INSERT OR IGNORE INTO own_advance_fees VALUES (?, ?, ?)",
+			advance.TransactionHash,
+			advance.Timestamp.Unix(),
+			advance.Fee.String(),
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"could not record fee of relay advance [%v]: [%v]",
+				advance.TransactionHash,
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// RecordClaim stores the given reward claim.
+func (h *History) RecordClaim(claim *rewards.Claim) error {
+	_, err := h.db.Exec(
+		"INSERT INTO reward_claims VALUES (?, ?)",
+		claim.Timestamp.Unix(),
+		claim.Amount.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not record reward claim: [%v]", err)
+	}
+
+	return nil
+}
+
+// Prune removes samples and relay advances older than the retention period
+// and the oldest samples exceeding the maximum number of samples.
+func (h *History) Prune(now time.Time) error {
+	_, err := h.db.Exec(
+		"DELETE FROM samples WHERE timestamp < ?",
+		now.Add(-h.retention).Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not prune samples: [%v]", err)
+	}
+
+	_, err = h.db.Exec(
+		"DELETE FROM relay_advances WHERE timestamp < ?",
+		now.Add(-h.retention).Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not prune relay advances: [%v]", err)
+	}
+
+	_, err = h.db.Exec(
+		"DELETE FROM own_advance_fees WHERE timestamp < ?",
+		now.Add(-h.retention).Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not prune relay advance fees: [%v]", err)
+	}
+
+	_, err = h.db.Exec(
+		"DELETE FROM reward_claims WHERE timestamp < ?",
+		now.Add(-h.retention).Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not prune reward claims: [%v]", err)
+	}
+
+	if h.maxSamples > 0 {
+		_, err = h.db.Exec(
+			"DELETE FROM samples WHERE timestamp < "+
+				"(SELECT timestamp FROM samples "+
+				"ORDER BY timestamp DESC LIMIT 1 OFFSET ?)",
+			h.maxSamples-1,
+		)
+		if err != nil {
+			return fmt.Errorf("could not prune excess samples: [%v]", err)
+		}
+	}
+
+	return nil
+}
+
+// Compact rebuilds the history database, returning the space freed by
+// pruned records to the file system.
+func (h *History) Compact() error {
+	if _, err := h.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("could not compact history: [%v]", err)
+	}
+
+	return nil
+}
+
+// Advances returns all relay advances recorded since the given time, ordered
+// by their timestamps.
+func (h *History) Advances(since time.Time) ([]*competition.Advance, error) {
+	rows, err := h.db.Query(
+		"SELECT transaction_hash, block_number, timestamp, submitter, own "+
+			"FROM relay_advances WHERE timestamp >= ? "+
+			"ORDER BY timestamp, block_number",
+		since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query relay advances: [%v]", err)
+	}
+	defer rows.Close()
+
+	advances := make([]*competition.Advance, 0)
+	for rows.Next() {
+		var timestamp int64
+
+		advance := &competition.Advance{}
+		if err := rows.Scan(
+			&advance.TransactionHash,
+			&advance.BlockNumber,
+			&timestamp,
+			&advance.Submitter,
+			&advance.Own,
+		); err != nil {
+			return nil, fmt.Errorf("could not read relay advance: [%v]", err)
+		}
+
+		advance.Timestamp = time.Unix(timestamp, 0)
+
+		advances = append(advances, advance)
+	}
+
+	return advances, rows.Err()
+}
+
+// Claims returns all reward claims recorded since the given time, ordered
+// by their timestamps.
+func (h *History) Claims(since time.Time) ([]*rewards.Claim, error) {
+	rows, err := h.db.Query(
+		"SELECT timestamp, amount FROM reward_claims WHERE timestamp >= ? "+
+			"ORDER BY timestamp",
+		since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query reward claims: [%v]", err)
+	}
+	defer rows.Close()
+
+	claims := make([]*rewards.Claim, 0)
+	for rows.Next() {
+		var timestamp int64
+		var amount string
+
+		if err := rows.Scan(&timestamp, &amount); err != nil {
+			return nil, fmt.Errorf("could not read reward claim: [%v]", err)
+		}
+
+		value, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid reward claim amount [%v]", amount)
+		}
+
+		claims = append(claims, &rewards.Claim{
+			Timestamp: time.Unix(timestamp, 0),
+			Amount:    value,
+		})
+	}
+
+	return claims, rows.Err()
+}
+
+// GasExpenditure returns the total amount of fees paid for relay advances
+// made by this relay maintainer since the given time.
+func (h *History) GasExpenditure(since time.Time) (*big.Int, error) {
+	rows, err := h.db.Query(
+		"SELECT fee FROM own_advance_fees WHERE timestamp >= ?",
+		since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query relay advance fees: [%v]", err)
+	}
+	defer rows.Close()
+
+	expenditure := big.NewInt(0)
+	for rows.Next() {
+		var fee string
+
+		if err := rows.Scan(&fee); err != nil {
+			return nil, fmt.Errorf("could not read relay advance fee: [%v]", err)
+		}
+
+		value, ok := new(big.Int).SetString(fee, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid relay advance fee [%v]", fee)
+		}
+
+		expenditure.Add(expenditure, value)
+	}
+
+	return expenditure, rows.Err()
+}
+
+// Samples returns all samples recorded since the given time, ordered by
+// their timestamps.
+func (h *History) Samples(since time.Time) ([]*Sample, error) {
+	rows, err := h.db.Query(
+		"SELECT timestamp, relay_lag, headers_pulled, headers_pushed, "+
+			"relay_errors, gas_price_gwei FROM samples "+
+			"WHERE timestamp >= ? ORDER BY timestamp",
+		since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query samples: [%v]", err)
+	}
+	defer rows.Close()
+
+	samples := make([]*Sample, 0)
+	for rows.Next() {
+		var timestamp int64
+		var gasPrice sql.NullFloat64
+
+		sample := &Sample{}
+		if err := rows.Scan(
+			&timestamp,
+			&sample.RelayLag,
+			&sample.HeadersPulled,
+			&sample.HeadersPushed,
+			&sample.RelayErrors,
+			&gasPrice,
+		); err != nil {
+			return nil, fmt.Errorf("could not read sample: [%v]", err)
+		}
+
+		sample.Timestamp = time.Unix(timestamp, 0)
+		if gasPrice.Valid {
+			value := gasPrice.Float64
+			sample.GasPriceGwei = &value
+		}
+
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// StartRecording starts recording samples of the node statistics and the
+// host chain gas price in the given tick. Recording stops once the passed
+// context is done.
+func (h *History) StartRecording(
+	ctx context.Context,
+	nodeStats node.Stats,
+	gasOracle chain.GasOracle,
+	tick time.Duration,
+) {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				sample := &Sample{
+					Timestamp:     now,
+					RelayLag:      nodeStats.HeadersRelayLag(),
+					HeadersPulled: int64(nodeStats.UniqueHeadersPulled()),
+					HeadersPushed: int64(nodeStats.UniqueHeadersPushed()),
+					RelayErrors:   int64(nodeStats.HeadersRelayErrors()),
+					GasPriceGwei:  gasPriceGwei(ctx, gasOracle),
+				}
+
+				if err := h.Record(sample); err != nil {
+					logger.Warnf("could not record history sample: [%v]", err)
+				}
+
+				if err := h.Prune(now); err != nil {
+					logger.Warnf("could not prune history: [%v]", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func gasPriceGwei(ctx context.Context, gasOracle chain.GasOracle) *float64 {
+	gasPrice, err := gasOracle.GetGasPrice(ctx)
+	if err != nil {
+		logger.Debugf("could not get gas price: [%v]", err)
+		return nil
+	}
+
+	gwei, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(gasPrice),
+		big.NewFloat(1e9),
+	).Float64()
+
+	return &gwei
+}
@@ -0,0 +1,371 @@
+package history
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/rewards"
+)
+
+func TestHistory_RecordPruneSamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	history, err := Open(&Config{
+		File:          filepath.Join(dir, "history.db"),
+		RetentionDays: 7,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	now := time.Unix(1600000000, 0)
+	gasPrice := 42.5
+
+	for _, sample := range []*Sample{
+		{Timestamp: now.Add(-10 * 24 * time.Hour), RelayLag: 9},
+		{Timestamp: now.Add(-2 * time.Hour), RelayLag: 3},
+		{Timestamp: now.Add(-1 * time.Hour), RelayLag: 1, GasPriceGwei: &gasPrice},
+	} {
+		if err := history.Record(sample); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := history.Prune(now); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := history.Samples(time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 2 {
+		t.Fatalf("unexpected number of samples: [%v]", len(samples))
+	}
+
+	if samples[0].GasPriceGwei != nil {
+		t.Errorf("unexpected gas price: [%v]", *samples[0].GasPriceGwei)
+	}
+
+	if samples[1].GasPriceGwei == nil || *samples[1].GasPriceGwei != gasPrice {
+		t.Errorf("unexpected gas price: [%v]", samples[1].GasPriceGwei)
+	}
+}
+
+func TestHistory_PruneExcessSamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	history, err := Open(&Config{
+		File:       filepath.Join(dir, "history.db"),
+		MaxSamples: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	now := time.Unix(1600000000, 0)
+
+	for i := 3; i > 0; i-- {
+		if err := history.Record(&Sample{
+			Timestamp: now.Add(-time.Duration(i) * time.Hour),
+			RelayLag:  int64(i),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := history.Prune(now); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := history.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := history.Samples(time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 2 || samples[0].RelayLag != 2 || samples[1].RelayLag != 1 {
+		t.Errorf("unexpected samples kept: [%v]", len(samples))
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	gasPrice1 := 10.0
+	gasPrice2 := 30.0
+
+	samples := []*Sample{
+		{
+			Timestamp:     now,
+			RelayLag:      2,
+			HeadersPulled: 10,
+			HeadersPushed: 8,
+			GasPriceGwei:  &gasPrice1,
+		},
+		{
+			Timestamp:     now.Add(time.Minute),
+			RelayLag:      4,
+			HeadersPulled: 15,
+			HeadersPushed: 12,
+			RelayErrors:   1,
+		},
+		// The relay process has been restarted so the counters got reset.
+		{
+			Timestamp:     now.Add(2 * time.Minute),
+			RelayLag:      0,
+			HeadersPulled: 3,
+			HeadersPushed: 3,
+			GasPriceGwei:  &gasPrice2,
+		},
+	}
+
+	report := Summarize(now, samples)
+
+	expected := &Report{
+		Since:               now,
+		Until:               now.Add(2 * time.Minute),
+		Samples:             3,
+		AverageLag:          2,
+		MaxLag:              4,
+		HeadersPulled:       8,
+		HeadersPushed:       7,
+		RelayErrors:         1,
+		AverageGasPriceGwei: 20,
+		MaxGasPriceGwei:     30,
+		GasPriceSamples:     2,
+	}
+
+	if *expected != *report {
+		t.Errorf(
+			"unexpected report:\n"+
+				"expected: [%+v]\n"+
+				"actual:   [%+v]\n",
+			expected,
+			report,
+		)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	var tests = map[string]struct {
+		value    string
+		expected time.Time
+	}{
+		"days": {
+			value:    "7d",
+			expected: now.Add(-7 * 24 * time.Hour),
+		},
+		"duration": {
+			value:    "12h",
+			expected: now.Add(-12 * time.Hour),
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := ParseSince(test.value, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !test.expected.Equal(actual) {
+				t.Errorf(
+					"unexpected time:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expected,
+					actual,
+				)
+			}
+		})
+	}
+
+	if _, err := ParseSince("week", now); err == nil {
+		t.Error("expected error for invalid period")
+	}
+}
+
+func TestHistory_RecordAdvances(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	history, err := Open(&Config{
+		File:          filepath.Join(dir, "history.db"),
+		RetentionDays: 7,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	now := time.Unix(1600000000, 0)
+
+	advances := []*competition.Advance{
+		{
+			BlockNumber:     1,
+			Timestamp:       now.Add(-10 * 24 * time.Hour),
+			TransactionHash: "0x01",
+			Submitter:       "0xabc",
+			Own:             true,
+		},
+		{
+			BlockNumber:     2,
+			Timestamp:       now.Add(-2 * time.Hour),
+			TransactionHash: "0x02",
+			Submitter:       "0xdef",
+		},
+		{
+			BlockNumber:     3,
+			Timestamp:       now.Add(-1 * time.Hour),
+			TransactionHash: "0x03",
+			Submitter:       "0xabc",
+			Own:             true,
+		},
+	}
+
+	if err := history.RecordAdvances(advances); err != nil {
+		t.Fatal(err)
+	}
+
+	// Already recorded advances should be ignored.
+	if err := history.RecordAdvances(advances[2:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := history.Prune(now); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded, err := history.Advances(time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorded) != 2 {
+		t.Fatalf("unexpected number of advances: [%v]", len(recorded))
+	}
+
+	if recorded[0].TransactionHash != "0x02" || recorded[0].Own {
+		t.Errorf("unexpected advance: [%+v]", recorded[0])
+	}
+
+	if recorded[1].TransactionHash != "0x03" || !recorded[1].Own {
+		t.Errorf("unexpected advance: [%+v]", recorded[1])
+	}
+}
+
+func TestHistory_RewardsAndGasExpenditure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	history, err := Open(&Config{
+		File:          filepath.Join(dir, "history.db"),
+		RetentionDays: 7,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	now := time.Unix(1600000000, 0)
+
+	advances := []*competition.Advance{
+		{
+			BlockNumber:     1,
+			Timestamp:       now.Add(-10 * 24 * time.Hour),
+			TransactionHash: "0x01",
+			Submitter:       "0xabc",
+			Own:             true,
+			Fee:             big.NewInt(100),
+		},
+		{
+			BlockNumber:     2,
+			Timestamp:       now.Add(-2 * time.Hour),
+			TransactionHash: "0x02",
+			Submitter:       "0xdef",
+			Fee:             big.NewInt(200),
+		},
+		{
+			BlockNumber:     3,
+			Timestamp:       now.Add(-1 * time.Hour),
+			TransactionHash: "0x03",
+			Submitter:       "0xabc",
+			Own:             true,
+			Fee:             big.NewInt(300),
+		},
+	}
+
+	if err := history.RecordAdvances(advances); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fees of already recorded advances should not be counted twice.
+	if err := history.RecordAdvances(advances[2:]); err != nil {
+		t.Fatal(err)
+	}
+
+	claims := []*rewards.Claim{
+		{Timestamp: now.Add(-10 * 24 * time.Hour), Amount: big.NewInt(1000)},
+		{Timestamp: now.Add(-1 * time.Hour), Amount: big.NewInt(2000)},
+	}
+	for _, claim := range claims {
+		if err := history.RecordClaim(claim); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := history.Prune(now); err != nil {
+		t.Fatal(err)
+	}
+
+	gasExpenditure, err := history.GasExpenditure(time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gasExpenditure.Cmp(big.NewInt(300)) != 0 {
+		t.Errorf(
+			"unexpected gas expenditure:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			300,
+			gasExpenditure,
+		)
+	}
+
+	recorded, err := history.Claims(time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorded) != 1 || recorded[0].Amount.Cmp(big.NewInt(2000)) != 0 {
+		t.Errorf("unexpected claims: [%+v]", recorded)
+	}
+}
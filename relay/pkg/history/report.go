@@ -0,0 +1,159 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Report summarizes the relay performance over a period of time.
+type Report struct {
+	Since   time.Time
+	Until   time.Time
+	Samples int
+
+	AverageLag float64
+	MaxLag     int64
+
+	HeadersPulled int64
+	HeadersPushed int64
+	RelayErrors   int64
+
+	AverageGasPriceGwei float64
+	MaxGasPriceGwei     float64
+	GasPriceSamples     int
+}
+
+// Summarize builds a report from the given samples ordered by timestamps.
+// Counters are cumulative over the relay process lifetime so they are reset
+// on every restart. Only positive increments of the counters are summed up.
+func Summarize(since time.Time, samples []*Sample) *Report {
+	report := &Report{
+		Since:   since,
+		Samples: len(samples),
+	}
+
+	if len(samples) == 0 {
+		return report
+	}
+
+	report.Until = samples[len(samples)-1].Timestamp
+
+	var lagSum int64
+	var gasPriceSum float64
+
+	for i, sample := range samples {
+		lagSum += sample.RelayLag
+		if sample.RelayLag > report.MaxLag {
+			report.MaxLag = sample.RelayLag
+		}
+
+		if sample.GasPriceGwei != nil {
+			gasPriceSum += *sample.GasPriceGwei
+			report.GasPriceSamples++
+			if *sample.GasPriceGwei > report.MaxGasPriceGwei {
+				report.MaxGasPriceGwei = *sample.GasPriceGwei
+			}
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		previous := samples[i-1]
+		report.HeadersPulled += increment(
+			previous.HeadersPulled,
+			sample.HeadersPulled,
+		)
+		report.HeadersPushed += increment(
+			previous.HeadersPushed,
+			sample.HeadersPushed,
+		)
+		report.RelayErrors += increment(
+			previous.RelayErrors,
+			sample.RelayErrors,
+		)
+	}
+
+	report.AverageLag = float64(lagSum) / float64(len(samples))
+	if report.GasPriceSamples > 0 {
+		report.AverageGasPriceGwei = gasPriceSum /
+			float64(report.GasPriceSamples)
+	}
+
+	return report
+}
+
+// increment returns the increment of a cumulative counter between two
+// samples. If the counter has been reset in the meantime, the current value
+// is the increment.
+func increment(previous int64, current int64) int64 {
+	if current < previous {
+		return current
+	}
+
+	return current - previous
+}
+
+// Print writes the report in a human readable form.
+func (r *Report) Print(writer io.Writer) error {
+	if r.Samples == 0 {
+		_, err := fmt.Fprintf(
+			writer,
+			"no samples recorded since %v\n",
+			r.Since.Format(time.RFC3339),
+		)
+		return err
+	}
+
+	_, err := fmt.Fprintf(
+		writer,
+		"period:           %v - %v\n"+
+			"samples:          %v\n"+
+			"relay lag:        average %.2f, max %v blocks\n"+
+			"headers pulled:   %v\n"+
+			"headers pushed:   %v\n"+
+			"relay errors:     %v\n",
+		r.Since.Format(time.RFC3339),
+		r.Until.Format(time.RFC3339),
+		r.Samples,
+		r.AverageLag,
+		r.MaxLag,
+		r.HeadersPulled,
+		r.HeadersPushed,
+		r.RelayErrors,
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.GasPriceSamples == 0 {
+		_, err = fmt.Fprintf(writer, "gas price:        unknown\n")
+		return err
+	}
+
+	_, err = fmt.Fprintf(
+		writer,
+		"gas price:        average %.2f, max %.2f Gwei\n",
+		r.AverageGasPriceGwei,
+		r.MaxGasPriceGwei,
+	)
+	return err
+}
+
+// ParseSince parses the report period given as a duration, like `24h`, or
+// a number of days, like `7d`.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	var days int
+	var suffix string
+	if n, _ := fmt.Sscanf(value, "%d%s", &days, &suffix); n == 2 && suffix == "d" {
+		return now.Add(-time.Duration(days) * 24 * time.Hour), nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid period [%v]: [%v]", value, err)
+	}
+
+	return now.Add(-duration), nil
+}
@@ -0,0 +1,28 @@
+package logs
+
+import (
+	"github.com/ipfs/go-log"
+)
+
+// logger.go file contains the logger interface accepted by the packages
+// which can be embedded in other services, so the embedding service can route
+// their logs to its own logging stack and tests can assert on the log output.
+
+// Logger is a leveled, printf-style logger. Loggers returned by log.Logger
+// satisfy it.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// OrDefault returns the given logger or, if it is nil, the default logger
+// with the given name.
+func OrDefault(logger Logger, name string) Logger {
+	if logger == nil {
+		return log.Logger(name)
+	}
+
+	return logger
+}
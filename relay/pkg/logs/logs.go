@@ -0,0 +1,44 @@
+package logs
+
+import (
+	"sync"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/keep-common/pkg/logging"
+)
+
+var logger = log.Logger("tbtc-relay-logs")
+
+// logs.go file contains the runtime configuration of the log levels. The
+// levels set on startup can be changed later, e.g. through the admin API,
+// without restarting the process.
+
+var (
+	levelsMutex sync.Mutex
+	levels      = ""
+)
+
+// Configure sets the log levels using the given space-delimited level
+// directives, like `info tbtc-relay-header=debug`. See logging.Configure for
+// the supported directives.
+func Configure(directives string) error {
+	levelsMutex.Lock()
+	defer levelsMutex.Unlock()
+
+	if err := logging.Configure(directives); err != nil {
+		return err
+	}
+
+	levels = directives
+
+	return nil
+}
+
+// Levels returns the level directives most recently set with Configure. The
+// directives set before are in effect as well, unless overridden.
+func Levels() string {
+	levelsMutex.Lock()
+	defer levelsMutex.Unlock()
+
+	return levels
+}
@@ -0,0 +1,139 @@
+package logs
+
+import (
+	"bufio"
+	"io"
+	"sync"
+
+	golog "github.com/ipfs/go-log/v2"
+)
+
+// tail.go file contains the broadcasting of log entries written by the
+// process to subscribers, so the logs can be tailed remotely.
+
+// Size of the lines buffer of a single tail subscription.
+const subscriptionBufferSize = 1000
+
+// Maximum length of a single log line. Longer lines are cut.
+const maxLineLength = 64 * 1024
+
+// Tail broadcasts log lines to subscribers.
+type Tail struct {
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]bool
+}
+
+// Subscription represents a subscription to the log lines.
+type Subscription struct {
+	tail  *Tail
+	lines chan string
+}
+
+// StartTail starts capturing the entries of all loggers in a tail, which
+// broadcasts them to subscribers as JSON-encoded lines. Entries are captured
+// by a core attached to the logging core shared by all loggers, so only the
+// entries enabled by the log levels are captured and they are still written
+// to the configured log outputs.
+func StartTail() *Tail {
+	reader := golog.NewPipeReader(golog.PipeFormat(golog.JSONOutput))
+
+	logger.Infof("tailing logs")
+
+	return NewTail(reader)
+}
+
+// NewTail creates a tail broadcasting lines read from the given source.
+func NewTail(source io.Reader) *Tail {
+	tail := &Tail{subscriptions: make(map[*Subscription]bool)}
+
+	go tail.broadcast(source)
+
+	return tail
+}
+
+// broadcast reads the lines until the source is closed. The pipe of the
+// logging core is synchronous, so the lines are read without waiting for
+// the subscribers, not to block logging.
+func (t *Tail) broadcast(source io.Reader) {
+	reader := bufio.NewReaderSize(source, maxLineLength)
+
+	for {
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 {
+			t.emit(string(trimNewline(line)))
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func trimNewline(line []byte) []byte {
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		line = line[:len(line)-1]
+	}
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// Subscribe creates a new subscription to the log lines. If the
+// subscription buffer gets full, the subscription is cancelled and its lines
+// channel closed, so the subscriber knows it missed some lines.
+func (t *Tail) Subscribe() *Subscription {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	subscription := &Subscription{
+		tail:  t,
+		lines: make(chan string, subscriptionBufferSize),
+	}
+
+	t.subscriptions[subscription] = true
+
+	return subscription
+}
+
+// Lines returns the channel delivering log lines. The channel is closed once
+// the subscription is cancelled.
+func (s *Subscription) Lines() <-chan string {
+	return s.lines
+}
+
+// Unsubscribe cancels the subscription.
+func (s *Subscription) Unsubscribe() {
+	s.tail.mutex.Lock()
+	defer s.tail.mutex.Unlock()
+
+	s.tail.cancel(s)
+}
+
+// cancel must be called with the tail mutex held.
+func (t *Tail) cancel(subscription *Subscription) {
+	if !t.subscriptions[subscription] {
+		return
+	}
+
+	delete(t.subscriptions, subscription)
+	close(subscription.lines)
+}
+
+func (t *Tail) emit(line string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for subscription := range t.subscriptions {
+		select {
+		case subscription.lines <- line:
+		default:
+			// Logging here would emit another line to the lagging
+			// subscriber, so the subscription is cancelled silently.
+			t.cancel(subscription)
+		}
+	}
+}
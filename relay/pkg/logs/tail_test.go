@@ -0,0 +1,131 @@
+package logs
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-log"
+)
+
+func expectLine(t *testing.T, subscription *Subscription, expectedLine string) {
+	t.Helper()
+
+	select {
+	case line := <-subscription.Lines():
+		if expectedLine != line {
+			t.Errorf(
+				"unexpected line:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				expectedLine,
+				line,
+			)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("line [%v] should be received", expectedLine)
+	}
+}
+
+func TestTail(t *testing.T) {
+	reader, writer := io.Pipe()
+	tail := NewTail(reader)
+	subscription := tail.Subscribe()
+
+	if _, err := writer.Write([]byte("first line\nsecond line\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	expectLine(t, subscription, "first line")
+	expectLine(t, subscription, "second line")
+
+	subscription.Unsubscribe()
+
+	if _, ok := <-subscription.Lines(); ok {
+		t.Error("lines channel should be closed")
+	}
+
+	// Lines are still read after the subscriber left.
+	if _, err := writer.Write([]byte("third line\n")); err != nil {
+		t.Fatal(err)
+	}
+	_ = writer.Close()
+}
+
+func TestStartTail(t *testing.T) {
+	tail := StartTail()
+	subscription := tail.Subscribe()
+	defer subscription.Unsubscribe()
+
+	testLogger := log.Logger("tbtc-relay-logs-test")
+	if err := log.SetLogLevel("tbtc-relay-logs-test", "info"); err != nil {
+		t.Fatal(err)
+	}
+
+	testLogger.Debugf("not enabled message")
+	testLogger.Infof("tailed message")
+
+	select {
+	case line := <-subscription.Lines():
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+
+		expectedEntry := map[string]interface{}{
+			"level":  "info",
+			"logger": "tbtc-relay-logs-test",
+			"msg":    "tailed message",
+		}
+		for key, expectedValue := range expectedEntry {
+			if entry[key] != expectedValue {
+				t.Errorf(
+					"unexpected [%v] of entry:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					key,
+					expectedValue,
+					entry[key],
+				)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("log entry should be tailed")
+	}
+}
+
+func TestTail_LaggingSubscriber(t *testing.T) {
+	reader, writer := io.Pipe()
+
+	tail := NewTail(reader)
+	subscription := tail.Subscribe()
+
+	go func() {
+		for i := 0; i < 2*subscriptionBufferSize; i++ {
+			_, _ = writer.Write([]byte("line\n"))
+		}
+		_ = writer.Close()
+	}()
+
+	received := 0
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-subscription.Lines():
+			if !ok {
+				if received >= 2*subscriptionBufferSize {
+					t.Errorf("subscription should miss some lines")
+				}
+				return
+			}
+			received++
+			if received == 1 {
+				// Let the buffer overflow.
+				time.Sleep(200 * time.Millisecond)
+			}
+		case <-timeout:
+			t.Fatal("lagging subscription should be cancelled")
+		}
+	}
+}
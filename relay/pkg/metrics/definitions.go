@@ -0,0 +1,405 @@
+package metrics
+
+// definitions.go file contains the definitions of all metrics exposed by the
+// relay. The definitions are the single source of metric names, so the
+// generated alerting rules and dashboards always match the exposed metrics.
+
+// Names of the metrics exposed by the relay.
+const (
+	BtcChainConnectivity      = "btc_chain_connectivity"
+	HostChainConnectivity     = "host_chain_connectivity"
+	HeadersRelayActive        = "headers_relay_active"
+	HeadersRelayErrors        = "headers_relay_errors"
+	RelayState                = "relay_state"
+	EventBusDropped           = "event_bus_dropped"
+	HeadersPulled             = "headers_pulled"
+	HeadersPushed             = "headers_pushed"
+	HeadersRelayLag           = "headers_relay_lag"
+	RelayLatencySLOTarget     = "relay_latency_slo_target"
+	RelayLatencySLOCompliance = "relay_latency_slo_compliance"
+	RelayLatencyBurnRate1h    = "relay_latency_budget_burn_rate_1h"
+	RelayLatencyBurnRate6h    = "relay_latency_budget_burn_rate_6h"
+	RelayLatencyBurnRate3d    = "relay_latency_budget_burn_rate_3d"
+	RelayDivergence           = "relay_divergence"
+	RelayOwnPushes            = "relay_own_pushes"
+	RelayOtherPushes          = "relay_other_pushes"
+	RelayOwnPushShare         = "relay_own_push_share"
+	RelayGasPerHeader         = "relay_gas_per_header"
+	RelayGasPerHeaderBaseline = "relay_gas_per_header_baseline"
+	RelayGasRegressions       = "relay_gas_regressions"
+	RelayRewardsPending       = "relay_rewards_pending"
+	RelayRewardsClaimed       = "relay_rewards_claimed"
+	RelayGasExpenditure       = "relay_gas_expenditure"
+	BuildInfo                 = "build_info"
+	RelayUpdateAvailable      = "relay_update_available"
+	BtcForks                  = "btc_forks"
+	BtcForkLength             = "btc_fork_length"
+	BtcQuorumHeld             = "btc_quorum_held"
+	BtcSourceDisagreements    = "btc_source_disagreements"
+	BtcSourceMinTrust         = "btc_source_min_trust"
+	HostChainSubmissions      = "host_chain_submissions"
+	HostChainSubmitFailures   = "host_chain_submit_failures"
+	HostChainContractsPaused  = "host_chain_contracts_paused"
+)
+
+// Groups the metrics are organized in.
+const (
+	GroupChains      = "Chains"
+	GroupRelay       = "Relay"
+	GroupCompetition = "Competition"
+	GroupGas         = "Gas"
+	GroupRewards     = "Rewards"
+	GroupBuild       = "Build"
+)
+
+// Alert severities.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Definition describes a single metric exposed by the relay.
+type Definition struct {
+	// Name is the name of the exposed metric.
+	Name string
+	// Help is a short description of the metric.
+	Help string
+	// Group is the group the metric belongs to.
+	Group string
+	// Info determines whether the metric is an info metric carrying
+	// constant labels rather than a gauge.
+	Info bool
+	// Alert is the alerting rule of the metric. It is nil if the metric
+	// is not alerted on.
+	Alert *Alert
+}
+
+// Alert describes the recommended alerting rule of a metric.
+type Alert struct {
+	// Name is the name of the alert.
+	Name string
+	// Expression is the PromQL expression of the alert.
+	Expression string
+	// For is the time the expression must hold for the alert to fire,
+	// in the Prometheus duration format.
+	For string
+	// Severity is the severity of the alert.
+	Severity string
+	// Summary is a human-readable summary of the alert.
+	Summary string
+}
+
+var definitions = []*Definition{
+	{
+		Name:  BtcChainConnectivity,
+		Help:  "Whether the Bitcoin node is reachable (1) or not (0).",
+		Group: GroupChains,
+		Alert: &Alert{
+			Name:       "RelayBtcChainDisconnected",
+			Expression: BtcChainConnectivity + " == 0",
+			For:        "20m",
+			Severity:   SeverityCritical,
+			Summary:    "Relay cannot reach the Bitcoin node.",
+		},
+	},
+	{
+		Name:  HostChainConnectivity,
+		Help:  "Whether the host chain node is reachable (1) or not (0).",
+		Group: GroupChains,
+		Alert: &Alert{
+			Name:       "RelayHostChainDisconnected",
+			Expression: HostChainConnectivity + " == 0",
+			For:        "20m",
+			Severity:   SeverityCritical,
+			Summary:    "Relay cannot reach the host chain node.",
+		},
+	},
+	{
+		Name:  BtcForks,
+		Help:  "Number of competing Bitcoin forks near the chain tip.",
+		Group: GroupChains,
+		Alert: &Alert{
+			Name:       "RelayBtcForkPersisting",
+			Expression: BtcForks + " > 0",
+			For:        "1h",
+			Severity:   SeverityInfo,
+			Summary:    "Competing Bitcoin fork holds back relayed headers.",
+		},
+	},
+	{
+		Name:  BtcForkLength,
+		Help:  "Length, in blocks, of the longest competing Bitcoin fork.",
+		Group: GroupChains,
+	},
+	{
+		Name:  BtcQuorumHeld,
+		Help:  "Whether a header lacking quorum is held back (1) or not (0).",
+		Group: GroupChains,
+		Alert: &Alert{
+			Name:       "RelayQuorumNotReached",
+			Expression: BtcQuorumHeld + " == 1",
+			For:        "30m",
+			Severity:   SeverityCritical,
+			Summary:    "Bitcoin sources do not agree on the next header.",
+		},
+	},
+	{
+		Name:  BtcSourceDisagreements,
+		Help:  "Highest number of headers a Bitcoin source disagreed on.",
+		Group: GroupChains,
+		Alert: &Alert{
+			Name:       "RelayBtcSourceDisagrees",
+			Expression: "delta(" + BtcSourceDisagreements + "[1h]) > 3",
+			For:        "0m",
+			Severity:   SeverityWarning,
+			Summary:    "A Bitcoin source disagreed on over 3 headers in an hour.",
+		},
+	},
+	{
+		Name:  BtcSourceMinTrust,
+		Help:  "Lowest share of headers a Bitcoin source agreed on.",
+		Group: GroupChains,
+	},
+	{
+		Name:  HostChainSubmissions,
+		Help:  "Number of successful transaction submissions to the host chain.",
+		Group: GroupChains,
+	},
+	{
+		Name:  HostChainSubmitFailures,
+		Help:  "Number of failed transaction submissions to the host chain.",
+		Group: GroupChains,
+		Alert: &Alert{
+			Name:       "RelaySubmissionsFailing",
+			Expression: "delta(" + HostChainSubmitFailures + "[1h]) > 3",
+			For:        "0m",
+			Severity:   SeverityWarning,
+			Summary:    "More than 3 host chain submissions failed in an hour.",
+		},
+	},
+	{
+		Name:  HostChainContractsPaused,
+		Help:  "Number of paused contracts the relay submits to.",
+		Group: GroupChains,
+		Alert: &Alert{
+			Name:       "RelayContractPaused",
+			Expression: HostChainContractsPaused + " > 0",
+			For:        "0m",
+			Severity:   SeverityCritical,
+			Summary:    "A contract is paused; its submissions are held.",
+		},
+	},
+	{
+		Name:  HeadersRelayActive,
+		Help:  "Whether the headers relay process is active (1) or not (0).",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayInactive",
+			Expression: HeadersRelayActive + " == 0",
+			For:        "5m",
+			Severity:   SeverityCritical,
+			Summary:    "Headers relay process is not active.",
+		},
+	},
+	{
+		Name:  HeadersRelayErrors,
+		Help:  "Number of errors which restarted the headers relay.",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayRestarting",
+			Expression: "delta(" + HeadersRelayErrors + "[1h]) > 3",
+			For:        "0m",
+			Severity:   SeverityWarning,
+			Summary:    "Headers relay restarted more than 3 times in an hour.",
+		},
+	},
+	{
+		Name: RelayState,
+		Help: "Readiness state of the relay: starting (0), syncing (1), " +
+			"synced (2) or degraded (3).",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayDegraded",
+			Expression: RelayState + " == 3",
+			For:        "10m",
+			Severity:   SeverityWarning,
+			Summary:    "Relay fell behind or keeps failing after being synced.",
+		},
+	},
+	{
+		Name:  EventBusDropped,
+		Help:  "Number of node events dropped by slow event bus subscribers.",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayEventsDropped",
+			Expression: "delta(" + EventBusDropped + "[1h]) > 0",
+			For:        "0m",
+			Severity:   SeverityWarning,
+			Summary:    "Relay event consumers do not keep up with node events.",
+		},
+	},
+	{
+		Name:  HeadersPulled,
+		Help:  "Number of unique headers pulled from the Bitcoin chain.",
+		Group: GroupRelay,
+	},
+	{
+		Name:  HeadersPushed,
+		Help:  "Number of unique headers pushed to the host chain.",
+		Group: GroupRelay,
+	},
+	{
+		Name:  HeadersRelayLag,
+		Help:  "Number of Bitcoin blocks not known by the relay contract yet.",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayLagging",
+			Expression: HeadersRelayLag + " > 6",
+			For:        "30m",
+			Severity:   SeverityWarning,
+			Summary:    "Relay contract is more than 6 Bitcoin blocks behind.",
+		},
+	},
+	{
+		Name:  RelayLatencySLOTarget,
+		Help:  "Share of Bitcoin blocks to be relayed within the latency SLO.",
+		Group: GroupRelay,
+	},
+	{
+		Name:  RelayLatencySLOCompliance,
+		Help:  "Share of Bitcoin blocks relayed within the latency SLO.",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name: "RelayLatencySLOMissed",
+			Expression: RelayLatencySLOCompliance + " < " +
+				RelayLatencySLOTarget,
+			For:      "0m",
+			Severity: SeverityWarning,
+			Summary:  "Relay latency SLO is not met over its period.",
+		},
+	},
+	{
+		Name:  RelayLatencyBurnRate1h,
+		Help:  "Relay latency error budget burn rate in the last hour.",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayLatencyBudgetFastBurn",
+			Expression: RelayLatencyBurnRate1h + " > 14.4",
+			For:        "5m",
+			Severity:   SeverityCritical,
+			Summary:    "Relay latency error budget burns 14 times too fast.",
+		},
+	},
+	{
+		Name:  RelayLatencyBurnRate6h,
+		Help:  "Relay latency error budget burn rate in the last 6 hours.",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayLatencyBudgetBurn",
+			Expression: RelayLatencyBurnRate6h + " > 6",
+			For:        "30m",
+			Severity:   SeverityWarning,
+			Summary:    "Relay latency error budget burns 6 times too fast.",
+		},
+	},
+	{
+		Name:  RelayLatencyBurnRate3d,
+		Help:  "Relay latency error budget burn rate in the last 3 days.",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayLatencyBudgetSlowBurn",
+			Expression: RelayLatencyBurnRate3d + " > 1",
+			For:        "3h",
+			Severity:   SeverityInfo,
+			Summary:    "Relay latency error budget burns faster than planned.",
+		},
+	},
+	{
+		Name:  RelayDivergence,
+		Help:  "Whether the relay contract diverged from Bitcoin (1) or not (0).",
+		Group: GroupRelay,
+		Alert: &Alert{
+			Name:       "RelayContractDiverged",
+			Expression: RelayDivergence + " > 0",
+			For:        "0m",
+			Severity:   SeverityCritical,
+			Summary:    "Relay contract diverges from the heaviest Bitcoin chain.",
+		},
+	},
+	{
+		Name:  RelayOwnPushes,
+		Help:  "Number of pushes made by this relay maintainer in 24 hours.",
+		Group: GroupCompetition,
+	},
+	{
+		Name:  RelayOtherPushes,
+		Help:  "Number of pushes made by other relayers in 24 hours.",
+		Group: GroupCompetition,
+	},
+	{
+		Name:  RelayOwnPushShare,
+		Help:  "Share of pushes made by this relay maintainer in 24 hours.",
+		Group: GroupCompetition,
+	},
+	{
+		Name:  RelayGasPerHeader,
+		Help:  "Gas used per header by the most recent own push.",
+		Group: GroupGas,
+	},
+	{
+		Name:  RelayGasPerHeaderBaseline,
+		Help:  "Baseline of the gas used per pushed header.",
+		Group: GroupGas,
+	},
+	{
+		Name:  RelayGasRegressions,
+		Help:  "Number of pushes which used more gas than the baseline.",
+		Group: GroupGas,
+		Alert: &Alert{
+			Name:       "RelayGasRegression",
+			Expression: "delta(" + RelayGasRegressions + "[1h]) > 0",
+			For:        "0m",
+			Severity:   SeverityWarning,
+			Summary:    "Gas used per pushed header regressed.",
+		},
+	},
+	{
+		Name:  RelayRewardsPending,
+		Help:  "Accrued relay rewards not claimed yet, in ether.",
+		Group: GroupRewards,
+	},
+	{
+		Name:  RelayRewardsClaimed,
+		Help:  "Relay rewards claimed since the start, in ether.",
+		Group: GroupRewards,
+	},
+	{
+		Name:  RelayGasExpenditure,
+		Help:  "Fees paid for own pushes since the start, in ether.",
+		Group: GroupRewards,
+	},
+	{
+		Name:  BuildInfo,
+		Help:  "Version, revision, build date and Go version of the binary.",
+		Group: GroupBuild,
+		Info:  true,
+	},
+	{
+		Name:  RelayUpdateAvailable,
+		Help:  "Whether a newer relay release is available (1) or not (0).",
+		Group: GroupBuild,
+		Alert: &Alert{
+			Name:       "RelayUpdateAvailable",
+			Expression: RelayUpdateAvailable + " == 1",
+			For:        "1h",
+			Severity:   SeverityInfo,
+			Summary:    "A newer relay release is available.",
+		},
+	},
+}
+
+// Definitions returns the definitions of all metrics exposed by the relay.
+func Definitions() []*Definition {
+	return definitions
+}
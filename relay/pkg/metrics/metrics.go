@@ -2,13 +2,25 @@ package metrics
 
 import (
 	"context"
+	"math/big"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-log"
 	"github.com/keep-network/keep-common/pkg/metrics"
 	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/build"
 	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/events"
+	"github.com/keep-network/tbtc/relay/pkg/fraud"
+	"github.com/keep-network/tbtc/relay/pkg/gasusage"
 	"github.com/keep-network/tbtc/relay/pkg/node"
+	"github.com/keep-network/tbtc/relay/pkg/quorum"
+	"github.com/keep-network/tbtc/relay/pkg/rewards"
+	"github.com/keep-network/tbtc/relay/pkg/service"
+	"github.com/keep-network/tbtc/relay/pkg/slo"
 )
 
 var logger = log.Logger("tbtc-relay-metrics")
@@ -22,17 +34,80 @@ const (
 	DefaultNodeMetricsTick = 10 * time.Second
 )
 
-// Initialize set up the metrics registry and enables metrics server.
+// Registry is a metrics registry attaching a common set of labels to all
+// the metrics it exposes.
+type Registry struct {
+	*metrics.Registry
+
+	labels []metrics.Label
+	// labelValues holds the names and values of the common labels, used
+	// when the metrics are pushed.
+	labelValues map[string]string
+
+	// exposed holds the names of all the metrics exposed through the
+	// registry.
+	exposedMutex sync.Mutex
+	exposed      []string
+
+	// samples holds the last observed values of the exposed metrics, used
+	// when the metrics are pushed.
+	samplesMutex sync.Mutex
+	samples      map[string]*sample
+}
+
+// sample is the last observed value of a metric.
+type sample struct {
+	value float64
+	// labels are the labels of an info metric, attached in addition to the
+	// common labels.
+	labels map[string]string
+	time   time.Time
+}
+
+// Initialize sets up the metrics registry, enables the metrics server if the
+// port is set and starts pushing the metrics if configured. Labels with
+// empty values are ignored; the others are attached to all the exposed
+// metrics.
 func Initialize(
+	ctx context.Context,
 	port int,
-) (*metrics.Registry, bool) {
-	if port == 0 {
+	push *PushConfig,
+	labels map[string]string,
+) (*Registry, bool) {
+	if port == 0 && !push.IsEnabled() {
 		return nil, false
 	}
 
-	registry := metrics.NewRegistry()
+	registry := &Registry{
+		Registry:    metrics.NewRegistry(),
+		labelValues: make(map[string]string),
+	}
+
+	names := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
 
-	registry.EnableServer(port)
+	for _, name := range names {
+		registry.labels = append(
+			registry.labels,
+			metrics.NewLabel(name, labels[name]),
+		)
+		registry.labelValues[name] = labels[name]
+	}
+
+	if port != 0 {
+		registry.EnableServer(port)
+	}
+
+	if push.IsEnabled() {
+		if err := startPushing(ctx, registry, push); err != nil {
+			logger.Errorf("could not start pushing metrics: [%v]", err)
+		}
+	}
 
 	return registry, true
 }
@@ -41,12 +116,12 @@ func Initialize(
 // btc_chain_connectivity metric.
 func ObserveBtcChainConnectivity(
 	ctx context.Context,
-	registry *metrics.Registry,
+	registry *Registry,
 	btcHandle btc.Handle,
 	tick time.Duration,
 ) {
 	input := func() float64 {
-		_, err := btcHandle.GetBlockCount()
+		_, err := btcHandle.GetBlockCount(ctx)
 
 		if err != nil {
 			return 0
@@ -57,7 +132,7 @@ func ObserveBtcChainConnectivity(
 
 	observe(
 		ctx,
-		"btc_chain_connectivity",
+		BtcChainConnectivity,
 		input,
 		registry,
 		validateTick(tick, DefaultChainMetricsTick),
@@ -68,12 +143,12 @@ func ObserveBtcChainConnectivity(
 // host_chain_connectivity metric.
 func ObserveHostChainConnectivity(
 	ctx context.Context,
-	registry *metrics.Registry,
+	registry *Registry,
 	hostChain chain.Handle,
 	tick time.Duration,
 ) {
 	input := func() float64 {
-		_, err := hostChain.GetBestKnownDigest()
+		_, err := hostChain.GetBestKnownDigest(ctx)
 
 		if err != nil {
 			return 0
@@ -84,7 +159,7 @@ func ObserveHostChainConnectivity(
 
 	observe(
 		ctx,
-		"host_chain_connectivity",
+		HostChainConnectivity,
 		input,
 		registry,
 		validateTick(tick, DefaultChainMetricsTick),
@@ -95,7 +170,7 @@ func ObserveHostChainConnectivity(
 // headers_relay_active metric.
 func ObserveHeadersRelayActive(
 	ctx context.Context,
-	registry *metrics.Registry,
+	registry *Registry,
 	nodeStats node.Stats,
 	tick time.Duration,
 ) {
@@ -111,7 +186,7 @@ func ObserveHeadersRelayActive(
 
 	observe(
 		ctx,
-		"headers_relay_active",
+		HeadersRelayActive,
 		input,
 		registry,
 		validateTick(tick, DefaultNodeMetricsTick),
@@ -122,7 +197,7 @@ func ObserveHeadersRelayActive(
 // headers_relay_errors metric.
 func ObserveHeadersRelayErrors(
 	ctx context.Context,
-	registry *metrics.Registry,
+	registry *Registry,
 	nodeStats node.Stats,
 	tick time.Duration,
 ) {
@@ -132,7 +207,49 @@ func ObserveHeadersRelayErrors(
 
 	observe(
 		ctx,
-		"headers_relay_errors",
+		HeadersRelayErrors,
+		input,
+		registry,
+		validateTick(tick, DefaultNodeMetricsTick),
+	)
+}
+
+// ObserveRelayState triggers an observation process of the relay_state
+// metric.
+func ObserveRelayState(
+	ctx context.Context,
+	registry *Registry,
+	readiness *service.Readiness,
+	tick time.Duration,
+) {
+	input := func() float64 {
+		return float64(readiness.Status().State)
+	}
+
+	observe(
+		ctx,
+		RelayState,
+		input,
+		registry,
+		validateTick(tick, DefaultNodeMetricsTick),
+	)
+}
+
+// ObserveEventBus triggers an observation process of the event_bus_dropped
+// metric.
+func ObserveEventBus(
+	ctx context.Context,
+	registry *Registry,
+	bus *events.Bus,
+	tick time.Duration,
+) {
+	input := func() float64 {
+		return float64(bus.Dropped())
+	}
+
+	observe(
+		ctx,
+		EventBusDropped,
 		input,
 		registry,
 		validateTick(tick, DefaultNodeMetricsTick),
@@ -143,7 +260,7 @@ func ObserveHeadersRelayErrors(
 // headers_pulled metric.
 func ObserveHeadersPulled(
 	ctx context.Context,
-	registry *metrics.Registry,
+	registry *Registry,
 	nodeStats node.Stats,
 	tick time.Duration,
 ) {
@@ -153,7 +270,7 @@ func ObserveHeadersPulled(
 
 	observe(
 		ctx,
-		"headers_pulled",
+		HeadersPulled,
 		input,
 		registry,
 		validateTick(tick, DefaultNodeMetricsTick),
@@ -164,7 +281,7 @@ func ObserveHeadersPulled(
 // headers_pushed metric.
 func ObserveHeadersPushed(
 	ctx context.Context,
-	registry *metrics.Registry,
+	registry *Registry,
 	nodeStats node.Stats,
 	tick time.Duration,
 ) {
@@ -174,7 +291,392 @@ func ObserveHeadersPushed(
 
 	observe(
 		ctx,
-		"headers_pushed",
+		HeadersPushed,
+		input,
+		registry,
+		validateTick(tick, DefaultNodeMetricsTick),
+	)
+}
+
+// ObserveHeadersRelayLag triggers an observation process of the
+// headers_relay_lag metric.
+func ObserveHeadersRelayLag(
+	ctx context.Context,
+	registry *Registry,
+	nodeStats node.Stats,
+	tick time.Duration,
+) {
+	input := func() float64 {
+		return float64(nodeStats.HeadersRelayLag())
+	}
+
+	observe(
+		ctx,
+		HeadersRelayLag,
+		input,
+		registry,
+		validateTick(tick, DefaultNodeMetricsTick),
+	)
+}
+
+// ObserveLatencySLO triggers an observation process of the
+// relay_latency_slo_target, relay_latency_slo_compliance and
+// relay_latency_budget_burn_rate_* metrics.
+func ObserveLatencySLO(
+	ctx context.Context,
+	registry *Registry,
+	tracker *slo.Tracker,
+	tick time.Duration,
+) {
+	tick = validateTick(tick, DefaultNodeMetricsTick)
+
+	observe(ctx, RelayLatencySLOTarget, tracker.Target, registry, tick)
+	observe(ctx, RelayLatencySLOCompliance, tracker.Compliance, registry, tick)
+
+	burnRates := map[string]time.Duration{
+		RelayLatencyBurnRate1h: slo.BurnRateWindowShort,
+		RelayLatencyBurnRate6h: slo.BurnRateWindowMedium,
+		RelayLatencyBurnRate3d: slo.BurnRateWindowLong,
+	}
+	for name, window := range burnRates {
+		window := window
+		observe(
+			ctx,
+			name,
+			func() float64 {
+				return tracker.BurnRate(window)
+			},
+			registry,
+			tick,
+		)
+	}
+}
+
+// ObserveBtcForks triggers an observation process of the btc_forks and
+// btc_fork_length metrics.
+func ObserveBtcForks(
+	ctx context.Context,
+	registry *Registry,
+	nodeStats node.Stats,
+	tick time.Duration,
+) {
+	tick = validateTick(tick, DefaultNodeMetricsTick)
+
+	observe(
+		ctx,
+		BtcForks,
+		func() float64 {
+			return float64(nodeStats.BtcForks())
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		BtcForkLength,
+		func() float64 {
+			return float64(nodeStats.BtcForkLength())
+		},
+		registry,
+		tick,
+	)
+}
+
+// ObserveRelayDivergence triggers an observation process of the
+// relay_divergence metric.
+func ObserveRelayDivergence(
+	ctx context.Context,
+	registry *Registry,
+	watcher *fraud.Watcher,
+	tick time.Duration,
+) {
+	input := func() float64 {
+		if watcher.Divergent() {
+			return 1
+		}
+
+		return 0
+	}
+
+	observe(
+		ctx,
+		RelayDivergence,
+		input,
+		registry,
+		validateTick(tick, DefaultNodeMetricsTick),
+	)
+}
+
+// ObserveQuorum triggers an observation process of the btc_quorum_held,
+// btc_source_disagreements and btc_source_min_trust metrics.
+func ObserveQuorum(
+	ctx context.Context,
+	registry *Registry,
+	stage *quorum.Stage,
+	tick time.Duration,
+) {
+	tick = validateTick(tick, DefaultNodeMetricsTick)
+
+	observe(
+		ctx,
+		BtcQuorumHeld,
+		func() float64 {
+			if stage.Held() {
+				return 1
+			}
+
+			return 0
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		BtcSourceDisagreements,
+		func() float64 {
+			return float64(stage.MaxDisagreements())
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		BtcSourceMinTrust,
+		stage.MinScore,
+		registry,
+		tick,
+	)
+}
+
+// ObserveHostChainSubmissions triggers an observation process of the
+// host_chain_submissions and host_chain_submit_failures metrics.
+func ObserveHostChainSubmissions(
+	ctx context.Context,
+	registry *Registry,
+	stats *chain.SubmissionStats,
+	tick time.Duration,
+) {
+	tick = validateTick(tick, DefaultNodeMetricsTick)
+
+	observe(
+		ctx,
+		HostChainSubmissions,
+		func() float64 {
+			return float64(stats.Submitted())
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		HostChainSubmitFailures,
+		func() float64 {
+			return float64(stats.Failed())
+		},
+		registry,
+		tick,
+	)
+}
+
+// ObserveContractsPaused triggers an observation process of the
+// host_chain_contracts_paused metric.
+func ObserveContractsPaused(
+	ctx context.Context,
+	registry *Registry,
+	guard *chain.PauseGuard,
+	tick time.Duration,
+) {
+	observe(
+		ctx,
+		HostChainContractsPaused,
+		func() float64 {
+			return float64(len(guard.PausedContracts()))
+		},
+		registry,
+		validateTick(tick, DefaultNodeMetricsTick),
+	)
+}
+
+// ObserveRelayCompetition triggers an observation process of the
+// relay_own_pushes, relay_other_pushes and relay_own_push_share metrics.
+func ObserveRelayCompetition(
+	ctx context.Context,
+	registry *Registry,
+	tracker *competition.Tracker,
+	tick time.Duration,
+) {
+	tick = validateTick(tick, DefaultNodeMetricsTick)
+
+	observe(
+		ctx,
+		RelayOwnPushes,
+		func() float64 {
+			return float64(tracker.OwnPushes())
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		RelayOtherPushes,
+		func() float64 {
+			return float64(tracker.OtherPushes())
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		RelayOwnPushShare,
+		tracker.OwnPushShare,
+		registry,
+		tick,
+	)
+}
+
+// ObserveGasUsage triggers an observation process of the
+// relay_gas_per_header, relay_gas_per_header_baseline and
+// relay_gas_regressions metrics.
+func ObserveGasUsage(
+	ctx context.Context,
+	registry *Registry,
+	detector *gasusage.Detector,
+	tick time.Duration,
+) {
+	tick = validateTick(tick, DefaultNodeMetricsTick)
+
+	observe(
+		ctx,
+		RelayGasPerHeader,
+		detector.LastGasPerHeader,
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		RelayGasPerHeaderBaseline,
+		detector.BaselineGasPerHeader,
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		RelayGasRegressions,
+		func() float64 {
+			return float64(detector.Regressions())
+		},
+		registry,
+		tick,
+	)
+}
+
+// ObserveRewards triggers an observation process of the relay_rewards_pending,
+// relay_rewards_claimed and relay_gas_expenditure metrics. All of them are
+// expressed in ether.
+func ObserveRewards(
+	ctx context.Context,
+	registry *Registry,
+	tracker *rewards.Tracker,
+	tick time.Duration,
+) {
+	tick = validateTick(tick, DefaultNodeMetricsTick)
+
+	observe(
+		ctx,
+		RelayRewardsPending,
+		func() float64 {
+			return weiToEther(tracker.PendingRewards())
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		RelayRewardsClaimed,
+		func() float64 {
+			return weiToEther(tracker.ClaimedRewards())
+		},
+		registry,
+		tick,
+	)
+
+	observe(
+		ctx,
+		RelayGasExpenditure,
+		func() float64 {
+			return weiToEther(tracker.GasExpenditure())
+		},
+		registry,
+		tick,
+	)
+}
+
+func weiToEther(wei *big.Int) float64 {
+	ether, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(wei),
+		big.NewFloat(1e18),
+	).Float64()
+
+	return ether
+}
+
+// ExposeBuildInfo exposes the build_info metric with the version, revision,
+// build date and Go version of the relay binary as labels.
+func ExposeBuildInfo(registry *Registry) {
+	info := build.GetInfo()
+
+	if _, err := registry.NewInfo(
+		BuildInfo,
+		append(
+			[]metrics.Label{
+				metrics.NewLabel("version", info.Version),
+				metrics.NewLabel("revision", info.Revision),
+				metrics.NewLabel("date", info.Date),
+				metrics.NewLabel("go_version", info.GoVersion),
+			},
+			registry.labels...,
+		),
+	); err != nil {
+		logger.Warnf("could not create info metric [%v]", BuildInfo)
+		return
+	}
+	registry.markExposed(BuildInfo)
+	registry.record(BuildInfo, 1, map[string]string{
+		"version":    info.Version,
+		"revision":   info.Revision,
+		"date":       info.Date,
+		"go_version": info.GoVersion,
+	})
+}
+
+// ObserveUpdateAvailable triggers an observation process of the
+// relay_update_available metric.
+func ObserveUpdateAvailable(
+	ctx context.Context,
+	registry *Registry,
+	checker *build.UpdateChecker,
+	tick time.Duration,
+) {
+	input := func() float64 {
+		if checker.UpdateAvailable() {
+			return 1
+		}
+
+		return 0
+	}
+
+	observe(
+		ctx,
+		RelayUpdateAvailable,
 		input,
 		registry,
 		validateTick(tick, DefaultNodeMetricsTick),
@@ -185,14 +687,25 @@ func observe(
 	ctx context.Context,
 	name string,
 	input metrics.ObserverInput,
-	registry *metrics.Registry,
+	registry *Registry,
 	tick time.Duration,
 ) {
-	observer, err := registry.NewGaugeObserver(name, input)
+	recordedInput := func() float64 {
+		value := input()
+		registry.record(name, value, nil)
+		return value
+	}
+
+	observer, err := registry.NewGaugeObserver(
+		name,
+		recordedInput,
+		registry.labels...,
+	)
 	if err != nil {
 		logger.Warnf("could not create gauge observer [%v]", name)
 		return
 	}
+	registry.markExposed(name)
 
 	observer.Observe(ctx, tick)
 }
@@ -204,3 +717,22 @@ func validateTick(tick time.Duration, defaultTick time.Duration) time.Duration {
 
 	return defaultTick
 }
+
+func (r *Registry) markExposed(name string) {
+	r.exposedMutex.Lock()
+	defer r.exposedMutex.Unlock()
+
+	r.exposed = append(r.exposed, name)
+}
+
+// record stores the last observed value of the given metric.
+func (r *Registry) record(name string, value float64, labels map[string]string) {
+	r.samplesMutex.Lock()
+	defer r.samplesMutex.Unlock()
+
+	if r.samples == nil {
+		r.samples = make(map[string]*sample)
+	}
+
+	r.samples[name] = &sample{value: value, labels: labels, time: time.Now()}
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-common/pkg/metrics"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/events"
+	"github.com/keep-network/tbtc/relay/pkg/fraud"
+	"github.com/keep-network/tbtc/relay/pkg/gasusage"
+	"github.com/keep-network/tbtc/relay/pkg/quorum"
+	"github.com/keep-network/tbtc/relay/pkg/rewards"
+	"github.com/keep-network/tbtc/relay/pkg/service"
+	"github.com/keep-network/tbtc/relay/pkg/slo"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+type nodeStats struct{}
+
+func (ns *nodeStats) HeadersRelayActive() bool      { return true }
+func (ns *nodeStats) HeadersRelayErrors() int       { return 0 }
+func (ns *nodeStats) UniqueHeadersPulled() int      { return 0 }
+func (ns *nodeStats) UniqueHeadersPushed() int      { return 0 }
+func (ns *nodeStats) HeadersRelayLag() int64        { return 0 }
+func (ns *nodeStats) HeadersRelayLagObserved() bool { return true }
+func (ns *nodeStats) BtcForks() int                 { return 0 }
+func (ns *nodeStats) BtcForkLength() int64          { return 0 }
+
+// TestDefinitions verifies that the metric definitions cover exactly the
+// metrics exposed by the relay.
+func TestDefinitions(t *testing.T) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	btcChain, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostChain, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewardsTracker, err := rewards.NewTracker(hostChain, &rewards.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quorumStage, err := quorum.NewStage(btcChain, nil, &quorum.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := &Registry{Registry: metrics.NewRegistry()}
+	tick := time.Hour
+
+	ExposeBuildInfo(registry)
+	ObserveBtcChainConnectivity(ctx, registry, btcChain, tick)
+	ObserveHostChainConnectivity(ctx, registry, hostChain, tick)
+	ObserveHeadersRelayActive(ctx, registry, &nodeStats{}, tick)
+	ObserveHeadersRelayErrors(ctx, registry, &nodeStats{}, tick)
+	ObserveRelayState(
+		ctx,
+		registry,
+		service.NewReadiness(&service.Config{}, &nodeStats{}),
+		tick,
+	)
+	ObserveEventBus(ctx, registry, events.NewBus(), tick)
+	ObserveHeadersPulled(ctx, registry, &nodeStats{}, tick)
+	ObserveHeadersPushed(ctx, registry, &nodeStats{}, tick)
+	ObserveHeadersRelayLag(ctx, registry, &nodeStats{}, tick)
+	ObserveBtcForks(ctx, registry, &nodeStats{}, tick)
+	ObserveHostChainSubmissions(ctx, registry, &chain.SubmissionStats{}, tick)
+	ObserveContractsPaused(
+		ctx,
+		registry,
+		chain.NewPauseGuard(hostChain, nil, nil),
+		tick,
+	)
+	ObserveQuorum(ctx, registry, quorumStage, tick)
+	ObserveRelayDivergence(
+		ctx,
+		registry,
+		fraud.NewWatcher(hostChain, nil, nil),
+		tick,
+	)
+	ObserveRelayCompetition(
+		ctx,
+		registry,
+		competition.NewTracker(hostChain, nil),
+		tick,
+	)
+	ObserveGasUsage(
+		ctx,
+		registry,
+		gasusage.NewDetector(&gasusage.Config{}, store.OpenMemory()),
+		tick,
+	)
+	ObserveLatencySLO(ctx, registry, slo.NewTracker(&slo.Config{}), tick)
+	ObserveRewards(ctx, registry, rewardsTracker, tick)
+	ObserveUpdateAvailable(
+		ctx,
+		registry,
+		build.NewUpdateChecker(&build.UpdateCheckConfig{}),
+		tick,
+	)
+
+	exposed := append([]string{}, registry.exposed...)
+	sort.Strings(exposed)
+
+	defined := make([]string, 0)
+	for _, definition := range Definitions() {
+		defined = append(defined, definition.Name)
+	}
+	sort.Strings(defined)
+
+	if !reflect.DeepEqual(defined, exposed) {
+		t.Errorf(
+			"unexpected metrics:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			defined,
+			exposed,
+		)
+	}
+}
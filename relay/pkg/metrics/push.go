@@ -0,0 +1,390 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// push.go file contains pushing of the metrics for relays which cannot be
+// scraped by Prometheus, e.g. when running behind NAT or a firewall. The
+// last observed values of the exposed metrics are periodically pushed to
+// a Prometheus Pushgateway or an OTLP/HTTP metrics endpoint, under the same
+// names and with the same labels as exposed by the metrics server.
+
+const (
+	// PushProtocolPushgateway pushes metrics to a Prometheus Pushgateway.
+	PushProtocolPushgateway = "pushgateway"
+	// PushProtocolOTLP pushes metrics to an OTLP/HTTP metrics endpoint
+	// using the JSON encoding.
+	PushProtocolOTLP = "otlp"
+
+	// Default job name the metrics are pushed under.
+	defaultPushJob = "tbtc-relay"
+
+	// Default interval, in seconds, in which the metrics are pushed.
+	defaultPushInterval = 60
+
+	// Maximum time a single push can take.
+	pushTimeout = 30 * time.Second
+)
+
+// PushConfig is the configuration of pushing the metrics.
+type PushConfig struct {
+	// URL is the URL of the Pushgateway, e.g. `http://pushgateway:9091`, or
+	// of the OTLP/HTTP metrics endpoint, e.g.
+	// `http://collector:4318/v1/metrics`. If empty, metrics are not pushed.
+	URL string
+
+	// Protocol is either `pushgateway` or `otlp`. If empty, metrics are
+	// pushed to a Pushgateway.
+	Protocol string
+
+	// Job is the job name the metrics are pushed under; it is the `job`
+	// grouping key of the Pushgateway and the `service.name` resource
+	// attribute of OTLP. If empty, a default value is used.
+	Job string
+
+	// Interval is the interval, in seconds, in which the metrics are
+	// pushed. If zero, a default value is used.
+	Interval int
+
+	// Headers are the extra HTTP headers sent with each push, e.g. to
+	// authenticate to the endpoint.
+	Headers map[string]string
+}
+
+// IsEnabled checks whether the metrics are pushed.
+func (pc *PushConfig) IsEnabled() bool {
+	return pc != nil && pc.URL != ""
+}
+
+// pusher pushes the metrics of the registry.
+type pusher struct {
+	registry *Registry
+	config   *PushConfig
+	job      string
+	client   *http.Client
+	// encode encodes the metrics and returns the HTTP method, the URL, the
+	// content type and the body of the push.
+	encode func() (string, string, string, []byte, error)
+}
+
+func startPushing(
+	ctx context.Context,
+	registry *Registry,
+	config *PushConfig,
+) error {
+	pusher, err := newPusher(registry, config)
+	if err != nil {
+		return err
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultPushInterval
+	}
+
+	logger.Infof(
+		"pushing metrics to [%v] every [%v] seconds",
+		config.URL,
+		interval,
+	)
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := pusher.push(ctx); err != nil {
+					logger.Warnf("could not push metrics: [%v]", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func newPusher(registry *Registry, config *PushConfig) (*pusher, error) {
+	if _, err := url.Parse(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid push URL: [%v]", err)
+	}
+
+	job := config.Job
+	if job == "" {
+		job = defaultPushJob
+	}
+
+	pusher := &pusher{
+		registry: registry,
+		config:   config,
+		job:      job,
+		client:   &http.Client{Timeout: pushTimeout},
+	}
+
+	switch config.Protocol {
+	case "", PushProtocolPushgateway:
+		pusher.encode = pusher.encodePushgateway
+	case PushProtocolOTLP:
+		pusher.encode = pusher.encodeOTLP
+	default:
+		return nil, fmt.Errorf(
+			"unsupported metrics push protocol [%v]",
+			config.Protocol,
+		)
+	}
+
+	return pusher, nil
+}
+
+// push sends the last observed values of the metrics.
+func (p *pusher) push(ctx context.Context) error {
+	method, pushURL, contentType, body, err := p.encode()
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		method,
+		pushURL,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", contentType)
+	for name, value := range p.config.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf(
+			"unexpected status [%v]: [%v]",
+			response.Status,
+			strings.TrimSpace(string(message)),
+		)
+	}
+
+	return nil
+}
+
+// namedSample is the last observed value of the metric with the given name.
+type namedSample struct {
+	name string
+	*sample
+}
+
+// snapshot returns the last observed values of the metrics, sorted by name.
+func (r *Registry) snapshot() []*namedSample {
+	r.samplesMutex.Lock()
+	defer r.samplesMutex.Unlock()
+
+	samples := make([]*namedSample, 0, len(r.samples))
+	for name, sample := range r.samples {
+		samples = append(samples, &namedSample{name: name, sample: sample})
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].name < samples[j].name
+	})
+
+	return samples
+}
+
+// encodePushgateway encodes the metrics in the Prometheus text format. The
+// common labels are part of the grouping key, so each relay target replaces
+// only its own metrics. Pushgateway rejects timestamps, so they are omitted.
+func (p *pusher) encodePushgateway() (string, string, string, []byte, error) {
+	pushURL := strings.TrimSuffix(p.config.URL, "/") +
+		"/metrics/job/" + url.PathEscape(p.job)
+	for _, name := range sortedKeys(p.registry.labelValues) {
+		pushURL += "/" + url.PathEscape(name) + "/" +
+			url.PathEscape(p.registry.labelValues[name])
+	}
+
+	help := make(map[string]string)
+	for _, definition := range Definitions() {
+		help[definition.Name] = definition.Help
+	}
+
+	var body bytes.Buffer
+	for _, sample := range p.registry.snapshot() {
+		if text, ok := help[sample.name]; ok {
+			fmt.Fprintf(&body, "# HELP %v %v\n", sample.name, text)
+		}
+		fmt.Fprintf(&body, "# TYPE %v gauge\n", sample.name)
+
+		labels := make([]string, 0, len(sample.labels))
+		for _, name := range sortedKeys(sample.labels) {
+			labels = append(
+				labels,
+				name+"="+strconv.Quote(sample.labels[name]),
+			)
+		}
+
+		body.WriteString(sample.name)
+		if len(labels) > 0 {
+			body.WriteString("{" + strings.Join(labels, ",") + "}")
+		}
+		fmt.Fprintf(&body, " %v\n", formatValue(sample.value))
+	}
+
+	return http.MethodPut,
+		pushURL,
+		"text/plain; version=0.0.4",
+		body.Bytes(),
+		nil
+}
+
+// OTLP/HTTP JSON encoding of the metrics.
+type otlpRequest struct {
+	ResourceMetrics []*otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     *otlpResource       `json:"resource"`
+	ScopeMetrics []*otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   *otlpScope    `json:"scope"`
+	Metrics []*otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpMetric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Gauge       *otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []*otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []*otlpAttribute `json:"attributes,omitempty"`
+	TimeUnixNano string           `json:"timeUnixNano"`
+	AsDouble     float64          `json:"asDouble"`
+}
+
+type otlpAttribute struct {
+	Key   string        `json:"key"`
+	Value *otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// encodeOTLP encodes the metrics as an OTLP/HTTP JSON export request. The
+// common labels become resource attributes and the metrics are gauges.
+func (p *pusher) encodeOTLP() (string, string, string, []byte, error) {
+	resource := &otlpResource{
+		Attributes: []*otlpAttribute{newOTLPAttribute("service.name", p.job)},
+	}
+	for _, name := range sortedKeys(p.registry.labelValues) {
+		resource.Attributes = append(
+			resource.Attributes,
+			newOTLPAttribute(name, p.registry.labelValues[name]),
+		)
+	}
+
+	help := make(map[string]string)
+	for _, definition := range Definitions() {
+		help[definition.Name] = definition.Help
+	}
+
+	scopeMetrics := &otlpScopeMetrics{
+		Scope:   &otlpScope{Name: defaultPushJob},
+		Metrics: []*otlpMetric{},
+	}
+	for _, sample := range p.registry.snapshot() {
+		// Non-finite values cannot be encoded in JSON.
+		if math.IsNaN(sample.value) || math.IsInf(sample.value, 0) {
+			continue
+		}
+
+		dataPoint := &otlpDataPoint{
+			TimeUnixNano: strconv.FormatInt(sample.time.UnixNano(), 10),
+			AsDouble:     sample.value,
+		}
+		for _, name := range sortedKeys(sample.labels) {
+			dataPoint.Attributes = append(
+				dataPoint.Attributes,
+				newOTLPAttribute(name, sample.labels[name]),
+			)
+		}
+
+		scopeMetrics.Metrics = append(scopeMetrics.Metrics, &otlpMetric{
+			Name:        sample.name,
+			Description: help[sample.name],
+			Gauge:       &otlpGauge{DataPoints: []*otlpDataPoint{dataPoint}},
+		})
+	}
+
+	body, err := json.Marshal(&otlpRequest{
+		ResourceMetrics: []*otlpResourceMetrics{{
+			Resource:     resource,
+			ScopeMetrics: []*otlpScopeMetrics{scopeMetrics},
+		}},
+	})
+	if err != nil {
+		return "", "", "", nil, fmt.Errorf(
+			"could not encode metrics: [%v]",
+			err,
+		)
+	}
+
+	return http.MethodPost, p.config.URL, "application/json", body, nil
+}
+
+func newOTLPAttribute(key string, value string) *otlpAttribute {
+	return &otlpAttribute{Key: key, Value: &otlpAnyValue{StringValue: value}}
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
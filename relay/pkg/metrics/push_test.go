@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/keep-network/keep-common/pkg/metrics"
+)
+
+// pushRequest is a push received by the test server.
+type pushRequest struct {
+	method        string
+	path          string
+	contentType   string
+	authorization string
+	body          string
+}
+
+func newPushServer(t *testing.T) (*httptest.Server, chan *pushRequest) {
+	requests := make(chan *pushRequest, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+
+			requests <- &pushRequest{
+				method:        r.Method,
+				path:          r.URL.Path,
+				contentType:   r.Header.Get("Content-Type"),
+				authorization: r.Header.Get("Authorization"),
+				body:          string(body),
+			}
+		},
+	))
+
+	return server, requests
+}
+
+func newPushRegistry() *Registry {
+	registry := &Registry{
+		Registry:    metrics.NewRegistry(),
+		labelValues: map[string]string{"target": "mainnet"},
+	}
+
+	registry.record(HeadersRelayLag, 3, nil)
+	registry.record(BuildInfo, 1, map[string]string{"version": "v1.2.3"})
+
+	return registry
+}
+
+func TestPusher_Pushgateway(t *testing.T) {
+	server, requests := newPushServer(t)
+	defer server.Close()
+
+	pusher, err := newPusher(newPushRegistry(), &PushConfig{
+		URL:     server.URL + "/",
+		Headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pusher.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+
+	if request.method != http.MethodPut {
+		t.Errorf("unexpected method [%v]", request.method)
+	}
+
+	expectedPath := "/metrics/job/tbtc-relay/target/mainnet"
+	if request.path != expectedPath {
+		t.Errorf(
+			"unexpected path\nexpected: [%v]\nactual:   [%v]",
+			expectedPath,
+			request.path,
+		)
+	}
+
+	if request.authorization != "Basic dXNlcjpwYXNz" {
+		t.Errorf("missing configured header")
+	}
+
+	expectedLines := []string{
+		"# TYPE build_info gauge",
+		`build_info{version="v1.2.3"} 1`,
+		"# TYPE headers_relay_lag gauge",
+		"headers_relay_lag 3",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(request.body, line+"\n") {
+			t.Errorf("missing line [%v] in body:\n%v", line, request.body)
+		}
+	}
+
+	if !strings.Contains(request.body, "# HELP headers_relay_lag ") {
+		t.Errorf("missing help of defined metric in body:\n%v", request.body)
+	}
+}
+
+func TestPusher_OTLP(t *testing.T) {
+	server, requests := newPushServer(t)
+	defer server.Close()
+
+	pusher, err := newPusher(newPushRegistry(), &PushConfig{
+		URL:      server.URL + "/v1/metrics",
+		Protocol: PushProtocolOTLP,
+		Job:      "relay",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pusher.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+
+	if request.method != http.MethodPost || request.path != "/v1/metrics" {
+		t.Errorf("unexpected request [%v %v]", request.method, request.path)
+	}
+
+	if request.contentType != "application/json" {
+		t.Errorf("unexpected content type [%v]", request.contentType)
+	}
+
+	var decoded otlpRequest
+	if err := json.Unmarshal([]byte(request.body), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	resource := decoded.ResourceMetrics[0].Resource
+	attributes := make(map[string]string)
+	for _, attribute := range resource.Attributes {
+		attributes[attribute.Key] = attribute.Value.StringValue
+	}
+	if attributes["service.name"] != "relay" ||
+		attributes["target"] != "mainnet" {
+		t.Errorf("unexpected resource attributes [%v]", attributes)
+	}
+
+	pushed := decoded.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(pushed) != 2 {
+		t.Fatalf("unexpected number of metrics [%v]", len(pushed))
+	}
+
+	lag := pushed[1]
+	if lag.Name != HeadersRelayLag ||
+		lag.Gauge.DataPoints[0].AsDouble != 3 ||
+		lag.Description == "" {
+		t.Errorf("unexpected metric [%+v]", lag)
+	}
+}
+
+func TestPusher_UnsupportedProtocol(t *testing.T) {
+	_, err := newPusher(newPushRegistry(), &PushConfig{
+		URL:      "http://localhost:9091",
+		Protocol: "graphite",
+	})
+	if err == nil {
+		t.Errorf("expected error for unsupported protocol")
+	}
+}
@@ -0,0 +1,65 @@
+package monitoring
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/keep-network/tbtc/relay/pkg/metrics"
+)
+
+// alerts.go file contains the generator of Prometheus alerting rules. The
+// rules are derived from the alerts attached to the relay metric
+// definitions, so they always refer to the metrics the relay exposes.
+
+// AlertGroupName is the name of the generated alerting rules group.
+const AlertGroupName = "tbtc-relay"
+
+// WriteAlertRules writes Prometheus alerting rules of the given metric
+// definitions in the YAML rules file format.
+func WriteAlertRules(
+	writer io.Writer,
+	definitions []*metrics.Definition,
+) error {
+	var builder strings.Builder
+
+	builder.WriteString("groups:\n")
+	builder.WriteString(fmt.Sprintf("  - name: %v\n", AlertGroupName))
+	builder.WriteString("    rules:\n")
+
+	for _, definition := range definitions {
+		alert := definition.Alert
+		if alert == nil {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("      - alert: %v\n", alert.Name))
+		builder.WriteString(
+			fmt.Sprintf("        expr: %v\n", strconv.Quote(alert.Expression)),
+		)
+		builder.WriteString(fmt.Sprintf("        for: %v\n", alert.For))
+		builder.WriteString("        labels:\n")
+		builder.WriteString(
+			fmt.Sprintf("          severity: %v\n", alert.Severity),
+		)
+		builder.WriteString("        annotations:\n")
+		builder.WriteString(
+			fmt.Sprintf("          summary: %v\n", strconv.Quote(alert.Summary)),
+		)
+		builder.WriteString(
+			fmt.Sprintf(
+				"          description: %v\n",
+				strconv.Quote(
+					definition.Help+
+						" Instance {{ $labels.instance }}, "+
+						"target {{ $labels.target }}, "+
+						"value {{ $value }}.",
+				),
+			),
+		)
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
@@ -0,0 +1,199 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/keep-network/tbtc/relay/pkg/metrics"
+)
+
+// dashboard.go file contains the generator of a Grafana dashboard showing
+// all the relay metrics. The dashboard contains a row per metric group and
+// a panel per metric, so it always matches the metrics the relay exposes.
+
+const (
+	// DashboardTitle is the title of the generated dashboard.
+	DashboardTitle = "tBTC Relay"
+
+	// DashboardUID is the UID of the generated dashboard.
+	DashboardUID = "tbtc-relay"
+
+	// Number of panels in a single dashboard row.
+	panelsPerRow = 3
+	// Width of the dashboard grid.
+	gridWidth = 24
+	// Height of a single panel.
+	panelHeight = 8
+)
+
+type dashboard struct {
+	Title         string     `json:"title"`
+	UID           string     `json:"uid"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          timeRange  `json:"time"`
+	Templating    templating `json:"templating"`
+	Panels        []*panel   `json:"panels"`
+	Tags          []string   `json:"tags"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type templating struct {
+	List []*variable `json:"list"`
+}
+
+type variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Query      interface{} `json:"query"`
+	Datasource string      `json:"datasource,omitempty"`
+	IncludeAll bool        `json:"includeAll,omitempty"`
+	AllValue   string      `json:"allValue,omitempty"`
+	Multi      bool        `json:"multi,omitempty"`
+	Refresh    int         `json:"refresh,omitempty"`
+}
+
+type panel struct {
+	ID          int       `json:"id"`
+	Type        string    `json:"type"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Datasource  string    `json:"datasource,omitempty"`
+	GridPos     gridPos   `json:"gridPos"`
+	Targets     []*target `json:"targets,omitempty"`
+}
+
+type gridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type target struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+	RefID        string `json:"refId"`
+	Instant      bool   `json:"instant,omitempty"`
+	Format       string `json:"format,omitempty"`
+}
+
+// WriteDashboard writes a Grafana dashboard of the given metric definitions
+// in the JSON format accepted by the Grafana dashboard import.
+func WriteDashboard(
+	writer io.Writer,
+	definitions []*metrics.Definition,
+) error {
+	const datasource = "${datasource}"
+
+	result := &dashboard{
+		Title:         DashboardTitle,
+		UID:           DashboardUID,
+		SchemaVersion: 27,
+		Refresh:       "1m",
+		Time:          timeRange{From: "now-24h", To: "now"},
+		Tags:          []string{"tbtc", "relay"},
+		Templating: templating{
+			List: []*variable{
+				{
+					Name:  "datasource",
+					Label: "Data source",
+					Type:  "datasource",
+					Query: "prometheus",
+				},
+				{
+					Name:       "target",
+					Label:      "Target",
+					Type:       "query",
+					Datasource: datasource,
+					Query: fmt.Sprintf(
+						"label_values(%v, target)",
+						metrics.HeadersRelayActive,
+					),
+					IncludeAll: true,
+					AllValue:   ".*",
+					Multi:      true,
+					Refresh:    2,
+				},
+			},
+		},
+	}
+
+	id := 0
+	nextID := func() int {
+		id++
+		return id
+	}
+
+	y := 0
+	group := ""
+	column := 0
+
+	for _, definition := range definitions {
+		if definition.Group != group {
+			if column > 0 {
+				y += panelHeight
+			}
+
+			result.Panels = append(result.Panels, &panel{
+				ID:      nextID(),
+				Type:    "row",
+				Title:   definition.Group,
+				GridPos: gridPos{X: 0, Y: y, W: gridWidth, H: 1},
+			})
+
+			y++
+			group = definition.Group
+			column = 0
+		}
+
+		width := gridWidth / panelsPerRow
+		selector := fmt.Sprintf("%v{target=~\"$target\"}", definition.Name)
+
+		metricPanel := &panel{
+			ID:          nextID(),
+			Type:        "timeseries",
+			Title:       definition.Name,
+			Description: definition.Help,
+			Datasource:  datasource,
+			GridPos: gridPos{
+				X: column * width,
+				Y: y,
+				W: width,
+				H: panelHeight,
+			},
+			Targets: []*target{
+				{
+					Expr:         selector,
+					LegendFormat: "{{instance}} {{target}}",
+					RefID:        "A",
+				},
+			},
+		}
+
+		if definition.Info {
+			metricPanel.Type = "table"
+			metricPanel.Targets[0].Instant = true
+			metricPanel.Targets[0].Format = "table"
+		}
+
+		result.Panels = append(result.Panels, metricPanel)
+
+		column++
+		if column == panelsPerRow {
+			column = 0
+			y += panelHeight
+		}
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(result)
+}
@@ -0,0 +1,65 @@
+package monitoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/metrics"
+)
+
+func TestWriteAlertRules(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteAlertRules(&buffer, metrics.Definitions()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, definition := range metrics.Definitions() {
+		if definition.Alert == nil {
+			continue
+		}
+
+		for _, expected := range []string{
+			"      - alert: " + definition.Alert.Name + "\n",
+			"        expr: \"" + definition.Alert.Expression + "\"\n",
+		} {
+			if !strings.Contains(buffer.String(), expected) {
+				t.Errorf(
+					"missing alert rule line:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expected,
+					buffer.String(),
+				)
+			}
+		}
+	}
+}
+
+func TestWriteDashboard(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteDashboard(&buffer, metrics.Definitions()); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &dashboard{}
+	if err := json.Unmarshal(buffer.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	titles := make(map[string]bool)
+	for _, panel := range decoded.Panels {
+		titles[panel.Title] = true
+	}
+
+	for _, definition := range metrics.Definitions() {
+		if !titles[definition.Name] {
+			t.Errorf("missing panel of metric [%v]", definition.Name)
+		}
+
+		if !titles[definition.Group] {
+			t.Errorf("missing row of group [%v]", definition.Group)
+		}
+	}
+}
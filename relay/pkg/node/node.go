@@ -6,39 +6,126 @@ import (
 
 	"github.com/keep-network/tbtc/relay/pkg/header"
 
-	"github.com/ipfs/go-log"
 	"github.com/keep-network/tbtc/relay/pkg/btc"
 	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/events"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/store"
 )
 
-// Back-off time which should be applied when the relay is restarted.
-// It helps to avoid being flooded with error logs in case of a permanent error
-// in the relay.
-const restartBackoffTime = 10 * time.Second
+const (
+	// Back-off time which should be applied when the relay is restarted.
+	// It helps to avoid being flooded with error logs in case of a permanent
+	// error in the relay.
+	restartBackoffTime = 10 * time.Second
 
-var logger = log.Logger("tbtc-relay-node")
+	// Maximum back-off time applied once the relay keeps failing right
+	// after its restarts, e.g. because each RPC call to a misbehaving node
+	// fails. The back-off time doubles with each subsequent failure until
+	// it reaches this value.
+	maxRestartBackoffTime = 10 * time.Minute
+
+	// Time for which the relay must run before a failure is no longer
+	// considered subsequent to the previous ones.
+	stableRunTime = 10 * time.Minute
+
+	// Number of subsequent relay failures above which the relay is
+	// considered stuck in an error loop.
+	errorLoopThreshold = 5
+)
+
+// Number of relay errors buffered for the consumer of the node errors.
+// Errors raised while the buffer is full are not delivered.
+const errorsBufferSize = 10
+
+// Name of the default logger of the relay node.
+const loggerName = "tbtc-relay-node"
 
 // Node represents a relay node.
 type Node struct {
-	stats *stats
+	stats   *stats
+	control *header.Control
+	feed    *header.Feed
+	bus     *events.Bus
+	errors  chan error
+	stopped chan struct{}
+	clock   clock.Clock
+
+	logger logs.Logger
+	// relayLogger is the logger injected into the headers relay. If nil,
+	// the relay uses its default logger.
+	relayLogger logs.Logger
 }
 
-// Initialize initializes the relay node.
+// Initialize initializes the relay node. The profitability estimator is
+// optional and can be nil; in that case pushes are never gated by their
+// profitability. The queue store is optional as well; without it, headers
+// pulled but not pushed yet are lost once the relay stops. The pipeline
+// customizations can be nil if the relay should pull headers from the
+// Bitcoin chain and push them to the relay contract. Messages of the node and
+// its headers relay are logged to the given logger or, if it is nil, to their
+// default loggers.
 //
 // TODO: This function will be probably the right place to handle relay auctions
-//  which will require starting and stopping the headers relay.
+// which will require starting and stopping the headers relay.
 func Initialize(
 	ctx context.Context,
 	btcChain btc.Handle,
 	hostChain chain.Handle,
+	relayStore *store.Store,
+	relayConfig *header.Config,
+	profitability header.ProfitabilityEstimator,
+	queueStore header.QueueStore,
+	pipeline *header.Pipeline,
+	logger logs.Logger,
 ) *Node {
-	logger.Infof("initializing relay node")
-
 	node := &Node{
-		stats: newStats(),
+		stats:       newStats(),
+		control:     header.NewControl(logger),
+		feed:        header.NewFeed(logger),
+		bus:         events.NewBus(),
+		errors:      make(chan error, errorsBufferSize),
+		stopped:     make(chan struct{}),
+		clock:       clock.System,
+		logger:      logs.OrDefault(logger, loggerName),
+		relayLogger: logger,
 	}
 
-	go node.startRelayControlLoop(ctx, btcChain, hostChain)
+	node.logger.Infof("initializing relay node")
+
+	// The statistics and the headers feed are consumers of the relay events
+	// like any other, so they learn about them from the event bus.
+	node.bus.Subscribe("stats", node.stats.handleEvent)
+	node.bus.Subscribe(
+		"headers feed",
+		events.RelayHandler(node.feed),
+		events.RelayTopics...,
+	)
+
+	for task, schedule := range relayConfig.Schedules {
+		if err := node.control.Scheduler().SetSchedule(
+			task,
+			schedule,
+		); err != nil {
+			node.logger.Errorf(
+				"could not set schedule of task [%v]: [%v]",
+				task,
+				err,
+			)
+		}
+	}
+
+	go node.startRelayControlLoop(
+		ctx,
+		btcChain,
+		hostChain,
+		relayStore,
+		relayConfig,
+		profitability,
+		queueStore,
+		pipeline,
+	)
 
 	return node
 }
@@ -51,34 +138,145 @@ func (n *Node) startRelayControlLoop(
 	ctx context.Context,
 	btcChain btc.Handle,
 	hostChain chain.Handle,
+	relayStore *store.Store,
+	relayConfig *header.Config,
+	profitability header.ProfitabilityEstimator,
+	queueStore header.QueueStore,
+	pipeline *header.Pipeline,
 ) {
-	logger.Infof("starting headers relay")
+	n.logger.Infof("starting headers relay")
 	n.stats.notifyHeadersRelayActive()
 
 	defer func() {
-		logger.Infof("stopping headers relay")
+		n.logger.Infof("stopping headers relay")
 		n.stats.notifyHeadersRelayInactive()
+		close(n.stopped)
 	}()
 
+	// failures is the number of subsequent relay failures.
+	failures := 0
+
 	for {
-		relay := header.StartRelay(ctx, btcChain, hostChain, n.stats)
+		startedAt := n.clock.Now()
+
+		relay := header.StartRelay(
+			ctx,
+			btcChain,
+			hostChain,
+			relayStore,
+			relayConfig,
+			n.control,
+			events.RelayPublisher(n.bus),
+			profitability,
+			queueStore,
+			pipeline,
+			n.relayLogger,
+		)
 
 		select {
 		case err := <-relay.ErrChan():
-			logger.Errorf(
+			// The next relay must not start before the current one
+			// persisted its queue, as both would touch the same state.
+			<-relay.Stopped()
+
+			if err == header.ErrResyncRequested {
+				n.logger.Infof("restarting headers relay to resync best header")
+				continue
+			}
+
+			n.logger.Errorf(
 				"headers relay raised an error: [%v]",
 				err,
 			)
 
-			n.stats.notifyHeadersRelayErrored()
+			n.bus.Publish(&events.Event{
+				Topic: events.TopicErrorOccurred,
+				Err:   err,
+			})
+			n.reportError(err)
 		case <-ctx.Done():
+			// Let the relay persist its state before the node is
+			// considered stopped.
+			<-relay.Stopped()
 			return
 		}
 
-		time.Sleep(restartBackoffTime)
+		if n.clock.Since(startedAt) >= stableRunTime {
+			failures = 0
+		}
+		failures++
+
+		backoff := restartBackoff(failures)
+		if failures > errorLoopThreshold {
+			n.logger.Warnf(
+				"headers relay failed [%v] times in a row; "+
+					"delaying restart for [%v]",
+				failures,
+				backoff,
+			)
+		}
+
+		select {
+		case <-n.clock.After(backoff):
+		case <-ctx.Done():
+			<-relay.Stopped()
+			return
+		}
+	}
+}
+
+// restartBackoff returns the back-off time applied before the relay restart
+// after the given number of subsequent failures.
+func restartBackoff(failures int) time.Duration {
+	backoff := restartBackoffTime
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+		if backoff >= maxRestartBackoffTime {
+			return maxRestartBackoffTime
+		}
+	}
+
+	return backoff
+}
+
+// reportError passes the relay error to the consumer of the node errors
+// unless the errors buffer is full.
+func (n *Node) reportError(err error) {
+	select {
+	case n.errors <- err:
+	default:
 	}
 }
 
+// Control returns the runtime control of the headers relay.
+func (n *Node) Control() *header.Control {
+	return n.control
+}
+
+// Feed returns the feed of headers pulled and pushed by the relay.
+func (n *Node) Feed() *header.Feed {
+	return n.feed
+}
+
+// Bus returns the bus of events published by the node, like headers pulled
+// and pushed by the relay or relay errors.
+func (n *Node) Bus() *events.Bus {
+	return n.bus
+}
+
+// Errors returns the channel delivering errors raised by the headers relay.
+// The relay is restarted after each error, so the errors are informational.
+// Consuming them is optional.
+func (n *Node) Errors() <-chan error {
+	return n.errors
+}
+
+// Stopped returns a channel which is closed once the headers relay of the
+// node has stopped after the node context was cancelled.
+func (n *Node) Stopped() <-chan struct{} {
+	return n.stopped
+}
+
 // Stats returns relay node statistics.
 func (n *Node) Stats() Stats {
 	return n.stats
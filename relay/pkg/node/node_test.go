@@ -0,0 +1,68 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	var tests = map[string]struct {
+		failures        int
+		expectedBackoff time.Duration
+	}{
+		"first failure": {
+			failures:        1,
+			expectedBackoff: 10 * time.Second,
+		},
+		"subsequent failure": {
+			failures:        3,
+			expectedBackoff: 40 * time.Second,
+		},
+		"error loop": {
+			failures:        50,
+			expectedBackoff: maxRestartBackoffTime,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			backoff := restartBackoff(test.failures)
+
+			if backoff != test.expectedBackoff {
+				t.Errorf(
+					"unexpected backoff:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedBackoff,
+					backoff,
+				)
+			}
+		})
+	}
+}
+
+func TestUniqueHeights(t *testing.T) {
+	heights := newUniqueHeights()
+
+	for height := int64(0); height < 3*uniqueHeightsWindow; height++ {
+		heights.add(height)
+		heights.add(height)
+	}
+
+	if heights.count != 3*uniqueHeightsWindow {
+		t.Errorf(
+			"unexpected count:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			3*uniqueHeightsWindow,
+			heights.count,
+		)
+	}
+
+	if len(heights.seen) != uniqueHeightsWindow {
+		t.Errorf(
+			"unexpected number of remembered heights: [%v]",
+			len(heights.seen),
+		)
+	}
+}
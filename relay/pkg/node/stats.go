@@ -1,6 +1,13 @@
 package node
 
-import "sync"
+import (
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/events"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
 
 // Stats exposes statistics of the relay node.
 type Stats interface {
@@ -17,6 +24,54 @@ type Stats interface {
 	// UniqueHeadersPushed returns the number of unique headers pushed during
 	// the relay node lifetime.
 	UniqueHeadersPushed() int
+
+	// HeadersRelayLag returns the most recently observed relay lag, i.e.
+	// the number of Bitcoin blocks not yet known by the host chain.
+	HeadersRelayLag() int64
+
+	// HeadersRelayLagObserved returns whether the relay lag has been
+	// observed at least once.
+	HeadersRelayLagObserved() bool
+
+	// BtcForks returns the most recently observed number of competing
+	// Bitcoin forks near the chain tip.
+	BtcForks() int
+
+	// BtcForkLength returns the branch length of the longest competing
+	// Bitcoin fork near the chain tip.
+	BtcForkLength() int64
+}
+
+// Number of the most recent heights remembered to tell whether a pulled or
+// pushed header is unique. It bounds the memory used by the statistics of
+// a long-running node, while headers pulled or pushed again after a relay
+// restart or a reorg are still counted once.
+const uniqueHeightsWindow = 2 * 2016
+
+// uniqueHeights counts unique heights, remembering only the most recent ones.
+type uniqueHeights struct {
+	count  int
+	seen   map[int64]bool
+	recent []int64
+}
+
+func newUniqueHeights() *uniqueHeights {
+	return &uniqueHeights{seen: make(map[int64]bool)}
+}
+
+func (uh *uniqueHeights) add(height int64) {
+	if uh.seen[height] {
+		return
+	}
+
+	if len(uh.recent) >= uniqueHeightsWindow {
+		delete(uh.seen, uh.recent[0])
+		uh.recent = uh.recent[1:]
+	}
+
+	uh.recent = append(uh.recent, height)
+	uh.seen[height] = true
+	uh.count++
 }
 
 // stats gathers and exposes statistics of the relay node.
@@ -25,14 +80,18 @@ type stats struct {
 
 	headersRelayActive  bool
 	headersRelayErrors  int
-	uniqueHeadersPulled map[int64]bool
-	uniqueHeadersPushed map[int64]bool
+	uniqueHeadersPulled *uniqueHeights
+	uniqueHeadersPushed *uniqueHeights
+	headersRelayLag     int64
+	headersRelayLagSeen bool
+	btcForks            int
+	btcForkLength       int64
 }
 
 func newStats() *stats {
 	return &stats{
-		uniqueHeadersPulled: make(map[int64]bool),
-		uniqueHeadersPushed: make(map[int64]bool),
+		uniqueHeadersPulled: newUniqueHeights(),
+		uniqueHeadersPushed: newUniqueHeights(),
 	}
 }
 
@@ -57,24 +116,80 @@ func (s *stats) notifyHeadersRelayErrored() {
 	s.headersRelayErrors++
 }
 
+// handleEvent updates the statistics with the given event of the node
+// event bus.
+func (s *stats) handleEvent(event *events.Event) {
+	if event.Topic == events.TopicErrorOccurred {
+		s.notifyHeadersRelayErrored()
+		return
+	}
+
+	events.RelayHandler(s)(event)
+}
+
 // NotifyHeaderPulled notifies about new header pulled from the Bitcoin chain.
-func (s *stats) NotifyHeaderPulled(headerHeight int64) {
+func (s *stats) NotifyHeaderPulled(header *btc.Header) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.uniqueHeadersPulled[headerHeight] = true
+	s.uniqueHeadersPulled.add(header.Height)
 }
 
 // NotifyHeadersPushed notifies about new headers pushed to the host chain.
-func (s *stats) NotifyHeadersPushed(headersHeights []int64) {
+func (s *stats) NotifyHeadersPushed(headers []*btc.Header) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	for _, headerHeight := range headersHeights {
-		s.uniqueHeadersPushed[headerHeight] = true
+	for _, header := range headers {
+		s.uniqueHeadersPushed.add(header.Height)
 	}
 }
 
+// NotifyHeadersConfirmed notifies about pushed headers known by the host
+// chain. Confirmations are not tracked by the stats.
+func (s *stats) NotifyHeadersConfirmed(
+	headers []*btc.Header,
+	confirmedAt time.Time,
+) {
+	// no-op
+}
+
+// NotifyRelayLag notifies about the current relay lag.
+func (s *stats) NotifyRelayLag(lag int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.headersRelayLag = lag
+	s.headersRelayLagSeen = true
+}
+
+// NotifyForks notifies about the competing Bitcoin forks near the chain tip.
+func (s *stats) NotifyForks(forks int, length int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.btcForks = forks
+	s.btcForkLength = length
+}
+
+// NotifyRetargetSubmitted notifies about a retarget submitted to the host
+// chain. Retargets are not tracked by the stats.
+func (s *stats) NotifyRetargetSubmitted(epoch uint64, header *btc.Header) {
+	// no-op
+}
+
+// NotifyEpochEndApproaching notifies that the difficulty epoch end
+// approaches. Epoch ends are not tracked by the stats.
+func (s *stats) NotifyEpochEndApproaching(epoch uint64, header *btc.Header) {
+	// no-op
+}
+
+// NotifyReorg notifies about a reorg recorded by the relay. Reorgs are kept
+// in the relay store rather than the stats.
+func (s *stats) NotifyReorg(header *btc.Header, reorg *store.Reorg) {
+	// no-op
+}
+
 // HeadersRelayActive returns whether the headers relay process is active.
 func (s *stats) HeadersRelayActive() bool {
 	s.mutex.RLock()
@@ -97,7 +212,7 @@ func (s *stats) UniqueHeadersPulled() int {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	return len(s.uniqueHeadersPulled)
+	return s.uniqueHeadersPulled.count
 }
 
 // UniqueHeadersPushed returns the number of unique headers pushed during
@@ -106,5 +221,41 @@ func (s *stats) UniqueHeadersPushed() int {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	return len(s.uniqueHeadersPushed)
+	return s.uniqueHeadersPushed.count
+}
+
+// HeadersRelayLag returns the most recently observed relay lag, i.e.
+// the number of Bitcoin blocks not yet known by the host chain.
+func (s *stats) HeadersRelayLag() int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.headersRelayLag
+}
+
+// HeadersRelayLagObserved returns whether the relay lag has been observed
+// at least once.
+func (s *stats) HeadersRelayLagObserved() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.headersRelayLagSeen
+}
+
+// BtcForks returns the most recently observed number of competing Bitcoin
+// forks near the chain tip.
+func (s *stats) BtcForks() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.btcForks
+}
+
+// BtcForkLength returns the branch length of the longest competing Bitcoin
+// fork near the chain tip.
+func (s *stats) BtcForkLength() int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.btcForkLength
 }
@@ -0,0 +1,291 @@
+package proof
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// archive.go file contains the archival sources of transaction proofs. If
+// the Bitcoin node cannot serve a proof, e.g. because it prunes old blocks
+// or runs without the transaction index, the block including the transaction
+// and its transaction IDs are fetched from an archival source instead. The
+// archival source is not trusted: the block must be in the longest chain
+// known by the node, it must be known by the relay and its transaction IDs
+// must match the merkle root of its header.
+
+// Sources a proof bundle can be built from.
+const (
+	// SourceCache means the proof has been built from a cached merkle tree.
+	SourceCache = "cache"
+	// SourceNode means the proof has been built from the block fetched from
+	// the Bitcoin node.
+	SourceNode = "node"
+	// SourceArchive means the proof has been built from the block fetched
+	// from the archival source.
+	SourceArchive = "archive"
+)
+
+// Maximum time a single request to the Esplora API can take.
+const esploraRequestTimeout = 30 * time.Second
+
+// ArchiveConfig is the configuration of the archival source of proofs the
+// Bitcoin node cannot serve.
+type ArchiveConfig struct {
+	// Esplora is the base URL of an Esplora API, e.g.
+	// `https://blockstream.info/api`.
+	Esplora string
+
+	// Bitcoin is the connection to an archival Bitcoin node running with
+	// the transaction index. It is used if Esplora is not set.
+	Bitcoin btc.Config
+}
+
+// IsEnabled checks whether an archival source is configured.
+func (ac *ArchiveConfig) IsEnabled() bool {
+	return ac.Esplora != "" || ac.Bitcoin.URL != ""
+}
+
+// ArchiveSource is an archival source of blocks.
+type ArchiveSource interface {
+	// TransactionBlock returns the digest of the block including the
+	// confirmed transaction with the given ID.
+	TransactionBlock(ctx context.Context, txID btc.Digest) (btc.Digest, error)
+
+	// BlockTxIDs returns IDs of all transactions included in the block with
+	// the given digest, in the order of the block.
+	BlockTxIDs(ctx context.Context, digest btc.Digest) ([]btc.Digest, error)
+
+	String() string
+}
+
+// BlockVerifier checks whether the proofs of transactions from the block
+// with the given header can be served, e.g. whether the block is known by
+// the relay.
+type BlockVerifier func(ctx context.Context, header *btc.Header) error
+
+// NewChainSource creates an archival source backed by the given handle of an
+// archival Bitcoin node.
+func NewChainSource(btcChain btc.Handle) ArchiveSource {
+	return &chainSource{btcChain: btcChain}
+}
+
+type chainSource struct {
+	btcChain btc.Handle
+}
+
+func (cs *chainSource) TransactionBlock(
+	ctx context.Context,
+	txID btc.Digest,
+) (btc.Digest, error) {
+	transaction, err := cs.btcChain.GetTransaction(ctx, txID)
+	if err != nil {
+		return btc.Digest{}, err
+	}
+
+	if !transaction.IsConfirmed() {
+		return btc.Digest{}, fmt.Errorf(
+			"transaction [%v] is not confirmed",
+			chainhash.Hash(txID),
+		)
+	}
+
+	return *transaction.BlockHash, nil
+}
+
+func (cs *chainSource) BlockTxIDs(
+	ctx context.Context,
+	digest btc.Digest,
+) ([]btc.Digest, error) {
+	return cs.btcChain.GetBlockTxIDs(ctx, digest)
+}
+
+func (cs *chainSource) String() string {
+	return "archival Bitcoin node"
+}
+
+// NewEsploraSource creates an archival source backed by the Esplora API with
+// the given base URL.
+func NewEsploraSource(url string) ArchiveSource {
+	return &esploraSource{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: esploraRequestTimeout},
+	}
+}
+
+type esploraSource struct {
+	url    string
+	client *http.Client
+}
+
+// esploraStatus is the confirmation status of a transaction returned by the
+// Esplora API.
+type esploraStatus struct {
+	Confirmed bool   `json:"confirmed"`
+	BlockHash string `json:"block_hash"`
+}
+
+func (es *esploraSource) TransactionBlock(
+	ctx context.Context,
+	txID btc.Digest,
+) (btc.Digest, error) {
+	var status esploraStatus
+	if err := es.get(
+		ctx,
+		"/tx/"+chainhash.Hash(txID).String()+"/status",
+		&status,
+	); err != nil {
+		return btc.Digest{}, err
+	}
+
+	if !status.Confirmed {
+		return btc.Digest{}, fmt.Errorf(
+			"transaction [%v] is not confirmed",
+			chainhash.Hash(txID),
+		)
+	}
+
+	hash, err := chainhash.NewHashFromStr(status.BlockHash)
+	if err != nil {
+		return btc.Digest{}, fmt.Errorf("invalid block hash: [%v]", err)
+	}
+
+	return btc.Digest(*hash), nil
+}
+
+func (es *esploraSource) BlockTxIDs(
+	ctx context.Context,
+	digest btc.Digest,
+) ([]btc.Digest, error) {
+	var encodedTxIDs []string
+	if err := es.get(
+		ctx,
+		"/block/"+chainhash.Hash(digest).String()+"/txids",
+		&encodedTxIDs,
+	); err != nil {
+		return nil, err
+	}
+
+	txIDs := make([]btc.Digest, len(encodedTxIDs))
+	for i, encodedTxID := range encodedTxIDs {
+		txID, err := chainhash.NewHashFromStr(encodedTxID)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid ID of transaction [%v]: [%v]",
+				i,
+				err,
+			)
+		}
+
+		txIDs[i] = btc.Digest(*txID)
+	}
+
+	return txIDs, nil
+}
+
+func (es *esploraSource) get(
+	ctx context.Context,
+	path string,
+	target interface{},
+) error {
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		es.url+path,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	response, err := es.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf(
+			"unexpected status [%v]: [%v]",
+			response.Status,
+			strings.TrimSpace(string(message)),
+		)
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+func (es *esploraSource) String() string {
+	return fmt.Sprintf("Esplora API [%v]", es.url)
+}
+
+// archivedProof returns the proof of inclusion of the confirmed transaction
+// with the given ID using the block fetched from the archival source. The
+// header of the block is taken from the Bitcoin node.
+func archivedProof(
+	ctx context.Context,
+	btcChain btc.Handle,
+	source ArchiveSource,
+	verify BlockVerifier,
+	txID btc.Digest,
+) (*Bundle, error) {
+	blockDigest, err := source.TransactionBlock(ctx, txID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction block: [%v]", err)
+	}
+
+	header, err := btcChain.GetHeaderByDigest(ctx, blockDigest)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"block [%v] is not known by the Bitcoin node: [%v]",
+			blockDigest,
+			err,
+		)
+	}
+
+	bestHeader, err := btcChain.GetHeaderByHeight(ctx, header.Height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block header: [%v]", err)
+	}
+
+	if bestHeader.Hash != header.Hash {
+		return nil, fmt.Errorf(
+			"block [%v] is not in the longest chain",
+			blockDigest,
+		)
+	}
+
+	if verify != nil {
+		if err := verify(ctx, header); err != nil {
+			return nil, err
+		}
+	}
+
+	txIDs, err := source.BlockTxIDs(ctx, blockDigest)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block transactions: [%v]", err)
+	}
+
+	tree, err := verifiedMerkleTree(txIDs, header)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := newBundle(txID, header, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle.Source = SourceArchive
+
+	return bundle, nil
+}
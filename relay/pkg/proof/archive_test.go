@@ -0,0 +1,100 @@
+package proof
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestCache_ArchivedProof(t *testing.T) {
+	ctx := context.Background()
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	// The node keeps the headers but not the blocks, as if it was pruned.
+	txIDs := syntheticTxIDs(5)
+	merkleRoot, err := ComputeMerkleRoot(txIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := &btc.Header{
+		Height:     1,
+		Hash:       btc.Digest{0xb0, 1},
+		MerkleRoot: merkleRoot,
+	}
+	btcChain.SetHeaders([]*btc.Header{header})
+
+	archivedTxIDs := txIDs
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			blockHash := chainhash.Hash(header.Hash).String()
+
+			switch r.URL.Path {
+			case "/tx/" + chainhash.Hash(txIDs[3]).String() + "/status":
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"confirmed":  true,
+					"block_hash": blockHash,
+				})
+			case "/block/" + blockHash + "/txids":
+				encoded := make([]string, len(archivedTxIDs))
+				for i, txID := range archivedTxIDs {
+					encoded[i] = chainhash.Hash(txID).String()
+				}
+				_ = json.NewEncoder(w).Encode(encoded)
+			default:
+				http.NotFound(w, r)
+			}
+		},
+	))
+	defer server.Close()
+
+	var verifyErr error
+	cache := NewCache(btcChain, 1)
+	cache.SetArchive(
+		NewEsploraSource(server.URL+"/"),
+		func(ctx context.Context, header *btc.Header) error {
+			return verifyErr
+		},
+	)
+
+	bundle, err := cache.TransactionProof(ctx, txIDs[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bundle.Source != SourceArchive ||
+		bundle.BlockDigest != header.Hash ||
+		bundle.Index != 3 {
+		t.Errorf(
+			"unexpected bundle [%v] of block [%v] at index [%v]",
+			bundle.Source,
+			bundle.BlockDigest,
+			bundle.Index,
+		)
+	}
+
+	// Blocks rejected by the verifier are not served.
+	verifyErr = fmt.Errorf("block is not known by the relay")
+	if _, err := cache.TransactionProof(ctx, txIDs[3]); err == nil {
+		t.Errorf("expected error for block rejected by the verifier")
+	}
+	verifyErr = nil
+
+	// Transactions of the archival source must match the block header.
+	archivedTxIDs = syntheticTxIDs(4)
+	if _, err := cache.TransactionProof(ctx, txIDs[3]); err == nil {
+		t.Errorf("expected error for transactions not matching the header")
+	}
+}
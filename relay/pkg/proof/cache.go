@@ -0,0 +1,356 @@
+package proof
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// cache.go file contains the cache of merkle trees of the most recent
+// Bitcoin blocks. Trees of new blocks are built as soon as the blocks are
+// mined, so proofs of fresh transactions, which is the common case in tBTC
+// funding, are served from memory without any call to the Bitcoin node.
+// Proofs of older transactions are built on demand.
+
+var logger = log.Logger("tbtc-relay-proof")
+
+const (
+	// DefaultCachedBlocks is the default number of the most recent blocks
+	// whose merkle trees are cached.
+	DefaultCachedBlocks = 12
+
+	// DefaultPregenerationTick is the default interval in which new blocks
+	// are checked.
+	DefaultPregenerationTick = 30 * time.Second
+)
+
+// Config holds the configuration of the proof cache.
+type Config struct {
+	// Enabled determines whether merkle trees of recent blocks are cached.
+	Enabled bool
+
+	// CachedBlocks is the number of the most recent blocks whose merkle trees
+	// are cached. If zero, a default value is used.
+	CachedBlocks int
+
+	// PregenerationTick is the interval, in seconds, in which new blocks are
+	// checked. If zero, a default value is used.
+	PregenerationTick int
+
+	// Archive configures the archival source of proofs the Bitcoin node
+	// cannot serve.
+	Archive ArchiveConfig
+}
+
+// Bundle is the proof of inclusion of a transaction in a block.
+type Bundle struct {
+	// TxID is the ID of the proven transaction.
+	TxID btc.Digest
+	// BlockDigest is the digest of the block including the transaction.
+	BlockDigest btc.Digest
+	// BlockHeight is the height of the block including the transaction.
+	BlockHeight int64
+	// Index is the position of the transaction in the block.
+	Index uint64
+	// MerkleProof is the merkle proof in the format accepted by
+	// VerifyMerkleProof.
+	MerkleProof []byte
+	// Source is the source the proof has been built from; one of
+	// SourceCache, SourceNode and SourceArchive.
+	Source string
+}
+
+// cachedBlock is a single block whose merkle tree is cached.
+type cachedBlock struct {
+	header *btc.Header
+	tree   *MerkleTree
+}
+
+// Cache holds merkle trees of the most recent blocks and serves proofs of
+// transaction inclusion.
+type Cache struct {
+	btcChain btc.Handle
+	size     int
+
+	mutex sync.RWMutex
+	// blocks maps block heights to the cached blocks.
+	blocks map[int64]*cachedBlock
+	// txBlocks maps IDs of transactions included in the cached blocks to the
+	// heights of their blocks.
+	txBlocks map[btc.Digest]int64
+
+	archive       ArchiveSource
+	verifyArchive BlockVerifier
+}
+
+// NewCache creates a new cache holding merkle trees of the given number of
+// the most recent blocks.
+func NewCache(btcChain btc.Handle, size int) *Cache {
+	if size <= 0 {
+		size = DefaultCachedBlocks
+	}
+
+	return &Cache{
+		btcChain: btcChain,
+		size:     size,
+		blocks:   make(map[int64]*cachedBlock),
+		txBlocks: make(map[btc.Digest]int64),
+	}
+}
+
+// SetArchive sets the archival source of proofs the Bitcoin chain cannot
+// serve. Blocks fetched from the archival source are checked with the given
+// verifier, if any, before their proofs are served. It must be called before
+// any proof is requested.
+func (c *Cache) SetArchive(source ArchiveSource, verify BlockVerifier) {
+	c.archive = source
+	c.verifyArchive = verify
+}
+
+// Start starts building the merkle trees of new blocks in the given tick.
+// Building stops once the passed context is done.
+func (c *Cache) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultPregenerationTick
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			if err := c.pregenerate(ctx); err != nil {
+				logger.Warnf("could not pregenerate merkle trees: [%v]", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// pregenerate builds the merkle trees of the most recent blocks which are
+// not cached yet. Blocks are checked from the chain tip down until a cached
+// block still on the chain is found, so blocks replaced by a reorg are
+// replaced in the cache as well. Blocks below the cached range are evicted.
+func (c *Cache) pregenerate(ctx context.Context) error {
+	tipHeight, err := c.btcChain.GetBlockCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	lowestHeight := tipHeight - int64(c.size) + 1
+	if lowestHeight < 0 {
+		lowestHeight = 0
+	}
+
+	for height := tipHeight; height >= lowestHeight; height-- {
+		header, err := c.btcChain.GetHeaderByHeight(ctx, height)
+		if err != nil {
+			return err
+		}
+
+		c.mutex.RLock()
+		cached, ok := c.blocks[height]
+		c.mutex.RUnlock()
+
+		if ok && cached.header.Hash == header.Hash {
+			break
+		}
+
+		tree, err := fetchBlockMerkleTree(ctx, c.btcChain, header)
+		if err != nil {
+			return err
+		}
+
+		c.put(height, &cachedBlock{header, tree})
+
+		logger.Debugf(
+			"cached merkle tree of block [%v] with [%v] transactions",
+			height,
+			tree.Size(),
+		)
+	}
+
+	c.evictBelow(lowestHeight)
+
+	return nil
+}
+
+func (c *Cache) put(height int64, block *cachedBlock) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.removeLocked(height)
+
+	c.blocks[height] = block
+	for txID := range block.tree.indexes {
+		c.txBlocks[txID] = height
+	}
+}
+
+func (c *Cache) evictBelow(height int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for cachedHeight := range c.blocks {
+		if cachedHeight < height {
+			c.removeLocked(cachedHeight)
+		}
+	}
+}
+
+// removeLocked must be called with the mutex held.
+func (c *Cache) removeLocked(height int64) {
+	block, ok := c.blocks[height]
+	if !ok {
+		return
+	}
+
+	for txID := range block.tree.indexes {
+		if c.txBlocks[txID] == height {
+			delete(c.txBlocks, txID)
+		}
+	}
+
+	delete(c.blocks, height)
+}
+
+// TransactionProof returns the proof of inclusion of the confirmed
+// transaction with the given ID. Proofs of transactions included in the
+// cached blocks are served from memory. Otherwise, the block including the
+// transaction is fetched from the Bitcoin chain or, if the chain cannot serve
+// it and an archival source is set, from the archival source.
+func (c *Cache) TransactionProof(
+	ctx context.Context,
+	txID btc.Digest,
+) (*Bundle, error) {
+	if bundle, ok := c.cachedProof(txID); ok {
+		return bundle, nil
+	}
+
+	bundle, err := FetchTransactionProof(ctx, c.btcChain, txID)
+	if err == nil || c.archive == nil {
+		return bundle, err
+	}
+
+	logger.Debugf(
+		"could not build proof of transaction [%v] from the Bitcoin chain; "+
+			"falling back to [%v]: [%v]",
+		chainhash.Hash(txID),
+		c.archive,
+		err,
+	)
+
+	bundle, archiveErr := archivedProof(
+		ctx,
+		c.btcChain,
+		c.archive,
+		c.verifyArchive,
+		txID,
+	)
+	if archiveErr != nil {
+		return nil, fmt.Errorf(
+			"%v; could not build proof using [%v]: [%v]",
+			err,
+			c.archive,
+			archiveErr,
+		)
+	}
+
+	logger.Infof(
+		"built proof of transaction [%v] using [%v]",
+		chainhash.Hash(txID),
+		c.archive,
+	)
+
+	return bundle, nil
+}
+
+// FetchTransactionProof returns the proof of inclusion of the confirmed
+// transaction with the given ID using the block fetched from the Bitcoin
+// chain.
+func FetchTransactionProof(
+	ctx context.Context,
+	btcChain btc.Handle,
+	txID btc.Digest,
+) (*Bundle, error) {
+	transaction, err := btcChain.GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !transaction.IsConfirmed() {
+		return nil, fmt.Errorf(
+			"transaction [%v] is not confirmed",
+			chainhash.Hash(txID),
+		)
+	}
+
+	header, err := btcChain.GetHeaderByDigest(ctx, *transaction.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block header: [%v]", err)
+	}
+
+	tree, err := fetchBlockMerkleTree(ctx, btcChain, header)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := newBundle(txID, header, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle.Source = SourceNode
+
+	return bundle, nil
+}
+
+func (c *Cache) cachedProof(txID btc.Digest) (*Bundle, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	height, ok := c.txBlocks[txID]
+	if !ok {
+		return nil, false
+	}
+
+	block := c.blocks[height]
+
+	bundle, err := newBundle(txID, block.header, block.tree)
+	if err != nil {
+		return nil, false
+	}
+
+	bundle.Source = SourceCache
+
+	return bundle, true
+}
+
+func newBundle(
+	txID btc.Digest,
+	header *btc.Header,
+	tree *MerkleTree,
+) (*Bundle, error) {
+	merkleProof, index, err := tree.TransactionProof(txID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bundle{
+		TxID:        txID,
+		BlockDigest: header.Hash,
+		BlockHeight: header.Height,
+		Index:       index,
+		MerkleProof: merkleProof,
+	}, nil
+}
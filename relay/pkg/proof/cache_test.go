@@ -0,0 +1,177 @@
+package proof
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestCache_TransactionProof(t *testing.T) {
+	ctx := context.Background()
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	blockTxIDs := make(map[int64][]btc.Digest)
+	for height := int64(1); height <= 3; height++ {
+		txIDs := syntheticTxIDs(5)
+		for i := range txIDs {
+			txIDs[i][30] = byte(height)
+		}
+		blockTxIDs[height] = txIDs
+
+		merkleRoot, err := ComputeMerkleRoot(txIDs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header := &btc.Header{
+			Height:     height,
+			Hash:       btc.Digest{0xb0, byte(height)},
+			MerkleRoot: merkleRoot,
+		}
+		btcChain.AppendHeader(header)
+		btcChain.SetBlockTxIDs(header.Hash, txIDs)
+	}
+
+	oldTxID := blockTxIDs[1][2]
+	oldBlockHash := btc.Digest{0xb0, 1}
+	btcChain.SetTransactions([]*btc.Transaction{
+		{TxID: oldTxID, BlockHash: &oldBlockHash, Confirmations: 3},
+	})
+
+	cache := NewCache(btcChain, 2)
+
+	if err := cache.pregenerate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cache.blocks) != 2 {
+		t.Fatalf("unexpected number of cached blocks: [%v]", len(cache.blocks))
+	}
+
+	// Proofs of transactions from cached blocks must not need the chain.
+	freshBlockHash := btc.Digest{0xb0, 3}
+	btcChain.SetBlockTxIDs(freshBlockHash, nil)
+
+	var tests = map[string]struct {
+		txID           btc.Digest
+		blockTxIDs     []btc.Digest
+		expectedHeight int64
+		expectedIndex  uint64
+	}{
+		"transaction from a cached block": {
+			txID:           blockTxIDs[3][4],
+			blockTxIDs:     blockTxIDs[3],
+			expectedHeight: 3,
+			expectedIndex:  4,
+		},
+		"transaction from an older block": {
+			txID:           oldTxID,
+			blockTxIDs:     blockTxIDs[1],
+			expectedHeight: 1,
+			expectedIndex:  2,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bundle, err := cache.TransactionProof(ctx, test.txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bundle.BlockHeight != test.expectedHeight ||
+				bundle.Index != test.expectedIndex {
+				t.Errorf(
+					"unexpected proof position:\n"+
+						"expected: [height %v, index %v]\n"+
+						"actual:   [height %v, index %v]\n",
+					test.expectedHeight,
+					test.expectedIndex,
+					bundle.BlockHeight,
+					bundle.Index,
+				)
+			}
+
+			expectedProof, _, err := BuildMerkleProof(
+				test.blockTxIDs,
+				test.expectedIndex,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expectedProof, bundle.MerkleProof) {
+				t.Errorf("unexpected merkle proof")
+			}
+		})
+	}
+}
+
+func TestCache_Reorg(t *testing.T) {
+	ctx := context.Background()
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	txIDs := syntheticTxIDs(3)
+	merkleRoot, err := ComputeMerkleRoot(txIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := &btc.Header{
+		Height:     1,
+		Hash:       btc.Digest{0xb1},
+		MerkleRoot: merkleRoot,
+	}
+	btcChain.SetHeaders([]*btc.Header{header})
+	btcChain.SetBlockTxIDs(header.Hash, txIDs)
+
+	cache := NewCache(btcChain, 1)
+	if err := cache.pregenerate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	reorgedTxIDs := syntheticTxIDs(2)
+	reorgedRoot, err := ComputeMerkleRoot(reorgedTxIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reorgedHeader := &btc.Header{
+		Height:     1,
+		Hash:       btc.Digest{0xb2},
+		MerkleRoot: reorgedRoot,
+	}
+	btcChain.SetHeaders([]*btc.Header{reorgedHeader})
+	btcChain.SetBlockTxIDs(reorgedHeader.Hash, reorgedTxIDs)
+
+	if err := cache.pregenerate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.cachedProof(txIDs[2]); ok {
+		t.Errorf("transaction from a reorged block should not be cached")
+	}
+
+	bundle, ok := cache.cachedProof(reorgedTxIDs[1])
+	if !ok {
+		t.Fatal("transaction from the new block should be cached")
+	}
+
+	if bundle.BlockDigest != reorgedHeader.Hash {
+		t.Errorf("unexpected block digest: [%v]", bundle.BlockDigest)
+	}
+}
@@ -0,0 +1,145 @@
+package proof
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// merkle.go file contains the logic of building and verifying merkle proofs
+// of transaction inclusion. All digests are in the internal (little-endian)
+// byte order, the same as used by btc.Digest and the bitcoin-spv Solidity
+// library.
+
+// digestLength is the length of a single merkle tree node.
+const digestLength = 32
+
+// VerifyMerkleProof checks whether the transaction with the given ID is
+// included in the block with the given merkle root. The proof is a
+// tightly-packed list of intermediate merkle tree nodes, from the leaf level
+// up to, but excluding, the root. The index is the position of the
+// transaction in the block. This is the same format which is accepted by the
+// tBTC Deposit contract.
+func VerifyMerkleProof(
+	txID btc.Digest,
+	proof []byte,
+	index uint64,
+	merkleRoot btc.Digest,
+) error {
+	if len(proof)%digestLength != 0 {
+		return fmt.Errorf(
+			"proof length [%v] is not a multiple of [%v]",
+			len(proof),
+			digestLength,
+		)
+	}
+
+	depth := len(proof) / digestLength
+
+	// A block with a single transaction has an empty proof and its merkle
+	// root is equal to the transaction ID.
+	if depth == 0 {
+		if index != 0 {
+			return fmt.Errorf("index must be zero for an empty proof")
+		}
+		if txID != merkleRoot {
+			return fmt.Errorf("transaction ID does not match merkle root")
+		}
+		return nil
+	}
+
+	if depth < 64 && index >= uint64(1)<<uint(depth) {
+		return fmt.Errorf(
+			"index [%v] is out of range for proof of depth [%v]",
+			index,
+			depth,
+		)
+	}
+
+	current := txID
+	position := index
+
+	for i := 0; i < depth; i++ {
+		var sibling btc.Digest
+		copy(sibling[:], proof[i*digestLength:(i+1)*digestLength])
+
+		if position%2 == 1 {
+			current = hashNodes(sibling, current)
+		} else {
+			current = hashNodes(current, sibling)
+		}
+
+		position /= 2
+	}
+
+	if current != merkleRoot {
+		return fmt.Errorf(
+			"computed merkle root [%v] does not match expected [%v]",
+			current,
+			merkleRoot,
+		)
+	}
+
+	return nil
+}
+
+// BuildMerkleProof builds the merkle proof of inclusion of the transaction at
+// the given index in a block containing transactions with the given IDs.
+// It returns the proof in the format accepted by VerifyMerkleProof and the
+// merkle root of the block.
+func BuildMerkleProof(
+	txIDs []btc.Digest,
+	index uint64,
+) ([]byte, btc.Digest, error) {
+	if len(txIDs) == 0 {
+		return nil, btc.Digest{}, fmt.Errorf("no transaction IDs given")
+	}
+
+	if index >= uint64(len(txIDs)) {
+		return nil, btc.Digest{}, fmt.Errorf(
+			"index [%v] is out of range for [%v] transactions",
+			index,
+			len(txIDs),
+		)
+	}
+
+	proof := make([]byte, 0)
+	level := append([]btc.Digest{}, txIDs...)
+	position := index
+
+	for len(level) > 1 {
+		// Bitcoin duplicates the last node of levels with odd length.
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		sibling := level[position^1]
+		proof = append(proof, sibling[:]...)
+
+		nextLevel := make([]btc.Digest, len(level)/2)
+		for i := range nextLevel {
+			nextLevel[i] = hashNodes(level[2*i], level[2*i+1])
+		}
+
+		level = nextLevel
+		position /= 2
+	}
+
+	return proof, level[0], nil
+}
+
+// ComputeMerkleRoot computes the merkle root of a block containing
+// transactions with the given IDs.
+func ComputeMerkleRoot(txIDs []btc.Digest) (btc.Digest, error) {
+	_, root, err := BuildMerkleProof(txIDs, 0)
+	return root, err
+}
+
+func hashNodes(left, right btc.Digest) btc.Digest {
+	concatenated := make([]byte, 0, 2*digestLength)
+	concatenated = append(concatenated, left[:]...)
+	concatenated = append(concatenated, right[:]...)
+
+	return btc.Digest(chainhash.DoubleHashH(concatenated))
+}
@@ -0,0 +1,215 @@
+package proof
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// Transactions and merkle root of the Bitcoin mainnet block 100000, in the
+// display (big-endian) byte order.
+var block100000 = struct {
+	txIDs      []string
+	merkleRoot string
+}{
+	txIDs: []string{
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	},
+	merkleRoot: "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+}
+
+func TestBuildAndVerifyMerkleProof_Mainnet(t *testing.T) {
+	txIDs := make([]btc.Digest, len(block100000.txIDs))
+	for i, txID := range block100000.txIDs {
+		txIDs[i] = fromDisplayHex(t, txID)
+	}
+	merkleRoot := fromDisplayHex(t, block100000.merkleRoot)
+
+	computedRoot, err := ComputeMerkleRoot(txIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if computedRoot != merkleRoot {
+		t.Fatalf(
+			"unexpected merkle root:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			merkleRoot,
+			computedRoot,
+		)
+	}
+
+	for index, txID := range txIDs {
+		proof, _, err := BuildMerkleProof(txIDs, uint64(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := VerifyMerkleProof(
+			txID,
+			proof,
+			uint64(index),
+			merkleRoot,
+		); err != nil {
+			t.Errorf("proof for index [%v] is invalid: [%v]", index, err)
+		}
+	}
+}
+
+func TestBuildAndVerifyMerkleProof_AllTreeSizes(t *testing.T) {
+	for size := 1; size <= 33; size++ {
+		txIDs := syntheticTxIDs(size)
+
+		root, err := ComputeMerkleRoot(txIDs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for index := range txIDs {
+			proof, proofRoot, err := BuildMerkleProof(txIDs, uint64(index))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proofRoot != root {
+				t.Fatalf("size [%v]: inconsistent merkle root", size)
+			}
+
+			if err := VerifyMerkleProof(
+				txIDs[index],
+				proof,
+				uint64(index),
+				root,
+			); err != nil {
+				t.Errorf(
+					"size [%v]: proof for index [%v] is invalid: [%v]",
+					size,
+					index,
+					err,
+				)
+			}
+		}
+	}
+}
+
+func TestVerifyMerkleProof_Invalid(t *testing.T) {
+	txIDs := syntheticTxIDs(7)
+
+	proof, root, err := BuildMerkleProof(txIDs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamperedProof := append([]byte{}, proof...)
+	tamperedProof[5] ^= 0x01
+
+	var tests = map[string]struct {
+		txID  btc.Digest
+		proof []byte
+		index uint64
+		root  btc.Digest
+	}{
+		"wrong transaction": {
+			txID:  txIDs[3],
+			proof: proof,
+			index: 2,
+			root:  root,
+		},
+		"wrong index": {
+			txID:  txIDs[2],
+			proof: proof,
+			index: 3,
+			root:  root,
+		},
+		"index out of range": {
+			txID:  txIDs[2],
+			proof: proof,
+			index: 8,
+			root:  root,
+		},
+		"tampered proof": {
+			txID:  txIDs[2],
+			proof: tamperedProof,
+			index: 2,
+			root:  root,
+		},
+		"truncated proof": {
+			txID:  txIDs[2],
+			proof: proof[:len(proof)-1],
+			index: 2,
+			root:  root,
+		},
+		"wrong root": {
+			txID:  txIDs[2],
+			proof: proof,
+			index: 2,
+			root:  txIDs[0],
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := VerifyMerkleProof(test.txID, test.proof, test.index, test.root)
+			if err == nil {
+				t.Error("expected proof verification error")
+			}
+		})
+	}
+}
+
+func TestVerifyMerkleProof_SingleTransaction(t *testing.T) {
+	txIDs := syntheticTxIDs(1)
+
+	if err := VerifyMerkleProof(txIDs[0], []byte{}, 0, txIDs[0]); err != nil {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+
+	if err := VerifyMerkleProof(txIDs[0], []byte{}, 1, txIDs[0]); err == nil {
+		t.Error("expected error for non-zero index")
+	}
+}
+
+// Number of transactions of a full block, used by the proof benchmarks.
+const benchBlockTransactions = 3000
+
+func BenchmarkBuildMerkleProof(b *testing.B) {
+	txIDs := syntheticTxIDs(benchBlockTransactions)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		index := uint64(i % len(txIDs))
+		if _, _, err := BuildMerkleProof(txIDs, index); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func syntheticTxIDs(count int) []btc.Digest {
+	txIDs := make([]btc.Digest, count)
+	for i := range txIDs {
+		binary.LittleEndian.PutUint32(txIDs[i][:], uint32(i+1))
+		txIDs[i][31] = 0xff
+	}
+	return txIDs
+}
+
+func fromDisplayHex(t *testing.T, value string) btc.Digest {
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var digest btc.Digest
+	for i := range decoded {
+		digest[i] = decoded[len(decoded)-1-i]
+	}
+
+	return digest
+}
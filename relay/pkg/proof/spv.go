@@ -0,0 +1,208 @@
+package proof
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// spv.go file contains the SPV proofs of Bitcoin transactions in the format
+// accepted by the tBTC Deposit contract. Besides the merkle proof, the
+// contract needs the transaction split into its version, inputs, outputs and
+// lock time, and the headers of the block including the transaction and of
+// the blocks confirming it, so the accumulated work can be checked against
+// the difficulty known by the relay.
+
+// SPVProof is the proof of a confirmed Bitcoin transaction.
+type SPVProof struct {
+	// TxID is the ID of the proven transaction.
+	TxID btc.Digest
+	// TxVersion is the 4-byte little-endian transaction version.
+	TxVersion []byte
+	// TxInputVector is the number of inputs, as a compact size integer,
+	// followed by the serialized inputs without witness data.
+	TxInputVector []byte
+	// TxOutputVector is the number of outputs, as a compact size integer,
+	// followed by the serialized outputs.
+	TxOutputVector []byte
+	// TxLocktime is the 4-byte little-endian transaction lock time.
+	TxLocktime []byte
+	// MerkleProof is the merkle proof in the format accepted by
+	// VerifyMerkleProof.
+	MerkleProof []byte
+	// TxIndexInBlock is the position of the transaction in the block.
+	TxIndexInBlock uint64
+	// BlockHeight is the height of the block including the transaction.
+	BlockHeight int64
+	// BitcoinHeaders are the tightly-packed 80-byte headers of the block
+	// including the transaction and of the blocks confirming it.
+	BitcoinHeaders []byte
+}
+
+// BuildSPVProof builds the SPV proof of the transaction included in a block
+// according to the given bundle. The proof contains the given number of
+// headers, starting from the header of the block including the transaction.
+// An error is returned if there are not enough headers yet or if the block
+// is no longer in the longest chain.
+func BuildSPVProof(
+	ctx context.Context,
+	btcChain btc.Handle,
+	bundle *Bundle,
+	headersCount int,
+) (*SPVProof, error) {
+	if headersCount < 1 {
+		return nil, fmt.Errorf("at least one header is required")
+	}
+
+	transaction, err := btcChain.GetTransaction(ctx, bundle.TxID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction: [%v]", err)
+	}
+
+	headers, err := confirmingHeaders(ctx, btcChain, bundle, headersCount)
+	if err != nil {
+		return nil, err
+	}
+
+	txVersion := make([]byte, 4)
+	binary.LittleEndian.PutUint32(txVersion, uint32(transaction.Version))
+
+	txLocktime := make([]byte, 4)
+	binary.LittleEndian.PutUint32(txLocktime, transaction.Locktime)
+
+	inputVector, err := serializeInputVector(transaction.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	outputVector, err := serializeOutputVector(transaction.Outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SPVProof{
+		TxID:           bundle.TxID,
+		TxVersion:      txVersion,
+		TxInputVector:  inputVector,
+		TxOutputVector: outputVector,
+		TxLocktime:     txLocktime,
+		MerkleProof:    bundle.MerkleProof,
+		TxIndexInBlock: bundle.Index,
+		BlockHeight:    bundle.BlockHeight,
+		BitcoinHeaders: headers,
+	}, nil
+}
+
+// confirmingHeaders returns the tightly-packed headers of the block with the
+// bundled transaction and of the blocks built on top of it.
+func confirmingHeaders(
+	ctx context.Context,
+	btcChain btc.Handle,
+	bundle *Bundle,
+	headersCount int,
+) ([]byte, error) {
+	headers := make([]byte, 0, headersCount*80)
+
+	var previous *btc.Header
+	for i := 0; i < headersCount; i++ {
+		height := bundle.BlockHeight + int64(i)
+
+		header, err := btcChain.GetHeaderByHeight(ctx, height)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not get header at height [%v]: [%v]",
+				height,
+				err,
+			)
+		}
+
+		if previous == nil && header.Hash != bundle.BlockDigest {
+			return nil, fmt.Errorf(
+				"block at height [%v] is no longer in the longest chain",
+				height,
+			)
+		}
+
+		if previous != nil && header.PrevHash != previous.Hash {
+			return nil, fmt.Errorf(
+				"header at height [%v] does not build on the previous one",
+				height,
+			)
+		}
+
+		headers = append(headers, header.Raw...)
+		previous = header
+	}
+
+	return headers, nil
+}
+
+func serializeInputVector(inputs []*btc.TransactionInput) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if err := wire.WriteVarInt(&buffer, 0, uint64(len(inputs))); err != nil {
+		return nil, err
+	}
+
+	for _, input := range inputs {
+		buffer.Write(input.PrevTxID[:])
+
+		if err := binary.Write(
+			&buffer,
+			binary.LittleEndian,
+			input.PrevOutputIndex,
+		); err != nil {
+			return nil, err
+		}
+
+		if err := wire.WriteVarBytes(
+			&buffer,
+			0,
+			input.SignatureScript,
+		); err != nil {
+			return nil, err
+		}
+
+		if err := binary.Write(
+			&buffer,
+			binary.LittleEndian,
+			input.Sequence,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func serializeOutputVector(outputs []*btc.TransactionOutput) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if err := wire.WriteVarInt(&buffer, 0, uint64(len(outputs))); err != nil {
+		return nil, err
+	}
+
+	for _, output := range outputs {
+		if err := binary.Write(
+			&buffer,
+			binary.LittleEndian,
+			output.Value,
+		); err != nil {
+			return nil, err
+		}
+
+		if err := wire.WriteVarBytes(
+			&buffer,
+			0,
+			output.PublicKeyScript,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,165 @@
+package proof
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestBuildSPVProof(t *testing.T) {
+	ctx := context.Background()
+
+	msgTx := wire.NewMsgTx(2)
+	msgTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 3},
+		SignatureScript:  []byte{0xaa, 0xbb},
+		Witness:          wire.TxWitness{[]byte{0xcc}},
+		Sequence:         0xfffffffd,
+	})
+	msgTx.AddTxOut(&wire.TxOut{Value: 100000, PkScript: []byte{0x00, 0x14}})
+	msgTx.AddTxOut(&wire.TxOut{Value: 2500, PkScript: []byte{0x51}})
+	msgTx.LockTime = 650000
+
+	var raw bytes.Buffer
+	if err := msgTx.Serialize(&raw); err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := btc.ParseTransaction(raw.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := localChainWithHeaders(t, 4)
+	blockHash := btc.Digest{0xb0, 2}
+	transaction.BlockHash = &blockHash
+	transaction.Confirmations = 3
+	btcChain.SetTransactions([]*btc.Transaction{transaction})
+
+	bundle := &Bundle{
+		TxID:        transaction.TxID,
+		BlockDigest: blockHash,
+		BlockHeight: 2,
+		Index:       1,
+		MerkleProof: []byte{0x01},
+	}
+
+	spvProof, err := BuildSPVProof(ctx, btcChain, bundle, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var noWitness bytes.Buffer
+	if err := msgTx.SerializeNoWitness(&noWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	serialized := make([]byte, 0)
+	serialized = append(serialized, spvProof.TxVersion...)
+	serialized = append(serialized, spvProof.TxInputVector...)
+	serialized = append(serialized, spvProof.TxOutputVector...)
+	serialized = append(serialized, spvProof.TxLocktime...)
+
+	if !bytes.Equal(noWitness.Bytes(), serialized) {
+		t.Errorf(
+			"unexpected serialized transaction:\n"+
+				"expected: [%x]\n"+
+				"actual:   [%x]\n",
+			noWitness.Bytes(),
+			serialized,
+		)
+	}
+
+	expectedHeaders := []byte{2, 3, 4}
+	if len(spvProof.BitcoinHeaders) != len(expectedHeaders)*80 {
+		t.Fatalf(
+			"unexpected headers length: [%v]",
+			len(spvProof.BitcoinHeaders),
+		)
+	}
+	for i, height := range expectedHeaders {
+		if spvProof.BitcoinHeaders[i*80] != height {
+			t.Errorf(
+				"unexpected header at position [%v]:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				i,
+				height,
+				spvProof.BitcoinHeaders[i*80],
+			)
+		}
+	}
+
+	if spvProof.TxIndexInBlock != 1 {
+		t.Errorf("unexpected index: [%v]", spvProof.TxIndexInBlock)
+	}
+}
+
+func TestBuildSPVProof_NotEnoughHeaders(t *testing.T) {
+	btcChain := localChainWithHeaders(t, 3)
+	blockHash := btc.Digest{0xb0, 2}
+	txID := btc.Digest{0x11}
+	btcChain.SetTransactions([]*btc.Transaction{
+		{TxID: txID, BlockHash: &blockHash, Confirmations: 2},
+	})
+
+	_, err := BuildSPVProof(
+		context.Background(),
+		btcChain,
+		&Bundle{TxID: txID, BlockDigest: blockHash, BlockHeight: 2},
+		3,
+	)
+	if err == nil || !strings.Contains(err.Error(), "height [4]") {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+}
+
+func TestBuildSPVProof_Reorganized(t *testing.T) {
+	btcChain := localChainWithHeaders(t, 3)
+	staleHash := btc.Digest{0xee}
+	txID := btc.Digest{0x11}
+	btcChain.SetTransactions([]*btc.Transaction{
+		{TxID: txID, BlockHash: &staleHash, Confirmations: 2},
+	})
+
+	_, err := BuildSPVProof(
+		context.Background(),
+		btcChain,
+		&Bundle{TxID: txID, BlockDigest: staleHash, BlockHeight: 2},
+		2,
+	)
+	if err == nil || !strings.Contains(err.Error(), "longest chain") {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+}
+
+// localChainWithHeaders returns a local chain with linked headers from
+// height 1 up to the given one. The first byte of each raw header is its
+// height.
+func localChainWithHeaders(t *testing.T, tipHeight int64) *btc.LocalChain {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+
+	for height := int64(1); height <= tipHeight; height++ {
+		raw := make([]byte, 80)
+		raw[0] = byte(height)
+
+		btcChain.AppendHeader(&btc.Header{
+			Height:   height,
+			Hash:     btc.Digest{0xb0, byte(height)},
+			PrevHash: btc.Digest{0xb0, byte(height - 1)},
+			Raw:      raw,
+		})
+	}
+
+	return btcChain
+}
@@ -0,0 +1,179 @@
+package proof
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+// tree.go file contains the full merkle tree of a block. The tree is built
+// once from IDs of all transactions in the block so inclusion proofs of any
+// number of transactions are derived locally without further calls to the
+// Bitcoin node.
+
+// MerkleTree is the merkle tree of transactions included in a block.
+type MerkleTree struct {
+	// levels holds all levels of the tree, from the leaves up to the root.
+	// Levels of odd length, except the root one, have their last node
+	// duplicated, as Bitcoin does.
+	levels [][]btc.Digest
+	// indexes maps transaction IDs to their positions in the block.
+	indexes map[btc.Digest]uint64
+	// size is the number of transactions in the block.
+	size int
+}
+
+// NewMerkleTree builds the merkle tree of a block containing transactions
+// with the given IDs, in the order of the block.
+func NewMerkleTree(txIDs []btc.Digest) (*MerkleTree, error) {
+	if len(txIDs) == 0 {
+		return nil, fmt.Errorf("no transaction IDs given")
+	}
+
+	indexes := make(map[btc.Digest]uint64, len(txIDs))
+	for i, txID := range txIDs {
+		if _, ok := indexes[txID]; !ok {
+			indexes[txID] = uint64(i)
+		}
+	}
+
+	level := append([]btc.Digest{}, txIDs...)
+	levels := make([][]btc.Digest, 0)
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		levels = append(levels, level)
+
+		nextLevel := make([]btc.Digest, len(level)/2)
+		for i := range nextLevel {
+			nextLevel[i] = hashNodes(level[2*i], level[2*i+1])
+		}
+
+		level = nextLevel
+	}
+
+	levels = append(levels, level)
+
+	return &MerkleTree{
+		levels:  levels,
+		indexes: indexes,
+		size:    len(txIDs),
+	}, nil
+}
+
+// FetchMerkleTree builds the merkle tree of the block with the given digest
+// using IDs of its transactions retrieved from the Bitcoin chain. The tree
+// root is checked against the merkle root of the block header.
+func FetchMerkleTree(
+	ctx context.Context,
+	btcChain btc.Handle,
+	blockDigest btc.Digest,
+) (*MerkleTree, error) {
+	header, err := btcChain.GetHeaderByDigest(ctx, blockDigest)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block header: [%v]", err)
+	}
+
+	return fetchBlockMerkleTree(ctx, btcChain, header)
+}
+
+// fetchBlockMerkleTree builds the merkle tree of the block with the given
+// header, checking the tree root against the merkle root of the header.
+func fetchBlockMerkleTree(
+	ctx context.Context,
+	btcChain btc.Handle,
+	header *btc.Header,
+) (*MerkleTree, error) {
+	txIDs, err := btcChain.GetBlockTxIDs(ctx, header.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block transactions: [%v]", err)
+	}
+
+	return verifiedMerkleTree(txIDs, header)
+}
+
+// verifiedMerkleTree builds the merkle tree of the given transaction IDs,
+// checking the tree root against the merkle root of the given header.
+func verifiedMerkleTree(
+	txIDs []btc.Digest,
+	header *btc.Header,
+) (*MerkleTree, error) {
+	tree, err := NewMerkleTree(txIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if tree.Root() != header.MerkleRoot {
+		return nil, fmt.Errorf(
+			"computed merkle root [%v] does not match block header [%v]",
+			tree.Root(),
+			header.MerkleRoot,
+		)
+	}
+
+	return tree, nil
+}
+
+// Root returns the merkle root of the tree.
+func (mt *MerkleTree) Root() btc.Digest {
+	return mt.levels[len(mt.levels)-1][0]
+}
+
+// Size returns the number of transactions in the tree.
+func (mt *MerkleTree) Size() int {
+	return mt.size
+}
+
+// IndexOf returns the position of the transaction with the given ID in the
+// block. The second return value is false if the transaction is not
+// included in the block.
+func (mt *MerkleTree) IndexOf(txID btc.Digest) (uint64, bool) {
+	index, ok := mt.indexes[txID]
+	return index, ok
+}
+
+// Proof returns the merkle proof of inclusion of the transaction at the
+// given index, in the format accepted by VerifyMerkleProof.
+func (mt *MerkleTree) Proof(index uint64) ([]byte, error) {
+	if index >= uint64(mt.size) {
+		return nil, fmt.Errorf(
+			"index [%v] is out of range for [%v] transactions",
+			index,
+			mt.size,
+		)
+	}
+
+	proof := make([]byte, 0, (len(mt.levels)-1)*digestLength)
+	position := index
+
+	for _, level := range mt.levels[:len(mt.levels)-1] {
+		sibling := level[position^1]
+		proof = append(proof, sibling[:]...)
+		position /= 2
+	}
+
+	return proof, nil
+}
+
+// TransactionProof returns the merkle proof of inclusion of the transaction
+// with the given ID along with its position in the block.
+func (mt *MerkleTree) TransactionProof(txID btc.Digest) ([]byte, uint64, error) {
+	index, ok := mt.IndexOf(txID)
+	if !ok {
+		return nil, 0, fmt.Errorf(
+			"transaction [%v] is not included in the block",
+			txID,
+		)
+	}
+
+	proof, err := mt.Proof(index)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return proof, index, nil
+}
@@ -0,0 +1,144 @@
+package proof
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+func TestMerkleTree_MatchesBuildMerkleProof(t *testing.T) {
+	for size := 1; size <= 33; size++ {
+		txIDs := syntheticTxIDs(size)
+
+		tree, err := NewMerkleTree(txIDs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for index, txID := range txIDs {
+			expectedProof, expectedRoot, err := BuildMerkleProof(
+				txIDs,
+				uint64(index),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tree.Root() != expectedRoot {
+				t.Fatalf("size [%v]: unexpected merkle root", size)
+			}
+
+			proof, proofIndex, err := tree.TransactionProof(txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proofIndex != uint64(index) {
+				t.Errorf(
+					"size [%v]: unexpected index:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					size,
+					index,
+					proofIndex,
+				)
+			}
+
+			if !bytes.Equal(proof, expectedProof) {
+				t.Errorf(
+					"size [%v]: unexpected proof for index [%v]",
+					size,
+					index,
+				)
+			}
+		}
+
+		if _, err := tree.Proof(uint64(size)); err == nil {
+			t.Errorf("size [%v]: expected error for index out of range", size)
+		}
+	}
+}
+
+func TestFetchMerkleTree(t *testing.T) {
+	ctx := context.Background()
+
+	txIDs := make([]btc.Digest, len(block100000.txIDs))
+	for i, txID := range block100000.txIDs {
+		txIDs[i] = fromDisplayHex(t, txID)
+	}
+
+	blockDigest := btc.Digest{0x01}
+
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcChain := bc.(*btc.LocalChain)
+	btcChain.SetBlockTxIDs(blockDigest, txIDs)
+
+	var tests = map[string]struct {
+		merkleRoot    btc.Digest
+		expectedError bool
+	}{
+		"matching merkle root": {
+			merkleRoot: fromDisplayHex(t, block100000.merkleRoot),
+		},
+		"mismatching merkle root": {
+			merkleRoot:    btc.Digest{0x02},
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			btcChain.SetHeaders([]*btc.Header{
+				{Hash: blockDigest, MerkleRoot: test.merkleRoot},
+			})
+
+			tree, err := FetchMerkleTree(ctx, btcChain, blockDigest)
+			if test.expectedError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tree.Size() != len(txIDs) {
+				t.Errorf("unexpected tree size: [%v]", tree.Size())
+			}
+		})
+	}
+}
+
+func BenchmarkMerkleTree(b *testing.B) {
+	txIDs := syntheticTxIDs(benchBlockTransactions)
+
+	b.Run("build", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := NewMerkleTree(txIDs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("proof", func(b *testing.B) {
+		tree, err := NewMerkleTree(txIDs)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := tree.Proof(uint64(i % len(txIDs))); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
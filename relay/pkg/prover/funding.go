@@ -0,0 +1,276 @@
+package prover
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+)
+
+// funding.go file contains the service submitting funding proofs of the
+// configured deposits. Funding transactions are found by the deposit
+// monitor. Once a funding transaction has enough confirmations and the
+// header of its block is stored by the relay contract, the service builds
+// the SPV proof and submits it to the Deposit contract, so the depositor
+// does not have to.
+
+// fundedDeposit is a configured deposit along with its funding transaction.
+type fundedDeposit struct {
+	contract string
+	address  string
+
+	// fundingTxID is the ID of the transaction funding the deposit. It is
+	// nil until the funding transaction is seen by the deposit monitor.
+	fundingTxID *btc.Digest
+	// fundingOutputIndex is the index of the output paying the deposit.
+	fundingOutputIndex uint32
+	// done is set once the deposit no longer waits for the funding proof.
+	done bool
+}
+
+// FundingService submits funding proofs of the configured deposits.
+type FundingService struct {
+	btcChain      btc.Handle
+	hostChain     chain.Handle
+	monitor       *deposit.Monitor
+	proofs        ProofSource
+	prioritizer   Prioritizer
+	confirmations int
+
+	mutex sync.Mutex
+	// deposits maps Bitcoin addresses to the configured deposits.
+	deposits map[string]*fundedDeposit
+}
+
+// NewFundingService creates the service submitting funding proofs of the
+// configured deposits. The deposit addresses are watched by the given
+// deposit monitor. Proofs of transaction inclusion are taken from the given
+// proof source or, if it is nil, built from blocks fetched from the Bitcoin
+// chain. Headers awaited by the proofs are prioritized by the given
+// prioritizer, unless it is nil.
+func NewFundingService(
+	btcChain btc.Handle,
+	hostChain chain.Handle,
+	monitor *deposit.Monitor,
+	proofs ProofSource,
+	prioritizer Prioritizer,
+	config *Config,
+) (*FundingService, error) {
+	service := &FundingService{
+		btcChain:      btcChain,
+		hostChain:     hostChain,
+		monitor:       monitor,
+		proofs:        resolveProofSource(btcChain, proofs),
+		prioritizer:   prioritizer,
+		confirmations: config.confirmations(),
+		deposits:      make(map[string]*fundedDeposit),
+	}
+
+	for _, depositConfig := range config.Deposits {
+		if depositConfig.Contract == "" || depositConfig.Address == "" {
+			return nil, fmt.Errorf(
+				"both contract and address of a deposit must be configured",
+			)
+		}
+
+		if err := monitor.Watch(depositConfig.Address); err != nil {
+			return nil, err
+		}
+
+		service.deposits[depositConfig.Address] = &fundedDeposit{
+			contract: depositConfig.Contract,
+			address:  depositConfig.Address,
+		}
+	}
+
+	return service, nil
+}
+
+// Start starts handling the deposit monitor events and checking the funding
+// transactions in the given tick. It stops once the passed context is done.
+func (fs *FundingService) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		subscription := fs.monitor.Feed().Subscribe()
+		defer func() {
+			subscription.Unsubscribe()
+		}()
+
+		for {
+			select {
+			case event, ok := <-subscription.Events():
+				if !ok {
+					logger.Warnf(
+						"deposit feed subscription cancelled; " +
+							"subscribing again",
+					)
+					subscription = fs.monitor.Feed().Subscribe()
+					continue
+				}
+
+				fs.handleEvent(event)
+			case <-ticker.C:
+				fs.submitProofs(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// handleEvent records funding transactions of the configured deposits and
+// forgets the double-spent ones.
+func (fs *FundingService) handleEvent(event *deposit.Event) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	switch event.Type {
+	case deposit.EventSeenUnconfirmed, deposit.EventSeenConfirmed:
+		funded, ok := fs.deposits[event.Address]
+		if !ok || funded.done {
+			return
+		}
+
+		txID := event.TxID
+		funded.fundingTxID = &txID
+		funded.fundingOutputIndex = event.OutputIndex
+
+		logger.Infof(
+			"seen transaction [%v] funding deposit [%v]",
+			chainhash.Hash(txID),
+			funded.contract,
+		)
+	case deposit.EventDoubleSpent:
+		for _, funded := range fs.deposits {
+			if funded.fundingTxID != nil && *funded.fundingTxID == event.TxID {
+				logger.Warnf(
+					"transaction funding deposit [%v] has been double-spent",
+					funded.contract,
+				)
+				funded.fundingTxID = nil
+				deprioritizeProof(fs.prioritizer, fundingProofKey(funded))
+			}
+		}
+	}
+}
+
+// submitProofs submits funding proofs of all deposits whose funding
+// transactions can be proven.
+func (fs *FundingService) submitProofs(ctx context.Context) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	for _, funded := range fs.deposits {
+		if funded.done || funded.fundingTxID == nil {
+			continue
+		}
+
+		if err := fs.submitProof(ctx, funded); err != nil {
+			logger.Warnf(
+				"could not submit funding proof of deposit [%v]: [%v]",
+				funded.contract,
+				err,
+			)
+		}
+
+		if funded.done {
+			deprioritizeProof(fs.prioritizer, fundingProofKey(funded))
+		}
+	}
+}
+
+// submitProof submits the funding proof of the given deposit if its funding
+// transaction has enough confirmations and its block is known by the relay
+// contract. Must be called with the mutex held.
+func (fs *FundingService) submitProof(
+	ctx context.Context,
+	funded *fundedDeposit,
+) error {
+	state, err := fs.hostChain.GetDepositState(ctx, funded.contract)
+	if err != nil {
+		return fmt.Errorf("could not get deposit state: [%v]", err)
+	}
+
+	if state < chain.DepositAwaitingFundingProof {
+		return nil
+	}
+
+	if state != chain.DepositAwaitingFundingProof {
+		logger.Infof(
+			"deposit [%v] no longer awaits funding proof",
+			funded.contract,
+		)
+		funded.done = true
+		return nil
+	}
+
+	if funded.fundingOutputIndex > math.MaxUint8 {
+		funded.done = true
+		return fmt.Errorf(
+			"funding output index [%v] cannot be proven",
+			funded.fundingOutputIndex,
+		)
+	}
+
+	transaction, err := fs.btcChain.GetTransaction(ctx, *funded.fundingTxID)
+	if err != nil {
+		return fmt.Errorf("could not get funding transaction: [%v]", err)
+	}
+
+	prioritizeProof(
+		ctx,
+		fs.btcChain,
+		fs.prioritizer,
+		fundingProofKey(funded),
+		transaction,
+		fs.confirmations,
+	)
+
+	transactionProof, err := buildProof(
+		ctx,
+		fs.btcChain,
+		fs.hostChain,
+		fs.proofs,
+		transaction,
+		fs.confirmations,
+	)
+	if err != nil || transactionProof == nil {
+		return err
+	}
+
+	if err := fs.hostChain.ProvideFundingProof(
+		ctx,
+		funded.contract,
+		transactionProof,
+		uint8(funded.fundingOutputIndex),
+	); err != nil {
+		return err
+	}
+
+	logger.Infof(
+		"submitted funding proof of deposit [%v] for transaction [%v]",
+		funded.contract,
+		chainhash.Hash(transaction.TxID),
+	)
+	funded.done = true
+
+	return nil
+}
+
+// fundingProofKey returns the key identifying the funding proof of the given
+// deposit among the proofs awaiting headers.
+func fundingProofKey(funded *fundedDeposit) string {
+	return "funding:" + funded.contract
+}
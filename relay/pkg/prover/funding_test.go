@@ -0,0 +1,331 @@
+package prover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+	"github.com/keep-network/tbtc/relay/pkg/proof"
+)
+
+const (
+	// P2WPKH address on the regtest network.
+	depositAddress  = "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"
+	depositContract = "0x1111111111111111111111111111111111111111"
+)
+
+func TestFundingService_SubmitProofs(t *testing.T) {
+	var tests = map[string]struct {
+		confirmations       uint64
+		relayed             bool
+		state               chain.DepositState
+		expectedSubmissions int
+	}{
+		"transaction confirmed and block relayed": {
+			confirmations:       6,
+			relayed:             true,
+			state:               chain.DepositAwaitingFundingProof,
+			expectedSubmissions: 1,
+		},
+		"not enough confirmations": {
+			confirmations:       5,
+			relayed:             true,
+			state:               chain.DepositAwaitingFundingProof,
+			expectedSubmissions: 0,
+		},
+		"block not relayed": {
+			confirmations:       6,
+			relayed:             false,
+			state:               chain.DepositAwaitingFundingProof,
+			expectedSubmissions: 0,
+		},
+		"deposit not awaiting funding yet": {
+			confirmations:       6,
+			relayed:             true,
+			state:               chain.DepositState(1),
+			expectedSubmissions: 0,
+		},
+		"deposit already proven": {
+			confirmations:       6,
+			relayed:             true,
+			state:               chain.DepositActive,
+			expectedSubmissions: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := context.Background()
+
+			btcChain, hostChain, fundingTx := fundedChains(t, test.confirmations)
+
+			if test.relayed {
+				hostChain.SetHeaderHeight(*fundingTx.BlockHash, 2)
+			}
+			hostChain.SetDepositState(depositContract, test.state)
+
+			service := newTestFundingService(t, btcChain, hostChain)
+
+			service.handleEvent(&deposit.Event{
+				Type:        deposit.EventSeenUnconfirmed,
+				Address:     depositAddress,
+				TxID:        fundingTx.TxID,
+				OutputIndex: 1,
+			})
+
+			service.submitProofs(ctx)
+
+			events := hostChain.FundingProofEvents()
+			if len(events) != test.expectedSubmissions {
+				t.Fatalf(
+					"unexpected number of submissions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedSubmissions,
+					len(events),
+				)
+			}
+
+			if test.expectedSubmissions == 0 {
+				return
+			}
+
+			if events[0].DepositAddress != depositContract {
+				t.Errorf(
+					"unexpected deposit: [%v]",
+					events[0].DepositAddress,
+				)
+			}
+			if events[0].FundingOutputIndex != 1 {
+				t.Errorf(
+					"unexpected funding output index: [%v]",
+					events[0].FundingOutputIndex,
+				)
+			}
+			if len(events[0].Proof.BitcoinHeaders) != 6*80 {
+				t.Errorf(
+					"unexpected headers length: [%v]",
+					len(events[0].Proof.BitcoinHeaders),
+				)
+			}
+			if events[0].Proof.TxIndexInBlock != 1 {
+				t.Errorf(
+					"unexpected index in block: [%v]",
+					events[0].Proof.TxIndexInBlock,
+				)
+			}
+
+			// Proven deposits are not submitted again.
+			service.submitProofs(ctx)
+			if len(hostChain.FundingProofEvents()) != 1 {
+				t.Errorf("funding proof submitted again")
+			}
+		})
+	}
+}
+
+func TestFundingService_DoubleSpent(t *testing.T) {
+	btcChain, hostChain, fundingTx := fundedChains(t, 6)
+	hostChain.SetHeaderHeight(*fundingTx.BlockHash, 2)
+	hostChain.SetDepositState(depositContract, chain.DepositAwaitingFundingProof)
+
+	service := newTestFundingService(t, btcChain, hostChain)
+
+	service.handleEvent(&deposit.Event{
+		Type:        deposit.EventSeenUnconfirmed,
+		Address:     depositAddress,
+		TxID:        fundingTx.TxID,
+		OutputIndex: 1,
+	})
+	service.handleEvent(&deposit.Event{
+		Type: deposit.EventDoubleSpent,
+		TxID: fundingTx.TxID,
+	})
+
+	service.submitProofs(context.Background())
+
+	if len(hostChain.FundingProofEvents()) != 0 {
+		t.Errorf("funding proof of double-spent transaction submitted")
+	}
+}
+
+func TestFundingService_PrioritizesAwaitedHeaders(t *testing.T) {
+	ctx := context.Background()
+
+	btcChain, hostChain, fundingTx := fundedChains(t, 6)
+	hostChain.SetDepositState(depositContract, chain.DepositAwaitingFundingProof)
+
+	prioritizer := &mockPrioritizer{priorities: make(map[string]int64)}
+
+	service := newTestFundingService(t, btcChain, hostChain)
+	service.prioritizer = prioritizer
+
+	service.handleEvent(&deposit.Event{
+		Type:        deposit.EventSeenUnconfirmed,
+		Address:     depositAddress,
+		TxID:        fundingTx.TxID,
+		OutputIndex: 1,
+	})
+
+	// The block at height 2 is not relayed yet, so the headers from it up
+	// to the one providing six confirmations are awaited.
+	service.submitProofs(ctx)
+
+	key := "funding:" + depositContract
+	if height := prioritizer.priorities[key]; height != 7 {
+		t.Errorf(
+			"unexpected prioritized height:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			7,
+			height,
+		)
+	}
+
+	hostChain.SetHeaderHeight(*fundingTx.BlockHash, 2)
+
+	service.submitProofs(ctx)
+
+	if len(hostChain.FundingProofEvents()) != 1 {
+		t.Fatalf("funding proof not submitted")
+	}
+
+	if _, ok := prioritizer.priorities[key]; ok {
+		t.Errorf("headers still prioritized after proof submission")
+	}
+}
+
+type mockPrioritizer struct {
+	priorities map[string]int64
+}
+
+func (mp *mockPrioritizer) Prioritize(key string, height int64) {
+	mp.priorities[key] = height
+}
+
+func (mp *mockPrioritizer) Deprioritize(key string) {
+	delete(mp.priorities, key)
+}
+
+func newTestFundingService(
+	t *testing.T,
+	btcChain *btc.LocalChain,
+	hostChain chain.Handle,
+) *FundingService {
+	monitor, err := deposit.NewMonitor(btcChain, &deposit.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := NewFundingService(
+		btcChain,
+		hostChain,
+		monitor,
+		nil,
+		nil,
+		&Config{
+			Deposits: []DepositConfig{
+				{Contract: depositContract, Address: depositAddress},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return service
+}
+
+// fundedChains returns local chains with the deposit funding transaction
+// included in the block at height 2, at the second position.
+func fundedChains(
+	t *testing.T,
+	confirmations uint64,
+) (*btc.LocalChain, *chainlocal.Chain, *btc.Transaction) {
+	fundingTx := &btc.Transaction{
+		TxID:    btc.Digest{0x0f},
+		Version: 1,
+		Inputs: []*btc.TransactionInput{
+			{PrevTxID: btc.Digest{0x01}, Sequence: 0xffffffff},
+		},
+		Outputs: []*btc.TransactionOutput{
+			{Value: 10, PublicKeyScript: []byte{0x6a}},
+			{Value: 100000, PublicKeyScript: outputScript(t, depositAddress)},
+		},
+	}
+
+	btcChain, hostChain := chainsIncluding(t, fundingTx, confirmations)
+
+	return btcChain, hostChain, fundingTx
+}
+
+// chainsIncluding returns local chains with the given transaction included
+// in the block at height 2, at the second position.
+func chainsIncluding(
+	t *testing.T,
+	transaction *btc.Transaction,
+	confirmations uint64,
+) (*btc.LocalChain, *chainlocal.Chain) {
+	bc, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	btcChain := bc.(*btc.LocalChain)
+
+	hc, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostChain := hc.(*chainlocal.Chain)
+
+	blockHash := btc.Digest{0xb0, 2}
+	transaction.BlockHash = &blockHash
+	transaction.Confirmations = confirmations
+	btcChain.SetTransactions([]*btc.Transaction{transaction})
+
+	blockTxIDs := []btc.Digest{{0x0c}, transaction.TxID, {0x0d}}
+	merkleRoot, err := proof.ComputeMerkleRoot(blockTxIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tipHeight := int64(1 + confirmations)
+	for height := int64(1); height <= tipHeight; height++ {
+		header := &btc.Header{
+			Height:   height,
+			Hash:     btc.Digest{0xb0, byte(height)},
+			PrevHash: btc.Digest{0xb0, byte(height - 1)},
+			Raw:      make([]byte, 80),
+		}
+		if height == 2 {
+			header.MerkleRoot = merkleRoot
+		}
+		btcChain.AppendHeader(header)
+	}
+	btcChain.SetBlockTxIDs(blockHash, blockTxIDs)
+
+	return btcChain, hostChain
+}
+
+func outputScript(t *testing.T, address string) []byte {
+	decoded, err := btcutil.DecodeAddress(
+		address,
+		&chaincfg.RegressionNetParams,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := txscript.PayToAddrScript(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return script
+}
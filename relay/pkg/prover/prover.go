@@ -0,0 +1,240 @@
+package prover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/proof"
+)
+
+// prover.go file contains the configuration and common parts of the
+// services submitting SPV proofs of Bitcoin transactions to the tBTC
+// contracts on behalf of their users. The relay already knows the Bitcoin
+// chain and the headers stored by the relay contract, so it can submit the
+// proofs as soon as the contracts are able to accept them.
+
+var logger = log.Logger("tbtc-relay-prover")
+
+const (
+	// DefaultConfirmations is the default number of confirmations of
+	// a transaction, including the block the transaction is included in,
+	// required before its proof is submitted. It matches the proof
+	// difficulty factor of the tBTC system.
+	DefaultConfirmations = 6
+
+	// DefaultTick is the default interval in which the proven transactions
+	// are checked.
+	DefaultTick = 1 * time.Minute
+
+	// DefaultRedemptionLookback is the default number of the most recent
+	// host chain blocks searched for redemption requests. It spans more
+	// than the redemption proof timeout of the tBTC system.
+	DefaultRedemptionLookback = 5000
+)
+
+// Config holds the configuration of the proof submission services.
+type Config struct {
+	// Deposits are the deposits whose funding proofs are submitted.
+	Deposits []DepositConfig
+
+	// Redemptions are the addresses of the Deposit contracts whose
+	// redemption proofs are submitted.
+	Redemptions []string
+
+	// RedemptionLookback is the number of the most recent host chain blocks
+	// searched for redemption requests. If zero, a default value is used.
+	RedemptionLookback int
+
+	// MaxGasPrice is the gas price, in Gwei, above which proofs are not
+	// submitted. If zero, submissions are not gated.
+	MaxGasPrice int64
+
+	// Confirmations is the number of confirmations of a transaction,
+	// including the block the transaction is included in, required before
+	// its proof is submitted. If zero, a default value is used.
+	Confirmations int
+
+	// Tick is the interval, in seconds, in which the proven transactions
+	// are checked. If zero, a default value is used.
+	Tick int
+}
+
+// DepositConfig identifies a deposit whose funding proof is submitted.
+type DepositConfig struct {
+	// Contract is the address of the Deposit contract on the host chain.
+	Contract string
+	// Address is the Bitcoin address the deposit is funded to.
+	Address string
+}
+
+// IsFundingEnabled checks whether funding proofs are submitted for any
+// deposit.
+func (c *Config) IsFundingEnabled() bool {
+	return len(c.Deposits) > 0
+}
+
+// IsRedemptionEnabled checks whether redemption proofs are submitted for any
+// deposit.
+func (c *Config) IsRedemptionEnabled() bool {
+	return len(c.Redemptions) > 0
+}
+
+func (c *Config) redemptionLookback() uint64 {
+	if c.RedemptionLookback > 0 {
+		return uint64(c.RedemptionLookback)
+	}
+
+	return DefaultRedemptionLookback
+}
+
+func (c *Config) confirmations() int {
+	if c.Confirmations > 0 {
+		return c.Confirmations
+	}
+
+	return DefaultConfirmations
+}
+
+// ProofSource provides proofs of transaction inclusion, e.g. the proof
+// cache.
+type ProofSource interface {
+	// TransactionProof returns the proof of inclusion of the confirmed
+	// transaction with the given ID.
+	TransactionProof(ctx context.Context, txID btc.Digest) (*proof.Bundle, error)
+}
+
+// Prioritizer prioritizes relaying the headers awaited by pending proofs,
+// e.g. the headers relay control.
+type Prioritizer interface {
+	// Prioritize makes the headers up to the given height, awaited by the
+	// proof with the given key, relayed ahead of routine work.
+	Prioritize(key string, height int64)
+
+	// Deprioritize stops prioritizing the headers awaited by the proof with
+	// the given key.
+	Deprioritize(key string)
+}
+
+// prioritizeProof makes the given prioritizer, if any, prioritize the
+// headers the proof of the given transaction awaits: the header of the block
+// including the transaction and the headers providing the required
+// confirmations. Nothing is prioritized for unconfirmed transactions, as
+// their block height is not known yet.
+func prioritizeProof(
+	ctx context.Context,
+	btcChain btc.Handle,
+	prioritizer Prioritizer,
+	key string,
+	transaction *btc.Transaction,
+	confirmations int,
+) {
+	if prioritizer == nil || !transaction.IsConfirmed() {
+		return
+	}
+
+	height, err := btcChain.GetHeightByDigest(ctx, *transaction.BlockHash)
+	if err != nil {
+		logger.Warnf(
+			"could not get height of block including transaction [%v]: [%v]",
+			chainhash.Hash(transaction.TxID),
+			err,
+		)
+		return
+	}
+
+	prioritizer.Prioritize(key, height+int64(confirmations)-1)
+}
+
+// deprioritizeProof makes the given prioritizer, if any, stop prioritizing
+// the headers awaited by the proof with the given key.
+func deprioritizeProof(prioritizer Prioritizer, key string) {
+	if prioritizer != nil {
+		prioritizer.Deprioritize(key)
+	}
+}
+
+// chainProofSource builds proofs from blocks fetched from the Bitcoin chain.
+// It is used if the proof cache is not enabled.
+type chainProofSource struct {
+	btcChain btc.Handle
+}
+
+func (cps *chainProofSource) TransactionProof(
+	ctx context.Context,
+	txID btc.Digest,
+) (*proof.Bundle, error) {
+	return proof.FetchTransactionProof(ctx, cps.btcChain, txID)
+}
+
+// resolveProofSource returns the given proof source or, if it is nil, the
+// proof source fetching blocks from the Bitcoin chain.
+func resolveProofSource(btcChain btc.Handle, proofs ProofSource) ProofSource {
+	if proofs == nil {
+		return &chainProofSource{btcChain}
+	}
+
+	return proofs
+}
+
+// buildProof builds the proof of the given transaction if it has the given
+// number of confirmations and the header of its block is known by the relay
+// contract. Nil is returned if the transaction cannot be proven yet.
+func buildProof(
+	ctx context.Context,
+	btcChain btc.Handle,
+	hostChain chain.RelayReader,
+	proofs ProofSource,
+	transaction *btc.Transaction,
+	confirmations int,
+) (*chain.TransactionProof, error) {
+	if !transaction.IsConfirmed() ||
+		transaction.Confirmations < uint64(confirmations) {
+		logger.Debugf(
+			"transaction [%v] has [%v] of [%v] required confirmations",
+			chainhash.Hash(transaction.TxID),
+			transaction.Confirmations,
+			confirmations,
+		)
+		return nil, nil
+	}
+
+	bundle, err := proofs.TransactionProof(ctx, transaction.TxID)
+	if err != nil {
+		return nil, fmt.Errorf("could not build merkle proof: [%v]", err)
+	}
+
+	if _, err := hostChain.FindHeight(ctx, bundle.BlockDigest); err != nil {
+		logger.Debugf(
+			"block including transaction [%v] is not relayed yet: [%v]",
+			chainhash.Hash(transaction.TxID),
+			err,
+		)
+		return nil, nil
+	}
+
+	spvProof, err := proof.BuildSPVProof(ctx, btcChain, bundle, confirmations)
+	if err != nil {
+		return nil, fmt.Errorf("could not build SPV proof: [%v]", err)
+	}
+
+	return toTransactionProof(spvProof), nil
+}
+
+// toTransactionProof converts the SPV proof to the format accepted by the
+// host chain.
+func toTransactionProof(spvProof *proof.SPVProof) *chain.TransactionProof {
+	return &chain.TransactionProof{
+		TxVersion:      spvProof.TxVersion,
+		TxInputVector:  spvProof.TxInputVector,
+		TxOutputVector: spvProof.TxOutputVector,
+		TxLocktime:     spvProof.TxLocktime,
+		MerkleProof:    spvProof.MerkleProof,
+		TxIndexInBlock: spvProof.TxIndexInBlock,
+		BitcoinHeaders: spvProof.BitcoinHeaders,
+	}
+}
@@ -0,0 +1,428 @@
+package prover
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+)
+
+// redemption.go file contains the service submitting redemption proofs of
+// the configured deposits. Once a deposit awaits the redemption proof, the
+// request emitted by the tBTC system tells the deposit output spent by the
+// redemption transaction and the output script it pays. The deposit monitor
+// watches the address of that script, so the redemption transaction
+// published by the signers is found in the mempool. If it got confirmed
+// unnoticed, the most recent blocks are scanned. Once the transaction has
+// enough confirmations and the header of its block is stored by the relay
+// contract, the service submits the proof, so the redeemer does not have to
+// and the redemption does not time out.
+
+const (
+	// resubmissionBlocks is the number of host chain blocks after which the
+	// redemption proof is submitted again if the deposit still awaits it,
+	// e.g. because the previous transaction has been dropped.
+	resubmissionBlocks = 20
+
+	// redemptionScanBlocks is the number of the most recent Bitcoin blocks
+	// scanned for a redemption transaction confirmed unnoticed.
+	redemptionScanBlocks = 144
+)
+
+// redeemedDeposit is a configured deposit along with its redemption.
+type redeemedDeposit struct {
+	contract string
+
+	// request is the redemption request of the deposit. It is nil until
+	// the deposit awaits the redemption proof.
+	request *chain.RedemptionRequest
+	// redeemerAddress is the address the redemption transaction pays.
+	redeemerAddress string
+	// candidates are IDs of the transactions paying the redeemer address.
+	candidates map[btc.Digest]bool
+	// scanned is set once the recent blocks have been scanned for the
+	// redemption transaction.
+	scanned bool
+
+	// submitted is set once the redemption proof has been submitted.
+	submitted bool
+	// submittedAt is the host chain block in which the redemption proof
+	// has been submitted.
+	submittedAt uint64
+	// done is set once the deposit is redeemed.
+	done bool
+}
+
+// RedemptionService submits redemption proofs of the configured deposits.
+type RedemptionService struct {
+	btcChain      btc.Handle
+	hostChain     chain.Handle
+	monitor       *deposit.Monitor
+	proofs        ProofSource
+	prioritizer   Prioritizer
+	confirmations int
+	lookback      uint64
+
+	mutex sync.Mutex
+	// deposits maps Deposit contract addresses to the configured deposits.
+	deposits map[string]*redeemedDeposit
+}
+
+// NewRedemptionService creates the service submitting redemption proofs of
+// the configured deposits. Redemption transactions are found by the given
+// deposit monitor. Proofs of transaction inclusion are taken from the given
+// proof source or, if it is nil, built from blocks fetched from the Bitcoin
+// chain. Headers awaited by the proofs are prioritized by the given
+// prioritizer, unless it is nil.
+func NewRedemptionService(
+	btcChain btc.Handle,
+	hostChain chain.Handle,
+	monitor *deposit.Monitor,
+	proofs ProofSource,
+	prioritizer Prioritizer,
+	config *Config,
+) *RedemptionService {
+	service := &RedemptionService{
+		btcChain:      btcChain,
+		hostChain:     hostChain,
+		monitor:       monitor,
+		proofs:        resolveProofSource(btcChain, proofs),
+		prioritizer:   prioritizer,
+		confirmations: config.confirmations(),
+		lookback:      config.redemptionLookback(),
+		deposits:      make(map[string]*redeemedDeposit),
+	}
+
+	for _, contract := range config.Redemptions {
+		service.deposits[contract] = &redeemedDeposit{
+			contract:   contract,
+			candidates: make(map[btc.Digest]bool),
+		}
+	}
+
+	return service
+}
+
+// Start starts handling the deposit monitor events and checking the
+// redemptions in the given tick. It stops once the passed context is done.
+func (rs *RedemptionService) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		subscription := rs.monitor.Feed().Subscribe()
+		defer func() {
+			subscription.Unsubscribe()
+		}()
+
+		for {
+			select {
+			case event, ok := <-subscription.Events():
+				if !ok {
+					logger.Warnf(
+						"deposit feed subscription cancelled; " +
+							"subscribing again",
+					)
+					subscription = rs.monitor.Feed().Subscribe()
+					continue
+				}
+
+				rs.handleEvent(event)
+			case <-ticker.C:
+				rs.submitProofs(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// handleEvent records transactions paying the redeemer addresses. Whether
+// they spend the deposit outputs is checked before the proof is submitted.
+func (rs *RedemptionService) handleEvent(event *deposit.Event) {
+	if event.Type != deposit.EventSeenUnconfirmed &&
+		event.Type != deposit.EventSeenConfirmed {
+		return
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	for _, redeemed := range rs.deposits {
+		if !redeemed.done && redeemed.redeemerAddress == event.Address {
+			redeemed.candidates[event.TxID] = true
+		}
+	}
+}
+
+// submitProofs submits redemption proofs of all deposits whose redemption
+// transactions can be proven.
+func (rs *RedemptionService) submitProofs(ctx context.Context) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	for _, redeemed := range rs.deposits {
+		if redeemed.done {
+			continue
+		}
+
+		if err := rs.submitProof(ctx, redeemed); err != nil {
+			logger.Warnf(
+				"could not submit redemption proof of deposit [%v]: [%v]",
+				redeemed.contract,
+				err,
+			)
+		}
+
+		if redeemed.done || redeemed.submitted {
+			deprioritizeProof(rs.prioritizer, redemptionProofKey(redeemed))
+		}
+	}
+}
+
+// submitProof submits the redemption proof of the given deposit if it awaits
+// the proof and its redemption transaction can be proven. The proof is not
+// submitted again until resubmissionBlocks host chain blocks pass. Must be
+// called with the mutex held.
+func (rs *RedemptionService) submitProof(
+	ctx context.Context,
+	redeemed *redeemedDeposit,
+) error {
+	state, err := rs.hostChain.GetDepositState(ctx, redeemed.contract)
+	if err != nil {
+		return fmt.Errorf("could not get deposit state: [%v]", err)
+	}
+
+	if state == chain.DepositRedeemed {
+		logger.Infof("deposit [%v] is redeemed", redeemed.contract)
+		redeemed.done = true
+		if redeemed.redeemerAddress != "" {
+			rs.monitor.Unwatch(redeemed.redeemerAddress)
+		}
+		return nil
+	}
+
+	if state != chain.DepositAwaitingWithdrawalProof {
+		return nil
+	}
+
+	currentBlock, err := rs.hostChain.CurrentBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get current block: [%v]", err)
+	}
+
+	if redeemed.submitted &&
+		currentBlock < redeemed.submittedAt+resubmissionBlocks {
+		return nil
+	}
+
+	if redeemed.request == nil {
+		if err := rs.resolveRequest(ctx, redeemed, currentBlock); err != nil {
+			return err
+		}
+	}
+
+	transaction, err := rs.findRedemption(ctx, redeemed)
+	if err != nil || transaction == nil {
+		return err
+	}
+
+	prioritizeProof(
+		ctx,
+		rs.btcChain,
+		rs.prioritizer,
+		redemptionProofKey(redeemed),
+		transaction,
+		rs.confirmations,
+	)
+
+	transactionProof, err := buildProof(
+		ctx,
+		rs.btcChain,
+		rs.hostChain,
+		rs.proofs,
+		transaction,
+		rs.confirmations,
+	)
+	if err != nil || transactionProof == nil {
+		return err
+	}
+
+	if redeemed.submitted {
+		logger.Warnf(
+			"deposit [%v] still awaits redemption proof; submitting again",
+			redeemed.contract,
+		)
+	}
+
+	if err := rs.hostChain.ProvideRedemptionProof(
+		ctx,
+		redeemed.contract,
+		transactionProof,
+	); err != nil {
+		return err
+	}
+
+	logger.Infof(
+		"submitted redemption proof of deposit [%v] for transaction [%v]",
+		redeemed.contract,
+		chainhash.Hash(transaction.TxID),
+	)
+	redeemed.submitted = true
+	redeemed.submittedAt = currentBlock
+
+	return nil
+}
+
+// resolveRequest finds the redemption request of the given deposit and
+// starts watching the redeemer address. Must be called with the mutex held.
+func (rs *RedemptionService) resolveRequest(
+	ctx context.Context,
+	redeemed *redeemedDeposit,
+	currentBlock uint64,
+) error {
+	fromBlock := uint64(0)
+	if currentBlock > rs.lookback {
+		fromBlock = currentBlock - rs.lookback
+	}
+
+	request, err := rs.hostChain.GetRedemptionRequest(
+		ctx,
+		redeemed.contract,
+		fromBlock,
+	)
+	if err != nil {
+		return fmt.Errorf("could not get redemption request: [%v]", err)
+	}
+
+	if request == nil {
+		return fmt.Errorf(
+			"no redemption request found since block [%v]",
+			fromBlock,
+		)
+	}
+
+	address, err := outputScriptAddress(
+		request.RedeemerOutputScript,
+		rs.btcChain.NetworkParams(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := rs.monitor.Watch(address); err != nil {
+		return err
+	}
+
+	redeemed.request = request
+	redeemed.redeemerAddress = address
+
+	return nil
+}
+
+// findRedemption returns the transaction spending the deposit output. Nil is
+// returned if the transaction has not been found yet. If the deposit output
+// turns out to be spent by a confirmed transaction not seen so far, the most
+// recent blocks are scanned once. Must be called with the mutex held.
+func (rs *RedemptionService) findRedemption(
+	ctx context.Context,
+	redeemed *redeemedDeposit,
+) (*btc.Transaction, error) {
+	outpoint := redeemed.request.Outpoint
+
+	for txID := range redeemed.candidates {
+		transaction, err := rs.btcChain.GetTransaction(ctx, txID)
+		if err != nil {
+			logger.Debugf("could not get redemption candidate: [%v]", err)
+			continue
+		}
+
+		for _, input := range transaction.Inputs {
+			if input.Outpoint() == outpoint {
+				return transaction, nil
+			}
+		}
+
+		delete(redeemed.candidates, txID)
+	}
+
+	if redeemed.scanned {
+		return nil, nil
+	}
+
+	unspent, err := rs.btcChain.IsOutputUnspent(
+		ctx,
+		outpoint.TxID,
+		outpoint.OutputIndex,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not check deposit output: [%v]", err)
+	}
+
+	if unspent {
+		return nil, nil
+	}
+
+	tip, err := rs.btcChain.GetBlockCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fromHeight := tip - redemptionScanBlocks + 1
+	if fromHeight < 0 {
+		fromHeight = 0
+	}
+
+	logger.Infof(
+		"output of deposit [%v] is spent by a transaction not seen yet; "+
+			"scanning recent blocks",
+		redeemed.contract,
+	)
+
+	// Transactions found are delivered as the deposit monitor events.
+	if _, err := rs.monitor.ScanBlocks(ctx, fromHeight, tip); err != nil {
+		return nil, fmt.Errorf("could not scan recent blocks: [%v]", err)
+	}
+
+	redeemed.scanned = true
+
+	return nil, nil
+}
+
+// outputScriptAddress returns the address of the given output script. The
+// script may be prefixed by its length, as stored by the Deposit contract.
+func outputScriptAddress(
+	script []byte,
+	params *chaincfg.Params,
+) (string, error) {
+	if len(script) > 0 && int(script[0]) == len(script)-1 {
+		script = script[1:]
+	}
+
+	_, addresses, _, err := txscript.ExtractPkScriptAddrs(script, params)
+	if err != nil || len(addresses) != 1 {
+		return "", fmt.Errorf(
+			"could not resolve address of redeemer output script [%x]",
+			script,
+		)
+	}
+
+	return addresses[0].EncodeAddress(), nil
+}
+
+// redemptionProofKey returns the key identifying the redemption proof of the
+// given deposit among the proofs awaiting headers.
+func redemptionProofKey(redeemed *redeemedDeposit) string {
+	return "redemption:" + redeemed.contract
+}
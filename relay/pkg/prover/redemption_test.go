@@ -0,0 +1,255 @@
+package prover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+)
+
+// P2WPKH address on the regtest network the redeemed bitcoins are paid to.
+const redeemerAddress = "bcrt1qgfpyysjzgfpyysjzgfpyysjzgfpyysjzuyhhvw"
+
+func TestRedemptionService_SubmitProofs(t *testing.T) {
+	var tests = map[string]struct {
+		confirmations       uint64
+		relayed             bool
+		state               chain.DepositState
+		spendsDeposit       bool
+		expectedSubmissions int
+	}{
+		"redemption confirmed and block relayed": {
+			confirmations:       6,
+			relayed:             true,
+			state:               chain.DepositAwaitingWithdrawalProof,
+			spendsDeposit:       true,
+			expectedSubmissions: 1,
+		},
+		"not enough confirmations": {
+			confirmations:       3,
+			relayed:             true,
+			state:               chain.DepositAwaitingWithdrawalProof,
+			spendsDeposit:       true,
+			expectedSubmissions: 0,
+		},
+		"block not relayed": {
+			confirmations:       6,
+			relayed:             false,
+			state:               chain.DepositAwaitingWithdrawalProof,
+			spendsDeposit:       true,
+			expectedSubmissions: 0,
+		},
+		"deposit not awaiting redemption proof": {
+			confirmations:       6,
+			relayed:             true,
+			state:               chain.DepositActive,
+			spendsDeposit:       true,
+			expectedSubmissions: 0,
+		},
+		"transaction not spending deposit output": {
+			confirmations:       6,
+			relayed:             true,
+			state:               chain.DepositAwaitingWithdrawalProof,
+			spendsDeposit:       false,
+			expectedSubmissions: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := context.Background()
+
+			btcChain, hostChain, redemptionTx := redeemedChains(
+				t,
+				test.confirmations,
+				test.spendsDeposit,
+			)
+
+			if test.relayed {
+				hostChain.SetHeaderHeight(*redemptionTx.BlockHash, 2)
+			}
+			hostChain.SetDepositState(depositContract, test.state)
+
+			service := newTestRedemptionService(t, btcChain, hostChain)
+
+			// Resolve the request so the redeemer address gets watched.
+			service.submitProofs(ctx)
+
+			service.handleEvent(&deposit.Event{
+				Type:    deposit.EventSeenUnconfirmed,
+				Address: redeemerAddress,
+				TxID:    redemptionTx.TxID,
+			})
+
+			service.submitProofs(ctx)
+
+			events := hostChain.RedemptionProofEvents()
+			if len(events) != test.expectedSubmissions {
+				t.Fatalf(
+					"unexpected number of submissions:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedSubmissions,
+					len(events),
+				)
+			}
+
+			if test.expectedSubmissions == 0 {
+				return
+			}
+
+			if events[0].DepositAddress != depositContract {
+				t.Errorf(
+					"unexpected deposit: [%v]",
+					events[0].DepositAddress,
+				)
+			}
+			if len(events[0].Proof.BitcoinHeaders) != 6*80 {
+				t.Errorf(
+					"unexpected headers length: [%v]",
+					len(events[0].Proof.BitcoinHeaders),
+				)
+			}
+		})
+	}
+}
+
+func TestRedemptionService_Resubmission(t *testing.T) {
+	ctx := context.Background()
+
+	btcChain, hostChain, redemptionTx := redeemedChains(t, 6, true)
+	hostChain.SetHeaderHeight(*redemptionTx.BlockHash, 2)
+	hostChain.SetDepositState(
+		depositContract,
+		chain.DepositAwaitingWithdrawalProof,
+	)
+	hostChain.SetCurrentBlock(100)
+
+	service := newTestRedemptionService(t, btcChain, hostChain)
+	service.submitProofs(ctx)
+	service.handleEvent(&deposit.Event{
+		Type:    deposit.EventSeenConfirmed,
+		Address: redeemerAddress,
+		TxID:    redemptionTx.TxID,
+	})
+	service.submitProofs(ctx)
+
+	// The submitted transaction is dropped and the deposit still awaits
+	// the redemption proof.
+	hostChain.SetDepositState(
+		depositContract,
+		chain.DepositAwaitingWithdrawalProof,
+	)
+
+	service.submitProofs(ctx)
+	if submissions := len(hostChain.RedemptionProofEvents()); submissions != 1 {
+		t.Fatalf("unexpected number of submissions: [%v]", submissions)
+	}
+
+	hostChain.SetCurrentBlock(100 + resubmissionBlocks)
+
+	service.submitProofs(ctx)
+	if submissions := len(hostChain.RedemptionProofEvents()); submissions != 2 {
+		t.Fatalf("unexpected number of submissions: [%v]", submissions)
+	}
+
+	// Redeemed deposits are no longer checked.
+	service.submitProofs(ctx)
+	if !service.deposits[depositContract].done {
+		t.Errorf("redeemed deposit is still checked")
+	}
+}
+
+func TestOutputScriptAddress(t *testing.T) {
+	script := outputScript(t, redeemerAddress)
+	prefixed := append([]byte{byte(len(script))}, script...)
+
+	for name, script := range map[string][]byte{
+		"plain script":           script,
+		"length-prefixed script": prefixed,
+	} {
+		t.Run(name, func(t *testing.T) {
+			address, err := outputScriptAddress(
+				script,
+				&chaincfg.RegressionNetParams,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if address != redeemerAddress {
+				t.Errorf(
+					"unexpected address:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					redeemerAddress,
+					address,
+				)
+			}
+		})
+	}
+}
+
+func newTestRedemptionService(
+	t *testing.T,
+	btcChain *btc.LocalChain,
+	hostChain chain.Handle,
+) *RedemptionService {
+	monitor, err := deposit.NewMonitor(btcChain, &deposit.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewRedemptionService(
+		btcChain,
+		hostChain,
+		monitor,
+		nil,
+		nil,
+		&Config{Redemptions: []string{depositContract}},
+	)
+}
+
+// redeemedChains returns local chains with the redemption transaction
+// included in the block at height 2 and the redemption request of the
+// deposit set.
+func redeemedChains(
+	t *testing.T,
+	confirmations uint64,
+	spendsDeposit bool,
+) (*btc.LocalChain, *chainlocal.Chain, *btc.Transaction) {
+	depositOutpoint := btc.Outpoint{TxID: btc.Digest{0x0f}, OutputIndex: 1}
+
+	spentOutpoint := depositOutpoint
+	if !spendsDeposit {
+		spentOutpoint.OutputIndex = 0
+	}
+
+	redemptionTx := &btc.Transaction{
+		TxID:    btc.Digest{0x1f},
+		Version: 1,
+		Inputs: []*btc.TransactionInput{
+			{
+				PrevTxID:        spentOutpoint.TxID,
+				PrevOutputIndex: spentOutpoint.OutputIndex,
+				Sequence:        0xffffffff,
+			},
+		},
+		Outputs: []*btc.TransactionOutput{
+			{Value: 99000, PublicKeyScript: outputScript(t, redeemerAddress)},
+		},
+	}
+
+	btcChain, hostChain := chainsIncluding(t, redemptionTx, confirmations)
+
+	hostChain.SetRedemptionRequest(depositContract, &chain.RedemptionRequest{
+		Outpoint:             depositOutpoint,
+		RedeemerOutputScript: outputScript(t, redeemerAddress),
+	})
+
+	return btcChain, hostChain, redemptionTx
+}
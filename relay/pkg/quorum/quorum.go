@@ -0,0 +1,324 @@
+package quorum
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	relayheader "github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+// quorum.go file contains the quorum stage of the headers pipeline. Each
+// header pulled from the relay Bitcoin node is compared with the headers at
+// the same height on the secondary Bitcoin nodes. The header is relayed only
+// if the required number of sources, the relay Bitcoin node included, agree
+// on its digest, so a single compromised data provider cannot make the relay
+// push headers of a chain the rest of the network does not follow. Each
+// source is scored by the share of headers it agreed on.
+
+var logger = log.Logger("tbtc-relay-quorum")
+
+// DefaultRecheckInterval is the default interval in which the header held
+// back for the lack of quorum is checked again.
+const DefaultRecheckInterval = 30 * time.Second
+
+// Config holds the configuration of the quorum mode.
+type Config struct {
+	// Enabled determines whether headers are relayed only once the quorum
+	// of sources agree on them.
+	Enabled bool
+
+	// Sources are secondary Bitcoin nodes the pulled headers are compared
+	// with, besides the relay Bitcoin node.
+	Sources []btc.Config
+
+	// Required is the number of sources, the relay Bitcoin node included,
+	// which must agree on a header. If zero, the majority of sources is
+	// required.
+	Required int
+
+	// RecheckInterval is the interval, in seconds, in which the header held
+	// back for the lack of quorum is checked again. If zero, a default value
+	// is used.
+	RecheckInterval int
+}
+
+// Source is a secondary Bitcoin chain the pulled headers are compared with.
+type Source struct {
+	// Name identifies the source in logs and statistics.
+	Name  string
+	Chain btc.Handle
+}
+
+// SourceStats holds the statistics of a single source.
+type SourceStats struct {
+	// Agreements is the number of headers the source agreed on.
+	Agreements int
+	// Disagreements is the number of headers the source had a different
+	// header for at the same height, including the headers it did not have
+	// yet.
+	Disagreements int
+	// Failures is the number of headers the source could not be asked
+	// about.
+	Failures int
+}
+
+// Score returns the trust score of the source, i.e. the share of the checked
+// headers the source agreed on, from 0 to 1. A source which has not been
+// checked yet has the score of 1.
+func (ss SourceStats) Score() float64 {
+	checked := ss.Agreements + ss.Disagreements
+	if checked == 0 {
+		return 1
+	}
+
+	return float64(ss.Agreements) / float64(checked)
+}
+
+// Stage holds back pulled headers the quorum of sources does not agree on.
+// It implements header.Stage.
+type Stage struct {
+	primary         btc.Handle
+	sources         []*Source
+	required        int
+	recheckInterval time.Duration
+	clock           clock.Clock
+
+	mutex sync.RWMutex
+	stats map[string]*SourceStats
+	held  bool
+}
+
+// NewStage creates the quorum stage comparing headers pulled from the given
+// primary Bitcoin chain with the given secondary sources.
+func NewStage(
+	primary btc.Handle,
+	sources []*Source,
+	config *Config,
+) (*Stage, error) {
+	total := len(sources) + 1
+
+	required := config.Required
+	if required == 0 {
+		required = total/2 + 1
+	}
+
+	if required < 1 || required > total {
+		return nil, fmt.Errorf(
+			"required quorum [%v] is not within [1-%v] sources",
+			required,
+			total,
+		)
+	}
+
+	recheckInterval := DefaultRecheckInterval
+	if config.RecheckInterval > 0 {
+		recheckInterval = time.Duration(config.RecheckInterval) * time.Second
+	}
+
+	stats := make(map[string]*SourceStats, len(sources))
+	for _, source := range sources {
+		stats[source.Name] = &SourceStats{}
+	}
+
+	return &Stage{
+		primary:         primary,
+		sources:         sources,
+		required:        required,
+		recheckInterval: recheckInterval,
+		clock:           clock.System,
+		stats:           stats,
+	}, nil
+}
+
+// Process relays the header once the quorum of sources agrees on it. Until
+// then, the header is held back. If the relay Bitcoin node switches to
+// another header at the same height in the meantime, the held header is
+// dropped, so the new one is pulled instead.
+func (s *Stage) Process(ctx context.Context, header *btc.Header) (bool, error) {
+	agreeing := s.check(ctx, header, true)
+	if agreeing >= s.required {
+		return true, nil
+	}
+
+	s.setHeld(true)
+	defer s.setHeld(false)
+
+	// Holding back is a legitimate wait of the relay pulling loop.
+	defer relayheader.Idle(ctx)()
+
+	for agreeing < s.required {
+		logger.Warnf(
+			"holding back header [%v] with digest [%v]; [%v] of [%v] "+
+				"required sources agree on it",
+			header.Height,
+			chainhash.Hash(header.Hash),
+			agreeing,
+			s.required,
+		)
+
+		select {
+		case <-s.clock.After(s.recheckInterval):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+
+		activeHeader, err := s.primary.GetHeaderByHeight(ctx, header.Height)
+		if err != nil {
+			return false, err
+		}
+
+		if activeHeader.Hash != header.Hash {
+			logger.Infof(
+				"relay Bitcoin node switched header [%v]; "+
+					"pulling the new header",
+				header.Height,
+			)
+			return false, nil
+		}
+
+		agreeing = s.check(ctx, header, false)
+	}
+
+	logger.Infof(
+		"releasing header [%v] agreed by [%v] sources",
+		header.Height,
+		agreeing,
+	)
+
+	return true, nil
+}
+
+// check returns the number of sources, the relay Bitcoin node included,
+// which agree on the given header. The source statistics are updated only
+// on the first check of the header.
+func (s *Stage) check(
+	ctx context.Context,
+	header *btc.Header,
+	first bool,
+) int {
+	agreeing := 1
+
+	for _, source := range s.sources {
+		sourceHeader, err := source.Chain.GetHeaderByHeight(ctx, header.Height)
+
+		switch {
+		case err != nil && isUnreachable(ctx, source.Chain):
+			logger.Warnf(
+				"could not get header [%v] from source [%v]: [%v]",
+				header.Height,
+				source.Name,
+				err,
+			)
+			s.record(source.Name, first, func(stats *SourceStats) {
+				stats.Failures++
+			})
+		case err == nil && sourceHeader.Hash == header.Hash:
+			agreeing++
+			s.record(source.Name, first, func(stats *SourceStats) {
+				stats.Agreements++
+			})
+		default:
+			if first {
+				logger.Warnf(
+					"source [%v] disagrees on header [%v] with digest [%v]",
+					source.Name,
+					header.Height,
+					chainhash.Hash(header.Hash),
+				)
+			}
+			s.record(source.Name, first, func(stats *SourceStats) {
+				stats.Disagreements++
+			})
+		}
+	}
+
+	return agreeing
+}
+
+// isUnreachable checks whether the given source cannot be reached, as
+// opposed to not having the requested header.
+func isUnreachable(ctx context.Context, btcChain btc.Handle) bool {
+	_, err := btcChain.GetBlockCount(ctx)
+	return err != nil
+}
+
+func (s *Stage) record(
+	name string,
+	first bool,
+	update func(stats *SourceStats),
+) {
+	if !first {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	update(s.stats[name])
+}
+
+func (s *Stage) setHeld(held bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.held = held
+}
+
+// Held returns whether a header is currently held back for the lack of
+// quorum.
+func (s *Stage) Held() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.held
+}
+
+// Stats returns the statistics of all sources by their names.
+func (s *Stage) Stats() map[string]SourceStats {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	stats := make(map[string]SourceStats, len(s.stats))
+	for name, sourceStats := range s.stats {
+		stats[name] = *sourceStats
+	}
+
+	return stats
+}
+
+// MaxDisagreements returns the highest number of disagreements among the
+// sources.
+func (s *Stage) MaxDisagreements() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	max := 0
+	for _, sourceStats := range s.stats {
+		if sourceStats.Disagreements > max {
+			max = sourceStats.Disagreements
+		}
+	}
+
+	return max
+}
+
+// MinScore returns the lowest trust score among the sources.
+func (s *Stage) MinScore() float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	min := 1.0
+	for _, sourceStats := range s.stats {
+		if score := sourceStats.Score(); score < min {
+			min = score
+		}
+	}
+
+	return min
+}
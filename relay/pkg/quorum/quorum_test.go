@@ -0,0 +1,216 @@
+package quorum
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+var (
+	honestHeader = &btc.Header{Height: 1, Hash: btc.Digest{0xa1}}
+	forgedHeader = &btc.Header{Height: 1, Hash: btc.Digest{0xf1}}
+)
+
+func TestStage_Process(t *testing.T) {
+	var tests = map[string]struct {
+		sourceHeaders         []*btc.Header
+		required              int
+		expectedPassed        bool
+		expectedDisagreements int
+	}{
+		"all sources agree": {
+			sourceHeaders:         []*btc.Header{honestHeader, honestHeader},
+			expectedPassed:        true,
+			expectedDisagreements: 0,
+		},
+		"majority agrees": {
+			sourceHeaders:         []*btc.Header{honestHeader, forgedHeader},
+			expectedPassed:        true,
+			expectedDisagreements: 1,
+		},
+		"source does not have header": {
+			sourceHeaders:         []*btc.Header{honestHeader, nil},
+			expectedPassed:        true,
+			expectedDisagreements: 1,
+		},
+		"all sources required": {
+			sourceHeaders:         []*btc.Header{honestHeader, honestHeader},
+			required:              3,
+			expectedPassed:        true,
+			expectedDisagreements: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			sources := make([]*Source, len(test.sourceHeaders))
+			for i, header := range test.sourceHeaders {
+				sources[i] = &Source{
+					Name:  string(rune('a' + i)),
+					Chain: localChain(t, header),
+				}
+			}
+
+			stage, err := NewStage(
+				localChain(t, honestHeader),
+				sources,
+				&Config{Required: test.required},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			passed, err := stage.Process(context.Background(), honestHeader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if passed != test.expectedPassed {
+				t.Errorf(
+					"unexpected result:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedPassed,
+					passed,
+				)
+			}
+
+			if stage.MaxDisagreements() != test.expectedDisagreements {
+				t.Errorf(
+					"unexpected disagreements:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedDisagreements,
+					stage.MaxDisagreements(),
+				)
+			}
+		})
+	}
+}
+
+func TestStage_HoldsUntilQuorum(t *testing.T) {
+	compromised := localChain(t, forgedHeader)
+	lagging := localChain(t, nil)
+
+	stage, err := NewStage(
+		compromised,
+		[]*Source{
+			{Name: "first", Chain: localChain(t, honestHeader)},
+			{Name: "second", Chain: lagging},
+		},
+		&Config{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	stage.clock = fakeClock
+
+	type result struct {
+		passed bool
+		err    error
+	}
+	results := make(chan result)
+	go func() {
+		passed, err := stage.Process(context.Background(), forgedHeader)
+		results <- result{passed, err}
+	}()
+
+	fakeClock.BlockUntil(1)
+
+	if !stage.Held() {
+		t.Errorf("header not held back")
+	}
+
+	// The second source learns the forged header, which gives the quorum.
+	lagging.AppendHeader(forgedHeader)
+	fakeClock.Advance(DefaultRecheckInterval)
+
+	select {
+	case r := <-results:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if !r.passed {
+			t.Errorf("header not released")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("header not released in time")
+	}
+
+	if stage.Held() {
+		t.Errorf("header still held back")
+	}
+
+	stats := stage.Stats()
+	if stats["first"].Score() != 0 {
+		t.Errorf("unexpected score of first source: [%v]", stats["first"])
+	}
+}
+
+func TestStage_DropsSwitchedHeader(t *testing.T) {
+	primary := localChain(t, forgedHeader)
+
+	stage, err := NewStage(
+		primary,
+		[]*Source{{Name: "honest", Chain: localChain(t, honestHeader)}},
+		&Config{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	stage.clock = fakeClock
+
+	results := make(chan bool)
+	go func() {
+		passed, _ := stage.Process(context.Background(), forgedHeader)
+		results <- passed
+	}()
+
+	fakeClock.BlockUntil(1)
+
+	primary.SetHeaders([]*btc.Header{honestHeader})
+	fakeClock.Advance(DefaultRecheckInterval)
+
+	select {
+	case passed := <-results:
+		if passed {
+			t.Errorf("switched header not dropped")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("switched header not dropped in time")
+	}
+}
+
+func TestNewStage_InvalidQuorum(t *testing.T) {
+	_, err := NewStage(
+		localChain(t, honestHeader),
+		[]*Source{{Name: "a", Chain: localChain(t, honestHeader)}},
+		&Config{Required: 3},
+	)
+	if err == nil {
+		t.Errorf("expected error for quorum exceeding the sources")
+	}
+}
+
+// localChain returns a local Bitcoin chain with the given header. If the
+// header is nil, the chain has no headers.
+func localChain(t *testing.T, header *btc.Header) *btc.LocalChain {
+	handle, err := btc.ConnectLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	btcChain := handle.(*btc.LocalChain)
+
+	if header != nil {
+		btcChain.AppendHeader(header)
+	}
+
+	return btcChain
+}
@@ -0,0 +1,39 @@
+package rewards
+
+import (
+	"math/big"
+
+	"github.com/keep-network/tbtc/relay/pkg/gasusage"
+)
+
+// Profitability estimates the economics of pushing headers by combining the
+// reward per header observed by the rewards tracker with the gas per header
+// baseline of the gas usage detector.
+type Profitability struct {
+	tracker  *Tracker
+	detector *gasusage.Detector
+}
+
+// NewProfitability creates a new profitability estimator.
+func NewProfitability(
+	tracker *Tracker,
+	detector *gasusage.Detector,
+) *Profitability {
+	return &Profitability{
+		tracker:  tracker,
+		detector: detector,
+	}
+}
+
+// RewardPerHeader returns the reward expected for a single pushed header.
+// False is returned if the reward is not known yet.
+func (p *Profitability) RewardPerHeader() (*big.Int, bool) {
+	return p.tracker.RewardPerHeader()
+}
+
+// GasPerHeader returns the gas expected to be used per pushed header.
+// False is returned if there are not enough pushes to compute the baseline.
+func (p *Profitability) GasPerHeader() (float64, bool) {
+	baseline := p.detector.BaselineGasPerHeader()
+	return baseline, baseline > 0
+}
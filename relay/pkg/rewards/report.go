@@ -0,0 +1,63 @@
+package rewards
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Report summarizes the reward income versus the gas expenditure over
+// a period of time.
+type Report struct {
+	Claims int
+	// Claimed is the total amount of claimed rewards, expressed in the
+	// smallest unit of the host chain currency.
+	Claimed *big.Int
+	// GasExpenditure is the total amount of fees paid for pushes made by
+	// this relay maintainer, expressed in the smallest unit of the host
+	// chain currency.
+	GasExpenditure *big.Int
+}
+
+// Summarize builds a report from the given claims and gas expenditure.
+func Summarize(claims []*Claim, gasExpenditure *big.Int) *Report {
+	report := &Report{
+		Claims:         len(claims),
+		Claimed:        big.NewInt(0),
+		GasExpenditure: new(big.Int).Set(gasExpenditure),
+	}
+
+	for _, claim := range claims {
+		report.Claimed.Add(report.Claimed, claim.Amount)
+	}
+
+	return report
+}
+
+// NetIncome returns the claimed rewards reduced by the gas expenditure.
+func (r *Report) NetIncome() *big.Int {
+	return new(big.Int).Sub(r.Claimed, r.GasExpenditure)
+}
+
+// Print writes the report in a human readable form.
+func (r *Report) Print(writer io.Writer) error {
+	_, err := fmt.Fprintf(
+		writer,
+		"reward income:    %v ETH (%v claims)\n"+
+			"gas expenditure:  %v ETH\n"+
+			"net income:       %v ETH\n",
+		formatEther(r.Claimed),
+		r.Claims,
+		formatEther(r.GasExpenditure),
+		formatEther(r.NetIncome()),
+	)
+	return err
+}
+
+// formatEther formats the given amount of wei as ether.
+func formatEther(wei *big.Int) string {
+	return new(big.Float).Quo(
+		new(big.Float).SetInt(wei),
+		big.NewFloat(1e18),
+	).Text('f', 6)
+}
@@ -0,0 +1,304 @@
+package rewards
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+)
+
+// rewards.go file contains the tracker of rewards paid by the relay contract
+// for header submissions. The tracker periodically checks the rewards accrued
+// by the operator and claims them once they reach the claim threshold or
+// once the claim interval passes since the last claim. Fees paid for pushes
+// made by this relay maintainer are summed up as well, so the reward income
+// can be compared with the gas expenditure.
+
+var logger = log.Logger("tbtc-relay-rewards")
+
+const (
+	// DefaultTick is the default interval in which accrued rewards are
+	// checked.
+	DefaultTick = 10 * time.Minute
+
+	// DefaultClaimInterval is the default interval in which accrued rewards
+	// are claimed.
+	DefaultClaimInterval = 24 * time.Hour
+)
+
+// Config holds the configuration of the relay rewards claiming.
+type Config struct {
+	// Enabled determines whether accrued rewards are tracked and claimed.
+	Enabled bool
+
+	// ClaimThreshold is the amount of accrued rewards, in the smallest unit
+	// of the host chain currency, at which the rewards are claimed right
+	// away. If empty, rewards are claimed only in the claim interval.
+	ClaimThreshold string
+
+	// ClaimInterval is the interval, in seconds, in which accrued rewards
+	// are claimed. If zero, a default value is used.
+	ClaimInterval int
+
+	// Tick is the interval, in seconds, in which accrued rewards are
+	// checked. If zero, a default value is used.
+	Tick int
+}
+
+// Claim is a single claim of accrued rewards.
+type Claim struct {
+	Timestamp time.Time
+	// Amount is the amount of rewards accrued when the claim was submitted,
+	// expressed in the smallest unit of the host chain currency.
+	Amount *big.Int
+}
+
+// ClaimRecorder is an interface of a store the reward claims are persisted to.
+type ClaimRecorder interface {
+	RecordClaim(claim *Claim) error
+}
+
+// Tracker tracks and claims the rewards accrued by the operator. It
+// implements the competition.Recorder interface so it can be fed with
+// advances tracked by the competition tracker to sum up the gas expenditure.
+type Tracker struct {
+	hostChain      chain.RelayRewards
+	recorder       ClaimRecorder
+	claimThreshold *big.Int
+	claimInterval  time.Duration
+
+	mutex       sync.RWMutex
+	pending     *big.Int
+	claimed     *big.Int
+	claims      int
+	expenditure *big.Int
+	lastClaim   time.Time
+
+	// startTime, startPending and ownHeaders are used to estimate the reward
+	// per header. Only headers pushed since the tracker started are counted,
+	// along with the rewards accrued since the first check.
+	startTime    time.Time
+	startPending *big.Int
+	ownHeaders   int
+}
+
+// NewTracker creates a new relay rewards tracker. The recorder is optional
+// and can be nil.
+func NewTracker(
+	hostChain chain.RelayRewards,
+	config *Config,
+	recorder ClaimRecorder,
+) (*Tracker, error) {
+	var claimThreshold *big.Int
+	if config.ClaimThreshold != "" {
+		threshold, ok := new(big.Int).SetString(config.ClaimThreshold, 10)
+		if !ok || threshold.Sign() <= 0 {
+			return nil, fmt.Errorf(
+				"invalid claim threshold [%v]",
+				config.ClaimThreshold,
+			)
+		}
+		claimThreshold = threshold
+	}
+
+	claimInterval := DefaultClaimInterval
+	if config.ClaimInterval > 0 {
+		claimInterval = time.Duration(config.ClaimInterval) * time.Second
+	}
+
+	now := time.Now()
+
+	return &Tracker{
+		hostChain:      hostChain,
+		recorder:       recorder,
+		claimThreshold: claimThreshold,
+		claimInterval:  claimInterval,
+		pending:        big.NewInt(0),
+		claimed:        big.NewInt(0),
+		expenditure:    big.NewInt(0),
+		lastClaim:      now,
+		startTime:      now,
+	}, nil
+}
+
+// Start starts checking and claiming accrued rewards in the given tick.
+// Tracking stops once the passed context is done or if the relay contract
+// does not pay rewards.
+func (t *Tracker) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	go func() {
+		if err := t.update(ctx, time.Now()); err != nil {
+			if err == chain.ErrRewardsNotSupported {
+				logger.Warnf("rewards tracking disabled: [%v]", err)
+				return
+			}
+
+			logger.Warnf("could not update relay rewards: [%v]", err)
+		}
+
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				if err := t.update(ctx, now); err != nil {
+					logger.Warnf("could not update relay rewards: [%v]", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// update fetches the accrued rewards and claims them if they reached the
+// claim threshold or the claim interval passed since the last claim.
+func (t *Tracker) update(ctx context.Context, now time.Time) error {
+	pending, err := t.hostChain.PendingRewards(ctx)
+	if err == chain.ErrRewardsNotSupported {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("could not get pending rewards: [%v]", err)
+	}
+
+	t.mutex.Lock()
+	if t.startPending == nil {
+		t.startPending = pending
+	}
+	t.pending = pending
+	shouldClaim := t.shouldClaim(now)
+	t.mutex.Unlock()
+
+	if !shouldClaim {
+		return nil
+	}
+
+	logger.Infof("claiming accrued relay rewards [%v]", pending)
+
+	// ClaimRewards returns once the claim transaction is mined, so claims
+	// never mined are not counted.
+	if err := t.hostChain.ClaimRewards(ctx); err != nil {
+		return fmt.Errorf("could not claim rewards: [%v]", err)
+	}
+
+	t.mutex.Lock()
+	t.claimed.Add(t.claimed, pending)
+	t.claims++
+	t.pending = big.NewInt(0)
+	t.lastClaim = now
+	t.mutex.Unlock()
+
+	if t.recorder != nil {
+		if err := t.recorder.RecordClaim(&Claim{
+			Timestamp: now,
+			Amount:    pending,
+		}); err != nil {
+			logger.Warnf("could not record rewards claim: [%v]", err)
+		}
+	}
+
+	return nil
+}
+
+// shouldClaim determines whether the pending rewards should be claimed.
+// It must be called with the mutex held.
+func (t *Tracker) shouldClaim(now time.Time) bool {
+	if t.pending.Sign() <= 0 {
+		return false
+	}
+
+	if t.claimThreshold != nil && t.pending.Cmp(t.claimThreshold) >= 0 {
+		return true
+	}
+
+	return now.Sub(t.lastClaim) >= t.claimInterval
+}
+
+// RecordAdvances sums up the fees paid for the given advances made by this
+// relay maintainer and the number of headers they pushed. Fees of advances
+// with unknown fees are ignored.
+func (t *Tracker) RecordAdvances(advances []*competition.Advance) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, advance := range advances {
+		if !advance.Own {
+			continue
+		}
+
+		if !advance.Timestamp.Before(t.startTime) {
+			t.ownHeaders += advance.HeadersCount
+		}
+
+		if advance.Fee != nil {
+			t.expenditure.Add(t.expenditure, advance.Fee)
+		}
+	}
+
+	return nil
+}
+
+// RewardPerHeader returns the average reward accrued per header pushed by
+// this relay maintainer since the tracker started. False is returned if no
+// rewards have been accrued or no headers have been pushed yet.
+func (t *Tracker) RewardPerHeader() (*big.Int, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if t.startPending == nil || t.ownHeaders == 0 {
+		return nil, false
+	}
+
+	earned := new(big.Int).Add(t.claimed, t.pending)
+	earned.Sub(earned, t.startPending)
+
+	if earned.Sign() <= 0 {
+		return nil, false
+	}
+
+	return earned.Div(earned, big.NewInt(int64(t.ownHeaders))), true
+}
+
+// PendingRewards returns the accrued rewards not claimed yet.
+func (t *Tracker) PendingRewards() *big.Int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return new(big.Int).Set(t.pending)
+}
+
+// ClaimedRewards returns the total amount of rewards claimed since the
+// tracker started.
+func (t *Tracker) ClaimedRewards() *big.Int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return new(big.Int).Set(t.claimed)
+}
+
+// Claims returns the number of claims submitted since the tracker started.
+func (t *Tracker) Claims() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.claims
+}
+
+// GasExpenditure returns the total amount of fees paid for the tracked pushes
+// made by this relay maintainer.
+func (t *Tracker) GasExpenditure() *big.Int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return new(big.Int).Set(t.expenditure)
+}
@@ -0,0 +1,272 @@
+package rewards
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+)
+
+type claimsRecorder struct {
+	claims []*Claim
+}
+
+func (cr *claimsRecorder) RecordClaim(claim *Claim) error {
+	cr.claims = append(cr.claims, claim)
+	return nil
+}
+
+func TestTrackerUpdate(t *testing.T) {
+	start := time.Now()
+
+	var tests = map[string]struct {
+		config         *Config
+		pendingRewards *big.Int
+		now            time.Time
+		expectedClaims int
+	}{
+		"threshold reached": {
+			config:         &Config{ClaimThreshold: "1000"},
+			pendingRewards: big.NewInt(1000),
+			now:            start.Add(time.Minute),
+			expectedClaims: 1,
+		},
+		"threshold not reached": {
+			config:         &Config{ClaimThreshold: "1000"},
+			pendingRewards: big.NewInt(999),
+			now:            start.Add(time.Minute),
+			expectedClaims: 0,
+		},
+		"claim interval passed": {
+			config:         &Config{ClaimInterval: 3600},
+			pendingRewards: big.NewInt(1),
+			now:            start.Add(2 * time.Hour),
+			expectedClaims: 1,
+		},
+		"claim interval not passed": {
+			config:         &Config{ClaimInterval: 3600},
+			pendingRewards: big.NewInt(1),
+			now:            start.Add(30 * time.Minute),
+			expectedClaims: 0,
+		},
+		"nothing to claim": {
+			config:         &Config{ClaimInterval: 3600},
+			pendingRewards: big.NewInt(0),
+			now:            start.Add(2 * time.Hour),
+			expectedClaims: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			hostChain, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+			localChain := hostChain.(*chainlocal.Chain)
+			localChain.SetPendingRewards(test.pendingRewards)
+
+			recorder := &claimsRecorder{}
+
+			tracker, err := NewTracker(localChain, test.config, recorder)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tracker.update(context.Background(), test.now); err != nil {
+				t.Fatal(err)
+			}
+
+			actualClaims := len(localChain.ClaimedRewards())
+			if test.expectedClaims != actualClaims {
+				t.Fatalf(
+					"unexpected number of claims:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedClaims,
+					actualClaims,
+				)
+			}
+
+			if len(recorder.claims) != test.expectedClaims {
+				t.Errorf(
+					"unexpected number of recorded claims:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedClaims,
+					len(recorder.claims),
+				)
+			}
+
+			expectedClaimed := big.NewInt(0)
+			if test.expectedClaims > 0 {
+				expectedClaimed = test.pendingRewards
+			}
+
+			if tracker.ClaimedRewards().Cmp(expectedClaimed) != 0 {
+				t.Errorf(
+					"unexpected claimed rewards:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					expectedClaimed,
+					tracker.ClaimedRewards(),
+				)
+			}
+		})
+	}
+}
+
+func TestTrackerUpdate_NotSupported(t *testing.T) {
+	hostChain, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker, err := NewTracker(hostChain, &Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tracker.update(context.Background(), time.Now())
+	if err != chain.ErrRewardsNotSupported {
+		t.Errorf(
+			"unexpected error:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			chain.ErrRewardsNotSupported,
+			err,
+		)
+	}
+}
+
+func TestNewTracker_InvalidThreshold(t *testing.T) {
+	hostChain, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, threshold := range []string{"abc", "-1", "0"} {
+		_, err := NewTracker(hostChain, &Config{ClaimThreshold: threshold}, nil)
+		if err == nil {
+			t.Errorf("expected error for threshold [%v]", threshold)
+		}
+	}
+}
+
+func TestTrackerRecordAdvances(t *testing.T) {
+	hostChain, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker, err := NewTracker(hostChain, &Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	advances := []*competition.Advance{
+		{Own: true, Fee: big.NewInt(100)},
+		{Own: false, Fee: big.NewInt(200)},
+		{Own: true},
+		{Own: true, Fee: big.NewInt(300)},
+	}
+
+	if err := tracker.RecordAdvances(advances); err != nil {
+		t.Fatal(err)
+	}
+
+	if tracker.GasExpenditure().Cmp(big.NewInt(400)) != 0 {
+		t.Errorf(
+			"unexpected gas expenditure:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			400,
+			tracker.GasExpenditure(),
+		)
+	}
+}
+
+func TestReportPrint(t *testing.T) {
+	report := Summarize(
+		[]*Claim{
+			{Amount: big.NewInt(2e18)},
+			{Amount: big.NewInt(5e17)},
+		},
+		big.NewInt(1e18),
+	)
+
+	var buffer bytes.Buffer
+	if err := report.Print(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "reward income:    2.500000 ETH (2 claims)\n" +
+		"gas expenditure:  1.000000 ETH\n" +
+		"net income:       1.500000 ETH\n"
+
+	if buffer.String() != expected {
+		t.Errorf(
+			"unexpected report:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expected,
+			buffer.String(),
+		)
+	}
+}
+
+func TestTrackerRewardPerHeader(t *testing.T) {
+	hostChain, err := chainlocal.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	localChain := hostChain.(*chainlocal.Chain)
+	localChain.SetPendingRewards(big.NewInt(1000))
+
+	tracker, err := NewTracker(localChain, &Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tracker.RewardPerHeader(); ok {
+		t.Fatal("reward per header should not be known before the first check")
+	}
+
+	if err := tracker.update(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tracker.RecordAdvances([]*competition.Advance{
+		// Advances made before the tracker started are not counted.
+		{Own: true, Timestamp: time.Now().Add(-time.Hour), HeadersCount: 100},
+		{Own: true, Timestamp: time.Now(), HeadersCount: 4},
+		{Own: false, Timestamp: time.Now(), HeadersCount: 100},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tracker.RewardPerHeader(); ok {
+		t.Fatal("reward per header should not be known before rewards accrue")
+	}
+
+	localChain.SetPendingRewards(big.NewInt(1400))
+	if err := tracker.update(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	rewardPerHeader, ok := tracker.RewardPerHeader()
+	if !ok || rewardPerHeader.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf(
+			"unexpected reward per header:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			100,
+			rewardPerHeader,
+		)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// notify.go file contains the client of the systemd notification protocol.
+// The service state is sent as newline-separated assignments in a datagram
+// to the unix socket set in the NOTIFY_SOCKET environment variable. The
+// watchdog interval is set in the WATCHDOG_USEC environment variable.
+
+const (
+	notifySocketEnvVariable = "NOTIFY_SOCKET"
+	watchdogEnvVariable     = "WATCHDOG_USEC"
+)
+
+func notificationsEnabled() bool {
+	return os.Getenv(notifySocketEnvVariable) != ""
+}
+
+// notify sends the given state assignments to the service supervisor.
+func notify(assignments ...string) error {
+	socket := os.Getenv(notifySocketEnvVariable)
+	if socket == "" {
+		return nil
+	}
+
+	// Abstract namespace sockets are denoted with a leading `@`.
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix(
+		"unixgram",
+		nil,
+		&net.UnixAddr{Name: socket, Net: "unixgram"},
+	)
+	if err != nil {
+		return fmt.Errorf("could not connect notification socket: [%v]", err)
+	}
+	defer conn.Close()
+
+	message := strings.Join(assignments, "\n")
+	if _, err := conn.Write([]byte(message)); err != nil {
+		return fmt.Errorf("could not send notification: [%v]", err)
+	}
+
+	return nil
+}
+
+// watchdogInterval returns the watchdog interval set by the service
+// supervisor or zero if the watchdog is not enabled.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv(watchdogEnvVariable), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+const (
+	// Default time, in seconds, for which a synced relay is considered
+	// degraded after the headers relay raised an error. It matches the time
+	// after which the node no longer considers relay failures subsequent.
+	defaultDegradedPeriod = 600
+
+	// Interval in which the readiness state is updated by Watch.
+	readinessUpdateInterval = 5 * time.Second
+)
+
+// State is the readiness state of the relay. The relay goes from starting
+// through syncing to synced; a synced relay becomes degraded once it falls
+// behind, fails or stops, and becomes synced again once it recovers.
+type State int
+
+const (
+	// StateStarting means the headers relay has not been started or has not
+	// observed the relay lag yet.
+	StateStarting State = iota
+	// StateSyncing means the relay catches up with the Bitcoin chain and
+	// has not been synced yet.
+	StateSyncing
+	// StateSynced means the relay lag is small enough for the relay to be
+	// considered ready.
+	StateSynced
+	// StateDegraded means the relay has been synced but fell behind, raised
+	// an error recently or stopped.
+	StateDegraded
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateSyncing:
+		return "syncing"
+	case StateSynced:
+		return "synced"
+	case StateDegraded:
+		return "degraded"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(s))
+	}
+}
+
+// Status is the current readiness state of the relay.
+type Status struct {
+	State State
+	// Reason is a human-readable description of the state.
+	Reason string
+	// Since is the time of the transition to the state.
+	Since time.Time
+}
+
+// Transition is a change of the readiness state of the relay.
+type Transition struct {
+	From   State
+	To     State
+	Reason string
+	Time   time.Time
+}
+
+// TransitionHook is called on each transition of the readiness state.
+type TransitionHook func(transition *Transition)
+
+// Readiness tracks the readiness state of the relay derived from the relay
+// statistics.
+type Readiness struct {
+	stats          RelayStats
+	caughtUpLag    int64
+	degradedPeriod time.Duration
+	clock          clock.Clock
+
+	mutex  sync.RWMutex
+	status *Status
+	hooks  []TransitionHook
+
+	// synced determines whether the relay has been synced at least once.
+	synced      bool
+	relayErrors int
+	lastError   time.Time
+}
+
+// NewReadiness creates a tracker of the readiness state of the relay with
+// the given statistics. The state is updated by Update or Watch.
+func NewReadiness(config *Config, stats RelayStats) *Readiness {
+	return newReadiness(config, stats, clock.System)
+}
+
+func newReadiness(
+	config *Config,
+	stats RelayStats,
+	clock clock.Clock,
+) *Readiness {
+	caughtUpLag := config.CaughtUpLag
+	if caughtUpLag <= 0 {
+		caughtUpLag = defaultCaughtUpLag
+	}
+
+	degradedPeriod := config.DegradedPeriod
+	if degradedPeriod <= 0 {
+		degradedPeriod = defaultDegradedPeriod
+	}
+
+	return &Readiness{
+		stats:          stats,
+		caughtUpLag:    caughtUpLag,
+		degradedPeriod: time.Duration(degradedPeriod) * time.Second,
+		clock:          clock,
+		status: &Status{
+			State:  StateStarting,
+			Reason: "headers relay is not started yet",
+			Since:  clock.Now(),
+		},
+	}
+}
+
+// OnTransition registers a hook called on each transition of the readiness
+// state. Hooks are called synchronously by Update, in the order of their
+// registration.
+func (r *Readiness) OnTransition(hook TransitionHook) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// Status returns the current readiness state of the relay.
+func (r *Readiness) Status() *Status {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	status := *r.status
+	return &status
+}
+
+// Update evaluates the readiness state from the current relay statistics
+// and calls the transition hooks if the state has changed.
+func (r *Readiness) Update() *Status {
+	r.mutex.Lock()
+
+	now := r.clock.Now()
+	state, reason := r.evaluate(now)
+
+	var transition *Transition
+	if state != r.status.State {
+		transition = &Transition{
+			From:   r.status.State,
+			To:     state,
+			Reason: reason,
+			Time:   now,
+		}
+		r.status = &Status{State: state, Reason: reason, Since: now}
+	} else {
+		r.status = &Status{State: state, Reason: reason, Since: r.status.Since}
+	}
+
+	status := *r.status
+	hooks := r.hooks
+
+	r.mutex.Unlock()
+
+	if transition != nil {
+		for _, hook := range hooks {
+			hook(transition)
+		}
+	}
+
+	return &status
+}
+
+func (r *Readiness) evaluate(now time.Time) (State, string) {
+	if errors := r.stats.HeadersRelayErrors(); errors > r.relayErrors {
+		r.relayErrors = errors
+		r.lastError = now
+	}
+
+	if !r.stats.HeadersRelayActive() {
+		if r.synced {
+			return StateDegraded, "headers relay is not active"
+		}
+
+		return StateStarting, "headers relay is not started yet"
+	}
+
+	if !r.stats.HeadersRelayLagObserved() {
+		if r.synced {
+			return StateDegraded, "relay lag is not observed"
+		}
+
+		return StateStarting, "waiting for relay lag"
+	}
+
+	lag := r.stats.HeadersRelayLag()
+
+	if lag > r.caughtUpLag {
+		if r.synced {
+			return StateDegraded, fmt.Sprintf(
+				"relay lag is [%v] blocks, above [%v]",
+				lag,
+				r.caughtUpLag,
+			)
+		}
+
+		return StateSyncing, fmt.Sprintf("relay lag is [%v] blocks", lag)
+	}
+
+	r.synced = true
+
+	if !r.lastError.IsZero() && now.Sub(r.lastError) < r.degradedPeriod {
+		return StateDegraded, fmt.Sprintf(
+			"headers relay raised an error [%v] ago",
+			now.Sub(r.lastError).Truncate(time.Second),
+		)
+	}
+
+	return StateSynced, fmt.Sprintf("relay lag is [%v] blocks", lag)
+}
+
+// Watch updates the readiness state periodically until the context is done.
+func (r *Readiness) Watch(ctx context.Context) {
+	ticker := r.clock.NewTicker(readinessUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		r.Update()
+
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
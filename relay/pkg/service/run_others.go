@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Run runs the given function. The context passed to the function is
+// cancelled once the process receives an interrupt or a termination signal,
+// so the function can stop gracefully.
+func Run(name string, run func(ctx context.Context) error) error {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		select {
+		case received := <-signals:
+			logger.Infof("received [%v] signal; stopping [%v]", received, name)
+			cancelCtx()
+		case <-ctx.Done():
+		}
+	}()
+
+	return run(ctx)
+}
@@ -0,0 +1,84 @@
+//go:build windows
+// +build windows
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// run_windows.go file contains the integration with the Windows service
+// control manager.
+
+// Run runs the given function. If the process is started by the Windows
+// service control manager, the service state is reported to it and the
+// context passed to the function is cancelled once the service is stopped.
+func Run(name string, run func(ctx context.Context) error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("could not detect Windows service: [%v]", err)
+	}
+
+	if !isService {
+		return run(context.Background())
+	}
+
+	logger.Infof("running as Windows service [%v]", name)
+
+	handler := &windowsHandler{run: run}
+	if err := svc.Run(name, handler); err != nil {
+		return fmt.Errorf("could not run Windows service: [%v]", err)
+	}
+
+	return handler.err
+}
+
+type windowsHandler struct {
+	run func(ctx context.Context) error
+	err error
+}
+
+// Execute runs the service function and handles the control requests of
+// the service control manager.
+func (wh *windowsHandler) Execute(
+	args []string,
+	requests <-chan svc.ChangeRequest,
+	statuses chan<- svc.Status,
+) (bool, uint32) {
+	statuses <- svc.Status{State: svc.StartPending}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- wh.run(ctx)
+	}()
+
+	statuses <- svc.Status{
+		State:   svc.Running,
+		Accepts: svc.AcceptStop | svc.AcceptShutdown,
+	}
+
+	for {
+		select {
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				statuses <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				logger.Infof("stop requested by service control manager")
+				statuses <- svc.Status{State: svc.StopPending}
+				cancelCtx()
+				<-done
+				return false, 0
+			}
+		case err := <-done:
+			wh.err = err
+			return false, 1
+		}
+	}
+}
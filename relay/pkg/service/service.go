@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-log"
+)
+
+var logger = log.Logger("tbtc-relay-service")
+
+const (
+	// Default relay lag, expressed in blocks, at or below which the relay is
+	// considered caught up.
+	defaultCaughtUpLag = 6
+
+	// Maximum interval in which the service state is reported to the
+	// process supervisor.
+	maxReportInterval = 10 * time.Second
+)
+
+// Config holds the configuration of the integration with process
+// supervisors.
+type Config struct {
+	// CaughtUpLag is the relay lag, expressed in blocks, at or below which
+	// the relay is reported as ready. If zero, a default value is used.
+	CaughtUpLag int64
+
+	// DegradedPeriod is the time, in seconds, for which a synced relay is
+	// considered degraded after the headers relay raised an error. If zero,
+	// a default value is used.
+	DegradedPeriod int
+}
+
+// RelayStats exposes the relay statistics the service state is derived
+// from.
+type RelayStats interface {
+	// HeadersRelayActive returns whether the headers relay process is active.
+	HeadersRelayActive() bool
+
+	// HeadersRelayErrors returns the total number of headers relay errors.
+	HeadersRelayErrors() int
+
+	// HeadersRelayLag returns the most recently observed relay lag.
+	HeadersRelayLag() int64
+
+	// HeadersRelayLagObserved returns whether the relay lag has been
+	// observed at least once.
+	HeadersRelayLagObserved() bool
+}
+
+// Supervise reports the state of the relay to the process supervisor until
+// the context is done. The readiness state is reported as the service status
+// and readiness is reported once the relay is synced for the first time; the
+// watchdog is kicked only while the headers relay is active. It has no effect if the process is not run by a supervisor
+// supporting the systemd notification protocol.
+func Supervise(ctx context.Context, config *Config, stats RelayStats) {
+	if !notificationsEnabled() {
+		logger.Infof("service notifications are not enabled")
+		return
+	}
+
+	readiness := NewReadiness(config, stats)
+
+	interval := maxReportInterval
+	if watchdog := watchdogInterval(); watchdog > 0 && watchdog/2 < interval {
+		interval = watchdog / 2
+	}
+
+	logger.Infof("reporting service state every [%v]", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ready := false
+
+	for {
+		status := readiness.Update()
+
+		messages := []string{
+			fmt.Sprintf("STATUS=relay is %v: %v", status.State, status.Reason),
+		}
+		if status.State == StateSynced && !ready {
+			logger.Infof("relay synced; reporting readiness")
+			messages = append(messages, "READY=1")
+			ready = true
+		}
+		if stats.HeadersRelayActive() {
+			messages = append(messages, "WATCHDOG=1")
+		}
+
+		if err := notify(messages...); err != nil {
+			logger.Warnf("could not notify service supervisor: [%v]", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if err := notify("STOPPING=1"); err != nil {
+				logger.Warnf("could not notify service supervisor: [%v]", err)
+			}
+			return
+		}
+	}
+}
+
+// CombineStats combines statistics of several relay targets run by the same
+// process. The combined relay is active only if all the relays are active and
+// its lag is the biggest lag of all the relays, so the process is reported as
+// ready only once all of them catch up. Errors of all the relays are summed
+// up, so the process is degraded if any of the relays fails.
+func CombineStats(stats ...RelayStats) RelayStats {
+	if len(stats) == 1 {
+		return stats[0]
+	}
+
+	return combinedStats(stats)
+}
+
+type combinedStats []RelayStats
+
+func (cs combinedStats) HeadersRelayActive() bool {
+	for _, stats := range cs {
+		if !stats.HeadersRelayActive() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cs combinedStats) HeadersRelayErrors() int {
+	errors := 0
+	for _, stats := range cs {
+		errors += stats.HeadersRelayErrors()
+	}
+
+	return errors
+}
+
+func (cs combinedStats) HeadersRelayLag() int64 {
+	var lag int64
+	for _, stats := range cs {
+		if stats.HeadersRelayLag() > lag {
+			lag = stats.HeadersRelayLag()
+		}
+	}
+
+	return lag
+}
+
+func (cs combinedStats) HeadersRelayLagObserved() bool {
+	for _, stats := range cs {
+		if !stats.HeadersRelayLagObserved() {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+type relayStats struct {
+	active      bool
+	errors      int
+	lag         int64
+	lagObserved bool
+}
+
+func (rs *relayStats) HeadersRelayActive() bool      { return rs.active }
+func (rs *relayStats) HeadersRelayErrors() int       { return rs.errors }
+func (rs *relayStats) HeadersRelayLag() int64        { return rs.lag }
+func (rs *relayStats) HeadersRelayLagObserved() bool { return rs.lagObserved }
+
+func TestReadiness(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	stats := &relayStats{}
+
+	readiness := newReadiness(&Config{}, stats, fakeClock)
+
+	var transitions []string
+	readiness.OnTransition(func(transition *Transition) {
+		transitions = append(
+			transitions,
+			transition.From.String()+"->"+transition.To.String(),
+		)
+	})
+
+	var steps = []struct {
+		description   string
+		update        func()
+		expectedState State
+	}{
+		{
+			description:   "relay not active",
+			update:        func() {},
+			expectedState: StateStarting,
+		},
+		{
+			description:   "lag not observed yet",
+			update:        func() { stats.active = true },
+			expectedState: StateStarting,
+		},
+		{
+			description: "lag above threshold",
+			update: func() {
+				stats.lag = 7
+				stats.lagObserved = true
+			},
+			expectedState: StateSyncing,
+		},
+		{
+			description:   "lag at threshold",
+			update:        func() { stats.lag = 6 },
+			expectedState: StateSynced,
+		},
+		{
+			description:   "relay error",
+			update:        func() { stats.errors++ },
+			expectedState: StateDegraded,
+		},
+		{
+			description:   "degraded period passed",
+			update:        func() { fakeClock.Advance(10 * time.Minute) },
+			expectedState: StateSynced,
+		},
+		{
+			description:   "lag above threshold once synced",
+			update:        func() { stats.lag = 7 },
+			expectedState: StateDegraded,
+		},
+		{
+			description:   "lag back at threshold",
+			update:        func() { stats.lag = 1 },
+			expectedState: StateSynced,
+		},
+		{
+			description:   "relay not active once synced",
+			update:        func() { stats.active = false },
+			expectedState: StateDegraded,
+		},
+	}
+
+	for _, step := range steps {
+		step.update()
+
+		if state := readiness.Update().State; state != step.expectedState {
+			t.Errorf(
+				"unexpected state after [%v]:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				step.description,
+				step.expectedState,
+				state,
+			)
+		}
+	}
+
+	expectedTransitions := []string{
+		"starting->syncing",
+		"syncing->synced",
+		"synced->degraded",
+		"degraded->synced",
+		"synced->degraded",
+		"degraded->synced",
+		"synced->degraded",
+	}
+	if !reflect.DeepEqual(expectedTransitions, transitions) {
+		t.Errorf(
+			"unexpected transitions:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedTransitions,
+			transitions,
+		)
+	}
+}
+
+func TestCombineStats(t *testing.T) {
+	stats := CombineStats(
+		&relayStats{active: true, lag: 2, lagObserved: true},
+		&relayStats{active: true, errors: 1, lag: 9, lagObserved: true},
+	)
+
+	if !stats.HeadersRelayActive() || stats.HeadersRelayLag() != 9 ||
+		stats.HeadersRelayErrors() != 1 {
+		t.Errorf(
+			"unexpected combined stats:\n"+
+				"expected: [active, lag 9, 1 error]\n"+
+				"actual:   [active %v, lag %v, %v errors]\n",
+			stats.HeadersRelayActive(),
+			stats.HeadersRelayLag(),
+			stats.HeadersRelayErrors(),
+		)
+	}
+
+	stats = CombineStats(
+		&relayStats{active: true, lag: 2, lagObserved: true},
+		&relayStats{lag: 0, lagObserved: true},
+	)
+
+	if stats.HeadersRelayActive() {
+		t.Errorf("combined relay should not be active if any relay is not")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+
+	conn, err := net.ListenUnixgram(
+		"unixgram",
+		&net.UnixAddr{Name: socket, Net: "unixgram"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := os.Setenv(notifySocketEnvVariable, socket); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(notifySocketEnvVariable)
+
+	if err := notify("READY=1", "WATCHDOG=1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := make([]byte, 1024)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte("READY=1\nWATCHDOG=1")
+	if !reflect.DeepEqual(expected, buffer[:n]) {
+		t.Errorf(
+			"unexpected notification:\n"+
+				"expected: [%s]\n"+
+				"actual:   [%s]\n",
+			expected,
+			buffer[:n],
+		)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	if err := os.Setenv(watchdogEnvVariable, "30000000"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(watchdogEnvVariable)
+
+	if interval := watchdogInterval(); interval != 30*time.Second {
+		t.Errorf(
+			"unexpected watchdog interval:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			30*time.Second,
+			interval,
+		)
+	}
+}
@@ -0,0 +1,269 @@
+package slo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/events"
+)
+
+// slo.go file contains the tracker of the headers relay latency service level
+// objective. The latency of a Bitcoin block is the time from its header
+// timestamp to the confirmation of the push of the header on the host chain.
+// The objective requires the given share of blocks to be relayed within the
+// latency threshold. Blocks relayed later consume the error budget, i.e. the
+// share of blocks allowed to miss the threshold, and the burn rate tells how
+// many times faster than sustainable the budget is consumed in a window.
+
+var logger = log.Logger("tbtc-relay-slo")
+
+const (
+	// Default share of blocks, in percent, which should be relayed within
+	// the latency threshold.
+	defaultTarget = 95
+
+	// Default latency threshold, in minutes.
+	defaultThreshold = 30
+
+	// Default period, in days, the objective compliance is computed over.
+	defaultPeriod = 30
+)
+
+// Windows the error budget burn rate is reported over.
+const (
+	BurnRateWindowShort  = time.Hour
+	BurnRateWindowMedium = 6 * time.Hour
+	BurnRateWindowLong   = 3 * 24 * time.Hour
+)
+
+// FastBurnRate is the burn rate in the short window above which a warning is
+// logged. Sustained for an hour, it consumes 2% of a 30 days error budget.
+const FastBurnRate = 14.4
+
+// Config holds the configuration of the relay latency objective.
+type Config struct {
+	// Target is the share of Bitcoin blocks, in percent, which should be
+	// relayed within the threshold. If zero, a default value is used.
+	Target float64
+
+	// Threshold is the latency, in minutes, from the block header timestamp
+	// to the confirmation of its push on the host chain a block should be
+	// relayed within. If zero, a default value is used.
+	Threshold int
+
+	// Period is the number of days the objective compliance is computed
+	// over. If zero, a default value is used.
+	Period int
+}
+
+// sample is the relay latency of a single Bitcoin block.
+type sample struct {
+	height      int64
+	confirmedAt time.Time
+	met         bool
+}
+
+// Tracker tracks the compliance with the relay latency objective and the
+// consumption of its error budget.
+type Tracker struct {
+	target    float64
+	threshold time.Duration
+	period    time.Duration
+	clock     clock.Clock
+
+	mutex       sync.RWMutex
+	samples     []*sample
+	heights     map[int64]bool
+	fastBurning bool
+}
+
+// NewTracker creates a new relay latency objective tracker.
+func NewTracker(config *Config) *Tracker {
+	target := config.Target
+	if target <= 0 || target >= 100 {
+		target = defaultTarget
+	}
+
+	threshold := config.Threshold
+	if threshold <= 0 {
+		threshold = defaultThreshold
+	}
+
+	period := config.Period
+	if period <= 0 {
+		period = defaultPeriod
+	}
+
+	return &Tracker{
+		target:    target / 100,
+		threshold: time.Duration(threshold) * time.Minute,
+		period:    time.Duration(period) * 24 * time.Hour,
+		clock:     clock.System,
+		heights:   make(map[int64]bool),
+	}
+}
+
+// Observe records the latency of the headers of batches confirmed on the
+// host chain, as published on the given bus, until the context is done.
+func (t *Tracker) Observe(ctx context.Context, bus *events.Bus) {
+	unsubscribe := bus.Subscribe(
+		"slo",
+		func(event *events.Event) {
+			t.Record(event.Headers, event.Time)
+		},
+		events.TopicBatchConfirmed,
+	)
+
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+	}()
+}
+
+// Record records the latency of the given headers confirmed on the host
+// chain at the given time. Headers already recorded within the period, e.g.
+// pushed again after a reorg, are ignored.
+func (t *Tracker) Record(headers []*btc.Header, confirmedAt time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, header := range headers {
+		if t.heights[header.Height] {
+			continue
+		}
+
+		timestamp, err := header.Timestamp()
+		if err != nil {
+			logger.Warnf(
+				"could not get timestamp of block [%v]: [%v]",
+				header.Height,
+				err,
+			)
+			continue
+		}
+
+		latency := confirmedAt.Sub(timestamp)
+		met := latency <= t.threshold
+
+		if !met {
+			logger.Infof(
+				"block [%v] relayed in [%v], exceeding the latency "+
+					"objective threshold [%v]",
+				header.Height,
+				latency.Round(time.Second),
+				t.threshold,
+			)
+		}
+
+		t.samples = append(t.samples, &sample{
+			height:      header.Height,
+			confirmedAt: confirmedAt,
+			met:         met,
+		})
+		t.heights[header.Height] = true
+	}
+
+	t.prune()
+
+	burnRate := t.burnRate(BurnRateWindowShort)
+	if burnRate > FastBurnRate && !t.fastBurning {
+		logger.Warnf(
+			"relay latency error budget burns [%.1f] times faster than "+
+				"sustainable in the last [%v]",
+			burnRate,
+			BurnRateWindowShort,
+		)
+	}
+	t.fastBurning = burnRate > FastBurnRate
+}
+
+// prune drops samples which fell out of both the period and the longest burn
+// rate window. Must be called with the mutex locked.
+func (t *Tracker) prune() {
+	retention := t.period
+	if retention < BurnRateWindowLong {
+		retention = BurnRateWindowLong
+	}
+
+	cutoff := t.clock.Now().Add(-retention)
+
+	dropped := 0
+	for _, s := range t.samples {
+		if !s.confirmedAt.Before(cutoff) {
+			break
+		}
+
+		delete(t.heights, s.height)
+		dropped++
+	}
+
+	t.samples = t.samples[dropped:]
+}
+
+// errorRatio returns the share of blocks which missed the threshold among
+// the blocks confirmed in the given window, and the number of those blocks.
+// Must be called with the mutex locked.
+func (t *Tracker) errorRatio(window time.Duration) (float64, int) {
+	cutoff := t.clock.Now().Add(-window)
+
+	total := 0
+	missed := 0
+	for _, s := range t.samples {
+		if s.confirmedAt.Before(cutoff) {
+			continue
+		}
+
+		total++
+		if !s.met {
+			missed++
+		}
+	}
+
+	if total == 0 {
+		return 0, 0
+	}
+
+	return float64(missed) / float64(total), total
+}
+
+// burnRate returns the burn rate of the error budget in the given window.
+// Must be called with the mutex locked.
+func (t *Tracker) burnRate(window time.Duration) float64 {
+	ratio, _ := t.errorRatio(window)
+	return ratio / (1 - t.target)
+}
+
+// Target returns the share of blocks, from 0 to 1, which should be relayed
+// within the threshold.
+func (t *Tracker) Target() float64 {
+	return t.target
+}
+
+// Compliance returns the share of blocks, from 0 to 1, relayed within the
+// threshold during the period. If no block was relayed during the period,
+// the objective is considered met.
+func (t *Tracker) Compliance() float64 {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	ratio, total := t.errorRatio(t.period)
+	if total == 0 {
+		return 1
+	}
+
+	return 1 - ratio
+}
+
+// BurnRate returns how many times faster than sustainable over the period
+// the error budget was consumed in the given window. A burn rate of 1
+// consumes exactly the whole budget by the end of the period.
+func (t *Tracker) BurnRate(window time.Duration) float64 {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.burnRate(window)
+}
@@ -0,0 +1,82 @@
+package slo
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+)
+
+func newHeader(t *testing.T, height int64, timestamp time.Time) *btc.Header {
+	var raw bytes.Buffer
+	if err := (&wire.BlockHeader{Timestamp: timestamp}).Serialize(
+		&raw,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	return &btc.Header{Height: height, Raw: raw.Bytes()}
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	fakeClock := clock.NewFake(now)
+
+	tracker := NewTracker(&Config{Target: 90, Threshold: 30, Period: 7})
+	tracker.clock = fakeClock
+
+	if compliance := tracker.Compliance(); compliance != 1 {
+		t.Errorf("unexpected compliance without blocks: [%v]", compliance)
+	}
+
+	// Nine blocks relayed in time two days ago.
+	for height := int64(1); height <= 9; height++ {
+		confirmedAt := now.Add(-48 * time.Hour)
+		tracker.Record(
+			[]*btc.Header{
+				newHeader(t, height, confirmedAt.Add(-20*time.Minute)),
+			},
+			confirmedAt,
+		)
+	}
+
+	// One block relayed late in the last hour, recorded twice.
+	late := newHeader(t, 10, now.Add(-time.Hour))
+	tracker.Record([]*btc.Header{late}, now.Add(-10*time.Minute))
+	tracker.Record([]*btc.Header{late}, now)
+
+	assertEqual := func(description string, expected, actual float64) {
+		if math.Abs(expected-actual) > 1e-9 {
+			t.Errorf(
+				"unexpected %v:\n"+
+					"expected: [%v]\n"+
+					"actual:   [%v]\n",
+				description,
+				expected,
+				actual,
+			)
+		}
+	}
+
+	assertEqual("compliance", 0.9, tracker.Compliance())
+	// All blocks of the last hour missed the threshold, consuming the 10%
+	// budget ten times faster than sustainable.
+	assertEqual("short burn rate", 10, tracker.BurnRate(BurnRateWindowShort))
+	assertEqual("long burn rate", 1, tracker.BurnRate(BurnRateWindowLong))
+
+	// Once the late block falls out of the period, the objective is met
+	// again and nothing is consumed.
+	fakeClock.Advance(8 * 24 * time.Hour)
+	tracker.Record(nil, fakeClock.Now())
+
+	assertEqual("compliance", 1, tracker.Compliance())
+	assertEqual("short burn rate", 0, tracker.BurnRate(BurnRateWindowShort))
+
+	if len(tracker.samples) != 0 || len(tracker.heights) != 0 {
+		t.Errorf("samples out of the period not pruned")
+	}
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+const checkpointName = "checkpoint"
+
+// Checkpoint represents the most recent header which has been pushed to the
+// host chain and reached the required finality depth there.
+type Checkpoint struct {
+	// Height is the Bitcoin height of the checkpoint header.
+	Height int64
+	// Digest is the digest of the checkpoint header.
+	Digest btc.Digest
+	// HostBlock is the host chain block at which the checkpoint header has
+	// been confirmed as final.
+	HostBlock uint64
+}
+
+// LoadCheckpoint returns the stored checkpoint or nil if no checkpoint has
+// been stored so far.
+func (s *Store) LoadCheckpoint() (*Checkpoint, error) {
+	checkpoint := &Checkpoint{}
+
+	ok, err := s.get(checkpointName, checkpoint)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// SaveCheckpoint stores the given checkpoint replacing the previous one.
+func (s *Store) SaveCheckpoint(checkpoint *Checkpoint) error {
+	return s.put(checkpointName, checkpoint)
+}
+
+// DeleteCheckpoint removes the stored checkpoint, e.g. once the host chain
+// lost the checkpoint header.
+func (s *Store) DeleteCheckpoint() error {
+	return s.remove(checkpointName)
+}
@@ -0,0 +1,26 @@
+package store
+
+const gasUsageName = "gas-usage"
+
+// GasUsageSamples holds the gas used per header by the recent pushes of this
+// relay maintainer, keyed by the number of headers pushed at once.
+type GasUsageSamples map[int][]float64
+
+// LoadGasUsageSamples returns the stored gas usage samples or nil if no
+// samples have been stored so far.
+func (s *Store) LoadGasUsageSamples() (GasUsageSamples, error) {
+	samples := make(GasUsageSamples)
+
+	ok, err := s.get(gasUsageName, &samples)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// SaveGasUsageSamples stores the given gas usage samples replacing the
+// previous ones.
+func (s *Store) SaveGasUsageSamples(samples GasUsageSamples) error {
+	return s.put(gasUsageName, samples)
+}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+const (
+	journalName = "journal"
+
+	// Default maximum number of confirmed and abandoned entries kept in
+	// the journal. Older entries are removed once the limit is exceeded.
+	defaultJournalMaxEntries = 500
+)
+
+// BatchID is a deterministic identifier of a headers batch derived from its
+// first and last digest.
+type BatchID string
+
+// NewBatchID computes the identifier of the batch with the given first and
+// last header digests.
+func NewBatchID(firstDigest btc.Digest, lastDigest btc.Digest) BatchID {
+	hash := sha256.Sum256(append(firstDigest[:], lastDigest[:]...))
+	return BatchID(hex.EncodeToString(hash[:]))
+}
+
+// BatchStatus is the status of a journaled batch.
+type BatchStatus string
+
+const (
+	// BatchSubmitted is the status of a batch whose submission to the host
+	// chain has been started but not confirmed yet.
+	BatchSubmitted BatchStatus = "submitted"
+
+	// BatchConfirmed is the status of a batch known by the host chain.
+	BatchConfirmed BatchStatus = "confirmed"
+
+	// BatchAbandoned is the status of a batch whose submission has been
+	// cancelled as it was not mined before the push deadline. Such a batch
+	// can be resubmitted right away.
+	BatchAbandoned BatchStatus = "abandoned"
+)
+
+// JournalEntry records the submission of a single headers batch.
+type JournalEntry struct {
+	ID          BatchID
+	FirstHeight int64
+	LastHeight  int64
+	LastDigest  btc.Digest
+	Status      BatchStatus
+	// SubmissionBlock is the host chain block at which the submission has
+	// been started.
+	SubmissionBlock uint64
+	UpdatedAt       time.Time
+}
+
+// LoadJournalEntry returns the journal entry of the given batch or nil if
+// the batch has not been journaled.
+func (s *Store) LoadJournalEntry(id BatchID) (*JournalEntry, error) {
+	journal, err := s.loadJournal()
+	if err != nil {
+		return nil, err
+	}
+
+	return journal[id], nil
+}
+
+// SaveJournalEntry stores the given journal entry replacing the previous
+// entry of the same batch.
+func (s *Store) SaveJournalEntry(entry *JournalEntry) error {
+	s.journalMutex.Lock()
+	defer s.journalMutex.Unlock()
+
+	journal, err := s.loadJournal()
+	if err != nil {
+		return err
+	}
+
+	journal[entry.ID] = entry
+
+	s.pruneJournal(journal, time.Now())
+
+	return s.put(journalName, journal)
+}
+
+// AbandonSubmittedEntries marks all journal entries of submitted batches as
+// abandoned and returns their number.
+func (s *Store) AbandonSubmittedEntries() (int, error) {
+	s.journalMutex.Lock()
+	defer s.journalMutex.Unlock()
+
+	journal, err := s.loadJournal()
+	if err != nil {
+		return 0, err
+	}
+
+	abandoned := 0
+	for _, entry := range journal {
+		if entry.Status == BatchSubmitted {
+			entry.Status = BatchAbandoned
+			entry.UpdatedAt = time.Now()
+			abandoned++
+		}
+	}
+
+	if abandoned == 0 {
+		return 0, nil
+	}
+
+	s.pruneJournal(journal, time.Now())
+
+	return abandoned, s.put(journalName, journal)
+}
+
+// ArchiveJournal moves all journal entries to a separate archive named after
+// the given operator address and the given time, and starts an empty journal.
+// It is called when the operator account is rotated, so the submissions of
+// the new account do not wait for the batches submitted by the old one.
+// Returns the name of the archive.
+func (s *Store) ArchiveJournal(
+	operatorAddress string,
+	archivedAt time.Time,
+) (string, error) {
+	s.journalMutex.Lock()
+	defer s.journalMutex.Unlock()
+
+	journal, err := s.loadJournal()
+	if err != nil {
+		return "", err
+	}
+
+	archiveName := fmt.Sprintf(
+		"%v-%v-%v",
+		journalName,
+		strings.ToLower(operatorAddress),
+		archivedAt.Unix(),
+	)
+
+	if err := s.put(archiveName, journal); err != nil {
+		return "", err
+	}
+
+	if err := s.put(
+		journalName,
+		make(map[BatchID]*JournalEntry),
+	); err != nil {
+		return "", err
+	}
+
+	return archiveName, nil
+}
+
+// ReadJournal reads the journal file persisted in the data directory of
+// a relay store, e.g. one copied from a production deployment. Entries are
+// returned in the order of their first header height.
+func ReadJournal(path string) ([]*JournalEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read journal [%v]: [%v]", path, err)
+	}
+
+	journal := make(map[BatchID]*JournalEntry)
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf(
+			"could not unmarshal journal [%v]: [%v]",
+			path,
+			err,
+		)
+	}
+
+	entries := make([]*JournalEntry, 0, len(journal))
+	for _, entry := range journal {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FirstHeight < entries[j].FirstHeight
+	})
+
+	return entries, nil
+}
+
+func (s *Store) loadJournal() (map[BatchID]*JournalEntry, error) {
+	journal := make(map[BatchID]*JournalEntry)
+
+	if _, err := s.get(journalName, &journal); err != nil {
+		return nil, err
+	}
+
+	return journal, nil
+}
+
+// PruneJournal removes the confirmed and abandoned journal entries exceeding
+// the retention policy of the store.
+func (s *Store) PruneJournal(now time.Time) error {
+	s.journalMutex.Lock()
+	defer s.journalMutex.Unlock()
+
+	journal, err := s.loadJournal()
+	if err != nil {
+		return err
+	}
+
+	pruned := s.pruneJournal(journal, now)
+	if pruned == 0 {
+		return nil
+	}
+
+	logger.Infof("pruned [%v] journal entries", pruned)
+
+	return s.put(journalName, journal)
+}
+
+// pruneJournal removes the confirmed and abandoned entries older than the
+// journal retention period and the oldest ones exceeding the maximum number
+// of entries. Submitted entries are never removed as they guard against
+// resubmission. Returns the number of removed entries.
+func (s *Store) pruneJournal(
+	journal map[BatchID]*JournalEntry,
+	now time.Time,
+) int {
+	confirmed := make([]*JournalEntry, 0)
+	for _, entry := range journal {
+		if entry.Status != BatchSubmitted {
+			confirmed = append(confirmed, entry)
+		}
+	}
+
+	sort.Slice(confirmed, func(i, j int) bool {
+		return confirmed[i].UpdatedAt.Before(confirmed[j].UpdatedAt)
+	})
+
+	pruned := 0
+	if len(confirmed) > s.journalMaxEntries {
+		pruned = len(confirmed) - s.journalMaxEntries
+	}
+
+	if s.journalRetention > 0 {
+		for pruned < len(confirmed) &&
+			now.Sub(confirmed[pruned].UpdatedAt) > s.journalRetention {
+			pruned++
+		}
+	}
+
+	for _, entry := range confirmed[:pruned] {
+		delete(journal, entry.ID)
+	}
+
+	return pruned
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/btc"
+)
+
+const (
+	reorgsName = "reorgs"
+
+	// Maximum number of reorgs kept in the reorg history. The oldest reorgs
+	// are removed once the limit is exceeded.
+	reorgsMaxEntries = 500
+)
+
+// Reorg records a single Bitcoin reorg observed by the relay, i.e. a new best
+// header set in the host chain which does not descend from the previous best
+// header.
+type Reorg struct {
+	// Depth is the number of replaced headers.
+	Depth int64
+	// AncestorHeight is the height of the last common ancestor of the
+	// replaced and the new branch.
+	AncestorHeight int64
+	// AncestorDigest is the digest of the last common ancestor.
+	AncestorDigest btc.Digest
+	// ReplacedDigests are the digests of the headers of the replaced branch,
+	// starting from the one right above the last common ancestor.
+	ReplacedDigests []btc.Digest
+	// NewBestHeight is the height of the new best header.
+	NewBestHeight int64
+	// NewBestDigest is the digest of the new best header.
+	NewBestDigest btc.Digest
+	// ObservedAt is the time at which the reorg has been observed.
+	ObservedAt time.Time
+}
+
+// ReorgStats summarizes the reorg history.
+type ReorgStats struct {
+	// Count is the number of recorded reorgs.
+	Count int
+	// MaxDepth is the depth of the deepest recorded reorg.
+	MaxDepth int64
+	// AverageDepth is the average depth of the recorded reorgs.
+	AverageDepth float64
+	// RecommendedConfirmations is the recommended number of confirmations of
+	// a proven transaction. It exceeds the depth of the deepest recorded
+	// reorg, so none of the recorded reorgs would have replaced the block of
+	// a proven transaction.
+	RecommendedConfirmations int
+}
+
+// SaveReorg adds the given reorg to the reorg history.
+func (s *Store) SaveReorg(reorg *Reorg) error {
+	s.reorgsMutex.Lock()
+	defer s.reorgsMutex.Unlock()
+
+	reorgs, err := s.LoadReorgs()
+	if err != nil {
+		return err
+	}
+
+	reorgs = append(reorgs, reorg)
+	if len(reorgs) > reorgsMaxEntries {
+		reorgs = reorgs[len(reorgs)-reorgsMaxEntries:]
+	}
+
+	return s.put(reorgsName, reorgs)
+}
+
+// LoadReorgs returns the reorg history in the order the reorgs have been
+// observed.
+func (s *Store) LoadReorgs() ([]*Reorg, error) {
+	reorgs := make([]*Reorg, 0)
+
+	if _, err := s.get(reorgsName, &reorgs); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(reorgs, func(i, j int) bool {
+		return reorgs[i].ObservedAt.Before(reorgs[j].ObservedAt)
+	})
+
+	return reorgs, nil
+}
+
+// SummarizeReorgs summarizes the given reorg history. The recommended number
+// of confirmations is never lower than the given minimum.
+func SummarizeReorgs(reorgs []*Reorg, minConfirmations int) *ReorgStats {
+	stats := &ReorgStats{
+		Count:                    len(reorgs),
+		RecommendedConfirmations: minConfirmations,
+	}
+
+	if len(reorgs) == 0 {
+		return stats
+	}
+
+	totalDepth := int64(0)
+	for _, reorg := range reorgs {
+		totalDepth += reorg.Depth
+		if reorg.Depth > stats.MaxDepth {
+			stats.MaxDepth = reorg.Depth
+		}
+	}
+
+	stats.AverageDepth = float64(totalDepth) / float64(len(reorgs))
+
+	if recommended := int(stats.MaxDepth) + 1; recommended > minConfirmations {
+		stats.RecommendedConfirmations = recommended
+	}
+
+	return stats
+}
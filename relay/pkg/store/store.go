@@ -0,0 +1,202 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log"
+)
+
+var logger = log.Logger("tbtc-relay-store")
+
+// Config holds the configuration of the local relay storage.
+type Config struct {
+	// DataDir is the directory where the relay data are persisted. If empty,
+	// the data are kept in memory only and are lost on restart.
+	DataDir string
+
+	// JournalMaxEntries is the maximum number of confirmed and abandoned
+	// batches kept in the journal. If zero, a default value is used.
+	JournalMaxEntries int
+
+	// JournalRetentionDays is the number of days for which confirmed and
+	// abandoned batches are kept in the journal. If zero, batches are
+	// removed only once the journal exceeds its maximum number of entries.
+	JournalRetentionDays int
+}
+
+// Store is a local storage of the relay data which need to survive restarts.
+// All data are kept in memory and, if the data directory is configured,
+// persisted to JSON files in that directory.
+type Store struct {
+	dataDir string
+
+	journalMaxEntries int
+	journalRetention  time.Duration
+
+	mutex sync.RWMutex
+	cache map[string][]byte
+
+	// journalMutex serializes read-modify-write updates of the journal.
+	journalMutex sync.Mutex
+
+	// reorgsMutex serializes read-modify-write updates of the reorg
+	// history.
+	reorgsMutex sync.Mutex
+
+	// webhooksMutex serializes read-modify-write updates of the webhook
+	// deliveries.
+	webhooksMutex sync.Mutex
+}
+
+// Open opens the local relay storage using the given config.
+func Open(config *Config) (*Store, error) {
+	journalMaxEntries := config.JournalMaxEntries
+	if journalMaxEntries <= 0 {
+		journalMaxEntries = defaultJournalMaxEntries
+	}
+
+	store := &Store{
+		dataDir:           config.DataDir,
+		journalMaxEntries: journalMaxEntries,
+		journalRetention: time.Duration(config.JournalRetentionDays) *
+			24 * time.Hour,
+		cache: make(map[string][]byte),
+	}
+
+	if len(store.dataDir) == 0 {
+		logger.Warnf(
+			"data directory is not configured; " +
+				"relay data will not be persisted across restarts",
+		)
+		return store, nil
+	}
+
+	if err := os.MkdirAll(store.dataDir, 0700); err != nil {
+		return nil, fmt.Errorf(
+			"could not create data directory [%v]: [%v]",
+			store.dataDir,
+			err,
+		)
+	}
+
+	logger.Infof("using data directory [%v]", store.dataDir)
+
+	return store, nil
+}
+
+// OpenMemory opens a local relay storage which keeps the data in memory only.
+func OpenMemory() *Store {
+	store, _ := Open(&Config{})
+	return store
+}
+
+// IsPersistent returns whether the data are persisted across restarts.
+func (s *Store) IsPersistent() bool {
+	return len(s.dataDir) > 0
+}
+
+// get reads the value stored under the given name and unmarshals it into the
+// given target. It returns false if there is no such value.
+func (s *Store) get(name string, target interface{}) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, ok := s.cache[name]
+	if !ok && s.IsPersistent() {
+		fileData, err := ioutil.ReadFile(s.path(name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("could not read [%v]: [%v]", name, err)
+		}
+
+		data, ok = fileData, true
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return false, fmt.Errorf("could not unmarshal [%v]: [%v]", name, err)
+	}
+
+	return true, nil
+}
+
+// put marshals the given value and stores it under the given name.
+func (s *Store) put(name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal [%v]: [%v]", name, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.IsPersistent() {
+		if err := writeFileAtomically(s.path(name), data); err != nil {
+			return fmt.Errorf("could not write [%v]: [%v]", name, err)
+		}
+	}
+
+	s.cache[name] = data
+
+	return nil
+}
+
+// remove removes the value stored under the given name, if any.
+func (s *Store) remove(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.IsPersistent() {
+		if err := os.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove [%v]: [%v]", name, err)
+		}
+	}
+
+	delete(s.cache, name)
+
+	return nil
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dataDir, name+".json")
+}
+
+// writeFileAtomically writes the data to a temporary file and renames it
+// to the target path, so the target file is never left partially written.
+func writeFileAtomically(path string, data []byte) error {
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath) // no-op if renamed successfully
+
+	if _, err := tempFile.Write(data); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Sync(); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, path)
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	webhooksName = "webhooks"
+
+	// Maximum number of undelivered webhook deliveries kept. The oldest
+	// deliveries are dropped once the limit is exceeded.
+	webhooksMaxEntries = 1000
+)
+
+// WebhookDelivery is a webhook event not yet delivered to a single endpoint.
+type WebhookDelivery struct {
+	// ID identifies the delivery.
+	ID string
+	// URL is the endpoint the event is delivered to.
+	URL string
+	// EventType is the type of the delivered event.
+	EventType string
+	// Body is the signed request body, kept verbatim so the signature stays
+	// valid across restarts.
+	Body []byte
+	// Attempts is the number of failed delivery attempts.
+	Attempts int
+	// CreatedAt is the time at which the event has been emitted.
+	CreatedAt time.Time
+	// NextAttemptAt is the time of the next delivery attempt.
+	NextAttemptAt time.Time
+}
+
+// SaveWebhookDelivery adds the given webhook delivery or replaces the one
+// with the same ID.
+func (s *Store) SaveWebhookDelivery(delivery *WebhookDelivery) error {
+	s.webhooksMutex.Lock()
+	defer s.webhooksMutex.Unlock()
+
+	deliveries, err := s.LoadWebhookDeliveries()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, existing := range deliveries {
+		if existing.ID == delivery.ID {
+			deliveries[i] = delivery
+			replaced = true
+			break
+		}
+	}
+
+	if !replaced {
+		deliveries = append(deliveries, delivery)
+	}
+
+	if len(deliveries) > webhooksMaxEntries {
+		logger.Warnf(
+			"dropping [%v] oldest undelivered webhook events",
+			len(deliveries)-webhooksMaxEntries,
+		)
+		deliveries = deliveries[len(deliveries)-webhooksMaxEntries:]
+	}
+
+	return s.put(webhooksName, deliveries)
+}
+
+// RemoveWebhookDelivery removes the webhook delivery with the given ID.
+func (s *Store) RemoveWebhookDelivery(id string) error {
+	s.webhooksMutex.Lock()
+	defer s.webhooksMutex.Unlock()
+
+	deliveries, err := s.LoadWebhookDeliveries()
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]*WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		if delivery.ID != id {
+			remaining = append(remaining, delivery)
+		}
+	}
+
+	return s.put(webhooksName, remaining)
+}
+
+// LoadWebhookDeliveries returns the undelivered webhook deliveries in the
+// order the events have been emitted.
+func (s *Store) LoadWebhookDeliveries() ([]*WebhookDelivery, error) {
+	deliveries := make([]*WebhookDelivery, 0)
+
+	if _, err := s.get(webhooksName, &deliveries); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+
+	return deliveries, nil
+}
@@ -0,0 +1,258 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/fraud"
+	"github.com/keep-network/tbtc/relay/pkg/gasusage"
+	"github.com/keep-network/tbtc/relay/pkg/logs"
+	"github.com/keep-network/tbtc/relay/pkg/node"
+)
+
+// summary.go file contains the summary of a single relay run. Once the relay
+// maintainer is requested to stop, the summary of the run, like the uptime,
+// the number of pushed headers, the gas spent, the errors by category and the
+// final relay lag, is logged and optionally appended to a file, giving the
+// operators a clean record of each run for the post-incident review.
+
+// Categories of the errors counted in the summary.
+const (
+	// ErrorsRelay counts the restarts of the headers relay due to errors.
+	ErrorsRelay = "relay"
+	// ErrorsSubmission counts the failed host chain submissions.
+	ErrorsSubmission = "submission"
+	// ErrorsGasRegression counts the pushes whose gas used per header
+	// deviated from the baseline.
+	ErrorsGasRegression = "gasRegression"
+	// ErrorsDivergence is one if the relay contract diverged from the
+	// heaviest Bitcoin chain at the end of the run.
+	ErrorsDivergence = "divergence"
+)
+
+// Config holds the configuration of the run summary.
+type Config struct {
+	// File is the path of the file the run summary is appended to, as
+	// a single line of JSON. If empty, the summary is only logged.
+	File string
+
+	// Webhook determines whether the run summary is posted to the webhook
+	// endpoints as well.
+	Webhook bool
+}
+
+// IsEnabled checks whether the run summary is recorded beyond the log, i.e.
+// appended to the file or posted to the webhook endpoints.
+func (c *Config) IsEnabled() bool {
+	return c.File != "" || c.Webhook
+}
+
+// Sources groups the statistics of the relay components a run summary is
+// made of. Components which are not enabled are nil.
+type Sources struct {
+	Node        node.Stats
+	Submissions *chain.SubmissionStats
+	GasUsage    *gasusage.Detector
+	Fraud       *fraud.Watcher
+}
+
+// Summary is the summary of a single relay run.
+type Summary struct {
+	Target        string    `json:"target,omitempty"`
+	Version       string    `json:"version"`
+	StartedAt     time.Time `json:"startedAt"`
+	StoppedAt     time.Time `json:"stoppedAt"`
+	UptimeSeconds int64     `json:"uptimeSeconds"`
+	HeadersPulled int       `json:"headersPulled"`
+	HeadersPushed int       `json:"headersPushed"`
+	Submissions   uint64    `json:"submissions"`
+	// GasTracked is set if the gas spent has been tracked. Gas is tracked
+	// only if the relay contract advances are tracked, which is always the
+	// case if the summary file or webhook is enabled.
+	GasTracked bool   `json:"gasTracked"`
+	GasUsed    uint64 `json:"gasUsed"`
+	// FeesPaid is the total fee paid for the tracked transactions,
+	// expressed in the smallest unit of the host chain currency.
+	FeesPaid string         `json:"feesPaid,omitempty"`
+	Errors   map[string]int `json:"errors"`
+	// FinalLag is the relay lag most recently observed before the stop. It
+	// is nil if the lag has never been observed.
+	FinalLag *int64 `json:"finalLag"`
+}
+
+// Tracker tracks a single relay run. It implements the competition.Recorder
+// interface, so it can be fed with advances tracked by the competition
+// tracker to sum up the gas spent.
+type Tracker struct {
+	target    string
+	sources   *Sources
+	clock     clock.Clock
+	startedAt time.Time
+
+	mutex      sync.Mutex
+	gasTracked bool
+	gasUsed    uint64
+	feesPaid   *big.Int
+}
+
+// NewTracker starts tracking the run of the relay target with the given name
+// made of the given components.
+func NewTracker(target string, sources *Sources) *Tracker {
+	return &Tracker{
+		target:    target,
+		sources:   sources,
+		clock:     clock.System,
+		startedAt: clock.System.Now(),
+		feesPaid:  new(big.Int),
+	}
+}
+
+// RecordAdvances sums up the gas spent by the given advances made by this
+// relay maintainer.
+func (t *Tracker) RecordAdvances(advances []*competition.Advance) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.gasTracked = true
+
+	for _, advance := range advances {
+		if !advance.Own {
+			continue
+		}
+
+		t.gasUsed += advance.GasUsed
+		if advance.Fee != nil {
+			t.feesPaid.Add(t.feesPaid, advance.Fee)
+		}
+	}
+
+	return nil
+}
+
+// Summarize returns the summary of the run until now.
+func (t *Tracker) Summarize() *Summary {
+	stoppedAt := t.clock.Now()
+
+	summary := &Summary{
+		Target:        t.target,
+		Version:       build.Version,
+		StartedAt:     t.startedAt,
+		StoppedAt:     stoppedAt,
+		UptimeSeconds: int64(stoppedAt.Sub(t.startedAt).Seconds()),
+		Errors:        make(map[string]int),
+	}
+
+	t.mutex.Lock()
+	summary.GasTracked = t.gasTracked
+	summary.GasUsed = t.gasUsed
+	if t.feesPaid.Sign() > 0 {
+		summary.FeesPaid = t.feesPaid.String()
+	}
+	t.mutex.Unlock()
+
+	if nodeStats := t.sources.Node; nodeStats != nil {
+		summary.HeadersPulled = nodeStats.UniqueHeadersPulled()
+		summary.HeadersPushed = nodeStats.UniqueHeadersPushed()
+		summary.Errors[ErrorsRelay] = nodeStats.HeadersRelayErrors()
+
+		if nodeStats.HeadersRelayLagObserved() {
+			lag := nodeStats.HeadersRelayLag()
+			summary.FinalLag = &lag
+		}
+	}
+
+	if submissions := t.sources.Submissions; submissions != nil {
+		summary.Submissions = submissions.Submitted()
+		summary.Errors[ErrorsSubmission] = int(submissions.Failed())
+	}
+
+	if gasUsage := t.sources.GasUsage; gasUsage != nil {
+		summary.Errors[ErrorsGasRegression] = gasUsage.Regressions()
+	}
+
+	if fraudWatcher := t.sources.Fraud; fraudWatcher != nil {
+		summary.Errors[ErrorsDivergence] = 0
+		if fraudWatcher.Divergent() {
+			summary.Errors[ErrorsDivergence] = 1
+		}
+	}
+
+	return summary
+}
+
+// Log logs the summary to the given logger.
+func (s *Summary) Log(logger logs.Logger) {
+	categories := make([]string, 0, len(s.Errors))
+	for category := range s.Errors {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	errors := make([]string, len(categories))
+	for i, category := range categories {
+		errors[i] = fmt.Sprintf("%v: %v", category, s.Errors[category])
+	}
+
+	gasUsed := "not tracked"
+	if s.GasTracked {
+		gasUsed = fmt.Sprintf("%v", s.GasUsed)
+		if s.FeesPaid != "" {
+			gasUsed += fmt.Sprintf(" (fees paid: %v)", s.FeesPaid)
+		}
+	}
+
+	finalLag := "not observed"
+	if s.FinalLag != nil {
+		finalLag = fmt.Sprintf("%v blocks", *s.FinalLag)
+	}
+
+	run := "run"
+	if s.Target != "" {
+		run = fmt.Sprintf("run of target [%v]", s.Target)
+	}
+
+	logger.Infof(
+		"%v summary: uptime: [%v], headers pulled: [%v], "+
+			"headers pushed: [%v], submissions: [%v], gas used: [%v], "+
+			"errors: [%v], final lag: [%v]",
+		run,
+		time.Duration(s.UptimeSeconds)*time.Second,
+		s.HeadersPulled,
+		s.HeadersPushed,
+		s.Submissions,
+		gasUsed,
+		strings.Join(errors, ", "),
+		finalLag,
+	)
+}
+
+// AppendToFile appends the summary to the file with the given path as
+// a single line of JSON. The file is created if it does not exist.
+func (s *Summary) AppendToFile(path string) error {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("could not marshal summary: [%v]", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open summary file: [%v]", err)
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("could not write summary file: [%v]", err)
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,221 @@
+package summary
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/competition"
+	"github.com/keep-network/tbtc/relay/pkg/node"
+)
+
+// nodeStats is a stub of the relay node statistics.
+type nodeStats struct {
+	node.Stats
+
+	errors      int
+	pulled      int
+	pushed      int
+	lag         int64
+	lagObserved bool
+}
+
+func (ns *nodeStats) HeadersRelayErrors() int       { return ns.errors }
+func (ns *nodeStats) UniqueHeadersPulled() int      { return ns.pulled }
+func (ns *nodeStats) UniqueHeadersPushed() int      { return ns.pushed }
+func (ns *nodeStats) HeadersRelayLag() int64        { return ns.lag }
+func (ns *nodeStats) HeadersRelayLagObserved() bool { return ns.lagObserved }
+
+func newTestTracker(sources *Sources) (*Tracker, *clock.Fake) {
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+
+	tracker := NewTracker("mainnet", sources)
+	tracker.clock = fakeClock
+	tracker.startedAt = fakeClock.Now()
+
+	return tracker, fakeClock
+}
+
+func TestTrackerSummarize(t *testing.T) {
+	tracker, fakeClock := newTestTracker(&Sources{
+		Node: &nodeStats{
+			errors:      2,
+			pulled:      12,
+			pushed:      10,
+			lag:         1,
+			lagObserved: true,
+		},
+		Submissions: &chain.SubmissionStats{},
+	})
+
+	if err := tracker.RecordAdvances([]*competition.Advance{
+		{Own: true, GasUsed: 100000, Fee: big.NewInt(3000)},
+		{Own: false, GasUsed: 500000, Fee: big.NewInt(9000)},
+		{Own: true, GasUsed: 50000},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fakeClock.Advance(90 * time.Minute)
+
+	summary := tracker.Summarize()
+
+	if summary.UptimeSeconds != 5400 {
+		t.Errorf(
+			"unexpected uptime\nexpected: [%v]\nactual:   [%v]",
+			5400,
+			summary.UptimeSeconds,
+		)
+	}
+
+	if summary.HeadersPulled != 12 || summary.HeadersPushed != 10 {
+		t.Errorf(
+			"unexpected headers\nexpected: [%v %v]\nactual:   [%v %v]",
+			12,
+			10,
+			summary.HeadersPulled,
+			summary.HeadersPushed,
+		)
+	}
+
+	if !summary.GasTracked || summary.GasUsed != 150000 {
+		t.Errorf(
+			"unexpected gas used\nexpected: [%v]\nactual:   [%v]",
+			150000,
+			summary.GasUsed,
+		)
+	}
+
+	if summary.FeesPaid != "3000" {
+		t.Errorf(
+			"unexpected fees paid\nexpected: [%v]\nactual:   [%v]",
+			"3000",
+			summary.FeesPaid,
+		)
+	}
+
+	expectedErrors := map[string]int{
+		ErrorsRelay:      2,
+		ErrorsSubmission: 0,
+	}
+	if !reflect.DeepEqual(summary.Errors, expectedErrors) {
+		t.Errorf(
+			"unexpected errors\nexpected: [%v]\nactual:   [%v]",
+			expectedErrors,
+			summary.Errors,
+		)
+	}
+
+	if summary.FinalLag == nil || *summary.FinalLag != 1 {
+		t.Errorf("unexpected final lag: [%v]", summary.FinalLag)
+	}
+}
+
+func TestTrackerSummarizeWithoutSources(t *testing.T) {
+	tracker, _ := newTestTracker(&Sources{})
+
+	summary := tracker.Summarize()
+
+	if summary.GasTracked {
+		t.Errorf("gas should not be tracked")
+	}
+
+	if summary.FinalLag != nil {
+		t.Errorf("final lag should not be observed")
+	}
+
+	if len(summary.Errors) != 0 {
+		t.Errorf("unexpected errors: [%v]", summary.Errors)
+	}
+}
+
+func TestSummaryAppendToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "summary.jsonl")
+
+	for uptime := int64(1); uptime <= 2; uptime++ {
+		summary := &Summary{
+			Target:        "mainnet",
+			UptimeSeconds: uptime,
+			Errors:        map[string]int{ErrorsRelay: 0},
+		}
+
+		if err := summary.AppendToFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected number of lines: [%v]", len(lines))
+	}
+
+	for i, line := range lines {
+		summary := &Summary{}
+		if err := json.Unmarshal([]byte(line), summary); err != nil {
+			t.Fatal(err)
+		}
+
+		if summary.UptimeSeconds != int64(i+1) {
+			t.Errorf(
+				"unexpected uptime of line [%v]\nexpected: [%v]\nactual:   [%v]",
+				i,
+				i+1,
+				summary.UptimeSeconds,
+			)
+		}
+	}
+}
+
+func TestConfigIsEnabled(t *testing.T) {
+	var tests = map[string]struct {
+		config          *Config
+		expectedEnabled bool
+	}{
+		"not configured": {
+			config:          &Config{},
+			expectedEnabled: false,
+		},
+		"file": {
+			config:          &Config{File: "summary.jsonl"},
+			expectedEnabled: true,
+		},
+		"webhook": {
+			config:          &Config{Webhook: true},
+			expectedEnabled: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			enabled := test.config.IsEnabled()
+			if enabled != test.expectedEnabled {
+				t.Errorf(
+					"unexpected enabled state:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedEnabled,
+					enabled,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// dial.go file contains the dialing of the node connections over IPv4 and
+// IPv6. By default, connections are dual-stack: if the node host name
+// resolves to both IPv4 and IPv6 addresses, the addresses of the preferred
+// family are dialed first and the other family is raced after the fallback
+// delay, as described by the Happy Eyeballs algorithm (RFC 6555). Operators
+// on IPv6-only or IPv4-only infrastructure can restrict the connections to
+// a single family.
+
+// IP families of the node connections.
+const (
+	// IPFamilyDual dials both IPv4 and IPv6 addresses.
+	IPFamilyDual = "dual"
+	// IPFamilyIPv4 dials only IPv4 addresses.
+	IPFamilyIPv4 = "ipv4"
+	// IPFamilyIPv6 dials only IPv6 addresses.
+	IPFamilyIPv6 = "ipv6"
+)
+
+const (
+	// Maximum time a connection can take to be established.
+	dialTimeout = 30 * time.Second
+	// Interval between the keep-alive probes of open connections.
+	dialKeepAlive = 30 * time.Second
+)
+
+// DialFunc dials the connection to the given address.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// isDialingSet returns true if the dialing of the connections is configured.
+func (c *Config) isDialingSet() bool {
+	return c.IPFamily != "" || c.FallbackDelay != 0
+}
+
+// NewDialer returns the function dialing the connections according to the
+// configured IP family and Happy Eyeballs fallback delay.
+func NewDialer(config *Config) (DialFunc, error) {
+	family := config.IPFamily
+	if family == "" {
+		family = IPFamilyDual
+	}
+
+	switch family {
+	case IPFamilyDual, IPFamilyIPv4, IPFamilyIPv6:
+	default:
+		return nil, fmt.Errorf(
+			"unsupported IP family [%v]; use %v, %v or %v",
+			config.IPFamily,
+			IPFamilyDual,
+			IPFamilyIPv4,
+			IPFamilyIPv6,
+		)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: dialKeepAlive,
+		// Zero uses the default delay of 300 ms and a negative value
+		// disables the fallback, so the addresses are dialed one by one.
+		FallbackDelay: time.Duration(config.FallbackDelay) * time.Millisecond,
+	}
+
+	return func(
+		ctx context.Context,
+		network string,
+		address string,
+	) (net.Conn, error) {
+		if network == "tcp" {
+			switch family {
+			case IPFamilyIPv4:
+				network = "tcp4"
+			case IPFamilyIPv6:
+				network = "tcp6"
+			}
+		}
+
+		return dialer.DialContext(ctx, network, address)
+	}, nil
+}
+
+// CheckHostPort checks whether the given `host:port` address, optionally
+// followed by a path, has a valid form. IPv6 literals must be enclosed in
+// square brackets, e.g. `[::1]:8332`, as the port cannot be told apart from
+// the address otherwise.
+func CheckHostPort(address string) error {
+	hostPort := address
+	if index := strings.Index(hostPort, "/"); index >= 0 {
+		hostPort = hostPort[:index]
+	}
+
+	if strings.Count(hostPort, ":") > 1 && !strings.HasPrefix(hostPort, "[") {
+		return fmt.Errorf(
+			"IPv6 address in [%v] must be enclosed in square brackets, "+
+				"e.g. [::1]:8332",
+			address,
+		)
+	}
+
+	return nil
+}
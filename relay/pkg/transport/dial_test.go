@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewDialer_IPFamily(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	var tests = map[string]struct {
+		family      string
+		expectError bool
+	}{
+		"default": {
+			family:      "",
+			expectError: false,
+		},
+		"dual-stack": {
+			family:      IPFamilyDual,
+			expectError: false,
+		},
+		"IPv4 only": {
+			family:      IPFamilyIPv4,
+			expectError: false,
+		},
+		"IPv6 only": {
+			family:      IPFamilyIPv6,
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			dial, err := NewDialer(&Config{IPFamily: test.family})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			conn, err := dial(
+				context.Background(),
+				"tcp",
+				listener.Addr().String(),
+			)
+			if err == nil {
+				conn.Close()
+			}
+
+			actualError := err != nil
+			if test.expectError != actualError {
+				t.Errorf(
+					"unexpected dial result:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					test.expectError,
+					err,
+				)
+			}
+		})
+	}
+}
+
+func TestNewDialer_UnsupportedIPFamily(t *testing.T) {
+	if _, err := NewDialer(&Config{IPFamily: "ipv5"}); err == nil {
+		t.Fatal("expected error for unsupported IP family")
+	}
+
+	if _, err := NewClient(&Config{IPFamily: "ipv5"}, 0); err == nil {
+		t.Fatal("expected error for unsupported IP family")
+	}
+}
+
+func TestNewClient_IPv6Literal(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: [%v]", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {},
+	))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client, err := NewClient(&Config{IPFamily: IPFamilyIPv6}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server URL contains the bracketed IPv6 literal.
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+}
+
+func TestCheckHostPort(t *testing.T) {
+	var tests = map[string]bool{
+		"localhost:8332":        false,
+		"127.0.0.1:8332":        false,
+		"[::1]:8332":            false,
+		"[2001:db8::1]:8332":    false,
+		"[::1]:8332/wallet/foo": false,
+		"node.onion:8332":       false,
+		"::1:8332":              true,
+		"2001:db8::1:8332":      true,
+	}
+
+	for address, expectError := range tests {
+		t.Run(address, func(t *testing.T) {
+			err := CheckHostPort(address)
+
+			actualError := err != nil
+			if expectError != actualError {
+				t.Errorf(
+					"unexpected check result:\n"+
+						"expected error: [%v]\n"+
+						"actual error:   [%v]\n",
+					expectError,
+					err,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/build"
+	"github.com/keep-network/tbtc/relay/pkg/correlation"
+)
+
+var logger = log.Logger("tbtc-relay-transport")
+
+// tagging.go file contains the tagging of the requests sent to hosted node
+// providers. Requests carry the relay user agent, the configured provider
+// headers and, optionally, a unique request ID, so the relay usage can be
+// attributed and failed requests found in provider dashboards. Header values
+// often hold API keys and node URLs often embed them, so neither is logged
+// as is.
+
+// redacted replaces secrets in log messages.
+const redacted = "redacted"
+
+// apiKeyPattern matches URL path segments looking like API keys, e.g. the
+// project ID in `https://mainnet.infura.io/v3/<project-id>`.
+var apiKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}$`)
+
+// DefaultUserAgent returns the user agent identifying the relay and its
+// version.
+func DefaultUserAgent() string {
+	return "tbtc-relay/" + build.Version
+}
+
+// RedactedHeaders returns the names of the configured headers with their
+// values redacted, in a form suitable for logging.
+func (c *Config) RedactedHeaders() string {
+	names := make([]string, 0, len(c.Headers))
+	for name := range c.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := make([]string, len(names))
+	for i, name := range names {
+		headers[i] = fmt.Sprintf("%v=%v", name, redacted)
+	}
+
+	return strings.Join(headers, ", ")
+}
+
+// Redact replaces the values of the configured headers found in the given
+// message.
+func (c *Config) Redact(message string) string {
+	for _, value := range c.Headers {
+		if value != "" {
+			message = strings.ReplaceAll(message, value, redacted)
+		}
+	}
+
+	return message
+}
+
+// RedactURL replaces the secrets possibly embedded in the given URL: the
+// password, the query values and the path segments looking like API keys.
+// URLs without a scheme, like the `host:port` Bitcoin node addresses, are
+// redacted as well.
+func RedactURL(rawURL string) string {
+	schemeless := !strings.Contains(rawURL, "://")
+	if schemeless {
+		rawURL = "//" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+
+	if parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			parsed.User = url.UserPassword(parsed.User.Username(), redacted)
+		}
+	}
+
+	segments := strings.Split(parsed.Path, "/")
+	for i, segment := range segments {
+		if apiKeyPattern.MatchString(segment) {
+			segments[i] = redacted
+		}
+	}
+	parsed.Path = strings.Join(segments, "/")
+	parsed.RawPath = ""
+
+	query := parsed.Query()
+	for name := range query {
+		query.Set(name, redacted)
+	}
+	parsed.RawQuery = query.Encode()
+
+	if schemeless {
+		return strings.TrimPrefix(parsed.String(), "//")
+	}
+
+	return parsed.String()
+}
+
+// taggingRoundTripper adds the user agent, the configured headers and the
+// request ID to every request.
+type taggingRoundTripper struct {
+	config    *Config
+	userAgent string
+	next      http.RoundTripper
+}
+
+func newTaggingRoundTripper(
+	config *Config,
+	next http.RoundTripper,
+) *taggingRoundTripper {
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent()
+	}
+
+	if len(config.Headers) > 0 || config.RequestIDHeader != "" {
+		logger.Debugf(
+			"tagging requests with user agent [%v], headers [%v] "+
+				"and request ID header [%v]",
+			userAgent,
+			config.RedactedHeaders(),
+			config.RequestIDHeader,
+		)
+	}
+
+	return &taggingRoundTripper{
+		config:    config,
+		userAgent: userAgent,
+		next:      next,
+	}
+}
+
+func (trt *taggingRoundTripper) RoundTrip(
+	request *http.Request,
+) (*http.Response, error) {
+	// Round trippers must not modify the passed request.
+	request = request.Clone(request.Context())
+
+	request.Header.Set("User-Agent", trt.userAgent)
+	for name, value := range trt.config.Headers {
+		request.Header.Set(name, value)
+	}
+
+	requestID := ""
+	if trt.config.RequestIDHeader != "" {
+		requestID = newRequestID(request)
+		request.Header.Set(trt.config.RequestIDHeader, requestID)
+	}
+
+	response, err := trt.next.RoundTrip(request)
+	if err != nil {
+		logger.Debugf(
+			"request [%v] to [%v] failed: [%v]",
+			requestID,
+			RedactURL(request.URL.String()),
+			trt.config.Redact(err.Error()),
+		)
+		return nil, err
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		logger.Debugf(
+			"request [%v] to [%v] failed with status [%v]",
+			requestID,
+			RedactURL(request.URL.String()),
+			response.StatusCode,
+		)
+	}
+
+	return response, nil
+}
+
+// newRequestID generates a unique request ID, prefixed with the correlation
+// ID carried by the request context, if any, so the request can be tied to
+// the relay log messages.
+func newRequestID(request *http.Request) string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		// Request IDs are not critical; an ID without the random part only
+		// makes the requests harder to tell apart.
+		logger.Debugf("could not generate request ID: [%v]", err)
+	}
+
+	id := hex.EncodeToString(bytes)
+
+	correlationID := correlation.FromContext(request.Context())
+	if correlationID != "" {
+		id = fmt.Sprintf("%v-%v", correlationID, id)
+	}
+
+	return id
+}
@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// transport.go file contains the HTTP transport of the clients connecting
+// the Bitcoin and host chain nodes. It lets operators send extra headers,
+// like the API keys of node providers, route the connections through
+// a proxy and tune the TLS settings of each node endpoint separately.
+
+// Config is the configuration of the HTTP transport of a node endpoint.
+type Config struct {
+	// Headers are extra HTTP headers sent with every request, e.g.
+	// the header carrying the API key of a node provider.
+	Headers map[string]string
+
+	// Proxy is the URL of the proxy the connections are routed through.
+	// Supported schemes are `http`, `https` and `socks5`, e.g.
+	// `socks5://127.0.0.1:1080`. If empty, connections are direct.
+	Proxy string
+
+	// TLSCAFile is a path to the PEM-encoded certificates of authorities
+	// used to verify the node certificate. If empty, the system
+	// certificates are used.
+	TLSCAFile string
+
+	// TLSCertFile and TLSKeyFile are paths to the PEM-encoded certificate
+	// and private key presented to nodes requiring client certificates.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSInsecureSkipVerify disables the verification of the node
+	// certificate. Should only be used for testing.
+	TLSInsecureSkipVerify bool
+
+	// IPFamily restricts the connections to IPv4 (`ipv4`) or IPv6
+	// (`ipv6`) addresses. If empty or `dual`, both are dialed.
+	IPFamily string
+
+	// FallbackDelay is the time, in milliseconds, the dual-stack dialing
+	// waits for the preferred IP family before racing the other one. If
+	// zero, a default value is used; if negative, the addresses are dialed
+	// one by one.
+	FallbackDelay int
+
+	// UserAgent is the user agent the requests are sent with, so the
+	// usage of hosted providers can be attributed to the relay. If empty,
+	// the relay name and version are used.
+	UserAgent string
+
+	// RequestIDHeader is the name of the header carrying a unique ID of
+	// each request, e.g. `X-Request-Id`, so the requests can be found in
+	// provider dashboards. If empty, no request IDs are sent.
+	RequestIDHeader string
+}
+
+// IsSet returns true if any property of the transport is configured.
+func (c *Config) IsSet() bool {
+	return c.RequiresHTTPClient() || c.isDialingSet()
+}
+
+// RequiresHTTPClient returns true if the transport has properties applied
+// only by the relay HTTP client. Other clients can still apply the dialing
+// of the connections.
+func (c *Config) RequiresHTTPClient() bool {
+	return len(c.Headers) > 0 ||
+		c.UserAgent != "" ||
+		c.RequestIDHeader != "" ||
+		c.Proxy != "" ||
+		c.TLSCAFile != "" ||
+		c.TLSCertFile != "" ||
+		c.TLSKeyFile != "" ||
+		c.TLSInsecureSkipVerify
+}
+
+// NewClient creates an HTTP client using the configured transport. Requests
+// taking longer than the given timeout are cancelled; zero means no
+// timeout.
+func NewClient(config *Config, timeout time.Duration) (*http.Client, error) {
+	roundTripper, err := newRoundTripper(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: roundTripper, Timeout: timeout}, nil
+}
+
+func newRoundTripper(config *Config) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dial, err := NewDialer(config)
+	if err != nil {
+		return nil, err
+	}
+	transport.DialContext = dial
+
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not parse proxy URL: [%v]",
+				err,
+			)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf(
+				"unsupported proxy scheme [%v]; use http, https or socks5",
+				proxyURL.Scheme,
+			)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return newTaggingRoundTripper(config, transport), nil
+}
+
+func newTLSConfig(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// #nosec G402 (TLS InsecureSkipVerify set true)
+		// Explicitly requested by the operator for testing setups.
+		InsecureSkipVerify: config.TLSInsecureSkipVerify,
+	}
+
+	if config.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not read TLS CA file [%v]: [%v]",
+				config.TLSCAFile,
+				err,
+			)
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf(
+				"no certificates found in TLS CA file [%v]",
+				config.TLSCAFile,
+			)
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+			return nil, fmt.Errorf(
+				"both TLS certificate and key files must be configured",
+			)
+		}
+
+		certificate, err := tls.LoadX509KeyPair(
+			config.TLSCertFile,
+			config.TLSKeyFile,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not load TLS client certificate: [%v]",
+				err,
+			)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewClient_Headers(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClient(
+		&Config{Headers: map[string]string{"X-Api-Key": "secret"}},
+		0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if value := received.Get("X-Api-Key"); value != "secret" {
+		t.Errorf(
+			"unexpected header value:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			"secret",
+			value,
+		)
+	}
+
+	if request.Header.Get("X-Api-Key") != "" {
+		t.Errorf("passed request should not be modified")
+	}
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	var proxiedURL string
+	proxy := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			proxiedURL = r.URL.String()
+		},
+	))
+	defer proxy.Close()
+
+	client, err := NewClient(&Config{Proxy: proxy.URL}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Get("http://node.invalid:8332/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if proxiedURL != "http://node.invalid:8332/" {
+		t.Errorf(
+			"unexpected proxied URL:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			"http://node.invalid:8332/",
+			proxiedURL,
+		)
+	}
+}
+
+func TestNewClient_InvalidConfig(t *testing.T) {
+	var tests = map[string]struct {
+		config *Config
+	}{
+		"unsupported proxy scheme": {
+			config: &Config{Proxy: "ftp://127.0.0.1:21"},
+		},
+		"certificate without key": {
+			config: &Config{TLSCertFile: "client.crt"},
+		},
+		"missing CA file": {
+			config: &Config{TLSCAFile: "missing-ca.pem"},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if _, err := NewClient(test.config, 0); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
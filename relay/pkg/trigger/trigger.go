@@ -0,0 +1,237 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+// trigger.go file contains the trigger switching the relay into the
+// low-latency mode while tBTC users wait for their proofs. The events
+// emitted by the tBTC system are fetched periodically. Once a deposit
+// awaits the funding proof or a redemption is requested, the proof can be
+// submitted only after the relay contract learns the headers confirming the
+// Bitcoin transaction, so the relay pushes new headers as soon as they are
+// mined. Once no proofs are pending, the relay gets back to the economical
+// mode.
+
+var logger = log.Logger("tbtc-relay-trigger")
+
+const (
+	// DefaultTick is the default interval in which the tBTC system events
+	// are fetched.
+	DefaultTick = 1 * time.Minute
+
+	// DefaultInterval is the default maximum rest between pushes and
+	// between checks for new Bitcoin headers in the low-latency mode.
+	DefaultInterval = 10 * time.Second
+
+	// DefaultBatchSize is the default maximum size of pushed batches in the
+	// low-latency mode.
+	DefaultBatchSize = 1
+
+	// DefaultPendingBlocks is the default number of host chain blocks after
+	// which a proof is no longer considered pending. It spans more than the
+	// redemption proof timeout of the tBTC system.
+	DefaultPendingBlocks = 2000
+)
+
+// Maximum number of host chain blocks whose events are fetched in a single
+// query.
+const maxBlocksPerQuery = 1000
+
+// Config holds the configuration of the low-latency trigger.
+type Config struct {
+	// Enabled determines whether the relay switches into the low-latency
+	// mode while proofs are pending.
+	Enabled bool
+
+	// Tick is the interval, in seconds, in which the tBTC system events are
+	// fetched. If zero, a default value is used.
+	Tick int
+
+	// Interval is the maximum rest, in seconds, between pushes and between
+	// checks for new Bitcoin headers in the low-latency mode. If zero,
+	// a default value is used.
+	Interval int
+
+	// BatchSize is the maximum size of pushed batches in the low-latency
+	// mode. If zero, a default value is used.
+	BatchSize int
+
+	// PendingBlocks is the number of host chain blocks after which a proof
+	// is no longer considered pending, e.g. because the deposit timed out.
+	// If zero, a default value is used.
+	PendingBlocks int
+}
+
+func (c *Config) interval() time.Duration {
+	if c.Interval > 0 {
+		return time.Duration(c.Interval) * time.Second
+	}
+
+	return DefaultInterval
+}
+
+func (c *Config) batchSize() int {
+	if c.BatchSize > 0 {
+		return c.BatchSize
+	}
+
+	return DefaultBatchSize
+}
+
+func (c *Config) pendingBlocks() uint64 {
+	if c.PendingBlocks > 0 {
+		return uint64(c.PendingBlocks)
+	}
+
+	return DefaultPendingBlocks
+}
+
+// Trigger switches the relay into the low-latency mode while proofs of
+// tBTC deposits are pending.
+type Trigger struct {
+	hostChain     chain.Reader
+	control       *header.Control
+	interval      time.Duration
+	batchSize     int
+	pendingBlocks uint64
+
+	mutex     sync.RWMutex
+	nextBlock uint64
+	// pending maps the addresses of the deposits awaiting proofs to the
+	// host chain blocks they await the proofs since.
+	pending map[string]uint64
+}
+
+// New creates the trigger switching the relay controlled by the given
+// control.
+func New(
+	hostChain chain.Reader,
+	control *header.Control,
+	config *Config,
+) *Trigger {
+	return &Trigger{
+		hostChain:     hostChain,
+		control:       control,
+		interval:      config.interval(),
+		batchSize:     config.batchSize(),
+		pendingBlocks: config.pendingBlocks(),
+		pending:       make(map[string]uint64),
+	}
+}
+
+// Start starts fetching the tBTC system events in the given tick. It stops
+// once the passed context is done.
+func (t *Trigger) Start(ctx context.Context, tick time.Duration) {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			if err := t.update(ctx); err != nil {
+				logger.Warnf("could not fetch deposit events: [%v]", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				t.control.LeaveLowLatency()
+				return
+			}
+		}
+	}()
+}
+
+// update fetches the tBTC system events emitted since the last processed
+// block and switches the relay mode according to the pending proofs.
+func (t *Trigger) update(ctx context.Context) error {
+	currentBlock, err := t.hostChain.CurrentBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get current block: [%v]", err)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	fromBlock := t.nextBlock
+	if fromBlock == 0 && currentBlock > t.pendingBlocks {
+		fromBlock = currentBlock - t.pendingBlocks
+	}
+
+	for fromBlock <= currentBlock {
+		toBlock := fromBlock + maxBlocksPerQuery - 1
+		if toBlock > currentBlock {
+			toBlock = currentBlock
+		}
+
+		events, err := t.hostChain.PastDepositEvents(ctx, fromBlock, toBlock)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			t.handleEvent(event)
+		}
+
+		t.nextBlock = toBlock + 1
+		fromBlock = toBlock + 1
+	}
+
+	for depositAddress, since := range t.pending {
+		if since+t.pendingBlocks < currentBlock {
+			logger.Infof(
+				"proof of deposit [%v] pending since block [%v] "+
+					"is no longer awaited",
+				depositAddress,
+				since,
+			)
+			delete(t.pending, depositAddress)
+		}
+	}
+
+	if len(t.pending) > 0 {
+		t.control.EnterLowLatency(t.interval, t.batchSize)
+	} else {
+		t.control.LeaveLowLatency()
+	}
+
+	return nil
+}
+
+// handleEvent updates the pending proofs with the given event. Must be
+// called with the mutex held.
+func (t *Trigger) handleEvent(event *chain.DepositEvent) {
+	switch event.Type {
+	case chain.DepositEventFundingAwaited,
+		chain.DepositEventRedemptionRequested:
+		if _, ok := t.pending[event.DepositAddress]; !ok {
+			logger.Infof(
+				"deposit [%v] awaits proof since block [%v]",
+				event.DepositAddress,
+				event.BlockNumber,
+			)
+		}
+		t.pending[event.DepositAddress] = event.BlockNumber
+	case chain.DepositEventFunded, chain.DepositEventRedeemed:
+		delete(t.pending, event.DepositAddress)
+	}
+}
+
+// Pending returns the number of deposits awaiting proofs.
+func (t *Trigger) Pending() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return len(t.pending)
+}
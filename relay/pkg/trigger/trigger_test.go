@@ -0,0 +1,133 @@
+package trigger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	chainlocal "github.com/keep-network/tbtc/relay/pkg/chain/local"
+	"github.com/keep-network/tbtc/relay/pkg/header"
+)
+
+const (
+	firstDeposit  = "0x1111111111111111111111111111111111111111"
+	secondDeposit = "0x2222222222222222222222222222222222222222"
+)
+
+func TestTrigger_Update(t *testing.T) {
+	var tests = map[string]struct {
+		events       []*chain.DepositEvent
+		currentBlock uint64
+		// seenAtBlock is the block the events are fetched at first. If
+		// zero, the events are fetched at the current block only.
+		seenAtBlock        uint64
+		expectedPending    int
+		expectedLowLatency bool
+	}{
+		"no events": {
+			currentBlock:       100,
+			expectedPending:    0,
+			expectedLowLatency: false,
+		},
+		"deposit awaits funding proof": {
+			events: []*chain.DepositEvent{
+				depositEvent(chain.DepositEventFundingAwaited, firstDeposit, 90),
+			},
+			currentBlock:       100,
+			expectedPending:    1,
+			expectedLowLatency: true,
+		},
+		"deposit funded": {
+			events: []*chain.DepositEvent{
+				depositEvent(chain.DepositEventFundingAwaited, firstDeposit, 90),
+				depositEvent(chain.DepositEventFunded, firstDeposit, 95),
+			},
+			currentBlock:       100,
+			expectedPending:    0,
+			expectedLowLatency: false,
+		},
+		"redemption requested": {
+			events: []*chain.DepositEvent{
+				depositEvent(chain.DepositEventFundingAwaited, firstDeposit, 90),
+				depositEvent(chain.DepositEventFunded, firstDeposit, 95),
+				depositEvent(chain.DepositEventRedemptionRequested, secondDeposit, 97),
+			},
+			currentBlock:       100,
+			expectedPending:    1,
+			expectedLowLatency: true,
+		},
+		"pending proof expired": {
+			events: []*chain.DepositEvent{
+				depositEvent(chain.DepositEventRedemptionRequested, firstDeposit, 3000),
+			},
+			currentBlock:       5100,
+			seenAtBlock:        3000,
+			expectedPending:    0,
+			expectedLowLatency: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			handle, err := chainlocal.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+			hostChain := handle.(*chainlocal.Chain)
+			hostChain.SetDepositEvents(test.events)
+
+			control := header.NewControl(nil)
+			trigger := New(hostChain, control, &Config{})
+
+			if test.seenAtBlock > 0 {
+				hostChain.SetCurrentBlock(test.seenAtBlock)
+
+				if err := trigger.update(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+
+				if !control.IsLowLatency() {
+					t.Fatalf("low-latency mode not entered")
+				}
+			}
+
+			hostChain.SetCurrentBlock(test.currentBlock)
+
+			if err := trigger.update(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if trigger.Pending() != test.expectedPending {
+				t.Errorf(
+					"unexpected number of pending proofs:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedPending,
+					trigger.Pending(),
+				)
+			}
+
+			if control.IsLowLatency() != test.expectedLowLatency {
+				t.Errorf(
+					"unexpected low-latency mode:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedLowLatency,
+					control.IsLowLatency(),
+				)
+			}
+		})
+	}
+}
+
+func depositEvent(
+	eventType chain.DepositEventType,
+	depositAddress string,
+	blockNumber uint64,
+) *chain.DepositEvent {
+	return &chain.DepositEvent{
+		Type:           eventType,
+		DepositAddress: depositAddress,
+		BlockNumber:    blockNumber,
+	}
+}
@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/keep-network/tbtc/relay/pkg/chain"
+	"github.com/keep-network/tbtc/relay/pkg/deposit"
+	"github.com/keep-network/tbtc/relay/pkg/events"
+	"github.com/keep-network/tbtc/relay/pkg/fraud"
+	"github.com/keep-network/tbtc/relay/pkg/service"
+)
+
+// sources.go file contains the sources of the events posted to the webhook
+// endpoints: the node event bus, the deposit monitor feed, the relay contract
+// watcher, the relay readiness state and the contract pause guard. Headers
+// pulled and pushed by the relay are not posted as there are too many of
+// them; they can be streamed by the headers subscription of the operator API
+// instead.
+
+// Types of the posted events.
+const (
+	EventRetarget        = "header.retarget"
+	EventEpochEnd        = "header.epoch-end"
+	EventReorg           = "header.reorg"
+	EventFraudDivergence = "fraud.divergence"
+	EventRunSummary      = "relay.summary"
+	EventRelayError      = "relay.error"
+	EventRelayState      = "relay.state"
+	EventContractPaused  = "contract.paused"
+	EventContractResumed = "contract.unpaused"
+
+	// depositEventPrefix prefixes the deposit event types, e.g.
+	// `deposit.double-spent`.
+	depositEventPrefix = "deposit."
+)
+
+// HeaderEvent is the data of the retarget, epoch end and reorg events.
+type HeaderEvent struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+	// Epoch is set only for the retarget and epoch end events.
+	Epoch uint64 `json:"epoch,omitempty"`
+	// Reorg fields are set only for the reorg events.
+	ReorgDepth     int64    `json:"reorgDepth,omitempty"`
+	AncestorHeight int64    `json:"ancestorHeight,omitempty"`
+	AncestorHash   string   `json:"ancestorHash,omitempty"`
+	ReplacedHashes []string `json:"replacedHashes,omitempty"`
+}
+
+// ErrorEvent is the data of the relay error events.
+type ErrorEvent struct {
+	Error string `json:"error"`
+}
+
+// StateEvent is the data of the relay readiness state transition events.
+type StateEvent struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+// DepositEvent is the data of the deposit events.
+type DepositEvent struct {
+	Address         string `json:"address,omitempty"`
+	TxID            string `json:"txid"`
+	OutputIndex     uint32 `json:"outputIndex"`
+	BlockHeight     int64  `json:"blockHeight,omitempty"`
+	Value           int64  `json:"value"`
+	ConflictingTxID string `json:"conflictingTxid,omitempty"`
+}
+
+// ContractEvent is the data of the contract pause events.
+type ContractEvent struct {
+	Contract string `json:"contract"`
+}
+
+// DivergenceEvent is the data of the relay contract divergence events.
+type DivergenceEvent struct {
+	Digest      string `json:"digest"`
+	Disagreeing int    `json:"disagreeing"`
+}
+
+// ForwardRelayEvents posts the retarget, epoch end, reorg and relay error
+// events published on the given node event bus until the passed context is
+// done.
+func (d *Dispatcher) ForwardRelayEvents(ctx context.Context, bus *events.Bus) {
+	unsubscribe := bus.Subscribe(
+		"webhooks",
+		d.forwardRelayEvent,
+		events.TopicRetargetSubmitted,
+		events.TopicEpochEndApproaching,
+		events.TopicReorgDetected,
+		events.TopicErrorOccurred,
+	)
+
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+	}()
+}
+
+// forwardRelayEvent posts the given event of the node event bus.
+func (d *Dispatcher) forwardRelayEvent(event *events.Event) {
+	var eventType string
+	var data interface{}
+
+	switch event.Topic {
+	case events.TopicRetargetSubmitted:
+		eventType = EventRetarget
+		data = newHeaderEvent(event)
+	case events.TopicEpochEndApproaching:
+		eventType = EventEpochEnd
+		data = newHeaderEvent(event)
+	case events.TopicReorgDetected:
+		eventType = EventReorg
+		data = newHeaderEvent(event)
+	case events.TopicErrorOccurred:
+		eventType = EventRelayError
+		data = &ErrorEvent{Error: event.Err.Error()}
+	default:
+		return
+	}
+
+	if err := d.Send(eventType, data); err != nil {
+		logger.Warnf("could not send [%v] event: [%v]", eventType, err)
+	}
+}
+
+// newHeaderEvent creates the data of the given retarget, epoch end or reorg
+// event of the node event bus.
+func newHeaderEvent(event *events.Event) *HeaderEvent {
+	data := &HeaderEvent{
+		Height: event.Header.Height,
+		Hash:   chainhash.Hash(event.Header.Hash).String(),
+		Epoch:  event.Epoch,
+	}
+	if reorg := event.Reorg; reorg != nil {
+		data.ReorgDepth = reorg.Depth
+		data.AncestorHeight = reorg.AncestorHeight
+		data.AncestorHash = chainhash.Hash(reorg.AncestorDigest).String()
+		for _, digest := range reorg.ReplacedDigests {
+			data.ReplacedHashes = append(
+				data.ReplacedHashes,
+				chainhash.Hash(digest).String(),
+			)
+		}
+	}
+
+	return data
+}
+
+// ForwardDepositEvents posts the events of the given deposit monitor feed
+// until the passed context is done.
+func (d *Dispatcher) ForwardDepositEvents(
+	ctx context.Context,
+	feed *deposit.Feed,
+) {
+	go func() {
+		for {
+			subscription := feed.Subscribe()
+
+			cancelled := d.forwardDepositSubscription(ctx, subscription)
+			subscription.Unsubscribe()
+
+			if !cancelled {
+				return
+			}
+
+			logger.Warnf("deposit feed subscription cancelled; resubscribing")
+		}
+	}()
+}
+
+// forwardDepositSubscription posts the events of the given subscription.
+// Returns true if the subscription has been cancelled by the feed.
+func (d *Dispatcher) forwardDepositSubscription(
+	ctx context.Context,
+	subscription *deposit.Subscription,
+) bool {
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return true
+			}
+
+			data := &DepositEvent{
+				Address:     event.Address,
+				TxID:        chainhash.Hash(event.TxID).String(),
+				OutputIndex: event.OutputIndex,
+				BlockHeight: event.BlockHeight,
+				Value:       event.Value,
+			}
+			if event.ConflictingTxID != nil {
+				data.ConflictingTxID = chainhash.Hash(
+					*event.ConflictingTxID,
+				).String()
+			}
+
+			eventType := depositEventPrefix + string(event.Type)
+			if err := d.Send(eventType, data); err != nil {
+				logger.Warnf("could not send [%v] event: [%v]", eventType, err)
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// ChallengeHook returns the relay contract watcher hook posting the
+// divergence events and then calling the given hook, which may be nil.
+func (d *Dispatcher) ChallengeHook(
+	next fraud.ChallengeHook,
+) fraud.ChallengeHook {
+	return func(ctx context.Context, divergence *fraud.Divergence) error {
+		if err := d.Send(EventFraudDivergence, &DivergenceEvent{
+			Digest:      chainhash.Hash(divergence.Digest).String(),
+			Disagreeing: divergence.Disagreeing,
+		}); err != nil {
+			logger.Warnf(
+				"could not send [%v] event: [%v]",
+				EventFraudDivergence,
+				err,
+			)
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		return next(ctx, divergence)
+	}
+}
+
+// TransitionHook returns the relay readiness hook posting the state
+// transition events.
+func (d *Dispatcher) TransitionHook() service.TransitionHook {
+	return func(transition *service.Transition) {
+		if err := d.Send(EventRelayState, &StateEvent{
+			From:   transition.From.String(),
+			To:     transition.To.String(),
+			Reason: transition.Reason,
+		}); err != nil {
+			logger.Warnf(
+				"could not send [%v] event: [%v]",
+				EventRelayState,
+				err,
+			)
+		}
+	}
+}
+
+// PauseHook returns the contract pause hook posting the contract paused and
+// unpaused events.
+func (d *Dispatcher) PauseHook() chain.PauseHook {
+	return func(contract string, paused bool) {
+		eventType := EventContractResumed
+		if paused {
+			eventType = EventContractPaused
+		}
+
+		if err := d.Send(
+			eventType,
+			&ContractEvent{Contract: contract},
+		); err != nil {
+			logger.Warnf("could not send [%v] event: [%v]", eventType, err)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+// webhook.go file contains the dispatcher of outbound webhooks. Events, like
+// reorgs, deposit events or relay contract divergences, are posted as JSON
+// to the configured endpoints. Each request body is signed with HMAC-SHA256
+// using the shared secret, so endpoints can verify the sender. Undelivered
+// events are kept in the relay store and retried with an exponential backoff,
+// also across restarts, so downstream automation can rely on the delivery.
+
+var logger = log.Logger("tbtc-relay-webhook")
+
+const (
+	// DefaultMaxAttempts is the default number of attempts after which an
+	// undelivered event is dropped.
+	DefaultMaxAttempts = 10
+
+	// DefaultTimeout is the default maximum time of a single delivery
+	// request.
+	DefaultTimeout = 10 * time.Second
+
+	// SignatureHeader is the request header carrying the signature of the
+	// request body, in the `sha256=<hex>` format.
+	SignatureHeader = "X-Relay-Signature"
+
+	// EventTypeHeader is the request header carrying the event type.
+	EventTypeHeader = "X-Relay-Event"
+
+	// DeliveryHeader is the request header carrying the delivery ID, which
+	// stays the same across the delivery attempts.
+	DeliveryHeader = "X-Relay-Delivery"
+
+	// Back-off time applied after the first failed delivery attempt. It is
+	// doubled on each subsequent failure.
+	retryBackoffTime = 5 * time.Second
+
+	// Maximum back-off time between delivery attempts.
+	maxRetryBackoffTime = 10 * time.Minute
+
+	// Interval in which the deliveries due are attempted.
+	deliveryCheckInterval = time.Second
+)
+
+// Config holds the configuration of the outbound webhooks.
+type Config struct {
+	// URLs are the endpoints every event is posted to. If empty, webhooks
+	// are disabled.
+	URLs []string
+
+	// Secret is the shared secret used to sign the request bodies. If
+	// empty, requests are not signed.
+	Secret string
+
+	// Events are the types of the posted events, e.g. `header.reorg`. If
+	// empty, events of all types are posted.
+	Events []string
+
+	// MaxAttempts is the number of attempts after which an undelivered
+	// event is dropped. If zero, a default value is used.
+	MaxAttempts int
+
+	// Timeout is the maximum time, in seconds, of a single delivery
+	// request. If zero, a default value is used.
+	Timeout int
+}
+
+// IsEnabled returns whether webhooks are enabled.
+func (c *Config) IsEnabled() bool {
+	return len(c.URLs) > 0
+}
+
+// Message is the body of a single webhook request.
+type Message struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher posts events to the webhook endpoints.
+type Dispatcher struct {
+	urls        []string
+	secret      string
+	events      map[string]bool
+	maxAttempts int
+	client      *http.Client
+	store       *store.Store
+	clock       clock.Clock
+
+	wake chan struct{}
+}
+
+// NewDispatcher creates the webhook dispatcher keeping the undelivered
+// events in the given relay store.
+func NewDispatcher(config *Config, relayStore *store.Store) *Dispatcher {
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	timeout := DefaultTimeout
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+
+	events := make(map[string]bool, len(config.Events))
+	for _, eventType := range config.Events {
+		events[eventType] = true
+	}
+
+	return &Dispatcher{
+		urls:        config.URLs,
+		secret:      config.Secret,
+		events:      events,
+		maxAttempts: maxAttempts,
+		client:      &http.Client{Timeout: timeout},
+		store:       relayStore,
+		clock:       clock.System,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Start starts delivering the events. Events left undelivered before the
+// restart are delivered as well. It stops once the passed context is done.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		for {
+			d.deliverDue(ctx)
+
+			select {
+			case <-d.clock.After(deliveryCheckInterval):
+			case <-d.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Send queues the event of the given type with the given data for the
+// delivery to all endpoints. Events of the types not configured are
+// ignored.
+func (d *Dispatcher) Send(eventType string, data interface{}) error {
+	if len(d.events) > 0 && !d.events[eventType] {
+		return nil
+	}
+
+	id, err := newID()
+	if err != nil {
+		return fmt.Errorf("could not generate event ID: [%v]", err)
+	}
+
+	createdAt := d.clock.Now()
+
+	body, err := json.Marshal(&Message{
+		ID:        id,
+		Type:      eventType,
+		CreatedAt: createdAt,
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal event: [%v]", err)
+	}
+
+	for i, url := range d.urls {
+		if err := d.store.SaveWebhookDelivery(&store.WebhookDelivery{
+			ID:            fmt.Sprintf("%v-%v", id, i),
+			URL:           url,
+			EventType:     eventType,
+			Body:          body,
+			CreatedAt:     createdAt,
+			NextAttemptAt: createdAt,
+		}); err != nil {
+			return fmt.Errorf("could not save webhook delivery: [%v]", err)
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Flush attempts the deliveries due right away, e.g. of the events sent on
+// the relay shutdown, once the dispatcher has been stopped. Undelivered
+// events are kept and retried after the restart.
+func (d *Dispatcher) Flush(ctx context.Context) {
+	d.deliverDue(ctx)
+}
+
+// deliverDue attempts the deliveries whose next attempt time has come.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	deliveries, err := d.store.LoadWebhookDeliveries()
+	if err != nil {
+		logger.Warnf("could not load webhook deliveries: [%v]", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if d.clock.Now().Before(delivery.NextAttemptAt) {
+			continue
+		}
+
+		err := d.deliver(ctx, delivery)
+		if err == nil {
+			logger.Debugf(
+				"delivered [%v] event [%v] to [%v]",
+				delivery.EventType,
+				delivery.ID,
+				delivery.URL,
+			)
+			d.remove(delivery)
+			continue
+		}
+
+		delivery.Attempts++
+
+		if delivery.Attempts >= d.maxAttempts {
+			logger.Errorf(
+				"dropping [%v] event [%v] to [%v] after [%v] attempts: [%v]",
+				delivery.EventType,
+				delivery.ID,
+				delivery.URL,
+				delivery.Attempts,
+				err,
+			)
+			d.remove(delivery)
+			continue
+		}
+
+		backoff := retryBackoff(delivery.Attempts)
+
+		logger.Warnf(
+			"could not deliver [%v] event [%v] to [%v]; "+
+				"retrying in [%v]: [%v]",
+			delivery.EventType,
+			delivery.ID,
+			delivery.URL,
+			backoff,
+			err,
+		)
+
+		delivery.NextAttemptAt = d.clock.Now().Add(backoff)
+		if err := d.store.SaveWebhookDelivery(delivery); err != nil {
+			logger.Warnf("could not save webhook delivery: [%v]", err)
+		}
+	}
+}
+
+func (d *Dispatcher) remove(delivery *store.WebhookDelivery) {
+	if err := d.store.RemoveWebhookDelivery(delivery.ID); err != nil {
+		logger.Warnf("could not remove webhook delivery: [%v]", err)
+	}
+}
+
+// deliver posts the given delivery to its endpoint.
+func (d *Dispatcher) deliver(
+	ctx context.Context,
+	delivery *store.WebhookDelivery,
+) error {
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		delivery.URL,
+		bytes.NewReader(delivery.Body),
+	)
+	if err != nil {
+		return fmt.Errorf("could not create request: [%v]", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventTypeHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	if d.secret != "" {
+		request.Header.Set(SignatureHeader, Sign(d.secret, delivery.Body))
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status [%v]", response.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the signature of the given request body computed with the
+// given secret, in the format of the signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryBackoff returns the back-off time after the given number of failed
+// attempts.
+func retryBackoff(attempts int) time.Duration {
+	backoff := retryBackoffTime
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxRetryBackoffTime {
+			return maxRetryBackoffTime
+		}
+	}
+
+	return backoff
+}
+
+func newID() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
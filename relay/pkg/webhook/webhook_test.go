@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/tbtc/relay/pkg/clock"
+	"github.com/keep-network/tbtc/relay/pkg/store"
+)
+
+const testSecret = "secret"
+
+// endpoint is a webhook endpoint recording the received requests.
+type endpoint struct {
+	mutex    sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	e.requests = append(e.requests, r)
+	e.bodies = append(e.bodies, body)
+
+	w.WriteHeader(e.status)
+}
+
+func newTestDispatcher(
+	url string,
+	relayStore *store.Store,
+	fakeClock *clock.Fake,
+) *Dispatcher {
+	dispatcher := NewDispatcher(
+		&Config{URLs: []string{url}, Secret: testSecret, MaxAttempts: 3},
+		relayStore,
+	)
+	dispatcher.clock = fakeClock
+
+	return dispatcher
+}
+
+func TestDispatcher_Delivers(t *testing.T) {
+	endpoint := &endpoint{status: http.StatusOK}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	relayStore := store.OpenMemory()
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	dispatcher := newTestDispatcher(server.URL, relayStore, fakeClock)
+
+	if err := dispatcher.Send(EventReorg, &HeaderEvent{Height: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher.deliverDue(context.Background())
+
+	if len(endpoint.requests) != 1 {
+		t.Fatalf("unexpected number of requests: [%v]", len(endpoint.requests))
+	}
+
+	request := endpoint.requests[0]
+	body := endpoint.bodies[0]
+
+	if request.Header.Get(SignatureHeader) != Sign(testSecret, body) {
+		t.Errorf(
+			"unexpected signature:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			Sign(testSecret, body),
+			request.Header.Get(SignatureHeader),
+		)
+	}
+
+	if request.Header.Get(EventTypeHeader) != EventReorg {
+		t.Errorf(
+			"unexpected event type:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			EventReorg,
+			request.Header.Get(EventTypeHeader),
+		)
+	}
+
+	var message struct {
+		Type string
+		Data HeaderEvent
+	}
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Type != EventReorg || message.Data.Height != 10 {
+		t.Errorf("unexpected message: [%+v]", message)
+	}
+
+	deliveries, err := relayStore.LoadWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 0 {
+		t.Errorf("delivered event not removed")
+	}
+}
+
+func TestDispatcher_RetriesAcrossRestarts(t *testing.T) {
+	endpoint := &endpoint{status: http.StatusInternalServerError}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	relayStore := store.OpenMemory()
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	dispatcher := newTestDispatcher(server.URL, relayStore, fakeClock)
+
+	if err := dispatcher.Send(EventEpochEnd, &HeaderEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher.deliverDue(context.Background())
+
+	deliveries, err := relayStore.LoadWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("undelivered event not kept")
+	}
+	if deliveries[0].Attempts != 1 {
+		t.Errorf("unexpected attempts: [%v]", deliveries[0].Attempts)
+	}
+
+	expectedNextAttemptAt := fakeClock.Now().Add(retryBackoffTime)
+	if !deliveries[0].NextAttemptAt.Equal(expectedNextAttemptAt) {
+		t.Errorf(
+			"unexpected next attempt time:\n"+
+				"expected: [%v]\n"+
+				"actual:   [%v]\n",
+			expectedNextAttemptAt,
+			deliveries[0].NextAttemptAt,
+		)
+	}
+
+	// The delivery is not attempted before the back-off time passes.
+	dispatcher.deliverDue(context.Background())
+	if len(endpoint.requests) != 1 {
+		t.Errorf("delivery attempted before the back-off time")
+	}
+
+	// A restarted dispatcher delivers the event left undelivered.
+	endpoint.status = http.StatusOK
+	fakeClock.Advance(retryBackoffTime)
+
+	restarted := newTestDispatcher(server.URL, relayStore, fakeClock)
+	restarted.deliverDue(context.Background())
+
+	if len(endpoint.requests) != 2 {
+		t.Fatalf("undelivered event not retried")
+	}
+	if string(endpoint.bodies[0]) != string(endpoint.bodies[1]) {
+		t.Errorf("retried request body differs from the original one")
+	}
+
+	deliveries, err = relayStore.LoadWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 0 {
+		t.Errorf("delivered event not removed")
+	}
+}
+
+func TestDispatcher_DropsAfterMaxAttempts(t *testing.T) {
+	endpoint := &endpoint{status: http.StatusBadGateway}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	relayStore := store.OpenMemory()
+	fakeClock := clock.NewFake(time.Unix(1000, 0))
+	dispatcher := newTestDispatcher(server.URL, relayStore, fakeClock)
+
+	if err := dispatcher.Send(EventRetarget, &HeaderEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		dispatcher.deliverDue(context.Background())
+		fakeClock.Advance(maxRetryBackoffTime)
+	}
+
+	deliveries, err := relayStore.LoadWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 0 {
+		t.Errorf("event not dropped after max attempts")
+	}
+	if len(endpoint.requests) != 3 {
+		t.Errorf("unexpected number of requests: [%v]", len(endpoint.requests))
+	}
+}
+
+func TestDispatcher_FiltersEvents(t *testing.T) {
+	relayStore := store.OpenMemory()
+	dispatcher := NewDispatcher(
+		&Config{
+			URLs:   []string{"http://localhost"},
+			Events: []string{EventReorg},
+		},
+		relayStore,
+	)
+
+	if err := dispatcher.Send(EventRetarget, &HeaderEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := relayStore.LoadWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 0 {
+		t.Errorf("event of not configured type queued")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var tests = map[string]struct {
+		attempts        int
+		expectedBackoff time.Duration
+	}{
+		"first failure": {
+			attempts:        1,
+			expectedBackoff: 5 * time.Second,
+		},
+		"third failure": {
+			attempts:        3,
+			expectedBackoff: 20 * time.Second,
+		},
+		"capped": {
+			attempts:        20,
+			expectedBackoff: maxRetryBackoffTime,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			backoff := retryBackoff(test.attempts)
+			if backoff != test.expectedBackoff {
+				t.Errorf(
+					"unexpected back-off:\n"+
+						"expected: [%v]\n"+
+						"actual:   [%v]\n",
+					test.expectedBackoff,
+					backoff,
+				)
+			}
+		})
+	}
+}